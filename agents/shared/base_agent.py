@@ -511,6 +511,22 @@ class BaseAgent(ABC):
             level=level,
         )
 
+    def task_log(self, line: str, level: str = "info"):
+        """Stream a line of output for the current task to the orchestrator."""
+        if not self.bus or not self._current_task_id:
+            return
+
+        message = Message(
+            type="log",
+            source=self.agent_id,
+            payload={
+                "task_id": self._current_task_id,
+                "line": line,
+                "level": level,
+            },
+        )
+        self.bus.publish("task_logs", message, maxlen=50000)
+
     def record_metric(self, name: str, value: float, tags: Optional[Dict[str, str]] = None):
         """Record a metric."""
         full_tags = {"agent": self.name, "agent_id": self.agent_id}
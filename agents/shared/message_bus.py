@@ -6,6 +6,8 @@
 # =============================================================================
 
 from __future__ import annotations
+import base64
+import gzip
 import json
 import time
 import uuid
@@ -73,7 +75,7 @@ class Message:
             type=data["type"],
             source=data["source"],
             target=data.get("target", "*"),
-            payload=json.loads(data.get("payload", "{}")),
+            payload=json.loads(_decode_payload(data.get("payload", "{}"), data.get("codec", ""))),
             priority=MessagePriority(int(data.get("priority", 1))),
             correlation_id=data.get("correlation_id") or None,
             timestamp=float(data.get("timestamp", time.time())),
@@ -81,6 +83,28 @@ class Message:
         )
 
 
+def _decode_payload(payload: str, codec: str) -> str:
+    """
+    Undo payload compression applied by the publisher.
+
+    Large payloads are compressed and base64 encoded, with the codec named
+    in the message's "codec" field. Messages without a codec are raw JSON.
+    """
+    if not codec:
+        return payload
+
+    compressed = base64.b64decode(payload)
+    if codec == "gzip":
+        return gzip.decompress(compressed).decode("utf-8")
+    if codec == "zstd":
+        try:
+            import zstandard
+        except ImportError:
+            raise RuntimeError("zstandard package required: pip install zstandard")
+        return zstandard.ZstdDecompressor().decompressobj().decompress(compressed).decode("utf-8")
+    raise ValueError(f"Unknown payload codec: {codec}")
+
+
 class MessageBus:
     """
     Redis Streams-based message bus for inter-agent communication.
@@ -15,12 +15,49 @@ CREATE TABLE IF NOT EXISTS tasks (
     sources JSONB,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    completed_at TIMESTAMP WITH TIME ZONE
+    completed_at TIMESTAMP WITH TIME ZONE,
+    archived_at TIMESTAMP WITH TIME ZONE,  -- soft-deleted, hidden from default listings
+    parent_id VARCHAR(64)  -- task whose agent submitted this one as a subtask
 );
 
 CREATE INDEX idx_tasks_status ON tasks(status);
 CREATE INDEX idx_tasks_type ON tasks(type);
 CREATE INDEX idx_tasks_created_at ON tasks(created_at);
+CREATE INDEX idx_tasks_archived_at ON tasks(archived_at);
+CREATE INDEX idx_tasks_parent_id ON tasks(parent_id);
+
+-- -----------------------------------------------------------------------------
+-- Tasks Archive Table (cold tier for tasks past the retention window)
+-- -----------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS tasks_archive (
+    id VARCHAR(64) PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    status VARCHAR(32),
+    payload JSONB,
+    result JSONB,
+    confidence FLOAT,
+    sources JSONB,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    parent_id VARCHAR(64)
+);
+
+CREATE INDEX idx_tasks_archive_created_at ON tasks_archive(created_at);
+
+-- -----------------------------------------------------------------------------
+-- Task Notes Table (append-only operator annotations)
+-- -----------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS task_notes (
+    id BIGSERIAL PRIMARY KEY,
+    task_id VARCHAR(64) NOT NULL,
+    author VARCHAR(128) NOT NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_task_notes_task_id ON task_notes(task_id, id);
 
 -- -----------------------------------------------------------------------------
 -- Checkpoints Table
@@ -115,6 +152,22 @@ CREATE INDEX idx_agent_logs_task_id ON agent_logs(task_id);
 CREATE INDEX idx_agent_logs_agent_name ON agent_logs(agent_name);
 CREATE INDEX idx_agent_logs_created_at ON agent_logs(created_at);
 
+-- -----------------------------------------------------------------------------
+-- Task Outbox Table (audit records written with the state change they
+-- explain, relayed into agent_logs by the orchestrator)
+-- -----------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS task_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    key VARCHAR(128) NOT NULL UNIQUE,  -- task ID and attempt, one record each
+    task_id VARCHAR(64) NOT NULL,
+    record JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    relayed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_task_outbox_pending ON task_outbox(id) WHERE relayed_at IS NULL;
+CREATE INDEX idx_task_outbox_relayed_at ON task_outbox(relayed_at);
+
 -- -----------------------------------------------------------------------------
 -- Metrics Table
 -- -----------------------------------------------------------------------------
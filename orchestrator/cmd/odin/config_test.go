@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// validate runs config validate against a config file written from yaml
+func validate(t *testing.T, yaml string) (stdout, stderr string, err error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "odin.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prev := cfgFile
+	cfgFile = path
+	t.Cleanup(func() { cfgFile = prev })
+
+	var out, errOut bytes.Buffer
+	cmd := configCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs([]string{"validate"})
+	err = cmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	stdout, stderr, err := validate(t, `
+orchestrator:
+  max_concurrent_tasks: 4
+  retry_jitter: full
+llm:
+  primary: {provider: ollama, model: llama3, base_url: "http://localhost:11434"}
+agents:
+  enabled: [dev]
+`)
+	if err != nil {
+		t.Fatalf("validate: %v\n%s", err, stderr)
+	}
+	if strings.TrimSpace(stdout) != "Config is valid" {
+		t.Errorf("output = %q", stdout)
+	}
+}
+
+func TestValidateListsEveryProblem(t *testing.T) {
+	_, stderr, err := validate(t, `
+orchestrator:
+  listen_addr: "9000"
+  max_concurrent_tasks: -1
+  retry_jitter: sometimes
+  tracing: {sample_rate: 2}
+llm:
+  fallback:
+    - {model: llama3}
+logging:
+  redact: {keys: ["("]}
+`)
+	if err == nil || err.Error() != "config has 6 errors" {
+		t.Fatalf("err = %v, want a failure counting six errors", err)
+	}
+
+	want := []string{
+		"llm.fallback[0].provider: required",
+		"orchestrator.listen_addr:",
+		"orchestrator.max_concurrent_tasks: must not be negative",
+		`orchestrator.retry_jitter: unknown value "sometimes"`,
+		"orchestrator.tracing.sample_rate: must be between 0 and 1",
+		"logging.redact.keys[0]:",
+	}
+	var lines []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.HasPrefix(line, "error: ") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("printed %d lines, want %d:\n%s", len(lines), len(want), stderr)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(lines[i], "error: "+prefix) {
+			t.Errorf("line %d = %q, want it to start with %q", i, lines[i], "error: "+prefix)
+		}
+	}
+}
+
+func TestValidateOrderIsStable(t *testing.T) {
+	yaml := `
+llm:
+  profiles:
+    review: {rate_limit: -1}
+    analysis: {rate_limit: -1}
+    code_write: {rate_limit: -1}
+orchestrator:
+  aggregation:
+    review: {policy: weighted, weights: {security: -1, dev: -1}}
+`
+	_, first, _ := validate(t, yaml)
+	for i := 0; i < 10; i++ {
+		if _, again, _ := validate(t, yaml); again != first {
+			t.Fatalf("run %d printed\n%s\nwant the first run's\n%s", i+2, again, first)
+		}
+	}
+
+	want := []string{
+		"llm.profiles.analysis.rate_limit",
+		"llm.profiles.code_write.rate_limit",
+		"llm.profiles.review.rate_limit",
+		"orchestrator.aggregation.review.weights.dev",
+		"orchestrator.aggregation.review.weights.security",
+	}
+	var keys []string
+	for _, line := range strings.Split(first, "\n") {
+		if rest, ok := strings.CutPrefix(line, "error: "); ok {
+			key, _, _ := strings.Cut(rest, ":")
+			keys = append(keys, key)
+		}
+	}
+	if !slices.Equal(keys, want) {
+		t.Errorf("reported %v, want %v", keys, want)
+	}
+}
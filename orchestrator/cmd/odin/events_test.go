@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+)
+
+var epoch = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// sequence is a known run of one task through the orchestrator
+var sequence = []events.Event{
+	{ID: 1, Type: events.TaskScheduled, TaskID: "t-1", Time: epoch},
+	{ID: 2, Type: events.TaskDispatched, TaskID: "t-1", Agent: "dev", Time: epoch.Add(time.Second)},
+	{ID: 3, Type: events.AgentOffline, Agent: "review", Message: "missed 3 heartbeats", Time: epoch.Add(2 * time.Second)},
+	{ID: 4, Type: events.TaskScheduled, TaskID: "t-2", Time: epoch.Add(3 * time.Second)},
+	{ID: 5, Type: events.TaskFailed, TaskID: "t-1", Agent: "dev", Message: "timeout", Time: epoch.Add(4 * time.Second)},
+}
+
+// syncBuffer is a bytes.Buffer safe to read while the command writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// serveEvents streams the sequence over SSE, then holds the stream open
+func serveEvents(t *testing.T) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range sequence {
+			data, _ := json.Marshal(e)
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.ID, data)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	prev := apiAddr
+	apiAddr = srv.URL
+	t.Cleanup(func() { apiAddr = prev })
+}
+
+// tail runs events tail with args until its output ends with last
+func tail(t *testing.T, last string, args ...string) []string {
+	t.Helper()
+	serveEvents(t)
+
+	out := &syncBuffer{}
+	cmd := eventsTailCmd()
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs(args)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- cmd.ExecuteContext(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.HasSuffix(out.String(), last+"\n") {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatalf("output never ended with %q:\n%s", last, out)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestTailRendersEventSequence(t *testing.T) {
+	got := tail(t, "timeout")
+	want := []string{
+		"2026-01-02T03:04:05Z task.scheduled       task=t-1",
+		"2026-01-02T03:04:06Z task.dispatched      task=t-1 agent=dev",
+		"2026-01-02T03:04:07Z agent.offline        agent=review missed 3 heartbeats",
+		"2026-01-02T03:04:08Z task.scheduled       task=t-2",
+		"2026-01-02T03:04:09Z task.failed          task=t-1 agent=dev timeout",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTailFiltersByType(t *testing.T) {
+	got := tail(t, "timeout", "--type", "task.scheduled", "--type", "task.failed")
+	if len(got) != 3 {
+		t.Fatalf("printed %d lines, want the 3 matching events:\n%s", len(got), strings.Join(got, "\n"))
+	}
+	for _, line := range got {
+		if !strings.Contains(line, "task.scheduled") && !strings.Contains(line, "task.failed") {
+			t.Errorf("unfiltered line %q", line)
+		}
+	}
+}
+
+func TestTailFiltersByTask(t *testing.T) {
+	got := tail(t, "timeout", "--task", "t-1")
+	if len(got) != 3 {
+		t.Fatalf("printed %d lines, want t-1's 3 events:\n%s", len(got), strings.Join(got, "\n"))
+	}
+	for _, line := range got {
+		if !strings.Contains(line, "task=t-1") {
+			t.Errorf("line for another task %q", line)
+		}
+	}
+}
@@ -7,23 +7,47 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/krigsexe/odin/orchestrator/internal/alert"
+	"github.com/krigsexe/odin/orchestrator/internal/api"
+	"github.com/krigsexe/odin/orchestrator/internal/batch"
+	"github.com/krigsexe/odin/orchestrator/internal/consensus"
+	"github.com/krigsexe/odin/orchestrator/internal/doctor"
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/internal/export"
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"github.com/krigsexe/odin/orchestrator/internal/postmortem"
+	"github.com/krigsexe/odin/orchestrator/internal/redact"
+	"github.com/krigsexe/odin/orchestrator/internal/redisclient"
+	"github.com/krigsexe/odin/orchestrator/internal/repl"
+	"github.com/krigsexe/odin/orchestrator/internal/retention"
 	"github.com/krigsexe/odin/orchestrator/internal/router"
 	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+	"github.com/krigsexe/odin/orchestrator/internal/stream"
+	"github.com/krigsexe/odin/orchestrator/internal/supervisor"
 	"github.com/krigsexe/odin/orchestrator/pkg/config"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
 var (
-	version   = "7.0.0"
-	cfgFile   string
-	logger    *zap.Logger
+	version = "7.0.0"
+	cfgFile string
+	apiAddr string
+	logger  *zap.Logger
 )
 
 func main() {
@@ -43,11 +67,17 @@ Provides:
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: odin.config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&apiAddr, "addr", "http://localhost:9000", "orchestrator API address")
 
 	// Add commands
 	rootCmd.AddCommand(serveCmd())
 	rootCmd.AddCommand(statusCmd())
 	rootCmd.AddCommand(taskCmd())
+	rootCmd.AddCommand(replCmd())
+	rootCmd.AddCommand(configCmd())
+	rootCmd.AddCommand(eventsCmd())
+	rootCmd.AddCommand(queueCmd())
+	rootCmd.AddCommand(doctorCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -82,6 +112,366 @@ func statusCmd() *cobra.Command {
 	}
 }
 
+// configCmd inspects the loaded configuration
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Configuration commands",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "features",
+		Short: "List feature flags and whether they are on",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			features := cfg.Features()
+			names := make([]string, 0, len(features))
+			for name := range features {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			out := cmd.OutOrStdout()
+			for _, name := range names {
+				state := "off"
+				if features.Enabled(name) {
+					state = "on"
+				}
+				if _, known := config.KnownFeatures[name]; !known {
+					state += " (unknown)"
+				}
+				fmt.Fprintf(out, "%-24s %s\n", name, state)
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Check a config file for errors without starting anything",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			err = cfg.Validate()
+			if err == nil {
+				fmt.Fprintln(cmd.OutOrStdout(), "Config is valid")
+				return nil
+			}
+			problems := []error{err}
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				problems = joined.Unwrap()
+			}
+			for _, problem := range problems {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error:", problem)
+			}
+			cmd.SilenceUsage = true
+			return fmt.Errorf("config has %d errors", len(problems))
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "check",
+		Short: "Report settings that would leave the orchestrator unable to work",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			warnings := cfg.Warnings()
+			if len(warnings) == 0 {
+				fmt.Fprintln(out, "No problems found")
+				return nil
+			}
+			for _, warning := range warnings {
+				fmt.Fprintln(out, "warning:", warning)
+			}
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+// doctorCmd checks an installation end to end, exiting non-zero when a
+// critical check fails
+func doctorCmd() *cobra.Command {
+	var (
+		timeout       time.Duration
+		skipProviders bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check config, Redis, Postgres, providers and routes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cmd.SilenceUsage = true
+
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				doctor.Print(out, []doctor.Outcome{{
+					Name:     "config loads",
+					Critical: true,
+					Result: doctor.Result{
+						Status: doctor.StatusFail,
+						Detail: err.Error(),
+						Hint:   "pass --config, set ODIN_CONFIG, or fix the file's YAML",
+					},
+				}})
+				return fmt.Errorf("config could not be loaded")
+			}
+
+			ctx := cmd.Context()
+			checks := doctor.ConfigChecks(cfg)
+
+			redisClient, err := redisclient.New(cfg.Redis)
+			if err != nil {
+				return err
+			}
+			defer redisClient.Close()
+			checks = append(checks, doctor.Ping("redis", func(ctx context.Context) error {
+				return redisClient.Ping(ctx).Err()
+			}, "check redis.url and that Redis is running"))
+
+			checks = append(checks, doctor.Ping("postgres", func(ctx context.Context) error {
+				taskStore, err := store.NewPostgres(ctx, cfg.Database.URL, 1)
+				if err != nil {
+					return err
+				}
+				defer taskStore.Close()
+				return taskStore.Ping(ctx)
+			}, "check database.url and that Postgres is running"))
+
+			checks = append(checks, doctor.ProviderKeyChecks(cfg)...)
+			if !skipProviders {
+				llmClient := llm.New(cfg, zap.NewNop())
+				checks = append(checks, doctor.Providers(cfg, llmClient.WarmUp))
+			}
+
+			// Saved route edits count, when Redis has them
+			taskRouter := router.New(cfg, zap.NewNop())
+			taskRouter.SetRouteStore(router.NewRedisRouteStore(redisClient))
+			loadCtx, cancel := context.WithTimeout(ctx, timeout)
+			taskRouter.LoadRoutes(loadCtx)
+			cancel()
+			checks = append(checks, doctor.Routes(cfg, taskRouter.Routes()))
+
+			outcomes := doctor.Run(ctx, checks, timeout)
+			doctor.Print(out, outcomes)
+			if n := doctor.Failed(outcomes); n > 0 {
+				return fmt.Errorf("%d critical checks failed", n)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "time limit for each check")
+	cmd.Flags().BoolVar(&skipProviders, "skip-providers", false, "skip the one-token request to each provider")
+	return cmd
+}
+
+// eventsCmd watches the orchestrator's event stream
+func eventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Event stream commands",
+	}
+
+	cmd.AddCommand(eventsTailCmd())
+
+	return cmd
+}
+
+// eventsTailCmd prints live events, reconnecting when the stream drops
+func eventsTailCmd() *cobra.Command {
+	var (
+		types  []string
+		taskID string
+		since  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Print events as they happen",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter := eventFilter{taskID: taskID}
+			if len(types) > 0 {
+				filter.types = make(map[events.Type]bool, len(types))
+				for _, t := range types {
+					filter.types[events.Type(t)] = true
+				}
+			}
+
+			// Replay starts from the oldest event the orchestrator retains;
+			// --since trims that history by time
+			var after *uint64
+			if since != "" {
+				from, err := parseSince(since, time.Now())
+				if err != nil {
+					return err
+				}
+				filter.from = from
+				after = new(uint64)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			out := cmd.OutOrStdout()
+			client := api.NewClient(apiAddr)
+			backoff := time.Second
+			for {
+				err := client.StreamEvents(ctx, after, func(e events.Event) {
+					// Resume after the last event seen when reconnecting
+					last := e.ID
+					after = &last
+					backoff = time.Second
+					if filter.match(e) {
+						renderEvent(out, e)
+					}
+				})
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err == nil {
+					err = fmt.Errorf("stream closed")
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "event stream lost (%v), reconnecting in %s\n", err, backoff)
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, 30*time.Second)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&types, "type", nil, "only show these event types (repeatable)")
+	cmd.Flags().StringVar(&taskID, "task", "", "only show events for this task ID")
+	cmd.Flags().StringVar(&since, "since", "", "first replay retained events from this long ago (RFC 3339, date, or duration like 15m)")
+
+	return cmd
+}
+
+// eventFilter selects which events tail prints
+type eventFilter struct {
+	types  map[events.Type]bool // nil allows every type
+	taskID string
+	from   time.Time
+}
+
+func (f eventFilter) match(e events.Event) bool {
+	if f.types != nil && !f.types[e.Type] {
+		return false
+	}
+	if f.taskID != "" && e.TaskID != f.taskID {
+		return false
+	}
+	return !e.Time.Before(f.from)
+}
+
+// renderEvent prints one event as a line
+func renderEvent(w io.Writer, e events.Event) {
+	fmt.Fprintf(w, "%s %-20s", e.Time.Format(time.RFC3339), e.Type)
+	if e.TaskID != "" {
+		fmt.Fprintf(w, " task=%s", e.TaskID)
+	}
+	if e.Agent != "" {
+		fmt.Fprintf(w, " agent=%s", e.Agent)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(w, " %s", e.Message)
+	}
+	fmt.Fprintln(w)
+}
+
+// queueCmd saves and restores the scheduler's queue across restarts
+func queueCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "queue",
+		Short: "Queue maintenance commands",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "dump <file>",
+		Short: "Stop dispatching and save the scheduler's state to a file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			state, err := api.NewClient(apiAddr).Drain(cmd.Context())
+			if err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(state, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(args[0], append(data, '\n'), 0o600); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "saved %d queued, %d running, %d waiting and %d reserved groups to %s; dispatch is stopped\n",
+				len(state.Queued), len(state.Running), len(state.Waiting), len(state.Reserved), args[0])
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "restore <file>",
+		Short: "Load a saved scheduler state into a fresh orchestrator",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			var state scheduler.QueueState
+			if err := json.Unmarshal(data, &state); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", args[0], err)
+			}
+			status, err := api.NewClient(apiAddr).Restore(cmd.Context(), &state)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "restored: %v queued, %v waiting\n", status["queued"], status["waiting"])
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "resume",
+		Short: "Restart dispatching after a dump",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return api.NewClient(apiAddr).Resume(cmd.Context())
+		},
+	})
+
+	return cmd
+}
+
+// replCmd opens an interactive shell against a running orchestrator
+func replCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "repl",
+		Short: "Interactive shell for a running orchestrator",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return repl.New(api.NewClient(apiAddr), cmd.OutOrStdout()).Run(cmd.Context())
+		},
+	}
+}
+
 // taskCmd manages tasks
 func taskCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -89,27 +479,719 @@ func taskCmd() *cobra.Command {
 		Short: "Task management commands",
 	}
 
-	cmd.AddCommand(&cobra.Command{
+	cmd.AddCommand(taskListCmd())
+	cmd.AddCommand(taskSubmitCmd())
+	cmd.AddCommand(taskExportCmd())
+	cmd.AddCommand(taskShowCmd())
+	cmd.AddCommand(taskNoteCmd())
+	cmd.AddCommand(taskArchiveCmd())
+	cmd.AddCommand(taskLogsCmd())
+	cmd.AddCommand(taskExplainCmd())
+	cmd.AddCommand(taskDiffCmd())
+	cmd.AddCommand(taskConfigCmd())
+	cmd.AddCommand(taskDeadletterCmd())
+
+	return cmd
+}
+
+// taskListCmd lists recent tasks from the store
+func taskListCmd() *cobra.Command {
+	var (
+		includeArchived bool
+		limit           int
+	)
+
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List recent tasks",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// TODO: Query task store
-			fmt.Println("Recent tasks:")
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			taskStore, err := store.NewPostgres(cmd.Context(), cfg.Database.URL, cfg.Database.MaxConnections)
+			if err != nil {
+				return fmt.Errorf("failed to open task store: %w", err)
+			}
+			defer taskStore.Close()
+
+			tasks, err := taskStore.ListTasks(cmd.Context(), store.ListOptions{
+				IncludeArchived: includeArchived,
+				Limit:           limit,
+			})
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for _, t := range tasks {
+				status := t.Status
+				if t.Archived {
+					status += " (archived)"
+				}
+				fmt.Fprintf(out, "%-32s  %-12s  %-22s  %s\n", t.ID, t.Type, status, t.CreatedAt.Format(time.RFC3339))
+			}
 			return nil
 		},
-	})
+	}
 
-	cmd.AddCommand(&cobra.Command{
-		Use:   "submit [description]",
-		Short: "Submit a new task",
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "include soft-deleted and cold-storage tasks")
+	cmd.Flags().IntVar(&limit, "limit", 50, "maximum number of tasks to show")
+
+	return cmd
+}
+
+// taskArchiveCmd soft-deletes finished tasks
+func taskArchiveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "archive <task-id>...",
+		Short: "Archive finished tasks, hiding them from default listings",
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			description := args[0]
-			fmt.Printf("Submitting task: %s\n", description)
-			// TODO: Submit to orchestrator
+			client := api.NewClient(apiAddr)
+			for _, id := range args {
+				if err := client.ArchiveTask(cmd.Context(), id); err != nil {
+					return fmt.Errorf("failed to archive %s: %w", id, err)
+				}
+			}
 			return nil
 		},
-	})
+	}
+}
+
+// taskLogsCmd prints the buffered tail of a task's agent output
+func taskLogsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logs <task-id>",
+		Short: "Show recent agent output for a task",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lines, err := api.NewClient(apiAddr).TaskLogs(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			for _, line := range lines {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s  %-10s %s\n", line.Time.Format(time.RFC3339), line.Agent, line.Line)
+			}
+			return nil
+		},
+	}
+}
+
+// taskConfigCmd prints the resolved settings a task was dispatched under
+func taskConfigCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "config <task-id>",
+		Short: "Show the effective settings a task was last dispatched under",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ec, err := api.NewClient(apiAddr).EffectiveConfig(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(ec)
+		},
+	}
+}
+
+// deadLetterFlags are the filter flags shared by the deadletter commands
+type deadLetterFlags struct {
+	taskType, category, errText, since, until string
+}
+
+func (f *deadLetterFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.taskType, "type", "", "only tasks of this type")
+	cmd.Flags().StringVar(&f.category, "category", "", "only failures in this error category")
+	cmd.Flags().StringVar(&f.errText, "error", "", "only failures whose error contains this text")
+	cmd.Flags().StringVar(&f.since, "since", "", "only failures from this long ago on (RFC 3339, date, or duration like 24h)")
+	cmd.Flags().StringVar(&f.until, "until", "", "only failures before this (RFC 3339, date, or duration ago)")
+}
+
+// filter builds the dead-letter filter the flags describe
+func (f *deadLetterFlags) filter() (scheduler.DeadLetterFilter, error) {
+	filter := scheduler.DeadLetterFilter{Type: f.taskType, Category: f.category, Error: f.errText}
+	now := time.Now()
+	var err error
+	if f.since != "" {
+		if filter.Since, err = parseSince(f.since, now); err != nil {
+			return filter, err
+		}
+	}
+	if f.until != "" {
+		if filter.Until, err = parseSince(f.until, now); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// empty reports whether no filter flag was given
+func (f *deadLetterFlags) empty() bool {
+	return *f == deadLetterFlags{}
+}
+
+// taskDeadletterCmd inspects and manages tasks that failed for good
+func taskDeadletterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deadletter",
+		Short: "Inspect, retry and purge tasks that failed for good",
+	}
+
+	cmd.AddCommand(taskDeadletterListCmd())
+	cmd.AddCommand(taskDeadletterShowCmd())
+	cmd.AddCommand(taskDeadletterRetryCmd())
+	cmd.AddCommand(taskDeadletterPurgeCmd())
+
+	return cmd
+}
+
+func taskDeadletterListCmd() *cobra.Command {
+	var flags deadLetterFlags
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List dead-lettered tasks, oldest failure first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, err := flags.filter()
+			if err != nil {
+				return err
+			}
+			letters, err := api.NewClient(apiAddr).DeadLetters(cmd.Context(), filter)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, d := range letters {
+				fmt.Fprintf(out, "%-32s  %-16s  %-12s  %s  %s\n",
+					d.Task.ID, d.Task.Type, d.Category, d.FailedAt.Format(time.RFC3339), d.Error)
+			}
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	return cmd
+}
+
+func taskDeadletterShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <task-id>",
+		Short: "Show a dead-lettered task with its failure, payload and timeline",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, err := api.NewClient(apiAddr).DeadLetter(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			task := d.Task
+			fmt.Fprintf(out, "ID:        %s\n", task.ID)
+			if task.Name != "" {
+				fmt.Fprintf(out, "Name:      %s\n", task.Name)
+			}
+			fmt.Fprintf(out, "Type:      %s\n", task.Type)
+			fmt.Fprintf(out, "Failed at: %s\n", d.FailedAt.Format(time.RFC3339))
+			fmt.Fprintf(out, "Category:  %s\n", d.Category)
+			fmt.Fprintf(out, "Error:     %s\n", d.Error)
+			fmt.Fprintf(out, "Retries:   %d\n", task.Retries)
+			fmt.Fprintf(out, "Priority:  %d\n", task.Priority)
+			if len(task.Agents) > 0 {
+				fmt.Fprintf(out, "Agents:    %s\n", strings.Join(task.Agents, ", "))
+			}
+			if len(task.Dependencies) > 0 {
+				fmt.Fprintf(out, "Depends:   %s\n", strings.Join(task.Dependencies, ", "))
+			}
+
+			if len(task.Input) > 0 {
+				input, err := json.MarshalIndent(task.Input, "  ", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "\nInput:\n  %s\n", input)
+			}
+			if len(d.Notes) > 0 {
+				fmt.Fprintln(out, "\nNotes:")
+				for _, note := range d.Notes {
+					fmt.Fprintf(out, "  %s  %s: %s\n", note.CreatedAt.Format(time.RFC3339), note.Author, note.Text)
+				}
+			}
+			if len(d.Timeline) > 0 {
+				fmt.Fprintln(out, "\nTimeline:")
+				for _, e := range d.Timeline {
+					fmt.Fprintf(out, "  %s  %s %s\n", e.Time.Format(time.RFC3339), e.Type, e.Message)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func taskDeadletterRetryCmd() *cobra.Command {
+	var (
+		flags deadLetterFlags
+		all   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "retry <task-id>... | --all | <filter flags>",
+		Short: "Submit dead-lettered tasks again",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := api.NewClient(apiAddr)
+			out := cmd.OutOrStdout()
+
+			if len(args) > 0 {
+				if all || !flags.empty() {
+					return fmt.Errorf("give task IDs or a filter, not both")
+				}
+				for _, id := range args {
+					if err := client.RetryDeadLetter(cmd.Context(), id); err != nil {
+						return fmt.Errorf("failed to retry %s: %w", id, err)
+					}
+					fmt.Fprintf(out, "retried %s\n", id)
+				}
+				return nil
+			}
+
+			if !all && flags.empty() {
+				return fmt.Errorf("give task IDs, --all, or a filter")
+			}
+			filter, err := flags.filter()
+			if err != nil {
+				return err
+			}
+			retried, err := client.RetryDeadLetters(cmd.Context(), filter)
+			if err != nil {
+				return err
+			}
+			for _, id := range retried {
+				fmt.Fprintf(out, "retried %s\n", id)
+			}
+			fmt.Fprintf(out, "%d tasks retried\n", len(retried))
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	cmd.Flags().BoolVar(&all, "all", false, "retry every dead-lettered task")
+	return cmd
+}
+
+func taskDeadletterPurgeCmd() *cobra.Command {
+	var (
+		flags deadLetterFlags
+		yes   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Delete dead-lettered tasks for good",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, err := flags.filter()
+			if err != nil {
+				return err
+			}
+			client := api.NewClient(apiAddr)
+			out := cmd.OutOrStdout()
+
+			if !yes {
+				letters, err := client.DeadLetters(cmd.Context(), filter)
+				if err != nil {
+					return err
+				}
+				if len(letters) == 0 {
+					fmt.Fprintln(out, "no dead letters match")
+					return nil
+				}
+				fmt.Fprintf(out, "Purge %d dead-lettered tasks? [y/N] ", len(letters))
+				answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+					fmt.Fprintln(out, "aborted")
+					return nil
+				}
+			}
+
+			n, err := client.PurgeDeadLetters(cmd.Context(), filter)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%d dead letters purged\n", n)
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "purge without asking")
+	return cmd
+}
+
+// taskShowCmd prints a task's state, notes and timeline
+func taskShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <task-id>",
+		Short: "Show a task with its notes and timeline",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			detail, err := api.NewClient(apiAddr).Task(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			task := detail.Task
+			fmt.Fprintf(out, "ID:       %s\n", task.ID)
+			if task.Name != "" {
+				fmt.Fprintf(out, "Name:     %s\n", task.Name)
+			}
+			fmt.Fprintf(out, "State:    %s\n", task.State)
+			if !task.ScheduledAt.IsZero() {
+				fmt.Fprintf(out, "Priority: %d\n", task.Priority)
+				fmt.Fprintf(out, "Retries:  %d\n", task.Retries)
+			}
+			if task.Parent != "" {
+				fmt.Fprintf(out, "Parent:   %s\n", task.Parent)
+			}
+
+			if st := detail.Subtasks; st != nil {
+				fmt.Fprintf(out, "\nSubtasks: %d/%d completed, %d failed\n", st.Completed, st.Total, st.Failed)
+				for _, sub := range st.Subtasks {
+					wait := ""
+					if sub.Wait {
+						wait = " (awaited)"
+					}
+					fmt.Fprintf(out, "  %-32s  %-16s  %s%s\n", sub.TaskID, sub.Type, sub.State, wait)
+				}
+			}
+
+			fmt.Fprintln(out, "\nNotes:")
+			for _, note := range detail.Notes {
+				fmt.Fprintf(out, "  %s  %s: %s\n", note.CreatedAt.Format(time.RFC3339), note.Author, note.Text)
+			}
+
+			fmt.Fprintln(out, "\nTimeline:")
+			for _, e := range detail.Timeline {
+				fmt.Fprintf(out, "  %s  %s %s\n", e.Time.Format(time.RFC3339), e.Type, e.Message)
+			}
+			return nil
+		},
+	}
+}
+
+// taskExplainCmd shows how a task type would be routed right now, or
+// why a finished task failed
+func taskExplainCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain <task-type|task-id>",
+		Short: "Explain which agents a task type would be routed to, or why a task failed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !router.TaskType(args[0]).Valid() {
+				return explainFailure(cmd, args[0])
+			}
+
+			exp, err := api.NewClient(apiAddr).ExplainTask(cmd.Context(), &router.Task{Type: router.TaskType(args[0])})
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Type:   %s\n", exp.TaskType)
+			fmt.Fprintf(out, "Policy: %s\n\n", exp.Policy)
+			for _, c := range exp.Candidates {
+				verdict := "selected"
+				if !c.Selected {
+					verdict = "dropped: " + c.Reason
+				}
+				status := c.Status
+				if status == "" {
+					status = "-"
+				}
+				fmt.Fprintf(out, "  %-14s %-10s %s\n", c.Agent, status, verdict)
+			}
+			if exp.Error != "" {
+				fmt.Fprintf(out, "\n%s\n", exp.Error)
+			}
+			return nil
+		},
+	}
+}
+
+// explainFailure prints the root cause of a failed task from the store
+// and the agents' audit log
+func explainFailure(cmd *cobra.Command, id string) error {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	taskStore, err := store.NewPostgres(cmd.Context(), cfg.Database.URL, cfg.Database.MaxConnections)
+	if err != nil {
+		return fmt.Errorf("failed to open task store: %w", err)
+	}
+	defer taskStore.Close()
+
+	var normalize consensus.Normalizer
+	if names := cfg.LLM.Consensus.Normalize; len(names) > 0 {
+		if normalize, err = consensus.NewNormalizer(names); err != nil {
+			return err
+		}
+	}
+	exp, err := postmortem.Explain(cmd.Context(), taskStore, id, normalize)
+	if err != nil {
+		return err
+	}
+	exp.Write(cmd.OutOrStdout())
+	return nil
+}
+
+// taskNoteCmd attaches an operator note to a task
+func taskNoteCmd() *cobra.Command {
+	var author string
+
+	cmd := &cobra.Command{
+		Use:   "note <task-id> <text>",
+		Short: "Add a note to a task",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if author == "" {
+				author = os.Getenv("USER")
+			}
+			if author == "" {
+				return fmt.Errorf("--author is required")
+			}
+
+			note, err := api.NewClient(apiAddr).AddNote(cmd.Context(), args[0], author, strings.Join(args[1:], " "))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "note %d added\n", note.ID)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&author, "author", "", "note author (defaults to $USER)")
+
+	return cmd
+}
+
+// taskExportCmd streams finished tasks from the store as CSV or JSON lines
+func taskExportCmd() *cobra.Command {
+	var (
+		since  string
+		format string
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export finished tasks for offline analysis",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := parseSince(since, time.Now())
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			taskStore, err := store.NewPostgres(cmd.Context(), cfg.Database.URL, cfg.Database.MaxConnections)
+			if err != nil {
+				return fmt.Errorf("failed to open task store: %w", err)
+			}
+			defer taskStore.Close()
+
+			out := cmd.OutOrStdout()
+			if output != "" && output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+
+			n, err := export.Export(cmd.Context(), taskStore, from, format, out)
+			if err != nil {
+				return fmt.Errorf("export failed after %d tasks: %w", n, err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "exported %d tasks\n", n)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "24h", "start time (RFC 3339, date, or duration ago like 72h)")
+	cmd.Flags().StringVar(&format, "format", export.FormatCSV, "output format: csv or jsonl")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write to file instead of stdout")
+
+	return cmd
+}
+
+// taskDiffCmd compares the results of two runs of a task
+func taskDiffCmd() *cobra.Command {
+	var includeMetadata bool
+
+	cmd := &cobra.Command{
+		Use:   "diff <task-id> <other-task-id>",
+		Short: "Show how a task's result differs from another run's",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			taskStore, err := store.NewPostgres(cmd.Context(), cfg.Database.URL, cfg.Database.MaxConnections)
+			if err != nil {
+				return fmt.Errorf("failed to open task store: %w", err)
+			}
+			defer taskStore.Close()
+
+			diff, err := store.CompareResults(cmd.Context(), taskStore, args[0], args[1], store.DiffOptions{
+				IncludeMetadata: includeMetadata,
+			})
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if diff.Identical() {
+				fmt.Fprintln(out, "results are identical")
+				return nil
+			}
+			for _, c := range diff.Changes {
+				renderChange(out, c)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&includeMetadata, "include-metadata", false, "also compare agents, cost and start time")
+
+	return cmd
+}
+
+// renderChange prints one result difference, with a line diff for
+// multi-line text
+func renderChange(w io.Writer, c store.Change) {
+	switch {
+	case c.Kind == store.ChangeAdded:
+		fmt.Fprintf(w, "+ %s: %s\n", c.Path, compactJSON(c.After))
+	case c.Kind == store.ChangeRemoved:
+		fmt.Fprintf(w, "- %s: %s\n", c.Path, compactJSON(c.Before))
+	case len(c.Lines) > 0:
+		fmt.Fprintf(w, "~ %s:\n", c.Path)
+		for _, line := range c.Lines {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	default:
+		fmt.Fprintf(w, "~ %s: %s -> %s\n", c.Path, compactJSON(c.Before), compactJSON(c.After))
+	}
+}
+
+// compactJSON renders a decoded JSON value on one line
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// parseSince accepts an RFC 3339 time, a YYYY-MM-DD date, or a duration
+// counted back from now
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: want RFC 3339, YYYY-MM-DD or a duration", v)
+}
+
+// taskSubmitCmd submits a single task or a batch from a file or stdin
+func taskSubmitCmd() *cobra.Command {
+	var (
+		file     string
+		stdin    bool
+		taskType string
+		priority int
+
+		allOrNothing bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "submit [description]",
+		Short: "Submit a new task, or a batch with --file/--stdin",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var tasks []*router.Task
+
+			switch {
+			case file != "" && stdin:
+				return fmt.Errorf("--file and --stdin are mutually exclusive")
+			case file != "" || stdin:
+				in := cmd.InOrStdin()
+				if file != "" {
+					f, err := os.Open(file)
+					if err != nil {
+						return err
+					}
+					defer f.Close()
+					in = f
+				}
+				parsed, err := batch.Parse(in)
+				if err != nil {
+					return err
+				}
+				tasks = parsed
+			case len(args) > 0:
+				parsed, err := batch.Resolve([]batch.TaskSpec{{
+					Type:        taskType,
+					Description: strings.Join(args, " "),
+					Priority:    priority,
+				}})
+				if err != nil {
+					return err
+				}
+				tasks = parsed
+			default:
+				return fmt.Errorf("provide a description, --file or --stdin")
+			}
+
+			if allOrNothing {
+				status, err := api.NewClient(apiAddr).SubmitGroup(cmd.Context(), tasks)
+				if err != nil {
+					return fmt.Errorf("failed to submit tasks: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "group", status.ID)
+				for _, m := range status.Members {
+					fmt.Fprintln(cmd.OutOrStdout(), m.TaskID)
+				}
+				return nil
+			}
+
+			ids, err := api.NewClient(apiAddr).SubmitTasks(cmd.Context(), tasks)
+			if err != nil {
+				return fmt.Errorf("failed to submit tasks: %w", err)
+			}
+			for _, id := range ids {
+				fmt.Fprintln(cmd.OutOrStdout(), id)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "read task definitions (JSON/YAML) from file")
+	cmd.Flags().BoolVar(&stdin, "stdin", false, "read task definitions (JSON/YAML) from stdin")
+	cmd.Flags().StringVar(&taskType, "type", string(router.TaskCodeWrite), "task type for a single description")
+	cmd.Flags().IntVar(&priority, "priority", 1, "task priority (0-3) for a single description")
+	cmd.Flags().BoolVar(&allOrNothing, "all-or-nothing", false, "cancel the batch and run compensate tasks if any task fails")
 
 	return cmd
 }
@@ -132,15 +1214,163 @@ func runServer() error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	// Mask secrets in everything logged from here on
+	redactor, err := redact.New(cfg.Logging.Redact)
+	if err != nil {
+		return err
+	}
+	logger = logger.WithOptions(zap.WrapCore(redactor.Core))
+
+	for _, warning := range cfg.Warnings() {
+		logger.Warn("Questionable configuration", zap.String("problem", warning))
+	}
+	for _, name := range cfg.Features().Unknown() {
+		logger.Warn("Ignoring unknown feature flag", zap.String("flag", name))
+	}
+
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Initialize components
 	taskRouter := router.New(cfg, logger)
+
+	// Supervised agents stand in for the static list; other discovery
+	// backends see them through their own heartbeats or registrations
+	agentSupervisor := supervisor.New(cfg, logger)
+	if agentSupervisor.Enabled() && cfg.Agents.Discovery.Backend == "static" {
+		taskRouter.SetDiscovery(agentSupervisor)
+	}
 	taskScheduler := scheduler.New(cfg, logger)
+	taskScheduler.SetRouteCheck(taskRouter.Routable)
+	taskScheduler.SetReroute(taskRouter.Reroute)
+	taskScheduler.SetInstanceOf(taskRouter.Instance)
+	taskScheduler.SetBusyCheck(taskRouter.Saturated)
+	taskScheduler.SetDispatcher(taskRouter.Dispatch)
+
+	llmClient := llm.New(cfg, logger)
+	taskRouter.SetProviderHealth(llmClient.ProviderHealth)
+	taskScheduler.SetSpend(llmClient)
+
+	finishReplay, err := configureReplay(taskScheduler, cfg.Orchestrator.Replay)
+	if err != nil {
+		return err
+	}
+	defer finishReplay()
+
+	redisClient, err := redisclient.New(cfg.Redis)
+	if err != nil {
+		return fmt.Errorf("failed to create redis client: %w", err)
+	}
+	defer redisClient.Close()
+
+	compressor, err := stream.NewCompressor(cfg.Redis.Compression)
+	if err != nil {
+		return err
+	}
+	taskRouter.SetPublisher(stream.NewPublisher(redisClient, compressor))
+	taskRouter.SetRouteStore(router.NewRedisRouteStore(redisClient))
+	if err := taskRouter.LoadRoutes(ctx); err != nil {
+		logger.Warn("Could not load saved routes, using defaults", zap.Error(err))
+	}
+	go taskScheduler.ConsumeLogs(ctx, stream.NewConsumer(redisClient))
+
+	dedupTTL := time.Duration(cfg.Orchestrator.CompletionDedupTTL) * time.Second
+	taskScheduler.SetDeduper(scheduler.NewRedisDeduper(redisClient, dedupTTL))
+	if lc := cfg.Orchestrator.Leases; lc.Enabled {
+		owner := cfg.Orchestrator.InstanceID
+		if owner == "" {
+			host, _ := os.Hostname()
+			owner = fmt.Sprintf("%s-%d", host, os.Getpid())
+		}
+		leaseTTL := time.Duration(lc.TTL) * time.Second
+		taskScheduler.SetLeases(scheduler.NewRedisLeaseStore(redisClient), owner, leaseTTL)
+		logger.Info("Task leases enabled", zap.String("owner", owner), zap.Duration("ttl", leaseTTL))
+	}
+	taskStore, err := store.NewPostgres(ctx, cfg.Database.URL, cfg.Database.MaxConnections)
+	if err != nil {
+		return fmt.Errorf("failed to open task store: %w", err)
+	}
+	defer taskStore.Close()
+
+	apiServer := api.New(cfg, logger, taskRouter, taskScheduler)
+
+	eventBus := events.New(1000)
+	taskScheduler.SetEvents(eventBus)
+	taskRouter.SetEvents(eventBus)
+	llmClient.SetEvents(eventBus)
+	apiServer.SetEvents(eventBus)
+	apiServer.SetStore(taskStore)
+	if cfg.Orchestrator.Outbox.Enabled {
+		taskScheduler.SetTransitions(taskStore)
+	}
+	if cfg.Orchestrator.DeadLetter {
+		taskScheduler.SetDeadLetters(scheduler.NewRedisDeadLetterQueue(redisClient))
+	}
+	apiServer.SetRedactor(redactor)
+	apiServer.SetMetrics(llmClient.WriteMetrics)
+	apiServer.SetProviders(llmClient.Providers)
+
+	// Finish results a crash left unacknowledged before taking new ones
+	if cfg.Orchestrator.DurableResults {
+		taskScheduler.SetInbox(scheduler.NewRedisResultInbox(redisClient))
+		n, err := taskScheduler.RecoverResults(ctx)
+		if err != nil {
+			logger.Warn("Result inbox recovery failed", zap.Int("processed", n), zap.Error(err))
+		} else if n > 0 {
+			logger.Info("Recovered unacknowledged results", zap.Int("processed", n))
+		}
+	}
 
 	// Start components
+	if bf := cfg.Orchestrator.Backfill; bf.Enabled {
+		go func() {
+			window := time.Duration(bf.Window) * time.Hour
+			n, err := taskScheduler.Backfill(ctx, taskStore, window, bf.PageSize)
+			if err != nil {
+				logger.Warn("Completed-state backfill failed", zap.Int("loaded", n), zap.Error(err))
+				return
+			}
+			logger.Info("Completed-state backfill done", zap.Int("loaded", n))
+		}()
+	}
+
+	if oc := cfg.Orchestrator.Outbox; oc.Enabled {
+		go store.RelayLoop(ctx, taskStore, time.Duration(oc.Interval)*time.Second, oc.BatchSize, logger)
+	}
+
+	if ac := cfg.Orchestrator.Archival; ac.Enabled {
+		go taskScheduler.SweepArchive(ctx, taskStore,
+			time.Duration(ac.Retention)*time.Hour,
+			time.Duration(ac.Interval)*time.Minute,
+			ac.BatchSize,
+		)
+	}
+
+	if rc := cfg.Orchestrator.Retention; rc.Enabled {
+		go retention.New(rc, taskStore, logger).Run(ctx)
+	}
+
+	if cfg.Alerts.WebhookURL != "" {
+		alerts, err := alert.New(cfg.Alerts, logger)
+		if err != nil {
+			return err
+		}
+		go alerts.Run(ctx, eventBus)
+	}
+
+	if cfg.LLM.WarmUp {
+		go llmClient.WarmUp(ctx)
+	}
+
+	if agentSupervisor.Enabled() {
+		go func() {
+			if err := agentSupervisor.Start(ctx); err != nil {
+				logger.Error("Supervisor error", zap.Error(err))
+			}
+		}()
+	}
+
 	go func() {
 		if err := taskRouter.Start(ctx); err != nil {
 			logger.Error("Router error", zap.Error(err))
@@ -153,6 +1383,12 @@ func runServer() error {
 		}
 	}()
 
+	go func() {
+		if err := apiServer.Start(ctx); err != nil {
+			logger.Error("API server error", zap.Error(err))
+		}
+	}()
+
 	logger.Info("Orchestrator started",
 		zap.String("redis", cfg.Redis.URL),
 		zap.String("postgres", cfg.Database.URL),
@@ -168,3 +1404,50 @@ func runServer() error {
 
 	return nil
 }
+
+// configureReplay applies the replay settings to the scheduler. The returned
+// func closes the decision log and reports any divergence from a verified run.
+func configureReplay(s *scheduler.Scheduler, rc config.ReplayConfig) (func(), error) {
+	if rc.Seed != 0 {
+		s.SetRand(rand.New(rand.NewSource(rc.Seed)))
+	}
+
+	switch {
+	case rc.VerifyPath != "":
+		f, err := os.Open(rc.VerifyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open decision log: %w", err)
+		}
+		expected, err := scheduler.ReadDecisions(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		replayer := scheduler.NewReplayer(expected)
+		s.SetRecorder(replayer)
+		return func() {
+			if err := replayer.Err(); err != nil {
+				logger.Warn("Scheduling diverged from recorded run", zap.Error(err))
+				return
+			}
+			logger.Info("Scheduling matched recorded run", zap.Int("decisions", len(expected)))
+		}, nil
+
+	case rc.RecordPath != "":
+		f, err := os.OpenFile(rc.RecordPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create decision log: %w", err)
+		}
+		recorder := scheduler.NewJSONRecorder(f)
+		s.SetRecorder(recorder)
+		return func() {
+			if err := recorder.Err(); err != nil {
+				logger.Warn("Decision log incomplete", zap.Error(err))
+			}
+			f.Close()
+		}, nil
+	}
+
+	return func() {}, nil
+}
@@ -0,0 +1,239 @@
+// Package alert posts important events to an operator webhook
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Severity ranks how urgently an event needs an operator
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityInfo:     "info",
+	SeverityWarning:  "warning",
+	SeverityCritical: "critical",
+}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// ParseSeverity converts a config name to a Severity
+func ParseSeverity(name string) (Severity, error) {
+	for s, n := range severityNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown alert severity: %s", name)
+}
+
+// severities rates the event types worth alerting on; other types are
+// never posted
+var severities = map[events.Type]Severity{
+	events.TaskFailed:          SeverityCritical,
+	events.PipelineFailed:      SeverityCritical,
+	events.GroupFailed:         SeverityCritical,
+	events.ProviderCircuitOpen: SeverityCritical,
+	events.AgentOffline:        SeverityWarning,
+	events.TaskStarved:         SeverityWarning,
+	events.TaskExpired:         SeverityWarning,
+	events.TaskRetrying:        SeverityInfo,
+	events.TaskRequeued:        SeverityInfo,
+	events.TaskSuspended:       SeverityInfo,
+}
+
+// Payload is the Slack-compatible body posted for an alert
+type Payload struct {
+	Text string `json:"text"`
+}
+
+// Sink filters events and posts the ones that matter to a webhook
+type Sink struct {
+	url     string
+	types   map[events.Type]bool
+	min     Severity // lowest severity posted
+	window  time.Duration
+	limit   int
+	client  *http.Client
+	logger  *zap.Logger
+	now     func() time.Time
+	mu      sync.Mutex
+	sent    map[string]time.Time // dedupe key -> last post
+	recent  []time.Time          // posts in the last minute, oldest first
+	dropped int
+}
+
+// New creates a sink from config. An empty type list alerts on every
+// event type that has a severity.
+func New(cfg config.AlertsConfig, logger *zap.Logger) (*Sink, error) {
+	threshold, err := ParseSeverity(cfg.MinSeverity)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sink{
+		url:    cfg.WebhookURL,
+		min:    threshold,
+		window: time.Duration(cfg.DedupeWindow) * time.Second,
+		limit:  cfg.RateLimit,
+		client: &http.Client{Timeout: time.Duration(max(cfg.Timeout, 1)) * time.Second},
+		logger: logger,
+		now:    time.Now,
+		sent:   make(map[string]time.Time),
+	}
+	if len(cfg.Types) > 0 {
+		s.types = make(map[events.Type]bool, len(cfg.Types))
+		for _, t := range cfg.Types {
+			if _, ok := severities[events.Type(t)]; !ok {
+				return nil, fmt.Errorf("event type %s cannot be alerted on", t)
+			}
+			s.types[events.Type(t)] = true
+		}
+	}
+	return s, nil
+}
+
+// Run posts alerts for events published on the bus until ctx is done
+func (s *Sink) Run(ctx context.Context, bus *events.Bus) {
+	ch, unsubscribe := bus.Subscribe(256)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-ch:
+			if err := s.Handle(ctx, e); err != nil {
+				s.logger.Warn("Alert delivery failed",
+					zap.String("type", string(e.Type)),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// Handle posts an alert for one event unless it is filtered out, a
+// duplicate within the dedupe window, or over the rate limit
+func (s *Sink) Handle(ctx context.Context, e events.Event) error {
+	severity, ok := s.admit(e)
+	if !ok {
+		return nil
+	}
+	return s.post(ctx, Payload{Text: format(e, severity)})
+}
+
+// admit applies the type, severity, dedupe and rate limit filters,
+// counting the event as sent when it passes
+func (s *Sink) admit(e events.Event) (Severity, bool) {
+	severity, ok := severities[e.Type]
+	if !ok || severity < s.min || (s.types != nil && !s.types[e.Type]) {
+		return severity, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	key := dedupeKey(e)
+	if last, ok := s.sent[key]; ok && now.Sub(last) < s.window {
+		return severity, false
+	}
+
+	cutoff := now.Add(-time.Minute)
+	for len(s.recent) > 0 && !s.recent[0].After(cutoff) {
+		s.recent = s.recent[1:]
+	}
+	if s.limit > 0 && len(s.recent) >= s.limit {
+		s.dropped++
+		return severity, false
+	}
+
+	for k, t := range s.sent {
+		if now.Sub(t) >= s.window {
+			delete(s.sent, k)
+		}
+	}
+	s.sent[key] = now
+	s.recent = append(s.recent, now)
+
+	// Report what the rate limit swallowed since the last alert
+	if s.dropped > 0 {
+		s.logger.Warn("Alerts dropped by rate limit", zap.Int("dropped", s.dropped))
+		s.dropped = 0
+	}
+	return severity, true
+}
+
+// dedupeKey identifies repeats of the same alert: same event type about
+// the same task, agent or provider
+func dedupeKey(e events.Event) string {
+	provider, _ := e.Data["provider"].(string)
+	pipeline, _ := e.Data["pipeline_id"].(string)
+	return strings.Join([]string{string(e.Type), e.TaskID, e.Agent, provider, pipeline}, "|")
+}
+
+// format renders an event as a one-line alert message
+func format(e events.Event, severity Severity) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", severity, e.Type)
+	if e.TaskID != "" {
+		fmt.Fprintf(&b, " task=%s", e.TaskID)
+	}
+	if e.Agent != "" {
+		fmt.Fprintf(&b, " agent=%s", e.Agent)
+	}
+	if provider, ok := e.Data["provider"].(string); ok {
+		fmt.Fprintf(&b, " provider=%s", provider)
+	}
+	if pipeline, ok := e.Data["pipeline_id"].(string); ok {
+		fmt.Fprintf(&b, " pipeline=%s", pipeline)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	return b.String()
+}
+
+// post sends a payload to the webhook
+func (s *Sink) post(ctx context.Context, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// webhook records the payloads posted to it
+type webhook struct {
+	mu       sync.Mutex
+	payloads []Payload
+	status   int
+}
+
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var p Payload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.payloads = append(w.payloads, p)
+	if w.status != 0 {
+		rw.WriteHeader(w.status)
+	}
+}
+
+func (w *webhook) received() []Payload {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]Payload(nil), w.payloads...)
+}
+
+// newTestSink creates a sink posting to a test webhook on a clock the
+// test advances
+func newTestSink(t *testing.T, cfg config.AlertsConfig) (*Sink, *webhook, *time.Time) {
+	t.Helper()
+	hook := &webhook{}
+	srv := httptest.NewServer(hook)
+	t.Cleanup(srv.Close)
+
+	cfg.WebhookURL = srv.URL
+	if cfg.MinSeverity == "" {
+		cfg.MinSeverity = "warning"
+	}
+	s, err := New(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, hook, &now
+}
+
+func failed(taskID string) events.Event {
+	return events.Event{Type: events.TaskFailed, TaskID: taskID, Message: "retries exhausted"}
+}
+
+func TestPermanentFailurePostsOnce(t *testing.T) {
+	s, hook, now := newTestSink(t, config.AlertsConfig{DedupeWindow: 300})
+	ctx := context.Background()
+
+	if err := s.Handle(ctx, failed("t-1")); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	got := hook.received()
+	if len(got) != 1 {
+		t.Fatalf("webhook called %d times, want once", len(got))
+	}
+	if want := "[critical] task.failed task=t-1: retries exhausted"; got[0].Text != want {
+		t.Errorf("payload = %q, want %q", got[0].Text, want)
+	}
+
+	// The same failure again within the window is a duplicate
+	*now = now.Add(time.Minute)
+	if err := s.Handle(ctx, failed("t-1")); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if n := len(hook.received()); n != 1 {
+		t.Errorf("duplicate posted: %d calls", n)
+	}
+
+	// Another task is not a duplicate, and the window runs out
+	if err := s.Handle(ctx, failed("t-2")); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	*now = now.Add(5 * time.Minute)
+	if err := s.Handle(ctx, failed("t-1")); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if n := len(hook.received()); n != 3 {
+		t.Errorf("webhook called %d times, want 3", n)
+	}
+}
+
+func TestAlertFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.AlertsConfig
+		event  events.Event
+		posted bool
+	}{
+		{"below min severity", config.AlertsConfig{}, events.Event{Type: events.TaskRetrying, TaskID: "t-1"}, false},
+		{"at min severity", config.AlertsConfig{}, events.Event{Type: events.AgentOffline, Agent: "dev"}, true},
+		{"info when asked for", config.AlertsConfig{MinSeverity: "info"}, events.Event{Type: events.TaskRetrying, TaskID: "t-1"}, true},
+		{"not an alerting type", config.AlertsConfig{MinSeverity: "info"}, events.Event{Type: events.TaskCompleted, TaskID: "t-1"}, false},
+		{"outside the type list", config.AlertsConfig{Types: []string{"provider.circuit_open"}}, failed("t-1"), false},
+		{"in the type list", config.AlertsConfig{Types: []string{"provider.circuit_open"}},
+			events.Event{Type: events.ProviderCircuitOpen, Data: map[string]interface{}{"provider": "ollama"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, hook, _ := newTestSink(t, tt.cfg)
+			if err := s.Handle(context.Background(), tt.event); err != nil {
+				t.Fatalf("Handle: %v", err)
+			}
+			if posted := len(hook.received()) > 0; posted != tt.posted {
+				t.Errorf("posted = %v, want %v", posted, tt.posted)
+			}
+		})
+	}
+}
+
+func TestRateLimitCapsAlertsPerMinute(t *testing.T) {
+	s, hook, now := newTestSink(t, config.AlertsConfig{RateLimit: 2})
+	ctx := context.Background()
+
+	for _, id := range []string{"t-1", "t-2", "t-3"} {
+		if err := s.Handle(ctx, failed(id)); err != nil {
+			t.Fatalf("Handle: %v", err)
+		}
+	}
+	if n := len(hook.received()); n != 2 {
+		t.Fatalf("webhook called %d times, want the limit of 2", n)
+	}
+
+	*now = now.Add(time.Minute)
+	if err := s.Handle(ctx, failed("t-4")); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if n := len(hook.received()); n != 3 {
+		t.Errorf("webhook called %d times, want another once the minute passed", n)
+	}
+}
+
+func TestRunPostsBusEvents(t *testing.T) {
+	s, hook, _ := newTestSink(t, config.AlertsConfig{DedupeWindow: 60})
+	bus := events.New(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx, bus)
+
+	// Publish until the subscription is in place and the alert lands
+	deadline := time.Now().Add(time.Second)
+	for len(hook.received()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no alert posted for a published failure")
+		}
+		bus.Publish(failed("t-1"))
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(hook.received()); n != 1 {
+		t.Errorf("webhook called %d times for one repeated failure, want once", n)
+	}
+}
+
+func TestWebhookErrorReported(t *testing.T) {
+	s, hook, _ := newTestSink(t, config.AlertsConfig{})
+	hook.status = http.StatusInternalServerError
+
+	if err := s.Handle(context.Background(), failed("t-1")); err == nil {
+		t.Error("webhook failure not reported")
+	}
+}
+
+func TestNewRejectsBadConfig(t *testing.T) {
+	if _, err := New(config.AlertsConfig{MinSeverity: "panic"}, zap.NewNop()); err == nil {
+		t.Error("unknown severity accepted")
+	}
+	if _, err := New(config.AlertsConfig{MinSeverity: "info", Types: []string{"task.completed"}}, zap.NewNop()); err == nil {
+		t.Error("non-alerting event type accepted")
+	}
+}
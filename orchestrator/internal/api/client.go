@@ -0,0 +1,383 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+// Client talks to a running orchestrator's HTTP API
+type Client struct {
+	baseURL string
+	http    *http.Client
+	stream  *http.Client
+}
+
+// NewClient creates a new API client for the given base URL
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+		stream:  &http.Client{},
+	}
+}
+
+// SubmitTasks submits a batch of tasks and returns their IDs
+func (c *Client) SubmitTasks(ctx context.Context, tasks []*router.Task) ([]string, error) {
+	var ids []string
+	if err := c.do(ctx, http.MethodPost, "/api/v1/tasks", SubmitRequest{Tasks: tasks}, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// SubmitGroup submits tasks as an all-or-nothing group and returns its
+// initial status
+func (c *Client) SubmitGroup(ctx context.Context, tasks []*router.Task) (*scheduler.GroupStatus, error) {
+	var status scheduler.GroupStatus
+	if err := c.do(ctx, http.MethodPost, "/api/v1/tasks", SubmitRequest{Tasks: tasks, AllOrNothing: true}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Group returns the progress of an all-or-nothing group
+func (c *Client) Group(ctx context.Context, id string) (*scheduler.GroupStatus, error) {
+	var status scheduler.GroupStatus
+	if err := c.do(ctx, http.MethodGet, "/api/v1/groups/"+url.PathEscape(id), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// ExplainTask reports how a task would be routed without submitting it
+func (c *Client) ExplainTask(ctx context.Context, task *router.Task) (*router.RoutingExplanation, error) {
+	var exp router.RoutingExplanation
+	if err := c.do(ctx, http.MethodPost, "/api/v1/tasks/explain", task, &exp); err != nil {
+		return nil, err
+	}
+	return &exp, nil
+}
+
+// Plan reports how a batch or pipeline would run, without submitting it
+func (c *Client) Plan(ctx context.Context, req *PlanRequest) (*Plan, error) {
+	var plan Plan
+	if err := c.do(ctx, http.MethodPost, "/api/v1/plan", req, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// SubmitPipeline submits a pipeline and returns its initial status
+func (c *Client) SubmitPipeline(ctx context.Context, req PipelineRequest) (*scheduler.PipelineStatus, error) {
+	var status scheduler.PipelineStatus
+	if err := c.do(ctx, http.MethodPost, "/api/v1/pipelines", req, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Pipeline returns the progress of a pipeline
+func (c *Client) Pipeline(ctx context.Context, id string) (*scheduler.PipelineStatus, error) {
+	var status scheduler.PipelineStatus
+	if err := c.do(ctx, http.MethodGet, "/api/v1/pipelines/"+url.PathEscape(id), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Status returns the scheduler status counters
+func (c *Client) Status(ctx context.Context) (map[string]interface{}, error) {
+	var status map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/scheduler/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Drain stops dispatch and returns the scheduler's state
+func (c *Client) Drain(ctx context.Context) (*scheduler.QueueState, error) {
+	var state scheduler.QueueState
+	if err := c.do(ctx, http.MethodPost, "/api/v1/scheduler/drain", nil, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Resume restarts dispatch after Drain
+func (c *Client) Resume(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/scheduler/resume", nil, nil)
+}
+
+// Restore loads a drained scheduler's state and returns the new status
+func (c *Client) Restore(ctx context.Context, state *scheduler.QueueState) (map[string]interface{}, error) {
+	var status map[string]interface{}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/scheduler/restore", state, &status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ListTasks returns the queued and running tasks
+func (c *Client) ListTasks(ctx context.Context) ([]scheduler.TaskSnapshot, error) {
+	var tasks []scheduler.TaskSnapshot
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tasks", nil, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// CancelTask cancels a queued or running task
+func (c *Client) CancelTask(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/tasks/"+url.PathEscape(id)+"/cancel", nil, nil)
+}
+
+// ArchiveTask soft-deletes a finished task
+func (c *Client) ArchiveTask(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/tasks/"+url.PathEscape(id)+"/archive", nil, nil)
+}
+
+// TaskLogs returns the buffered tail of a task's agent output
+func (c *Client) TaskLogs(ctx context.Context, id string) ([]scheduler.LogLine, error) {
+	var lines []scheduler.LogLine
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tasks/"+url.PathEscape(id)+"/logs", nil, &lines); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// EffectiveConfig returns the resolved settings a task was last
+// dispatched under
+func (c *Client) EffectiveConfig(ctx context.Context, id string) (*scheduler.EffectiveConfig, error) {
+	var ec scheduler.EffectiveConfig
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tasks/"+url.PathEscape(id)+"/effective-config", nil, &ec); err != nil {
+		return nil, err
+	}
+	return &ec, nil
+}
+
+// Task returns a task's state, notes and timeline
+func (c *Client) Task(ctx context.Context, id string) (*TaskDetail, error) {
+	var detail TaskDetail
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tasks/"+url.PathEscape(id), nil, &detail); err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+// Subtasks returns a parent task's subtasks, live and recorded
+func (c *Client) Subtasks(ctx context.Context, id string) (*SubtasksResponse, error) {
+	var resp SubtasksResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tasks/"+url.PathEscape(id)+"/subtasks", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AddNote appends an operator note to a task
+func (c *Client) AddNote(ctx context.Context, id, author, text string) (*store.Note, error) {
+	var note store.Note
+	req := NoteRequest{Author: author, Text: text}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/tasks/"+url.PathEscape(id)+"/notes", req, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// DeadLetters lists the dead letters matching f, oldest failure first
+func (c *Client) DeadLetters(ctx context.Context, f scheduler.DeadLetterFilter) ([]scheduler.DeadLetter, error) {
+	var letters []scheduler.DeadLetter
+	if err := c.do(ctx, http.MethodGet, "/api/v1/deadletter"+deadLetterQuery(f), nil, &letters); err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+// DeadLetter returns one dead letter with its task's notes and timeline
+func (c *Client) DeadLetter(ctx context.Context, id string) (*DeadLetterDetail, error) {
+	var detail DeadLetterDetail
+	if err := c.do(ctx, http.MethodGet, "/api/v1/deadletter/"+url.PathEscape(id), nil, &detail); err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+// RetryDeadLetter submits a dead-lettered task again
+func (c *Client) RetryDeadLetter(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/deadletter/"+url.PathEscape(id)+"/retry", nil, nil)
+}
+
+// RetryDeadLetters submits every dead letter matching f again and
+// returns their task IDs
+func (c *Client) RetryDeadLetters(ctx context.Context, f scheduler.DeadLetterFilter) ([]string, error) {
+	var resp RetryResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/deadletter/retry", f, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Retried, nil
+}
+
+// PurgeDeadLetters deletes the dead letters matching f and returns how
+// many were deleted
+func (c *Client) PurgeDeadLetters(ctx context.Context, f scheduler.DeadLetterFilter) (int, error) {
+	var resp PurgeResponse
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/deadletter"+deadLetterQuery(f), nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Purged, nil
+}
+
+// deadLetterQuery encodes a filter as the query string the dead-letter
+// endpoints read
+func deadLetterQuery(f scheduler.DeadLetterFilter) string {
+	q := url.Values{}
+	for name, value := range map[string]string{"type": f.Type, "category": f.Category, "error": f.Error} {
+		if value != "" {
+			q.Set(name, value)
+		}
+	}
+	if !f.Since.IsZero() {
+		q.Set("since", f.Since.Format(time.RFC3339))
+	}
+	if !f.Until.IsZero() {
+		q.Set("until", f.Until.Format(time.RFC3339))
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	return "?" + q.Encode()
+}
+
+// ListAgents returns the agents known to the router
+func (c *Client) ListAgents(ctx context.Context) ([]router.AgentInfo, error) {
+	var agents []router.AgentInfo
+	if err := c.do(ctx, http.MethodGet, "/api/v1/agents", nil, &agents); err != nil {
+		return nil, err
+	}
+	return agents, nil
+}
+
+// DrainAgent stops new work being routed to an agent
+func (c *Client) DrainAgent(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/agents/"+url.PathEscape(name)+"/drain", nil, nil)
+}
+
+// UndrainAgent returns a drained agent to the routing pool
+func (c *Client) UndrainAgent(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/agents/"+url.PathEscape(name)+"/undrain", nil, nil)
+}
+
+// RestartAgent drains an agent and requeues its running tasks, returning
+// their IDs
+func (c *Client) RestartAgent(ctx context.Context, name string) ([]string, error) {
+	var out struct {
+		Requeued []string `json:"requeued"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/agents/"+url.PathEscape(name)+"/restart", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Requeued, nil
+}
+
+// StreamEvents calls fn for each event until ctx is cancelled or the
+// stream ends. A non-nil after replays retained events following that ID.
+func (c *Client) StreamEvents(ctx context.Context, after *uint64, fn func(events.Event)) error {
+	endpoint := c.baseURL + "/api/v1/events"
+	if after != nil {
+		endpoint += "?after=" + strconv.FormatUint(*after, 10)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// The stream is long-lived, so it can't share the client timeout
+	resp, err := c.stream.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("event stream: %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var e events.Event
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+			continue
+		}
+		fn(e)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
+
+// do performs a request and decodes the envelope's data into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   *Error          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("invalid response (%s): %w", resp.Status, err)
+	}
+	if !envelope.Success {
+		if envelope.Error == nil {
+			return &Error{Code: CodeInternal, Message: resp.Status}
+		}
+		return envelope.Error
+	}
+
+	if out != nil && len(envelope.Data) > 0 {
+		return json.Unmarshal(envelope.Data, out)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// dashboardFiles is the operator dashboard: a static page that polls the
+// JSON endpoints and follows the event stream
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// registerDashboard serves the embedded dashboard under /dashboard/ when
+// orchestrator.dashboard is on
+func (s *Server) registerDashboard() {
+	if !s.config.Orchestrator.Dashboard {
+		return
+	}
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		// The directory is embedded at build time, so this cannot happen
+		panic(err)
+	}
+	s.mux.Handle("GET /dashboard/", http.StripPrefix("/dashboard/", http.FileServerFS(files)))
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDashboardServedWhenEnabled(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.Dashboard = true
+	srv, _, _ := newTestServer(t, cfg)
+
+	rec := do(t, srv, http.MethodGet, "/dashboard/", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("content type = %q, want HTML", ct)
+	}
+	body := rec.Body.String()
+	for _, path := range []string{"/api/v1/scheduler/status", "/api/v1/agents", "/api/v1/tasks", "/api/v1/events"} {
+		if !strings.Contains(body, path) {
+			t.Errorf("page does not use %s", path)
+		}
+	}
+
+	rec = do(t, srv, http.MethodGet, "/dashboard", nil)
+	if rec.Code/100 != 3 || rec.Header().Get("Location") != "/dashboard/" {
+		t.Errorf("bare path: status %d to %q, want a redirect to /dashboard/", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestDashboardOffByDefault(t *testing.T) {
+	srv, _, _ := newTestServer(t, testConfig())
+	if rec := do(t, srv, http.MethodGet, "/dashboard/", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want not found with the dashboard off", rec.Code)
+	}
+}
+
+func TestDashboardDataEndpointsReturnJSON(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.Dashboard = true
+	srv, _, sched := newTestServer(t, cfg)
+	startRunning(t, sched, "t-1")
+
+	var status map[string]interface{}
+	if resp := decode(t, do(t, srv, http.MethodGet, "/api/v1/scheduler/status", nil), &status); !resp.Success || status == nil {
+		t.Errorf("scheduler status: %+v", resp)
+	}
+
+	var agents []map[string]interface{}
+	if resp := decode(t, do(t, srv, http.MethodGet, "/api/v1/agents", nil), &agents); !resp.Success || len(agents) != 3 {
+		t.Errorf("agents: %+v, want the three registered", agents)
+	}
+
+	var tasks []map[string]interface{}
+	if resp := decode(t, do(t, srv, http.MethodGet, "/api/v1/tasks", nil), &tasks); !resp.Success || len(tasks) != 1 || tasks[0]["id"] != "t-1" {
+		t.Errorf("tasks: %+v, want the running task", tasks)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+// DeadLetterDetail is a dead letter with the operator notes and retained
+// events of its task
+type DeadLetterDetail struct {
+	scheduler.DeadLetter
+	Notes    []store.Note   `json:"notes"`
+	Timeline []events.Event `json:"timeline"`
+}
+
+// RetryResponse lists the dead-lettered tasks a bulk retry submitted
+type RetryResponse struct {
+	Retried []string `json:"retried"`
+}
+
+// PurgeResponse is how many dead letters a purge deleted
+type PurgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+// deadLetterFilter reads a filter from query parameters: type, category,
+// error, and since and until as RFC 3339 times
+func deadLetterFilter(q url.Values) (scheduler.DeadLetterFilter, error) {
+	f := scheduler.DeadLetterFilter{
+		Type:     q.Get("type"),
+		Category: q.Get("category"),
+		Error:    q.Get("error"),
+	}
+	for _, p := range []struct {
+		name string
+		into *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, newError(CodeValidation, "invalid %s: %v", p.name, err)
+			}
+			*p.into = t
+		}
+	}
+	return f, nil
+}
+
+func (s *Server) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	f, err := deadLetterFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	letters, err := s.scheduler.DeadLetters(r.Context(), f)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: letters})
+}
+
+func (s *Server) handleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	letter, err := s.scheduler.DeadLetter(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	detail := DeadLetterDetail{DeadLetter: letter, Notes: []store.Note{}, Timeline: []events.Event{}}
+	if s.store != nil {
+		notes, err := s.store.Notes(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		detail.Notes = notes
+	}
+	if s.events != nil {
+		for _, e := range s.events.Since(0) {
+			if e.TaskID == id {
+				detail.Timeline = append(detail.Timeline, e)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: detail})
+}
+
+func (s *Server) handleRetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.scheduler.RetryDeadLetter(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: RetryResponse{Retried: []string{id}}})
+}
+
+// handleRetryDeadLetters retries every dead letter matching the filter
+// in the body. An error partway says how many were retried before it.
+func (s *Server) handleRetryDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var f scheduler.DeadLetterFilter
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+	retried, err := s.scheduler.RetryDeadLetters(r.Context(), f)
+	if err != nil {
+		writeError(w, fmt.Errorf("retried %d before failing: %w", len(retried), err))
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: RetryResponse{Retried: retried}})
+}
+
+func (s *Server) handlePurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	f, err := deadLetterFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	n, err := s.scheduler.PurgeDeadLetters(r.Context(), f)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: PurgeResponse{Purged: n}})
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+)
+
+// newDeadLetterServer has a review that timed out, a question that timed
+// out an hour later and a review with an invalid result
+func newDeadLetterServer(t *testing.T) (*Server, *scheduler.Scheduler) {
+	t.Helper()
+	srv, _, sched := newTestServer(t, testConfig())
+	dlq := scheduler.NewMemoryDeadLetterQueue()
+	sched.SetDeadLetters(dlq)
+
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, d := range []scheduler.DeadLetter{
+		{Task: scheduler.LeasedTask{ID: "rev-1", Type: "code_review", Agents: []string{"review"}}, Category: scheduler.CategoryTimeout, Error: "timed out"},
+		{Task: scheduler.LeasedTask{ID: "q-1", Type: "question", Agents: []string{"explain"}}, Category: scheduler.CategoryTimeout, Error: "timed out"},
+		{Task: scheduler.LeasedTask{ID: "rev-2", Type: "code_review", Agents: []string{"review"}}, Category: scheduler.CategoryInvalid, Error: "bad output"},
+	} {
+		d.FailedAt = at.Add(time.Duration(i) * time.Hour)
+		if err := dlq.Put(context.Background(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return srv, sched
+}
+
+func TestListDeadLettersWithFilters(t *testing.T) {
+	srv, _ := newDeadLetterServer(t)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"rev-1", "q-1", "rev-2"}},
+		{"?type=code_review", []string{"rev-1", "rev-2"}},
+		{"?category=timeout&error=TIMED", []string{"rev-1", "q-1"}},
+		{"?since=2026-01-01T01:00:00Z&until=2026-01-01T02:00:00Z", []string{"q-1"}},
+	}
+	for _, tt := range tests {
+		var letters []scheduler.DeadLetter
+		rec := do(t, srv, http.MethodGet, "/api/v1/deadletter"+tt.query, nil)
+		if resp := decode(t, rec, &letters); !resp.Success {
+			t.Fatalf("%s: status %d %+v", tt.query, rec.Code, resp.Error)
+		}
+		var ids []string
+		for _, d := range letters {
+			ids = append(ids, d.Task.ID)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("%q lists %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+
+	rec := do(t, srv, http.MethodGet, "/api/v1/deadletter?since=yesterday", nil)
+	if resp := decode(t, rec, nil); resp.Error == nil || resp.Error.Code != CodeValidation {
+		t.Errorf("bad since: status %d %s, want a validation error", rec.Code, rec.Body)
+	}
+}
+
+func TestBulkRetryOfMatchingDeadLetters(t *testing.T) {
+	srv, sched := newDeadLetterServer(t)
+
+	var data RetryResponse
+	rec := do(t, srv, http.MethodPost, "/api/v1/deadletter/retry", scheduler.DeadLetterFilter{Type: "code_review"})
+	if resp := decode(t, rec, &data); !resp.Success {
+		t.Fatalf("status %d: %+v", rec.Code, resp.Error)
+	}
+	if !slices.Equal(data.Retried, []string{"rev-1", "rev-2"}) {
+		t.Errorf("retried %v, want both reviews", data.Retried)
+	}
+	for _, id := range data.Retried {
+		if task, ok := sched.Task(id); !ok || task.State != scheduler.TaskQueued {
+			t.Errorf("%s is %v, want queued again", id, task.State)
+		}
+	}
+
+	var left []scheduler.DeadLetter
+	decode(t, do(t, srv, http.MethodGet, "/api/v1/deadletter", nil), &left)
+	if len(left) != 1 || left[0].Task.ID != "q-1" {
+		t.Errorf("left in the queue: %+v, want q-1", left)
+	}
+}
+
+func TestDeadLettersUnavailableWithoutQueue(t *testing.T) {
+	srv, _, _ := newTestServer(t, testConfig())
+
+	rec := do(t, srv, http.MethodGet, "/api/v1/deadletter", nil)
+	if resp := decode(t, rec, nil); resp.Error == nil || resp.Error.Code != CodeUnavailable {
+		t.Errorf("status %d %s, want unavailable", rec.Code, rec.Body)
+	}
+}
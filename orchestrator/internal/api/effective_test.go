@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+func TestEffectiveConfigEndpoint(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.Primary = config.ProviderConfig{Provider: "ollama", Model: "llama3"}
+	cfg.LLM.Profiles = map[string]config.ProviderConfig{"question": {Model: "llama3-mini"}}
+	cfg.Agents.Policies = map[string]config.AgentPolicy{"explain": {Timeout: 30}}
+	srv, _, sched := newTestServer(t, cfg)
+	srv.SetProviders(llm.New(cfg, zap.NewNop()).Providers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go sched.Start(ctx)
+
+	two := 2
+	rec := do(t, srv, http.MethodPost, "/api/v1/tasks", SubmitRequest{Tasks: []*router.Task{{
+		ID:          "t-1",
+		Type:        router.TaskQuestion,
+		Description: "why",
+		Priority:    int(scheduler.PriorityHigh),
+		Retry:       &scheduler.RetryPolicy{MaxRetries: &two},
+	}}})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("submit status %d: %s", rec.Code, rec.Body)
+	}
+	deadline := time.Now().Add(time.Second)
+	for task, _ := sched.Task("t-1"); task.State != scheduler.TaskRunning; task, _ = sched.Task("t-1") {
+		if time.Now().After(deadline) {
+			t.Fatal("task did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var ec scheduler.EffectiveConfig
+	if resp := decode(t, do(t, srv, http.MethodGet, "/api/v1/tasks/t-1/effective-config", nil), &ec); !resp.Success {
+		t.Fatalf("error = %+v", resp.Error)
+	}
+	if ec.Band != "high" || ec.Timeout != 30*time.Second || !slices.Equal(ec.Agents, []string{"explain"}) {
+		t.Errorf("effective config = %+v, want high priority, the agent's 30s and explain", ec)
+	}
+	if ec.Retry.MaxRetries == nil || *ec.Retry.MaxRetries != 2 {
+		t.Errorf("max retries = %v, want the task's 2", ec.Retry.MaxRetries)
+	}
+	chain, _ := ec.Submitted["providers"].([]interface{})
+	if len(chain) != 2 || chain[0] != "ollama/llama3-mini" || chain[1] != "ollama/llama3" {
+		t.Errorf("providers = %v, want the question profile then the primary", ec.Submitted["providers"])
+	}
+
+	rec = do(t, srv, http.MethodGet, "/api/v1/tasks/nope/effective-config", nil)
+	if resp := decode(t, rec, nil); resp.Error == nil || resp.Error.Code != CodeNotFound {
+		t.Errorf("unknown task: status %d %s, want not found", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+	"github.com/krigsexe/odin/orchestrator/internal/summary"
+)
+
+// ErrorCode is a stable, machine-readable error identifier
+type ErrorCode string
+
+const (
+	CodeValidation    ErrorCode = "validation"
+	CodeUnauthorized  ErrorCode = "unauthorized"
+	CodeForbidden     ErrorCode = "forbidden"
+	CodeNotFound      ErrorCode = "not_found"
+	CodeConflict      ErrorCode = "conflict"
+	CodeQueueFull     ErrorCode = "queue_full"
+	CodeQuotaExceeded ErrorCode = "quota_exceeded"
+	CodeUnavailable   ErrorCode = "unavailable"
+	CodeInternal      ErrorCode = "internal"
+)
+
+// codeStatus maps each code to its HTTP status
+var codeStatus = map[ErrorCode]int{
+	CodeValidation:    http.StatusBadRequest,
+	CodeUnauthorized:  http.StatusUnauthorized,
+	CodeForbidden:     http.StatusForbidden,
+	CodeNotFound:      http.StatusNotFound,
+	CodeConflict:      http.StatusConflict,
+	CodeQueueFull:     http.StatusServiceUnavailable,
+	CodeQuotaExceeded: http.StatusTooManyRequests,
+	CodeUnavailable:   http.StatusServiceUnavailable,
+	CodeInternal:      http.StatusInternalServerError,
+}
+
+// Status returns the HTTP status for the code
+func (c ErrorCode) Status() int {
+	if status, ok := codeStatus[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is the error body of a failed response
+type Error struct {
+	Code    ErrorCode              `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// newError builds an Error with a formatted message
+func newError(code ErrorCode, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// errorCodes maps sentinel errors from other packages to codes
+var errorCodes = []struct {
+	err  error
+	code ErrorCode
+}{
+	{router.ErrAgentNotFound, CodeNotFound},
+	{router.ErrRolloutNotFound, CodeNotFound},
+	{router.ErrAgentNotDraining, CodeConflict},
+	{router.ErrInvalidWeight, CodeValidation},
+	{router.ErrRequiredAgentUnavailable, CodeUnavailable},
+	{router.ErrUnhealthy, CodeUnavailable},
+	{router.ErrInvalidSandbox, CodeValidation},
+	{router.ErrInvalidCapabilities, CodeValidation},
+	{router.ErrNoCapableAgent, CodeUnavailable},
+	{router.ErrInvalidRoute, CodeValidation},
+	{scheduler.ErrQueueFull, CodeQueueFull},
+	{scheduler.ErrPipelineNotFound, CodeNotFound},
+	{scheduler.ErrGroupNotFound, CodeNotFound},
+	{scheduler.ErrTaskActive, CodeConflict},
+	{scheduler.ErrUnknownTask, CodeNotFound},
+	{scheduler.ErrNotOwner, CodeForbidden},
+	{scheduler.ErrInvalidResult, CodeValidation},
+	{scheduler.ErrStaleResult, CodeConflict},
+	{scheduler.ErrInvalidReservation, CodeValidation},
+	{scheduler.ErrQueueState, CodeValidation},
+	{scheduler.ErrNotEmpty, CodeConflict},
+	{scheduler.ErrPinnedElsewhere, CodeConflict},
+	{scheduler.ErrNotSuspended, CodeConflict},
+	{scheduler.ErrNoDeadLetterQueue, CodeUnavailable},
+	{scheduler.ErrDeadLetterNotFound, CodeNotFound},
+	{scheduler.ErrNoSubtasks, CodeNotFound},
+	{store.ErrNotFound, CodeNotFound},
+	{summary.ErrInvalidQuery, CodeValidation},
+	{llm.ErrInvalidParams, CodeValidation},
+}
+
+// toError converts any error into an API Error, keeping one that already is
+func toError(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	for _, m := range errorCodes {
+		if errors.Is(err, m.err) {
+			return &Error{Code: m.code, Message: err.Error()}
+		}
+	}
+	return &Error{Code: CodeInternal, Message: err.Error()}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+func TestErrorsMapToStatusAndEnvelope(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   ErrorCode
+		status int
+	}{
+		{scheduler.ErrQueueFull, CodeQueueFull, http.StatusServiceUnavailable},
+		{fmt.Errorf("submitting: %w", scheduler.ErrQueueFull), CodeQueueFull, http.StatusServiceUnavailable},
+		{store.ErrNotFound, CodeNotFound, http.StatusNotFound},
+		{scheduler.ErrUnknownTask, CodeNotFound, http.StatusNotFound},
+		{router.ErrInvalidWeight, CodeValidation, http.StatusBadRequest},
+		{scheduler.ErrNotOwner, CodeForbidden, http.StatusForbidden},
+		{scheduler.ErrStaleResult, CodeConflict, http.StatusConflict},
+		{router.ErrRequiredAgentUnavailable, CodeUnavailable, http.StatusServiceUnavailable},
+		{fmt.Errorf("%w: 0 of 2 LLM providers available", router.ErrUnhealthy), CodeUnavailable, http.StatusServiceUnavailable},
+		{newError(CodeQuotaExceeded, "too many tasks"), CodeQuotaExceeded, http.StatusTooManyRequests},
+		{newError(CodeUnauthorized, "bad token"), CodeUnauthorized, http.StatusUnauthorized},
+		{errors.New("disk on fire"), CodeInternal, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		writeError(rec, tt.err)
+
+		if rec.Code != tt.status {
+			t.Errorf("%v: status = %d, want %d", tt.err, rec.Code, tt.status)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%v: content type = %q", tt.err, ct)
+		}
+		resp := decode(t, rec, nil)
+		if resp.Success || resp.Error == nil {
+			t.Errorf("%v: envelope = %s, want an error", tt.err, rec.Body)
+			continue
+		}
+		if resp.Error.Code != tt.code || resp.Error.Message == "" {
+			t.Errorf("%v: error = %+v, want code %s with a message", tt.err, resp.Error, tt.code)
+		}
+	}
+}
+
+func TestUnknownCodeIsInternal(t *testing.T) {
+	if got := ErrorCode("mystery").Status(); got != http.StatusInternalServerError {
+		t.Errorf("status of an unknown code = %d, want 500", got)
+	}
+}
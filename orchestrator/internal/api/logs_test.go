@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// logConfig is testConfig keeping task logs
+func logConfig() *config.Config {
+	cfg := testConfig()
+	cfg.Orchestrator.TaskLogs = config.TaskLogConfig{MaxLines: 100, MaxBytes: 1 << 16, MaxTasks: 10}
+	return cfg
+}
+
+func TestTaskLogsReturnedInOrder(t *testing.T) {
+	srv, _, sched := newTestServer(t, logConfig())
+	for _, line := range []string{"cloning", "running tests", "done"} {
+		sched.AppendLog("t-1", "test", "info", line)
+	}
+	sched.AppendLog("t-2", "test", "info", "unrelated")
+
+	var logs []scheduler.LogLine
+	rec := do(t, srv, http.MethodGet, "/api/v1/tasks/t-1/logs", nil)
+	decode(t, rec, &logs)
+
+	want := []string{"cloning", "running tests", "done"}
+	if len(logs) != len(want) {
+		t.Fatalf("logs = %+v, want %d lines", logs, len(want))
+	}
+	for i, l := range logs {
+		if l.Line != want[i] || l.Seq != uint64(i+1) || l.Agent != "test" {
+			t.Errorf("line %d = %+v, want %q", i, l, want[i])
+		}
+	}
+}
+
+func TestTaskLogStreamReplaysTailThenFollows(t *testing.T) {
+	srv, _, sched := newTestServer(t, logConfig())
+	sched.AppendLog("t-1", "dev", "info", "buffered")
+
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/tasks/t-1/logs/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Lines published once the stream is open follow the buffered tail
+	events := make(chan scheduler.LogLine)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var line scheduler.LogLine
+			if json.Unmarshal([]byte(data), &line) == nil {
+				events <- line
+			}
+		}
+		close(events)
+	}()
+
+	if line := <-events; line.Line != "buffered" {
+		t.Fatalf("first event = %+v, want the buffered line", line)
+	}
+	sched.AppendLog("t-1", "dev", "info", "live")
+	if line := <-events; line.Line != "live" || line.Seq != 2 {
+		t.Errorf("second event = %+v, want the live line", line)
+	}
+}
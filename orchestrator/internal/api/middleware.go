@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Request headers read and written by the middleware
+const (
+	HeaderRequestID = "X-Request-ID"
+	HeaderTenant    = "X-Tenant-ID"
+)
+
+type requestIDKey struct{}
+
+// RequestID returns the ID of the request being served, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID generates a random request identifier
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("req-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// authenticateAgent resolves the agent named by the request's bearer token
+// against agents.tokens
+func (s *Server) authenticateAgent(r *http.Request) (string, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", newError(CodeUnauthorized, "missing agent token")
+	}
+	for agent, want := range s.config.Agents.Tokens {
+		if want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			return agent, nil
+		}
+	}
+	return "", newError(CodeUnauthorized, "invalid agent token")
+}
+
+// statusRecorder captures the response status for logging
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps the event stream working through the wrapper
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// requestLogger logs one entry per request. GETs of paths listed as
+// sampled go through a sampling logger so polling doesn't flood the log.
+type requestLogger struct {
+	logger  *zap.Logger
+	sampled *zap.Logger
+	paths   map[string]bool
+}
+
+func newRequestLogger(cfg config.RequestLogConfig, logger *zap.Logger) *requestLogger {
+	rl := &requestLogger{
+		logger: logger,
+		sampled: logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewSamplerWithOptions(core, time.Second, cfg.SampleFirst, cfg.SampleThereafter)
+		})),
+		paths: make(map[string]bool, len(cfg.SampledPaths)),
+	}
+	for _, path := range cfg.SampledPaths {
+		rl.paths[path] = true
+	}
+	return rl
+}
+
+// wrap assigns or propagates the request ID, recovers handler panics as
+// 500s and logs the outcome of every request
+func (rl *requestLogger) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(HeaderRequestID)
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, id)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			if p := recover(); p != nil {
+				rl.logger.Error("Handler panicked",
+					zap.String("request_id", id),
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.Any("panic", p),
+					zap.ByteString("stack", debug.Stack()),
+				)
+				if rec.status == 0 {
+					writeError(rec, newError(CodeInternal, "internal error"))
+				}
+			}
+
+			logger := rl.logger
+			if r.Method == http.MethodGet && rl.paths[r.URL.Path] {
+				logger = rl.sampled
+			}
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			logger.Info("HTTP request",
+				zap.String("request_id", id),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", status),
+				zap.Duration("latency", time.Since(start)),
+				zap.String("tenant", r.Header.Get(HeaderTenant)),
+			)
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
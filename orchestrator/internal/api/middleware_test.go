@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newObservedServer is newTestServer logging to an observer
+func newObservedServer(t *testing.T) (*Server, *scheduler.Scheduler, *observer.ObservedLogs) {
+	t.Helper()
+	core, logs := observer.New(zapcore.InfoLevel)
+	cfg := testConfig()
+	r := router.New(cfg, zap.NewNop())
+	r.RegisterAgent(&router.AgentInfo{ID: "explain-1", Name: "explain", Status: router.AgentStatusReady})
+	s := scheduler.New(cfg, zap.NewNop())
+	return New(cfg, zap.New(core), r, s), s, logs
+}
+
+func TestRequestIsLoggedWithItsFields(t *testing.T) {
+	srv, _, logs := newObservedServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	req.Header.Set(HeaderRequestID, "req-123")
+	req.Header.Set(HeaderTenant, "acme")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(HeaderRequestID); got != "req-123" {
+		t.Errorf("response request ID = %q, want it propagated", got)
+	}
+	entries := logs.FilterMessage("HTTP request").All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d request entries, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	want := map[string]interface{}{
+		"request_id": "req-123",
+		"method":     "GET",
+		"path":       "/api/v1/health",
+		"status":     int64(rec.Code),
+		"tenant":     "acme",
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %v, want %v", key, fields[key], value)
+		}
+	}
+	if _, ok := fields["latency"].(time.Duration); !ok {
+		t.Errorf("latency = %v, want a duration", fields["latency"])
+	}
+}
+
+func TestRequestIDIsGeneratedAndThreadedIntoTask(t *testing.T) {
+	srv, sched, _ := newObservedServer(t)
+	payloads := make(chan json.RawMessage, 1)
+	sched.SetDispatcher(func(ctx context.Context, d scheduler.Dispatched) error {
+		payloads <- d.Payload
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sched.Start(ctx)
+
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(SubmitRequest{Tasks: []*router.Task{{Type: router.TaskQuestion, Description: "6 x 7?"}}})
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", &body))
+
+	id := rec.Header().Get(HeaderRequestID)
+	if id == "" {
+		t.Fatalf("no request ID generated; status %d: %s", rec.Code, rec.Body)
+	}
+	select {
+	case payload := <-payloads:
+		var task router.Task
+		if err := json.Unmarshal(payload, &task); err != nil {
+			t.Fatalf("decoding payload: %v", err)
+		}
+		if task.RequestID != id {
+			t.Errorf("task request ID = %q, want %q", task.RequestID, id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("task was not dispatched")
+	}
+}
+
+func TestPanickingHandlerReturns500(t *testing.T) {
+	srv, _, logs := newObservedServer(t)
+	srv.mux.HandleFunc("GET /boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("kaboom")
+	})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if resp := decode(t, rec, nil); resp.Error == nil || resp.Error.Code != CodeInternal {
+		t.Errorf("envelope = %s, want an internal error", rec.Body)
+	}
+	if logs.FilterMessage("Handler panicked").Len() != 1 {
+		t.Error("panic was not logged")
+	}
+	entries := logs.FilterMessage("HTTP request").All()
+	if len(entries) != 1 || entries[0].ContextMap()["status"] != int64(500) {
+		t.Errorf("request entries = %v, want one with status 500", entries)
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+func TestNotesAreReturnedInOrderWithTaskDetail(t *testing.T) {
+	srv, _, sched := newTestServer(t, testConfig())
+	srv.SetStore(store.NewMemory())
+	srv.SetEvents(events.New(100))
+	if err := sched.Schedule(&scheduler.ScheduledTask{ID: "t-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, text := range []string{"paused pending approval from X", "approved", "resumed"} {
+		rec := do(t, srv, http.MethodPost, "/api/v1/tasks/t-1/notes", NoteRequest{Author: "ops", Text: text})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("add note %q: status %d: %s", text, rec.Code, rec.Body)
+		}
+	}
+
+	var detail TaskDetail
+	rec := do(t, srv, http.MethodGet, "/api/v1/tasks/t-1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	decode(t, rec, &detail)
+
+	if detail.Task.ID != "t-1" {
+		t.Errorf("task = %+v, want t-1", detail.Task)
+	}
+	want := []string{"paused pending approval from X", "approved", "resumed"}
+	if len(detail.Notes) != len(want) {
+		t.Fatalf("notes = %+v, want %d", detail.Notes, len(want))
+	}
+	for i, note := range detail.Notes {
+		if note.Text != want[i] || note.Author != "ops" || note.TaskID != "t-1" {
+			t.Errorf("note %d = %+v, want %q by ops", i, note, want[i])
+		}
+		if i > 0 && note.ID <= detail.Notes[i-1].ID {
+			t.Errorf("note %d ID %d does not follow %d", i, note.ID, detail.Notes[i-1].ID)
+		}
+	}
+
+	noted := 0
+	for _, e := range detail.Timeline {
+		if e.Type == events.TaskNoted {
+			noted++
+		}
+	}
+	if noted != 3 {
+		t.Errorf("timeline has %d note events, want 3", noted)
+	}
+}
+
+func TestAddNoteRejections(t *testing.T) {
+	srv, _, sched := newTestServer(t, testConfig())
+	if err := sched.Schedule(&scheduler.ScheduledTask{ID: "t-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := do(t, srv, http.MethodPost, "/api/v1/tasks/t-1/notes", NoteRequest{Author: "ops", Text: "hi"})
+	if resp := decode(t, rec, nil); rec.Code != http.StatusServiceUnavailable || resp.Error.Code != CodeUnavailable {
+		t.Errorf("without a store: status %d %s, want unavailable", rec.Code, rec.Body)
+	}
+
+	srv.SetStore(store.NewMemory())
+	tests := []struct {
+		name string
+		path string
+		body NoteRequest
+		code ErrorCode
+	}{
+		{"missing author", "/api/v1/tasks/t-1/notes", NoteRequest{Text: "hi"}, CodeValidation},
+		{"missing text", "/api/v1/tasks/t-1/notes", NoteRequest{Author: "ops"}, CodeValidation},
+		{"unknown task", "/api/v1/tasks/nope/notes", NoteRequest{Author: "ops", Text: "hi"}, CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := decode(t, do(t, srv, http.MethodPost, tt.path, tt.body), nil)
+			if resp.Error == nil || resp.Error.Code != tt.code {
+				t.Errorf("error = %+v, want %s", resp.Error, tt.code)
+			}
+		})
+	}
+}
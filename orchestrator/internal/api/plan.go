@@ -0,0 +1,253 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+)
+
+// Plan kinds
+const (
+	PlanTasks       = "tasks"
+	PlanReservation = "reservation"
+	PlanGroup       = "group"
+	PlanPipeline    = "pipeline"
+)
+
+// PlanRequest is the body accepted by the plan endpoint: a batch as for
+// POST /api/v1/tasks, or a pipeline as for POST /api/v1/pipelines
+type PlanRequest struct {
+	SubmitRequest
+	Pipeline *PipelineRequest `json:"pipeline,omitempty"`
+}
+
+// Plan is how a submission would run, worked out without submitting it.
+// IDs are generated for the plan only; a real submission gets new ones.
+type Plan struct {
+	Kind  string        `json:"kind"`
+	Tasks []PlannedTask `json:"tasks"`
+
+	// Waves are the task IDs by dependency depth: every task of a wave
+	// may run alongside the others once the waves before it finished.
+	// Compensating tasks only run on failure and are in none.
+	Waves [][]string `json:"waves"`
+
+	// MaxParallel is the widest wave, against the Slots the scheduler
+	// currently allows
+	MaxParallel int `json:"max_parallel"`
+	Slots       int `json:"slots"`
+
+	// Consensus is set when completions are verified by consensus
+	Consensus *PlanConsensus `json:"consensus,omitempty"`
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// PlannedTask is one task of a plan and how it would be routed
+type PlannedTask struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// Stage names the pipeline stage; PrerequisiteFor and Compensates the
+	// task a generated task was added for
+	Stage           string `json:"stage,omitempty"`
+	PrerequisiteFor string `json:"prerequisite_for,omitempty"`
+	Compensates     string `json:"compensates,omitempty"`
+
+	Dependencies []string                  `json:"dependencies,omitempty"`
+	Agents       []string                  `json:"agents"`
+	Providers    []string                  `json:"providers,omitempty"`
+	Routing      router.RoutingExplanation `json:"routing"`
+}
+
+// PlanConsensus is the consensus every completion would go through
+type PlanConsensus struct {
+	Providers    []string `json:"providers"`
+	MinAgreement float64  `json:"min_agreement"`
+	Fallback     string   `json:"fallback"`
+}
+
+// handlePlan validates and routes a submission as its endpoint would,
+// and reports the resulting task graph without running any of it
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	var req PlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+
+	var plan *Plan
+	if req.Pipeline != nil {
+		if len(req.Tasks) > 0 {
+			writeError(w, newError(CodeValidation, "plan either tasks or a pipeline, not both"))
+			return
+		}
+		pipeline, err := s.preparePipeline(r, req.Pipeline)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		plan = s.planPipeline(req.Pipeline, pipeline)
+	} else {
+		sub, err := s.prepareSubmission(r, &req.SubmitRequest)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		plan = s.planSubmission(&req.SubmitRequest, sub)
+	}
+
+	s.schedulePlan(plan)
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: plan})
+}
+
+// planSubmission lays out a prepared batch
+func (s *Server) planSubmission(req *SubmitRequest, sub *submission) *Plan {
+	plan := &Plan{Kind: PlanTasks}
+	switch {
+	case sub.group != nil:
+		plan.Kind = PlanGroup
+	case req.Reserve:
+		plan.Kind = PlanReservation
+	}
+
+	for i, task := range sub.tasks {
+		planned := s.plannedTask(task, sub.scheduled[i])
+		if id, ok := task.Input[router.InputPrerequisiteFor].(string); ok {
+			planned.PrerequisiteFor = id
+		}
+		plan.Tasks = append(plan.Tasks, planned)
+	}
+	if sub.group != nil {
+		// Members are in the order of the submission's tasks
+		for i, m := range sub.group.Members {
+			if m.Compensate == nil {
+				continue
+			}
+			planned := s.plannedTask(sub.tasks[i].Compensate, m.Compensate)
+			planned.Compensates = m.Task.ID
+			plan.Tasks = append(plan.Tasks, planned)
+		}
+	}
+	return plan
+}
+
+// planPipeline lays out a prepared pipeline, each stage depending on the
+// one before it as SchedulePipeline will chain them
+func (s *Server) planPipeline(req *PipelineRequest, pipeline *scheduler.Pipeline) *Plan {
+	plan := &Plan{Kind: PlanPipeline}
+	for i, stage := range pipeline.Stages {
+		planned := s.plannedTask(req.Stages[i].Task, stage.Task)
+		planned.Stage = stage.Name
+		if i > 0 {
+			planned.Dependencies = []string{pipeline.Stages[i-1].Task.ID}
+		}
+		plan.Tasks = append(plan.Tasks, planned)
+	}
+	return plan
+}
+
+func (s *Server) plannedTask(task *router.Task, scheduled *scheduler.ScheduledTask) PlannedTask {
+	planned := PlannedTask{
+		ID:           scheduled.ID,
+		Name:         scheduled.Name,
+		Type:         scheduled.Type,
+		Dependencies: scheduled.Dependencies,
+		Agents:       scheduled.Agents,
+		Routing:      s.router.Explain(task),
+	}
+	if chain, ok := scheduled.Settings["providers"].([]string); ok {
+		planned.Providers = chain
+	}
+	return planned
+}
+
+// schedulePlan works out the waves, concurrency and consensus of a plan,
+// warning of what would keep it from running as submitted
+func (s *Server) schedulePlan(plan *Plan) {
+	status := s.scheduler.GetStatus()
+	plan.Slots, _ = status["limit"].(int)
+
+	inPlan := make(map[string]PlannedTask, len(plan.Tasks))
+	for _, task := range plan.Tasks {
+		if task.Compensates == "" {
+			inPlan[task.ID] = task
+		}
+	}
+
+	// A task's wave is one past the deepest of its dependencies in the
+	// plan; others are already known to the scheduler, or never complete
+	depth := make(map[string]int, len(inPlan))
+	var depthOf func(id string) int
+	depthOf = func(id string) int {
+		if d, ok := depth[id]; ok {
+			return d
+		}
+		depth[id] = 0 // a cycle ends here rather than recursing forever
+		d := 0
+		for _, dep := range inPlan[id].Dependencies {
+			if _, ok := inPlan[dep]; ok {
+				d = max(d, depthOf(dep)+1)
+			}
+		}
+		depth[id] = d
+		return d
+	}
+	plan.Waves = [][]string{}
+	for _, task := range plan.Tasks {
+		if task.Compensates != "" {
+			continue
+		}
+		d := depthOf(task.ID)
+		for len(plan.Waves) <= d {
+			plan.Waves = append(plan.Waves, []string{})
+		}
+		plan.Waves[d] = append(plan.Waves[d], task.ID)
+
+		for _, dep := range task.Dependencies {
+			if _, ok := inPlan[dep]; ok {
+				continue
+			}
+			if _, known := s.scheduler.Task(dep); !known {
+				plan.Warnings = append(plan.Warnings,
+					fmt.Sprintf("%s depends on unknown task %s and will wait until it completes", task.ID, dep))
+			}
+		}
+	}
+	for _, wave := range plan.Waves {
+		plan.MaxParallel = max(plan.MaxParallel, len(wave))
+	}
+
+	// A reservation is checked against the configured limit, as Reserve
+	// checks it
+	maxConcurrent, _ := status["max_concurrent"].(int)
+	switch {
+	case plan.Kind == PlanReservation && len(plan.Tasks) > maxConcurrent:
+		plan.Warnings = append(plan.Warnings,
+			fmt.Sprintf("the reservation needs %d slots but at most %d exist, so it will be refused", len(plan.Tasks), maxConcurrent))
+	case plan.Kind == PlanReservation && slices.ContainsFunc(plan.Tasks, func(t PlannedTask) bool { return len(t.Dependencies) > 0 }):
+		plan.Warnings = append(plan.Warnings, "reserved tasks cannot have dependencies, so the reservation will be refused")
+	case plan.MaxParallel > plan.Slots:
+		plan.Warnings = append(plan.Warnings,
+			fmt.Sprintf("up to %d tasks could run at once but only %d slots exist; the rest will queue", plan.MaxParallel, plan.Slots))
+	}
+
+	if c := s.config.LLM.Consensus; c.Enabled {
+		plan.Consensus = &PlanConsensus{
+			Providers:    make([]string, len(c.Providers)),
+			MinAgreement: c.MinAgreement,
+			Fallback:     c.Fallback,
+		}
+		for i, pc := range c.Providers {
+			plan.Consensus.Providers[i] = pc.Provider + "/" + pc.Model
+		}
+		if len(c.Providers) < 2 {
+			plan.Warnings = append(plan.Warnings, "consensus is enabled with fewer than two providers")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// plan posts a plan request, failing the test unless it succeeds
+func plan(t *testing.T, srv *Server, req PlanRequest) Plan {
+	t.Helper()
+	var got Plan
+	rec := do(t, srv, http.MethodPost, "/api/v1/plan", req)
+	if resp := decode(t, rec, &got); !resp.Success {
+		t.Fatalf("status %d: %+v", rec.Code, resp.Error)
+	}
+	return got
+}
+
+func TestPipelinePlanMatchesExpandedGraph(t *testing.T) {
+	srv, _, sched := newTestServer(t, testConfig())
+
+	got := plan(t, srv, PlanRequest{Pipeline: &PipelineRequest{
+		Name: "release",
+		Stages: []PipelineStage{
+			{Name: "gather", Task: &router.Task{Type: router.TaskQuestion, Description: "what changed"}},
+			{Name: "review", Task: &router.Task{Type: router.TaskCodeReview, Description: "review it"}},
+			{Task: &router.Task{Type: router.TaskQuestion, Description: "summarize"}},
+		},
+	}})
+
+	if got.Kind != PlanPipeline || len(got.Tasks) != 3 {
+		t.Fatalf("plan = %+v, want a three-stage pipeline", got)
+	}
+	want := []struct {
+		stage  string
+		agents []string
+	}{
+		{"gather", []string{"explain"}},
+		{"review", []string{"review", "security"}},
+		{"stage-3", []string{"explain"}},
+	}
+	for i, w := range want {
+		task := got.Tasks[i]
+		if task.Stage != w.stage || !slices.Equal(task.Agents, w.agents) {
+			t.Errorf("task %d = stage %q agents %v, want %q %v", i, task.Stage, task.Agents, w.stage, w.agents)
+		}
+		if !slices.Equal(task.Routing.Selected, task.Agents) {
+			t.Errorf("%s routing selected %v, agents %v", task.Stage, task.Routing.Selected, task.Agents)
+		}
+		var deps []string
+		if i > 0 {
+			deps = []string{got.Tasks[i-1].ID}
+		}
+		if !slices.Equal(task.Dependencies, deps) {
+			t.Errorf("%s depends on %v, want %v", task.Stage, task.Dependencies, deps)
+		}
+		if len(got.Waves) != 3 || !slices.Equal(got.Waves[i], []string{task.ID}) {
+			t.Errorf("waves = %v, want one stage per wave in order", got.Waves)
+		}
+	}
+	if got.MaxParallel != 1 || got.Slots != 10 || len(got.Warnings) != 0 {
+		t.Errorf("max parallel %d of %d slots, warnings %v", got.MaxParallel, got.Slots, got.Warnings)
+	}
+
+	// Nothing was scheduled
+	if tasks := sched.List(); len(tasks) != 0 {
+		t.Errorf("planning scheduled %d tasks", len(tasks))
+	}
+	if pipelines := sched.Pipelines(); len(pipelines) != 0 {
+		t.Errorf("planning started %d pipelines", len(pipelines))
+	}
+}
+
+func TestPlanWarnsAboutMisconfiguration(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 2
+	cfg.LLM.Consensus.Enabled = true
+	cfg.LLM.Consensus.MinAgreement = 0.66
+	cfg.LLM.Consensus.Providers = []config.ProviderConfig{{Provider: "ollama", Model: "llama3"}}
+	srv, _, _ := newTestServer(t, cfg)
+
+	got := plan(t, srv, PlanRequest{SubmitRequest: SubmitRequest{Tasks: []*router.Task{
+		{ID: "a", Type: router.TaskQuestion, Description: "one"},
+		{ID: "b", Type: router.TaskQuestion, Description: "two"},
+		{ID: "c", Type: router.TaskQuestion, Description: "three", Dependencies: []string{"ghost"}},
+	}}})
+
+	if got.Kind != PlanTasks || got.MaxParallel != 3 || got.Slots != 2 {
+		t.Errorf("plan = %s, %d parallel of %d slots", got.Kind, got.MaxParallel, got.Slots)
+	}
+	if got.Consensus == nil || !slices.Equal(got.Consensus.Providers, []string{"ollama/llama3"}) {
+		t.Errorf("consensus = %+v", got.Consensus)
+	}
+	for _, want := range []string{
+		"depends on unknown task ghost",
+		"up to 3 tasks could run at once but only 2 slots exist",
+		"consensus is enabled with fewer than two providers",
+	} {
+		if !slices.ContainsFunc(got.Warnings, func(w string) bool { return strings.Contains(w, want) }) {
+			t.Errorf("warnings %q lack %q", got.Warnings, want)
+		}
+	}
+}
+
+func TestPlanRefusesTasksAndPipelineTogether(t *testing.T) {
+	srv, _, _ := newTestServer(t, testConfig())
+
+	rec := do(t, srv, http.MethodPost, "/api/v1/plan", PlanRequest{
+		SubmitRequest: SubmitRequest{Tasks: []*router.Task{{Type: router.TaskQuestion, Description: "one"}}},
+		Pipeline:      &PipelineRequest{Stages: []PipelineStage{{Task: &router.Task{Type: router.TaskQuestion, Description: "two"}}}},
+	})
+	if resp := decode(t, rec, nil); resp.Error == nil || resp.Error.Code != CodeValidation {
+		t.Errorf("status %d %s, want a validation error", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+func TestSubmissionAddsPrerequisiteAhead(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.Prerequisites = map[string][]config.PrerequisiteConfig{
+		"code_review": {{Type: "question", Description: "What changed in {{.Description}}?"}},
+	}
+	srv, _, sched := newTestServer(t, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go sched.Start(ctx)
+
+	var ids []string
+	rec := do(t, srv, http.MethodPost, "/api/v1/tasks", SubmitRequest{Tasks: []*router.Task{
+		{ID: "rev", Type: router.TaskCodeReview, Description: "PR 7"},
+	}})
+	if resp := decode(t, rec, &ids); !resp.Success {
+		t.Fatalf("submit status %d: %s", rec.Code, rec.Body)
+	}
+	if len(ids) != 2 || ids[1] != "rev" {
+		t.Fatalf("ids = %v, want a prerequisite then rev", ids)
+	}
+
+	// The prerequisite runs; the review waits on it
+	deadline := time.Now().Add(time.Second)
+	for task, _ := sched.Task(ids[0]); task.State != scheduler.TaskRunning; task, _ = sched.Task(ids[0]) {
+		if time.Now().After(deadline) {
+			t.Fatal("prerequisite did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if task, ok := sched.Task("rev"); !ok || task.State != scheduler.TaskQueued {
+		t.Errorf("review = %+v, want waiting on its prerequisite", task)
+	}
+
+	rec = do(t, srv, http.MethodPost, "/api/v1/tasks", SubmitRequest{Tasks: []*router.Task{
+		{ID: "solo", Type: router.TaskCodeReview, Description: "PR 8", NoPrerequisites: true},
+	}})
+	if resp := decode(t, rec, &ids); !resp.Success || len(ids) != 1 || ids[0] != "solo" {
+		t.Errorf("opted out: ids = %v (%s), want only solo", ids, rec.Body)
+	}
+}
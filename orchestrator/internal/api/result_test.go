@@ -0,0 +1,175 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+// report posts a result for a task as the agent holding token
+func report(t *testing.T, srv *Server, token, taskID string, result scheduler.Result) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(result); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/"+taskID+"/result", &body)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+// startRunning starts the scheduler and runs a task routed to review
+func startRunning(t *testing.T, sched *scheduler.Scheduler, id string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go sched.Start(ctx)
+
+	if err := sched.Schedule(&scheduler.ScheduledTask{ID: id, Agents: []string{"review"}}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for task, _ := sched.Task(id); task.State != scheduler.TaskRunning; task, _ = sched.Task(id) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not start", id)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// newResultServer is newTestServer with review and security holding agent tokens
+func newResultServer(t *testing.T) (*Server, *scheduler.Scheduler) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Agents.Tokens = map[string]string{"review": "review-secret", "security": "security-secret"}
+	srv, _, sched := newTestServer(t, cfg)
+	return srv, sched
+}
+
+func TestAgentReportsResult(t *testing.T) {
+	srv, sched := newResultServer(t)
+	startRunning(t, sched, "t-1")
+
+	rec := report(t, srv, "review-secret", "t-1", scheduler.Result{
+		Status:  scheduler.ResultCompleted,
+		Output:  map[string]interface{}{"verdict": "lgtm"},
+		Attempt: 1,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if task, _ := sched.Task("t-1"); task.State != scheduler.TaskCompleted {
+		t.Errorf("task is %s, want completed", task.State)
+	}
+}
+
+// countingTransitions counts the transitions the scheduler records
+type countingTransitions struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (c *countingTransitions) RecordTransition(ctx context.Context, t store.Transition) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.count++
+	return nil
+}
+
+func TestRedeliveredResultTransitionsOnce(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Tokens = map[string]string{"review": "review-secret"}
+	cfg.Orchestrator.CompletionDedupTTL = 60
+	srv, _, sched := newTestServer(t, cfg)
+	transitions := &countingTransitions{}
+	sched.SetTransitions(transitions)
+	startRunning(t, sched, "t-1")
+
+	result := scheduler.Result{Status: scheduler.ResultCompleted, Attempt: 1, DeliveryID: "d-1"}
+	for i := 0; i < 2; i++ {
+		if rec := report(t, srv, "review-secret", "t-1", result); rec.Code != http.StatusOK {
+			t.Fatalf("delivery %d: status %d: %s", i+1, rec.Code, rec.Body)
+		}
+	}
+
+	transitions.mu.Lock()
+	defer transitions.mu.Unlock()
+	if transitions.count != 1 {
+		t.Errorf("%d transitions recorded, want 1", transitions.count)
+	}
+}
+
+func TestReportResultRejections(t *testing.T) {
+	srv, sched := newResultServer(t)
+	startRunning(t, sched, "t-1")
+	done := scheduler.Result{Status: scheduler.ResultCompleted, Attempt: 1}
+
+	tests := []struct {
+		name   string
+		token  string
+		taskID string
+		result scheduler.Result
+		code   ErrorCode
+	}{
+		{"no token", "", "t-1", done, CodeUnauthorized},
+		{"bad token", "guess", "t-1", done, CodeUnauthorized},
+		{"agent does not own task", "security-secret", "t-1", done, CodeForbidden},
+		{"unknown task", "review-secret", "nope", done, CodeNotFound},
+		{"unknown status", "review-secret", "t-1", scheduler.Result{Status: "maybe", Attempt: 1}, CodeValidation},
+		{"earlier attempt", "review-secret", "t-1", scheduler.Result{Status: scheduler.ResultCompleted, Attempt: 0}, CodeConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := report(t, srv, tt.token, tt.taskID, tt.result)
+			resp := decode(t, rec, nil)
+			if resp.Error == nil || resp.Error.Code != tt.code || rec.Code != tt.code.Status() {
+				t.Errorf("status %d %s, want %s", rec.Code, rec.Body, tt.code)
+			}
+		})
+	}
+
+	if task, _ := sched.Task("t-1"); task.State != scheduler.TaskRunning {
+		t.Errorf("rejected reports changed the task to %s", task.State)
+	}
+}
+
+func TestRestartAgentDrainsAndRequeues(t *testing.T) {
+	srv, r, sched := newTestServer(t, testConfig())
+	startRunning(t, sched, "t-1")
+
+	var data struct {
+		Agent    string   `json:"agent"`
+		Requeued []string `json:"requeued"`
+	}
+	rec := do(t, srv, http.MethodPost, "/api/v1/agents/review/restart", nil)
+	if resp := decode(t, rec, &data); !resp.Success {
+		t.Fatalf("status %d: %+v", rec.Code, resp.Error)
+	}
+	if data.Agent != "review" || len(data.Requeued) != 1 || data.Requeued[0] != "t-1" {
+		t.Errorf("response = %+v, want t-1 requeued", data)
+	}
+	for _, agent := range r.GetAgents() {
+		if agent.Name == "review" && agent.Status != router.AgentStatusDraining {
+			t.Errorf("review is %s, want draining", agent.Status)
+		}
+	}
+
+	rec = do(t, srv, http.MethodPost, "/api/v1/agents/ghost/restart", nil)
+	if resp := decode(t, rec, nil); resp.Error == nil || resp.Error.Code != CodeNotFound {
+		t.Errorf("unknown agent: status %d %s, want not found", rec.Code, rec.Body)
+	}
+}
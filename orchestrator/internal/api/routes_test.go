@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"go.uber.org/zap"
+)
+
+// memoryRoutes is a RouteStore that keeps edits in memory
+type memoryRoutes struct {
+	mu      sync.Mutex
+	routes  map[router.TaskType][]router.RouteStage
+	changes []router.RouteChange
+	err     error
+}
+
+func (m *memoryRoutes) Save(ctx context.Context, change router.RouteChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	if m.routes == nil {
+		m.routes = make(map[router.TaskType][]router.RouteStage)
+	}
+	m.routes[change.TaskType] = change.After
+	m.changes = append(m.changes, change)
+	return nil
+}
+
+func (m *memoryRoutes) Load(ctx context.Context) (map[router.TaskType][]router.RouteStage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.routes, nil
+}
+
+func TestListRoutes(t *testing.T) {
+	srv, _, _ := newTestServer(t, testConfig())
+
+	var routes map[router.TaskType][]router.RouteStage
+	if resp := decode(t, do(t, srv, http.MethodGet, "/api/v1/routes", nil), &routes); !resp.Success {
+		t.Fatalf("error = %+v", resp.Error)
+	}
+	want := []router.RouteStage{{Agent: "retrieval", Optional: true}, {Agent: "explain"}}
+	if !slices.Equal(routes[router.TaskQuestion], want) {
+		t.Errorf("question route = %+v, want %+v", routes[router.TaskQuestion], want)
+	}
+}
+
+func TestSetRoutePersistsAndAudits(t *testing.T) {
+	srv, r, _ := newTestServer(t, testConfig())
+	store := &memoryRoutes{}
+	r.SetRouteStore(store)
+
+	stages := []router.RouteStage{{Agent: "review"}, {Agent: "security", Optional: true}}
+	rec := do(t, srv, http.MethodPut, "/api/v1/routes/question", RouteRequest{Stages: stages})
+	if resp := decode(t, rec, nil); !resp.Success {
+		t.Fatalf("status %d: %+v", rec.Code, resp.Error)
+	}
+
+	var routes map[router.TaskType][]router.RouteStage
+	decode(t, do(t, srv, http.MethodGet, "/api/v1/routes", nil), &routes)
+	if !slices.Equal(routes[router.TaskQuestion], stages) {
+		t.Errorf("question route = %+v, want the edit", routes[router.TaskQuestion])
+	}
+	if agents, err := r.Route(&router.Task{Type: router.TaskQuestion}); err != nil || !slices.Equal(agents, []string{"review", "security"}) {
+		t.Errorf("Route = %v, %v; want the edited route used", agents, err)
+	}
+
+	if len(store.changes) != 1 {
+		t.Fatalf("audited %d changes, want 1", len(store.changes))
+	}
+	change := store.changes[0]
+	if len(change.Before) != 2 || change.Before[1].Agent != "explain" || !strings.HasPrefix(change.Source, "api request") {
+		t.Errorf("audit = %+v, want the old route and the request recorded", change)
+	}
+
+	// A restarted router loads the edit over its defaults
+	restarted := router.New(testConfig(), zap.NewNop())
+	restarted.SetRouteStore(store)
+	if err := restarted.LoadRoutes(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := restarted.Routes()[router.TaskQuestion]; !slices.Equal(got, stages) {
+		t.Errorf("route after restart = %+v, want the edit", got)
+	}
+}
+
+func TestSetRouteRejectsInvalidRoutes(t *testing.T) {
+	srv, r, _ := newTestServer(t, testConfig())
+	store := &memoryRoutes{}
+	r.SetRouteStore(store)
+
+	tests := []struct {
+		name   string
+		path   string
+		stages []router.RouteStage
+	}{
+		{"unknown agent", "/api/v1/routes/question", []router.RouteStage{{Agent: "ghost"}}},
+		{"unknown task type", "/api/v1/routes/translate", []router.RouteStage{{Agent: "explain"}}},
+		{"no stages", "/api/v1/routes/question", nil},
+		{"agent listed twice", "/api/v1/routes/question", []router.RouteStage{{Agent: "review"}, {Agent: "review"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, srv, http.MethodPut, tt.path, RouteRequest{Stages: tt.stages})
+			resp := decode(t, rec, nil)
+			if resp.Error == nil || resp.Error.Code != CodeValidation || rec.Code != http.StatusBadRequest {
+				t.Errorf("status %d %s, want a validation error", rec.Code, rec.Body)
+			}
+		})
+	}
+
+	if len(store.changes) != 0 {
+		t.Errorf("rejected edits were saved: %+v", store.changes)
+	}
+	if got := r.Routes()[router.TaskQuestion]; got[len(got)-1].Agent != "explain" {
+		t.Errorf("question route = %+v, want it unchanged", got)
+	}
+}
+
+func TestSetRouteNotAppliedWhenSaveFails(t *testing.T) {
+	srv, r, _ := newTestServer(t, testConfig())
+	r.SetRouteStore(&memoryRoutes{err: errors.New("redis down")})
+
+	rec := do(t, srv, http.MethodPut, "/api/v1/routes/question", RouteRequest{Stages: []router.RouteStage{{Agent: "review"}}})
+	if rec.Code == http.StatusOK {
+		t.Fatal("edit accepted without being saved")
+	}
+	if got := r.Routes()[router.TaskQuestion]; got[len(got)-1].Agent != "explain" {
+		t.Errorf("question route = %+v, want it unchanged", got)
+	}
+}
@@ -0,0 +1,1199 @@
+// =============================================================================
+// ODIN v7.0 - Orchestrator HTTP API
+// =============================================================================
+// Operator-facing endpoints for agents, tasks and scheduler state
+// =============================================================================
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"github.com/krigsexe/odin/orchestrator/internal/redact"
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+	"github.com/krigsexe/odin/orchestrator/internal/summary"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Response is the JSON envelope returned by every endpoint
+type Response struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   *Error      `json:"error,omitempty"`
+}
+
+// Server exposes orchestrator components over HTTP
+type Server struct {
+	config    *config.Config
+	logger    *zap.Logger
+	router    *router.Router
+	scheduler *scheduler.Scheduler
+	events    *events.Bus
+	store     store.Store
+	mux       *http.ServeMux
+	requests  *requestLogger
+	redactor  *redact.Redactor
+	metrics   func(io.Writer) error
+	providers func(*llm.Request) ([]string, error)
+}
+
+// New creates a new API Server instance
+func New(cfg *config.Config, logger *zap.Logger, r *router.Router, s *scheduler.Scheduler) *Server {
+	srv := &Server{
+		config:    cfg,
+		logger:    logger,
+		router:    r,
+		scheduler: s,
+		mux:       http.NewServeMux(),
+		requests:  newRequestLogger(cfg.Orchestrator.RequestLog, logger),
+	}
+	srv.registerRoutes()
+	return srv
+}
+
+// registerRoutes wires handlers into the mux
+func (s *Server) registerRoutes() {
+	s.mux.HandleFunc("GET /api/v1/health", s.handleHealth)
+	s.mux.HandleFunc("GET /api/v1/agents", s.handleListAgents)
+	s.mux.HandleFunc("POST /api/v1/agents/{name}/drain", s.handleDrainAgent)
+	s.mux.HandleFunc("POST /api/v1/agents/{name}/undrain", s.handleUndrainAgent)
+	s.mux.HandleFunc("POST /api/v1/agents/{name}/restart", s.handleRestartAgent)
+	s.mux.HandleFunc("GET /api/v1/routes", s.handleListRoutes)
+	s.mux.HandleFunc("PUT /api/v1/routes/{type}", s.handleSetRoute)
+	s.mux.HandleFunc("GET /api/v1/rollouts", s.handleListRollouts)
+	s.mux.HandleFunc("PUT /api/v1/rollouts/{agent}", s.handleSetRollout)
+	s.mux.HandleFunc("POST /api/v1/rollouts/{agent}/weight", s.handleSetCanaryWeight)
+	s.mux.HandleFunc("DELETE /api/v1/rollouts/{agent}", s.handleRemoveRollout)
+	s.mux.HandleFunc("GET /api/v1/tasks", s.handleListTasks)
+	s.mux.HandleFunc("POST /api/v1/tasks", s.handleSubmitTasks)
+	s.mux.HandleFunc("POST /api/v1/tasks/explain", s.handleExplainTask)
+	s.mux.HandleFunc("GET /api/v1/tasks/{id}", s.handleGetTask)
+	s.mux.HandleFunc("POST /api/v1/tasks/{id}/cancel", s.handleCancelTask)
+	s.mux.HandleFunc("POST /api/v1/tasks/{id}/notes", s.handleAddNote)
+	s.mux.HandleFunc("POST /api/v1/tasks/{id}/ack", s.handleAckTask)
+	s.mux.HandleFunc("POST /api/v1/tasks/{id}/suspend", s.handleSuspendTask)
+	s.mux.HandleFunc("POST /api/v1/tasks/{id}/approval", s.handleApproveTask)
+	s.mux.HandleFunc("POST /api/v1/tasks/{id}/result", s.handleReportResult)
+	s.mux.HandleFunc("POST /api/v1/tasks/{id}/subtasks", s.handleSubmitSubtask)
+	s.mux.HandleFunc("GET /api/v1/tasks/{id}/subtasks", s.handleListSubtasks)
+	s.mux.HandleFunc("POST /api/v1/tasks/{id}/archive", s.handleArchiveTask)
+	s.mux.HandleFunc("GET /api/v1/tasks/{id}/logs", s.handleTaskLogs)
+	s.mux.HandleFunc("GET /api/v1/tasks/{id}/trace", s.handleTaskTrace)
+	s.mux.HandleFunc("GET /api/v1/tasks/{id}/effective-config", s.handleEffectiveConfig)
+	s.mux.HandleFunc("GET /api/v1/tasks/{id}/logs/stream", s.handleTailTaskLogs)
+	s.mux.HandleFunc("GET /api/v1/pipelines", s.handleListPipelines)
+	s.mux.HandleFunc("POST /api/v1/pipelines", s.handleSubmitPipeline)
+	s.mux.HandleFunc("GET /api/v1/pipelines/{id}", s.handleGetPipeline)
+	s.mux.HandleFunc("POST /api/v1/plan", s.handlePlan)
+	s.mux.HandleFunc("GET /api/v1/deadletter", s.handleListDeadLetters)
+	s.mux.HandleFunc("DELETE /api/v1/deadletter", s.handlePurgeDeadLetters)
+	s.mux.HandleFunc("POST /api/v1/deadletter/retry", s.handleRetryDeadLetters)
+	s.mux.HandleFunc("GET /api/v1/deadletter/{id}", s.handleGetDeadLetter)
+	s.mux.HandleFunc("POST /api/v1/deadletter/{id}/retry", s.handleRetryDeadLetter)
+	s.mux.HandleFunc("GET /api/v1/groups", s.handleListGroups)
+	s.mux.HandleFunc("GET /api/v1/groups/{id}", s.handleGetGroup)
+	s.mux.HandleFunc("GET /api/v1/events", s.handleEvents)
+	s.mux.HandleFunc("GET /api/v1/summary", s.handleSummary)
+	s.mux.HandleFunc("GET /api/v1/scheduler/status", s.handleSchedulerStatus)
+	s.mux.HandleFunc("GET /api/v1/scheduler/dump", s.handleSchedulerDump)
+	s.mux.HandleFunc("POST /api/v1/scheduler/drain", s.handleSchedulerDrain)
+	s.mux.HandleFunc("POST /api/v1/scheduler/resume", s.handleSchedulerResume)
+	s.mux.HandleFunc("POST /api/v1/scheduler/restore", s.handleSchedulerRestore)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+	s.registerDashboard()
+}
+
+// SetEvents attaches the event bus streamed by the events endpoint
+func (s *Server) SetEvents(bus *events.Bus) {
+	s.events = bus
+}
+
+// SetRedactor sets how task payloads are masked in debugging output.
+// Without one, payloads are left out.
+func (s *Server) SetRedactor(r *redact.Redactor) {
+	s.redactor = r
+}
+
+// SetMetrics sets the writer behind the Prometheus metrics endpoint
+func (s *Server) SetMetrics(fn func(io.Writer) error) {
+	s.metrics = fn
+}
+
+// SetProviders sets how a task's LLM provider chain is resolved for its
+// effective config. Without it, the chain is left out.
+func (s *Server) SetProviders(fn func(*llm.Request) ([]string, error)) {
+	s.providers = fn
+}
+
+// SetStore attaches the task store that operator notes are kept in
+func (s *Server) SetStore(st store.Store) {
+	s.store = st
+}
+
+// Handler returns the root HTTP handler
+func (s *Server) Handler() http.Handler {
+	return s.requests.wrap(s.mux)
+}
+
+// Start serves the API until the context is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:              s.config.Orchestrator.ListenAddr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("Starting HTTP API", zap.String("addr", httpServer.Addr))
+
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: map[string]string{"status": "ok"}})
+}
+
+func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.router.GetAgents()})
+}
+
+func (s *Server) handleDrainAgent(w http.ResponseWriter, r *http.Request) {
+	if err := s.router.DrainAgent(r.PathValue("name")); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true})
+}
+
+func (s *Server) handleUndrainAgent(w http.ResponseWriter, r *http.Request) {
+	if err := s.router.UndrainAgent(r.PathValue("name")); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true})
+}
+
+// handleRestartAgent prepares an agent for a restart: it is drained, and
+// the tasks running on it are requeued for other instances
+func (s *Server) handleRestartAgent(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	instance := s.router.Instance(name)
+	if err := s.router.DrainAgent(name); err != nil {
+		writeError(w, err)
+		return
+	}
+	requeued := s.scheduler.RequeueAgent(name, instance, scheduler.ReasonAgentRestart)
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: map[string]interface{}{"agent": name, "requeued": requeued}})
+}
+
+func (s *Server) handleListRoutes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.router.Routes()})
+}
+
+// RouteRequest is the body accepted when setting a task type's route
+type RouteRequest struct {
+	Stages []router.RouteStage `json:"stages"`
+}
+
+// handleSetRoute replaces one task type's route; the edit is audited
+// under the request that made it
+func (s *Server) handleSetRoute(w http.ResponseWriter, r *http.Request) {
+	var req RouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+
+	taskType := router.TaskType(r.PathValue("type"))
+	source := fmt.Sprintf("api request %s from %s", RequestID(r.Context()), r.RemoteAddr)
+	if err := s.router.SetRoute(r.Context(), taskType, req.Stages, source); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: req.Stages})
+}
+
+func (s *Server) handleListRollouts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.router.GetRollouts()})
+}
+
+func (s *Server) handleSetRollout(w http.ResponseWriter, r *http.Request) {
+	var ro router.Rollout
+	if err := json.NewDecoder(r.Body).Decode(&ro); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+	ro.Agent = r.PathValue("agent")
+
+	if err := s.router.SetRollout(ro); err != nil {
+		writeError(w, &Error{Code: CodeValidation, Message: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true})
+}
+
+func (s *Server) handleSetCanaryWeight(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Weight int `json:"weight"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+
+	if err := s.router.SetCanaryWeight(r.PathValue("agent"), body.Weight); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true})
+}
+
+func (s *Server) handleRemoveRollout(w http.ResponseWriter, r *http.Request) {
+	if err := s.router.RemoveRollout(r.PathValue("agent")); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true})
+}
+
+// SubmitRequest is the body accepted by the task submission endpoint
+type SubmitRequest struct {
+	Tasks []*router.Task `json:"tasks"`
+
+	// Reserve starts the batch together once enough concurrency slots
+	// are free for all of it, failing it if that takes longer than
+	// ReserveTimeout seconds (0 = no limit)
+	Reserve        bool `json:"reserve,omitempty"`
+	ReserveTimeout int  `json:"reserve_timeout,omitempty"`
+
+	// AllOrNothing runs the batch as a group: once any task fails for
+	// good, the rest are cancelled and the Compensate task of each one
+	// that completed is run. The response is then the group's status.
+	AllOrNothing bool `json:"all_or_nothing,omitempty"`
+}
+
+// prepareTask fills in a submitted task's defaults, validates it and
+// routes it, returning its agents
+func (s *Server) prepareTask(r *http.Request, task *router.Task) ([]string, error) {
+	if task.ID == "" {
+		task.ID = router.NewTaskID()
+	}
+	if task.RequestID == "" {
+		task.RequestID = RequestID(r.Context())
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+	if err := llm.ValidateOverride(task.Provider, task.Model); err != nil {
+		return nil, err
+	}
+	if err := llm.ValidateBudget(task.MaxTokens, task.MaxCost); err != nil {
+		return nil, err
+	}
+	if err := llm.ValidateParams(task.Params); err != nil {
+		return nil, err
+	}
+	if _, err := s.router.Sandbox(task); err != nil {
+		return nil, err
+	}
+	if task.MaxQueueTime < 0 {
+		return nil, newError(CodeValidation, "max_queue_time must not be negative")
+	}
+	if task.Timeout < 0 {
+		return nil, newError(CodeValidation, "timeout must not be negative")
+	}
+	return s.router.Route(task)
+}
+
+// prerequisites builds and routes the prerequisite tasks configured for
+// the submitted task at index, which then depends on them
+func (s *Server) prerequisites(r *http.Request, index int, task *router.Task) ([]*router.Task, [][]string, error) {
+	pres := s.router.Prerequisites(task)
+	routes := make([][]string, len(pres))
+	for i, pre := range pres {
+		agents, err := s.prepareTask(r, pre)
+		if err != nil {
+			return nil, nil, taskError(index, fmt.Errorf("prerequisite %s: %w", pre.Type, err))
+		}
+		routes[i] = agents
+	}
+	return pres, routes, nil
+}
+
+// scheduledTask builds the scheduler's view of a routed task
+func (s *Server) scheduledTask(task *router.Task, agents []string) *scheduler.ScheduledTask {
+	scheduled := &scheduler.ScheduledTask{
+		ID:           task.ID,
+		Name:         s.router.DisplayName(task),
+		Type:         string(task.Type),
+		Priority:     scheduler.TaskPriority(task.Priority),
+		Dependencies: task.Dependencies,
+		Agents:       agents,
+		Capabilities: task.Capabilities,
+		Traced:       s.router.Traced(task),
+		MaxQueueTime: task.MaxQueueTime,
+	}
+	if task.Retry != nil {
+		scheduled.Retry = *task.Retry
+	}
+	scheduled.Timeout = task.Timeout
+	scheduled.Settings = s.settings(task)
+	scheduled.ResultSchema = task.ResultSchema
+	scheduled.PinnedInstance = task.PinnedInstance
+	scheduled.Fallback = s.router.Fallback(task, agents)
+	return scheduled
+}
+
+// submitTask routes a task and attaches it to its scheduled task, to be
+// published to its agents when the scheduler dispatches it
+func (s *Server) submitTask(task *router.Task, scheduled *scheduler.ScheduledTask) error {
+	if err := s.router.SubmitTask(task); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to encode task: %w", err)
+	}
+	scheduled.Payload = payload
+	return nil
+}
+
+// settings resolves what a task will run under outside the scheduler,
+// for its effective config
+func (s *Server) settings(task *router.Task) map[string]interface{} {
+	settings := map[string]interface{}{"traced": s.router.Traced(task)}
+	if sandbox, err := s.router.Sandbox(task); err == nil {
+		settings["sandbox"] = sandbox
+	}
+	if s.providers != nil {
+		chain, err := s.providers(&llm.Request{TaskType: string(task.Type), Provider: task.Provider, Model: task.Model})
+		if err == nil {
+			settings["providers"] = chain
+		}
+	}
+	if budget := task.Budget(); budget != nil {
+		settings["budget"] = budget
+	}
+	if task.Params != nil {
+		settings["params"] = task.Params
+	}
+	if len(task.Capabilities) > 0 {
+		settings["capabilities"] = task.Capabilities
+	}
+	return settings
+}
+
+// submission is a validated and routed batch, ready to be submitted
+type submission struct {
+	// Prerequisites come just ahead of the tasks they were added for
+	tasks     []*router.Task
+	scheduled []*scheduler.ScheduledTask // by task
+
+	group *scheduler.Group // the batch as a group, for all_or_nothing
+}
+
+// prepareSubmission validates and routes a batch, expanding its
+// prerequisites, without submitting anything. A bad entry rejects the
+// whole batch.
+func (s *Server) prepareSubmission(r *http.Request, req *SubmitRequest) (*submission, error) {
+	if len(req.Tasks) == 0 {
+		return nil, newError(CodeValidation, "no tasks submitted")
+	}
+	routes := make([][]string, len(req.Tasks))
+	for i, task := range req.Tasks {
+		agents, err := s.prepareTask(r, task)
+		if err != nil {
+			return nil, taskError(i, err)
+		}
+		routes[i] = agents
+	}
+
+	if req.ReserveTimeout < 0 {
+		return nil, newError(CodeValidation, "reserve_timeout must not be negative")
+	}
+	if req.AllOrNothing {
+		if req.Reserve {
+			return nil, newError(CodeValidation, "reserve and all_or_nothing cannot be combined")
+		}
+		return s.prepareGroup(r, req.Tasks, routes)
+	}
+	for i, task := range req.Tasks {
+		if task.Compensate != nil {
+			return nil, taskError(i, newError(CodeValidation, "compensate requires all_or_nothing"))
+		}
+	}
+
+	sub := &submission{}
+	for i, task := range req.Tasks {
+		// A reservation cannot hold tasks with dependencies, so it gets
+		// no prerequisites
+		if !req.Reserve {
+			pres, preRoutes, err := s.prerequisites(r, i, task)
+			if err != nil {
+				return nil, err
+			}
+			for j, pre := range pres {
+				sub.tasks = append(sub.tasks, pre)
+				sub.scheduled = append(sub.scheduled, s.scheduledTask(pre, preRoutes[j]))
+			}
+		}
+		sub.tasks = append(sub.tasks, task)
+		sub.scheduled = append(sub.scheduled, s.scheduledTask(task, routes[i]))
+	}
+	return sub, nil
+}
+
+// prepareGroup builds an all-or-nothing group from a validated batch.
+// Compensating tasks are validated and routed now, so a group is never
+// accepted with a rollback that cannot run.
+func (s *Server) prepareGroup(r *http.Request, tasks []*router.Task, routes [][]string) (*submission, error) {
+	sub := &submission{group: &scheduler.Group{
+		ID:      router.NewTaskID(),
+		Members: make([]scheduler.GroupMember, 0, len(tasks)),
+	}}
+	for i, task := range tasks {
+		// Prerequisites join the group, but have nothing to compensate
+		pres, preRoutes, err := s.prerequisites(r, i, task)
+		if err != nil {
+			return nil, err
+		}
+		for j, pre := range pres {
+			member := scheduler.GroupMember{Task: s.scheduledTask(pre, preRoutes[j])}
+			sub.group.Members = append(sub.group.Members, member)
+			sub.tasks = append(sub.tasks, pre)
+			sub.scheduled = append(sub.scheduled, member.Task)
+		}
+
+		member := scheduler.GroupMember{Task: s.scheduledTask(task, routes[i])}
+		if comp := task.Compensate; comp != nil {
+			if comp.Compensate != nil || len(comp.Dependencies) > 0 {
+				return nil, taskError(i, newError(CodeValidation, "a compensating task cannot have dependencies or its own compensation"))
+			}
+			agents, err := s.prepareTask(r, comp)
+			if err != nil {
+				return nil, taskError(i, fmt.Errorf("compensate: %w", err))
+			}
+			member.Compensate = s.scheduledTask(comp, agents)
+			member.Compensate.Input = comp.Input
+		}
+		sub.group.Members = append(sub.group.Members, member)
+		sub.tasks = append(sub.tasks, task)
+		sub.scheduled = append(sub.scheduled, member.Task)
+	}
+	return sub, nil
+}
+
+// submitGroup schedules a prepared all-or-nothing group
+func (s *Server) submitGroup(w http.ResponseWriter, sub *submission) {
+	for i, task := range sub.tasks {
+		member := sub.group.Members[i]
+		if err := s.submitTask(task, member.Task); err != nil {
+			writeError(w, err)
+			return
+		}
+		if task.Compensate != nil {
+			if err := s.submitTask(task.Compensate, member.Compensate); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+	}
+	if err := s.scheduler.ScheduleGroup(sub.group); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	status, err := s.scheduler.Group(sub.group.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, Response{Success: true, Data: status})
+}
+
+func (s *Server) handleSubmitTasks(w http.ResponseWriter, r *http.Request) {
+	var req SubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+	sub, err := s.prepareSubmission(r, &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if sub.group != nil {
+		s.submitGroup(w, sub)
+		return
+	}
+
+	ids := make([]string, 0, len(sub.tasks))
+	reserved := make([]*scheduler.ScheduledTask, 0, len(sub.tasks))
+	for i, task := range sub.tasks {
+		scheduled := sub.scheduled[i]
+		if err := s.submitTask(task, scheduled); err != nil {
+			writeError(w, err)
+			return
+		}
+		if req.Reserve {
+			reserved = append(reserved, scheduled)
+			ids = append(ids, task.ID)
+			continue
+		}
+		err := s.scheduler.Schedule(scheduled)
+		if err != nil {
+			// Earlier tasks in the batch are already queued
+			apiErr := toError(err)
+			apiErr.Details = map[string]interface{}{"accepted": ids}
+			writeError(w, apiErr)
+			return
+		}
+		ids = append(ids, task.ID)
+	}
+
+	if req.Reserve {
+		// A reservation is all or nothing
+		if err := s.scheduler.Reserve(reserved, time.Duration(req.ReserveTimeout)*time.Second); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusAccepted, Response{Success: true, Data: ids})
+}
+
+// handleExplainTask reports how a task spec would be routed, without
+// submitting it
+func (s *Server) handleExplainTask(w http.ResponseWriter, r *http.Request) {
+	var task router.Task
+	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+	if task.Type == "" {
+		writeError(w, newError(CodeValidation, "task type is required"))
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.router.Explain(&task)})
+}
+
+// PipelineRequest is the body accepted by the pipeline submission endpoint
+type PipelineRequest struct {
+	Name   string          `json:"name"`
+	Policy string          `json:"policy"`
+	Budget int             `json:"budget,omitempty"` // seconds shared by all stages
+	Stages []PipelineStage `json:"stages"`
+}
+
+// PipelineStage is one named stage of a submitted pipeline
+type PipelineStage struct {
+	Name string       `json:"name"`
+	Task *router.Task `json:"task"`
+}
+
+func (s *Server) handleSubmitPipeline(w http.ResponseWriter, r *http.Request) {
+	var req PipelineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+	pipeline, err := s.preparePipeline(r, &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	for i, stage := range req.Stages {
+		if err := s.submitTask(stage.Task, pipeline.Stages[i].Task); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if err := s.scheduler.SchedulePipeline(pipeline); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	status, err := s.scheduler.Pipeline(pipeline.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, Response{Success: true, Data: status})
+}
+
+// preparePipeline validates and routes every stage of a pipeline without
+// submitting anything
+func (s *Server) preparePipeline(r *http.Request, req *PipelineRequest) (*scheduler.Pipeline, error) {
+	if len(req.Stages) == 0 {
+		return nil, newError(CodeValidation, "pipeline has no stages")
+	}
+	if req.Budget < 0 {
+		return nil, newError(CodeValidation, "pipeline budget must not be negative")
+	}
+
+	pipeline := &scheduler.Pipeline{
+		ID:     router.NewTaskID(),
+		Name:   req.Name,
+		Policy: req.Policy,
+		Stages: make([]scheduler.Stage, len(req.Stages)),
+		Budget: time.Duration(req.Budget) * time.Second,
+	}
+
+	for i, stage := range req.Stages {
+		task := stage.Task
+		if task == nil {
+			return nil, taskError(i, newError(CodeValidation, "missing task"))
+		}
+		task.ID = router.NewTaskID()
+		agents, err := s.prepareTask(r, task)
+		if err != nil {
+			return nil, taskError(i, err)
+		}
+
+		name := stage.Name
+		if name == "" {
+			name = fmt.Sprintf("stage-%d", i+1)
+		}
+		// A stage depends only on the stage before it, which
+		// SchedulePipeline chains, and starts from its own input
+		scheduled := s.scheduledTask(task, agents)
+		scheduled.Dependencies = nil
+		scheduled.Input = task.Input
+		pipeline.Stages[i] = scheduler.Stage{Name: name, Task: scheduled}
+	}
+	return pipeline, nil
+}
+
+func (s *Server) handleListPipelines(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.scheduler.Pipelines()})
+}
+
+func (s *Server) handleGetPipeline(w http.ResponseWriter, r *http.Request) {
+	status, err := s.scheduler.Pipeline(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: status})
+}
+
+func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.scheduler.Groups()})
+}
+
+func (s *Server) handleGetGroup(w http.ResponseWriter, r *http.Request) {
+	status, err := s.scheduler.Group(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: status})
+}
+
+func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.scheduler.List()})
+}
+
+func (s *Server) handleCancelTask(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !s.scheduler.Cancel(id) {
+		writeError(w, newError(CodeNotFound, "task not found: %s", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true})
+}
+
+// handleArchiveTask soft-deletes a finished task
+func (s *Server) handleArchiveTask(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, newError(CodeUnavailable, "task store not enabled"))
+		return
+	}
+	if err := s.scheduler.Archive(r.Context(), s.store, r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true})
+}
+
+// TaskDetail is a task's current state, operator notes and the retained
+// events concerning it
+type TaskDetail struct {
+	Task     scheduler.TaskSnapshot `json:"task"`
+	Notes    []store.Note           `json:"notes"`
+	Timeline []events.Event         `json:"timeline"`
+
+	// Subtasks is the task's progress through the subtasks its agent
+	// submitted; nil when it has none
+	Subtasks *scheduler.SubtasksStatus `json:"subtasks,omitempty"`
+}
+
+func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	task, ok := s.scheduler.Task(id)
+	if !ok {
+		writeError(w, newError(CodeNotFound, "task not found: %s", id))
+		return
+	}
+
+	detail := TaskDetail{Task: task, Notes: []store.Note{}, Timeline: []events.Event{}}
+	if subtasks, err := s.scheduler.Subtasks(id); err == nil {
+		detail.Subtasks = &subtasks
+	}
+	if s.store != nil {
+		notes, err := s.store.Notes(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		detail.Notes = notes
+	}
+	if s.events != nil {
+		for _, e := range s.events.Since(0) {
+			if e.TaskID == id {
+				detail.Timeline = append(detail.Timeline, e)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: detail})
+}
+
+// NoteRequest is the body accepted by the task notes endpoint
+type NoteRequest struct {
+	Author string `json:"author"`
+	Text   string `json:"text"`
+}
+
+func (s *Server) handleAddNote(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, newError(CodeUnavailable, "task store not enabled"))
+		return
+	}
+
+	var req NoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+	if req.Author == "" || req.Text == "" {
+		writeError(w, newError(CodeValidation, "note requires author and text"))
+		return
+	}
+
+	id := r.PathValue("id")
+	if _, ok := s.scheduler.Task(id); !ok {
+		writeError(w, newError(CodeNotFound, "task not found: %s", id))
+		return
+	}
+
+	note, err := s.store.AddNote(r.Context(), id, req.Author, req.Text)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s.events.Publish(events.Event{
+		Type:    events.TaskNoted,
+		TaskID:  id,
+		Message: note.Text,
+		Data:    map[string]interface{}{"author": note.Author},
+		Time:    note.CreatedAt,
+	})
+	writeJSON(w, http.StatusCreated, Response{Success: true, Data: note})
+}
+
+// handleAckTask lets an agent acknowledge a task dispatched to it, before
+// the ack timeout re-routes it. The agent authenticates as for results.
+func (s *Server) handleAckTask(w http.ResponseWriter, r *http.Request) {
+	agent, err := s.authenticateAgent(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.scheduler.Acknowledge(agent, id); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: map[string]string{"id": id, "status": "acknowledged"}})
+}
+
+// SuspendRequest is the body accepted when an agent suspends a task
+type SuspendRequest struct {
+	Reason  string `json:"reason,omitempty"`
+	Timeout int    `json:"timeout,omitempty"` // seconds to wait for approval (0 = configured default)
+}
+
+// handleSuspendTask lets the agent running a task park it until an
+// approval arrives. The agent authenticates as for results.
+func (s *Server) handleSuspendTask(w http.ResponseWriter, r *http.Request) {
+	agent, err := s.authenticateAgent(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req SuspendRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+			return
+		}
+	}
+	if req.Timeout < 0 {
+		writeError(w, newError(CodeValidation, "timeout must not be negative"))
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.scheduler.Suspend(agent, id, req.Reason, time.Duration(req.Timeout)*time.Second); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: map[string]string{"id": id, "status": "suspended"}})
+}
+
+// handleApproveTask approves or rejects a suspended task
+func (s *Server) handleApproveTask(w http.ResponseWriter, r *http.Request) {
+	var approval scheduler.Approval
+	if err := json.NewDecoder(r.Body).Decode(&approval); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.scheduler.ResumeTask(id, approval); err != nil {
+		writeError(w, err)
+		return
+	}
+	status := "rejected"
+	if approval.Approved {
+		status = "resumed"
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: map[string]string{"id": id, "status": status}})
+}
+
+// handleReportResult lets the agent running a task report how it ended.
+// The agent authenticates with its bearer token and must be one the task
+// was routed to.
+func (s *Server) handleReportResult(w http.ResponseWriter, r *http.Request) {
+	agent, err := s.authenticateAgent(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var result scheduler.Result
+	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+	result.TaskID = r.PathValue("id")
+	if result.Instance == "" {
+		result.Instance = s.router.Instance(agent)
+	}
+
+	if err := s.scheduler.ReportResult(agent, result); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: map[string]string{"id": result.TaskID, "status": result.Status}})
+}
+
+// handleTaskLogs returns the buffered tail of a task's agent output
+func (s *Server) handleTaskLogs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.scheduler.Logs(r.PathValue("id"))})
+}
+
+// handleTaskTrace returns the debug spans recorded for a traced task
+func (s *Server) handleTaskTrace(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.scheduler.Trace(r.PathValue("id"))})
+}
+
+// handleEffectiveConfig returns the fully resolved settings a task was
+// last dispatched under
+func (s *Server) handleEffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	ec, ok := s.scheduler.EffectiveConfig(id)
+	if !ok {
+		writeError(w, newError(CodeNotFound, "no effective config recorded for task %s", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: ec})
+}
+
+// handleTailTaskLogs streams a task's agent output as Server-Sent Events,
+// starting with the buffered tail
+func (s *Server) handleTailTaskLogs(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, newError(CodeInternal, "streaming not supported"))
+		return
+	}
+
+	tail, live, stop := s.scheduler.TailLogs(r.PathValue("id"), 256)
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(line scheduler.LogLine) {
+		data, _ := json.Marshal(line)
+		fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", line.Seq, data)
+	}
+
+	for _, line := range tail {
+		send(line)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line, ok := <-live:
+			if !ok {
+				return
+			}
+			send(line)
+			flusher.Flush()
+		}
+	}
+}
+
+// handleEvents streams events as Server-Sent Events. Retained history
+// after ?after=<id> is replayed before live events.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		writeError(w, newError(CodeUnavailable, "event stream not enabled"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, newError(CodeInternal, "streaming not supported"))
+		return
+	}
+
+	var after uint64
+	if v := r.URL.Query().Get("after"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeError(w, newError(CodeValidation, "invalid after: %s", v))
+			return
+		}
+		after = parsed
+	}
+
+	// Subscribe before replaying so nothing published in between is lost
+	live, unsubscribe := s.events.Subscribe(64)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	last := after
+	send := func(e events.Event) {
+		if e.ID <= last {
+			return
+		}
+		last = e.ID
+		data, _ := json.Marshal(e)
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	}
+
+	if r.URL.Query().Has("after") {
+		for _, e := range s.events.Since(after) {
+			send(e)
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-live:
+			if !ok {
+				return
+			}
+			send(e)
+			flusher.Flush()
+		}
+	}
+}
+
+// handleSummary rolls tasks up into time buckets. Query parameters:
+// bucket (default 1h, or e.g. 15m, 1d), since and until (RFC 3339 or a
+// duration ago such as 24h; default the last day up to now) and tz (an
+// IANA zone the buckets align to; default UTC).
+func (s *Server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, newError(CodeUnavailable, "task store not enabled"))
+		return
+	}
+
+	params := r.URL.Query()
+	now := time.Now()
+	q := summary.Query{Bucket: time.Hour, Since: now.Add(-24 * time.Hour), Until: now, Location: time.UTC}
+	if v := params.Get("bucket"); v != "" {
+		bucket, err := summary.ParseBucket(v)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		q.Bucket = bucket
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		v := params.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := parseTimeParam(v, now)
+		if err != nil {
+			writeError(w, newError(CodeValidation, "invalid %s: %v", p.name, err))
+			return
+		}
+		*p.dst = t
+	}
+	if v := params.Get("tz"); v != "" {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			writeError(w, newError(CodeValidation, "unknown time zone %q", v))
+			return
+		}
+		q.Location = loc
+	}
+
+	sum, err := summary.Summarize(r.Context(), s.store, q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: sum})
+}
+
+// parseTimeParam reads an RFC 3339 time, or a duration meaning that long
+// before now
+func parseTimeParam(v string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func (s *Server) handleSchedulerStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.scheduler.GetStatus()})
+}
+
+// handleSchedulerDump returns a consistent snapshot of the scheduler's
+// internals for debugging, with task inputs redacted
+func (s *Server) handleSchedulerDump(w http.ResponseWriter, r *http.Request) {
+	dump := s.scheduler.Dump()
+	sections := append([][]scheduler.DumpTask{dump.Queued, dump.Running, dump.Waiting, dump.Suspended}, dump.Reserved...)
+	for _, tasks := range sections {
+		for i := range tasks {
+			tasks[i].Input = s.redactInput(tasks[i].Input)
+		}
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: dump})
+}
+
+// handleSchedulerDrain stops dispatch and returns the scheduler's state
+// for restoring on another instance. Inputs are kept, since the tasks
+// cannot run again without them.
+func (s *Server) handleSchedulerDrain(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.scheduler.Drain()})
+}
+
+func (s *Server) handleSchedulerResume(w http.ResponseWriter, r *http.Request) {
+	s.scheduler.Resume()
+	writeJSON(w, http.StatusOK, Response{Success: true})
+}
+
+// handleSchedulerRestore loads a drained scheduler's state into this
+// instance, which must not hold any tasks yet
+func (s *Server) handleSchedulerRestore(w http.ResponseWriter, r *http.Request) {
+	var state scheduler.QueueState
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+	if err := s.scheduler.Restore(state); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: s.scheduler.GetStatus()})
+}
+
+// handleMetrics serves metrics in the Prometheus text format rather than
+// the JSON envelope, so scrapers can read it directly
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if s.metrics == nil {
+		return
+	}
+	if err := s.metrics(w); err != nil {
+		s.logger.Warn("Failed to write metrics", zap.Error(err))
+	}
+}
+
+// redactInput masks sensitive fields of a task payload, or drops the
+// payload when no redactor is set
+func (s *Server) redactInput(input map[string]interface{}) map[string]interface{} {
+	if s.redactor == nil || input == nil {
+		return nil
+	}
+	masked, _ := s.redactor.Value(input).(map[string]interface{})
+	return masked
+}
+
+// writeJSON encodes a response with the given status code
+func writeJSON(w http.ResponseWriter, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeError writes the error envelope with the status for its code
+func writeError(w http.ResponseWriter, err error) {
+	apiErr := toError(err)
+	writeJSON(w, apiErr.Code.Status(), Response{Success: false, Error: apiErr})
+}
+
+// taskError reports a rejected batch entry as a validation error
+func taskError(index int, err error) *Error {
+	apiErr := toError(err)
+	if apiErr.Code == CodeInternal {
+		apiErr.Code = CodeValidation
+	}
+	apiErr.Message = fmt.Sprintf("task %d: %s", index, apiErr.Message)
+	apiErr.Details = map[string]interface{}{"index": index}
+	return apiErr
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/redact"
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Orchestrator.MaxConcurrentTasks = 10
+	cfg.Orchestrator.TickInterval = 10
+	return cfg
+}
+
+// newTestServer creates a server over a router with the explain, review
+// and security agents ready, and a scheduler that is not started
+func newTestServer(t *testing.T, cfg *config.Config) (*Server, *router.Router, *scheduler.Scheduler) {
+	t.Helper()
+	r := router.New(cfg, zap.NewNop())
+	for _, name := range []string{"explain", "review", "security"} {
+		r.RegisterAgent(&router.AgentInfo{ID: name + "-1", Name: name, Status: router.AgentStatusReady})
+	}
+	s := scheduler.New(cfg, zap.NewNop())
+	return New(cfg, zap.NewNop(), r, s), r, s
+}
+
+// do sends a request through the server's handler. A non-nil body is
+// sent as JSON.
+func do(t *testing.T, srv *Server, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encoding request: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+// decode reads the response envelope, decoding its data into data when
+// it is not nil
+func decode(t *testing.T, rec *httptest.ResponseRecorder, data interface{}) Response {
+	t.Helper()
+	var raw struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   *Error          `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+	if data != nil && len(raw.Data) > 0 {
+		if err := json.Unmarshal(raw.Data, data); err != nil {
+			t.Fatalf("decoding data %s: %v", raw.Data, err)
+		}
+	}
+	return Response{Success: raw.Success, Data: data, Error: raw.Error}
+}
+
+func TestSubmitOverQueueBoundReportsAccepted(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxQueuedTasks = 1
+	srv, _, _ := newTestServer(t, cfg)
+
+	rec := do(t, srv, http.MethodPost, "/api/v1/tasks", SubmitRequest{Tasks: []*router.Task{
+		{ID: "first", Type: router.TaskQuestion, Description: "one"},
+		{ID: "second", Type: router.TaskQuestion, Description: "two"},
+	}})
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	resp := decode(t, rec, nil)
+	if resp.Success || resp.Error == nil || resp.Error.Code != CodeQueueFull {
+		t.Fatalf("envelope = %s, want a queue_full error", rec.Body)
+	}
+	accepted, _ := resp.Error.Details["accepted"].([]interface{})
+	if len(accepted) != 1 || accepted[0] != "first" {
+		t.Errorf("details = %v, want the first task accepted", resp.Error.Details)
+	}
+}
+
+func TestBadRequestsGetValidationErrors(t *testing.T) {
+	srv, _, _ := newTestServer(t, testConfig())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", bytes.NewBufferString("{not json"))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if resp := decode(t, rec, nil); rec.Code != http.StatusBadRequest || resp.Error.Code != CodeValidation {
+		t.Errorf("malformed body: %d %s, want 400 validation", rec.Code, rec.Body)
+	}
+
+	rec = do(t, srv, http.MethodGet, "/api/v1/tasks/nonesuch", nil)
+	if resp := decode(t, rec, nil); rec.Code != http.StatusNotFound || resp.Error.Code != CodeNotFound {
+		t.Errorf("unknown task: %d %s, want 404 not_found", rec.Code, rec.Body)
+	}
+}
+
+func TestExplainTaskDoesNotSubmit(t *testing.T) {
+	srv, _, sched := newTestServer(t, testConfig())
+
+	rec := do(t, srv, http.MethodPost, "/api/v1/tasks/explain", router.Task{Type: router.TaskCodeReview})
+	var exp router.RoutingExplanation
+	if resp := decode(t, rec, &exp); !resp.Success {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if exp.Policy != router.PolicyRouteTable || len(exp.Candidates) != 3 {
+		t.Errorf("explanation = %+v, want the code_review route", exp)
+	}
+	if tasks := sched.List(); len(tasks) != 0 {
+		t.Errorf("explaining scheduled %d tasks", len(tasks))
+	}
+
+	rec = do(t, srv, http.MethodPost, "/api/v1/tasks/explain", router.Task{})
+	if resp := decode(t, rec, nil); resp.Error == nil || resp.Error.Code != CodeValidation {
+		t.Errorf("task without a type: status %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestSchedulerDumpRedactsInputs(t *testing.T) {
+	srv, _, sched := newTestServer(t, testConfig())
+	redactor, err := redact.New(config.RedactConfig{Keys: []string{"password"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetRedactor(redactor)
+
+	secret := map[string]interface{}{"user": "ada", "password": "hunter2"}
+	if err := sched.Schedule(&scheduler.ScheduledTask{ID: "queued", Type: "question", Input: secret}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sched.Reserve([]*scheduler.ScheduledTask{{ID: "reserved", Type: "question", Input: secret}}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := do(t, srv, http.MethodGet, "/api/v1/scheduler/dump", nil)
+	var dump scheduler.Dump
+	if resp := decode(t, rec, &dump); !resp.Success {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if len(dump.Queued) != 1 || len(dump.Reserved) != 1 {
+		t.Fatalf("dump = %+v, want one queued and one reserved task", dump)
+	}
+	for _, task := range []scheduler.DumpTask{dump.Queued[0], dump.Reserved[0][0]} {
+		if task.Input["user"] != "ada" || task.Input["password"] != redact.Mask {
+			t.Errorf("%s input = %v, want the password masked", task.ID, task.Input)
+		}
+	}
+	if secret["password"] != "hunter2" {
+		t.Error("redacting the dump changed the task's own input")
+	}
+}
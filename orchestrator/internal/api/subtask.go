@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+// SubtaskRequest is the body accepted when an agent submits a subtask of
+// the task it is running
+type SubtaskRequest struct {
+	Task *router.Task `json:"task"`
+
+	// Wait holds the parent's completion until the subtask finishes, and
+	// fails the parent if the subtask fails
+	Wait bool `json:"wait,omitempty"`
+}
+
+// SubtasksResponse is a parent's subtasks as the scheduler tracks them,
+// and as recorded in the store once they finished a run
+type SubtasksResponse struct {
+	Live     *scheduler.SubtasksStatus `json:"live,omitempty"`
+	Recorded []store.TaskSummary       `json:"recorded"`
+}
+
+// handleSubmitSubtask lets the agent running a task submit a subtask of
+// it, routed and validated like any submitted task. The agent
+// authenticates as for results.
+func (s *Server) handleSubmitSubtask(w http.ResponseWriter, r *http.Request) {
+	agent, err := s.authenticateAgent(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req SubtaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, newError(CodeValidation, "invalid request body: %v", err))
+		return
+	}
+	if req.Task == nil || req.Task.Type == "" {
+		writeError(w, newError(CodeValidation, "task type is required"))
+		return
+	}
+	task := req.Task
+	if task.Compensate != nil {
+		writeError(w, newError(CodeValidation, "a subtask cannot have compensation"))
+		return
+	}
+	agents, err := s.prepareTask(r, task)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	parentID := r.PathValue("id")
+	scheduled := s.scheduledTask(task, agents)
+	if err := s.submitTask(task, scheduled); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.scheduler.SubmitSubtask(agent, parentID, scheduled, req.Wait); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, Response{Success: true, Data: map[string]string{"id": task.ID, "parent_id": parentID}})
+}
+
+// handleListSubtasks reports a parent's progress through its subtasks
+func (s *Server) handleListSubtasks(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	resp := SubtasksResponse{Recorded: []store.TaskSummary{}}
+
+	live, err := s.scheduler.Subtasks(id)
+	switch {
+	case err == nil:
+		resp.Live = &live
+	case !errors.Is(err, scheduler.ErrNoSubtasks):
+		writeError(w, err)
+		return
+	}
+	if s.store != nil {
+		recorded, err := s.store.Children(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		resp.Recorded = append(resp.Recorded, recorded...)
+	}
+	if resp.Live == nil && len(resp.Recorded) == 0 {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: resp})
+}
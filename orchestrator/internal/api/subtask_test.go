@@ -0,0 +1,88 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+)
+
+// submitSubtask posts a subtask of parentID as the agent holding token
+func submitSubtask(t *testing.T, srv *Server, token, parentID string, req SubtaskRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(req); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/"+parentID+"/subtasks", &body)
+	r.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, r)
+	return rec
+}
+
+func TestAgentSubmitsSubtask(t *testing.T) {
+	srv, sched := newResultServer(t)
+	startRunning(t, sched, "t-1")
+
+	var created map[string]string
+	rec := submitSubtask(t, srv, "review-secret", "t-1", SubtaskRequest{
+		Task: &router.Task{Type: router.TaskQuestion, Input: map[string]interface{}{"question": "why?"}},
+		Wait: true,
+	})
+	if resp := decode(t, rec, &created); !resp.Success || rec.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if created["parent_id"] != "t-1" || created["id"] == "" {
+		t.Fatalf("created = %v, want a subtask of t-1", created)
+	}
+	if task, _ := sched.Task(created["id"]); task.Parent != "t-1" {
+		t.Errorf("subtask parent = %q, want t-1", task.Parent)
+	}
+
+	var progress SubtasksResponse
+	rec = do(t, srv, http.MethodGet, "/api/v1/tasks/t-1/subtasks", nil)
+	if resp := decode(t, rec, &progress); !resp.Success {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if progress.Live == nil || progress.Live.Total != 1 || progress.Live.ParentState != scheduler.TaskRunning {
+		t.Errorf("progress = %+v, want one subtask of a running parent", progress.Live)
+	}
+}
+
+func TestSubtaskRejections(t *testing.T) {
+	srv, sched := newResultServer(t)
+	startRunning(t, sched, "t-1")
+	question := SubtaskRequest{Task: &router.Task{Type: router.TaskQuestion}}
+
+	tests := []struct {
+		name   string
+		token  string
+		parent string
+		req    SubtaskRequest
+		code   ErrorCode
+	}{
+		{"bad token", "guess", "t-1", question, CodeUnauthorized},
+		{"agent does not own parent", "security-secret", "t-1", question, CodeForbidden},
+		{"unknown parent", "review-secret", "nope", question, CodeNotFound},
+		{"missing type", "review-secret", "t-1", SubtaskRequest{Task: &router.Task{}}, CodeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := submitSubtask(t, srv, tt.token, tt.parent, tt.req)
+			resp := decode(t, rec, nil)
+			if resp.Error == nil || resp.Error.Code != tt.code || rec.Code != tt.code.Status() {
+				t.Errorf("status %d %s, want %s", rec.Code, rec.Body, tt.code)
+			}
+		})
+	}
+
+	rec := do(t, srv, http.MethodGet, "/api/v1/tasks/t-1/subtasks", nil)
+	if resp := decode(t, rec, nil); resp.Error == nil || resp.Error.Code != CodeNotFound {
+		t.Errorf("parent without subtasks: status %d %s, want not found", rec.Code, rec.Body)
+	}
+}
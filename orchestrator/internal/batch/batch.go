@@ -0,0 +1,271 @@
+// =============================================================================
+// ODIN v7.0 - Batch Task Definitions
+// =============================================================================
+// Parses task batches from JSON/YAML and resolves intra-batch dependencies
+// =============================================================================
+
+package batch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/schema"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// TaskSpec is a single task definition as written in a batch file
+type TaskSpec struct {
+	// Ref is a file-local identifier other specs can depend on
+	Ref          string                 `yaml:"ref"`
+	Name         string                 `yaml:"name"`
+	Labels       map[string]string      `yaml:"labels"`
+	Type         string                 `yaml:"type"`
+	Description  string                 `yaml:"description"`
+	Input        map[string]interface{} `yaml:"input"`
+	Context      map[string]interface{} `yaml:"context"`
+	Dependencies []string               `yaml:"dependencies"`
+	Priority     int                    `yaml:"priority"`
+	Provider     string                 `yaml:"provider"`
+	Model        string                 `yaml:"model"`
+	MaxTokens    int                    `yaml:"max_tokens"`
+	MaxCost      float64                `yaml:"max_cost"`
+	Trace        bool                   `yaml:"trace"`
+	Sandbox      *router.SandboxPolicy  `yaml:"sandbox"`
+	Capabilities map[string]float64     `yaml:"capabilities"`
+	MaxQueueTime time.Duration          `yaml:"max_queue_time"` // e.g. 10m
+	Timeout      time.Duration          `yaml:"timeout"`        // how long a run may take
+	Retry        *scheduler.RetryPolicy `yaml:"retry"`
+	ResultSchema *schema.Schema         `yaml:"result_schema"` // JSON Schema the result must match
+
+	// Params override the configured generation parameters
+	Params *config.GenerationParams `yaml:"params"`
+
+	// PinnedInstance is the only orchestrator instance that may run it
+	PinnedInstance string `yaml:"pinned_instance"`
+
+	// NoPrerequisites skips the prerequisite tasks configured for its type
+	NoPrerequisites bool `yaml:"no_prerequisites"`
+
+	// Compensate undoes the task if it completed but its all-or-nothing
+	// batch failed. It takes no ref or dependencies.
+	Compensate *TaskSpec `yaml:"compensate"`
+}
+
+// File is the document form of a batch, either a bare list or {tasks: [...]}
+type File struct {
+	Tasks []TaskSpec `yaml:"tasks"`
+}
+
+// Parse reads a batch of task definitions and converts them into tasks with
+// generated IDs. Dependencies naming a ref in the same batch are rewritten to
+// the generated ID; anything else is kept as an existing task ID.
+func Parse(r io.Reader) ([]*router.Task, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch: %w", err)
+	}
+
+	specs, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("batch contains no tasks")
+	}
+
+	return Resolve(specs)
+}
+
+// decode accepts either a top-level list or a document with a tasks key.
+// YAML is a superset of JSON, so both formats go through the same decoder.
+func decode(data []byte) ([]TaskSpec, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '-') {
+		var specs []TaskSpec
+		if err := yaml.Unmarshal(data, &specs); err != nil {
+			return nil, fmt.Errorf("failed to parse batch: %w", err)
+		}
+		return specs, nil
+	}
+
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse batch: %w", err)
+	}
+	return file.Tasks, nil
+}
+
+// Resolve validates specs and maps local refs to generated task IDs
+func Resolve(specs []TaskSpec) ([]*router.Task, error) {
+	ids := make(map[string]string, len(specs))
+	for i, spec := range specs {
+		if spec.Ref == "" {
+			continue
+		}
+		if _, dup := ids[spec.Ref]; dup {
+			return nil, fmt.Errorf("task %d: duplicate ref %q", i, spec.Ref)
+		}
+		ids[spec.Ref] = router.NewTaskID()
+	}
+
+	now := time.Now()
+	tasks := make([]*router.Task, len(specs))
+	for i, spec := range specs {
+		if err := validate(spec); err != nil {
+			return nil, fmt.Errorf("task %d: %w", i, err)
+		}
+
+		id := ids[spec.Ref]
+		if id == "" {
+			id = router.NewTaskID()
+		}
+
+		deps := make([]string, 0, len(spec.Dependencies))
+		for _, dep := range spec.Dependencies {
+			if dep == spec.Ref && dep != "" {
+				return nil, fmt.Errorf("task %d: depends on itself", i)
+			}
+			if resolved, ok := ids[dep]; ok {
+				dep = resolved
+			}
+			deps = append(deps, dep)
+		}
+
+		tasks[i] = build(spec, id, deps, now)
+
+		if comp := spec.Compensate; comp != nil {
+			if comp.Ref != "" || len(comp.Dependencies) > 0 || comp.Compensate != nil {
+				return nil, fmt.Errorf("task %d: compensate cannot have a ref, dependencies or its own compensate", i)
+			}
+			if err := validate(*comp); err != nil {
+				return nil, fmt.Errorf("task %d: compensate: %w", i, err)
+			}
+			tasks[i].Compensate = build(*comp, router.NewTaskID(), nil, now)
+		}
+	}
+
+	if i, ok := findCycle(specs); ok {
+		return nil, fmt.Errorf("task %d: dependency cycle", i)
+	}
+
+	return tasks, nil
+}
+
+// build converts a validated spec into a task
+func build(spec TaskSpec, id string, deps []string, now time.Time) *router.Task {
+	return &router.Task{
+		ID:           id,
+		Name:         spec.Name,
+		Labels:       spec.Labels,
+		Type:         router.TaskType(spec.Type),
+		Description:  spec.Description,
+		Input:        spec.Input,
+		Context:      spec.Context,
+		Priority:     spec.Priority,
+		CreatedAt:    now,
+		Dependencies: deps,
+		Provider:     spec.Provider,
+		Model:        spec.Model,
+		MaxTokens:    spec.MaxTokens,
+		MaxCost:      spec.MaxCost,
+		Trace:        spec.Trace,
+		Sandbox:      spec.Sandbox,
+		Capabilities: spec.Capabilities,
+		MaxQueueTime: spec.MaxQueueTime,
+		Timeout:      spec.Timeout,
+		Retry:        spec.Retry,
+		ResultSchema: spec.ResultSchema,
+		Params:       spec.Params,
+
+		PinnedInstance:  spec.PinnedInstance,
+		NoPrerequisites: spec.NoPrerequisites,
+	}
+}
+
+// validate checks a single spec independently of the rest of the batch
+func validate(spec TaskSpec) error {
+	if spec.Type == "" {
+		return fmt.Errorf("missing type")
+	}
+	if !router.TaskType(spec.Type).Valid() {
+		return fmt.Errorf("unknown type %q", spec.Type)
+	}
+	if spec.Description == "" {
+		return fmt.Errorf("missing description")
+	}
+	if spec.Priority < 0 || spec.Priority > 3 {
+		return fmt.Errorf("priority %d out of range 0-3", spec.Priority)
+	}
+	if err := llm.ValidateOverride(spec.Provider, spec.Model); err != nil {
+		return err
+	}
+	if err := llm.ValidateParams(spec.Params); err != nil {
+		return err
+	}
+	if err := llm.ValidateBudget(spec.MaxTokens, spec.MaxCost); err != nil {
+		return err
+	}
+	if err := router.ValidateCapabilities(spec.Capabilities); err != nil {
+		return err
+	}
+	if spec.MaxQueueTime < 0 {
+		return fmt.Errorf("max_queue_time must not be negative")
+	}
+	if spec.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if spec.Sandbox != nil {
+		return spec.Sandbox.Validate()
+	}
+	return nil
+}
+
+// findCycle returns the index of a spec taking part in a ref cycle
+func findCycle(specs []TaskSpec) (int, bool) {
+	index := make(map[string]int, len(specs))
+	for i, spec := range specs {
+		if spec.Ref != "" {
+			index[spec.Ref] = i
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(specs))
+
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		state[i] = visiting
+		for _, dep := range specs[i].Dependencies {
+			j, ok := index[dep]
+			if !ok {
+				continue
+			}
+			if state[j] == visiting {
+				return true
+			}
+			if state[j] == unvisited && visit(j) {
+				return true
+			}
+		}
+		state[i] = done
+		return false
+	}
+
+	for i := range specs {
+		if state[i] == unvisited && visit(i) {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,83 @@
+package batch
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseResolvesIntraFileDependencies(t *testing.T) {
+	const file = `
+tasks:
+  - ref: spec
+    type: analysis
+    description: Write the spec
+  - ref: impl
+    type: code_write
+    description: Implement it
+    dependencies: [spec]
+  - type: code_review
+    description: Review it
+    dependencies: [impl, spec, existing-task]
+`
+	tasks, err := Parse(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("got %d tasks, want 3", len(tasks))
+	}
+	spec, impl, review := tasks[0], tasks[1], tasks[2]
+	if spec.ID == "" || impl.ID == "" || review.ID == "" || spec.ID == impl.ID {
+		t.Fatalf("tasks need distinct generated IDs, got %q %q %q", spec.ID, impl.ID, review.ID)
+	}
+	if want := []string{spec.ID}; !slices.Equal(impl.Dependencies, want) {
+		t.Errorf("impl dependencies = %v, want %v", impl.Dependencies, want)
+	}
+	// A dependency that is not a ref in the file is an existing task ID
+	if want := []string{impl.ID, spec.ID, "existing-task"}; !slices.Equal(review.Dependencies, want) {
+		t.Errorf("review dependencies = %v, want %v", review.Dependencies, want)
+	}
+}
+
+func TestParseAcceptsJSONList(t *testing.T) {
+	const file = `[
+  {"ref": "a", "type": "question", "description": "first"},
+  {"type": "question", "description": "second", "dependencies": ["a"]}
+]`
+	tasks, err := Parse(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(tasks) != 2 || !slices.Equal(tasks[1].Dependencies, []string{tasks[0].ID}) {
+		t.Errorf("JSON batch not resolved: %+v", tasks)
+	}
+}
+
+func TestParseRejectsBadBatches(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"empty", `tasks: []`, "no tasks"},
+		{"duplicate ref", `
+- {ref: a, type: question, description: x}
+- {ref: a, type: question, description: y}`, "duplicate ref"},
+		{"self dependency", `
+- {ref: a, type: question, description: x, dependencies: [a]}`, "depends on itself"},
+		{"cycle", `
+- {ref: a, type: question, description: x, dependencies: [b]}
+- {ref: b, type: question, description: y, dependencies: [a]}`, "cycle"},
+		{"unknown type", `
+- {type: poetry, description: x}`, "unknown type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.file))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
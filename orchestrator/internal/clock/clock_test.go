@@ -0,0 +1,64 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fired reports whether a timer channel has fired
+func fired(ch <-chan time.Time) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestManualOnlyMovesWhenAdvanced(t *testing.T) {
+	c := NewManual(epoch)
+	if !c.Now().Equal(epoch) {
+		t.Fatalf("Now = %s, want %s", c.Now(), epoch)
+	}
+	time.Sleep(time.Millisecond)
+	if !c.Now().Equal(epoch) {
+		t.Errorf("clock moved on its own to %s", c.Now())
+	}
+	c.Advance(90 * time.Second)
+	if want := epoch.Add(90 * time.Second); !c.Now().Equal(want) {
+		t.Errorf("Now = %s, want %s", c.Now(), want)
+	}
+}
+
+func TestManualTimersFireWhenDue(t *testing.T) {
+	c := NewManual(epoch)
+	short := c.After(10 * time.Second)
+	long := c.After(time.Minute)
+
+	c.Advance(9 * time.Second)
+	if fired(short) || fired(long) {
+		t.Fatal("timer fired early")
+	}
+
+	c.Advance(time.Second)
+	if !fired(short) {
+		t.Error("10s timer did not fire at 10s")
+	}
+	if fired(long) {
+		t.Error("1m timer fired at 10s")
+	}
+
+	c.Advance(time.Hour)
+	if !fired(long) {
+		t.Error("1m timer did not fire once overdue")
+	}
+}
+
+func TestManualZeroTimerFiresImmediately(t *testing.T) {
+	c := NewManual(epoch)
+	if !fired(c.After(0)) {
+		t.Error("zero timer did not fire without advancing")
+	}
+}
@@ -0,0 +1,323 @@
+// =============================================================================
+// ODIN v7.0 - Consensus Verification
+// =============================================================================
+// Asks several providers the same question and accepts an answer once
+// enough of them agree
+// =============================================================================
+
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Consensus errors
+var (
+	ErrNoProviders      = errors.New("no consensus providers configured")
+	ErrTooFewResponders = errors.New("too few consensus providers responded")
+)
+
+// Completer runs a single completion; *llm.Client satisfies it
+type Completer interface {
+	Complete(ctx context.Context, req *llm.Request) (*llm.Response, error)
+}
+
+// Vote is one provider's contribution
+type Vote struct {
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	Content   string `json:"content,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Cancelled bool   `json:"cancelled,omitempty"`
+}
+
+// Result is the outcome of a consensus round
+type Result struct {
+	Agreed   bool    `json:"agreed"`
+	Answer   string  `json:"answer,omitempty"`
+	Support  int     `json:"support"`  // votes for the answer
+	Required int     `json:"required"` // votes needed to agree
+	Total    int     `json:"total"`
+	Votes    []Vote  `json:"votes"`
+	Ratio    float64 `json:"ratio"`
+
+	// TimedOut marks a round decided at the timeout from the providers
+	// that had answered; Required and Ratio then count only those
+	TimedOut  bool `json:"timed_out,omitempty"`
+	Responded int  `json:"responded"`
+
+	// Fallback names the degradation applied because too few providers
+	// were usable; LowConfidence marks an answer no quorum agreed on
+	Fallback      string        `json:"fallback,omitempty"`
+	LowConfidence bool          `json:"low_confidence,omitempty"`
+	Waited        time.Duration `json:"waited,omitempty"` // queued for providers to recover
+}
+
+// Dissenters returns the votes that did not back the answer: those whose
+// content differs from it under normalize, and those that failed or were
+// cancelled. A failed round's answer is the best supported one.
+func (r *Result) Dissenters(normalize Normalizer) []Vote {
+	answer := normalize(r.Answer)
+	var out []Vote
+	for _, vote := range r.Votes {
+		if vote.Error != "" || vote.Cancelled || normalize(vote.Content) != answer {
+			out = append(out, vote)
+		}
+	}
+	return out
+}
+
+// Verifier runs consensus rounds against the configured providers
+type Verifier struct {
+	config    config.ConsensusConfig
+	logger    *zap.Logger
+	completer Completer
+
+	// Normalize maps responses to a comparable form; two responses agree
+	// when their normalized forms are equal
+	Normalize Normalizer
+
+	// EarlyExit cancels outstanding calls once the outcome is decided;
+	// off waits for every provider
+	EarlyExit bool
+
+	fallback string
+
+	// Agreement observed per task type, for metrics
+	mu    sync.Mutex
+	stats map[string]*agreementStats
+}
+
+// New creates a new Verifier instance
+func New(cfg config.ConsensusConfig, completer Completer, logger *zap.Logger) *Verifier {
+	names := cfg.Normalize
+	if len(names) == 0 {
+		names = DefaultNormalizers
+	}
+	normalize, err := NewNormalizer(names)
+	if err != nil {
+		logger.Warn("Invalid consensus normalization, using defaults", zap.Error(err))
+		normalize, _ = NewNormalizer(DefaultNormalizers)
+	}
+
+	return &Verifier{
+		config:    cfg,
+		logger:    logger,
+		completer: completer,
+		Normalize: normalize,
+		fallback:  fallbackMode(cfg.Fallback, logger),
+		stats:     make(map[string]*agreementStats),
+	}
+}
+
+// required returns how many of n votes are needed to meet minAgreement
+func required(n int, minAgreement float64) int {
+	if minAgreement <= 0 {
+		return 1
+	}
+	need := int(math.Ceil(minAgreement*float64(n) - 1e-9))
+	if need < 1 {
+		need = 1
+	}
+	if need > n {
+		need = n
+	}
+	return need
+}
+
+type reply struct {
+	index int
+	resp  *llm.Response
+	err   error
+}
+
+// Verify sends req to every consensus provider concurrently. With
+// EarlyExit, as soon as one answer has enough votes, or no answer can
+// still reach the threshold, the remaining calls are cancelled. With a
+// timeout, calls still outstanding when it fires are cancelled and their
+// late answers discarded; the round fails with ErrTooFewResponders unless
+// MinResponders providers answered by then.
+//
+// When fewer providers are healthy than agreement needs, the configured
+// fallback applies: fail returns ErrInsufficientProviders, fallback_to_primary
+// answers from the primary provider alone, and queue_until_available waits
+// for enough providers to recover before running the round.
+// fallback_to_primary also applies when provider errors leave too few votes.
+func (v *Verifier) Verify(ctx context.Context, req *llm.Request) (*Result, error) {
+	providers := v.config.Providers
+	if len(providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	need := required(len(providers), v.config.MinAgreement)
+	var waited time.Duration
+	if healthy := v.healthy(providers); healthy < need {
+		switch v.fallback {
+		case FallbackPrimary:
+			return v.fallbackToPrimary(ctx, req, len(providers), need)
+		case FallbackQueue:
+			v.logger.Warn("Consensus waiting for providers",
+				zap.Int("healthy", healthy),
+				zap.Int("required", need),
+			)
+			start := time.Now()
+			if err := v.waitForProviders(ctx, providers, need); err != nil {
+				return nil, err
+			}
+			waited = time.Since(start)
+		default:
+			return nil, fmt.Errorf("%w: %d of %d healthy, %d needed",
+				ErrInsufficientProviders, healthy, len(providers), need)
+		}
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &Result{
+		Required: need,
+		Total:    len(providers),
+		Votes:    make([]Vote, len(providers)),
+	}
+	if v.fallback == FallbackQueue && waited > 0 {
+		result.Fallback = FallbackQueue
+		result.Waited = waited
+	}
+
+	replies := make(chan reply, len(providers))
+	for i, pc := range providers {
+		result.Votes[i] = Vote{Provider: pc.Provider, Model: pc.Model, Cancelled: true}
+
+		call := *req
+		call.Provider = pc.Provider
+		call.Model = pc.Model
+		go func(i int, call llm.Request) {
+			resp, err := v.completer.Complete(ctx, &call)
+			replies <- reply{index: i, resp: resp, err: err}
+		}(i, call)
+	}
+
+	var deadline <-chan time.Time
+	if v.config.Timeout > 0 {
+		timer := time.NewTimer(time.Duration(v.config.Timeout) * time.Second)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	counts := make(map[string]int)
+	first := make(map[string]string) // normalized -> first raw answer
+	pending := len(providers)
+	var agreed string
+
+	for pending > 0 && !result.TimedOut {
+		var rep reply
+		select {
+		case rep = <-replies:
+		case <-deadline:
+			result.TimedOut = true
+			continue
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		pending--
+
+		vote := &result.Votes[rep.index]
+		vote.Cancelled = false
+		if rep.err != nil {
+			vote.Error = rep.err.Error()
+		} else {
+			vote.Content = rep.resp.Content
+			key := v.Normalize(rep.resp.Content)
+			counts[key]++
+			if _, ok := first[key]; !ok {
+				first[key] = rep.resp.Content
+			}
+			if !result.Agreed && counts[key] >= result.Required {
+				result.Agreed = true
+				result.Answer = first[key]
+				agreed = key
+				if v.EarlyExit {
+					break
+				}
+			}
+		}
+		if !v.EarlyExit {
+			continue
+		}
+
+		best := 0
+		for _, c := range counts {
+			if c > best {
+				best = c
+			}
+		}
+		if best+pending < result.Required {
+			break // Threshold can no longer be met
+		}
+	}
+
+	for _, c := range counts {
+		result.Responded += c
+	}
+	if !result.Agreed && !result.TimedOut && v.fallback == FallbackPrimary && failedQuorum(result) {
+		cancel()
+		return v.fallbackToPrimary(parent, req, result.Total, result.Required)
+	}
+	if result.TimedOut {
+		if result.Responded < max(v.config.MinResponders, 1) {
+			return nil, fmt.Errorf("%w: %d of %d within %ds",
+				ErrTooFewResponders, result.Responded, result.Total, v.config.Timeout)
+		}
+		if !result.Agreed {
+			result.Required = required(result.Responded, v.config.MinAgreement)
+			for key, c := range counts {
+				if c >= result.Required && (!result.Agreed || c > counts[agreed]) {
+					result.Agreed = true
+					result.Answer = first[key]
+					agreed = key
+				}
+			}
+		}
+		v.logger.Warn("Consensus timed out",
+			zap.Int("responded", result.Responded),
+			zap.Int("total", result.Total),
+			zap.Bool("agreed", result.Agreed),
+		)
+	}
+
+	if result.Agreed {
+		result.Support = counts[agreed]
+	} else {
+		for key, c := range counts {
+			if c > result.Support {
+				result.Support = c
+				result.Answer = first[key]
+			}
+		}
+	}
+	if result.TimedOut {
+		result.Ratio = float64(result.Support) / float64(result.Responded)
+	} else {
+		result.Ratio = float64(result.Support) / float64(result.Total)
+	}
+
+	v.observe(req.TaskType, result)
+
+	if pending > 0 && !result.TimedOut {
+		v.logger.Debug("Consensus decided early",
+			zap.Bool("agreed", result.Agreed),
+			zap.Int("cancelled", pending),
+		)
+	}
+	return result, nil
+}
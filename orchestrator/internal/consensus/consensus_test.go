@@ -0,0 +1,220 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// script is how a fake provider answers: with content or err after
+// delay, or not until its call is cancelled when hang is set
+type script struct {
+	content string
+	err     error
+	delay   time.Duration
+	hang    bool
+}
+
+// fakeCompleter answers each provider per its script, recording the
+// calls that were cancelled
+type fakeCompleter struct {
+	scripts map[string]script
+	primary string // answered for a request without a provider
+
+	mu        sync.Mutex
+	calls     []string
+	cancelled map[string]bool
+}
+
+func (f *fakeCompleter) Complete(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, req.Provider)
+	f.mu.Unlock()
+
+	if req.Provider == "" {
+		return &llm.Response{Provider: "primary", Content: f.primary}, nil
+	}
+	sc := f.scripts[req.Provider]
+	wait := sc.delay
+	if sc.hang {
+		wait = time.Hour
+	}
+	if wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			f.mu.Lock()
+			if f.cancelled == nil {
+				f.cancelled = make(map[string]bool)
+			}
+			f.cancelled[req.Provider] = true
+			f.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+	if sc.err != nil {
+		return nil, sc.err
+	}
+	return &llm.Response{Provider: req.Provider, Model: req.Model, Content: sc.content}, nil
+}
+
+// wasCancelled waits briefly for a provider's call to see its
+// cancellation
+func (f *fakeCompleter) wasCancelled(provider string) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		f.mu.Lock()
+		done := f.cancelled[provider]
+		f.mu.Unlock()
+		if done {
+			return true
+		}
+	}
+	return false
+}
+
+// consensusConfig asks providers a, b and c, needing the given agreement
+func consensusConfig(minAgreement float64) config.ConsensusConfig {
+	return config.ConsensusConfig{
+		Enabled:      true,
+		MinAgreement: minAgreement,
+		Providers: []config.ProviderConfig{
+			{Provider: "a", Model: "m"},
+			{Provider: "b", Model: "m"},
+			{Provider: "c", Model: "m"},
+		},
+	}
+}
+
+func question() *llm.Request {
+	return &llm.Request{Messages: []llm.Message{{Role: "user", Content: "6 x 7?"}}, TaskType: "question"}
+}
+
+func TestEarlyExitCancelsThirdCallOnceTwoAgree(t *testing.T) {
+	fake := &fakeCompleter{scripts: map[string]script{
+		"a": {content: "42"},
+		"b": {content: " 42\n"},
+		"c": {hang: true},
+	}}
+	v := New(consensusConfig(0.66), fake, zap.NewNop())
+	v.EarlyExit = true
+
+	result, err := v.Verify(context.Background(), question())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !result.Agreed || result.Support != 2 || result.Required != 2 {
+		t.Errorf("result = %+v, want agreement 2 of 2 needed", result)
+	}
+	if !result.Votes[2].Cancelled {
+		t.Errorf("third vote = %+v, want cancelled", result.Votes[2])
+	}
+	if !fake.wasCancelled("c") {
+		t.Error("the third provider call was not cancelled")
+	}
+}
+
+func TestEarlyExitStopsWhenAgreementIsOutOfReach(t *testing.T) {
+	fake := &fakeCompleter{scripts: map[string]script{
+		"a": {content: "42"},
+		"b": {content: "41", delay: 10 * time.Millisecond},
+		"c": {hang: true},
+	}}
+	v := New(consensusConfig(1), fake, zap.NewNop())
+	v.EarlyExit = true
+
+	result, err := v.Verify(context.Background(), question())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if result.Agreed {
+		t.Errorf("result = %+v, want no agreement", result)
+	}
+	if !fake.wasCancelled("c") {
+		t.Error("the outstanding call was not cancelled")
+	}
+}
+
+func TestWithoutEarlyExitEveryProviderVotes(t *testing.T) {
+	fake := &fakeCompleter{scripts: map[string]script{
+		"a": {content: "42"},
+		"b": {content: "42"},
+		"c": {content: "43", delay: 20 * time.Millisecond},
+	}}
+	v := New(consensusConfig(0.66), fake, zap.NewNop())
+
+	result, err := v.Verify(context.Background(), question())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !result.Agreed || result.Answer != "42" || result.Responded != 3 {
+		t.Errorf("result = %+v, want 42 agreed with all 3 responding", result)
+	}
+	if dissent := result.Dissenters(v.Normalize); len(dissent) != 1 || dissent[0].Provider != "c" {
+		t.Errorf("dissenters = %+v, want c", dissent)
+	}
+}
+
+func TestVerifyWithoutProviders(t *testing.T) {
+	v := New(config.ConsensusConfig{}, &fakeCompleter{}, zap.NewNop())
+	if _, err := v.Verify(context.Background(), question()); !errors.Is(err, ErrNoProviders) {
+		t.Errorf("Verify = %v, want ErrNoProviders", err)
+	}
+}
+
+// timedConfig is consensusConfig with a one second round timeout
+func timedConfig(minAgreement float64, minResponders int) config.ConsensusConfig {
+	cfg := consensusConfig(minAgreement)
+	cfg.Timeout = 1
+	cfg.MinResponders = minResponders
+	return cfg
+}
+
+func TestTimeoutDecidesFromProvidersThatAnswered(t *testing.T) {
+	fake := &fakeCompleter{scripts: map[string]script{
+		"a": {content: "42"},
+		"b": {content: "42"},
+		"c": {hang: true},
+	}}
+	v := New(timedConfig(1, 2), fake, zap.NewNop())
+
+	start := time.Now()
+	result, err := v.Verify(context.Background(), question())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("round took %v past a one second timeout", elapsed)
+	}
+	if !result.TimedOut || !result.Agreed || result.Answer != "42" {
+		t.Errorf("result = %+v, want agreement on 42 at the timeout", result)
+	}
+	if result.Responded != 2 || result.Required != 2 || result.Ratio != 1 {
+		t.Errorf("responded %d, required %d, ratio %g; want 2, 2 and 1", result.Responded, result.Required, result.Ratio)
+	}
+	if !result.Votes[2].Cancelled || result.Votes[2].Content != "" {
+		t.Errorf("late vote = %+v, want it cancelled and discarded", result.Votes[2])
+	}
+	if !fake.wasCancelled("c") {
+		t.Error("the hung provider call was not cancelled")
+	}
+}
+
+func TestTimeoutWithTooFewRespondersFails(t *testing.T) {
+	fake := &fakeCompleter{scripts: map[string]script{
+		"a": {content: "42"},
+		"b": {hang: true},
+		"c": {hang: true},
+	}}
+	v := New(timedConfig(0.66, 2), fake, zap.NewNop())
+
+	_, err := v.Verify(context.Background(), question())
+	if !errors.Is(err, ErrTooFewResponders) {
+		t.Errorf("Verify = %v, want ErrTooFewResponders", err)
+	}
+}
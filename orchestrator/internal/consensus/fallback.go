@@ -0,0 +1,122 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Fallback modes for a round that cannot reach agreement because too few
+// providers are usable
+const (
+	FallbackFail    = "fail"
+	FallbackPrimary = "fallback_to_primary"
+	FallbackQueue   = "queue_until_available"
+)
+
+// healthPollInterval is how often a queued round rechecks provider health
+const healthPollInterval = time.Second
+
+// ErrInsufficientProviders is returned in fail mode when fewer providers
+// are healthy than agreement needs
+var ErrInsufficientProviders = errors.New("too few healthy consensus providers")
+
+var fallbackModes = map[string]bool{
+	FallbackFail:    true,
+	FallbackPrimary: true,
+	FallbackQueue:   true,
+}
+
+// HealthChecker reports whether a provider is taking calls. A Completer
+// that also implements it, as *llm.Client does, lets a round degrade
+// before calling providers that are known to be down.
+type HealthChecker interface {
+	Healthy(provider string) bool
+}
+
+// fallbackMode resolves the configured mode, defaulting to fail
+func fallbackMode(name string, logger *zap.Logger) string {
+	if name == "" {
+		return FallbackFail
+	}
+	if !fallbackModes[name] {
+		logger.Warn("Unknown consensus fallback, using fail", zap.String("fallback", name))
+		return FallbackFail
+	}
+	return name
+}
+
+// healthy counts the providers the completer considers usable; without a
+// health check every provider counts
+func (v *Verifier) healthy(providers []config.ProviderConfig) int {
+	hc, ok := v.completer.(HealthChecker)
+	if !ok {
+		return len(providers)
+	}
+	n := 0
+	for _, pc := range providers {
+		if hc.Healthy(pc.Provider) {
+			n++
+		}
+	}
+	return n
+}
+
+// waitForProviders blocks until at least need providers are healthy
+func (v *Verifier) waitForProviders(ctx context.Context, providers []config.ProviderConfig, need int) error {
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+
+	for v.healthy(providers) < need {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// failedQuorum reports whether provider errors alone left too few votes
+// for agreement, as opposed to the providers answering and disagreeing
+func failedQuorum(result *Result) bool {
+	failed := 0
+	for _, vote := range result.Votes {
+		if vote.Error != "" {
+			failed++
+		}
+	}
+	return result.Total-failed < result.Required
+}
+
+// fallbackToPrimary answers from the completer's primary provider chain
+// alone. The answer is accepted but flagged low-confidence.
+func (v *Verifier) fallbackToPrimary(ctx context.Context, req *llm.Request, total, need int) (*Result, error) {
+	call := *req
+	resp, err := v.completer.Complete(ctx, &call)
+	if err != nil {
+		return nil, fmt.Errorf("consensus fallback to primary: %w", err)
+	}
+
+	v.logger.Warn("Consensus degraded to a single provider",
+		zap.String("provider", resp.Provider),
+		zap.Int("required", need),
+	)
+	return &Result{
+		Agreed:        true,
+		Answer:        resp.Content,
+		Support:       1,
+		Required:      need,
+		Total:         total,
+		Votes:         []Vote{{Provider: resp.Provider, Model: resp.Model, Content: resp.Content}},
+		Ratio:         1,
+		Responded:     1,
+		Fallback:      FallbackPrimary,
+		LowConfidence: true,
+	}, nil
+}
@@ -0,0 +1,137 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// healthCompleter is a fakeCompleter whose providers can be marked down
+type healthCompleter struct {
+	*fakeCompleter
+
+	mu   sync.Mutex
+	down map[string]bool
+}
+
+func (h *healthCompleter) Healthy(provider string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !h.down[provider]
+}
+
+func (h *healthCompleter) setDown(provider string, down bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.down[provider] = down
+}
+
+// degraded has providers b and c down, leaving one of the two needed
+func degraded() *healthCompleter {
+	return &healthCompleter{
+		fakeCompleter: &fakeCompleter{
+			primary: "42",
+			scripts: map[string]script{"a": {content: "42"}, "b": {content: "42"}, "c": {content: "42"}},
+		},
+		down: map[string]bool{"b": true, "c": true},
+	}
+}
+
+func TestFallbackFailRefusesWithoutCalling(t *testing.T) {
+	fake := degraded()
+	cfg := consensusConfig(0.66)
+	cfg.Fallback = FallbackFail
+	v := New(cfg, fake, zap.NewNop())
+
+	_, err := v.Verify(context.Background(), question())
+	if !errors.Is(err, ErrInsufficientProviders) {
+		t.Fatalf("err = %v, want ErrInsufficientProviders", err)
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("providers called: %v", fake.calls)
+	}
+}
+
+func TestFallbackDefaultsToFail(t *testing.T) {
+	for _, mode := range []string{"", "shrug"} {
+		cfg := consensusConfig(0.66)
+		cfg.Fallback = mode
+		v := New(cfg, degraded(), zap.NewNop())
+		if _, err := v.Verify(context.Background(), question()); !errors.Is(err, ErrInsufficientProviders) {
+			t.Errorf("fallback %q: err = %v, want ErrInsufficientProviders", mode, err)
+		}
+	}
+}
+
+func TestFallbackToPrimaryIsLowConfidence(t *testing.T) {
+	fake := degraded()
+	cfg := consensusConfig(0.66)
+	cfg.Fallback = FallbackPrimary
+	v := New(cfg, fake, zap.NewNop())
+
+	result, err := v.Verify(context.Background(), question())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if result.Answer != "42" || result.Fallback != FallbackPrimary || !result.LowConfidence {
+		t.Errorf("result = %+v, want the primary's answer flagged low-confidence", result)
+	}
+	if len(fake.calls) != 1 || fake.calls[0] != "" {
+		t.Errorf("calls = %q, want only the primary chain", fake.calls)
+	}
+}
+
+func TestFallbackToPrimaryWhenProvidersError(t *testing.T) {
+	// Every provider looks healthy but two fail their calls
+	fake := &fakeCompleter{primary: "42", scripts: map[string]script{
+		"a": {content: "42"},
+		"b": {err: errors.New("rate limited")},
+		"c": {err: errors.New("rate limited")},
+	}}
+	cfg := consensusConfig(0.66)
+	cfg.Fallback = FallbackPrimary
+	v := New(cfg, fake, zap.NewNop())
+
+	result, err := v.Verify(context.Background(), question())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if result.Fallback != FallbackPrimary || !result.LowConfidence {
+		t.Errorf("result = %+v, want the primary fallback", result)
+	}
+}
+
+func TestFallbackQueueWaitsForProviders(t *testing.T) {
+	fake := degraded()
+	cfg := consensusConfig(0.66)
+	cfg.Fallback = FallbackQueue
+	v := New(cfg, fake, zap.NewNop())
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		fake.setDown("b", false)
+	}()
+	result, err := v.Verify(context.Background(), question())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !result.Agreed || result.Fallback != FallbackQueue || result.Waited <= 0 || result.LowConfidence {
+		t.Errorf("result = %+v, want agreement recorded as queued", result)
+	}
+}
+
+func TestFallbackQueueEndsWithContext(t *testing.T) {
+	cfg := consensusConfig(0.66)
+	cfg.Fallback = FallbackQueue
+	v := New(cfg, degraded(), zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := v.Verify(ctx, question()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the wait to end with the context", err)
+	}
+}
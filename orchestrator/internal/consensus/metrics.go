@@ -0,0 +1,95 @@
+package consensus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// agreementBuckets are the upper bounds of the agreement ratio histogram
+var agreementBuckets = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+// agreementStats accumulates the rounds run for one task type
+type agreementStats struct {
+	rounds  uint64
+	agreed  uint64
+	buckets []uint64 // cumulative counts per agreementBuckets bound
+	sum     float64
+}
+
+// observe records a decided round's agreement ratio and outcome. Rounds
+// answered by the primary alone measure no agreement and are not counted.
+// With EarlyExit the ratio only counts the votes in when the round was
+// decided, so it understates agreement; turn it off while tuning.
+func (v *Verifier) observe(taskType string, result *Result) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	st, ok := v.stats[taskType]
+	if !ok {
+		st = &agreementStats{buckets: make([]uint64, len(agreementBuckets))}
+		v.stats[taskType] = st
+	}
+	st.rounds++
+	st.sum += result.Ratio
+	if result.Agreed {
+		st.agreed++
+	}
+	for i, bound := range agreementBuckets {
+		if result.Ratio <= bound {
+			st.buckets[i]++
+		}
+	}
+}
+
+// WriteMetrics writes the agreement ratio histogram and the rounds that
+// did and did not reach min_agreement, labeled by task type, in the
+// Prometheus text exposition format
+func (v *Verifier) WriteMetrics(w io.Writer) error {
+	v.mu.Lock()
+	types := make([]string, 0, len(v.stats))
+	stats := make(map[string]agreementStats, len(v.stats))
+	for taskType, st := range v.stats {
+		types = append(types, taskType)
+		copied := *st
+		copied.buckets = append([]uint64(nil), st.buckets...)
+		stats[taskType] = copied
+	}
+	v.mu.Unlock()
+	sort.Strings(types)
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "# HELP odin_consensus_agreement_ratio Share of votes behind the leading answer of each consensus round, by task type.")
+	fmt.Fprintln(b, "# TYPE odin_consensus_agreement_ratio histogram")
+	for _, taskType := range types {
+		st := stats[taskType]
+		labels := "task_type=" + quoteLabel(taskType)
+		for i, bound := range agreementBuckets {
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(b, "odin_consensus_agreement_ratio_bucket{%s,le=%q} %d\n", labels, le, st.buckets[i])
+		}
+		fmt.Fprintf(b, "odin_consensus_agreement_ratio_bucket{%s,le=\"+Inf\"} %d\n", labels, st.rounds)
+		fmt.Fprintf(b, "odin_consensus_agreement_ratio_sum{%s} %g\n", labels, st.sum)
+		fmt.Fprintf(b, "odin_consensus_agreement_ratio_count{%s} %d\n", labels, st.rounds)
+	}
+
+	fmt.Fprintln(b, "# HELP odin_consensus_rounds_total Consensus rounds decided, by task type and whether they reached min_agreement.")
+	fmt.Fprintln(b, "# TYPE odin_consensus_rounds_total counter")
+	for _, taskType := range types {
+		st := stats[taskType]
+		labels := "task_type=" + quoteLabel(taskType)
+		fmt.Fprintf(b, "odin_consensus_rounds_total{%s,outcome=\"pass\"} %d\n", labels, st.agreed)
+		fmt.Fprintf(b, "odin_consensus_rounds_total{%s,outcome=\"fail\"} %d\n", labels, st.rounds-st.agreed)
+	}
+	return b.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel quotes a label value with the escaping the text format expects
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
@@ -0,0 +1,113 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// metricLines returns the samples a verifier writes, keyed by name and
+// labels
+func metricLines(t *testing.T, v *Verifier) map[string]string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := v.WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	samples := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		samples[line[:i]] = line[i+1:]
+	}
+	return samples
+}
+
+// round runs one consensus round with a, b and c answering as given
+func round(t *testing.T, v *Verifier, fake *fakeCompleter, taskType string, answers ...string) {
+	t.Helper()
+	for i, provider := range []string{"a", "b", "c"} {
+		fake.scripts[provider] = script{content: answers[i]}
+	}
+	req := question()
+	req.TaskType = taskType
+	if _, err := v.Verify(context.Background(), req); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestAgreementHistogramByTaskType(t *testing.T) {
+	fake := &fakeCompleter{scripts: make(map[string]script)}
+	v := New(consensusConfig(0.67), fake, zap.NewNop())
+
+	round(t, v, fake, "question", "42", "42", "42")
+	round(t, v, fake, "question", "42", "42", "41")
+	round(t, v, fake, "question", "42", "41", "40")
+	round(t, v, fake, "code_review", "lgtm", "lgtm", "lgtm")
+
+	samples := metricLines(t, v)
+	q := `task_type="question"`
+	want := map[string]string{
+		`odin_consensus_agreement_ratio_bucket{` + q + `,le="0.3"}`:  "0",
+		`odin_consensus_agreement_ratio_bucket{` + q + `,le="0.4"}`:  "1",
+		`odin_consensus_agreement_ratio_bucket{` + q + `,le="0.6"}`:  "1",
+		`odin_consensus_agreement_ratio_bucket{` + q + `,le="0.7"}`:  "2",
+		`odin_consensus_agreement_ratio_bucket{` + q + `,le="0.9"}`:  "2",
+		`odin_consensus_agreement_ratio_bucket{` + q + `,le="1"}`:    "3",
+		`odin_consensus_agreement_ratio_bucket{` + q + `,le="+Inf"}`: "3",
+		`odin_consensus_agreement_ratio_count{` + q + `}`:            "3",
+		`odin_consensus_rounds_total{` + q + `,outcome="pass"}`:      "1",
+		`odin_consensus_rounds_total{` + q + `,outcome="fail"}`:      "2",
+
+		`odin_consensus_agreement_ratio_bucket{task_type="code_review",le="0.9"}`: "0",
+		`odin_consensus_agreement_ratio_bucket{task_type="code_review",le="1"}`:   "1",
+		`odin_consensus_rounds_total{task_type="code_review",outcome="pass"}`:     "1",
+	}
+	for key, value := range want {
+		if samples[key] != value {
+			t.Errorf("%s = %q, want %s", key, samples[key], value)
+		}
+	}
+	sum, err := strconv.ParseFloat(samples[`odin_consensus_agreement_ratio_sum{`+q+`}`], 64)
+	if err != nil || math.Abs(sum-2) > 1e-9 {
+		t.Errorf("sum = %v (%v), want 2", sum, err)
+	}
+}
+
+func TestRoundsWithoutAgreementNotRecorded(t *testing.T) {
+	// Answered by the primary alone
+	fake := degraded()
+	cfg := consensusConfig(0.66)
+	cfg.Fallback = FallbackPrimary
+	v := New(cfg, fake, zap.NewNop())
+	if _, err := v.Verify(context.Background(), question()); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// Refused before any provider answered
+	cfg = consensusConfig(0.66)
+	cfg.Fallback = FallbackFail
+	v2 := New(cfg, degraded(), zap.NewNop())
+	if _, err := v2.Verify(context.Background(), question()); err == nil {
+		t.Fatal("a round short of quorum succeeded")
+	}
+
+	for _, verifier := range []*Verifier{v, v2} {
+		if samples := metricLines(t, verifier); len(samples) != 0 {
+			t.Errorf("recorded %v", samples)
+		}
+	}
+}
+
+func TestTaskTypeLabelEscaped(t *testing.T) {
+	if got := quoteLabel("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Errorf("quoteLabel = %s", got)
+	}
+}
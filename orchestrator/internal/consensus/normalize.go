@@ -0,0 +1,108 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Normalizer maps a response to the form compared for agreement
+type Normalizer func(string) string
+
+// DefaultNormalizers ignore case and whitespace differences
+var DefaultNormalizers = []string{"trim", "collapse_whitespace", "lowercase"}
+
+// normalizers are the named steps a pipeline can be built from
+var normalizers = map[string]Normalizer{
+	"trim":                strings.TrimSpace,
+	"lowercase":           strings.ToLower,
+	"collapse_whitespace": collapseWhitespace,
+	"strip_markdown":      stripMarkdown,
+	"extract_code":        extractCode,
+	"canonical_json":      canonicalJSON,
+}
+
+// Chain applies normalizers left to right
+func Chain(steps ...Normalizer) Normalizer {
+	return func(s string) string {
+		for _, step := range steps {
+			s = step(s)
+		}
+		return s
+	}
+}
+
+// NewNormalizer builds a pipeline from step names, applied in order
+func NewNormalizer(names []string) (Normalizer, error) {
+	steps := make([]Normalizer, 0, len(names))
+	for _, name := range names {
+		step, ok := normalizers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown normalizer: %s", name)
+		}
+		steps = append(steps, step)
+	}
+	return Chain(steps...), nil
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var (
+	codeFence  = regexp.MustCompile("(?s)```[^\\n]*\\n(.*?)```")
+	fenceLine  = regexp.MustCompile("(?m)^\\s*```.*$")
+	heading    = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	blockquote = regexp.MustCompile(`(?m)^\s*>\s?`)
+	listMarker = regexp.MustCompile(`(?m)^\s*(?:[-*+]|\d+[.)])\s+`)
+	link       = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	emphasis   = regexp.MustCompile("(\\*\\*|\\*|`|~~)") // not _, which identifiers use
+)
+
+// stripMarkdown removes formatting so prose and code read the same
+// whether or not a model chose to render it as markdown
+func stripMarkdown(s string) string {
+	s = fenceLine.ReplaceAllString(s, "")
+	s = heading.ReplaceAllString(s, "")
+	s = blockquote.ReplaceAllString(s, "")
+	s = listMarker.ReplaceAllString(s, "")
+	s = link.ReplaceAllString(s, "$1")
+	return emphasis.ReplaceAllString(s, "")
+}
+
+// extractCode keeps only the contents of fenced code blocks, so answers
+// wrapping the same code in different explanations agree. Responses
+// without a code block are left as they are.
+func extractCode(s string) string {
+	blocks := codeFence.FindAllStringSubmatch(s, -1)
+	if len(blocks) == 0 {
+		return s
+	}
+	code := make([]string, len(blocks))
+	for i, block := range blocks {
+		code[i] = strings.TrimSpace(block[1])
+	}
+	return strings.Join(code, "\n")
+}
+
+// canonicalJSON re-encodes JSON responses with sorted keys and no
+// insignificant whitespace. Anything that isn't valid JSON is unchanged.
+func canonicalJSON(s string) string {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return s
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return s
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
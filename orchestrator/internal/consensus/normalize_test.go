@@ -0,0 +1,63 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestNormalizedAnswersAgree(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []string
+		a, b  string
+		agree bool
+	}{
+		{"default case and spacing", DefaultNormalizers, "  The Answer is 42\n", "the answer  is 42", true},
+		{"default keeps distinct answers", DefaultNormalizers, "42", "43", false},
+		{"markdown", []string{"strip_markdown", "trim", "collapse_whitespace"}, "## Result\n**Use** a [map](https://go.dev)", "Result Use a map", true},
+		{"code blocks", []string{"extract_code"}, "Here you go:\n```go\nreturn x + 1\n```\nHope it helps", "```\nreturn x + 1\n```", true},
+		{"different code", []string{"extract_code"}, "```go\nreturn x + 1\n```", "```go\nreturn x - 1\n```", false},
+		{"json", []string{"trim", "canonical_json"}, `{"b": 2, "a": [1, 2]}`, "\n{\"a\":[1,2],\"b\":2}\n", true},
+		{"json values differ", []string{"canonical_json"}, `{"a": 1}`, `{"a": 2}`, false},
+		{"json large numbers kept", []string{"canonical_json"}, `{"n": 12345678901234567890}`, `{"n": 12345678901234567891}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalize, err := NewNormalizer(tt.steps)
+			if err != nil {
+				t.Fatalf("NewNormalizer: %v", err)
+			}
+			a, b := normalize(tt.a), normalize(tt.b)
+			if (a == b) != tt.agree {
+				t.Errorf("normalized %q and %q, want agree=%v", a, b, tt.agree)
+			}
+		})
+	}
+}
+
+func TestNormalizerRejectsUnknownStep(t *testing.T) {
+	if _, err := NewNormalizer([]string{"trim", "stem"}); err == nil {
+		t.Error("unknown step accepted")
+	}
+}
+
+func TestVerifyUsesConfiguredNormalization(t *testing.T) {
+	fake := &fakeCompleter{scripts: map[string]script{
+		"a": {content: "```python\nprint(42)\n```"},
+		"b": {content: "Sure! Run this:\n```\nprint(42)\n```"},
+		"c": {content: "```\nprint(41)\n```"},
+	}}
+	cfg := consensusConfig(0.66)
+	cfg.Normalize = []string{"extract_code", "trim"}
+	v := New(cfg, fake, zap.NewNop())
+
+	result, err := v.Verify(context.Background(), question())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !result.Agreed || result.Support != 2 {
+		t.Errorf("result = %+v, want a and b to agree", result)
+	}
+}
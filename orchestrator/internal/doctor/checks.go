@@ -0,0 +1,194 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// ConfigChecks check that the config is valid, and warn of settings that
+// would leave the orchestrator doing nothing useful
+func ConfigChecks(cfg *config.Config) []Check {
+	return []Check{
+		{
+			Name:     "config valid",
+			Critical: true,
+			Run: func(context.Context) Result {
+				err := cfg.Validate()
+				if err == nil {
+					return pass("")
+				}
+				problems := []error{err}
+				if joined, ok := err.(interface{ Unwrap() []error }); ok {
+					problems = joined.Unwrap()
+				}
+				detail := problems[0].Error()
+				if len(problems) > 1 {
+					detail = fmt.Sprintf("%s (and %d more)", detail, len(problems)-1)
+				}
+				return fail(detail, "run `odin config validate` to list every problem")
+			},
+		},
+		{
+			Name: "config sensible",
+			Run: func(context.Context) Result {
+				warnings := cfg.Warnings()
+				if len(warnings) == 0 {
+					return pass("")
+				}
+				return warn(strings.Join(warnings, "; "), "run `odin config check` for details")
+			},
+		},
+	}
+}
+
+// Ping checks that a backing service answers. Every service the
+// orchestrator connects to is critical.
+func Ping(name string, ping func(ctx context.Context) error, hint string) Check {
+	return Check{
+		Name:     name + " reachable",
+		Critical: true,
+		Run: func(ctx context.Context) Result {
+			if err := ping(ctx); err != nil {
+				return fail(err.Error(), hint)
+			}
+			return pass("")
+		},
+	}
+}
+
+// providerEntry is a provider as configured, and where
+type providerEntry struct {
+	field string
+	pc    config.ProviderConfig
+}
+
+func providerEntries(cfg *config.Config) []providerEntry {
+	entries := []providerEntry{{"llm.primary", cfg.LLM.Primary}}
+	for i, pc := range cfg.LLM.Fallback {
+		entries = append(entries, providerEntry{fmt.Sprintf("llm.fallback[%d]", i), pc})
+	}
+	for i, pc := range cfg.LLM.Consensus.Providers {
+		entries = append(entries, providerEntry{fmt.Sprintf("llm.consensus.providers[%d]", i), pc})
+	}
+	return slices.DeleteFunc(entries, func(e providerEntry) bool { return e.pc.Provider == "" })
+}
+
+// ProviderKeyChecks check that every configured hosted provider has an
+// API key. Only the primary's is critical.
+func ProviderKeyChecks(cfg *config.Config) []Check {
+	var checks []Check
+	for _, e := range providerEntries(cfg) {
+		checks = append(checks, Check{
+			Name:     fmt.Sprintf("%s API key (%s)", e.pc.Provider, e.field),
+			Critical: e.field == "llm.primary",
+			Run: func(context.Context) Result {
+				envVar, needed := config.APIKeyEnv(e.pc.Provider)
+				switch {
+				case !needed:
+					return pass("not needed")
+				case e.pc.APIKey != "":
+					return pass("")
+				case e.field == "llm.primary":
+					// The primary's key always comes from the environment
+					return fail("missing", "set "+envVar)
+				}
+				return fail("missing", fmt.Sprintf("set %s.api_key, e.g. to ${%s}", e.field, envVar))
+			},
+		})
+	}
+	return checks
+}
+
+// Providers checks that each configured provider and model answers a
+// one-token request. warm sends them, reporting by provider/model, as
+// llm.Client.WarmUp does. An unreachable primary is critical; an
+// unreachable fallback, profile or consensus provider only warns.
+func Providers(cfg *config.Config, warm func(ctx context.Context) map[string]error) Check {
+	primary := cfg.LLM.Primary.Provider + "/" + cfg.LLM.Primary.Model
+	return Check{
+		Name:     "providers reachable",
+		Critical: true,
+		Run: func(ctx context.Context) Result {
+			report := warm(ctx)
+			if len(report) == 0 {
+				return Result{Status: StatusSkip, Detail: "no provider configured"}
+			}
+			var failed []string
+			for key, err := range report {
+				if err != nil {
+					failed = append(failed, fmt.Sprintf("%s (%v)", key, err))
+				}
+			}
+			sort.Strings(failed)
+
+			hint := "check the provider's base_url, API key and network access"
+			switch {
+			case report[primary] != nil:
+				return fail(strings.Join(failed, "; "), hint)
+			case len(failed) > 0:
+				return warn(strings.Join(failed, "; "), hint)
+			}
+			return pass(fmt.Sprintf("%d answered", len(report)))
+		},
+	}
+}
+
+// Routes checks that every required stage of every route names an
+// enabled agent, and that every enabled agent is on some route. Agents
+// found by a dynamic discovery backend are only known once running, so
+// a missing agent then only warns.
+func Routes(cfg *config.Config, routes map[router.TaskType][]router.RouteStage) Check {
+	return Check{
+		Name:     "routes",
+		Critical: true,
+		Run: func(context.Context) Result {
+			enabled := make(map[string]bool, len(cfg.Agents.Enabled))
+			for _, name := range cfg.Agents.Enabled {
+				enabled[name] = true
+			}
+
+			types := make([]string, 0, len(routes))
+			for taskType := range routes {
+				types = append(types, string(taskType))
+			}
+			sort.Strings(types)
+
+			var missing []string
+			routed := make(map[string]bool)
+			for _, taskType := range types {
+				for _, stage := range routes[router.TaskType(taskType)] {
+					routed[stage.Agent] = true
+					if !stage.Optional && !enabled[stage.Agent] {
+						missing = append(missing, fmt.Sprintf("%s needs %s", taskType, stage.Agent))
+					}
+				}
+			}
+			if len(missing) > 0 {
+				detail := "required agents not enabled: " + strings.Join(missing, ", ")
+				hint := "add the agents to agents.enabled, or edit the routes"
+				if backend := cfg.Agents.Discovery.Backend; backend != "" && backend != "static" {
+					return warn(detail, hint+"; ignore this if "+backend+" discovery registers them")
+				}
+				return fail(detail, hint)
+			}
+
+			var unrouted []string
+			for _, name := range cfg.Agents.Enabled {
+				if !routed[name] {
+					unrouted = append(unrouted, name)
+				}
+			}
+			if len(unrouted) > 0 {
+				return warn("enabled agents on no route: "+strings.Join(unrouted, ", "),
+					"add them to a route, or remove them from agents.enabled")
+			}
+			return pass(fmt.Sprintf("%d task types routed", len(types)))
+		},
+	}
+}
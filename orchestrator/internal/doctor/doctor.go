@@ -0,0 +1,99 @@
+// =============================================================================
+// ODIN v7.0 - Setup Diagnostics
+// =============================================================================
+// One-shot checks of an installation's config, backing services, providers
+// and routes, reported as a checklist with hints for fixing what fails
+// =============================================================================
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Check outcomes
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn" // works, but probably not as intended
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip" // could not be checked
+)
+
+// Status is the outcome of one check
+type Status string
+
+// Result is what one check found
+type Result struct {
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"` // how to fix a warning or failure
+}
+
+// Check is one diagnostic. A critical check that fails leaves the
+// orchestrator unable to work; any other failure only degrades it.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) Result
+}
+
+// Outcome is a check and its result
+type Outcome struct {
+	Name     string `json:"name"`
+	Critical bool   `json:"critical"`
+	Result
+}
+
+// Run runs the checks in order, bounding each by timeout (0 = none)
+func Run(ctx context.Context, checks []Check, timeout time.Duration) []Outcome {
+	out := make([]Outcome, 0, len(checks))
+	for _, check := range checks {
+		out = append(out, Outcome{Name: check.Name, Critical: check.Critical, Result: run(ctx, check, timeout)})
+	}
+	return out
+}
+
+func run(ctx context.Context, check Check, timeout time.Duration) Result {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return check.Run(ctx)
+}
+
+// Failed counts the critical checks that failed
+func Failed(outcomes []Outcome) int {
+	n := 0
+	for _, o := range outcomes {
+		if o.Critical && o.Status == StatusFail {
+			n++
+		}
+	}
+	return n
+}
+
+// Print writes the outcomes as a checklist, each warning or failure
+// followed by its hint
+func Print(w io.Writer, outcomes []Outcome) {
+	for _, o := range outcomes {
+		line := fmt.Sprintf("[%s] %s", strings.ToUpper(string(o.Status)), o.Name)
+		if o.Detail != "" {
+			line += ": " + o.Detail
+		}
+		fmt.Fprintln(w, line)
+		if o.Hint != "" && (o.Status == StatusWarn || o.Status == StatusFail) {
+			fmt.Fprintln(w, "       hint:", o.Hint)
+		}
+	}
+}
+
+func pass(detail string) Result { return Result{Status: StatusPass, Detail: detail} }
+
+func fail(detail, hint string) Result { return Result{Status: StatusFail, Detail: detail, Hint: hint} }
+
+func warn(detail, hint string) Result { return Result{Status: StatusWarn, Detail: detail, Hint: hint} }
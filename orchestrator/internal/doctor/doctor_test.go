@@ -0,0 +1,165 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// runOne runs a single check
+func runOne(check Check) Outcome {
+	return Run(context.Background(), []Check{check}, time.Second)[0]
+}
+
+func TestServiceDownFailsWithHint(t *testing.T) {
+	redis := Ping("redis", func(context.Context) error { return errors.New("connection refused") }, "start Redis or fix redis.host")
+	postgres := Ping("postgres", func(context.Context) error { return nil }, "start Postgres")
+	outcomes := Run(context.Background(), []Check{redis, postgres}, time.Second)
+
+	if got := outcomes[0]; got.Status != StatusFail || !got.Critical || got.Detail != "connection refused" {
+		t.Errorf("redis = %+v, want a critical failure", got)
+	}
+	if got := outcomes[1]; got.Status != StatusPass {
+		t.Errorf("postgres = %+v, want a pass", got)
+	}
+	if got := Failed(outcomes); got != 1 {
+		t.Errorf("Failed = %d, want 1", got)
+	}
+
+	var out bytes.Buffer
+	Print(&out, outcomes)
+	want := "[FAIL] redis reachable: connection refused\n" +
+		"       hint: start Redis or fix redis.host\n" +
+		"[PASS] postgres reachable\n"
+	if out.String() != want {
+		t.Errorf("printed:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestHangingServiceBoundedByTimeout(t *testing.T) {
+	hang := Ping("redis", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, "")
+	start := time.Now()
+	got := Run(context.Background(), []Check{hang}, 10*time.Millisecond)[0]
+	if got.Status != StatusFail || !strings.Contains(got.Detail, "deadline exceeded") {
+		t.Errorf("outcome = %+v, want failed on its deadline", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("check ran %v past its 10ms timeout", elapsed)
+	}
+}
+
+func TestConfigChecks(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Orchestrator.ListenAddr = ":9000"
+	cfg.Orchestrator.MaxConcurrentTasks = 4
+	cfg.LLM.Primary.Provider = "ollama"
+	cfg.Agents.Enabled = []string{"dev"}
+	for _, c := range ConfigChecks(cfg) {
+		if got := runOne(c); got.Status != StatusPass {
+			t.Errorf("%s = %+v on a good config", c.Name, got)
+		}
+	}
+
+	cfg.Orchestrator.RetryJitter = "sometimes"
+	cfg.Orchestrator.Scheduling = "fifo"
+	cfg.Agents.Enabled = nil
+	checks := ConfigChecks(cfg)
+	if got := runOne(checks[0]); got.Status != StatusFail || !strings.HasSuffix(got.Detail, "(and 1 more)") {
+		t.Errorf("config valid = %+v, want the first problem and a count", got)
+	}
+	if got := runOne(checks[1]); got.Status != StatusWarn || !strings.Contains(got.Detail, "agents.enabled is empty") || checks[1].Critical {
+		t.Errorf("config sensible = %+v, want a non-critical warning", got)
+	}
+}
+
+func TestProviderKeyChecks(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.LLM.Primary = config.ProviderConfig{Provider: "anthropic", Model: "claude"}
+	cfg.LLM.Fallback = []config.ProviderConfig{
+		{Provider: "ollama", Model: "llama3"},
+		{Provider: "openai", Model: "gpt"},
+		{Provider: "groq", Model: "llama", APIKey: "set"},
+	}
+
+	checks := ProviderKeyChecks(cfg)
+	tests := []struct {
+		name     string
+		status   Status
+		critical bool
+		hint     string
+	}{
+		{"anthropic API key (llm.primary)", StatusFail, true, "set ANTHROPIC_API_KEY"},
+		{"ollama API key (llm.fallback[0])", StatusPass, false, ""},
+		{"openai API key (llm.fallback[1])", StatusFail, false, "set llm.fallback[1].api_key, e.g. to ${OPENAI_API_KEY}"},
+		{"groq API key (llm.fallback[2])", StatusPass, false, ""},
+	}
+	if len(checks) != len(tests) {
+		t.Fatalf("%d checks, want %d", len(checks), len(tests))
+	}
+	for i, tt := range tests {
+		got := runOne(checks[i])
+		if got.Name != tt.name || got.Status != tt.status || got.Critical != tt.critical || got.Hint != tt.hint {
+			t.Errorf("check %d = %+v, want %s %s critical=%v hint %q", i, got, tt.name, tt.status, tt.critical, tt.hint)
+		}
+	}
+}
+
+func TestProvidersReachable(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.LLM.Primary = config.ProviderConfig{Provider: "ollama", Model: "llama3"}
+	down := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		report map[string]error
+		status Status
+	}{
+		{"all answer", map[string]error{"ollama/llama3": nil, "openai/gpt": nil}, StatusPass},
+		{"fallback down", map[string]error{"ollama/llama3": nil, "openai/gpt": down}, StatusWarn},
+		{"primary down", map[string]error{"ollama/llama3": down, "openai/gpt": nil}, StatusFail},
+		{"nothing configured", map[string]error{}, StatusSkip},
+	}
+	for _, tt := range tests {
+		check := Providers(cfg, func(context.Context) map[string]error { return tt.report })
+		if got := runOne(check); got.Status != tt.status {
+			t.Errorf("%s: %+v, want %s", tt.name, got, tt.status)
+		}
+	}
+}
+
+func TestRoutesCheck(t *testing.T) {
+	routes := map[router.TaskType][]router.RouteStage{
+		router.TaskCodeWrite: {{Agent: "retrieval", Optional: true}, {Agent: "dev"}, {Agent: "approbation"}},
+		router.TaskQuestion:  {{Agent: "explain"}},
+	}
+	tests := []struct {
+		name    string
+		enabled []string
+		backend string
+		status  Status
+		detail  string
+	}{
+		{"every stage enabled", []string{"dev", "approbation", "explain"}, "", StatusPass, "2 task types routed"},
+		{"required agent missing", []string{"dev", "explain"}, "", StatusFail, "required agents not enabled: code_write needs approbation"},
+		{"missing under dynamic discovery", []string{"dev", "explain"}, "consul", StatusWarn, "required agents not enabled: code_write needs approbation"},
+		{"enabled agent unrouted", []string{"dev", "approbation", "explain", "security"}, "", StatusWarn, "enabled agents on no route: security"},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{}
+		cfg.Agents.Enabled = tt.enabled
+		cfg.Agents.Discovery.Backend = tt.backend
+		got := runOne(Routes(cfg, routes))
+		if got.Status != tt.status || got.Detail != tt.detail {
+			t.Errorf("%s: %+v, want %s %q", tt.name, got, tt.status, tt.detail)
+		}
+	}
+}
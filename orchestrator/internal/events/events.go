@@ -0,0 +1,139 @@
+// =============================================================================
+// ODIN v7.0 - Event Bus
+// =============================================================================
+// In-process fan-out of scheduler and router events with a recent history
+// =============================================================================
+
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type identifies what happened
+type Type string
+
+const (
+	TaskScheduled  Type = "task.scheduled"
+	TaskDispatched Type = "task.dispatched"
+	TaskCompleted  Type = "task.completed"
+	TaskRetrying   Type = "task.retrying"
+	TaskFailed     Type = "task.failed"
+	TaskExpired    Type = "task.expired"
+	TaskCancelled  Type = "task.cancelled"
+	TaskNoted      Type = "task.noted"
+	TaskStarved    Type = "task.starved"
+	TaskRequeued   Type = "task.requeued"
+	TaskSuspended  Type = "task.suspended"
+	TaskResumed    Type = "task.resumed"
+
+	// TaskAgentResult is one agent's result of a task still waiting on
+	// the others before its results are aggregated
+	TaskAgentResult Type = "task.agent_result"
+
+	PipelineCompleted Type = "pipeline.completed"
+	PipelineFailed    Type = "pipeline.failed"
+	GroupCompleted    Type = "group.completed"
+	GroupFailed       Type = "group.failed"
+
+	AgentOffline        Type = "agent.offline"
+	ProviderCircuitOpen Type = "provider.circuit_open"
+)
+
+// Event is a single occurrence published on the bus
+type Event struct {
+	ID      uint64                 `json:"id"`
+	Type    Type                   `json:"type"`
+	TaskID  string                 `json:"task_id,omitempty"`
+	Agent   string                 `json:"agent,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Time    time.Time              `json:"time"`
+}
+
+// Bus fans events out to subscribers and keeps a bounded history
+type Bus struct {
+	mu      sync.Mutex
+	seq     uint64
+	history []Event
+	size    int
+	subs    map[int]chan Event
+	nextSub int
+}
+
+// New creates a new Bus keeping up to historySize recent events
+func New(historySize int) *Bus {
+	return &Bus{
+		size: historySize,
+		subs: make(map[int]chan Event),
+	}
+}
+
+// Publish assigns the event an ID and delivers it. Slow subscribers miss
+// events rather than blocking the publisher.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.ID = b.seq
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if b.size > 0 {
+		if len(b.history) == b.size {
+			copy(b.history, b.history[1:])
+			b.history = b.history[:b.size-1]
+		}
+		b.history = append(b.history, e)
+	}
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of future events and a function that
+// unsubscribes and closes it
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextSub
+	b.nextSub++
+	ch := make(chan Event, buffer)
+	b.subs[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs, id)
+			close(ch)
+		})
+	}
+}
+
+// Since returns retained events with an ID greater than afterID
+func (b *Bus) Since(afterID uint64) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]Event, 0)
+	for _, e := range b.history {
+		if e.ID > afterID {
+			out = append(out, e)
+		}
+	}
+	return out
+}
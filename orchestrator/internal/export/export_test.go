@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+var day = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// knownTasks returns a store with three finished tasks, the first of
+// them finished before day
+func knownTasks() *store.MemoryStore {
+	st := store.NewMemory()
+	st.AddFinished(store.TaskRecord{
+		ID: "old", Type: "question", Status: store.StatusCompleted,
+		CreatedAt: day.Add(-2 * time.Hour), CompletedAt: day.Add(-time.Hour),
+	})
+	st.AddFinished(store.TaskRecord{
+		ID: "t1", Type: "code_review", Priority: 2, Status: store.StatusCompleted,
+		Agents: []string{"review", "security"}, Cost: 0.125,
+		WaitTime: 1500 * time.Millisecond, ExecTime: 4 * time.Second,
+		CreatedAt: day, CompletedAt: day.Add(5 * time.Second),
+	})
+	st.AddFinished(store.TaskRecord{
+		ID: "t2", Type: "question", Priority: 1, Status: store.StatusFailed,
+		CreatedAt: day, CompletedAt: day.Add(time.Minute),
+	})
+	return st
+}
+
+func TestExportCSV(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := Export(context.Background(), knownTasks(), day, FormatCSV, &buf)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("exported %d records, want the 2 finished since", n)
+	}
+
+	want := strings.Join([]string{
+		"id,type,priority,status,wait_ms,exec_ms,agents,cost,created_at,completed_at",
+		"t1,code_review,2,completed,1500,4000,review;security,0.125,2024-03-01T12:00:00Z,2024-03-01T12:00:05Z",
+		"t2,question,1,failed,0,0,,0,2024-03-01T12:00:00Z,2024-03-01T12:01:00Z",
+	}, "\n") + "\n"
+	if buf.String() != want {
+		t.Errorf("CSV export:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Export(context.Background(), knownTasks(), day, FormatJSONL, &buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("line 1 is not JSON: %v", err)
+	}
+	if first["id"] != "t1" || first["wait_ms"] != 1500.0 || first["exec_ms"] != 4000.0 || first["cost"] != 0.125 {
+		t.Errorf("line 1 = %v", first)
+	}
+	var second jsonlRecord
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("line 2 is not JSON: %v", err)
+	}
+	if second.ID != "t2" || second.Agents == nil || len(second.Agents) != 0 {
+		t.Errorf("line 2 = %+v, want t2 with an empty agent list", second)
+	}
+}
+
+func TestExportRejectsUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Export(context.Background(), knownTasks(), day, "xml", &buf); err == nil {
+		t.Error("Export accepted an unknown format")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q for an unknown format", buf.String())
+	}
+}
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// ErrCircuitOpen is returned for a provider whose breaker is open
+var ErrCircuitOpen = errors.New("provider circuit open")
+
+// breaker opens after a run of consecutive failures and stays open for
+// the cooldown. The first call after the cooldown is let through as a
+// trial: success closes the breaker, failure reopens it. A zero threshold
+// never opens.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	trial     bool
+}
+
+func newBreaker(cfg config.BreakerConfig) *breaker {
+	return &breaker{
+		threshold: cfg.Failures,
+		cooldown:  time.Duration(cfg.Cooldown) * time.Second,
+	}
+}
+
+// allow reports whether a call may go ahead
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openedAt.IsZero() {
+		return true
+	}
+	if b.trial || now.Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// record counts the outcome of an allowed call and reports whether it
+// opened a closed breaker. A failed trial reopens without reporting.
+func (b *breaker) record(err error, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if err == nil {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return false
+	}
+
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		opened := b.openedAt.IsZero()
+		b.openedAt = now
+		return opened
+	}
+	return false
+}
+
+// abandon ends an allowed call that never got an answer from the
+// provider, without counting it either way
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+// open reports whether the breaker is rejecting calls
+func (b *breaker) open(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !b.openedAt.IsZero() && (b.trial || now.Sub(b.openedAt) < b.cooldown)
+}
+
+// breakerFor returns the breaker shared by every model of a provider
+func (c *Client) breakerFor(provider string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[provider]
+	if !ok {
+		b = newBreaker(c.config.LLM.Breaker)
+		c.breakers[provider] = b
+	}
+	return b
+}
+
+// Healthy reports whether a provider's circuit is closed, so calls to it
+// would be attempted
+func (c *Client) Healthy(provider string) bool {
+	return !c.breakerFor(provider).open(time.Now())
+}
+
+// ProviderHealth counts the primary and fallback providers whose circuit
+// is closed. Providers never called yet count as healthy.
+func (c *Client) ProviderHealth() (healthy, total int) {
+	now := time.Now()
+	seen := make(map[string]bool)
+	chain := append([]config.ProviderConfig{c.config.LLM.Primary}, c.config.LLM.Fallback...)
+	for _, pc := range chain {
+		if pc.Provider == "" || seen[pc.Provider] {
+			continue
+		}
+		seen[pc.Provider] = true
+		total++
+		if !c.breakerFor(pc.Provider).open(now) {
+			healthy++
+		}
+	}
+	return healthy, total
+}
@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+func TestProviderHealthCountsOpenCircuits(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.Fallback = []config.ProviderConfig{{Provider: "anthropic", Model: "claude"}}
+	cfg.LLM.Breaker = config.BreakerConfig{Failures: 2, Cooldown: 60}
+	primary := &fakeProvider{name: "ollama", err: errors.New("connection refused")}
+	fallback := &fakeProvider{name: "anthropic", err: errors.New("overloaded")}
+	c := newTestClient(t, cfg, primary, fallback)
+
+	if healthy, total := c.ProviderHealth(); healthy != 2 || total != 2 {
+		t.Fatalf("health before any call = %d/%d, want 2/2", healthy, total)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := ask(c, Request{}); err == nil {
+			t.Fatal("call succeeded with every provider failing")
+		}
+	}
+	if healthy, total := c.ProviderHealth(); healthy != 0 || total != 2 {
+		t.Errorf("health with both circuits open = %d/%d, want 0/2", healthy, total)
+	}
+	if c.Healthy("ollama") {
+		t.Error("primary reported healthy with its circuit open")
+	}
+
+	_, err := ask(c, Request{})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("call with every circuit open = %v, want ErrCircuitOpen", err)
+	}
+	if n := len(primary.called()); n != 2 {
+		t.Errorf("primary called %d times, want no calls once open", n)
+	}
+}
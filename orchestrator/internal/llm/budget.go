@@ -0,0 +1,141 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// Budget errors
+var (
+	// ErrBudgetExceeded is wrapped by every BudgetError
+	ErrBudgetExceeded = errors.New("task budget exceeded")
+	ErrInvalidBudget  = errors.New("invalid budget")
+)
+
+// Budget caps what one task may spend across all of its calls, including
+// those made by retries. Zero fields are unlimited.
+type Budget struct {
+	MaxTokens int     `json:"max_tokens,omitempty" yaml:"max_tokens"`
+	MaxCost   float64 `json:"max_cost,omitempty" yaml:"max_cost"`
+}
+
+// ValidateBudget checks task-level token and cost caps
+func ValidateBudget(maxTokens int, maxCost float64) error {
+	if maxTokens < 0 {
+		return fmt.Errorf("%w: max_tokens %d is negative", ErrInvalidBudget, maxTokens)
+	}
+	if maxCost < 0 {
+		return fmt.Errorf("%w: max_cost %g is negative", ErrInvalidBudget, maxCost)
+	}
+	return nil
+}
+
+// BudgetError reports a task that has spent its budget
+type BudgetError struct {
+	TaskID string
+	Tokens int
+	Cost   float64
+	Budget Budget
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("task %s exceeded its budget: %d/%d tokens, %.4f/%.4f cost",
+		e.TaskID, e.Tokens, e.Budget.MaxTokens, e.Cost, e.Budget.MaxCost)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// spend is what a task has used so far
+type spend struct {
+	tokens int
+	cost   float64
+}
+
+// exhausted reports whether nothing of the budget is left
+func (s spend) exhausted(b Budget) bool {
+	return (b.MaxTokens > 0 && s.tokens >= b.MaxTokens) ||
+		(b.MaxCost > 0 && s.cost >= b.MaxCost)
+}
+
+// over reports whether the budget has been overrun
+func (s spend) over(b Budget) bool {
+	return (b.MaxTokens > 0 && s.tokens > b.MaxTokens) ||
+		(b.MaxCost > 0 && s.cost > b.MaxCost)
+}
+
+// checkBudget refuses a call for a task that has already spent its budget
+func (c *Client) checkBudget(req *Request) error {
+	if req.TaskID == "" || req.Budget == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	used := c.spent[req.TaskID]
+	if used.exhausted(*req.Budget) {
+		return &BudgetError{TaskID: req.TaskID, Tokens: used.tokens, Cost: used.cost, Budget: *req.Budget}
+	}
+	return nil
+}
+
+// charge adds a completion to its task's spend. Spend is tracked for any
+// request with a task ID so a budget set later still sees earlier calls.
+// The call that crosses the budget is failed, aborting the task.
+func (c *Client) charge(req *Request, resp *Response) error {
+	if req.TaskID == "" {
+		return nil
+	}
+	return c.addSpend(req.TaskID, resp.Usage.TotalTokens, resp.Cost, req.Budget)
+}
+
+// ChargeTask adds what an agent reported spending on a task's own LLM
+// calls to the task's spend, as charge does for calls made here. It
+// returns a BudgetError when this charge overruns budget.
+func (c *Client) ChargeTask(taskID string, usage Usage, cost float64, budget *Budget) error {
+	if taskID == "" {
+		return nil
+	}
+	return c.addSpend(taskID, usage.TotalTokens, cost, budget)
+}
+
+func (c *Client) addSpend(taskID string, tokens int, cost float64, budget *Budget) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	used := c.spent[taskID]
+	used.tokens += tokens
+	used.cost += cost
+	c.spent[taskID] = used
+
+	if budget != nil && used.over(*budget) {
+		return &BudgetError{TaskID: taskID, Tokens: used.tokens, Cost: used.cost, Budget: *budget}
+	}
+	return nil
+}
+
+// TaskSpend returns the tokens and cost charged to a task so far
+func (c *Client) TaskSpend(taskID string) (int, float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	used := c.spent[taskID]
+	return used.tokens, used.cost
+}
+
+// ReleaseTask forgets a task's spend once it has finished for good
+func (c *Client) ReleaseTask(taskID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.spent, taskID)
+}
+
+// cost prices a completion using the provider's per-1K-token rates
+func cost(pc config.ProviderConfig, usage Usage) float64 {
+	return float64(usage.PromptTokens)/1000*pc.InputCost + float64(usage.CompletionTokens)/1000*pc.OutputCost
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTaskOverTokenCapIsAborted(t *testing.T) {
+	p := &fakeProvider{name: "ollama", usage: Usage{TotalTokens: 60}}
+	c := newTestClient(t, testConfig(), p)
+	budget := &Budget{MaxTokens: 100}
+
+	if _, err := ask(c, Request{TaskID: "t-1", Budget: budget}); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+
+	// The call that crosses the cap fails
+	_, err := ask(c, Request{TaskID: "t-1", Budget: budget})
+	var be *BudgetError
+	if !errors.As(err, &be) || !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("second call = %v, want a BudgetError", err)
+	}
+	if be.TaskID != "t-1" || be.Tokens != 120 {
+		t.Errorf("budget error = %+v, want t-1 at 120 tokens", be)
+	}
+
+	// Later calls, as from a retry, are refused before reaching the provider
+	if _, err := ask(c, Request{TaskID: "t-1", Budget: budget}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("call after the cap = %v, want ErrBudgetExceeded", err)
+	}
+	if n := len(p.called()); n != 2 {
+		t.Errorf("provider called %d times, want 2", n)
+	}
+
+	// Other tasks have their own spend
+	if _, err := ask(c, Request{TaskID: "t-2", Budget: budget}); err != nil {
+		t.Errorf("another task: %v", err)
+	}
+}
+
+func TestChargeTaskAggregatesReportedUsage(t *testing.T) {
+	c := newTestClient(t, testConfig())
+	budget := &Budget{MaxTokens: 100}
+
+	if err := c.ChargeTask("t-1", Usage{TotalTokens: 70}, 0.01, budget); err != nil {
+		t.Fatalf("first charge: %v", err)
+	}
+	if err := c.ChargeTask("t-1", Usage{TotalTokens: 40}, 0.01, budget); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("second charge = %v, want ErrBudgetExceeded", err)
+	}
+	if tokens, cost := c.TaskSpend("t-1"); tokens != 110 || cost != 0.02 {
+		t.Errorf("spend = %d tokens, %g cost; want 110 and 0.02", tokens, cost)
+	}
+
+	c.ReleaseTask("t-1")
+	if tokens, _ := c.TaskSpend("t-1"); tokens != 0 {
+		t.Errorf("spend after release = %d, want 0", tokens)
+	}
+}
+
+func TestValidateBudget(t *testing.T) {
+	if err := ValidateBudget(0, 0); err != nil {
+		t.Errorf("unlimited budget rejected: %v", err)
+	}
+	if err := ValidateBudget(-1, 0); !errors.Is(err, ErrInvalidBudget) {
+		t.Errorf("negative tokens = %v, want ErrInvalidBudget", err)
+	}
+	if err := ValidateBudget(0, -0.5); !errors.Is(err, ErrInvalidBudget) {
+		t.Errorf("negative cost = %v, want ErrInvalidBudget", err)
+	}
+}
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// Cassette modes
+const (
+	CassetteOff    = "off"
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// ErrCassetteMiss is returned in replay mode for a request with no
+// recorded response
+var ErrCassetteMiss = errors.New("no recorded response for request")
+
+// cassetteKey is the part of a request that identifies it on disk
+type cassetteKey struct {
+	Provider    string    `json:"provider"`
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature"`
+	TopP        float64   `json:"top_p,omitempty"`
+	MaxTokens   int       `json:"max_tokens"`
+	Stop        []string  `json:"stop,omitempty"`
+}
+
+// cassette is one recorded request/response pair
+type cassette struct {
+	Request  cassetteKey `json:"request"`
+	Response *Response   `json:"response"`
+}
+
+// cassetteProvider records a provider's responses to disk, or serves them
+// back without calling the provider. Requests are matched by a hash of
+// provider, model and the fitted request.
+type cassetteProvider struct {
+	Provider
+	mode string
+	dir  string
+}
+
+// withCassette wraps a provider according to the configured mode
+func withCassette(p Provider, cfg config.CassetteConfig) (Provider, error) {
+	switch cfg.Mode {
+	case "", CassetteOff:
+		return p, nil
+	case CassetteRecord, CassetteReplay:
+		return &cassetteProvider{Provider: p, mode: cfg.Mode, dir: cfg.Dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown cassette mode: %s", cfg.Mode)
+	}
+}
+
+func (p *cassetteProvider) Complete(ctx context.Context, model string, req *Request) (*Response, error) {
+	key := cassetteKey{
+		Provider:    p.Name(),
+		Model:       model,
+		Messages:    req.Messages,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		MaxTokens:   req.MaxTokens,
+		Stop:        req.Stop,
+	}
+	raw, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	path := filepath.Join(p.dir, hex.EncodeToString(sum[:])+".json")
+
+	if p.mode == CassetteReplay {
+		return p.replay(path, key)
+	}
+
+	resp, err := p.Provider.Complete(ctx, model, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.record(path, cassette{Request: key, Response: resp}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (p *cassetteProvider) replay(path string, key cassetteKey) (*Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s/%s (%s)", ErrCassetteMiss, key.Provider, key.Model, filepath.Base(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil || c.Response == nil {
+		return nil, fmt.Errorf("invalid cassette %s", path)
+	}
+	return c.Response, nil
+}
+
+func (p *cassetteProvider) record(path string, c cassette) error {
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette dir: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
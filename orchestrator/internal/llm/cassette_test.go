@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// cassetteClient creates a client whose ollama provider goes through a
+// cassette in dir
+func cassetteClient(t *testing.T, mode, dir string, p *fakeProvider) *Client {
+	t.Helper()
+	wrapped, err := withCassette(p, config.CassetteConfig{Mode: mode, Dir: dir})
+	if err != nil {
+		t.Fatalf("withCassette: %v", err)
+	}
+	c := newTestClient(t, testConfig())
+	c.SetProvider("ollama", wrapped)
+	return c
+}
+
+func TestCassetteRecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	live := &fakeProvider{name: "ollama", usage: Usage{PromptTokens: 3, CompletionTokens: 2}}
+
+	recorded, err := ask(cassetteClient(t, CassetteRecord, dir, live), Request{Temperature: 0.2})
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if len(live.called()) != 1 {
+		t.Fatalf("provider called %d times while recording, want 1", len(live.called()))
+	}
+
+	// Replay never reaches the provider, which would fail now
+	offline := &fakeProvider{name: "ollama", err: errors.New("no network")}
+	replayed, err := ask(cassetteClient(t, CassetteReplay, dir, offline), Request{Temperature: 0.2})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(offline.called()) != 0 {
+		t.Error("replay called the provider")
+	}
+	if replayed.Content != recorded.Content || replayed.Usage != recorded.Usage {
+		t.Errorf("replayed %+v, want the recorded %+v", replayed, recorded)
+	}
+}
+
+func TestCassetteReplayMissFails(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ask(cassetteClient(t, CassetteRecord, dir, &fakeProvider{name: "ollama"}), Request{Temperature: 0.2}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	// A different temperature is a different request
+	c := cassetteClient(t, CassetteReplay, dir, &fakeProvider{name: "ollama"})
+	if _, err := ask(c, Request{Temperature: 0.9}); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("unmatched replay = %v, want ErrCassetteMiss", err)
+	}
+}
+
+func TestCassetteModes(t *testing.T) {
+	p := &fakeProvider{name: "ollama"}
+	for _, mode := range []string{"", CassetteOff} {
+		if got, err := withCassette(p, config.CassetteConfig{Mode: mode}); err != nil || got != Provider(p) {
+			t.Errorf("mode %q wrapped the provider (%v)", mode, err)
+		}
+	}
+	if _, err := withCassette(p, config.CassetteConfig{Mode: "rewind"}); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// attemptContext bounds the call to the i-th of n providers in a chain
+// by llm.fallback_timeout. The attempt never outlives ctx.
+func (c *Client) attemptContext(ctx context.Context, i, n int) (context.Context, context.CancelFunc) {
+	var remaining time.Duration
+	deadline, hasDeadline := ctx.Deadline()
+	if hasDeadline {
+		remaining = time.Until(deadline)
+	}
+	slice := attemptSlice(c.config.LLM.FallbackTimeout, remaining, hasDeadline, i, n)
+	if slice <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, slice)
+}
+
+// attemptSlice is how long the i-th of n attempts may take, given the
+// time remaining before the request's deadline, if it has one. 0 leaves
+// the attempt bounded by the deadline alone.
+func attemptSlice(cfg config.FallbackTimeoutConfig, remaining time.Duration, hasDeadline bool, i, n int) time.Duration {
+	if i >= n-1 {
+		return 0
+	}
+	var slice time.Duration
+	switch cfg.Policy {
+	case "even":
+		if !hasDeadline {
+			return 0
+		}
+		slice = remaining / time.Duration(n-i)
+	case "fixed":
+		slice = time.Duration(cfg.Slice) * time.Second
+	default:
+		return 0
+	}
+	if hasDeadline && slice >= remaining {
+		return 0
+	}
+	return slice
+}
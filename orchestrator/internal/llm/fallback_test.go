@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// slowProvider records how long each call was given, then hangs until
+// its context ends unless it answers
+type slowProvider struct {
+	name    string
+	answers bool
+
+	mu     sync.Mutex
+	budget []time.Duration
+}
+
+func (p *slowProvider) Name() string { return p.name }
+
+func (p *slowProvider) Complete(ctx context.Context, model string, req *Request) (*Response, error) {
+	deadline, _ := ctx.Deadline()
+	p.mu.Lock()
+	p.budget = append(p.budget, time.Until(deadline))
+	p.mu.Unlock()
+
+	if p.answers {
+		return &Response{Content: "ok from " + p.name, Model: model}, nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (p *slowProvider) given() []time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]time.Duration(nil), p.budget...)
+}
+
+func TestEvenSplitLeavesTimeForLastFallback(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.Fallback = []config.ProviderConfig{
+		{Provider: "anthropic", Model: "claude"},
+		{Provider: "openai", Model: "gpt"},
+	}
+	cfg.LLM.FallbackTimeout = config.FallbackTimeoutConfig{Policy: "even"}
+	primary := &slowProvider{name: "ollama"}
+	second := &slowProvider{name: "anthropic"}
+	last := &slowProvider{name: "openai", answers: true}
+	c := New(cfg, zap.NewNop())
+	for _, p := range []*slowProvider{primary, second, last} {
+		c.SetProvider(p.name, p)
+	}
+
+	const total = 600 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), total)
+	defer cancel()
+	resp, err := c.Complete(ctx, &Request{Messages: []Message{{Role: "user", Content: "hello"}}})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Provider != "openai" {
+		t.Errorf("answered by %s, want the last fallback", resp.Provider)
+	}
+
+	// Each gets a third of the budget: the first its share of the
+	// whole, the others half and all of what is left
+	slice := total / 3
+	for _, p := range []*slowProvider{primary, second, last} {
+		got := p.given()
+		if len(got) != 1 {
+			t.Fatalf("%s called %d times, want once", p.name, len(got))
+		}
+		if got[0] > slice || got[0] < slice-100*time.Millisecond {
+			t.Errorf("%s given %s, want about %s", p.name, got[0], slice)
+		}
+	}
+}
+
+func TestAttemptSlice(t *testing.T) {
+	even := config.FallbackTimeoutConfig{Policy: "even"}
+	fixed := config.FallbackTimeoutConfig{Policy: "fixed", Slice: 2}
+	tests := []struct {
+		name        string
+		cfg         config.FallbackTimeoutConfig
+		remaining   time.Duration
+		hasDeadline bool
+		i           int
+		want        time.Duration
+	}{
+		{"none leaves the deadline", config.FallbackTimeoutConfig{Policy: "none"}, 9 * time.Second, true, 0, 0},
+		{"even first of three", even, 9 * time.Second, true, 0, 3 * time.Second},
+		{"even second of three", even, 6 * time.Second, true, 1, 3 * time.Second},
+		{"even last gets the rest", even, 3 * time.Second, true, 2, 0},
+		{"even without a deadline", even, 0, false, 0, 0},
+		{"fixed slice", fixed, 9 * time.Second, true, 0, 2 * time.Second},
+		{"fixed without a deadline", fixed, 0, false, 1, 2 * time.Second},
+		{"fixed past the deadline", fixed, time.Second, true, 0, 0},
+		{"fixed last gets the rest", fixed, 9 * time.Second, true, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := attemptSlice(tt.cfg, tt.remaining, tt.hasDeadline, tt.i, 3); got != tt.want {
+				t.Errorf("attemptSlice = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,502 @@
+// =============================================================================
+// ODIN v7.0 - LLM Client
+// =============================================================================
+// Provider-agnostic completion client with fallback and rate limiting
+// =============================================================================
+
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// LLM errors
+var (
+	ErrUnknownProvider = errors.New("unknown provider")
+	ErrNoProviders     = errors.New("no providers configured")
+)
+
+// Message is a single turn in a conversation
+type Message struct {
+	Role    string `json:"role"` // system, user, assistant
+	Content string `json:"content"`
+}
+
+// Usage holds token accounting for a completion
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Request is a provider-agnostic completion request
+type Request struct {
+	Messages []Message
+
+	// Temperature, TopP, MaxTokens and Stop are sent as given when set.
+	// Left zero, they are resolved from Params, then the provider's
+	// configured params, then those of the task type.
+	Temperature float64
+	TopP        float64
+	MaxTokens   int
+	Stop        []string
+
+	// Params are the task's own generation parameters, taking precedence
+	// over the configured ones
+	Params config.GenerationParams
+
+	// Optional per-task overrides of the configured primary provider
+	Provider string
+	Model    string
+
+	// Context is rendered into the prompt and trimmed to fit the model's
+	// context window; see ContextItems
+	Context []ContextItem
+
+	// TaskID charges the call to a task; Budget, when set, caps that
+	// task's total spend
+	TaskID string
+	Budget *Budget
+
+	// TaskType selects the provider profile configured for the type
+	TaskType string
+}
+
+// Response is a provider-agnostic completion result
+type Response struct {
+	Content      string        `json:"content"`
+	Model        string        `json:"model"`
+	Provider     string        `json:"provider"`
+	Usage        Usage         `json:"usage"`
+	Latency      time.Duration `json:"latency"`
+	FinishReason string        `json:"finish_reason"`
+
+	// Cost is priced from the provider's configured per-1K-token rates
+	Cost float64 `json:"cost"`
+
+	// Prompt describes the prompt actually sent after context trimming
+	Prompt PromptReport `json:"prompt"`
+}
+
+// Provider is implemented by every LLM backend
+type Provider interface {
+	Name() string
+	Complete(ctx context.Context, model string, req *Request) (*Response, error)
+}
+
+// ProviderFactory builds a provider from its configuration, calling out
+// on client
+type ProviderFactory func(pc config.ProviderConfig, client *http.Client) Provider
+
+// providers maps provider names to their factories
+var providers = map[string]ProviderFactory{
+	"ollama":    newOllama,
+	"anthropic": newAnthropic,
+	"openai":    newOpenAICompatible("https://api.openai.com/v1"),
+	"groq":      newOpenAICompatible("https://api.groq.com/openai/v1"),
+	"together":  newOpenAICompatible("https://api.together.xyz/v1"),
+	"deepseek":  newOpenAICompatible("https://api.deepseek.com/v1"),
+	"mistral":   newOpenAICompatible("https://api.mistral.ai/v1"),
+	"xai":       newOpenAICompatible("https://api.x.ai/v1"),
+	"vllm":      newOpenAICompatible("http://localhost:8000/v1"),
+	"custom":    newOpenAICompatible("http://localhost:8000/v1"),
+}
+
+// KnownProvider reports whether a provider name is supported
+func KnownProvider(name string) bool {
+	_, ok := providers[name]
+	return ok
+}
+
+// ValidateOverride checks a task-level provider/model override
+func ValidateOverride(provider, model string) error {
+	if provider == "" {
+		return nil
+	}
+	if !KnownProvider(provider) {
+		return fmt.Errorf("%w: %s", ErrUnknownProvider, provider)
+	}
+	return nil
+}
+
+// Client dispatches completions to configured providers
+type Client struct {
+	config *config.Config
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	instances map[string]Provider // by instanceKey
+	overrides map[string]Provider // set with SetProvider, by provider name
+	limiters  map[string]*rateLimiter
+	breakers  map[string]*breaker
+	slots     map[string]semaphore
+	spent     map[string]spend
+	calls     map[callKey]*callStats
+
+	// Receives provider events; nil drops them
+	events *events.Bus
+}
+
+// New creates a new LLM Client instance
+func New(cfg *config.Config, logger *zap.Logger) *Client {
+	return &Client{
+		config:    cfg,
+		logger:    logger,
+		instances: make(map[string]Provider),
+		overrides: make(map[string]Provider),
+		limiters:  make(map[string]*rateLimiter),
+		breakers:  make(map[string]*breaker),
+		slots:     make(map[string]semaphore),
+		spent:     make(map[string]spend),
+		calls:     make(map[callKey]*callStats),
+	}
+}
+
+// SetProvider installs a provider instance under a name, replacing any
+// instance built from config
+func (c *Client) SetProvider(name string, p Provider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.overrides[name] = p
+}
+
+// SetEvents attaches an event bus that provider circuit openings are
+// published to. Set it before the client is used.
+func (c *Client) SetEvents(bus *events.Bus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.events = bus
+}
+
+// Complete runs a completion. A request carrying a provider override goes
+// to that provider only; otherwise the profile for the task type, if any,
+// is tried first, then the primary, then each fallback in order, each
+// attempt bounded by llm.fallback_timeout. A request for a task that has
+// spent its budget fails with a *BudgetError before any provider is called.
+func (c *Client) Complete(ctx context.Context, req *Request) (*Response, error) {
+	chain, err := c.resolve(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkBudget(req); err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for i, pc := range chain {
+		attemptCtx, cancel := c.attemptContext(ctx, i, len(chain))
+		resp, err := c.call(attemptCtx, pc, req)
+		cancel()
+		if err == nil {
+			if err := c.charge(req, resp); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+		lastErr = err
+		c.logger.Warn("Provider call failed",
+			zap.String("provider", pc.Provider),
+			zap.String("model", pc.Model),
+			zap.Error(err),
+		)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// resolve returns the ordered provider chain for a request
+func (c *Client) resolve(req *Request) ([]config.ProviderConfig, error) {
+	if req.Provider != "" {
+		if err := ValidateOverride(req.Provider, req.Model); err != nil {
+			return nil, err
+		}
+		pc := c.lookup(req.Provider)
+		if req.Model != "" {
+			pc.Model = req.Model
+		}
+		return []config.ProviderConfig{pc}, nil
+	}
+
+	chain := make([]config.ProviderConfig, 0, 2+len(c.config.LLM.Fallback))
+	profile, hasProfile := c.profile(req.TaskType)
+	if hasProfile {
+		if req.Model != "" {
+			profile.Model = req.Model
+		}
+		chain = append(chain, profile)
+		c.logger.Debug("Using provider profile",
+			zap.String("task_type", req.TaskType),
+			zap.String("provider", profile.Provider),
+			zap.String("model", profile.Model),
+		)
+	}
+	if c.config.LLM.Primary.Provider != "" {
+		primary := c.config.LLM.Primary
+		if req.Model != "" {
+			primary.Model = req.Model
+		}
+		if !hasProfile || primary.Provider != profile.Provider || primary.Model != profile.Model {
+			chain = append(chain, primary)
+		}
+	}
+	chain = append(chain, c.config.LLM.Fallback...)
+	if len(chain) == 0 {
+		return nil, ErrNoProviders
+	}
+	return chain, nil
+}
+
+// Providers lists the provider/model pairs a request would be tried on,
+// in order, without calling any of them
+func (c *Client) Providers(req *Request) ([]string, error) {
+	chain, err := c.resolve(req)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(chain))
+	for i, pc := range chain {
+		out[i] = pc.Provider + "/" + pc.Model
+	}
+	return out, nil
+}
+
+// profile returns the provider configured for a task type. A profile
+// without a provider uses the primary's provider, and its model when none
+// is given; credentials and transport the profile leaves out come from
+// lookup.
+func (c *Client) profile(taskType string) (config.ProviderConfig, bool) {
+	pc, ok := c.config.LLM.Profiles[taskType]
+	if taskType == "" || !ok {
+		return config.ProviderConfig{}, false
+	}
+
+	primary := c.config.LLM.Primary
+	if pc.Provider == "" {
+		pc.Provider = primary.Provider
+	}
+	if pc.Model == "" && pc.Provider == primary.Provider {
+		pc.Model = primary.Model
+	}
+	known := c.lookup(pc.Provider)
+	if pc.APIKey == "" {
+		pc.APIKey = known.APIKey
+	}
+	if pc.BaseURL == "" {
+		pc.BaseURL = known.BaseURL
+	}
+	if pc.Transport.IsZero() {
+		pc.Transport = known.Transport
+	}
+	return pc, true
+}
+
+// lookup finds configured settings (key, base URL, transport) for a provider name so
+// overrides reuse credentials, falling back to environment defaults
+func (c *Client) lookup(name string) config.ProviderConfig {
+	candidates := append([]config.ProviderConfig{c.config.LLM.Primary}, c.config.LLM.Fallback...)
+	candidates = append(candidates, c.config.LLM.Consensus.Providers...)
+	for _, pc := range candidates {
+		if pc.Provider == name {
+			return pc
+		}
+	}
+	return config.ProviderConfig{Provider: name, APIKey: config.APIKeyFor(name)}
+}
+
+// call performs a single rate- and concurrency-limited provider call
+func (c *Client) call(ctx context.Context, pc config.ProviderConfig, req *Request) (*Response, error) {
+	provider, limiter, err := c.instance(pc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Oversized prompts are refused before taking a slot or rate budget.
+	// Parameters come first, as the completion's size is reserved.
+	fitted, report := fit(c.withParams(req, pc), pc, c.config.LLM.Context)
+	if err := c.checkSize(pc, fitted, report.Tokens); err != nil {
+		return nil, err
+	}
+
+	breaker := c.breakerFor(pc.Provider)
+	if !breaker.allow(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, pc.Provider)
+	}
+
+	// Queue for a concurrency slot before spending rate limit budget
+	slots := c.slotsFor(pc)
+	if err := slots.Acquire(ctx); err != nil {
+		breaker.abandon()
+		return nil, err
+	}
+	defer slots.Release()
+
+	if err := limiter.Wait(ctx); err != nil {
+		breaker.abandon()
+		return nil, err
+	}
+
+	if report.Trimmed() {
+		c.logger.Info("Trimmed prompt context",
+			zap.String("provider", pc.Provider),
+			zap.String("strategy", report.Strategy),
+			zap.Int("tokens", report.Tokens),
+			zap.Int("budget", report.Budget),
+			zap.Strings("dropped", report.Dropped),
+		)
+	}
+
+	start := time.Now()
+	resp, err := provider.Complete(ctx, pc.Model, fitted)
+	if err == nil || ctx.Err() == nil {
+		c.observe(pc.Provider, pc.Model, time.Since(start), err)
+	}
+	if err != nil && ctx.Err() != nil {
+		// A caller giving up says nothing about the provider
+		breaker.abandon()
+	} else if breaker.record(err, time.Now()) {
+		c.logger.Warn("Provider circuit opened",
+			zap.String("provider", pc.Provider),
+			zap.Error(err),
+		)
+		c.events.Publish(events.Event{
+			Type:    events.ProviderCircuitOpen,
+			Message: err.Error(),
+			Data:    map[string]interface{}{"provider": pc.Provider},
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Latency = time.Since(start)
+	resp.Prompt = report
+	resp.Cost = cost(pc, resp.Usage)
+	c.observeSize(pc, fitted, report.Tokens, resp)
+	if resp.Provider == "" {
+		resp.Provider = pc.Provider
+	}
+	return resp, nil
+}
+
+// instance returns the cached provider and limiter for a config entry.
+// Limiters are keyed by provider name so every model and every task-level
+// override of a provider shares the same quota.
+func (c *Client) instance(pc config.ProviderConfig) (Provider, *rateLimiter, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := c.instanceKey(pc)
+	p, ok := c.overrides[pc.Provider]
+	if !ok {
+		p, ok = c.instances[key]
+	}
+	if !ok {
+		factory, known := providers[pc.Provider]
+		if !known {
+			return nil, nil, fmt.Errorf("%w: %s", ErrUnknownProvider, pc.Provider)
+		}
+		tc := c.transportFor(pc)
+		client, err := newHTTPClient(tc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s transport: %w", pc.Provider, err)
+		}
+		if tc.InsecureSkipVerify {
+			c.logger.Warn("Provider certificates are not verified", zap.String("provider", pc.Provider))
+		}
+		wrapped, err := withCassette(factory(pc, client), c.config.LLM.Cassette)
+		if err != nil {
+			return nil, nil, err
+		}
+		p = wrapped
+		c.instances[key] = p
+	}
+
+	limiter, ok := c.limiters[pc.Provider]
+	if !ok {
+		limiter = newRateLimiter(pc.RateLimit)
+		c.limiters[pc.Provider] = limiter
+	}
+
+	return p, limiter, nil
+}
+
+// instanceKey identifies the provider instance built for pc: configs that
+// differ in anything the instance is built from, such as the API key,
+// base URL or transport, get instances of their own
+func (c *Client) instanceKey(pc config.ProviderConfig) string {
+	data, err := json.Marshal(struct {
+		Provider  config.ProviderConfig
+		Transport config.TransportConfig
+	}{pc, c.transportFor(pc)})
+	if err != nil {
+		// Only reachable for a config json cannot encode; share by name
+		return pc.Provider
+	}
+	sum := sha256.Sum256(data)
+	return pc.Provider + ":" + hex.EncodeToString(sum[:8])
+}
+
+// WarmUp sends a one-token request to the primary, every fallback and
+// every task type profile, once per provider/model pair, so connections
+// are open and local models are loaded before real work arrives. Failures
+// are logged and reported per provider, never fatal.
+func (c *Client) WarmUp(ctx context.Context) map[string]error {
+	chain := append([]config.ProviderConfig{c.config.LLM.Primary}, c.config.LLM.Fallback...)
+	for taskType := range c.config.LLM.Profiles {
+		pc, _ := c.profile(taskType)
+		chain = append(chain, pc)
+	}
+
+	type outcome struct {
+		key string
+		err error
+	}
+
+	seen := make(map[string]bool, len(chain))
+	results := make(chan outcome, len(chain))
+	pending := 0
+	for _, pc := range chain {
+		key := pc.Provider + "/" + pc.Model
+		if pc.Provider == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		pending++
+
+		go func(pc config.ProviderConfig) {
+			req := &Request{
+				Messages:  []Message{{Role: "user", Content: "ping"}},
+				MaxTokens: 1,
+			}
+			_, err := c.call(ctx, pc, req)
+			results <- outcome{key: pc.Provider + "/" + pc.Model, err: err}
+		}(pc)
+	}
+
+	report := make(map[string]error, pending)
+	for ; pending > 0; pending-- {
+		o := <-results
+		report[o.key] = o.err
+		if o.err != nil {
+			c.logger.Warn("Provider warm-up failed", zap.String("provider", o.key), zap.Error(o.err))
+			continue
+		}
+		c.logger.Info("Provider ready", zap.String("provider", o.key))
+	}
+	return report
+}
@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// fakeProvider answers every call, recording the model it was asked for
+type fakeProvider struct {
+	name  string
+	usage Usage
+	err   error
+
+	mu     sync.Mutex
+	models []string
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Complete(ctx context.Context, model string, req *Request) (*Response, error) {
+	p.mu.Lock()
+	p.models = append(p.models, model)
+	p.mu.Unlock()
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Response{Content: "ok from " + p.name, Model: model, Usage: p.usage}, nil
+}
+
+// called returns the models the provider was called with
+func (p *fakeProvider) called() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.models...)
+}
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.LLM.Primary = config.ProviderConfig{Provider: "ollama", Model: "llama3"}
+	return cfg
+}
+
+// newTestClient creates a client with each fake installed under its name
+func newTestClient(t *testing.T, cfg *config.Config, fakes ...*fakeProvider) *Client {
+	t.Helper()
+	c := New(cfg, zap.NewNop())
+	for _, p := range fakes {
+		c.SetProvider(p.name, p)
+	}
+	return c
+}
+
+// ask sends a one-message request
+func ask(c *Client, req Request) (*Response, error) {
+	req.Messages = []Message{{Role: "user", Content: "hello"}}
+	return c.Complete(context.Background(), &req)
+}
+
+func TestTaskOverrideCallsItsProvider(t *testing.T) {
+	primary := &fakeProvider{name: "ollama"}
+	override := &fakeProvider{name: "anthropic"}
+	c := newTestClient(t, testConfig(), primary, override)
+
+	resp, err := ask(c, Request{Provider: "anthropic", Model: "claude-big"})
+	if err != nil {
+		t.Fatalf("Complete with override: %v", err)
+	}
+	if resp.Provider != "anthropic" || resp.Model != "claude-big" {
+		t.Errorf("override answered by %s/%s, want anthropic/claude-big", resp.Provider, resp.Model)
+	}
+
+	if _, err := ask(c, Request{}); err != nil {
+		t.Fatalf("Complete without override: %v", err)
+	}
+	if got := primary.called(); len(got) != 1 || got[0] != "llama3" {
+		t.Errorf("primary called with %v, want once with llama3", got)
+	}
+	if got := override.called(); len(got) != 1 {
+		t.Errorf("override provider called %d times, want once", len(got))
+	}
+}
+
+func TestOverrideDoesNotFallBack(t *testing.T) {
+	primary := &fakeProvider{name: "ollama"}
+	override := &fakeProvider{name: "anthropic", err: errors.New("overloaded")}
+	c := newTestClient(t, testConfig(), primary, override)
+
+	if _, err := ask(c, Request{Provider: "anthropic"}); err == nil {
+		t.Error("override failure fell back instead of failing")
+	}
+	if got := primary.called(); len(got) != 0 {
+		t.Errorf("primary called %v for an override task", got)
+	}
+}
+
+func TestValidateOverrideRejectsUnknownProvider(t *testing.T) {
+	if err := ValidateOverride("nonesuch", "x"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("ValidateOverride(nonesuch) = %v, want ErrUnknownProvider", err)
+	}
+	if err := ValidateOverride("", "any-model"); err != nil {
+		t.Errorf("ValidateOverride without a provider = %v, want nil", err)
+	}
+	c := newTestClient(t, testConfig())
+	if _, err := ask(c, Request{Provider: "nonesuch"}); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Complete with unknown override = %v, want ErrUnknownProvider", err)
+	}
+}
@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the call latency
+// histogram
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// callKey labels provider call metrics. Models of one provider are kept
+// apart so their reliability can be compared.
+type callKey struct {
+	provider string
+	model    string
+}
+
+// callStats accumulates the calls made to one provider and model
+type callStats struct {
+	calls   uint64
+	errors  uint64
+	buckets []uint64 // cumulative counts per latencyBuckets bound
+	sum     float64  // total seconds
+
+	size sizeStats
+}
+
+// statsFor returns the stats for a provider and model, creating them.
+// Callers hold c.mu.
+func (c *Client) statsFor(provider, model string) *callStats {
+	key := callKey{provider: provider, model: model}
+	st, ok := c.calls[key]
+	if !ok {
+		st = &callStats{
+			buckets: make([]uint64, len(latencyBuckets)),
+			size:    sizeStats{buckets: make([]uint64, len(promptTokenBuckets))},
+		}
+		c.calls[key] = st
+	}
+	return st
+}
+
+// observe records a finished provider call
+func (c *Client) observe(provider, model string, latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st := c.statsFor(provider, model)
+	seconds := latency.Seconds()
+	st.calls++
+	st.sum += seconds
+	if err != nil {
+		st.errors++
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			st.buckets[i]++
+		}
+	}
+}
+
+// WriteMetrics writes provider call counts, error counts, latency
+// histograms and request and response sizes, labeled by provider and
+// model, in the Prometheus text exposition format
+func (c *Client) WriteMetrics(w io.Writer) error {
+	c.mu.Lock()
+	keys := make([]callKey, 0, len(c.calls))
+	stats := make(map[callKey]callStats, len(c.calls))
+	for key, st := range c.calls {
+		keys = append(keys, key)
+		copied := *st
+		copied.buckets = append([]uint64(nil), st.buckets...)
+		copied.size.buckets = append([]uint64(nil), st.size.buckets...)
+		stats[key] = copied
+	}
+	c.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].provider != keys[j].provider {
+			return keys[i].provider < keys[j].provider
+		}
+		return keys[i].model < keys[j].model
+	})
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "# HELP odin_llm_requests_total Provider calls made, by provider and model.")
+	fmt.Fprintln(b, "# TYPE odin_llm_requests_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(b, "odin_llm_requests_total{%s} %d\n", key.labels(), stats[key].calls)
+	}
+
+	fmt.Fprintln(b, "# HELP odin_llm_errors_total Provider calls that failed, by provider and model.")
+	fmt.Fprintln(b, "# TYPE odin_llm_errors_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(b, "odin_llm_errors_total{%s} %d\n", key.labels(), stats[key].errors)
+	}
+
+	fmt.Fprintln(b, "# HELP odin_llm_request_duration_seconds Provider call latency, by provider and model.")
+	fmt.Fprintln(b, "# TYPE odin_llm_request_duration_seconds histogram")
+	for _, key := range keys {
+		st := stats[key]
+		labels := key.labels()
+		for i, bound := range latencyBuckets {
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(b, "odin_llm_request_duration_seconds_bucket{%s,le=%q} %d\n", labels, le, st.buckets[i])
+		}
+		fmt.Fprintf(b, "odin_llm_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, st.calls)
+		fmt.Fprintf(b, "odin_llm_request_duration_seconds_sum{%s} %g\n", labels, st.sum)
+		fmt.Fprintf(b, "odin_llm_request_duration_seconds_count{%s} %d\n", labels, st.calls)
+	}
+
+	writeSizeMetrics(b, keys, stats)
+	return b.Flush()
+}
+
+// labels renders the key as Prometheus labels
+func (k callKey) labels() string {
+	return fmt.Sprintf("provider=%s,model=%s", quoteLabel(k.provider), quoteLabel(k.model))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel quotes a label value with the escaping the text format expects
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
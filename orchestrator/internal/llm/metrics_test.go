@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// flakyModel fails every call to one model and answers the rest
+type flakyModel struct {
+	failing string
+}
+
+func (p *flakyModel) Name() string { return "ollama" }
+
+func (p *flakyModel) Complete(ctx context.Context, model string, req *Request) (*Response, error) {
+	if model == p.failing {
+		return nil, errors.New("out of memory")
+	}
+	return &Response{Content: "ok", Model: model}, nil
+}
+
+func TestMetricsLabeledByModel(t *testing.T) {
+	c := newTestClient(t, testConfig())
+	c.SetProvider("ollama", &flakyModel{failing: "qwen"})
+
+	for _, req := range []Request{{}, {}, {Provider: "ollama", Model: "qwen"}} {
+		_, _ = ask(c, req)
+	}
+
+	var buf bytes.Buffer
+	if err := c.WriteMetrics(&buf); err != nil {
+		t.Fatalf("WriteMetrics: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`odin_llm_requests_total{provider="ollama",model="llama3"} 2`,
+		`odin_llm_requests_total{provider="ollama",model="qwen"} 1`,
+		`odin_llm_errors_total{provider="ollama",model="llama3"} 0`,
+		`odin_llm_errors_total{provider="ollama",model="qwen"} 1`,
+		`odin_llm_request_duration_seconds_count{provider="ollama",model="llama3"} 2`,
+		`odin_llm_request_duration_seconds_bucket{provider="ollama",model="qwen",le="+Inf"} 1`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
+
+func TestMetricLabelsEscaped(t *testing.T) {
+	c := newTestClient(t, testConfig())
+	c.observe("ollama", `we"ird\model`, 0, nil)
+
+	var buf bytes.Buffer
+	if err := c.WriteMetrics(&buf); err != nil {
+		t.Fatalf("WriteMetrics: %v", err)
+	}
+	if want := `model="we\"ird\\model"`; !strings.Contains(buf.String(), want) {
+		t.Errorf("metrics = %s, want the label escaped as %s", buf.String(), want)
+	}
+}
@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// ErrInvalidParams is returned for task generation parameters out of range
+var ErrInvalidParams = errors.New("invalid generation params")
+
+// ValidateParams checks a task's generation parameters; nil has none
+func ValidateParams(p *config.GenerationParams) error {
+	if p == nil {
+		return nil
+	}
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	return nil
+}
+
+// params resolves the generation parameters a request is sent to a
+// provider with: the task type's, overridden by the provider's, overridden
+// by the request's own
+func (c *Client) params(req *Request, pc config.ProviderConfig) config.GenerationParams {
+	return c.config.LLM.Params[req.TaskType].Merge(pc.Params).Merge(req.Params)
+}
+
+// withParams returns a copy of the request with each parameter it leaves
+// unset filled in from the resolved params
+func (c *Client) withParams(req *Request, pc config.ProviderConfig) *Request {
+	p := c.params(req, pc)
+	out := *req
+	if out.Temperature == 0 && p.Temperature != nil {
+		out.Temperature = *p.Temperature
+	}
+	if out.TopP == 0 && p.TopP != nil {
+		out.TopP = *p.TopP
+	}
+	if out.MaxTokens == 0 {
+		out.MaxTokens = p.MaxTokens
+	}
+	if len(out.Stop) == 0 {
+		out.Stop = p.Stop
+	}
+	return &out
+}
@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// ollamaStub serves the Ollama chat API, keeping the options of each
+// request it receives
+type ollamaStub struct {
+	mu      sync.Mutex
+	options []map[string]interface{}
+}
+
+func (s *ollamaStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Options map[string]interface{} `json:"options"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.options = append(s.options, body.Options)
+	s.mu.Unlock()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"model":   "llama3",
+		"message": map[string]string{"content": "ok"},
+	})
+}
+
+// last returns the options of the latest request
+func (s *ollamaStub) last() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.options[len(s.options)-1]
+}
+
+func float(v float64) *float64 { return &v }
+
+// newParamsClient talks to a stub Ollama configured with provider params
+// on top of a code_write temperature
+func newParamsClient(t *testing.T) (*Client, *ollamaStub) {
+	t.Helper()
+	stub := &ollamaStub{}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig()
+	cfg.LLM.Primary.BaseURL = srv.URL
+	cfg.LLM.Primary.Params = config.GenerationParams{TopP: float(0.9), MaxTokens: 256, Stop: []string{"```"}}
+	cfg.LLM.Params = map[string]config.GenerationParams{
+		"code_write": {Temperature: float(0.2), MaxTokens: 1024},
+	}
+	return newTestClient(t, cfg), stub
+}
+
+func TestConfiguredParamsSentToProvider(t *testing.T) {
+	c, stub := newParamsClient(t)
+	if _, err := ask(c, Request{TaskType: "code_write"}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"temperature": 0.2,
+		"top_p":       0.9,
+		"num_predict": 256.0, // the provider's beats the task type's
+		"stop":        []interface{}{"```"},
+	}
+	if got := stub.last(); !reflect.DeepEqual(got, want) {
+		t.Errorf("options = %v, want %v", got, want)
+	}
+
+	// Another type gets only the provider's params
+	if _, err := ask(c, Request{TaskType: "question"}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if got := stub.last(); got["temperature"] != 0.0 || got["top_p"] != 0.9 {
+		t.Errorf("options = %v, want the provider's top_p and no temperature", got)
+	}
+}
+
+func TestTaskParamsTakePrecedence(t *testing.T) {
+	c, stub := newParamsClient(t)
+	_, err := ask(c, Request{
+		TaskType: "code_write",
+		Params:   config.GenerationParams{Temperature: float(0), Stop: []string{"END"}},
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	got := stub.last()
+	if got["temperature"] != 0.0 || !reflect.DeepEqual(got["stop"], []interface{}{"END"}) {
+		t.Errorf("options = %v, want the task's temperature 0 and stop sequence", got)
+	}
+	if got["top_p"] != 0.9 || got["num_predict"] != 256.0 {
+		t.Errorf("options = %v, want unset task params to fall through", got)
+	}
+
+	// A field set on the request itself is sent unchanged
+	if _, err := ask(c, Request{TaskType: "code_write", MaxTokens: 50}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if got := stub.last(); got["num_predict"] != 50.0 {
+		t.Errorf("num_predict = %v, want the request's 50", got["num_predict"])
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	if err := ValidateParams(nil); err != nil {
+		t.Errorf("no params: %v", err)
+	}
+	if err := ValidateParams(&config.GenerationParams{Temperature: float(0), TopP: float(1)}); err != nil {
+		t.Errorf("params at the bounds: %v", err)
+	}
+	for _, p := range []config.GenerationParams{
+		{Temperature: float(2.5)},
+		{TopP: float(0)},
+		{MaxTokens: -1},
+	} {
+		if err := ValidateParams(&p); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("%+v: err = %v, want ErrInvalidParams", p, err)
+		}
+	}
+}
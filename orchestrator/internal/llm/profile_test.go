@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// profileConfig sends code_write to a strong coding model and question to
+// a small model on the primary's provider
+func profileConfig() *config.Config {
+	cfg := testConfig()
+	cfg.LLM.Profiles = map[string]config.ProviderConfig{
+		"code_write": {Provider: "anthropic", Model: "claude-code"},
+		"question":   {Model: "llama3-mini"},
+	}
+	return cfg
+}
+
+func TestTaskTypeSelectsItsProfile(t *testing.T) {
+	primary := &fakeProvider{name: "ollama"}
+	coder := &fakeProvider{name: "anthropic"}
+	c := newTestClient(t, profileConfig(), primary, coder)
+
+	tests := []struct {
+		taskType string
+		provider string
+		model    string
+	}{
+		{"code_write", "anthropic", "claude-code"},
+		{"question", "ollama", "llama3-mini"},
+		{"analysis", "ollama", "llama3"},
+		{"", "ollama", "llama3"},
+	}
+	for _, tt := range tests {
+		resp, err := ask(c, Request{TaskType: tt.taskType})
+		if err != nil {
+			t.Fatalf("%q: %v", tt.taskType, err)
+		}
+		if resp.Provider != tt.provider || resp.Model != tt.model {
+			t.Errorf("%q answered by %s/%s, want %s/%s", tt.taskType, resp.Provider, resp.Model, tt.provider, tt.model)
+		}
+	}
+}
+
+func TestFailedProfileFallsBackToPrimary(t *testing.T) {
+	primary := &fakeProvider{name: "ollama"}
+	coder := &fakeProvider{name: "anthropic", err: errors.New("overloaded")}
+	c := newTestClient(t, profileConfig(), primary, coder)
+
+	resp, err := ask(c, Request{TaskType: "code_write"})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Provider != "ollama" || resp.Model != "llama3" {
+		t.Errorf("answered by %s/%s, want the primary", resp.Provider, resp.Model)
+	}
+	if got := coder.called(); len(got) != 1 {
+		t.Errorf("profile called %d times, want tried once first", len(got))
+	}
+}
+
+func TestOverridesWinOverProfiles(t *testing.T) {
+	primary := &fakeProvider{name: "ollama"}
+	coder := &fakeProvider{name: "anthropic"}
+	c := newTestClient(t, profileConfig(), primary, coder)
+
+	resp, err := ask(c, Request{TaskType: "code_write", Provider: "ollama", Model: "codellama"})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Provider != "ollama" || resp.Model != "codellama" || len(coder.called()) != 0 {
+		t.Errorf("answered by %s/%s, want the override alone", resp.Provider, resp.Model)
+	}
+
+	// A model override keeps the profile's provider
+	resp, _ = ask(c, Request{TaskType: "code_write", Model: "claude-small"})
+	if resp.Provider != "anthropic" || resp.Model != "claude-small" {
+		t.Errorf("answered by %s/%s, want anthropic/claude-small", resp.Provider, resp.Model)
+	}
+}
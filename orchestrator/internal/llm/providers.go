@@ -0,0 +1,294 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// defaultTimeout bounds a single provider HTTP call
+const defaultTimeout = 300 * time.Second
+
+// postJSON sends a JSON body and decodes a JSON response
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// -----------------------------------------------------------------------------
+// Ollama
+// -----------------------------------------------------------------------------
+
+type ollamaProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newOllama(pc config.ProviderConfig, client *http.Client) Provider {
+	baseURL := pc.BaseURL
+	if baseURL == "" {
+		baseURL = os.Getenv("OLLAMA_BASE_URL")
+	}
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	return &ollamaProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+func (p *ollamaProvider) Name() string { return "ollama" }
+
+func (p *ollamaProvider) Complete(ctx context.Context, model string, req *Request) (*Response, error) {
+	options := map[string]interface{}{
+		"temperature": req.Temperature,
+	}
+	if req.TopP > 0 {
+		options["top_p"] = req.TopP
+	}
+	if req.MaxTokens > 0 {
+		options["num_predict"] = req.MaxTokens
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+
+	body := map[string]interface{}{
+		"model":    model,
+		"messages": req.Messages,
+		"stream":   false,
+		"options":  options,
+	}
+
+	var data struct {
+		Model   string `json:"model"`
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+		DoneReason      string `json:"done_reason"`
+	}
+	if err := postJSON(ctx, p.client, p.baseURL+"/api/chat", nil, body, &data); err != nil {
+		return nil, fmt.Errorf("ollama: %w", err)
+	}
+
+	return &Response{
+		Content:  data.Message.Content,
+		Model:    data.Model,
+		Provider: p.Name(),
+		Usage: Usage{
+			PromptTokens:     data.PromptEvalCount,
+			CompletionTokens: data.EvalCount,
+			TotalTokens:      data.PromptEvalCount + data.EvalCount,
+		},
+		FinishReason: data.DoneReason,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+// Anthropic
+// -----------------------------------------------------------------------------
+
+type anthropicProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func newAnthropic(pc config.ProviderConfig, client *http.Client) Provider {
+	baseURL := pc.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com/v1"
+	}
+	return &anthropicProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  pc.APIKey,
+		client:  client,
+	}
+}
+
+func (p *anthropicProvider) Name() string { return "anthropic" }
+
+func (p *anthropicProvider) Complete(ctx context.Context, model string, req *Request) (*Response, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("anthropic: ANTHROPIC_API_KEY not set")
+	}
+
+	// System prompt is a top-level field rather than a message
+	var system string
+	messages := make([]Message, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		if m.Role == "system" {
+			system = m.Content
+			continue
+		}
+		messages = append(messages, m)
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 4096
+	}
+
+	body := map[string]interface{}{
+		"model":       model,
+		"messages":    messages,
+		"max_tokens":  maxTokens,
+		"temperature": req.Temperature,
+	}
+	if system != "" {
+		body["system"] = system
+	}
+	if req.TopP > 0 {
+		body["top_p"] = req.TopP
+	}
+	if len(req.Stop) > 0 {
+		body["stop_sequences"] = req.Stop
+	}
+
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": "2023-06-01",
+	}
+
+	var data struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+		StopReason string `json:"stop_reason"`
+	}
+	if err := postJSON(ctx, p.client, p.baseURL+"/messages", headers, body, &data); err != nil {
+		return nil, fmt.Errorf("anthropic: %w", err)
+	}
+
+	var content string
+	if len(data.Content) > 0 {
+		content = data.Content[0].Text
+	}
+
+	return &Response{
+		Content:  content,
+		Model:    data.Model,
+		Provider: p.Name(),
+		Usage: Usage{
+			PromptTokens:     data.Usage.InputTokens,
+			CompletionTokens: data.Usage.OutputTokens,
+			TotalTokens:      data.Usage.InputTokens + data.Usage.OutputTokens,
+		},
+		FinishReason: data.StopReason,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+// OpenAI-compatible (openai, groq, together, deepseek, mistral, xai, vllm, custom)
+// -----------------------------------------------------------------------------
+
+type openAIProvider struct {
+	name    string
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func newOpenAICompatible(defaultURL string) ProviderFactory {
+	return func(pc config.ProviderConfig, client *http.Client) Provider {
+		baseURL := pc.BaseURL
+		if baseURL == "" {
+			baseURL = defaultURL
+		}
+		return &openAIProvider{
+			name:    pc.Provider,
+			baseURL: strings.TrimRight(baseURL, "/"),
+			apiKey:  pc.APIKey,
+			client:  client,
+		}
+	}
+}
+
+func (p *openAIProvider) Name() string { return p.name }
+
+func (p *openAIProvider) Complete(ctx context.Context, model string, req *Request) (*Response, error) {
+	body := map[string]interface{}{
+		"model":       model,
+		"messages":    req.Messages,
+		"temperature": req.Temperature,
+	}
+	if req.TopP > 0 {
+		body["top_p"] = req.TopP
+	}
+	if req.MaxTokens > 0 {
+		body["max_tokens"] = req.MaxTokens
+	}
+	if len(req.Stop) > 0 {
+		body["stop"] = req.Stop
+	}
+
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+
+	var data struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage Usage `json:"usage"`
+	}
+	if err := postJSON(ctx, p.client, p.baseURL+"/chat/completions", headers, body, &data); err != nil {
+		return nil, fmt.Errorf("%s: %w", p.name, err)
+	}
+	if len(data.Choices) == 0 {
+		return nil, fmt.Errorf("%s: empty response", p.name)
+	}
+
+	return &Response{
+		Content:      data.Choices[0].Message.Content,
+		Model:        data.Model,
+		Provider:     p.name,
+		Usage:        data.Usage,
+		FinishReason: data.Choices[0].FinishReason,
+	}, nil
+}
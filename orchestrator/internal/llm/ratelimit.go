@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces calls to at most perMinute requests per minute.
+// A zero limit disables limiting.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	rl := &rateLimiter{}
+	if perMinute > 0 {
+		rl.interval = time.Minute / time.Duration(perMinute)
+	}
+	return rl
+}
+
+// Wait blocks until the caller may issue a request or ctx is done
+func (rl *rateLimiter) Wait(ctx context.Context) error {
+	if rl.interval == 0 {
+		return nil
+	}
+
+	rl.mu.Lock()
+	now := time.Now()
+	slot := rl.next
+	if slot.Before(now) {
+		slot = now
+	}
+	rl.next = slot.Add(rl.interval)
+	rl.mu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
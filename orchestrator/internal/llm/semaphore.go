@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// semaphore caps in-flight calls to a provider; callers past the cap
+// queue until a slot frees up. A nil semaphore is unlimited.
+type semaphore chan struct{}
+
+func newSemaphore(maxConcurrent int) semaphore {
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	return make(semaphore, maxConcurrent)
+}
+
+// Acquire blocks until a slot is free or ctx is done
+func (s semaphore) Acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (s semaphore) Release() {
+	if s == nil {
+		return
+	}
+	<-s
+}
+
+// slotsFor returns the concurrency cap shared by every model of a
+// provider, sized by the first config seen for it
+func (c *Client) slotsFor(pc config.ProviderConfig) semaphore {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	slots, ok := c.slots[pc.Provider]
+	if !ok {
+		slots = newSemaphore(pc.MaxConcurrent)
+		c.slots[pc.Provider] = slots
+	}
+	return slots
+}
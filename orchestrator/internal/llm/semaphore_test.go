@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// gatedProvider holds every call until the test lets one through
+type gatedProvider struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newGatedProvider() *gatedProvider {
+	return &gatedProvider{entered: make(chan struct{}, 10), release: make(chan struct{})}
+}
+
+func (p *gatedProvider) Name() string { return "ollama" }
+
+func (p *gatedProvider) Complete(ctx context.Context, model string, req *Request) (*Response, error) {
+	p.entered <- struct{}{}
+	select {
+	case <-p.release:
+		return &Response{Content: "ok", Model: model}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// waitEntered waits for a call to reach the provider
+func (p *gatedProvider) waitEntered(t *testing.T) {
+	t.Helper()
+	select {
+	case <-p.entered:
+	case <-time.After(time.Second):
+		t.Fatal("call never reached the provider")
+	}
+}
+
+func TestThirdCallWaitsForAFreeSlot(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.Primary.MaxConcurrent = 2
+	p := newGatedProvider()
+	c := newTestClient(t, cfg)
+	c.SetProvider("ollama", p)
+
+	done := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := ask(c, Request{})
+			done <- err
+		}()
+	}
+	p.waitEntered(t)
+	p.waitEntered(t)
+
+	select {
+	case <-p.entered:
+		t.Fatal("third call reached the provider with two in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Finishing one in-flight call lets the queued one through
+	p.release <- struct{}{}
+	if err := <-done; err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	p.waitEntered(t)
+
+	p.release <- struct{}{}
+	p.release <- struct{}{}
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("call: %v", err)
+		}
+	}
+}
+
+func TestQueuedCallGivesUpWithItsContext(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.Primary.MaxConcurrent = 1
+	p := newGatedProvider()
+	c := newTestClient(t, cfg)
+	c.SetProvider("ollama", p)
+
+	go ask(c, Request{})
+	p.waitEntered(t)
+	defer close(p.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := c.Complete(ctx, &Request{Messages: []Message{{Role: "user", Content: "hello"}}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued call = %v, want its deadline exceeded", err)
+	}
+}
+
+func TestUnlimitedWithoutMaxConcurrent(t *testing.T) {
+	p := newGatedProvider()
+	c := newTestClient(t, testConfig())
+	c.SetProvider("ollama", p)
+	defer close(p.release)
+
+	for i := 0; i < 3; i++ {
+		go ask(c, Request{})
+	}
+	for i := 0; i < 3; i++ {
+		p.waitEntered(t)
+	}
+}
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// ErrRequestTooLarge is returned for a prompt over the provider's size
+// limit; the provider is never called
+var ErrRequestTooLarge = errors.New("request too large")
+
+// promptTokenBuckets are the upper bounds of the prompt size histogram
+var promptTokenBuckets = []float64{256, 1024, 4096, 16384, 65536, 262144}
+
+// sizeStats accumulates the request and response sizes of one provider
+// and model
+type sizeStats struct {
+	observed         uint64 // successful calls
+	oversized        uint64
+	requestBytes     uint64
+	responseBytes    uint64
+	promptTokens     uint64
+	completionTokens uint64
+	buckets          []uint64 // cumulative counts per promptTokenBuckets bound
+}
+
+// requestBytes is the size of a fitted request's messages
+func requestBytes(req *Request) int {
+	n := 0
+	for _, m := range req.Messages {
+		n += len(m.Content)
+	}
+	return n
+}
+
+// checkSize rejects a fitted request over the provider's token or byte
+// limit, counting the rejection
+func (c *Client) checkSize(pc config.ProviderConfig, req *Request, tokens int) error {
+	maxTokens := pc.MaxRequestTokens
+	if maxTokens <= 0 {
+		maxTokens = c.config.LLM.MaxRequestTokens
+	}
+	maxBytes := pc.MaxRequestBytes
+	if maxBytes <= 0 {
+		maxBytes = c.config.LLM.MaxRequestBytes
+	}
+
+	var err error
+	if bytes := requestBytes(req); maxBytes > 0 && bytes > maxBytes {
+		err = fmt.Errorf("%w: %d bytes exceed %s's limit of %d", ErrRequestTooLarge, bytes, pc.Provider, maxBytes)
+	} else if maxTokens > 0 && tokens > maxTokens {
+		err = fmt.Errorf("%w: %d estimated tokens exceed %s's limit of %d", ErrRequestTooLarge, tokens, pc.Provider, maxTokens)
+	}
+	if err == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.statsFor(pc.Provider, pc.Model).size.oversized++
+	return err
+}
+
+// observeSize records the sizes of a successful call. Token counts come
+// from the provider's usage report, estimated when it has none.
+func (c *Client) observeSize(pc config.ProviderConfig, req *Request, tokens int, resp *Response) {
+	prompt, completion := resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+	if prompt <= 0 {
+		prompt = tokens
+	}
+	if completion <= 0 {
+		completion = EstimateTokens(resp.Content)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := &c.statsFor(pc.Provider, pc.Model).size
+	size.observed++
+	size.requestBytes += uint64(requestBytes(req))
+	size.responseBytes += uint64(len(resp.Content))
+	size.promptTokens += uint64(prompt)
+	size.completionTokens += uint64(completion)
+	for i, bound := range promptTokenBuckets {
+		if float64(prompt) <= bound {
+			size.buckets[i]++
+		}
+	}
+}
+
+// writeSizeMetrics writes the size counters and prompt size histogram
+// for the given keys, in the order given. Prompt tokens sent are the
+// histogram's sum.
+func writeSizeMetrics(b *bufio.Writer, keys []callKey, stats map[callKey]callStats) {
+	counters := []struct {
+		name, help string
+		value      func(sizeStats) uint64
+	}{
+		{"odin_llm_oversized_requests_total", "Requests rejected for exceeding the size limit, by provider and model.",
+			func(s sizeStats) uint64 { return s.oversized }},
+		{"odin_llm_request_bytes_total", "Prompt bytes sent, by provider and model.",
+			func(s sizeStats) uint64 { return s.requestBytes }},
+		{"odin_llm_response_bytes_total", "Completion bytes received, by provider and model.",
+			func(s sizeStats) uint64 { return s.responseBytes }},
+		{"odin_llm_completion_tokens_total", "Completion tokens received, by provider and model.",
+			func(s sizeStats) uint64 { return s.completionTokens }},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(b, "# HELP %s %s\n", counter.name, counter.help)
+		fmt.Fprintf(b, "# TYPE %s counter\n", counter.name)
+		for _, key := range keys {
+			fmt.Fprintf(b, "%s{%s} %d\n", counter.name, key.labels(), counter.value(stats[key].size))
+		}
+	}
+
+	fmt.Fprintln(b, "# HELP odin_llm_prompt_tokens Prompt size of successful calls, by provider and model.")
+	fmt.Fprintln(b, "# TYPE odin_llm_prompt_tokens histogram")
+	for _, key := range keys {
+		st := stats[key]
+		labels := key.labels()
+		for i, bound := range promptTokenBuckets {
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(b, "odin_llm_prompt_tokens_bucket{%s,le=%q} %d\n", labels, le, st.size.buckets[i])
+		}
+		fmt.Fprintf(b, "odin_llm_prompt_tokens_bucket{%s,le=\"+Inf\"} %d\n", labels, st.size.observed)
+		fmt.Fprintf(b, "odin_llm_prompt_tokens_sum{%s} %d\n", labels, st.size.promptTokens)
+		fmt.Fprintf(b, "odin_llm_prompt_tokens_count{%s} %d\n", labels, st.size.observed)
+	}
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// metrics renders the client's metrics
+func metrics(t *testing.T, c *Client) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := c.WriteMetrics(&buf); err != nil {
+		t.Fatalf("WriteMetrics: %v", err)
+	}
+	return buf.String()
+}
+
+func TestOversizedPromptRejectedBeforeSending(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.MaxRequestBytes = 100
+	primary := &fakeProvider{name: "ollama"}
+	c := newTestClient(t, cfg, primary)
+
+	req := &Request{Messages: []Message{{Role: "user", Content: strings.Repeat("x", 101)}}}
+	if _, err := c.Complete(context.Background(), req); !errors.Is(err, ErrRequestTooLarge) {
+		t.Fatalf("err = %v, want ErrRequestTooLarge", err)
+	}
+	if got := primary.called(); len(got) != 0 {
+		t.Errorf("provider called with an oversized prompt")
+	}
+	if want := `odin_llm_oversized_requests_total{provider="ollama",model="llama3"} 1`; !strings.Contains(metrics(t, c), want+"\n") {
+		t.Errorf("metrics missing %s", want)
+	}
+}
+
+func TestTokenLimitRejectsPrompt(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.MaxRequestTokens = 10
+	c := newTestClient(t, cfg, &fakeProvider{name: "ollama"})
+
+	req := &Request{Messages: []Message{{Role: "user", Content: strings.Repeat("word ", 200)}}}
+	if _, err := c.Complete(context.Background(), req); !errors.Is(err, ErrRequestTooLarge) {
+		t.Errorf("err = %v, want ErrRequestTooLarge", err)
+	}
+}
+
+func TestProviderLimitLetsFallbackAnswer(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.MaxRequestBytes = 100
+	cfg.LLM.Fallback = []config.ProviderConfig{{Provider: "anthropic", Model: "claude", MaxRequestBytes: 1000}}
+	c := newTestClient(t, cfg, &fakeProvider{name: "ollama"}, &fakeProvider{name: "anthropic"})
+
+	req := &Request{Messages: []Message{{Role: "user", Content: strings.Repeat("x", 500)}}}
+	resp, err := c.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Provider != "anthropic" {
+		t.Errorf("answered by %s, want the provider with the higher limit", resp.Provider)
+	}
+}
+
+func TestCallsRecordSizeMetrics(t *testing.T) {
+	c := newTestClient(t, testConfig(), &fakeProvider{name: "ollama", usage: Usage{PromptTokens: 300, CompletionTokens: 7}})
+	for i := 0; i < 2; i++ {
+		if _, err := ask(c, Request{}); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+	}
+
+	out := metrics(t, c)
+	labels := `provider="ollama",model="llama3"`
+	for _, want := range []string{
+		`odin_llm_request_bytes_total{` + labels + `} 10`,
+		`odin_llm_response_bytes_total{` + labels + `} 28`,
+		`odin_llm_completion_tokens_total{` + labels + `} 14`,
+		`odin_llm_oversized_requests_total{` + labels + `} 0`,
+		`odin_llm_prompt_tokens_bucket{` + labels + `,le="256"} 0`,
+		`odin_llm_prompt_tokens_bucket{` + labels + `,le="1024"} 2`,
+		`odin_llm_prompt_tokens_sum{` + labels + `} 600`,
+		`odin_llm_prompt_tokens_count{` + labels + `} 2`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
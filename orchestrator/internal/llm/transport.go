@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// newHTTPClient builds the client a provider's calls go out on. Proxy
+// credentials in the URL are sent to the proxy by net/http itself.
+func newHTTPClient(tc config.TransportConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	switch tc.Proxy {
+	case "":
+		// Keep the environment's proxy settings
+	case "none":
+		transport.Proxy = nil
+	default:
+		proxy, err := url.Parse(tc.Proxy)
+		if err != nil {
+			// The parse error would repeat the URL, credentials and all
+			return nil, errors.New("invalid proxy URL")
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if tc.CAFile != "" || tc.InsecureSkipVerify {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: tc.InsecureSkipVerify,
+		}
+		if tc.CAFile != "" {
+			pem, err := os.ReadFile(tc.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			roots, err := x509.SystemCertPool()
+			if err != nil {
+				roots = x509.NewCertPool()
+			}
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", tc.CAFile)
+			}
+			tlsConfig.RootCAs = roots
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{Timeout: defaultTimeout, Transport: transport}, nil
+}
+
+// transportFor returns the provider's own transport settings, or
+// llm.transport when it sets none
+func (c *Client) transportFor(pc config.ProviderConfig) config.TransportConfig {
+	if !pc.Transport.IsZero() {
+		return pc.Transport
+	}
+	return c.config.LLM.Transport
+}
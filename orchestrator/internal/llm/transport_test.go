@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// mockProxy answers proxied calls to Ollama itself, recording where each
+// was headed and the credentials it carried
+type mockProxy struct {
+	ollamaStub
+
+	mu      sync.Mutex
+	targets []string
+	auth    []string
+}
+
+func (p *mockProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.targets = append(p.targets, r.URL.String())
+	p.auth = append(p.auth, r.Header.Get("Proxy-Authorization"))
+	p.mu.Unlock()
+	p.ollamaStub.ServeHTTP(w, r)
+}
+
+func (p *mockProxy) calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.targets)
+}
+
+// newMockProxy starts a proxy, returning its URL with credentials
+func newMockProxy(t *testing.T) (*mockProxy, string) {
+	t.Helper()
+	proxy := &mockProxy{}
+	srv := httptest.NewServer(proxy)
+	t.Cleanup(srv.Close)
+	return proxy, strings.Replace(srv.URL, "http://", "http://odin:s3cret@", 1)
+}
+
+func TestCallsRouteThroughConfiguredProxy(t *testing.T) {
+	// The configured proxy wins over the environment's
+	t.Setenv("HTTP_PROXY", "http://127.0.0.1:1")
+	proxy, proxyURL := newMockProxy(t)
+
+	cfg := testConfig()
+	cfg.LLM.Primary.BaseURL = "http://ollama.internal:11434"
+	cfg.LLM.Transport = config.TransportConfig{Proxy: proxyURL}
+	c := New(cfg, zap.NewNop())
+
+	resp, err := c.Complete(context.Background(), &Request{Messages: []Message{{Role: "user", Content: "hello"}}})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Content != "ok" {
+		t.Errorf("content = %q", resp.Content)
+	}
+
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if len(proxy.targets) != 1 || !strings.HasPrefix(proxy.targets[0], "http://ollama.internal:11434/") {
+		t.Fatalf("proxy saw %v, want one call to ollama.internal", proxy.targets)
+	}
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("odin:s3cret"))
+	if proxy.auth[0] != want {
+		t.Errorf("Proxy-Authorization = %q, want %q", proxy.auth[0], want)
+	}
+}
+
+func TestProviderTransportReplacesGlobal(t *testing.T) {
+	global, globalURL := newMockProxy(t)
+	own, ownURL := newMockProxy(t)
+
+	cfg := testConfig()
+	cfg.LLM.Primary.BaseURL = "http://ollama.internal:11434"
+	cfg.LLM.Primary.Transport = config.TransportConfig{Proxy: ownURL}
+	cfg.LLM.Transport = config.TransportConfig{Proxy: globalURL}
+	c := New(cfg, zap.NewNop())
+
+	if _, err := c.Complete(context.Background(), &Request{Messages: []Message{{Role: "user", Content: "hello"}}}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if own.calls() != 1 || global.calls() != 0 {
+		t.Errorf("provider proxy saw %d calls, global %d; want only the provider's", own.calls(), global.calls())
+	}
+}
+
+func TestTLSVerification(t *testing.T) {
+	srv := httptest.NewTLSServer(&ollamaStub{})
+	t.Cleanup(srv.Close)
+
+	complete := func(tc config.TransportConfig) error {
+		cfg := testConfig()
+		cfg.LLM.Primary.BaseURL = srv.URL
+		cfg.LLM.Transport = tc
+		_, err := New(cfg, zap.NewNop()).Complete(context.Background(), &Request{Messages: []Message{{Role: "user", Content: "hello"}}})
+		return err
+	}
+	if err := complete(config.TransportConfig{}); err == nil {
+		t.Error("a self-signed certificate was accepted")
+	}
+	if err := complete(config.TransportConfig{InsecureSkipVerify: true}); err != nil {
+		t.Errorf("insecure_skip_verify: %v", err)
+	}
+	if err := complete(config.TransportConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("a missing CA file was accepted")
+	}
+}
+
+func TestInvalidProxyURLNotEchoed(t *testing.T) {
+	_, err := newHTTPClient(config.TransportConfig{Proxy: "http://odin:s3cret@[::1"})
+	if err == nil {
+		t.Fatal("invalid proxy URL accepted")
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("error %q repeats the proxy credentials", err)
+	}
+}
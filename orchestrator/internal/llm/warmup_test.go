@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+func TestWarmUpCallsEachProviderOnce(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.Fallback = []config.ProviderConfig{
+		{Provider: "anthropic", Model: "claude"},
+		{Provider: "ollama", Model: "llama3"}, // same pair as the primary
+	}
+	cfg.LLM.Profiles = map[string]config.ProviderConfig{
+		"question":  {Model: "small"}, // primary provider, own model
+		"code_fix":  {Provider: "anthropic", Model: "claude"},
+		"code_test": {Provider: "anthropic", Model: "claude"},
+	}
+	ollama := &fakeProvider{name: "ollama"}
+	anthropic := &fakeProvider{name: "anthropic"}
+	c := newTestClient(t, cfg, ollama, anthropic)
+
+	report := c.WarmUp(context.Background())
+
+	got := ollama.called()
+	slices.Sort(got)
+	if want := []string{"llama3", "small"}; !slices.Equal(got, want) {
+		t.Errorf("ollama warmed with %v, want %v", got, want)
+	}
+	if got := anthropic.called(); !slices.Equal(got, []string{"claude"}) {
+		t.Errorf("anthropic warmed with %v, want claude once", got)
+	}
+	if len(report) != 3 {
+		t.Errorf("report = %v, want one entry per provider/model pair", report)
+	}
+	for key, err := range report {
+		if err != nil {
+			t.Errorf("%s: %v", key, err)
+		}
+	}
+}
+
+func TestWarmUpFailureIsReportedNotFatal(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.Fallback = []config.ProviderConfig{{Provider: "anthropic", Model: "claude"}}
+	down := errors.New("connection refused")
+	c := newTestClient(t, cfg, &fakeProvider{name: "ollama", err: down}, &fakeProvider{name: "anthropic"})
+
+	report := c.WarmUp(context.Background())
+	if err := report["ollama/llama3"]; !errors.Is(err, down) {
+		t.Errorf("ollama warm-up = %v, want the provider's error", err)
+	}
+	if err, ok := report["anthropic/claude"]; !ok || err != nil {
+		t.Errorf("anthropic warm-up = %v (reported %v), want ready", err, ok)
+	}
+}
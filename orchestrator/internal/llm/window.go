@@ -0,0 +1,198 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// Context trimming strategies
+const (
+	TrimOldestFirst = "oldest_first"
+	TrimRelevance   = "relevance"
+)
+
+// defaultContextWindow applies when neither the provider nor the llm.context
+// section sets a window
+const defaultContextWindow = 8192
+
+// ContextItem is one piece of task context rendered into the prompt
+type ContextItem struct {
+	Key     string
+	Content string
+	Added   time.Time
+	Score   float64 // Relevance, higher is kept longer
+}
+
+// PromptReport records how a request was fitted to the model's window
+type PromptReport struct {
+	Tokens   int      `json:"tokens"`
+	Budget   int      `json:"budget"`
+	Strategy string   `json:"strategy,omitempty"`
+	Dropped  []string `json:"dropped,omitempty"`
+}
+
+// Trimmed reports whether any context was left out
+func (r PromptReport) Trimmed() bool {
+	return len(r.Dropped) > 0
+}
+
+// EstimateTokens approximates a token count at four characters per token,
+// which is close enough for budgeting across providers
+func EstimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// ContextItems converts a task context map into prompt items. A value may
+// be a plain value, or a map with "content" and optional "added" (RFC 3339)
+// and "score" fields.
+func ContextItems(m map[string]interface{}) []ContextItem {
+	items := make([]ContextItem, 0, len(m))
+	for key, value := range m {
+		item := ContextItem{Key: key}
+
+		if fields, ok := value.(map[string]interface{}); ok {
+			if content, ok := fields["content"]; ok {
+				item.Content = render(content)
+				if added, ok := fields["added"].(string); ok {
+					item.Added, _ = time.Parse(time.RFC3339, added)
+				}
+				if score, ok := fields["score"].(float64); ok {
+					item.Score = score
+				}
+				items = append(items, item)
+				continue
+			}
+		}
+
+		item.Content = render(value)
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	return items
+}
+
+func render(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// fit renders req.Context into the prompt, dropping items per the configured
+// strategy until the prompt fits the provider's window less the completion
+// reserve. Messages are never trimmed; only context is.
+func fit(req *Request, pc config.ProviderConfig, cc config.ContextConfig) (*Request, PromptReport) {
+	window := pc.ContextWindow
+	if window <= 0 {
+		window = cc.Window
+	}
+	if window <= 0 {
+		window = defaultContextWindow
+	}
+	reserve := req.MaxTokens
+	if reserve <= 0 {
+		reserve = cc.Reserve
+	}
+
+	report := PromptReport{Budget: window - reserve}
+
+	base := 0
+	for _, m := range req.Messages {
+		base += EstimateTokens(m.Content)
+	}
+	if len(req.Context) == 0 {
+		report.Tokens = base
+		return req, report
+	}
+
+	kept := append([]ContextItem(nil), req.Context...)
+	total := base
+	for _, item := range kept {
+		total += itemTokens(item)
+	}
+
+	if total > report.Budget {
+		report.Strategy = cc.Strategy
+		if report.Strategy == "" {
+			report.Strategy = TrimOldestFirst
+		}
+		order := dropOrder(kept, report.Strategy)
+
+		dropped := make(map[string]bool)
+		for _, item := range order {
+			if total <= report.Budget {
+				break
+			}
+			dropped[item.Key] = true
+			report.Dropped = append(report.Dropped, item.Key)
+			total -= itemTokens(item)
+		}
+
+		remaining := kept[:0]
+		for _, item := range kept {
+			if !dropped[item.Key] {
+				remaining = append(remaining, item)
+			}
+		}
+		kept = remaining
+	}
+
+	out := *req
+	out.Context = nil
+	out.Messages = withContext(req.Messages, kept, report.Dropped)
+
+	report.Tokens = 0
+	for _, m := range out.Messages {
+		report.Tokens += EstimateTokens(m.Content)
+	}
+	return &out, report
+}
+
+// itemTokens is the cost of an item as rendered by withContext
+func itemTokens(item ContextItem) int {
+	return EstimateTokens(item.Key) + EstimateTokens(item.Content) + 2
+}
+
+// dropOrder returns items in the order they should be discarded
+func dropOrder(items []ContextItem, strategy string) []ContextItem {
+	order := append([]ContextItem(nil), items...)
+	switch strategy {
+	case TrimRelevance:
+		sort.SliceStable(order, func(i, j int) bool { return order[i].Score < order[j].Score })
+	default:
+		sort.SliceStable(order, func(i, j int) bool { return order[i].Added.Before(order[j].Added) })
+	}
+	return order
+}
+
+// withContext prepends a system message carrying the kept context and a
+// short note naming anything that was left out
+func withContext(messages []Message, items []ContextItem, dropped []string) []Message {
+	if len(items) == 0 && len(dropped) == 0 {
+		return messages
+	}
+
+	var b strings.Builder
+	b.WriteString("Context:\n")
+	for _, item := range items {
+		fmt.Fprintf(&b, "- %s: %s\n", item.Key, item.Content)
+	}
+	if len(dropped) > 0 {
+		fmt.Fprintf(&b, "(%d context entries omitted to fit the context window: %s)\n",
+			len(dropped), strings.Join(dropped, ", "))
+	}
+
+	out := make([]Message, 0, len(messages)+1)
+	out = append(out, Message{Role: "system", Content: b.String()})
+	return append(out, messages...)
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// bulkyContext returns four ~100 token items added a minute apart, a
+// first, with d the most relevant
+func bulkyContext() []ContextItem {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := make([]ContextItem, 0, 4)
+	for i, key := range []string{"a", "b", "c", "d"} {
+		items = append(items, ContextItem{
+			Key:     key,
+			Content: strings.Repeat(key, 400),
+			Added:   start.Add(time.Duration(i) * time.Minute),
+			Score:   float64(i),
+		})
+	}
+	return items
+}
+
+func TestFitTrimsOldestFirst(t *testing.T) {
+	req := &Request{Messages: []Message{{Role: "user", Content: "summarise"}}, Context: bulkyContext()}
+	fitted, report := fit(req, config.ProviderConfig{ContextWindow: 300}, config.ContextConfig{Reserve: 50})
+
+	if report.Budget != 250 {
+		t.Errorf("budget = %d, want window less reserve", report.Budget)
+	}
+	if report.Tokens > report.Budget {
+		t.Errorf("prompt is %d tokens, over the %d budget", report.Tokens, report.Budget)
+	}
+	if report.Strategy != TrimOldestFirst || !slices.Equal(report.Dropped, []string{"a", "b"}) {
+		t.Errorf("report = %+v, want a and b dropped oldest first", report)
+	}
+
+	prompt := fitted.Messages[0].Content
+	if strings.Contains(prompt, "aaaa") || !strings.Contains(prompt, "dddd") {
+		t.Errorf("prompt kept the wrong items:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "2 context entries omitted") {
+		t.Errorf("prompt does not say what was omitted:\n%s", prompt)
+	}
+	if fitted.Messages[1].Content != "summarise" {
+		t.Errorf("messages were changed: %+v", fitted.Messages)
+	}
+}
+
+func TestFitTrimsLeastRelevant(t *testing.T) {
+	items := bulkyContext()
+	items[0].Score = 10 // the oldest is now the most relevant
+	req := &Request{Messages: []Message{{Role: "user", Content: "go"}}, Context: items}
+	_, report := fit(req, config.ProviderConfig{ContextWindow: 300}, config.ContextConfig{Strategy: TrimRelevance})
+
+	if !slices.Equal(report.Dropped, []string{"b", "c"}) {
+		t.Errorf("dropped %v, want the two least relevant", report.Dropped)
+	}
+}
+
+func TestFitLeavesSmallContext(t *testing.T) {
+	req := &Request{Messages: []Message{{Role: "user", Content: "go"}}, Context: bulkyContext()}
+	fitted, report := fit(req, config.ProviderConfig{}, config.ContextConfig{})
+
+	if report.Trimmed() || report.Budget != defaultContextWindow {
+		t.Errorf("report = %+v, want nothing trimmed from the default window", report)
+	}
+	if !strings.Contains(fitted.Messages[0].Content, "- a: aaaa") {
+		t.Errorf("context not rendered into the prompt: %+v", fitted.Messages)
+	}
+}
+
+func TestCompleteRecordsTrimming(t *testing.T) {
+	cfg := testConfig()
+	cfg.LLM.Primary.ContextWindow = 300
+	primary := &fakeProvider{name: "ollama"}
+	c := newTestClient(t, cfg, primary)
+
+	resp, err := ask(c, Request{Context: bulkyContext()})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if !resp.Prompt.Trimmed() || resp.Prompt.Tokens > 300 {
+		t.Errorf("prompt report = %+v, want trimming recorded within the window", resp.Prompt)
+	}
+}
@@ -0,0 +1,301 @@
+// =============================================================================
+// ODIN v7.0 - Failure Explanations
+// =============================================================================
+// Pulls what the store and the agents' audit log hold about a failed task
+// into a short account of why it failed
+// =============================================================================
+
+package postmortem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/consensus"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+// ErrNotFailed is returned for tasks that have not failed or been cancelled
+var ErrNotFailed = errors.New("task has not failed")
+
+// Keys read from a failed task's result, as the agents record them
+const (
+	KeyError      = "error"
+	KeyCategory   = "category"
+	KeyAgents     = "agents"
+	KeyProvider   = "provider"
+	KeyModel      = "model"
+	KeyRetries    = "retries"
+	KeyConsensus  = "consensus"  // the consensus.Result of the task's last round
+	KeyValidation = "validation" // messages, or objects with field and message
+)
+
+// Explanation is what is known about why a task failed
+type Explanation struct {
+	TaskID   string   `json:"task_id"`
+	Type     string   `json:"type"`
+	Status   string   `json:"status"`
+	Category string   `json:"category,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Agents   []string `json:"agents,omitempty"`
+	Provider string   `json:"provider,omitempty"` // provider/model of the failing call
+	Retries  int      `json:"retries"`
+
+	// Attempts are the failed agent actions in the audit log, oldest first
+	Attempts []Attempt `json:"attempts,omitempty"`
+
+	// Consensus is set when the task's last consensus round did not agree
+	Consensus *ConsensusFailure `json:"consensus,omitempty"`
+
+	Validation []string `json:"validation,omitempty"`
+}
+
+// Attempt is one failed agent action
+type Attempt struct {
+	Agent  string    `json:"agent"`
+	Action string    `json:"action"`
+	Error  string    `json:"error"`
+	At     time.Time `json:"at"`
+}
+
+// ConsensusFailure is a consensus round that did not agree
+type ConsensusFailure struct {
+	Support    int              `json:"support"`
+	Required   int              `json:"required"`
+	Total      int              `json:"total"`
+	TimedOut   bool             `json:"timed_out,omitempty"`
+	Dissenters []consensus.Vote `json:"dissenters"`
+}
+
+// Explain gathers the result and audit log of a failed or cancelled task.
+// normalize decides which consensus votes disagreed, as the verifier
+// compared them; nil uses consensus.DefaultNormalizers.
+func Explain(ctx context.Context, st store.Store, id string, normalize consensus.Normalizer) (*Explanation, error) {
+	task, err := st.Task(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != store.StatusFailed && task.Status != store.StatusCancelled {
+		return nil, fmt.Errorf("%w: %s is %s", ErrNotFailed, id, task.Status)
+	}
+	if normalize == nil {
+		normalize, _ = consensus.NewNormalizer(consensus.DefaultNormalizers)
+	}
+
+	exp := &Explanation{TaskID: task.ID, Type: task.Type, Status: task.Status}
+
+	result, err := st.Result(ctx, id)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	var round *consensus.Result
+	if result != nil {
+		exp.Error, _ = result[KeyError].(string)
+		exp.Category, _ = result[KeyCategory].(string)
+		exp.Agents = stringList(result[KeyAgents])
+		exp.Provider = providerOf(result)
+		if n, ok := result[KeyRetries].(float64); ok {
+			exp.Retries = int(n)
+		}
+		exp.Validation = validation(result[KeyValidation])
+		round = consensusOf(result[KeyConsensus])
+	}
+
+	logs, err := st.AgentLogs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var logged *consensus.Result
+	for _, l := range logs {
+		if !l.Success {
+			exp.Attempts = append(exp.Attempts, Attempt{Agent: l.Agent, Action: l.Action, Error: l.Error, At: l.CreatedAt})
+		}
+		if r := consensusOf(l.Output[KeyConsensus]); r != nil {
+			logged = r
+		}
+	}
+	// The result holds the final round; failing that, the last one logged
+	if round == nil {
+		round = logged
+	}
+	if len(exp.Agents) == 0 {
+		exp.Agents = agentsOf(logs)
+	}
+	if exp.Error == "" && len(exp.Attempts) > 0 {
+		exp.Error = exp.Attempts[len(exp.Attempts)-1].Error
+	}
+
+	if round != nil && !round.Agreed {
+		exp.Consensus = &ConsensusFailure{
+			Support:    round.Support,
+			Required:   round.Required,
+			Total:      round.Total,
+			TimedOut:   round.TimedOut,
+			Dissenters: round.Dissenters(normalize),
+		}
+	}
+	return exp, nil
+}
+
+// Cause is a one-line root cause, naming the most specific failure known
+func (e *Explanation) Cause() string {
+	switch {
+	case e.Consensus != nil:
+		c := e.Consensus
+		names := make([]string, len(c.Dissenters))
+		for i, v := range c.Dissenters {
+			names[i] = v.Provider + "/" + v.Model
+		}
+		cause := fmt.Sprintf("consensus not reached: %d of %d providers agreed, %d needed", c.Support, c.Total, c.Required)
+		if c.TimedOut {
+			cause += " before the timeout"
+		}
+		if len(names) > 0 {
+			cause += "; disagreeing: " + strings.Join(names, ", ")
+		}
+		return cause
+	case len(e.Validation) > 0:
+		return "output failed validation: " + e.Validation[0]
+	case e.Status == store.StatusCancelled && e.Error == "":
+		return "cancelled"
+	case e.Error == "":
+		return "no error was recorded"
+	}
+	cause := e.Error
+	if e.Category != "" {
+		cause = e.Category + ": " + cause
+	}
+	if len(e.Agents) > 0 {
+		cause += " (from " + e.Agents[len(e.Agents)-1] + ")"
+	}
+	return cause
+}
+
+// Write renders the explanation for a terminal
+func (e *Explanation) Write(w io.Writer) {
+	fmt.Fprintf(w, "Task:      %s (%s)\n", e.TaskID, e.Type)
+	fmt.Fprintf(w, "Status:    %s\n", e.Status)
+	fmt.Fprintf(w, "Cause:     %s\n", e.Cause())
+	if e.Category != "" {
+		fmt.Fprintf(w, "Category:  %s\n", e.Category)
+	}
+	if len(e.Agents) > 0 {
+		fmt.Fprintf(w, "Agents:    %s\n", strings.Join(e.Agents, ", "))
+	}
+	if e.Provider != "" {
+		fmt.Fprintf(w, "Provider:  %s\n", e.Provider)
+	}
+	fmt.Fprintf(w, "Retries:   %d\n", e.Retries)
+
+	if c := e.Consensus; c != nil {
+		fmt.Fprintln(w, "\nConsensus:")
+		for _, v := range c.Dissenters {
+			fmt.Fprintf(w, "  %-32s %s\n", v.Provider+"/"+v.Model, dissent(v))
+		}
+	}
+	if len(e.Validation) > 0 {
+		fmt.Fprintln(w, "\nValidation:")
+		for _, msg := range e.Validation {
+			fmt.Fprintf(w, "  - %s\n", msg)
+		}
+	}
+	if len(e.Attempts) > 0 {
+		fmt.Fprintln(w, "\nFailed attempts:")
+		for _, a := range e.Attempts {
+			fmt.Fprintf(w, "  %s  %-12s %-12s %s\n", a.At.Format(time.RFC3339), a.Agent, a.Action, a.Error)
+		}
+	}
+}
+
+// dissent describes how a vote differed from the answer
+func dissent(v consensus.Vote) string {
+	switch {
+	case v.Error != "":
+		return "failed: " + v.Error
+	case v.Cancelled:
+		return "did not answer"
+	}
+	content := strings.Join(strings.Fields(v.Content), " ")
+	if len(content) > 60 {
+		content = content[:57] + "..."
+	}
+	return fmt.Sprintf("answered %q", content)
+}
+
+// providerOf names the provider and model a result records
+func providerOf(result map[string]interface{}) string {
+	provider, _ := result[KeyProvider].(string)
+	model, _ := result[KeyModel].(string)
+	if provider == "" || model == "" {
+		return provider
+	}
+	return provider + "/" + model
+}
+
+// agentsOf lists the agents in the audit log, in the order they first
+// appear
+func agentsOf(logs []store.AgentLog) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, l := range logs {
+		if !seen[l.Agent] {
+			seen[l.Agent] = true
+			out = append(out, l.Agent)
+		}
+	}
+	return out
+}
+
+// consensusOf decodes a consensus.Result from a JSON-decoded value
+func consensusOf(v interface{}) *consensus.Result {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var r consensus.Result
+	if err := json.Unmarshal(data, &r); err != nil || len(r.Votes) == 0 {
+		return nil
+	}
+	return &r
+}
+
+// validation reads validation failures recorded as messages or as
+// objects with a field and message
+func validation(v interface{}) []string {
+	items, _ := v.([]interface{})
+	var out []string
+	for _, item := range items {
+		switch item := item.(type) {
+		case string:
+			out = append(out, item)
+		case map[string]interface{}:
+			msg, _ := item["message"].(string)
+			if field, _ := item["field"].(string); field != "" {
+				msg = field + ": " + msg
+			}
+			if msg != "" {
+				out = append(out, msg)
+			}
+		}
+	}
+	return out
+}
+
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,143 @@
+package postmortem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/consensus"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// decoded returns v as it reads back from the store's JSON columns
+func decoded(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// newFailedStore holds a question that failed a 1-of-3 consensus round,
+// openai having answered second
+func newFailedStore(t *testing.T, second string) *store.MemoryStore {
+	t.Helper()
+	st := store.NewMemory()
+	st.AddFinished(store.TaskRecord{ID: "t-1", Type: "question", Status: store.StatusFailed, CompletedAt: epoch})
+	st.SetResult("t-1", decoded(t, map[string]interface{}{
+		KeyError:    "consensus not reached",
+		KeyCategory: "consensus",
+		KeyAgents:   []string{"retrieval", "explain"},
+		KeyRetries:  2,
+		KeyConsensus: consensus.Result{
+			Answer:   "42",
+			Support:  1,
+			Required: 2,
+			Total:    3,
+			Votes: []consensus.Vote{
+				{Provider: "ollama", Model: "llama3", Content: "42"},
+				{Provider: "openai", Model: "gpt-4o", Content: second},
+				{Provider: "anthropic", Model: "claude", Error: "rate limited"},
+			},
+		},
+	}))
+	st.AddAgentLog("t-1", store.AgentLog{Agent: "explain", Action: "answer", Error: "consensus not reached", CreatedAt: epoch})
+	return st
+}
+
+func TestConsensusFailureNamesDisagreeingProviders(t *testing.T) {
+	exp, err := Explain(context.Background(), newFailedStore(t, "forty-one"), "t-1", nil)
+	if err != nil {
+		t.Fatalf("Explain: %v", err)
+	}
+	if exp.Consensus == nil {
+		t.Fatal("no consensus failure in the explanation")
+	}
+	var dissenters []string
+	for _, v := range exp.Consensus.Dissenters {
+		dissenters = append(dissenters, v.Provider)
+	}
+	if strings.Join(dissenters, ",") != "openai,anthropic" {
+		t.Errorf("dissenters = %v, want openai and anthropic", dissenters)
+	}
+
+	want := "consensus not reached: 1 of 3 providers agreed, 2 needed; disagreeing: openai/gpt-4o, anthropic/claude"
+	if got := exp.Cause(); got != want {
+		t.Errorf("cause = %q\nwant    %q", got, want)
+	}
+	if exp.Category != "consensus" || exp.Retries != 2 || len(exp.Attempts) != 1 {
+		t.Errorf("explanation = %+v", exp)
+	}
+
+	var out bytes.Buffer
+	exp.Write(&out)
+	for _, line := range []string{
+		`openai/gpt-4o`, `answered "forty-one"`,
+		`anthropic/claude`, `failed: rate limited`,
+		"Agents:    retrieval, explain",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output lacks %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestVotesComparedWithNormalizers(t *testing.T) {
+	exp, err := Explain(context.Background(), newFailedStore(t, "  42\n"), "t-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exp.Consensus.Dissenters) != 1 || exp.Consensus.Dissenters[0].Provider != "anthropic" {
+		t.Errorf("dissenters = %+v, want only the failed vote", exp.Consensus.Dissenters)
+	}
+}
+
+func TestCauseWithoutConsensus(t *testing.T) {
+	st := store.NewMemory()
+	st.AddFinished(store.TaskRecord{ID: "t-2", Type: "code_write", Status: store.StatusFailed, CompletedAt: epoch})
+	st.SetResult("t-2", decoded(t, map[string]interface{}{
+		KeyValidation: []interface{}{map[string]string{"field": "code", "message": "is required"}},
+	}))
+	st.AddFinished(store.TaskRecord{ID: "t-3", Type: "code_write", Status: store.StatusFailed, CompletedAt: epoch})
+	st.AddAgentLog("t-3", store.AgentLog{Agent: "dev", Action: "write", Error: "provider unavailable", CreatedAt: epoch})
+
+	tests := []struct {
+		id    string
+		cause string
+	}{
+		{"t-2", "output failed validation: code: is required"},
+		{"t-3", "provider unavailable (from dev)"},
+	}
+	for _, tt := range tests {
+		exp, err := Explain(context.Background(), st, tt.id, nil)
+		if err != nil {
+			t.Fatalf("Explain %s: %v", tt.id, err)
+		}
+		if got := exp.Cause(); got != tt.cause {
+			t.Errorf("%s cause = %q, want %q", tt.id, got, tt.cause)
+		}
+	}
+}
+
+func TestOnlyFailedTasksExplained(t *testing.T) {
+	st := store.NewMemory()
+	st.AddFinished(store.TaskRecord{ID: "done", Status: store.StatusCompleted, CompletedAt: epoch})
+
+	if _, err := Explain(context.Background(), st, "done", nil); !errors.Is(err, ErrNotFailed) {
+		t.Errorf("completed task: %v, want ErrNotFailed", err)
+	}
+	if _, err := Explain(context.Background(), st, "ghost", nil); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("unknown task: %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Package redact masks sensitive data before it reaches the logs
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Mask replaces every redacted value
+const Mask = "***"
+
+// Redactor masks sensitive keys and values. Patterns are compiled once;
+// it is safe for concurrent use.
+type Redactor struct {
+	keys   *regexp.Regexp
+	values []*regexp.Regexp
+}
+
+// New compiles a redactor from config
+func New(cfg config.RedactConfig) (*Redactor, error) {
+	r := &Redactor{}
+
+	if len(cfg.Keys) > 0 {
+		keys, err := regexp.Compile("(?i)(?:" + strings.Join(cfg.Keys, ")|(?:") + ")")
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact key pattern: %w", err)
+		}
+		r.keys = keys
+	}
+	for _, pattern := range cfg.Values {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact value pattern %q: %w", pattern, err)
+		}
+		r.values = append(r.values, re)
+	}
+	return r, nil
+}
+
+// Key reports whether a field or map key is sensitive
+func (r *Redactor) Key(key string) bool {
+	return r.keys != nil && r.keys.MatchString(key)
+}
+
+// String masks sensitive substrings
+func (r *Redactor) String(s string) string {
+	for _, re := range r.values {
+		s = re.ReplaceAllString(s, Mask)
+	}
+	return s
+}
+
+// Value masks sensitive keys and strings inside a decoded payload. Values
+// of other types are returned as they are.
+func (r *Redactor) Value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			if r.Key(key) {
+				out[key] = Mask
+				continue
+			}
+			out[key] = r.Value(val)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(v))
+		for key, val := range v {
+			if r.Key(key) {
+				out[key] = Mask
+				continue
+			}
+			out[key] = r.String(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = r.Value(val)
+		}
+		return out
+	case []string:
+		out := make([]string, len(v))
+		for i, val := range v {
+			out[i] = r.String(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Field masks a log field
+func (r *Redactor) Field(f zapcore.Field) zapcore.Field {
+	if r.Key(f.Key) {
+		return zap.String(f.Key, Mask)
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.String(f.String)
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return zap.String(f.Key, r.String(err.Error()))
+		}
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok && s != nil {
+			return zap.String(f.Key, r.String(s.String()))
+		}
+	case zapcore.ReflectType:
+		return zap.Any(f.Key, r.reflected(f.Interface))
+	}
+	return f
+}
+
+// reflected masks an arbitrary value logged with zap.Any. Types Value
+// doesn't know are decoded through their JSON form first, so struct
+// fields are checked by their JSON names.
+func (r *Redactor) reflected(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, string, map[string]interface{}, map[string]string, []interface{}, []string:
+		return r.Value(v)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return v
+	}
+	return r.Value(decoded)
+}
+
+func (r *Redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		out[i] = r.Field(f)
+	}
+	return out
+}
+
+// Core wraps a zap core so every entry it writes is redacted
+func (r *Redactor) Core(core zapcore.Core) zapcore.Core {
+	return &redactingCore{Core: core, r: r}
+}
+
+type redactingCore struct {
+	zapcore.Core
+	r *Redactor
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.r.fields(fields)), r: c.r}
+}
+
+func (c *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.String(ent.Message)
+	return c.Core.Write(ent, c.r.fields(fields))
+}
@@ -0,0 +1,103 @@
+package redact
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observed returns a logger redacting into an observer
+func observed(t *testing.T) (*zap.Logger, *observer.ObservedLogs) {
+	t.Helper()
+	r, err := New(config.RedactConfig{
+		Keys:   []string{"password", "api_?key", "^token$"},
+		Values: []string{`sk-[A-Za-z0-9]{8,}`, `\b\d{4}-\d{4}-\d{4}-\d{4}\b`},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	core, logs := observer.New(zapcore.DebugLevel)
+	return zap.New(r.Core(core)), logs
+}
+
+func TestSensitiveKeysAndValuesRedacted(t *testing.T) {
+	logger, logs := observed(t)
+
+	payload := map[string]interface{}{
+		"prompt": "use key sk-abcdef123456 please",
+		"config": map[string]interface{}{"API_KEY": "plain", "model": "llama3"},
+		"cards":  []interface{}{"1234-5678-9012-3456"},
+	}
+	logger.With(zap.String("token", "abc")).Debug("Task payload sk-zzzzzzzzzz",
+		zap.Any("input", payload),
+		zap.String("password", "hunter2"),
+		zap.Error(errors.New("auth failed for sk-abcdef123456")),
+		zap.Int("tokens", 42),
+	)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Message != "Task payload ***" {
+		t.Errorf("message = %q", e.Message)
+	}
+	fields := e.ContextMap()
+	if fields["token"] != Mask || fields["password"] != Mask {
+		t.Errorf("sensitive fields = %v, %v; want masked", fields["token"], fields["password"])
+	}
+	if fields["error"] != "auth failed for ***" {
+		t.Errorf("error = %q", fields["error"])
+	}
+	if fields["tokens"] != int64(42) {
+		t.Errorf("tokens = %v, want a field named like a secret but not matching left alone", fields["tokens"])
+	}
+
+	input, _ := fields["input"].(map[string]interface{})
+	if input["prompt"] != "use key *** please" {
+		t.Errorf("prompt = %q", input["prompt"])
+	}
+	nested, _ := input["config"].(map[string]interface{})
+	if nested["API_KEY"] != Mask || nested["model"] != "llama3" {
+		t.Errorf("nested config = %v, want only the key masked", nested)
+	}
+	if cards, _ := input["cards"].([]interface{}); len(cards) != 1 || cards[0] != Mask {
+		t.Errorf("cards = %v, want masked", input["cards"])
+	}
+}
+
+func TestStructsRedactedByJSONName(t *testing.T) {
+	logger, logs := observed(t)
+	type creds struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+	logger.Info("Login", zap.Any("creds", creds{User: "ada", Password: "hunter2"}))
+
+	got, _ := logs.All()[0].ContextMap()["creds"].(map[string]interface{})
+	if got["user"] != "ada" || got["password"] != Mask {
+		t.Errorf("creds = %v, want the password masked", got)
+	}
+}
+
+func TestInvalidPatternRejected(t *testing.T) {
+	if _, err := New(config.RedactConfig{Keys: []string{"("}}); err == nil {
+		t.Error("invalid key pattern accepted")
+	}
+	if _, err := New(config.RedactConfig{Values: []string{"[a-"}}); err == nil {
+		t.Error("invalid value pattern accepted")
+	}
+}
+
+func BenchmarkRedactedLog(b *testing.B) {
+	r, _ := New(config.RedactConfig{Keys: []string{"password", "api_?key"}, Values: []string{`sk-[A-Za-z0-9]{8,}`}})
+	logger := zap.New(r.Core(zapcore.NewNopCore()))
+	for i := 0; i < b.N; i++ {
+		logger.Info("Task dispatched", zap.String("id", "t-1"), zap.String("prompt", "no secrets here"))
+	}
+}
@@ -0,0 +1,29 @@
+// =============================================================================
+// ODIN v7.0 - Redis Client
+// =============================================================================
+// Shared constructor for Redis connections built from config
+// =============================================================================
+
+package redisclient
+
+import (
+	"fmt"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// New creates a Redis client from the orchestrator's Redis settings
+func New(cfg config.RedisConfig) (*redis.Client, error) {
+	opts, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	if cfg.Password != "" {
+		opts.Password = cfg.Password
+	}
+	if cfg.DB != 0 {
+		opts.DB = cfg.DB
+	}
+	return redis.NewClient(opts), nil
+}
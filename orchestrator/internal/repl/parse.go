@@ -0,0 +1,150 @@
+// =============================================================================
+// ODIN v7.0 - Interactive Shell
+// =============================================================================
+// Line parser and command loop for `odin repl`
+// =============================================================================
+
+package repl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+)
+
+// Command is a parsed REPL line
+type Command struct {
+	Name string
+	Args []string
+
+	// submit only
+	Type        router.TaskType
+	Description string
+	Priority    int
+}
+
+// commands lists every REPL command, used for help and completion
+var commands = []string{
+	"submit", "status", "watch", "events", "list", "cancel",
+	"agents", "drain", "undrain", "restart", "help", "exit", "quit",
+}
+
+// Parse turns a line into a Command. Quoted arguments may contain spaces.
+func Parse(line string) (Command, error) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return Command{}, err
+	}
+	if len(tokens) == 0 {
+		return Command{}, nil
+	}
+
+	cmd := Command{Name: strings.ToLower(tokens[0]), Args: tokens[1:]}
+
+	switch cmd.Name {
+	case "submit":
+		return parseSubmit(cmd)
+	case "cancel":
+		if len(cmd.Args) != 1 {
+			return Command{}, fmt.Errorf("usage: cancel <task-id>")
+		}
+	case "drain", "undrain", "restart":
+		if len(cmd.Args) != 1 {
+			return Command{}, fmt.Errorf("usage: %s <agent>", cmd.Name)
+		}
+	case "watch":
+		if len(cmd.Args) > 1 {
+			return Command{}, fmt.Errorf("usage: watch [seconds]")
+		}
+		if len(cmd.Args) == 1 {
+			if n, err := strconv.Atoi(cmd.Args[0]); err != nil || n <= 0 {
+				return Command{}, fmt.Errorf("invalid interval: %s", cmd.Args[0])
+			}
+		}
+	case "status", "events", "list", "agents", "help", "exit", "quit":
+		if len(cmd.Args) > 0 {
+			return Command{}, fmt.Errorf("%s takes no arguments", cmd.Name)
+		}
+	default:
+		return Command{}, fmt.Errorf("unknown command: %s (try help)", cmd.Name)
+	}
+
+	return cmd, nil
+}
+
+// parseSubmit handles: submit <type> <description...> [-p|--priority N]
+func parseSubmit(cmd Command) (Command, error) {
+	cmd.Priority = 1
+	words := make([]string, 0, len(cmd.Args))
+
+	for i := 0; i < len(cmd.Args); i++ {
+		arg := cmd.Args[i]
+		if arg == "-p" || arg == "--priority" {
+			if i+1 >= len(cmd.Args) {
+				return Command{}, fmt.Errorf("%s requires a value", arg)
+			}
+			p, err := strconv.Atoi(cmd.Args[i+1])
+			if err != nil || p < 0 || p > 3 {
+				return Command{}, fmt.Errorf("priority must be 0-3, got %s", cmd.Args[i+1])
+			}
+			cmd.Priority = p
+			i++
+			continue
+		}
+		words = append(words, arg)
+	}
+
+	if len(words) < 2 {
+		return Command{}, fmt.Errorf("usage: submit <type> <description> [--priority N]")
+	}
+
+	cmd.Type = router.TaskType(words[0])
+	if !cmd.Type.Valid() {
+		return Command{}, fmt.Errorf("unknown task type: %s", words[0])
+	}
+	cmd.Description = strings.Join(words[1:], " ")
+	return cmd, nil
+}
+
+// tokenize splits on whitespace, honouring single and double quotes
+func tokenize(line string) ([]string, error) {
+	var (
+		tokens  []string
+		current strings.Builder
+		quote   rune
+		inToken bool
+	)
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+		case r == ' ' || r == '\t':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
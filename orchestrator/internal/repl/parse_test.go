@@ -0,0 +1,73 @@
+package repl
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+)
+
+func TestParseSubmit(t *testing.T) {
+	cmd, err := Parse(`submit code_review "check the auth module" -p 3`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cmd.Name != "submit" || cmd.Type != router.TaskCodeReview || cmd.Priority != 3 {
+		t.Errorf("got %+v, want a priority 3 code_review submit", cmd)
+	}
+	if cmd.Description != "check the auth module" {
+		t.Errorf("description = %q", cmd.Description)
+	}
+
+	cmd, err = Parse("submit question why is the sky blue")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cmd.Priority != 1 || cmd.Description != "why is the sky blue" {
+		t.Errorf("got %+v, want normal priority and the words joined", cmd)
+	}
+}
+
+func TestParseStatusAndCancel(t *testing.T) {
+	cmd, err := Parse("  STATUS  ")
+	if err != nil || cmd.Name != "status" {
+		t.Errorf("Parse(status) = %+v, %v", cmd, err)
+	}
+
+	cmd, err = Parse("cancel 'task 42'")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cmd.Name != "cancel" || !slices.Equal(cmd.Args, []string{"task 42"}) {
+		t.Errorf("got %+v, want cancel of one quoted ID", cmd)
+	}
+
+	if cmd, err := Parse(""); err != nil || cmd.Name != "" {
+		t.Errorf("blank line = %+v, %v; want an empty command", cmd, err)
+	}
+}
+
+func TestParseRejectsBadLines(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"cancel", "usage: cancel"},
+		{"cancel a b", "usage: cancel"},
+		{"status now", "takes no arguments"},
+		{"submit poetry a sonnet", "unknown task type"},
+		{"submit question", "usage: submit"},
+		{"submit question why -p 9", "priority must be 0-3"},
+		{"submit question why --priority", "requires a value"},
+		{`submit question "unclosed`, "unterminated quote"},
+		{"watch 0", "invalid interval"},
+		{"frobnicate", "unknown command"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.line)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want one mentioning %q", tt.line, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package repl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/api"
+	"github.com/krigsexe/odin/orchestrator/internal/batch"
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/peterh/liner"
+)
+
+// taskTypes are offered when completing submit arguments
+var taskTypes = []string{
+	string(router.TaskCodeWrite), string(router.TaskCodeModify),
+	string(router.TaskCodeDebug), string(router.TaskCodeReview),
+	string(router.TaskTest), string(router.TaskAnalysis), string(router.TaskQuestion),
+}
+
+// REPL is an interactive shell bound to a running orchestrator
+type REPL struct {
+	client *api.Client
+	out    io.Writer
+	agents []string
+}
+
+// New creates a new REPL instance
+func New(client *api.Client, out io.Writer) *REPL {
+	return &REPL{client: client, out: out}
+}
+
+// Run reads and executes lines until exit or EOF
+func (r *REPL) Run(ctx context.Context) error {
+	line := liner.NewLiner()
+	defer line.Close()
+
+	line.SetCtrlCAborts(true)
+	line.SetCompleter(r.complete)
+
+	historyPath := filepath.Join(os.Getenv("HOME"), ".odin", "repl_history")
+	if f, err := os.Open(historyPath); err == nil {
+		line.ReadHistory(f)
+		f.Close()
+	}
+	defer func() {
+		if err := os.MkdirAll(filepath.Dir(historyPath), 0o755); err != nil {
+			return
+		}
+		if f, err := os.Create(historyPath); err == nil {
+			line.WriteHistory(f)
+			f.Close()
+		}
+	}()
+
+	r.refreshAgents(ctx)
+	fmt.Fprintln(r.out, "ODIN interactive shell - type help for commands")
+
+	for {
+		input, err := line.Prompt("odin> ")
+		if errors.Is(err, liner.ErrPromptAborted) {
+			continue
+		}
+		if err == io.EOF {
+			fmt.Fprintln(r.out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		cmd, err := Parse(input)
+		if err != nil {
+			fmt.Fprintln(r.out, "error:", err)
+			continue
+		}
+		if cmd.Name == "" {
+			continue
+		}
+		line.AppendHistory(input)
+
+		quit, err := r.Execute(ctx, cmd)
+		if err != nil {
+			fmt.Fprintln(r.out, "error:", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// Execute runs a single parsed command
+func (r *REPL) Execute(ctx context.Context, cmd Command) (bool, error) {
+	switch cmd.Name {
+	case "exit", "quit":
+		return true, nil
+	case "help":
+		r.help()
+	case "submit":
+		return false, r.submit(ctx, cmd)
+	case "status":
+		return false, r.status(ctx)
+	case "watch":
+		interval := 2 * time.Second
+		if len(cmd.Args) == 1 {
+			n, _ := strconv.Atoi(cmd.Args[0])
+			interval = time.Duration(n) * time.Second
+		}
+		return false, r.watch(ctx, interval)
+	case "events":
+		return false, r.events(ctx)
+	case "list":
+		return false, r.list(ctx)
+	case "cancel":
+		if err := r.client.CancelTask(ctx, cmd.Args[0]); err != nil {
+			return false, err
+		}
+		fmt.Fprintln(r.out, "cancelled", cmd.Args[0])
+	case "agents":
+		return false, r.listAgents(ctx)
+	case "drain":
+		if err := r.client.DrainAgent(ctx, cmd.Args[0]); err != nil {
+			return false, err
+		}
+		fmt.Fprintln(r.out, "draining", cmd.Args[0])
+	case "undrain":
+		if err := r.client.UndrainAgent(ctx, cmd.Args[0]); err != nil {
+			return false, err
+		}
+		fmt.Fprintln(r.out, "undrained", cmd.Args[0])
+	case "restart":
+		requeued, err := r.client.RestartAgent(ctx, cmd.Args[0])
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(r.out, "draining %s, requeued %d tasks\n", cmd.Args[0], len(requeued))
+		for _, id := range requeued {
+			fmt.Fprintln(r.out, " ", id)
+		}
+	}
+	return false, nil
+}
+
+func (r *REPL) help() {
+	fmt.Fprint(r.out, `Commands:
+  submit <type> <description> [--priority N]  Submit a task
+  status                                      Show scheduler status
+  watch [seconds]                             Refresh status until Ctrl-C
+  events                                      Tail live events until Ctrl-C
+  list                                        List queued and running tasks
+  cancel <task-id>                            Cancel a task
+  agents                                      List agents
+  drain <agent> / undrain <agent>             Take an agent out of / back into rotation
+  restart <agent>                             Drain an agent and requeue its running tasks
+  exit                                        Leave the shell
+`)
+}
+
+func (r *REPL) submit(ctx context.Context, cmd Command) error {
+	tasks, err := batch.Resolve([]batch.TaskSpec{{
+		Type:        string(cmd.Type),
+		Description: cmd.Description,
+		Priority:    cmd.Priority,
+	}})
+	if err != nil {
+		return err
+	}
+
+	ids, err := r.client.SubmitTasks(ctx, tasks)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		fmt.Fprintln(r.out, "submitted", id)
+	}
+	return nil
+}
+
+func (r *REPL) status(ctx context.Context) error {
+	status, err := r.client.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(status))
+	for k := range status {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(r.out, "%-16s %v\n", k, status[k])
+	}
+	return nil
+}
+
+// watch prints status every interval until interrupted
+func (r *REPL) watch(ctx context.Context, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fmt.Fprintf(r.out, "--- %s\n", time.Now().Format("15:04:05"))
+		if err := r.status(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// events tails the live event stream until interrupted
+func (r *REPL) events(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	return r.client.StreamEvents(ctx, nil, func(e events.Event) {
+		fmt.Fprintf(r.out, "%s %-16s %s %s\n", e.Time.Format("15:04:05"), e.Type, e.TaskID, e.Message)
+	})
+}
+
+func (r *REPL) list(ctx context.Context) error {
+	tasks, err := r.client.ListTasks(ctx)
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		fmt.Fprintln(r.out, "no queued or running tasks")
+		return nil
+	}
+	for _, t := range tasks {
+		name := t.Name
+		if name == "" {
+			name = t.ID
+		}
+		fmt.Fprintf(r.out, "%-34s %-9s p%d retries=%d\n", name, t.State, t.Priority, t.Retries)
+	}
+	return nil
+}
+
+func (r *REPL) listAgents(ctx context.Context) error {
+	agents, err := r.client.ListAgents(ctx)
+	if err != nil {
+		return err
+	}
+	r.agents = r.agents[:0]
+	for _, a := range agents {
+		r.agents = append(r.agents, a.Name)
+		fmt.Fprintf(r.out, "%-16s %-10s %s\n", a.Name, a.Status, a.ID)
+	}
+	return nil
+}
+
+// refreshAgents caches agent names for completion
+func (r *REPL) refreshAgents(ctx context.Context) {
+	agents, err := r.client.ListAgents(ctx)
+	if err != nil {
+		return
+	}
+	r.agents = r.agents[:0]
+	for _, a := range agents {
+		r.agents = append(r.agents, a.Name)
+	}
+}
+
+// complete offers command names, task types after submit and agent names
+// after drain/undrain
+func (r *REPL) complete(line string) []string {
+	fields := strings.Fields(line)
+	trailingSpace := strings.HasSuffix(line, " ")
+
+	if len(fields) == 0 || (len(fields) == 1 && !trailingSpace) {
+		prefix := ""
+		if len(fields) == 1 {
+			prefix = fields[0]
+		}
+		return withPrefix("", commands, prefix)
+	}
+
+	var candidates []string
+	switch fields[0] {
+	case "submit":
+		candidates = taskTypes
+	case "drain", "undrain", "restart":
+		candidates = r.agents
+	default:
+		return nil
+	}
+
+	if len(fields) == 1 && trailingSpace {
+		return withPrefix(line, candidates, "")
+	}
+	if len(fields) == 2 && !trailingSpace {
+		return withPrefix(fields[0]+" ", candidates, fields[1])
+	}
+	return nil
+}
+
+// withPrefix returns head+candidate for each candidate starting with prefix
+func withPrefix(head string, candidates []string, prefix string) []string {
+	out := make([]string, 0)
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, head+c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+// =============================================================================
+// ODIN v7.0 - History Retention
+// =============================================================================
+// Background pruning of finished tasks, results and audit entries past
+// their retention windows, optionally archiving them to JSON lines first
+// =============================================================================
+
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/clock"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Pruner removes history older than its retention window, a batch at a
+// time, so serving is never held up by a large sweep
+type Pruner struct {
+	config config.RetentionConfig
+	store  store.Store
+	clock  clock.Clock
+	logger *zap.Logger
+}
+
+// New creates a pruner for the given store
+func New(cfg config.RetentionConfig, st store.Store, logger *zap.Logger) *Pruner {
+	return &Pruner{config: cfg, store: st, clock: clock.Real, logger: logger}
+}
+
+// SetClock replaces the pruner's time source
+func (p *Pruner) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// window returns how long records of a kind are kept, 0 for forever
+func (p *Pruner) window(kind string) time.Duration {
+	hours := 0
+	switch kind {
+	case store.KindTasks:
+		hours = p.config.Tasks
+	case store.KindResults:
+		hours = p.config.Results
+	case store.KindAudit:
+		hours = p.config.Audit
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// Run sweeps every interval until ctx is cancelled
+func (p *Pruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(p.config.Interval) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.Prune(ctx); err != nil {
+			p.logger.Warn("Retention sweep failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune runs one sweep and returns how many records of each kind it
+// removed. A kind that fails is reported and the others still swept.
+func (p *Pruner) Prune(ctx context.Context) (map[string]int, error) {
+	now := p.clock.Now()
+	removed := make(map[string]int)
+	var errs []error
+
+	for _, kind := range store.Kinds {
+		window := p.window(kind)
+		if window <= 0 {
+			continue
+		}
+		cutoff := now.Add(-window)
+		n, err := p.prune(ctx, kind, cutoff)
+		removed[kind] = n
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if n > 0 {
+			p.logger.Info("Pruned history",
+				zap.String("kind", kind),
+				zap.Int("removed", n),
+				zap.Time("cutoff", cutoff),
+			)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// prune removes batches of one kind until one comes back short
+func (p *Pruner) prune(ctx context.Context, kind string, cutoff time.Time) (int, error) {
+	var keep func([]json.RawMessage) error
+	if p.config.ArchiveDir != "" {
+		keep = func(records []json.RawMessage) error {
+			return p.archive(kind, records)
+		}
+	}
+
+	total := 0
+	for ctx.Err() == nil {
+		n, err := p.store.Prune(ctx, kind, cutoff, p.config.BatchSize, keep)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < p.config.BatchSize {
+			break
+		}
+	}
+	return total, ctx.Err()
+}
+
+// archive appends records to the kind's archive file for the day, one
+// JSON document per line, and syncs it before the records are removed
+func (p *Pruner) archive(kind string, records []json.RawMessage) error {
+	if err := os.MkdirAll(p.config.ArchiveDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	name := filepath.Join(p.config.ArchiveDir, fmt.Sprintf("%s-%s.jsonl", kind, p.clock.Now().UTC().Format("2006-01-02")))
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	var buf []byte
+	for _, rec := range records {
+		buf = append(append(buf, rec...), '\n')
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync archive: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,153 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/clock"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const day = 24 * time.Hour
+
+// newHistory holds tasks finished on days 0, 5 and 9, each with a result
+// and an audit entry, and a note on the oldest
+func newHistory(t *testing.T) *store.MemoryStore {
+	t.Helper()
+	st := store.NewMemory()
+	for _, task := range []struct {
+		id  string
+		age time.Duration
+	}{{"old", 0}, {"mid", 5 * day}, {"new", 9 * day}} {
+		at := epoch.Add(task.age)
+		st.AddFinished(store.TaskRecord{ID: task.id, Type: "question", Status: store.StatusCompleted, CompletedAt: at})
+		st.SetResult(task.id, map[string]interface{}{"answer": task.id})
+		st.AddAgentLog(task.id, store.AgentLog{Agent: "explain", Action: "execute", Success: true, CreatedAt: at})
+	}
+	if _, err := st.AddNote(context.Background(), "old", "ada", "flaky"); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+// newPruner sweeps st on day 10, keeping tasks a week, results three
+// days and audit entries two, one record per batch
+func newPruner(st store.Store, archiveDir string) *Pruner {
+	p := New(config.RetentionConfig{
+		Enabled:    true,
+		Tasks:      7 * 24,
+		Results:    3 * 24,
+		Audit:      2 * 24,
+		BatchSize:  1,
+		ArchiveDir: archiveDir,
+	}, st, zap.NewNop())
+	p.SetClock(clock.NewManual(epoch.Add(10 * day)))
+	return p
+}
+
+func TestRecordsPastRetentionPruned(t *testing.T) {
+	ctx := context.Background()
+	st := newHistory(t)
+
+	removed, err := newPruner(st, "").Prune(ctx)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	want := map[string]int{store.KindTasks: 1, store.KindResults: 2, store.KindAudit: 2}
+	for kind, n := range want {
+		if removed[kind] != n {
+			t.Errorf("removed %d %s, want %d", removed[kind], kind, n)
+		}
+	}
+
+	if _, err := st.Task(ctx, "old"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("task past retention: %v, want pruned", err)
+	}
+	if notes, _ := st.Notes(ctx, "old"); len(notes) != 0 {
+		t.Errorf("notes of a pruned task kept: %v", notes)
+	}
+	if _, err := st.Task(ctx, "mid"); err != nil {
+		t.Errorf("task within retention: %v, want kept", err)
+	}
+	if _, err := st.Result(ctx, "mid"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("result past retention: %v, want pruned before its task", err)
+	}
+	if result, err := st.Result(ctx, "new"); err != nil || result["answer"] != "new" {
+		t.Errorf("result within retention = %v, %v; want kept", result, err)
+	}
+	if logs, _ := st.AgentLogs(ctx, "mid"); len(logs) != 0 {
+		t.Errorf("audit entry past retention kept")
+	}
+	if logs, _ := st.AgentLogs(ctx, "new"); len(logs) != 1 {
+		t.Errorf("audit entry within retention pruned")
+	}
+
+	// Nothing is left to prune
+	removed, err = newPruner(st, "").Prune(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for kind, n := range removed {
+		if n != 0 {
+			t.Errorf("second sweep removed %d %s", n, kind)
+		}
+	}
+}
+
+func TestZeroWindowKeepsForever(t *testing.T) {
+	ctx := context.Background()
+	st := newHistory(t)
+	p := newPruner(st, "")
+	p.config.Results = 0
+	p.config.Tasks = 0
+
+	removed, err := p.Prune(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, swept := removed[store.KindResults]; swept {
+		t.Error("results swept with a window of 0")
+	}
+	for _, id := range []string{"old", "mid", "new"} {
+		if _, err := st.Result(ctx, id); err != nil {
+			t.Errorf("result of %s: %v, want kept", id, err)
+		}
+	}
+}
+
+func TestPrunedRecordsArchivedFirst(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := newPruner(newHistory(t), dir).Prune(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	date := epoch.Add(10 * day).Format("2006-01-02")
+	for kind, want := range map[string][]string{
+		store.KindTasks:   {`"id":"old"`},
+		store.KindResults: {`"id":"old"`, `"id":"mid"`},
+		store.KindAudit:   {`"task_id":"mid"`, `"task_id":"old"`},
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, kind+"-"+date+".jsonl"))
+		if err != nil {
+			t.Fatalf("%s archive: %v", kind, err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != len(want) {
+			t.Fatalf("%s archive has %d lines, want %d:\n%s", kind, len(lines), len(want), data)
+		}
+		for i, fragment := range want {
+			if !strings.Contains(lines[i], fragment) {
+				t.Errorf("%s archive line %d = %s, want %s", kind, i, lines[i], fragment)
+			}
+		}
+	}
+}
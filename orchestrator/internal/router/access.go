@@ -0,0 +1,60 @@
+package router
+
+import (
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// accessList is the compiled allow/deny policy for one task type
+type accessList struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+func newAccessLists(cfg map[string]config.AccessConfig) map[TaskType]*accessList {
+	lists := make(map[TaskType]*accessList, len(cfg))
+	for taskType, ac := range cfg {
+		al := &accessList{deny: make(map[string]bool, len(ac.Deny))}
+		if len(ac.Allow) > 0 {
+			al.allow = make(map[string]bool, len(ac.Allow))
+			for _, name := range ac.Allow {
+				al.allow[name] = true
+			}
+		}
+		for _, name := range ac.Deny {
+			al.deny[name] = true
+		}
+		lists[TaskType(taskType)] = al
+	}
+	return lists
+}
+
+// permits reports whether an agent may handle the task type
+func (al *accessList) permits(agent string) bool {
+	if al.deny[agent] {
+		return false
+	}
+	return al.allow == nil || al.allow[agent]
+}
+
+// enforceAccess drops agents the task type's policy forbids, logging each
+// one that routing would otherwise have picked. Callers hold r.mu.
+func (r *Router) enforceAccess(taskType TaskType, agents []string) []string {
+	al, ok := r.access[taskType]
+	if !ok {
+		return agents
+	}
+
+	permitted := make([]string, 0, len(agents))
+	for _, name := range agents {
+		if al.permits(name) {
+			permitted = append(permitted, name)
+			continue
+		}
+		r.logger.Warn("Agent excluded by access policy",
+			zap.String("type", string(taskType)),
+			zap.String("agent", name),
+		)
+	}
+	return permitted
+}
@@ -0,0 +1,83 @@
+package router
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+func TestDeniedAgentIsNeverRouted(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Access = map[string]config.AccessConfig{
+		"code_review": {Deny: []string{"retrieval"}},
+	}
+	r := newTestRouter(t, cfg, "retrieval", "review", "security", "explain")
+
+	agents, err := r.Route(&Task{Type: TaskCodeReview})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if want := []string{"review", "security"}; !slices.Equal(agents, want) {
+		t.Errorf("route = %v, want %v", agents, want)
+	}
+
+	// Other types keep the agent
+	agents, err = r.Route(&Task{Type: TaskQuestion})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if !slices.Contains(agents, "retrieval") {
+		t.Errorf("unrestricted route = %v, want retrieval kept", agents)
+	}
+}
+
+func TestAllowListAdmitsOnlyVettedAgents(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Access = map[string]config.AccessConfig{
+		"code_review": {Allow: []string{"review", "security"}, Deny: []string{"security"}},
+	}
+	r := newTestRouter(t, cfg, "retrieval", "review", "security")
+
+	// Deny wins over allow, even for a required stage
+	agents, err := r.Route(&Task{Type: TaskCodeReview})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if want := []string{"review"}; !slices.Equal(agents, want) {
+		t.Errorf("route = %v, want %v", agents, want)
+	}
+}
+
+func TestCapabilityMatchSkipsDeniedAgent(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Access = map[string]config.AccessConfig{
+		"code_review": {Deny: []string{"strong"}},
+	}
+	r := New(cfg, zap.NewNop())
+	r.RegisterAgent(&AgentInfo{ID: "strong-1", Name: "strong", Status: AgentStatusReady, Capabilities: []string{"go"}, CapabilityWeights: map[string]float64{"go": 10}})
+	r.RegisterAgent(&AgentInfo{ID: "weak-1", Name: "weak", Status: AgentStatusReady, Capabilities: []string{"go"}})
+
+	for i := 0; i < 20; i++ {
+		agents, err := r.Route(&Task{Type: TaskCodeReview, Capabilities: map[string]float64{"go": 1}})
+		if err != nil {
+			t.Fatalf("Route: %v", err)
+		}
+		if !slices.Equal(agents, []string{"weak"}) {
+			t.Fatalf("route = %v, want the permitted weak agent", agents)
+		}
+	}
+}
+
+func TestDefaultRouteHonorsAccess(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Access = map[string]config.AccessConfig{
+		"migration": {Deny: []string{"dev"}},
+	}
+	r := newTestRouter(t, cfg, "dev")
+
+	if agents, err := r.Route(&Task{Type: "migration"}); err == nil {
+		t.Errorf("route = %v, want an error with the default agent denied", agents)
+	}
+}
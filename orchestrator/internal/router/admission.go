@@ -0,0 +1,50 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnhealthy rejects a submission while too little of downstream is up.
+// It is temporary: the same submission succeeds once health recovers.
+var ErrUnhealthy = errors.New("downstream unhealthy, retry later")
+
+// HealthSource counts healthy and total instances of a dependency
+type HealthSource func() (healthy, total int)
+
+// SetProviderHealth sets where admission control reads LLM provider
+// health from; without one, providers are not checked
+func (r *Router) SetProviderHealth(src HealthSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.providerHealth = src
+}
+
+// admit applies admission control to a new submission
+func (r *Router) admit() error {
+	ac := r.config.Orchestrator.Admission
+	if !ac.Enabled {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.providerHealth != nil {
+		if healthy, total := r.providerHealth(); healthy < ac.MinProviders {
+			return fmt.Errorf("%w: %d of %d LLM providers available, need %d", ErrUnhealthy, healthy, total, ac.MinProviders)
+		}
+	}
+
+	healthy := 0
+	for name := range r.agents {
+		if r.available(name) {
+			healthy++
+		}
+	}
+	if healthy < ac.MinAgents {
+		return fmt.Errorf("%w: %d of %d agents available, need %d", ErrUnhealthy, healthy, len(r.agents), ac.MinAgents)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package router
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// providers is a HealthSource the test can open and close circuits on
+type providers struct {
+	mu      sync.Mutex
+	healthy int
+	total   int
+}
+
+func (p *providers) health() (healthy, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.healthy, p.total
+}
+
+func (p *providers) set(healthy int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.healthy = healthy
+}
+
+func TestSubmissionsRejectedWhileProvidersOpen(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.Admission.Enabled = true
+	cfg.Orchestrator.Admission.MinProviders = 1
+	r := newTestRouter(t, cfg, "retrieval", "explain")
+	src := &providers{healthy: 0, total: 2}
+	r.SetProviderHealth(src.health)
+
+	err := r.SubmitTask(&Task{ID: "t-1", Type: TaskQuestion})
+	if !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("submit with every circuit open = %v, want ErrUnhealthy", err)
+	}
+
+	// One provider closing its circuit is enough
+	src.set(1)
+	if err := r.SubmitTask(&Task{ID: "t-1", Type: TaskQuestion}); err != nil {
+		t.Errorf("submit once a provider recovered: %v", err)
+	}
+}
+
+func TestAdmissionCountsAvailableAgents(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.Admission.Enabled = true
+	cfg.Orchestrator.Admission.MinAgents = 2
+	r := newTestRouter(t, cfg, "explain")
+	r.RegisterAgent(&AgentInfo{ID: "retrieval-1", Name: "retrieval", Status: AgentStatusOffline})
+
+	if err := r.SubmitTask(&Task{ID: "t-1", Type: TaskQuestion}); !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("submit with one agent available = %v, want ErrUnhealthy", err)
+	}
+
+	r.RegisterAgent(&AgentInfo{ID: "retrieval-1", Name: "retrieval", Status: AgentStatusDegraded})
+	if err := r.SubmitTask(&Task{ID: "t-1", Type: TaskQuestion}); err != nil {
+		t.Errorf("submit with a degraded agent back: %v", err)
+	}
+}
+
+func TestAdmissionDisabledAcceptsAnything(t *testing.T) {
+	r := newTestRouter(t, testConfig(), "explain")
+	r.SetProviderHealth(func() (int, int) { return 0, 3 })
+
+	if err := r.SubmitTask(&Task{ID: "t-1", Type: TaskQuestion}); err != nil {
+		t.Errorf("submit without admission control: %v", err)
+	}
+}
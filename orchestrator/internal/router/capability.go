@@ -0,0 +1,209 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Capability matching errors
+var (
+	ErrInvalidCapabilities = errors.New("invalid capability weights")
+	ErrNoCapableAgent      = errors.New("no available agent has the requested capabilities")
+)
+
+// ValidateCapabilities checks that desired capability weights are positive
+// and any version constraints parse
+func ValidateCapabilities(desired map[string]float64) error {
+	for tag, weight := range desired {
+		if weight <= 0 {
+			return fmt.Errorf("%w: %s has weight %g, must be positive", ErrInvalidCapabilities, tag, weight)
+		}
+		if _, err := parseRequirement(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CapabilityScore rates how well the agent covers a task's desired
+// capabilities: the sum of each desired weight times the agent's score
+// for that tag. A tag listed in CapabilityWeights scores its weight; one
+// only in Capabilities scores 1. A desired tag with a version constraint,
+// such as "python>=3.12", is a requirement: an agent that does not
+// advertise a satisfying version, as "python 3.12" or "python@3.12",
+// scores zero.
+func (a *AgentInfo) CapabilityScore(desired map[string]float64) float64 {
+	score := 0.0
+	for key, weight := range desired {
+		req, err := parseRequirement(key)
+		if err != nil {
+			continue
+		}
+		has, satisfied := a.capability(req)
+		if !satisfied {
+			return 0
+		}
+		if !has {
+			continue
+		}
+		if w, ok := a.CapabilityWeights[req.tag]; ok {
+			score += weight * w
+		} else {
+			score += weight
+		}
+	}
+	return score
+}
+
+// capability reports whether the agent has the required tag, and whether
+// it meets the requirement's version constraints. A requirement without
+// constraints is always satisfied.
+func (a *AgentInfo) capability(req requirement) (has, satisfied bool) {
+	_, has = a.CapabilityWeights[req.tag]
+	satisfied = len(req.constraints) == 0
+	for _, c := range a.Capabilities {
+		tag, version := parseCapability(c)
+		if tag != req.tag {
+			continue
+		}
+		has = true
+		if satisfied {
+			continue
+		}
+		if v, err := parseVersion(version); err == nil && req.allows(v) {
+			satisfied = true
+		}
+	}
+	return has, satisfied
+}
+
+// load is the agent's last reported backlog
+func (a *AgentInfo) load() int {
+	return a.InFlight + a.QueueDepth
+}
+
+// matchCapabilities picks the available agent with the highest capability
+// score for the task, breaking ties on reported load and then name.
+// Agents scoring zero never match. Callers hold r.mu.
+func (r *Router) matchCapabilities(task *Task) (string, error) {
+	return r.bestMatch(task, nil)
+}
+
+// bestMatch is matchCapabilities over the agents skip leaves; nil skips
+// none. Callers hold r.mu.
+func (r *Router) bestMatch(task *Task, skip func(name string) bool) (string, error) {
+	if err := ValidateCapabilities(task.Capabilities); err != nil {
+		return "", err
+	}
+
+	al := r.access[task.Type]
+	names := make([]string, 0, len(r.agents))
+	for name := range r.agents {
+		if skip != nil && skip(name) {
+			continue
+		}
+		if (al == nil || al.permits(name)) && r.available(name) && !r.excluded(name, task.Exclude) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	best, bestScore := "", 0.0
+	for _, name := range names {
+		agent := r.agents[name]
+		score := agent.CapabilityScore(task.Capabilities)
+		switch {
+		case score <= 0:
+		case best == "" || score > bestScore:
+			best, bestScore = name, score
+		case score == bestScore && agent.load() < r.agents[best].load():
+			best = name
+		}
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("%w: %s", ErrNoCapableAgent, task.Type)
+	}
+	return best, nil
+}
+
+// Fallback picks where a capability task goes if the agents it was routed
+// to are still saturated once agents.prefer_wait has passed: the best
+// match among the other agents with room to spare. It is nil when the
+// task asks for no capabilities, the window is off, or no other agent
+// matches.
+func (r *Router) Fallback(task *Task, preferred []string) []string {
+	if len(task.Capabilities) == 0 || r.config.Agents.PreferWait <= 0 {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	agent, err := r.bestMatch(task, func(name string) bool {
+		return slices.Contains(preferred, name) || r.overloaded(name)
+	})
+	if err != nil {
+		return nil
+	}
+	return []string{agent}
+}
+
+// Saturated reports whether every one of the named agents is at capacity,
+// by its last heartbeat
+func (r *Router) Saturated(agents []string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, name := range agents {
+		if !r.overloaded(name) {
+			return false
+		}
+	}
+	return len(agents) > 0
+}
+
+// explainCapabilities mirrors matchCapabilities for Explain. Callers hold
+// r.mu.
+func (r *Router) explainCapabilities(task *Task, exp *RoutingExplanation) {
+	exp.Policy = PolicyCapability
+
+	names := make([]string, 0, len(r.agents))
+	for name := range r.agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, err := r.matchCapabilities(task)
+	if err != nil {
+		exp.Error = err.Error()
+	}
+
+	al := r.access[task.Type]
+	for _, name := range names {
+		agent := r.agents[name]
+		c := Candidate{Agent: name, Status: agent.Status, Score: agent.CapabilityScore(task.Capabilities)}
+		switch {
+		case al != nil && al.deny[name]:
+			c.Reason = FilterDenied
+		case al != nil && !al.permits(name):
+			c.Reason = FilterNotAllowed
+		case c.Status == AgentStatusDraining:
+			c.Reason = FilterDraining
+		case c.Status != AgentStatusReady && c.Status != AgentStatusDegraded:
+			c.Reason = FilterOffline
+		case r.excluded(name, task.Exclude):
+			c.Reason = FilterExcluded
+		case c.Score <= 0:
+			c.Reason = FilterNoMatch
+		case name != best:
+			c.Reason = FilterOutscored
+		default:
+			c.Selected = true
+			exp.Selected = append(exp.Selected, name)
+		}
+		exp.Candidates = append(exp.Candidates, c)
+	}
+}
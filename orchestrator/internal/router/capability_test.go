@@ -0,0 +1,103 @@
+package router
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// registerSkilled registers a ready agent with weighted capabilities
+func registerSkilled(r *Router, name string, weights map[string]float64, inFlight int) {
+	r.RegisterAgent(&AgentInfo{
+		ID:                name + "-1",
+		Name:              name,
+		Status:            AgentStatusReady,
+		CapabilityWeights: weights,
+		InFlight:          inFlight,
+	})
+}
+
+func TestBestWeightedMatchIsChosen(t *testing.T) {
+	r := newTestRouter(t, testConfig())
+	registerSkilled(r, "gopher", map[string]float64{"go": 3, "python": 1}, 0)
+	registerSkilled(r, "pythonista", map[string]float64{"go": 0.5, "python": 3}, 0)
+	registerSkilled(r, "writer", map[string]float64{"prose": 5}, 0)
+
+	tests := []struct {
+		desired map[string]float64
+		want    string
+	}{
+		{map[string]float64{"go": 2, "python": 1}, "gopher"},     // 7 against 4
+		{map[string]float64{"go": 1, "python": 2}, "pythonista"}, // 5 against 6.5
+		{map[string]float64{"prose": 1, "go": 1}, "writer"},      // 5 against 3
+	}
+	for _, tt := range tests {
+		agents, err := r.Route(&Task{Type: TaskCodeWrite, Capabilities: tt.desired})
+		if err != nil {
+			t.Errorf("%v: Route: %v", tt.desired, err)
+			continue
+		}
+		if !slices.Equal(agents, []string{tt.want}) {
+			t.Errorf("%v: routed to %v, want %s", tt.desired, agents, tt.want)
+		}
+	}
+}
+
+func TestZeroMatchAgentIsExcluded(t *testing.T) {
+	r := newTestRouter(t, testConfig(), "dev")
+	registerSkilled(r, "writer", map[string]float64{"prose": 5}, 0)
+
+	_, err := r.Route(&Task{Type: TaskCodeWrite, Capabilities: map[string]float64{"rust": 1}})
+	if !errors.Is(err, ErrNoCapableAgent) {
+		t.Errorf("Route with no matching agent = %v, want ErrNoCapableAgent", err)
+	}
+}
+
+func TestCapabilityTiesGoToLeastLoaded(t *testing.T) {
+	r := newTestRouter(t, testConfig())
+	registerSkilled(r, "busy", map[string]float64{"go": 2}, 5)
+	registerSkilled(r, "idle", map[string]float64{"go": 2}, 1)
+	registerSkilled(r, "also-idle", map[string]float64{"go": 2}, 1)
+
+	agents, err := r.Route(&Task{Type: TaskCodeWrite, Capabilities: map[string]float64{"go": 1}})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	// Equal load falls back to the name, for a stable choice
+	if !slices.Equal(agents, []string{"also-idle"}) {
+		t.Errorf("routed to %v, want the least loaded, first by name", agents)
+	}
+}
+
+func TestCapabilityScore(t *testing.T) {
+	agent := &AgentInfo{
+		Capabilities:      []string{"go", "python 3.11"},
+		CapabilityWeights: map[string]float64{"go": 4},
+	}
+	tests := []struct {
+		desired map[string]float64
+		want    float64
+	}{
+		{map[string]float64{"go": 0.5}, 2},
+		{map[string]float64{"python": 2}, 2}, // plain capability scores 1
+		{map[string]float64{"go": 1, "rust": 3}, 4},
+		{map[string]float64{"rust": 1}, 0},
+		{map[string]float64{"go": 1, "python>=3.12": 1}, 0}, // unmet requirement
+	}
+	for _, tt := range tests {
+		if got := agent.CapabilityScore(tt.desired); got != tt.want {
+			t.Errorf("score for %v = %g, want %g", tt.desired, got, tt.want)
+		}
+	}
+}
+
+func TestValidateCapabilitiesRejectsNonPositiveWeight(t *testing.T) {
+	for _, weight := range []float64{0, -1} {
+		if err := ValidateCapabilities(map[string]float64{"go": weight}); !errors.Is(err, ErrInvalidCapabilities) {
+			t.Errorf("weight %g = %v, want ErrInvalidCapabilities", weight, err)
+		}
+	}
+	if err := ValidateCapabilities(map[string]float64{"go": 0.1}); err != nil {
+		t.Errorf("positive weight rejected: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+// =============================================================================
+// ODIN v7.0 - Agent Discovery
+// =============================================================================
+// Pluggable backends reporting which agents are currently available
+// =============================================================================
+
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/redisclient"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// Discovery reports the set of agents currently available. An agent
+// reported with a zero LastSeen counts as seen at the time of discovery,
+// by the router's clock.
+type Discovery interface {
+	Discover(ctx context.Context) ([]*AgentInfo, error)
+}
+
+// NewDiscovery creates the discovery backend selected in config
+func NewDiscovery(cfg *config.Config) (Discovery, error) {
+	dc := cfg.Agents.Discovery
+
+	switch dc.Backend {
+	case "", "static":
+		return NewStaticDiscovery(cfg.Agents.Enabled), nil
+	case "redis":
+		client, err := redisclient.New(cfg.Redis)
+		if err != nil {
+			return nil, err
+		}
+		ttl := time.Duration(dc.HeartbeatTTL) * time.Second
+		return NewRedisDiscovery(client, ttl), nil
+	case "consul":
+		return NewConsulDiscovery(dc.ConsulAddr, cfg.Agents.Enabled), nil
+	case "dns":
+		return NewDNSDiscovery(dc.DNSDomain, cfg.Agents.Enabled), nil
+	default:
+		return nil, fmt.Errorf("unknown discovery backend: %s", dc.Backend)
+	}
+}
+
+// StaticDiscovery reports a fixed list of agents from config
+type StaticDiscovery struct {
+	names []string
+}
+
+// NewStaticDiscovery creates a discovery backend for a fixed agent list
+func NewStaticDiscovery(names []string) *StaticDiscovery {
+	return &StaticDiscovery{names: names}
+}
+
+// Discover returns one instance per configured agent name
+func (d *StaticDiscovery) Discover(ctx context.Context) ([]*AgentInfo, error) {
+	agents := make([]*AgentInfo, 0, len(d.names))
+	for _, name := range d.names {
+		agents = append(agents, &AgentInfo{
+			ID:     fmt.Sprintf("%s-1", name),
+			Name:   name,
+			Status: AgentStatusReady,
+		})
+	}
+	return agents, nil
+}
+
+// heartbeatPrefix is the key prefix agents write their heartbeats under
+const heartbeatPrefix = "odin:heartbeat:"
+
+// RedisDiscovery reports agents with a recent heartbeat key in Redis.
+// Agents write JSON-encoded AgentInfo, including their current load, to
+// odin:heartbeat:<agent id> with an expiry, so dead agents drop out on
+// their own.
+type RedisDiscovery struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisDiscovery creates a heartbeat-based discovery backend
+func NewRedisDiscovery(client *redis.Client, ttl time.Duration) *RedisDiscovery {
+	return &RedisDiscovery{client: client, ttl: ttl}
+}
+
+// Discover scans heartbeat keys and returns agents seen within the TTL
+func (d *RedisDiscovery) Discover(ctx context.Context) ([]*AgentInfo, error) {
+	agents := make([]*AgentInfo, 0)
+	now := time.Now()
+
+	iter := d.client.Scan(ctx, 0, heartbeatPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		raw, err := d.client.Get(ctx, iter.Val()).Bytes()
+		if err == redis.Nil {
+			continue // Expired between SCAN and GET
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read heartbeat: %w", err)
+		}
+
+		var info AgentInfo
+		if err := json.Unmarshal(raw, &info); err != nil || info.Name == "" {
+			continue
+		}
+		if d.ttl > 0 && now.Sub(info.LastSeen) > d.ttl {
+			continue
+		}
+		if info.Status == "" {
+			info.Status = AgentStatusReady
+		}
+		agents = append(agents, &info)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan heartbeats: %w", err)
+	}
+
+	return agents, nil
+}
+
+// ConsulDiscovery reports agents registered as passing Consul services
+type ConsulDiscovery struct {
+	addr   string
+	names  []string
+	client *http.Client
+}
+
+// NewConsulDiscovery creates a Consul catalog discovery backend
+func NewConsulDiscovery(addr string, names []string) *ConsulDiscovery {
+	if addr == "" {
+		addr = "http://localhost:8500"
+	}
+	return &ConsulDiscovery{
+		addr:   strings.TrimRight(addr, "/"),
+		names:  names,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// consulEntry is the subset of /v1/health/service we care about
+type consulEntry struct {
+	Service struct {
+		ID      string   `json:"ID"`
+		Service string   `json:"Service"`
+		Tags    []string `json:"Tags"`
+	} `json:"Service"`
+}
+
+// Discover queries healthy instances of each agent service. Service tags
+// are treated as agent capabilities.
+func (d *ConsulDiscovery) Discover(ctx context.Context) ([]*AgentInfo, error) {
+	agents := make([]*AgentInfo, 0, len(d.names))
+
+	for _, name := range d.names {
+		endpoint := fmt.Sprintf("%s/v1/health/service/%s?passing=true", d.addr, url.PathEscape(name))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("consul query for %s failed: %w", name, err)
+		}
+
+		var entries []consulEntry
+		err = json.NewDecoder(resp.Body).Decode(&entries)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid consul response for %s: %w", name, err)
+		}
+
+		for _, entry := range entries {
+			agents = append(agents, &AgentInfo{
+				ID:           entry.Service.ID,
+				Name:         name,
+				Capabilities: entry.Service.Tags,
+				Status:       AgentStatusReady,
+			})
+		}
+	}
+
+	return agents, nil
+}
+
+// DNSDiscovery reports agents published as SRV records, looked up as
+// _<agent>._tcp.<domain>
+type DNSDiscovery struct {
+	domain   string
+	names    []string
+	resolver *net.Resolver
+}
+
+// NewDNSDiscovery creates an SRV record discovery backend
+func NewDNSDiscovery(domain string, names []string) *DNSDiscovery {
+	return &DNSDiscovery{
+		domain:   domain,
+		names:    names,
+		resolver: net.DefaultResolver,
+	}
+}
+
+// Discover resolves SRV records for each configured agent name. A missing
+// record means the agent is currently not available.
+func (d *DNSDiscovery) Discover(ctx context.Context) ([]*AgentInfo, error) {
+	agents := make([]*AgentInfo, 0, len(d.names))
+
+	for _, name := range d.names {
+		_, records, err := d.resolver.LookupSRV(ctx, name, "tcp", d.domain)
+		if err != nil {
+			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+				continue
+			}
+			return nil, fmt.Errorf("srv lookup for %s failed: %w", name, err)
+		}
+
+		for _, record := range records {
+			agents = append(agents, &AgentInfo{
+				ID:     fmt.Sprintf("%s@%s:%d", name, strings.TrimSuffix(record.Target, "."), record.Port),
+				Name:   name,
+				Status: AgentStatusReady,
+			})
+		}
+	}
+
+	return agents, nil
+}
@@ -0,0 +1,131 @@
+package router
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/clock"
+)
+
+// fakeDiscovery reports whichever agents the test has set, as a dynamic
+// backend would
+type fakeDiscovery struct {
+	mu     sync.Mutex
+	agents []string
+
+	// beats holds each agent's last heartbeat; an agent without one is
+	// reported as just seen
+	beats map[string]time.Time
+}
+
+func (d *fakeDiscovery) set(names ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.agents = names
+}
+
+// beat records a heartbeat from an agent
+func (d *fakeDiscovery) beat(name string, at time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.beats == nil {
+		d.beats = make(map[string]time.Time)
+	}
+	d.beats[name] = at
+}
+
+func (d *fakeDiscovery) Discover(ctx context.Context) ([]*AgentInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	found := make([]*AgentInfo, 0, len(d.agents))
+	for _, name := range d.agents {
+		found = append(found, &AgentInfo{ID: name + "-1", Name: name, Status: AgentStatusReady, LastSeen: d.beats[name]})
+	}
+	return found, nil
+}
+
+// agentNames lists the agents the router knows, sorted
+func agentNames(r *Router) []string {
+	var names []string
+	for _, agent := range r.GetAgents() {
+		names = append(names, agent.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestStaticDiscoveryReportsConfiguredAgents(t *testing.T) {
+	found, err := NewStaticDiscovery([]string{"explain", "review"}).Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("got %d agents, want 2", len(found))
+	}
+	for i, name := range []string{"explain", "review"} {
+		if found[i].Name != name || found[i].ID != name+"-1" || found[i].Status != AgentStatusReady {
+			t.Errorf("agent %d = %+v, want ready %s", i, found[i], name)
+		}
+	}
+
+	cfg := testConfig()
+	cfg.Agents.Enabled = []string{"explain", "review"}
+	r := newTestRouter(t, cfg)
+	r.refreshAgentList(context.Background())
+	if got := agentNames(r); !slices.Equal(got, []string{"explain", "review"}) {
+		t.Errorf("agents = %v, want the configured ones", got)
+	}
+}
+
+func TestDynamicDiscoveryAgentsComeAndGo(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRouter(t, testConfig())
+	c := clock.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r.SetClock(c)
+	d := &fakeDiscovery{}
+	r.SetDiscovery(d)
+
+	d.set("explain", "review")
+	r.refreshAgentList(ctx)
+	if got := agentNames(r); !slices.Equal(got, []string{"explain", "review"}) {
+		t.Fatalf("agents = %v after discovery, want explain and review", got)
+	}
+
+	// review stops being reported and is evicted once offline
+	c.Advance(time.Second)
+	d.set("explain")
+	r.refreshAgentList(ctx)
+	if got := agentNames(r); !slices.Equal(got, []string{"explain"}) {
+		t.Errorf("agents = %v after review left, want explain", got)
+	}
+	if _, err := r.Route(&Task{Type: TaskQuestion}); err != nil {
+		t.Errorf("Route to the remaining agent: %v", err)
+	}
+
+	c.Advance(time.Second)
+	d.set("explain", "review")
+	r.refreshAgentList(ctx)
+	if got := agentNames(r); !slices.Equal(got, []string{"explain", "review"}) {
+		t.Errorf("agents = %v after review came back, want both", got)
+	}
+}
+
+func TestPresenceOnlyAgentsStampedByRouterClock(t *testing.T) {
+	r := newTestRouter(t, testConfig())
+	c := clock.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r.SetClock(c)
+	r.SetDiscovery(NewStaticDiscovery([]string{"dev"}))
+
+	c.Advance(time.Minute)
+	r.refreshAgentList(context.Background())
+	agents := r.GetAgents()
+	if len(agents) != 1 || !agents[0].LastSeen.Equal(c.Now()) {
+		t.Errorf("agents = %+v, want dev last seen at the router clock's %s", agents, c.Now())
+	}
+}
@@ -0,0 +1,121 @@
+package router
+
+import "fmt"
+
+// Routing policies an explanation can report
+const (
+	PolicyRouteTable = "route_table" // the task type has a configured route
+	PolicyDefault    = "default"     // unknown type, sent to the dev agent
+	PolicyCapability = "capability"  // best match for the task's capabilities
+)
+
+// Reasons an agent is dropped from a route
+const (
+	FilterDenied       = "denied"       // on the task type's deny list
+	FilterNotAllowed   = "not_allowed"  // missing from the task type's allow list
+	FilterUnregistered = "unregistered" // never seen by discovery or registration
+	FilterOffline      = "offline"      // missed too many heartbeats
+	FilterDraining     = "draining"     // drained by an operator
+	FilterExcluded     = "excluded"     // instance recently failed this task
+	FilterOverloaded   = "overloaded"   // optional stage shed while the agent reports high load
+	FilterNoMatch      = "no_match"     // has none of the requested capabilities
+	FilterOutscored    = "outscored"    // another agent matched better or was less loaded
+)
+
+// Candidate is one agent routing considered for a task
+type Candidate struct {
+	Agent    string  `json:"agent"`
+	Status   string  `json:"status,omitempty"`
+	Optional bool    `json:"optional,omitempty"`
+	Score    float64 `json:"score,omitempty"`
+	Selected bool    `json:"selected"`
+	Reason   string  `json:"reason,omitempty"`
+}
+
+// RoutingExplanation describes how a task would be routed right now
+type RoutingExplanation struct {
+	TaskType   TaskType       `json:"task_type"`
+	Policy     string         `json:"policy"`
+	Candidates []Candidate    `json:"candidates"`
+	Selected   []string       `json:"selected"`
+	Sandbox    *SandboxPolicy `json:"sandbox,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Explain reports which agents Route would pick for a task and why each
+// other candidate was dropped, without submitting anything
+func (r *Router) Explain(task *Task) RoutingExplanation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	exp := RoutingExplanation{TaskType: task.Type, Policy: PolicyRouteTable, Selected: []string{}}
+	if sandbox, err := r.Sandbox(task); err == nil {
+		exp.Sandbox = &sandbox
+	}
+
+	if len(task.Capabilities) > 0 {
+		r.explainCapabilities(task, &exp)
+		return exp
+	}
+
+	stages, ok := r.routes[task.Type]
+	if !ok {
+		exp.Policy = PolicyDefault
+		stages = []RouteStage{{Agent: "dev"}}
+	}
+
+	al := r.access[task.Type]
+	for _, stage := range stages {
+		name := stage.Agent
+		c := Candidate{Agent: name, Optional: stage.Optional}
+		agent, registered := r.agents[name]
+		if registered {
+			c.Status = agent.Status
+		}
+
+		switch {
+		case al != nil && al.deny[name]:
+			c.Reason = FilterDenied
+		case al != nil && !al.permits(name):
+			c.Reason = FilterNotAllowed
+		case exp.Policy == PolicyDefault:
+			// The default route only applies the access policy
+		case !registered:
+			c.Reason = FilterUnregistered
+		case c.Status == AgentStatusDraining:
+			c.Reason = FilterDraining
+		case c.Status != AgentStatusReady && c.Status != AgentStatusDegraded:
+			c.Reason = FilterOffline
+		case r.excluded(name, task.Exclude):
+			c.Reason = FilterExcluded
+		case stage.Optional && r.overloaded(name):
+			c.Reason = FilterOverloaded
+		}
+
+		if c.Reason == "" {
+			c.Selected = true
+			exp.Selected = append(exp.Selected, name)
+		} else if !stage.Optional && exp.Error == "" && c.Reason != FilterDenied && c.Reason != FilterNotAllowed {
+			exp.Error = fmt.Sprintf("%s: %s for task type %s", ErrRequiredAgentUnavailable, name, task.Type)
+		}
+		exp.Candidates = append(exp.Candidates, c)
+	}
+
+	// A missing required stage fails the whole route
+	if exp.Error != "" {
+		exp.Selected = []string{}
+		for i := range exp.Candidates {
+			exp.Candidates[i].Selected = false
+		}
+		return exp
+	}
+
+	if len(exp.Selected) == 0 {
+		if exp.Policy == PolicyDefault {
+			exp.Error = "no permitted agents for task type: " + string(task.Type)
+		} else {
+			exp.Error = "no available agents for task type: " + string(task.Type)
+		}
+	}
+	return exp
+}
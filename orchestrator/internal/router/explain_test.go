@@ -0,0 +1,163 @@
+package router
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// reasons maps each candidate in an explanation to why it was dropped
+func reasons(exp RoutingExplanation) map[string]string {
+	got := make(map[string]string, len(exp.Candidates))
+	for _, c := range exp.Candidates {
+		got[c.Agent] = c.Reason
+	}
+	return got
+}
+
+func TestExplainEnumeratesFilterReasons(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Access = map[string]config.AccessConfig{
+		"code_review": {Deny: []string{"retrieval"}},
+		"analysis":    {Allow: []string{"retrieval"}},
+	}
+	r := newTestRouter(t, cfg, "retrieval", "dev", "approbation", "oracle_code", "analysis")
+	r.RegisterAgent(&AgentInfo{ID: "review-1", Name: "review", Status: AgentStatusReady})
+	if err := r.DrainAgent("review"); err != nil {
+		t.Fatal(err)
+	}
+	// security is never registered; test is offline
+	r.RegisterAgent(&AgentInfo{ID: "test-1", Name: "test", Status: AgentStatusOffline})
+
+	tests := []struct {
+		name     string
+		task     *Task
+		reasons  map[string]string
+		selected []string
+		failed   bool
+	}{
+		{
+			name:     "all stages available",
+			task:     &Task{Type: TaskCodeWrite},
+			reasons:  map[string]string{"retrieval": "", "dev": "", "approbation": ""},
+			selected: []string{"retrieval", "dev", "approbation"},
+		},
+		{
+			name:    "denied, draining and unregistered",
+			task:    &Task{Type: TaskCodeReview},
+			reasons: map[string]string{"retrieval": FilterDenied, "review": FilterDraining, "security": FilterUnregistered},
+			failed:  true,
+		},
+		{
+			name:    "offline required stage",
+			task:    &Task{Type: TaskTest},
+			reasons: map[string]string{"retrieval": "", "test": FilterOffline, "oracle_code": ""},
+			failed:  true,
+		},
+		{
+			name:     "excluded optional instance",
+			task:     &Task{Type: TaskCodeDebug, Exclude: []string{"retrieval-1"}},
+			reasons:  map[string]string{"retrieval": FilterExcluded, "dev": "", "oracle_code": ""},
+			selected: []string{"dev", "oracle_code"},
+		},
+		{
+			name:    "missing from the allow list",
+			task:    &Task{Type: TaskAnalysis},
+			reasons: map[string]string{"retrieval": "", "analysis": FilterNotAllowed},
+			// A required stage dropped by access policy is not an outage
+			selected: []string{"retrieval"},
+		},
+		{
+			name:     "unknown type goes to dev",
+			task:     &Task{Type: "translate"},
+			reasons:  map[string]string{"dev": ""},
+			selected: []string{"dev"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := r.Explain(tt.task)
+			if got := reasons(exp); len(got) != len(tt.reasons) {
+				t.Errorf("candidates = %v, want %v", got, tt.reasons)
+			} else {
+				for agent, want := range tt.reasons {
+					if got[agent] != want {
+						t.Errorf("%s reason = %q, want %q", agent, got[agent], want)
+					}
+				}
+			}
+			if tt.failed != (exp.Error != "") {
+				t.Errorf("error = %q, want failed=%v", exp.Error, tt.failed)
+			}
+			if !tt.failed && !slices.Equal(exp.Selected, tt.selected) {
+				t.Errorf("selected = %v, want %v", exp.Selected, tt.selected)
+			}
+
+			// The explanation agrees with what Route would do
+			agents, err := r.Route(tt.task)
+			if (err != nil) != tt.failed {
+				t.Errorf("Route error = %v, explanation error = %q", err, exp.Error)
+			}
+			if err == nil && !slices.Equal(agents, exp.Selected) {
+				t.Errorf("Route = %v, explanation selected %v", agents, exp.Selected)
+			}
+		})
+	}
+}
+
+func TestExplainPolicies(t *testing.T) {
+	r := newTestRouter(t, testConfig(), "dev")
+
+	if got := r.Explain(&Task{Type: TaskCodeWrite}).Policy; got != PolicyRouteTable {
+		t.Errorf("routed type policy = %q, want %q", got, PolicyRouteTable)
+	}
+	if got := r.Explain(&Task{Type: "translate"}).Policy; got != PolicyDefault {
+		t.Errorf("unknown type policy = %q, want %q", got, PolicyDefault)
+	}
+	if got := r.Explain(&Task{Type: TaskAnalysis, Capabilities: map[string]float64{"go": 1}}).Policy; got != PolicyCapability {
+		t.Errorf("capability task policy = %q, want %q", got, PolicyCapability)
+	}
+}
+
+func TestExplainOptionalStageShedUnderLoad(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.MaxQueueDepth = 5
+	r := newTestRouter(t, cfg, "explain")
+	r.RegisterAgent(&AgentInfo{ID: "retrieval-1", Name: "retrieval", Status: AgentStatusReady, QueueDepth: 8})
+
+	exp := r.Explain(&Task{Type: TaskQuestion})
+	if got := reasons(exp); got["retrieval"] != FilterOverloaded || got["explain"] != "" {
+		t.Errorf("reasons = %v, want retrieval shed as overloaded", got)
+	}
+	if !slices.Equal(exp.Selected, []string{"explain"}) {
+		t.Errorf("selected = %v, want [explain]", exp.Selected)
+	}
+}
+
+func TestExplainCapabilityMatch(t *testing.T) {
+	r := newTestRouter(t, testConfig())
+	r.RegisterAgent(&AgentInfo{ID: "dev-1", Name: "dev", Status: AgentStatusReady, Capabilities: []string{"go"}})
+	r.RegisterAgent(&AgentInfo{ID: "oracle-1", Name: "oracle_code", Status: AgentStatusReady,
+		CapabilityWeights: map[string]float64{"go": 3}})
+	r.RegisterAgent(&AgentInfo{ID: "test-1", Name: "test", Status: AgentStatusOffline,
+		CapabilityWeights: map[string]float64{"go": 9}})
+	r.RegisterAgent(&AgentInfo{ID: "explain-1", Name: "explain", Status: AgentStatusReady, Capabilities: []string{"prose"}})
+
+	exp := r.Explain(&Task{Type: TaskCodeWrite, Capabilities: map[string]float64{"go": 1}})
+	want := map[string]string{
+		"dev":         FilterOutscored,
+		"oracle_code": "",
+		"test":        FilterOffline,
+		"explain":     FilterNoMatch,
+	}
+	got := reasons(exp)
+	for agent, reason := range want {
+		if got[agent] != reason {
+			t.Errorf("%s reason = %q, want %q", agent, got[agent], reason)
+		}
+	}
+	if !slices.Equal(exp.Selected, []string{"oracle_code"}) || exp.Error != "" {
+		t.Errorf("selected = %v (error %q), want [oracle_code]", exp.Selected, exp.Error)
+	}
+}
@@ -0,0 +1,125 @@
+package router
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/clock"
+)
+
+// loadDiscovery reports agents with the load of their last heartbeat
+type loadDiscovery struct {
+	mu     sync.Mutex
+	agents map[string]AgentInfo
+}
+
+// report records a heartbeat from an agent carrying its load
+func (d *loadDiscovery) report(info AgentInfo) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.agents[info.Name] = info
+}
+
+func (d *loadDiscovery) Discover(ctx context.Context) ([]*AgentInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	found := make([]*AgentInfo, 0, len(d.agents))
+	for _, info := range d.agents {
+		info := info
+		found = append(found, &info)
+	}
+	return found, nil
+}
+
+// newLoadRouter creates a router fed by a loadDiscovery on a manual clock
+func newLoadRouter(t *testing.T) (*Router, *loadDiscovery, *clock.Manual) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Agents.MaxQueueDepth = 5
+	cfg.Agents.Discovery.MissGrace = 3
+	r := newTestRouter(t, cfg)
+	c := clock.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r.SetClock(c)
+	d := &loadDiscovery{agents: make(map[string]AgentInfo)}
+	r.SetDiscovery(d)
+	return r, d, c
+}
+
+func TestHighReportedLoadIsDeprioritized(t *testing.T) {
+	r, d, c := newLoadRouter(t)
+	caps := []string{"go"}
+	d.report(AgentInfo{ID: "dev-1", Name: "dev", Capabilities: caps, Status: AgentStatusReady, LastSeen: c.Now(), InFlight: 4, QueueDepth: 3})
+	d.report(AgentInfo{ID: "review-1", Name: "review", Capabilities: caps, Status: AgentStatusReady, LastSeen: c.Now(), InFlight: 1})
+	r.refreshAgentList(context.Background())
+
+	task := &Task{Type: TaskCodeWrite, Capabilities: map[string]float64{"go": 1}}
+	if agents, err := r.Route(task); err != nil || !slices.Equal(agents, []string{"review"}) {
+		t.Fatalf("route = %v, %v; want the less loaded review", agents, err)
+	}
+
+	// Fresh heartbeats flip the balance
+	c.Advance(10 * time.Second)
+	d.report(AgentInfo{ID: "dev-1", Name: "dev", Capabilities: caps, Status: AgentStatusReady, LastSeen: c.Now()})
+	d.report(AgentInfo{ID: "review-1", Name: "review", Capabilities: caps, Status: AgentStatusReady, LastSeen: c.Now(), InFlight: 2, QueueDepth: 2})
+	r.refreshAgentList(context.Background())
+	if agents, err := r.Route(task); err != nil || !slices.Equal(agents, []string{"dev"}) {
+		t.Errorf("route = %v, %v; want dev once its load cleared", agents, err)
+	}
+}
+
+func TestOverloadedAgentStopsGettingOptionalWork(t *testing.T) {
+	r, d, c := newLoadRouter(t)
+	beat := func(queueDepth int, at time.Time) {
+		d.report(AgentInfo{ID: "explain-1", Name: "explain", Status: AgentStatusReady, LastSeen: c.Now()})
+		d.report(AgentInfo{ID: "retrieval-1", Name: "retrieval", Status: AgentStatusReady, LastSeen: at, QueueDepth: queueDepth})
+		r.refreshAgentList(context.Background())
+	}
+	route := func() []string {
+		t.Helper()
+		agents, err := r.Route(&Task{Type: TaskQuestion})
+		if err != nil {
+			t.Fatalf("Route: %v", err)
+		}
+		return agents
+	}
+
+	overloadedAt := c.Now()
+	beat(9, overloadedAt)
+	if agents := route(); slices.Contains(agents, "retrieval") {
+		t.Errorf("route = %v, want the overloaded retrieval skipped", agents)
+	}
+
+	// A repeated, stale heartbeat does not count as the load coming down
+	c.Advance(10 * time.Second)
+	beat(0, overloadedAt)
+	if got := agentStatus(r, "retrieval"); got != AgentStatusDegraded {
+		t.Fatalf("status = %s after a stale heartbeat, want degraded but routable", got)
+	}
+	if agents := route(); slices.Contains(agents, "retrieval") {
+		t.Errorf("route = %v after a stale heartbeat, want retrieval still skipped", agents)
+	}
+
+	c.Advance(10 * time.Second)
+	beat(1, c.Now())
+	if agents := route(); !slices.Contains(agents, "retrieval") {
+		t.Errorf("route = %v, want retrieval back once its queue drained", agents)
+	}
+}
+
+func TestOverloadedAgentKeepsRequiredStages(t *testing.T) {
+	r, d, c := newLoadRouter(t)
+	d.report(AgentInfo{ID: "explain-1", Name: "explain", Status: AgentStatusReady, LastSeen: c.Now(), InFlight: 2, Capacity: 2})
+	r.refreshAgentList(context.Background())
+
+	if agents, err := r.Route(&Task{Type: TaskQuestion}); err != nil || !slices.Contains(agents, "explain") {
+		t.Errorf("route = %v, %v; want the required stage kept at capacity", agents, err)
+	}
+	if !r.Saturated([]string{"explain"}) {
+		t.Error("agent at capacity not reported saturated")
+	}
+}
@@ -0,0 +1,50 @@
+package router
+
+import (
+	"strings"
+	"text/template"
+)
+
+// namer derives display names for tasks that were submitted without one
+type namer struct {
+	tmpl *template.Template
+}
+
+// newNamer compiles the display name template. An empty or malformed
+// template yields a namer that always falls back to the task ID.
+func newNamer(text string) (*namer, error) {
+	if text == "" {
+		return &namer{}, nil
+	}
+	tmpl, err := template.New("task-name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return &namer{}, err
+	}
+	return &namer{tmpl: tmpl}, nil
+}
+
+// name renders the template for a task, falling back to its ID when the
+// template is unset, fails to execute, or renders nothing
+func (n *namer) name(task *Task) string {
+	if n.tmpl == nil {
+		return task.ID
+	}
+	var b strings.Builder
+	if err := n.tmpl.Execute(&b, task); err != nil {
+		return task.ID
+	}
+	name := strings.TrimSpace(b.String())
+	if name == "" {
+		return task.ID
+	}
+	return name
+}
+
+// DisplayName returns the task's name, deriving one from the configured
+// template when it was submitted without a name
+func (r *Router) DisplayName(task *Task) string {
+	if task.Name != "" {
+		return task.Name
+	}
+	return r.namer.name(task)
+}
@@ -0,0 +1,52 @@
+package router
+
+import "testing"
+
+func TestNameTemplateRenders(t *testing.T) {
+	n, err := newNamer("{{.Type}}:{{.Labels.repo}}")
+	if err != nil {
+		t.Fatalf("newNamer: %v", err)
+	}
+	task := &Task{ID: "t-1", Type: TaskCodeReview, Labels: map[string]string{"repo": "odin"}}
+	if got := n.name(task); got != "code_review:odin" {
+		t.Errorf("name = %q, want code_review:odin", got)
+	}
+}
+
+func TestNameTemplateFallsBackToID(t *testing.T) {
+	if _, err := newNamer("{{.Type"); err == nil {
+		t.Error("malformed template compiled")
+	}
+
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"unset", ""},
+		{"malformed", "{{.Type"},
+		{"missing label", "{{.Labels.repo}}"},
+		{"unknown field", "{{.Nonesuch}}"},
+		{"renders blank", "  {{.Name}} "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, _ := newNamer(tt.template)
+			if got := n.name(&Task{ID: "t-1", Type: TaskQuestion}); got != "t-1" {
+				t.Errorf("name = %q, want the task ID", got)
+			}
+		})
+	}
+}
+
+func TestDisplayNamePrefersGivenName(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.TaskNameTemplate = "{{.Type}}"
+	r := newTestRouter(t, cfg)
+
+	if got := r.DisplayName(&Task{ID: "t-1", Name: "nightly review", Type: TaskCodeReview}); got != "nightly review" {
+		t.Errorf("DisplayName = %q, want the given name", got)
+	}
+	if got := r.DisplayName(&Task{ID: "t-2", Type: TaskCodeReview}); got != "code_review" {
+		t.Errorf("DisplayName = %q, want the templated name", got)
+	}
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFallbackIsNextBestMatchWithRoom(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.PreferWait = 10
+	r := newTestRouter(t, cfg)
+	r.RegisterAgent(&AgentInfo{ID: "oracle-1", Name: "oracle_code", Status: AgentStatusReady,
+		CapabilityWeights: map[string]float64{"go": 3}, Capacity: 2, InFlight: 2})
+	r.RegisterAgent(&AgentInfo{ID: "test-1", Name: "test", Status: AgentStatusReady,
+		CapabilityWeights: map[string]float64{"go": 2}, Capacity: 1, InFlight: 1})
+	r.RegisterAgent(&AgentInfo{ID: "dev-1", Name: "dev", Status: AgentStatusReady,
+		CapabilityWeights: map[string]float64{"go": 1}})
+	task := &Task{Type: TaskCodeWrite, Capabilities: map[string]float64{"go": 1}}
+
+	preferred, err := r.Route(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(preferred, []string{"oracle_code"}) {
+		t.Fatalf("routed to %v, want the best match though it is busy", preferred)
+	}
+	if !r.Saturated(preferred) {
+		t.Error("oracle_code at capacity not reported saturated")
+	}
+	// test matches better than dev but is saturated too
+	if got := r.Fallback(task, preferred); !slices.Equal(got, []string{"dev"}) {
+		t.Errorf("fallback = %v, want [dev]", got)
+	}
+
+	if r.Saturated([]string{"oracle_code", "dev"}) {
+		t.Error("saturated with dev free")
+	}
+	if r.Saturated(nil) {
+		t.Error("no agents reported saturated")
+	}
+	if got := r.Fallback(&Task{Type: TaskCodeWrite}, preferred); got != nil {
+		t.Errorf("fallback for a task without capabilities = %v", got)
+	}
+
+	cfg.Agents.PreferWait = 0
+	if got := r.Fallback(task, preferred); got != nil {
+		t.Errorf("fallback with the window off = %v", got)
+	}
+}
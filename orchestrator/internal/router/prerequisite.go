@@ -0,0 +1,99 @@
+package router
+
+import (
+	"maps"
+	"strings"
+	"text/template"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// InputPrerequisiteFor is the input key naming the task a generated
+// prerequisite was added for
+const InputPrerequisiteFor = "prerequisite_for"
+
+// prerequisite is a compiled orchestrator.prerequisites entry
+type prerequisite struct {
+	taskType    TaskType
+	description *template.Template // nil copies the dependent's
+}
+
+// newPrerequisites compiles the configured prerequisites, dropping
+// entries for unknown task types or with a malformed description
+func newPrerequisites(cfg map[string][]config.PrerequisiteConfig, logger *zap.Logger) map[TaskType][]prerequisite {
+	out := make(map[TaskType][]prerequisite, len(cfg))
+	for taskType, entries := range cfg {
+		if !TaskType(taskType).Valid() {
+			logger.Warn("Ignoring prerequisites of unknown task type", zap.String("type", taskType))
+			continue
+		}
+		for _, pc := range entries {
+			p := prerequisite{taskType: TaskType(pc.Type)}
+			if !p.taskType.Valid() {
+				logger.Warn("Ignoring prerequisite of unknown task type",
+					zap.String("type", taskType),
+					zap.String("prerequisite", pc.Type),
+				)
+				continue
+			}
+			if pc.Description != "" {
+				tmpl, err := template.New("prerequisite").Option("missingkey=error").Parse(pc.Description)
+				if err != nil {
+					logger.Warn("Invalid prerequisite description, copying the task's",
+						zap.String("type", taskType),
+						zap.Error(err),
+					)
+				} else {
+					p.description = tmpl
+				}
+			}
+			out[TaskType(taskType)] = append(out[TaskType(taskType)], p)
+		}
+	}
+	return out
+}
+
+// Prerequisites builds the tasks configured to run ahead of task and adds
+// them to its dependencies. A task that opts out gets none, and the
+// prerequisites themselves are never expanded further. They share the
+// task's priority, labels and request.
+func (r *Router) Prerequisites(task *Task) []*Task {
+	if task.NoPrerequisites {
+		return nil
+	}
+	prereqs := r.prerequisites[task.Type]
+	if len(prereqs) == 0 {
+		return nil
+	}
+
+	out := make([]*Task, 0, len(prereqs))
+	for _, p := range prereqs {
+		pre := &Task{
+			ID:              NewTaskID(),
+			Labels:          maps.Clone(task.Labels),
+			Type:            p.taskType,
+			Description:     p.describe(task),
+			Input:           map[string]interface{}{InputPrerequisiteFor: task.ID},
+			Priority:        task.Priority,
+			RequestID:       task.RequestID,
+			NoPrerequisites: true,
+		}
+		task.Dependencies = append(task.Dependencies, pre.ID)
+		out = append(out, pre)
+	}
+	return out
+}
+
+// describe renders the prerequisite's description for the task it runs
+// ahead of, copying that task's when the template is unset or fails
+func (p prerequisite) describe(task *Task) string {
+	if p.description == nil {
+		return task.Description
+	}
+	var b strings.Builder
+	if err := p.description.Execute(&b, task); err != nil {
+		return task.Description
+	}
+	return b.String()
+}
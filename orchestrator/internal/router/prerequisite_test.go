@@ -0,0 +1,68 @@
+package router
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+func prerequisiteConfig() *config.Config {
+	cfg := testConfig()
+	cfg.Orchestrator.Prerequisites = map[string][]config.PrerequisiteConfig{
+		"code_write": {
+			{Type: "analysis", Description: "Gather context for: {{.Description}}"},
+			{Type: "question"},
+			{Type: "retrieval"}, // an agent, not a task type
+		},
+		"translate": {{Type: "analysis"}},
+	}
+	return cfg
+}
+
+func TestCodeWriteGetsItsPrerequisites(t *testing.T) {
+	r := newTestRouter(t, prerequisiteConfig())
+	task := &Task{
+		ID:           "write-1",
+		Type:         TaskCodeWrite,
+		Description:  "add retries",
+		Priority:     2,
+		Labels:       map[string]string{"team": "core"},
+		Dependencies: []string{"earlier"},
+	}
+
+	pres := r.Prerequisites(task)
+	if len(pres) != 2 {
+		t.Fatalf("%d prerequisites, want analysis and question with retrieval dropped", len(pres))
+	}
+	analysis, question := pres[0], pres[1]
+	if analysis.Type != TaskAnalysis || analysis.Description != "Gather context for: add retries" {
+		t.Errorf("first prerequisite = %s %q", analysis.Type, analysis.Description)
+	}
+	if question.Type != TaskQuestion || question.Description != "add retries" {
+		t.Errorf("second prerequisite = %s %q, want the task's description copied", question.Type, question.Description)
+	}
+	for _, pre := range pres {
+		if pre.ID == "" || pre.Priority != 2 || pre.Labels["team"] != "core" || !pre.NoPrerequisites {
+			t.Errorf("prerequisite %+v, want an ID, the task's priority and labels, and no expansion", pre)
+		}
+		if pre.Input[InputPrerequisiteFor] != "write-1" {
+			t.Errorf("prerequisite input = %v, want it to name the task", pre.Input)
+		}
+	}
+	if want := []string{"earlier", analysis.ID, question.ID}; !slices.Equal(task.Dependencies, want) {
+		t.Errorf("dependencies = %v, want %v", task.Dependencies, want)
+	}
+}
+
+func TestPrerequisitesOptOutAndUnconfiguredTypes(t *testing.T) {
+	r := newTestRouter(t, prerequisiteConfig())
+
+	optedOut := &Task{Type: TaskCodeWrite, NoPrerequisites: true}
+	if pres := r.Prerequisites(optedOut); pres != nil || len(optedOut.Dependencies) != 0 {
+		t.Errorf("opted-out task got %v and dependencies %v", pres, optedOut.Dependencies)
+	}
+	if pres := r.Prerequisites(&Task{Type: TaskQuestion}); pres != nil {
+		t.Errorf("task type without prerequisites got %v", pres)
+	}
+}
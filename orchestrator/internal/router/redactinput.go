@@ -0,0 +1,90 @@
+package router
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// redactedFields returns the payload fields withheld from an agent for a
+// task of the given type, sorted and without duplicates
+func (r *Router) redactedFields(agent string, taskType TaskType) []string {
+	rc := r.config.Agents.RedactInput
+	fields := slices.Concat(rc.Agents[agent], rc.TaskTypes[string(taskType)])
+	slices.Sort(fields)
+	return slices.Compact(fields)
+}
+
+// dispatches splits a routed task into one message per view of its
+// payload: agents with the same fields withheld share a message, in the
+// order the agents were routed
+func (r *Router) dispatches(task *Task, agents []string, versions map[string]string) []routedTask {
+	var out []routedTask
+	byFields := make(map[string]int) // index into out
+	for _, name := range agents {
+		fields := r.redactedFields(name, task.Type)
+		key := strings.Join(fields, ",")
+		i, ok := byFields[key]
+		if !ok {
+			i = len(out)
+			byFields[key] = i
+			out = append(out, routedTask{Task: withoutFields(task, fields), Redacted: fields})
+		}
+		out[i].Agents = append(out[i].Agents, name)
+		if version, ok := versions[name]; ok {
+			if out[i].Versions == nil {
+				out[i].Versions = make(map[string]string)
+			}
+			out[i].Versions[name] = version
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, routedTask{Task: task, Agents: agents})
+	}
+	return out
+}
+
+// withoutFields returns a copy of task with the given payload fields
+// removed. Only the maps on the way to a removed field are copied, so
+// the task itself keeps its full payload.
+func withoutFields(task *Task, fields []string) *Task {
+	if len(fields) == 0 {
+		return task
+	}
+	redacted := *task
+	for _, field := range fields {
+		root, path, _ := strings.Cut(field, ".")
+		switch root {
+		case "input":
+			redacted.Input, _ = removePath(redacted.Input, strings.Split(path, "."))
+		case "context":
+			redacted.Context, _ = removePath(redacted.Context, strings.Split(path, "."))
+		}
+	}
+	return &redacted
+}
+
+// removePath returns m without the value at path and whether there was
+// one; m is returned as it is when there was not
+func removePath(m map[string]interface{}, path []string) (map[string]interface{}, bool) {
+	value, ok := m[path[0]]
+	if !ok {
+		return m, false
+	}
+	if len(path) == 1 {
+		out := maps.Clone(m)
+		delete(out, path[0])
+		return out, true
+	}
+	child, isMap := value.(map[string]interface{})
+	if !isMap {
+		return m, false
+	}
+	child, removed := removePath(child, path[1:])
+	if !removed {
+		return m, false
+	}
+	out := maps.Clone(m)
+	out[path[0]] = child
+	return out, true
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// secretTask is a code review carrying credentials in its payload
+func secretTask() *Task {
+	return &Task{
+		ID:   "t-1",
+		Type: TaskCodeReview,
+		Input: map[string]interface{}{
+			"repo": map[string]interface{}{"url": "git@example.com:odin.git", "token": "ghp-hunter2"},
+		},
+		Context: map[string]interface{}{
+			"diff":     "+retry()",
+			"secrets":  "db-password",
+			"internal": "ops notes",
+		},
+	}
+}
+
+func TestRestrictedAgentGetsRedactedMessage(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.RedactInput = config.InputRedactionConfig{
+		Agents:    map[string][]string{"review": {"input.repo.token", "context.secrets"}},
+		TaskTypes: map[string][]string{"code_review": {"context.internal"}},
+	}
+	r := newTestRouter(t, cfg, "review", "security")
+	task := secretTask()
+	agents, err := r.Route(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := r.dispatches(task, agents, map[string]string{"review": "v2", "security": "v1"})
+	if len(messages) != 2 {
+		t.Fatalf("%d messages, want one per view of the payload", len(messages))
+	}
+	review, security := messages[0], messages[1]
+	if !slices.Equal(review.Agents, []string{"review"}) || !slices.Equal(security.Agents, []string{"security"}) {
+		t.Fatalf("agents = %v and %v", review.Agents, security.Agents)
+	}
+	if want := []string{"context.internal", "context.secrets", "input.repo.token"}; !slices.Equal(review.Redacted, want) {
+		t.Errorf("review redacted %v, want %v", review.Redacted, want)
+	}
+	if !slices.Equal(security.Redacted, []string{"context.internal"}) {
+		t.Errorf("security redacted %v, want the task type's field only", security.Redacted)
+	}
+	if review.Versions["review"] != "v2" || len(review.Versions) != 1 {
+		t.Errorf("review versions = %v, want its own only", review.Versions)
+	}
+
+	// What is published to review holds none of the withheld values
+	payload, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"ghp-hunter2", "db-password", "ops notes"} {
+		if strings.Contains(string(payload), secret) {
+			t.Errorf("review's message contains %q: %s", secret, payload)
+		}
+	}
+	repo, _ := review.Task.Input["repo"].(map[string]interface{})
+	if repo["url"] == nil || review.Task.Context["diff"] == nil {
+		t.Errorf("review lost fields it may see: %s", payload)
+	}
+	if security.Task.Context["secrets"] != "db-password" || security.Task.Context["internal"] != nil {
+		t.Errorf("security context = %v", security.Task.Context)
+	}
+
+	// The orchestrator keeps the full payload
+	if full := secretTask(); !jsonEqual(t, task, full) {
+		t.Errorf("routed task changed: %+v", task)
+	}
+}
+
+func TestNoRulesPublishesOneMessage(t *testing.T) {
+	r := newTestRouter(t, testConfig(), "review", "security")
+	task := secretTask()
+
+	messages := r.dispatches(task, []string{"review", "security"}, nil)
+	if len(messages) != 1 || messages[0].Task != task || len(messages[0].Redacted) != 0 {
+		t.Errorf("messages = %+v, want the task as it is", messages)
+	}
+	if !slices.Equal(messages[0].Agents, []string{"review", "security"}) {
+		t.Errorf("agents = %v", messages[0].Agents)
+	}
+}
+
+func jsonEqual(t *testing.T, a, b interface{}) bool {
+	t.Helper()
+	x, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(x) == string(y)
+}
@@ -0,0 +1,214 @@
+// =============================================================================
+// ODIN v7.0 - Blue/Green Agent Rollouts
+// =============================================================================
+// Weighted traffic splitting between a stable and a canary agent version
+// =============================================================================
+
+package router
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Rollout errors
+var (
+	ErrRolloutNotFound = errors.New("rollout not found")
+	ErrInvalidWeight   = errors.New("canary weight must be between 0 and 100")
+)
+
+// Rollout status values
+const (
+	RolloutActive     = "active"
+	RolloutRolledBack = "rolled_back"
+)
+
+// Rollout describes a traffic split for one agent name
+type Rollout struct {
+	Agent          string  `json:"agent"`
+	Stable         string  `json:"stable"`
+	Canary         string  `json:"canary"`
+	CanaryWeight   int     `json:"canary_weight"`
+	MaxFailureRate float64 `json:"max_failure_rate"`
+	MinSamples     int     `json:"min_samples"`
+	Status         string  `json:"status"`
+
+	CanaryTotal    int `json:"canary_total"`
+	CanaryFailures int `json:"canary_failures"`
+}
+
+// rollouts tracks active traffic splits, keyed by agent name
+type rollouts struct {
+	mu     sync.Mutex
+	logger *zap.Logger
+	rng    *rand.Rand
+	byName map[string]*Rollout
+}
+
+func newRollouts(cfgs []config.RolloutConfig, logger *zap.Logger) *rollouts {
+	rs := &rollouts{
+		logger: logger,
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		byName: make(map[string]*Rollout),
+	}
+	for _, rc := range cfgs {
+		if err := rs.set(rolloutFromConfig(rc)); err != nil {
+			logger.Warn("Ignoring invalid rollout",
+				zap.String("agent", rc.Agent),
+				zap.Error(err),
+			)
+		}
+	}
+	return rs
+}
+
+func rolloutFromConfig(rc config.RolloutConfig) *Rollout {
+	return &Rollout{
+		Agent:          rc.Agent,
+		Stable:         rc.Stable,
+		Canary:         rc.Canary,
+		CanaryWeight:   rc.CanaryWeight,
+		MaxFailureRate: rc.MaxFailureRate,
+		MinSamples:     rc.MinSamples,
+	}
+}
+
+// set installs or replaces a rollout, resetting its counters
+func (rs *rollouts) set(ro *Rollout) error {
+	if ro.Agent == "" || ro.Stable == "" || ro.Canary == "" {
+		return fmt.Errorf("rollout requires agent, stable and canary versions")
+	}
+	if ro.CanaryWeight < 0 || ro.CanaryWeight > 100 {
+		return ErrInvalidWeight
+	}
+	if ro.MinSamples <= 0 {
+		ro.MinSamples = 20
+	}
+	ro.Status = RolloutActive
+	ro.CanaryTotal = 0
+	ro.CanaryFailures = 0
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.byName[ro.Agent] = ro
+	return nil
+}
+
+// pick returns the version new work for an agent should go to, or "" when
+// the agent has no rollout
+func (rs *rollouts) pick(agent string) string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	ro, ok := rs.byName[agent]
+	if !ok {
+		return ""
+	}
+	if ro.Status == RolloutActive && rs.rng.Intn(100) < ro.CanaryWeight {
+		return ro.Canary
+	}
+	return ro.Stable
+}
+
+// record tallies a canary outcome and pulls the canary once its failure
+// rate exceeds the threshold over enough samples
+func (rs *rollouts) record(agent, version string, success bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	ro, ok := rs.byName[agent]
+	if !ok || ro.Status != RolloutActive || version != ro.Canary {
+		return
+	}
+
+	ro.CanaryTotal++
+	if !success {
+		ro.CanaryFailures++
+	}
+
+	if ro.MaxFailureRate <= 0 || ro.CanaryTotal < ro.MinSamples {
+		return
+	}
+
+	rate := float64(ro.CanaryFailures) / float64(ro.CanaryTotal)
+	if rate > ro.MaxFailureRate {
+		ro.CanaryWeight = 0
+		ro.Status = RolloutRolledBack
+		rs.logger.Warn("Canary rolled back",
+			zap.String("agent", agent),
+			zap.String("canary", ro.Canary),
+			zap.Float64("failure_rate", rate),
+		)
+	}
+}
+
+// SetRollout installs or replaces the rollout for an agent
+func (r *Router) SetRollout(ro Rollout) error {
+	if err := r.rollouts.set(&ro); err != nil {
+		return err
+	}
+	r.logger.Info("Rollout configured",
+		zap.String("agent", ro.Agent),
+		zap.String("stable", ro.Stable),
+		zap.String("canary", ro.Canary),
+		zap.Int("canary_weight", ro.CanaryWeight),
+	)
+	return nil
+}
+
+// SetCanaryWeight adjusts the canary share of an existing rollout.
+// Setting a weight re-activates a rolled-back rollout.
+func (r *Router) SetCanaryWeight(agent string, weight int) error {
+	if weight < 0 || weight > 100 {
+		return ErrInvalidWeight
+	}
+
+	r.rollouts.mu.Lock()
+	defer r.rollouts.mu.Unlock()
+
+	ro, ok := r.rollouts.byName[agent]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRolloutNotFound, agent)
+	}
+	ro.CanaryWeight = weight
+	ro.Status = RolloutActive
+	return nil
+}
+
+// RemoveRollout ends a rollout, sending all traffic to whatever version
+// the agent's instances run
+func (r *Router) RemoveRollout(agent string) error {
+	r.rollouts.mu.Lock()
+	defer r.rollouts.mu.Unlock()
+
+	if _, ok := r.rollouts.byName[agent]; !ok {
+		return fmt.Errorf("%w: %s", ErrRolloutNotFound, agent)
+	}
+	delete(r.rollouts.byName, agent)
+	return nil
+}
+
+// GetRollouts returns a snapshot of all rollouts
+func (r *Router) GetRollouts() []Rollout {
+	r.rollouts.mu.Lock()
+	defer r.rollouts.mu.Unlock()
+
+	out := make([]Rollout, 0, len(r.rollouts.byName))
+	for _, ro := range r.rollouts.byName {
+		out = append(out, *ro)
+	}
+	return out
+}
+
+// ReportOutcome feeds a task result for an agent version into rollout
+// health tracking
+func (r *Router) ReportOutcome(agent, version string, success bool) {
+	r.rollouts.record(agent, version, success)
+}
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRolloutSplitsTrafficByWeight(t *testing.T) {
+	rs := newRollouts(nil, zap.NewNop())
+	rs.rng = rand.New(rand.NewSource(1))
+	if err := rs.set(&Rollout{Agent: "dev", Stable: "v1", Canary: "v2", CanaryWeight: 10}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	const picks = 10000
+	canary := 0
+	for i := 0; i < picks; i++ {
+		if rs.pick("dev") == "v2" {
+			canary++
+		}
+	}
+	// 10% of 10000, give or take a generous margin
+	if canary < 800 || canary > 1200 {
+		t.Errorf("canary got %d of %d picks, want about 1000", canary, picks)
+	}
+	if got := rs.pick("review"); got != "" {
+		t.Errorf("agent without a rollout picked %q, want none", got)
+	}
+}
+
+func TestFailingCanaryIsPulled(t *testing.T) {
+	r := newTestRouter(t, testConfig())
+	err := r.SetRollout(Rollout{Agent: "dev", Stable: "v1", Canary: "v2", CanaryWeight: 50, MaxFailureRate: 0.2, MinSamples: 10})
+	if err != nil {
+		t.Fatalf("SetRollout: %v", err)
+	}
+
+	// Stable failures never count against the canary
+	for i := 0; i < 20; i++ {
+		r.ReportOutcome("dev", "v1", false)
+	}
+	for i := 0; i < 9; i++ {
+		r.ReportOutcome("dev", "v2", i%2 == 0)
+	}
+	if ro := r.GetRollouts()[0]; ro.Status != RolloutActive {
+		t.Fatalf("rolled back after %d canary samples, below the minimum", ro.CanaryTotal)
+	}
+
+	r.ReportOutcome("dev", "v2", false)
+	ro := r.GetRollouts()[0]
+	if ro.Status != RolloutRolledBack || ro.CanaryWeight != 0 {
+		t.Fatalf("rollout = %+v, want rolled back with no canary weight", ro)
+	}
+	for i := 0; i < 100; i++ {
+		if got := r.rollouts.pick("dev"); got != "v1" {
+			t.Fatalf("pick after rollback = %q, want the stable version", got)
+		}
+	}
+
+	if err := r.SetCanaryWeight("dev", 101); !errors.Is(err, ErrInvalidWeight) {
+		t.Errorf("SetCanaryWeight(101) = %v, want ErrInvalidWeight", err)
+	}
+	if err := r.SetCanaryWeight("dev", 5); err != nil {
+		t.Fatalf("SetCanaryWeight: %v", err)
+	}
+	if ro := r.GetRollouts()[0]; ro.Status != RolloutActive {
+		t.Errorf("status = %s after a new weight, want active", ro.Status)
+	}
+}
@@ -8,11 +8,22 @@ package router
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"sync"
 	"time"
 
+	"github.com/krigsexe/odin/orchestrator/internal/clock"
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/internal/schema"
+	"github.com/krigsexe/odin/orchestrator/internal/stream"
+	"github.com/krigsexe/odin/orchestrator/internal/trace"
 	"github.com/krigsexe/odin/orchestrator/pkg/config"
 	"go.uber.org/zap"
 )
@@ -21,72 +32,272 @@ import (
 type TaskType string
 
 const (
-	TaskCodeWrite   TaskType = "code_write"
-	TaskCodeModify  TaskType = "code_modify"
-	TaskCodeDebug   TaskType = "code_debug"
-	TaskCodeReview  TaskType = "code_review"
-	TaskTest        TaskType = "test"
-	TaskAnalysis    TaskType = "analysis"
-	TaskQuestion    TaskType = "question"
+	TaskCodeWrite  TaskType = "code_write"
+	TaskCodeModify TaskType = "code_modify"
+	TaskCodeDebug  TaskType = "code_debug"
+	TaskCodeReview TaskType = "code_review"
+	TaskTest       TaskType = "test"
+	TaskAnalysis   TaskType = "analysis"
+	TaskQuestion   TaskType = "question"
 )
 
+// Valid reports whether the task type is one of the known categories
+func (t TaskType) Valid() bool {
+	switch t {
+	case TaskCodeWrite, TaskCodeModify, TaskCodeDebug, TaskCodeReview,
+		TaskTest, TaskAnalysis, TaskQuestion:
+		return true
+	}
+	return false
+}
+
 // Task represents a unit of work
 type Task struct {
-	ID          string                 `json:"id"`
-	Type        TaskType               `json:"type"`
-	Description string                 `json:"description"`
-	Input       map[string]interface{} `json:"input"`
-	Context     map[string]interface{} `json:"context"`
-	Priority    int                    `json:"priority"`
-	CreatedAt   time.Time              `json:"created_at"`
-	Timeout     time.Duration          `json:"timeout"`
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name,omitempty"`
+	Labels       map[string]string      `json:"labels,omitempty"`
+	Type         TaskType               `json:"type"`
+	Description  string                 `json:"description"`
+	Input        map[string]interface{} `json:"input"`
+	Context      map[string]interface{} `json:"context"`
+	Priority     int                    `json:"priority"`
+	CreatedAt    time.Time              `json:"created_at"`
+	Timeout      time.Duration          `json:"timeout"`
+	Dependencies []string               `json:"dependencies,omitempty"`
+
+	// RequestID is the API request that submitted the task
+	RequestID string `json:"request_id,omitempty"`
+
+	// Retry overrides the default retry policy for this task
+	Retry *scheduler.RetryPolicy `json:"retry,omitempty"`
+
+	// Optional LLM overrides, bypassing the configured primary provider
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+
+	// Optional caps on LLM spend across every call and retry of the task
+	MaxTokens int     `json:"max_tokens,omitempty"`
+	MaxCost   float64 `json:"max_cost,omitempty"`
+
+	// Params override the configured generation parameters, such as
+	// temperature and stop sequences, for the task's LLM calls
+	Params *config.GenerationParams `json:"params,omitempty"`
+
+	// Trace forces debug tracing; Traced is the decision made at submit,
+	// which also covers tasks picked by sampling
+	Trace  bool `json:"trace,omitempty"`
+	Traced bool `json:"traced,omitempty"`
+
+	// Exclude lists agent instances, by ID, the task must not be routed to
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Sandbox narrows what the task may touch; set to the effective policy
+	// when the task is submitted
+	Sandbox *SandboxPolicy `json:"sandbox,omitempty"`
+
+	// Capabilities asks for the agent best matching these weighted tags
+	// in place of the task type's route. A tag may carry a version
+	// constraint, e.g. "python>=3.12", which the agent must satisfy.
+	Capabilities map[string]float64 `json:"capabilities,omitempty"`
+
+	// MaxQueueTime fails the task if it is still queued this long after
+	// submission; unlike Timeout it never applies once the task runs
+	MaxQueueTime time.Duration `json:"max_queue_time,omitempty"`
+
+	// Compensate undoes this task's effects if it completed but the
+	// all-or-nothing batch it was submitted in failed
+	Compensate *Task `json:"compensate,omitempty"`
+
+	// ResultSchema is a JSON Schema the result must match for the task
+	// to complete; a result that does not is retried as invalid
+	ResultSchema *schema.Schema `json:"result_schema,omitempty"`
+
+	// PinnedInstance names the only orchestrator instance that may
+	// dispatch the task, for tasks with side effects local to one node
+	PinnedInstance string `json:"pinned_instance,omitempty"`
+
+	// NoPrerequisites opts out of the prerequisite tasks configured for
+	// the task's type
+	NoPrerequisites bool `json:"no_prerequisites,omitempty"`
+}
+
+// Budget returns the task's LLM spend caps, or nil when it has none
+func (t *Task) Budget() *llm.Budget {
+	if t.MaxTokens == 0 && t.MaxCost == 0 {
+		return nil
+	}
+	return &llm.Budget{MaxTokens: t.MaxTokens, MaxCost: t.MaxCost}
 }
 
+// NewTaskID generates a random task identifier
+func NewTaskID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("task-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Routing errors
+var (
+	ErrAgentNotFound    = errors.New("agent not found")
+	ErrAgentNotDraining = errors.New("agent is not draining")
+
+	ErrRequiredAgentUnavailable = errors.New("required agent unavailable")
+)
+
+// Agent status values
+const (
+	AgentStatusReady    = "ready"
+	AgentStatusDraining = "draining"
+	AgentStatusDegraded = "degraded" // Missed a heartbeat, still routable
+	AgentStatusOffline  = "offline"  // Missed the grace count, not routable
+)
+
 // AgentInfo holds agent metadata
 type AgentInfo struct {
-	ID           string   `json:"id"`
-	Name         string   `json:"name"`
-	Capabilities []string `json:"capabilities"`
-	Status       string   `json:"status"`
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Capabilities []string  `json:"capabilities"`
+	Status       string    `json:"status"`
+	Version      string    `json:"version,omitempty"`
 	LastSeen     time.Time `json:"last_seen"`
+
+	// CapabilityWeights scores how strong the agent is at a capability,
+	// overriding the implicit 1 of a plain Capabilities entry
+	CapabilityWeights map[string]float64 `json:"capability_weights,omitempty"`
+
+	// Misses counts consecutive discovery rounds without a fresh heartbeat
+	Misses int `json:"misses,omitempty"`
+
+	// Load as last reported in the agent's heartbeat: tasks in progress,
+	// tasks waiting in its own queue, and how many it runs at once
+	InFlight   int `json:"in_flight"`
+	QueueDepth int `json:"queue_depth"`
+	Capacity   int `json:"capacity,omitempty"`
 }
 
 // Router handles task routing to agents
 type Router struct {
-	config  *config.Config
-	logger  *zap.Logger
-	agents  map[string]*AgentInfo
-	mu      sync.RWMutex
+	config *config.Config
+	logger *zap.Logger
+	agents map[string]*AgentInfo
+	mu     sync.RWMutex
+
+	// Agents added by discovery (as opposed to RegisterAgent)
+	discovery  Discovery
+	discovered map[string]bool
+
+	// Agent names the operator drained. Kept apart from the agents'
+	// health, so a drain outlasts the agent going offline or being
+	// evicted and rediscovered.
+	drained map[string]bool
+
+	// Routing table: task type -> stages, in order
+	routes map[TaskType][]RouteStage
+
+	// Persists routing table edits; nil keeps them in memory
+	routeStore RouteStore
 
-	// Routing table: task type -> agent names
-	routes map[TaskType][]string
+	// Blue/green traffic splits per agent name
+	rollouts *rollouts
+
+	// Derives display names for unnamed tasks
+	namer *namer
+
+	// Per task type allow/deny lists, applied to every route
+	access map[TaskType]*accessList
+
+	// Per task type sandbox policies handed to agents
+	sandboxes map[TaskType]SandboxPolicy
+
+	// Per task type tasks submitted ahead of each one
+	prerequisites map[TaskType][]prerequisite
+
+	// Publishes routed tasks for agents; nil leaves them unpublished
+	publisher *stream.Publisher
+
+	// Picks the tasks traced without asking to be
+	sampler *trace.Sampler
+
+	// Reports LLM provider health for admission control
+	providerHealth HealthSource
+
+	// Receives agent events; nil drops them
+	events *events.Bus
+
+	// Source of time for heartbeats and audit stamps
+	clock clock.Clock
+}
+
+// routedTask is the payload published to the tasks stream. Redacted
+// names the payload fields withheld from these agents.
+type routedTask struct {
+	Task     *Task             `json:"task"`
+	Agents   []string          `json:"agents"`
+	Versions map[string]string `json:"versions,omitempty"`
+	Redacted []string          `json:"redacted,omitempty"`
+
+	// Attempt and the agent's instance are reported back with the result
+	Attempt   int               `json:"attempt"`
+	Instances map[string]string `json:"instances,omitempty"`
 }
 
 // New creates a new Router instance
 func New(cfg *config.Config, logger *zap.Logger) *Router {
 	r := &Router{
-		config:  cfg,
-		logger:  logger,
-		agents:  make(map[string]*AgentInfo),
-		routes:  make(map[TaskType][]string),
+		config:     cfg,
+		logger:     logger,
+		agents:     make(map[string]*AgentInfo),
+		discovered: make(map[string]bool),
+		drained:    make(map[string]bool),
+		routes:     make(map[TaskType][]RouteStage),
+		rollouts:   newRollouts(cfg.Agents.Rollouts, logger),
+		access:     newAccessLists(cfg.Agents.Access),
+		sandboxes:  newSandboxes(cfg.Agents.Sandbox, logger),
+		sampler:    trace.NewSampler(cfg.Orchestrator.Tracing.SampleRate),
+		clock:      clock.Real,
 	}
 
+	discovery, err := NewDiscovery(cfg)
+	if err != nil {
+		logger.Warn("Falling back to static agent discovery", zap.Error(err))
+		discovery = NewStaticDiscovery(cfg.Agents.Enabled)
+	}
+	r.discovery = discovery
+
+	namer, err := newNamer(cfg.Orchestrator.TaskNameTemplate)
+	if err != nil {
+		logger.Warn("Invalid task name template, using task IDs", zap.Error(err))
+	}
+	r.namer = namer
+	r.prerequisites = newPrerequisites(cfg.Orchestrator.Prerequisites, logger)
+
 	// Initialize default routes
 	r.initRoutes()
 
 	return r
 }
 
-// initRoutes sets up default routing table
+// RouteStage is one agent on a task type's route. A required stage with
+// no available agent fails routing; an optional one is skipped.
+type RouteStage struct {
+	Agent    string `json:"agent"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// initRoutes sets up default routing table. Retrieval only adds context,
+// so every route can run without it.
 func (r *Router) initRoutes() {
-	r.routes = map[TaskType][]string{
-		TaskCodeWrite:  {"retrieval", "dev", "approbation"},
-		TaskCodeModify: {"retrieval", "dev", "approbation"},
-		TaskCodeDebug:  {"retrieval", "dev", "oracle_code"},
-		TaskCodeReview: {"retrieval", "review", "security"},
-		TaskTest:       {"retrieval", "test", "oracle_code"},
-		TaskAnalysis:   {"retrieval", "analysis"},
-		TaskQuestion:   {"retrieval", "explain"},
+	retrieval := RouteStage{Agent: "retrieval", Optional: true}
+	r.routes = map[TaskType][]RouteStage{
+		TaskCodeWrite:  {retrieval, {Agent: "dev"}, {Agent: "approbation"}},
+		TaskCodeModify: {retrieval, {Agent: "dev"}, {Agent: "approbation"}},
+		TaskCodeDebug:  {retrieval, {Agent: "dev"}, {Agent: "oracle_code"}},
+		TaskCodeReview: {retrieval, {Agent: "review"}, {Agent: "security"}},
+		TaskTest:       {retrieval, {Agent: "test"}, {Agent: "oracle_code"}},
+		TaskAnalysis:   {retrieval, {Agent: "analysis"}},
+		TaskQuestion:   {retrieval, {Agent: "explain"}},
 	}
 }
 
@@ -101,37 +312,183 @@ func (r *Router) Start(ctx context.Context) error {
 	return r.routingLoop(ctx)
 }
 
+// SetDiscovery replaces the agent discovery backend
+func (r *Router) SetDiscovery(d Discovery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.discovery = d
+}
+
+// SetEvents attaches an event bus that agent status changes are
+// published to
+func (r *Router) SetEvents(bus *events.Bus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = bus
+}
+
+// SetClock replaces the router's time source
+func (r *Router) SetClock(c clock.Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clock = c
+}
+
+// SetPublisher sets where routed tasks are published for agents
+func (r *Router) SetPublisher(p *stream.Publisher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.publisher = p
+}
+
 // discoverAgents periodically discovers available agents
 func (r *Router) discoverAgents(ctx context.Context) {
 	ticker := time.NewTicker(time.Duration(r.config.Agents.HealthCheck) * time.Second)
 	defer ticker.Stop()
 
+	r.refreshAgentList(ctx)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			r.refreshAgentList()
+			r.refreshAgentList(ctx)
 		}
 	}
 }
 
-// refreshAgentList updates the list of available agents
-func (r *Router) refreshAgentList() {
-	// TODO: Query Redis for agent heartbeats
-	// For now, assume agents from config are available
+// refreshAgentList syncs the agent table with the discovery backend.
+// An agent without a fresh heartbeat is degraded after one miss and goes
+// offline after the configured grace; only a fresh heartbeat recovers it.
+// An agent the backend no longer reports at all is evicted once offline.
+// A drain survives all of this, and is back in force when the agent is.
+func (r *Router) refreshAgentList(ctx context.Context) {
+	r.mu.RLock()
+	discovery := r.discovery
+	r.mu.RUnlock()
+
+	found, err := discovery.Discover(ctx)
+	if err != nil {
+		r.logger.Warn("Agent discovery failed, keeping current list", zap.Error(err))
+		return
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for _, agentName := range r.config.Agents.Enabled {
-		if _, exists := r.agents[agentName]; !exists {
-			r.agents[agentName] = &AgentInfo{
-				ID:       fmt.Sprintf("%s-1", agentName),
-				Name:     agentName,
-				Status:   "ready",
-				LastSeen: time.Now(),
+	now := r.clock.Now()
+	fresh := make(map[string]bool, len(found))
+	reported := make(map[string]bool, len(found))
+	for _, info := range found {
+		reported[info.Name] = true
+		if info.LastSeen.IsZero() {
+			info.LastSeen = now
+		}
+		agent, exists := r.agents[info.Name]
+		if !exists {
+			if r.drained[info.Name] {
+				info.Status = AgentStatusDraining
 			}
+			r.agents[info.Name] = info
+			r.discovered[info.Name] = true
+			fresh[info.Name] = true
+			r.logger.Info("Agent discovered",
+				zap.String("id", info.ID),
+				zap.String("name", info.Name),
+			)
+			continue
+		}
+
+		// A backend may keep reporting the same heartbeat until it
+		// expires; that is not evidence the agent is still alive
+		if !info.LastSeen.After(agent.LastSeen) {
+			continue
+		}
+		fresh[info.Name] = true
+
+		agent.ID = info.ID
+		agent.Capabilities = info.Capabilities
+		agent.CapabilityWeights = info.CapabilityWeights
+		agent.LastSeen = info.LastSeen
+		agent.Misses = 0
+		agent.InFlight = info.InFlight
+		agent.QueueDepth = info.QueueDepth
+		agent.Capacity = info.Capacity
+		if agent.Status == AgentStatusDegraded || agent.Status == AgentStatusOffline {
+			agent.Status = r.healthyStatus(info.Name)
+			r.logger.Info("Agent recovered",
+				zap.String("name", info.Name),
+				zap.String("status", agent.Status),
+			)
+		}
+	}
+
+	for name := range r.discovered {
+		agent, exists := r.agents[name]
+		if !exists || fresh[name] {
+			continue
+		}
+		r.missedHeartbeat(agent, now)
+		if !reported[name] && agent.Status == AgentStatusOffline {
+			delete(r.agents, name)
+			delete(r.discovered, name)
+			r.logger.Info("Agent evicted",
+				zap.String("id", agent.ID),
+				zap.String("name", name),
+			)
+		}
+	}
+}
+
+// healthyStatus is the status of an agent in good health: ready, or
+// draining while the operator's drain holds. Callers hold r.mu.
+func (r *Router) healthyStatus(name string) string {
+	if r.drained[name] {
+		return AgentStatusDraining
+	}
+	return AgentStatusReady
+}
+
+// missedHeartbeat degrades an agent on its first miss and takes it
+// offline once both the miss count and grace period are exhausted
+func (r *Router) missedHeartbeat(agent *AgentInfo, now time.Time) {
+	dc := r.config.Agents.Discovery
+	agent.Misses++
+
+	grace := dc.MissGrace
+	if grace < 1 {
+		grace = 1
+	}
+	period := time.Duration(dc.MissGracePeriod) * time.Second
+
+	if agent.Misses >= grace && now.Sub(agent.LastSeen) >= period {
+		if agent.Status != AgentStatusOffline {
+			agent.Status = AgentStatusOffline
+			r.logger.Warn("Agent offline",
+				zap.String("name", agent.Name),
+				zap.Int("misses", agent.Misses),
+			)
+			r.events.Publish(events.Event{
+				Type:    events.AgentOffline,
+				Agent:   agent.Name,
+				Message: fmt.Sprintf("missed %d heartbeats", agent.Misses),
+			})
 		}
+		return
+	}
+
+	// Draining is operator intent and outranks degraded
+	if agent.Status == AgentStatusReady {
+		agent.Status = AgentStatusDegraded
+		r.logger.Warn("Agent degraded",
+			zap.String("name", agent.Name),
+			zap.Int("misses", agent.Misses),
+		)
 	}
 }
 
@@ -147,18 +504,56 @@ func (r *Router) Route(task *Task) ([]string, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	agents, ok := r.routes[task.Type]
+	// Capability requests pick one agent by match instead of the route
+	if len(task.Capabilities) > 0 {
+		agent, err := r.matchCapabilities(task)
+		if err != nil {
+			return nil, err
+		}
+		return []string{agent}, nil
+	}
+
+	stages, ok := r.routes[task.Type]
 	if !ok {
-		return []string{"dev"}, nil // Default to dev agent
+		agents := []string{"dev"} // Default to dev agent
+		if permitted := r.enforceAccess(task.Type, agents); len(permitted) == 0 {
+			return nil, fmt.Errorf("no permitted agents for task type: %s", task.Type)
+		}
+		return agents, nil
 	}
 
-	// Filter for available agents
+	// Access policy first, so a denied agent is never a candidate. The
+	// policy reshapes the route: a denied stage is dropped even if required.
+	names := make([]string, len(stages))
+	for i, stage := range stages {
+		names[i] = stage.Agent
+	}
+	permitted := make(map[string]bool)
+	for _, name := range r.enforceAccess(task.Type, names) {
+		permitted[name] = true
+	}
+
+	// Filter for available agents; a required stage must have one. An
+	// overloaded agent still takes required stages, but optional work is
+	// shed until its reported load comes down.
 	available := make([]string, 0)
-	for _, agentName := range agents {
-		if agent, exists := r.agents[agentName]; exists {
-			if agent.Status == "ready" {
-				available = append(available, agentName)
+	for _, stage := range stages {
+		if !permitted[stage.Agent] {
+			continue
+		}
+		if r.available(stage.Agent) && !r.excluded(stage.Agent, task.Exclude) {
+			if stage.Optional && r.overloaded(stage.Agent) {
+				r.logger.Debug("Skipping optional stage on overloaded agent",
+					zap.String("type", string(task.Type)),
+					zap.String("agent", stage.Agent),
+				)
+				continue
 			}
+			available = append(available, stage.Agent)
+			continue
+		}
+		if !stage.Optional {
+			return nil, fmt.Errorf("%w: %s for task type %s", ErrRequiredAgentUnavailable, stage.Agent, task.Type)
 		}
 	}
 
@@ -169,11 +564,79 @@ func (r *Router) Route(task *Task) ([]string, error) {
 	return available, nil
 }
 
+// Routable reports whether any of the named agents can take work, for
+// spotting queued tasks whose agents have all gone offline
+func (r *Router) Routable(agents []string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, name := range agents {
+		if r.available(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// overloaded reports whether an agent's last heartbeat put it at capacity
+// or past the configured queue depth. Callers hold r.mu.
+func (r *Router) overloaded(name string) bool {
+	agent, exists := r.agents[name]
+	if !exists {
+		return false
+	}
+	maxQueue := r.config.Agents.MaxQueueDepth
+	return (agent.Capacity > 0 && agent.InFlight >= agent.Capacity) ||
+		(maxQueue > 0 && agent.QueueDepth >= maxQueue)
+}
+
+// available reports whether an agent can take new work. Callers hold r.mu.
+func (r *Router) available(name string) bool {
+	agent, exists := r.agents[name]
+	return exists && (agent.Status == AgentStatusReady || agent.Status == AgentStatusDegraded)
+}
+
+// excluded reports whether an agent's current instance is one the task
+// must avoid. Callers hold r.mu.
+func (r *Router) excluded(name string, exclude []string) bool {
+	agent, exists := r.agents[name]
+	if !exists {
+		return false
+	}
+	for _, id := range exclude {
+		if agent.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Instance returns the ID of the instance currently serving an agent name
+func (r *Router) Instance(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if agent, exists := r.agents[name]; exists {
+		return agent.ID
+	}
+	return ""
+}
+
+// Reroute routes a retried task of the given type, or with the given
+// capabilities, around the excluded agent instances, for the scheduler's
+// retry path
+func (r *Router) Reroute(taskType string, caps map[string]float64, exclude []string) ([]string, error) {
+	return r.Route(&Task{Type: TaskType(taskType), Capabilities: caps, Exclude: exclude})
+}
+
 // RegisterAgent registers a new agent
 func (r *Router) RegisterAgent(info *AgentInfo) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if r.drained[info.Name] {
+		info.Status = AgentStatusDraining
+	}
 	r.agents[info.Name] = info
 	r.logger.Info("Agent registered",
 		zap.String("id", info.ID),
@@ -189,12 +652,54 @@ func (r *Router) UnregisterAgent(agentID string) {
 	for name, agent := range r.agents {
 		if agent.ID == agentID {
 			delete(r.agents, name)
+			delete(r.discovered, name)
 			r.logger.Info("Agent unregistered", zap.String("id", agentID))
 			return
 		}
 	}
 }
 
+// DrainAgent stops routing new work to an agent while keeping it tracked,
+// so tasks already dispatched to it can finish before a restart
+func (r *Router) DrainAgent(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	agent, exists := r.agents[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, name)
+	}
+
+	r.drained[name] = true
+	// An agent out of health is marked draining once it recovers
+	if agent.Status == AgentStatusReady || agent.Status == AgentStatusDegraded {
+		agent.Status = AgentStatusDraining
+	}
+	r.logger.Info("Agent draining", zap.String("name", name))
+	return nil
+}
+
+// UndrainAgent returns a drained agent to the routing pool. An agent
+// evicted while drained can be undrained before it is rediscovered.
+func (r *Router) UndrainAgent(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.drained[name] {
+		if _, exists := r.agents[name]; !exists {
+			return fmt.Errorf("%w: %s", ErrAgentNotFound, name)
+		}
+		return fmt.Errorf("%w: %s", ErrAgentNotDraining, name)
+	}
+
+	delete(r.drained, name)
+	if agent, exists := r.agents[name]; exists && agent.Status == AgentStatusDraining {
+		agent.Status = AgentStatusReady
+	}
+	r.logger.Info("Agent undrained", zap.String("name", name))
+	return nil
+}
+
 // GetAgents returns all registered agents
 func (r *Router) GetAgents() []*AgentInfo {
 	r.mu.RLock()
@@ -207,23 +712,112 @@ func (r *Router) GetAgents() []*AgentInfo {
 	return agents
 }
 
-// SubmitTask submits a task to the routing queue
+// Traced reports whether a task is traced: always when it asks to be,
+// otherwise when sampled
+func (r *Router) Traced(task *Task) bool {
+	return r.sampler.Sampled(task.ID, task.Trace)
+}
+
+// SubmitTask routes a task and fixes what it runs under. It is published
+// to its agents by Dispatch, once the scheduler starts it.
 func (r *Router) SubmitTask(task *Task) error {
+	if err := r.admit(); err != nil {
+		return err
+	}
+
 	// Determine routing
 	agents, err := r.Route(task)
 	if err != nil {
 		return err
 	}
+	task.Traced = r.Traced(task)
+
+	sandbox, err := r.Sandbox(task)
+	if err != nil {
+		return err
+	}
+	task.Sandbox = &sandbox
 
 	r.logger.Info("Task routed",
 		zap.String("id", task.ID),
+		zap.String("request_id", task.RequestID),
+		zap.String("name", r.DisplayName(task)),
 		zap.String("type", string(task.Type)),
 		zap.Strings("agents", agents),
+		zap.Bool("traced", task.Traced),
+		zap.String("network", sandbox.Network),
+		zap.String("filesystem", sandbox.Filesystem),
+	)
+	r.logger.Debug("Task payload",
+		zap.String("id", task.ID),
+		zap.Any("input", task.Input),
+		zap.Any("context", task.Context),
 	)
+	return nil
+}
 
-	// TODO: Publish to Redis stream for agent consumption
-	taskJSON, _ := json.Marshal(task)
-	_ = taskJSON // Would publish to Redis
+// Dispatch publishes a task the scheduler has started to the agents it
+// was given, with the input the scheduler added merged over its own
+func (r *Router) Dispatch(ctx context.Context, d scheduler.Dispatched) error {
+	r.mu.RLock()
+	publisher := r.publisher
+	r.mu.RUnlock()
+	if publisher == nil {
+		return nil
+	}
+
+	if len(d.Payload) == 0 {
+		return fmt.Errorf("task %s has no payload to dispatch", d.TaskID)
+	}
+	var task Task
+	if err := json.Unmarshal(d.Payload, &task); err != nil {
+		return fmt.Errorf("failed to decode task %s: %w", d.TaskID, err)
+	}
+	if len(d.Input) > 0 {
+		input := make(map[string]interface{}, len(task.Input)+len(d.Input))
+		maps.Copy(input, task.Input)
+		maps.Copy(input, d.Input)
+		task.Input = input
+	}
 
+	// Pin agents under a rollout to the stable or canary version
+	versions := make(map[string]string)
+	for _, name := range d.Agents {
+		if version := r.rollouts.pick(name); version != "" {
+			versions[name] = version
+		}
+	}
+	r.logger.Debug("Task dispatched",
+		zap.String("id", task.ID),
+		zap.Int("attempt", d.Attempt),
+		zap.Strings("agents", d.Agents),
+		zap.Any("versions", versions),
+	)
+
+	// Agents with payload fields withheld get a message of their own
+	for _, routed := range r.dispatches(&task, d.Agents, versions) {
+		routed.Attempt = d.Attempt
+		for _, name := range routed.Agents {
+			if instance := d.Instances[name]; instance != "" {
+				if routed.Instances == nil {
+					routed.Instances = make(map[string]string)
+				}
+				routed.Instances[name] = instance
+			}
+		}
+		payload, err := json.Marshal(routed)
+		if err != nil {
+			return fmt.Errorf("failed to encode task: %w", err)
+		}
+		err = publisher.Publish(ctx, "tasks", stream.Message{
+			Type:     "task",
+			Source:   "orchestrator",
+			Payload:  payload,
+			Priority: task.Priority,
+		})
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,268 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/clock"
+	"github.com/krigsexe/odin/orchestrator/internal/scheduler"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Orchestrator.MaxConcurrentTasks = 10
+	cfg.Orchestrator.TickInterval = 10
+	return cfg
+}
+
+// newTestRouter creates a router with the named agents registered and
+// ready, each as instance "<name>-1"
+func newTestRouter(t *testing.T, cfg *config.Config, agents ...string) *Router {
+	t.Helper()
+	r := New(cfg, zap.NewNop())
+	for _, name := range agents {
+		r.RegisterAgent(&AgentInfo{ID: name + "-1", Name: name, Status: AgentStatusReady})
+	}
+	return r
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDrainedAgentGetsNoNewRoutes(t *testing.T) {
+	r := newTestRouter(t, testConfig(), "retrieval", "review", "security")
+
+	if err := r.DrainAgent("retrieval"); err != nil {
+		t.Fatalf("DrainAgent: %v", err)
+	}
+	agents, err := r.Route(&Task{Type: TaskCodeReview})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if slices.Contains(agents, "retrieval") {
+		t.Errorf("draining agent routed: %v", agents)
+	}
+
+	// A required stage on a draining agent cannot be routed at all
+	if err := r.DrainAgent("security"); err != nil {
+		t.Fatalf("DrainAgent: %v", err)
+	}
+	if _, err := r.Route(&Task{Type: TaskCodeReview}); !errors.Is(err, ErrRequiredAgentUnavailable) {
+		t.Errorf("Route with required agent draining: got %v, want ErrRequiredAgentUnavailable", err)
+	}
+
+	if err := r.UndrainAgent("security"); err != nil {
+		t.Fatalf("UndrainAgent: %v", err)
+	}
+	if err := r.UndrainAgent("security"); !errors.Is(err, ErrAgentNotDraining) {
+		t.Errorf("second UndrainAgent: got %v, want ErrAgentNotDraining", err)
+	}
+	if _, err := r.Route(&Task{Type: TaskCodeReview}); err != nil {
+		t.Errorf("Route after undrain: %v", err)
+	}
+}
+
+func TestRequiredStageOfflineFailsRoute(t *testing.T) {
+	r := newTestRouter(t, testConfig(), "retrieval", "dev")
+	r.RegisterAgent(&AgentInfo{ID: "approbation-1", Name: "approbation", Status: AgentStatusOffline})
+
+	_, err := r.Route(&Task{Type: TaskCodeWrite})
+	if !errors.Is(err, ErrRequiredAgentUnavailable) {
+		t.Fatalf("Route with approbation offline: got %v, want ErrRequiredAgentUnavailable", err)
+	}
+
+	// Without the optional retrieval stage the route still goes ahead
+	r.RegisterAgent(&AgentInfo{ID: "approbation-1", Name: "approbation", Status: AgentStatusReady})
+	r.RegisterAgent(&AgentInfo{ID: "retrieval-1", Name: "retrieval", Status: AgentStatusOffline})
+	agents, err := r.Route(&Task{Type: TaskCodeWrite})
+	if err != nil {
+		t.Fatalf("Route with retrieval offline: %v", err)
+	}
+	if want := []string{"dev", "approbation"}; !slices.Equal(agents, want) {
+		t.Errorf("route = %v, want %v", agents, want)
+	}
+}
+
+func TestEditedRouteKeepsStageOptionality(t *testing.T) {
+	r := newTestRouter(t, testConfig(), "analysis", "review")
+	err := r.SetRoute(context.Background(), TaskAnalysis, []RouteStage{
+		{Agent: "analysis"},
+		{Agent: "review", Optional: true},
+	}, "test")
+	if err != nil {
+		t.Fatalf("SetRoute: %v", err)
+	}
+
+	r.RegisterAgent(&AgentInfo{ID: "review-1", Name: "review", Status: AgentStatusOffline})
+	agents, err := r.Route(&Task{Type: TaskAnalysis})
+	if err != nil || !slices.Equal(agents, []string{"analysis"}) {
+		t.Errorf("route = %v, %v; want [analysis] without the optional stage", agents, err)
+	}
+
+	r.RegisterAgent(&AgentInfo{ID: "analysis-1", Name: "analysis", Status: AgentStatusOffline})
+	if _, err := r.Route(&Task{Type: TaskAnalysis}); !errors.Is(err, ErrRequiredAgentUnavailable) {
+		t.Errorf("Route with the required stage offline: got %v, want ErrRequiredAgentUnavailable", err)
+	}
+}
+
+func TestDrainingAgentFinishesDispatchedTask(t *testing.T) {
+	cfg := testConfig()
+	r := newTestRouter(t, cfg, "explain")
+	s := scheduler.New(cfg, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Start(ctx)
+
+	task := &Task{ID: NewTaskID(), Type: TaskQuestion}
+	agents, err := r.Route(task)
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if err := s.Schedule(&scheduler.ScheduledTask{ID: task.ID, Type: string(task.Type), Agents: agents}); err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	waitFor(t, "dispatch", func() bool {
+		snap, _ := s.Task(task.ID)
+		return snap.State == scheduler.TaskRunning
+	})
+
+	if err := r.DrainAgent("explain"); err != nil {
+		t.Fatalf("DrainAgent: %v", err)
+	}
+	if _, err := r.Route(&Task{Type: TaskQuestion}); err == nil {
+		t.Error("new task routed to the draining agent")
+	}
+
+	err = s.ReportResult("explain", scheduler.Result{TaskID: task.ID, Status: scheduler.ResultCompleted, Attempt: 1})
+	if err != nil {
+		t.Fatalf("ReportResult from draining agent: %v", err)
+	}
+	if snap, _ := s.Task(task.ID); snap.State != scheduler.TaskCompleted {
+		t.Errorf("task state = %s, want completed", snap.State)
+	}
+}
+
+func TestMissedHeartbeatsDegradeThenTakeOffline(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Discovery.MissGrace = 3
+	r := newTestRouter(t, cfg)
+	c := clock.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r.SetClock(c)
+	d := &fakeDiscovery{agents: []string{"explain"}}
+	r.SetDiscovery(d)
+	ctx := context.Background()
+
+	d.beat("explain", c.Now())
+	r.refreshAgentList(ctx)
+	if got := agentStatus(r, "explain"); got != AgentStatusReady {
+		t.Fatalf("status = %s after a heartbeat, want ready", got)
+	}
+
+	// The backend keeps reporting the same, stale heartbeat
+	for miss := 1; miss <= 2; miss++ {
+		c.Advance(10 * time.Second)
+		r.refreshAgentList(ctx)
+		if got := agentStatus(r, "explain"); got != AgentStatusDegraded {
+			t.Fatalf("status = %s after %d misses, want degraded", got, miss)
+		}
+		if _, err := r.Route(&Task{Type: TaskQuestion}); err != nil {
+			t.Errorf("degraded agent taken out of rotation after %d misses: %v", miss, err)
+		}
+	}
+
+	c.Advance(10 * time.Second)
+	r.refreshAgentList(ctx)
+	if got := agentStatus(r, "explain"); got != AgentStatusOffline {
+		t.Fatalf("status = %s after 3 misses, want offline", got)
+	}
+	if _, err := r.Route(&Task{Type: TaskQuestion}); err == nil {
+		t.Error("offline agent still routed")
+	}
+
+	d.beat("explain", c.Now())
+	r.refreshAgentList(ctx)
+	if got := agentStatus(r, "explain"); got != AgentStatusReady {
+		t.Errorf("status = %s after a fresh heartbeat, want ready", got)
+	}
+}
+
+func TestMissGracePeriodHoldsOffline(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Discovery.MissGrace = 1
+	cfg.Agents.Discovery.MissGracePeriod = 60
+	r := newTestRouter(t, cfg)
+	c := clock.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r.SetClock(c)
+	d := &fakeDiscovery{agents: []string{"explain"}}
+	d.beat("explain", c.Now())
+	r.SetDiscovery(d)
+	r.refreshAgentList(context.Background())
+
+	c.Advance(30 * time.Second)
+	r.refreshAgentList(context.Background())
+	if got := agentStatus(r, "explain"); got != AgentStatusDegraded {
+		t.Errorf("status = %s within the grace period, want degraded", got)
+	}
+	c.Advance(30 * time.Second)
+	r.refreshAgentList(context.Background())
+	if got := agentStatus(r, "explain"); got != AgentStatusOffline {
+		t.Errorf("status = %s once the grace period passed, want offline", got)
+	}
+}
+
+// agentStatus returns the status of a known agent, "" for none
+func agentStatus(r *Router, name string) string {
+	for _, agent := range r.GetAgents() {
+		if agent.Name == name {
+			return agent.Status
+		}
+	}
+	return ""
+}
+
+func TestRerouteWaitsForAReplacementInstance(t *testing.T) {
+	r := newTestRouter(t, testConfig(), "retrieval", "dev", "approbation")
+
+	if _, err := r.Reroute(string(TaskCodeWrite), nil, []string{"dev-1"}); !errors.Is(err, ErrRequiredAgentUnavailable) {
+		t.Fatalf("Reroute with the only dev instance excluded: got %v, want ErrRequiredAgentUnavailable", err)
+	}
+
+	// The crashed instance is replaced by a fresh one
+	r.RegisterAgent(&AgentInfo{ID: "dev-2", Name: "dev", Status: AgentStatusReady})
+	agents, err := r.Reroute(string(TaskCodeWrite), nil, []string{"dev-1"})
+	if err != nil {
+		t.Fatalf("Reroute with a new instance: %v", err)
+	}
+	if !slices.Contains(agents, "dev") || r.Instance("dev") != "dev-2" {
+		t.Errorf("route = %v on %s, want dev on the new instance", agents, r.Instance("dev"))
+	}
+}
+
+func TestRoutingOnEmptyConfigDoesNotPanic(t *testing.T) {
+	r := newTestRouter(t, &config.Config{})
+
+	for _, taskType := range []TaskType{TaskCodeWrite, TaskQuestion, "translate"} {
+		if _, err := r.Route(&Task{Type: taskType}); err == nil && taskType != "translate" {
+			t.Errorf("%s routed with no agents", taskType)
+		}
+		_ = r.Explain(&Task{Type: taskType})
+	}
+	if err := r.SubmitTask(&Task{ID: "t-1", Type: TaskQuestion}); err == nil {
+		t.Error("task submitted with no agents")
+	}
+}
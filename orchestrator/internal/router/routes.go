@@ -0,0 +1,200 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Route table keys in Redis
+const (
+	routesKey      = "odin:routes"       // hash of edited routes by task type
+	routesAudit    = "odin:routes:audit" // list of route changes, newest first
+	routesAuditMax = 1000
+)
+
+// ErrInvalidRoute rejects a routing table edit
+var ErrInvalidRoute = errors.New("invalid route")
+
+// RouteChange is the audit record of one routing table edit
+type RouteChange struct {
+	TaskType TaskType     `json:"task_type"`
+	Before   []RouteStage `json:"before,omitempty"`
+	After    []RouteStage `json:"after"`
+	Source   string       `json:"source,omitempty"` // who or what made the edit
+	Time     time.Time    `json:"time"`
+}
+
+// RouteStore persists routing table edits, with an audit trail, so they
+// survive a restart
+type RouteStore interface {
+	// Save records a task type's new route and the change that made it
+	Save(ctx context.Context, change RouteChange) error
+
+	// Load returns every saved route
+	Load(ctx context.Context) (map[TaskType][]RouteStage, error)
+}
+
+// RedisRouteStore keeps edited routes in Redis, shared by every instance
+type RedisRouteStore struct {
+	client *redis.Client
+}
+
+// NewRedisRouteStore creates a Redis-backed route store
+func NewRedisRouteStore(client *redis.Client) *RedisRouteStore {
+	return &RedisRouteStore{client: client}
+}
+
+// Save writes the route and appends the change to a capped audit list in
+// one transaction
+func (s *RedisRouteStore) Save(ctx context.Context, change RouteChange) error {
+	route, err := json.Marshal(change.After)
+	if err != nil {
+		return err
+	}
+	entry, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, routesKey, string(change.TaskType), route)
+		pipe.LPush(ctx, routesAudit, entry)
+		pipe.LTrim(ctx, routesAudit, 0, routesAuditMax-1)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save route for %s: %w", change.TaskType, err)
+	}
+	return nil
+}
+
+// Load reads every saved route
+func (s *RedisRouteStore) Load(ctx context.Context) (map[TaskType][]RouteStage, error) {
+	raw, err := s.client.HGetAll(ctx, routesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load routes: %w", err)
+	}
+
+	routes := make(map[TaskType][]RouteStage, len(raw))
+	for taskType, data := range raw {
+		var stages []RouteStage
+		if err := json.Unmarshal([]byte(data), &stages); err != nil {
+			return nil, fmt.Errorf("saved route for %s: %w", taskType, err)
+		}
+		routes[TaskType(taskType)] = stages
+	}
+	return routes, nil
+}
+
+// SetRouteStore makes routing table edits persistent
+func (r *Router) SetRouteStore(store RouteStore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routeStore = store
+}
+
+// LoadRoutes applies the routes saved by earlier edits over the defaults.
+// Saved agents are not checked against discovery, which may not have run
+// yet.
+func (r *Router) LoadRoutes(ctx context.Context) error {
+	r.mu.RLock()
+	store := r.routeStore
+	r.mu.RUnlock()
+
+	if store == nil {
+		return nil
+	}
+	saved, err := store.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for taskType, stages := range saved {
+		if !taskType.Valid() || len(stages) == 0 {
+			r.logger.Warn("Ignoring saved route", zap.String("type", string(taskType)))
+			continue
+		}
+		r.routes[taskType] = stages
+	}
+	return nil
+}
+
+// Routes returns a copy of the routing table
+func (r *Router) Routes() map[TaskType][]RouteStage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := make(map[TaskType][]RouteStage, len(r.routes))
+	for taskType, stages := range r.routes {
+		routes[taskType] = slices.Clone(stages)
+	}
+	return routes
+}
+
+// SetRoute replaces a task type's route. Every stage must name a known
+// agent, one that is registered or configured, and appear only once. With
+// a route store the edit is saved and audited first, so a change that
+// cannot be persisted is not applied.
+func (r *Router) SetRoute(ctx context.Context, taskType TaskType, stages []RouteStage, source string) error {
+	r.mu.RLock()
+	err := r.validateRoute(taskType, stages)
+	before := slices.Clone(r.routes[taskType])
+	store := r.routeStore
+	now := r.clock.Now()
+	r.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	stages = slices.Clone(stages)
+	if store != nil {
+		change := RouteChange{TaskType: taskType, Before: before, After: stages, Source: source, Time: now}
+		if err := store.Save(ctx, change); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.routes[taskType] = stages
+	r.mu.Unlock()
+
+	r.logger.Info("Route updated",
+		zap.String("type", string(taskType)),
+		zap.Any("stages", stages),
+		zap.String("source", source),
+	)
+	return nil
+}
+
+// validateRoute checks a route edit. Callers hold r.mu.
+func (r *Router) validateRoute(taskType TaskType, stages []RouteStage) error {
+	if !taskType.Valid() {
+		return fmt.Errorf("%w: unknown task type %s", ErrInvalidRoute, taskType)
+	}
+	if len(stages) == 0 {
+		return fmt.Errorf("%w: no stages", ErrInvalidRoute)
+	}
+
+	seen := make(map[string]bool, len(stages))
+	for _, stage := range stages {
+		if seen[stage.Agent] {
+			return fmt.Errorf("%w: agent %s listed twice", ErrInvalidRoute, stage.Agent)
+		}
+		seen[stage.Agent] = true
+		if _, registered := r.agents[stage.Agent]; !registered && !slices.Contains(r.config.Agents.Enabled, stage.Agent) {
+			return fmt.Errorf("%w: unknown agent %q", ErrInvalidRoute, stage.Agent)
+		}
+	}
+	return nil
+}
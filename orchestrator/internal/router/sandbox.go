@@ -0,0 +1,128 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidSandbox rejects an unknown sandbox setting, or a task asking
+// for more than its type's policy allows
+var ErrInvalidSandbox = errors.New("invalid sandbox policy")
+
+// Network access levels, least permissive first
+const (
+	NetworkNone       = "none"
+	NetworkRestricted = "restricted" // only AllowHosts
+	NetworkFull       = "full"
+)
+
+// Filesystem scopes, least permissive first
+const (
+	FilesystemNone      = "none"
+	FilesystemReadOnly  = "read_only"
+	FilesystemWorkspace = "workspace" // read-write inside the task workspace
+	FilesystemFull      = "full"
+)
+
+var (
+	networkLevels    = []string{NetworkNone, NetworkRestricted, NetworkFull}
+	filesystemLevels = []string{FilesystemNone, FilesystemReadOnly, FilesystemWorkspace, FilesystemFull}
+)
+
+// SandboxPolicy is what a task may touch while it runs. It travels with
+// the dispatched task; enforcing it is up to the agent.
+type SandboxPolicy struct {
+	Network    string   `json:"network" yaml:"network"`
+	Filesystem string   `json:"filesystem" yaml:"filesystem"`
+	AllowHosts []string `json:"allow_hosts,omitempty" yaml:"allow_hosts"`
+}
+
+// Validate checks the policy uses known levels
+func (p SandboxPolicy) Validate() error {
+	if !slices.Contains(networkLevels, p.Network) {
+		return fmt.Errorf("%w: unknown network access %q", ErrInvalidSandbox, p.Network)
+	}
+	if !slices.Contains(filesystemLevels, p.Filesystem) {
+		return fmt.Errorf("%w: unknown filesystem scope %q", ErrInvalidSandbox, p.Filesystem)
+	}
+	if len(p.AllowHosts) > 0 && p.Network != NetworkRestricted {
+		return fmt.Errorf("%w: allow_hosts needs restricted network access", ErrInvalidSandbox)
+	}
+	return nil
+}
+
+// within reports whether the policy grants nothing beyond limit
+func (p SandboxPolicy) within(limit SandboxPolicy) bool {
+	if slices.Index(networkLevels, p.Network) > slices.Index(networkLevels, limit.Network) ||
+		slices.Index(filesystemLevels, p.Filesystem) > slices.Index(filesystemLevels, limit.Filesystem) {
+		return false
+	}
+	if p.Network == NetworkRestricted && limit.Network == NetworkRestricted {
+		for _, host := range p.AllowHosts {
+			if !slices.Contains(limit.AllowHosts, host) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// defaultSandbox applies to task types without a built-in or configured
+// policy
+var defaultSandbox = SandboxPolicy{Network: NetworkNone, Filesystem: FilesystemReadOnly}
+
+// builtinSandboxes are the policies task types get unless configured
+// otherwise. Nothing gets the network by default; only types that change
+// code may write, and only to their workspace.
+var builtinSandboxes = map[TaskType]SandboxPolicy{
+	TaskCodeWrite:  {Network: NetworkNone, Filesystem: FilesystemWorkspace},
+	TaskCodeModify: {Network: NetworkNone, Filesystem: FilesystemWorkspace},
+	TaskCodeDebug:  {Network: NetworkNone, Filesystem: FilesystemWorkspace},
+	TaskTest:       {Network: NetworkNone, Filesystem: FilesystemWorkspace},
+	TaskCodeReview: {Network: NetworkNone, Filesystem: FilesystemReadOnly},
+	TaskAnalysis:   {Network: NetworkNone, Filesystem: FilesystemReadOnly},
+	TaskQuestion:   {Network: NetworkNone, Filesystem: FilesystemNone},
+}
+
+// newSandboxes overlays configured policies on the built-in ones,
+// skipping any that don't validate
+func newSandboxes(cfg map[string]config.SandboxConfig, logger *zap.Logger) map[TaskType]SandboxPolicy {
+	policies := make(map[TaskType]SandboxPolicy, len(builtinSandboxes)+len(cfg))
+	for taskType, policy := range builtinSandboxes {
+		policies[taskType] = policy
+	}
+	for taskType, sc := range cfg {
+		policy := SandboxPolicy{Network: sc.Network, Filesystem: sc.Filesystem, AllowHosts: sc.AllowHosts}
+		if err := policy.Validate(); err != nil {
+			logger.Warn("Ignoring sandbox policy", zap.String("type", taskType), zap.Error(err))
+			continue
+		}
+		policies[TaskType(taskType)] = policy
+	}
+	return policies
+}
+
+// Sandbox resolves the policy a task runs under: the one it carries, as
+// long as that grants no more than its type's policy, or else the type's
+func (r *Router) Sandbox(task *Task) (SandboxPolicy, error) {
+	limit, ok := r.sandboxes[task.Type]
+	if !ok {
+		limit = defaultSandbox
+	}
+	if task.Sandbox == nil {
+		return limit, nil
+	}
+
+	if err := task.Sandbox.Validate(); err != nil {
+		return SandboxPolicy{}, err
+	}
+	if !task.Sandbox.within(limit) {
+		return SandboxPolicy{}, fmt.Errorf("%w: task type %s allows at most network %s, filesystem %s",
+			ErrInvalidSandbox, task.Type, limit.Network, limit.Filesystem)
+	}
+	return *task.Sandbox, nil
+}
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// dispatchedSandbox submits a task and returns the sandbox policy its
+// agents would receive in the dispatched message
+func dispatchedSandbox(t *testing.T, r *Router, task *Task) *SandboxPolicy {
+	t.Helper()
+	if err := r.SubmitTask(task); err != nil {
+		t.Fatalf("SubmitTask: %v", err)
+	}
+	agents, err := r.Route(task)
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+
+	routed := r.dispatches(task, agents, nil)
+	payload, err := json.Marshal(routed[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg struct {
+		Task struct {
+			Sandbox *SandboxPolicy `json:"sandbox"`
+		} `json:"task"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg.Task.Sandbox
+}
+
+func TestSandboxPolicyTravelsWithDispatchedTask(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Sandbox = map[string]config.SandboxConfig{
+		"analysis": {Network: NetworkRestricted, Filesystem: FilesystemReadOnly, AllowHosts: []string{"docs.internal"}},
+	}
+	r := newTestRouter(t, cfg, "retrieval", "dev", "approbation", "explain", "analysis")
+
+	tests := []struct {
+		taskType TaskType
+		want     SandboxPolicy
+	}{
+		{TaskCodeWrite, SandboxPolicy{Network: NetworkNone, Filesystem: FilesystemWorkspace}},
+		{TaskQuestion, SandboxPolicy{Network: NetworkNone, Filesystem: FilesystemNone}},
+		{TaskAnalysis, SandboxPolicy{Network: NetworkRestricted, Filesystem: FilesystemReadOnly, AllowHosts: []string{"docs.internal"}}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.taskType), func(t *testing.T) {
+			got := dispatchedSandbox(t, r, &Task{ID: NewTaskID(), Type: tt.taskType})
+			if got == nil {
+				t.Fatal("dispatched message carries no sandbox policy")
+			}
+			if got.Network != tt.want.Network || got.Filesystem != tt.want.Filesystem || !slices.Equal(got.AllowHosts, tt.want.AllowHosts) {
+				t.Errorf("sandbox = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskMayOnlyNarrowItsSandbox(t *testing.T) {
+	r := newTestRouter(t, testConfig(), "retrieval", "dev", "approbation")
+
+	narrow := &SandboxPolicy{Network: NetworkNone, Filesystem: FilesystemReadOnly}
+	got := dispatchedSandbox(t, r, &Task{ID: "t-1", Type: TaskCodeWrite, Sandbox: narrow})
+	if got == nil || got.Filesystem != FilesystemReadOnly {
+		t.Errorf("sandbox = %+v, want the task's narrower policy", got)
+	}
+
+	wide := &SandboxPolicy{Network: NetworkFull, Filesystem: FilesystemWorkspace}
+	if err := r.SubmitTask(&Task{ID: "t-2", Type: TaskCodeWrite, Sandbox: wide}); !errors.Is(err, ErrInvalidSandbox) {
+		t.Errorf("task asking for the network = %v, want ErrInvalidSandbox", err)
+	}
+}
+
+func TestSandboxPolicyValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy SandboxPolicy
+		valid  bool
+	}{
+		{"locked down", SandboxPolicy{Network: NetworkNone, Filesystem: FilesystemNone}, true},
+		{"restricted hosts", SandboxPolicy{Network: NetworkRestricted, Filesystem: FilesystemWorkspace, AllowHosts: []string{"pypi.org"}}, true},
+		{"unknown network", SandboxPolicy{Network: "some", Filesystem: FilesystemNone}, false},
+		{"unknown filesystem", SandboxPolicy{Network: NetworkNone, Filesystem: "home"}, false},
+		{"hosts without restricted network", SandboxPolicy{Network: NetworkFull, Filesystem: FilesystemNone, AllowHosts: []string{"pypi.org"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidSandbox) {
+				t.Errorf("Validate = %v, want ErrInvalidSandbox", err)
+			}
+		})
+	}
+}
+
+func TestInvalidConfiguredSandboxKeepsBuiltin(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Sandbox = map[string]config.SandboxConfig{"code_write": {Network: "wide-open", Filesystem: FilesystemFull}}
+	r := newTestRouter(t, cfg)
+
+	got, err := r.Sandbox(&Task{Type: TaskCodeWrite})
+	if err != nil {
+		t.Fatalf("Sandbox: %v", err)
+	}
+	if got.Network != NetworkNone || got.Filesystem != FilesystemWorkspace {
+		t.Errorf("sandbox = %+v, want the built-in policy", got)
+	}
+}
@@ -0,0 +1,156 @@
+package router
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// versionOps are the constraint operators, longest first so ">=" is not
+// read as ">"
+var versionOps = []string{">=", "<=", "==", "!=", ">", "<", "=", "~", "^"}
+
+// versionConstraint is one comparison a capability version must pass
+type versionConstraint struct {
+	op      string
+	version []int
+}
+
+// requirement is a desired capability: a tag, optionally with version
+// constraints that must all hold
+type requirement struct {
+	tag         string
+	constraints []versionConstraint
+}
+
+// parseRequirement reads a desired capability such as "python",
+// "python>=3.12" or "python>=3.11,<3.13". The operators are =, ==, !=,
+// <, <=, >, >=, ~ (same minor, at least) and ^ (same major, at least).
+func parseRequirement(key string) (requirement, error) {
+	end := strings.IndexAny(key, "<>=!~^")
+	if end < 0 {
+		return requirement{tag: strings.TrimSpace(key)}, nil
+	}
+
+	req := requirement{tag: strings.TrimSpace(key[:end])}
+	if req.tag == "" {
+		return req, fmt.Errorf("%w: %q has no capability name", ErrInvalidCapabilities, key)
+	}
+	for _, part := range strings.Split(key[end:], ",") {
+		part = strings.TrimSpace(part)
+		op := ""
+		for _, candidate := range versionOps {
+			if strings.HasPrefix(part, candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return req, fmt.Errorf("%w: %q has no operator in %q", ErrInvalidCapabilities, key, part)
+		}
+		version, err := parseVersion(part[len(op):])
+		if err != nil {
+			return req, fmt.Errorf("%w: %q: %v", ErrInvalidCapabilities, key, err)
+		}
+		req.constraints = append(req.constraints, versionConstraint{op: op, version: version})
+	}
+	return req, nil
+}
+
+// allows reports whether a version passes every constraint
+func (r requirement) allows(version []int) bool {
+	for _, c := range r.constraints {
+		if !c.allows(version) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c versionConstraint) allows(version []int) bool {
+	cmp := compareVersions(version, c.version)
+	switch c.op {
+	case "=", "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "~":
+		return cmp >= 0 && samePrefix(version, c.version, 2)
+	case "^":
+		return cmp >= 0 && samePrefix(version, c.version, 1)
+	}
+	return false
+}
+
+// parseCapability splits an advertised capability into its tag and
+// version, written "python 3.11" or "python@3.11"; a plain tag has none
+func parseCapability(entry string) (tag, version string) {
+	if i := strings.IndexAny(entry, " @"); i >= 0 {
+		return entry[:i], strings.TrimSpace(entry[i+1:])
+	}
+	return entry, ""
+}
+
+// parseVersion reads a dotted numeric version such as 3.12 or v1.4.2
+func parseVersion(v string) ([]int, error) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if v == "" {
+		return nil, fmt.Errorf("missing version")
+	}
+	parts := strings.Split(v, ".")
+	version := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", v)
+		}
+		version[i] = n
+	}
+	return version, nil
+}
+
+// compareVersions orders versions component by component, reading
+// missing trailing components as 0
+func compareVersions(a, b []int) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// samePrefix reports whether the first n components of two versions match
+func samePrefix(a, b []int, n int) bool {
+	for i := 0; i < n; i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package router
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// registerVersioned registers a ready agent advertising capabilities
+func registerVersioned(r *Router, name string, capabilities ...string) {
+	r.RegisterAgent(&AgentInfo{ID: name + "-1", Name: name, Status: AgentStatusReady, Capabilities: capabilities})
+}
+
+func TestVersionConstraintPicksSatisfyingAgent(t *testing.T) {
+	r := newTestRouter(t, testConfig())
+	registerVersioned(r, "py311", "python 3.11")
+	registerVersioned(r, "py312", "python@3.12")
+
+	agents, err := r.Route(&Task{Type: TaskCodeWrite, Capabilities: map[string]float64{"python>=3.12": 1}})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if !slices.Equal(agents, []string{"py312"}) {
+		t.Errorf("routed to %v, want py312", agents)
+	}
+
+	exp := r.Explain(&Task{Type: TaskCodeWrite, Capabilities: map[string]float64{"python>=3.12": 1}})
+	if got := reasons(exp); got["py311"] != FilterNoMatch {
+		t.Errorf("py311 reason = %q, want %q", got["py311"], FilterNoMatch)
+	}
+}
+
+func TestNoAgentSatisfiesConstraint(t *testing.T) {
+	r := newTestRouter(t, testConfig())
+	registerVersioned(r, "py311", "python 3.11")
+	registerVersioned(r, "plain", "python")
+
+	_, err := r.Route(&Task{Type: TaskCodeWrite, Capabilities: map[string]float64{"python>=3.12": 1}})
+	if !errors.Is(err, ErrNoCapableAgent) {
+		t.Errorf("err = %v, want ErrNoCapableAgent", err)
+	}
+}
+
+func TestRerouteKeepsConstraint(t *testing.T) {
+	r := newTestRouter(t, testConfig(), "retrieval", "dev", "approbation")
+	registerVersioned(r, "py311", "python 3.11")
+	registerVersioned(r, "py312", "python@3.12")
+	caps := map[string]float64{"python>=3.12": 1}
+
+	// With the only satisfying instance excluded the retry waits, rather
+	// than falling back to the type's route
+	_, err := r.Reroute(string(TaskCodeWrite), caps, []string{"py312-1"})
+	if !errors.Is(err, ErrNoCapableAgent) {
+		t.Fatalf("err = %v, want ErrNoCapableAgent", err)
+	}
+
+	registerVersioned(r, "py313", "python 3.13")
+	agents, err := r.Reroute(string(TaskCodeWrite), caps, []string{"py312-1"})
+	if err != nil || !slices.Equal(agents, []string{"py313"}) {
+		t.Errorf("Reroute = %v, %v; want [py313]", agents, err)
+	}
+}
+
+func TestVersionConstraints(t *testing.T) {
+	tests := []struct {
+		req     string
+		version string
+		want    bool
+	}{
+		{"python>=3.12", "3.12", true},
+		{"python>=3.12", "3.11.9", false},
+		{"python>=3.11,<3.13", "3.12.1", true},
+		{"python>=3.11,<3.13", "3.13", false},
+		{"python=3.12", "3.12.0", true},
+		{"python!=3.12", "3.12", false},
+		{"python>3.12", "3.12", false},
+		{"python<=3.12", "3.12", true},
+		{"go~1.22", "1.22.5", true},
+		{"go~1.22", "1.23", false},
+		{"go^1.21", "1.23", true},
+		{"go^1.21", "2.0", false},
+		{"go>=v1.21", "v1.21.0", true},
+	}
+	for _, tt := range tests {
+		req, err := parseRequirement(tt.req)
+		if err != nil {
+			t.Fatalf("parseRequirement(%q): %v", tt.req, err)
+		}
+		version, err := parseVersion(tt.version)
+		if err != nil {
+			t.Fatalf("parseVersion(%q): %v", tt.version, err)
+		}
+		if got := req.allows(version); got != tt.want {
+			t.Errorf("%s allows %s = %v, want %v", tt.req, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidRequirementsRejected(t *testing.T) {
+	for _, key := range []string{">=3.12", "python>=", "python>=3.x", "python>=3.12,3.13"} {
+		if err := ValidateCapabilities(map[string]float64{key: 1}); !errors.Is(err, ErrInvalidCapabilities) {
+			t.Errorf("ValidateCapabilities(%q) = %v, want ErrInvalidCapabilities", key, err)
+		}
+	}
+}
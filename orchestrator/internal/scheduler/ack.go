@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// InstanceOf returns the discovery ID of an agent's current instance, or
+// "" if it has none
+type InstanceOf func(agent string) string
+
+// SetInstanceOf installs the lookup of each agent's current instance. A
+// dispatch records it, so only that instance's result is accepted and an
+// instance that never acknowledged the dispatch is kept off the task's
+// retry. Without one, results are not checked by instance and the retry
+// may be routed to the same instance.
+func (s *Scheduler) SetInstanceOf(fn InstanceOf) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.instanceOf = fn
+}
+
+// instancesOf records the instance each agent is running as, for the
+// results of a dispatch to be checked against. Callers hold s.mu.
+func (s *Scheduler) instancesOf(agents []string) map[string]string {
+	if s.instanceOf == nil || len(agents) == 0 {
+		return nil
+	}
+	instances := make(map[string]string, len(agents))
+	for _, agent := range agents {
+		instances[agent] = s.instanceOf(agent)
+	}
+	return instances
+}
+
+// ackTimeout resolves how long the given agents have to acknowledge a
+// dispatch. The most lenient agent policy wins; an agent without one
+// gets orchestrator.ack_timeout. Zero means no acknowledgement is
+// expected.
+func (s *Scheduler) ackTimeout(agents []string) time.Duration {
+	fallback := time.Duration(s.config.Orchestrator.AckTimeout) * time.Second
+	if len(agents) == 0 {
+		return fallback
+	}
+
+	var timeout time.Duration
+	for i, name := range agents {
+		agentTimeout := time.Duration(s.config.Agents.Policies[name].AckTimeout) * time.Second
+		if agentTimeout <= 0 {
+			agentTimeout = fallback
+		}
+		// An agent not expected to acknowledge leaves the dispatch unbounded
+		if agentTimeout <= 0 {
+			return 0
+		}
+		if i == 0 || agentTimeout > timeout {
+			timeout = agentTimeout
+		}
+	}
+	return timeout
+}
+
+// Acknowledge records that an agent has accepted a dispatched task, which
+// stops the ack timeout; the run's own timeout still applies. Only an
+// agent the task was routed to may acknowledge it. Acknowledging twice is
+// harmless.
+func (s *Scheduler) Acknowledge(agent, taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.running[taskID]
+	if !exists || task.State != TaskRunning {
+		return fmt.Errorf("%w: %s", ErrUnknownTask, taskID)
+	}
+	if !task.ownedBy(agent) {
+		return fmt.Errorf("%w: %s does not own %s", ErrNotOwner, agent, taskID)
+	}
+	if task.acked {
+		return nil
+	}
+	task.acked = true
+
+	now := s.clock.Now()
+	s.span(task, "ack", task.started, now, map[string]string{"agent": agent})
+	s.logger.Debug("Dispatch acknowledged",
+		zap.String("id", taskID),
+		zap.String("agent", agent),
+		zap.Duration("after", now.Sub(task.started)),
+	)
+	return nil
+}
+
+// timeoutAcks fails dispatches no agent acknowledged within the ack
+// timeout. The agents' instances are excluded from the task, so a retry
+// is routed elsewhere when it can be, and the failure is a
+// CategoryUnavailable error left to the task's retry policy.
+func (s *Scheduler) timeoutAcks() {
+	s.mu.Lock()
+	now := s.clock.Now()
+	overdue := make(map[string]time.Duration)
+	attempts := make(map[string]int)
+	for id, task := range s.running {
+		if task.acked || task.ackTimeout <= 0 || now.Sub(task.started) < task.ackTimeout {
+			continue
+		}
+		overdue[id] = task.ackTimeout
+		attempts[id] = task.attempt
+		for _, instance := range task.instances {
+			if instance != "" {
+				s.exclude(task, instance)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	// In ID order, so decision logs replay deterministically
+	ids := make([]string, 0, len(overdue))
+	for id := range overdue {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		s.logger.Warn("Dispatch not acknowledged", zap.String("id", id), zap.Duration("ack_timeout", overdue[id]))
+		s.recordTransition(context.Background(), s.completeAttempt(id, attempts[id], nil, &TaskError{
+			Category: CategoryUnavailable,
+			Message:  fmt.Sprintf("agent did not acknowledge the dispatch within %s", overdue[id]),
+		}))
+	}
+}
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// newAckScheduler expects an acknowledgement within 5s of a run allowed
+// 10 minutes, rerouting away from instances that stay silent
+func newAckScheduler(t *testing.T) (*Scheduler, *ManualClock, *instancePool) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Orchestrator.AckTimeout = 5
+	cfg.Orchestrator.RetryExclusion = 60
+	s, c := newTestScheduler(t, cfg)
+	pool := &instancePool{instances: map[string]string{"dev": "dev-1", "dev-canary": "dev-canary-1"}}
+	s.SetReroute(pool.reroute)
+	s.SetInstanceOf(func(agent string) string { return pool.instances[agent] })
+
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "code_write", Agents: []string{"dev"}, Timeout: 10 * time.Minute})
+	s.processQueue()
+	return s, c, pool
+}
+
+func TestUnacknowledgedDispatchReroutedAtAckTimeout(t *testing.T) {
+	s, c, pool := newAckScheduler(t)
+
+	c.Advance(4 * time.Second)
+	s.timeoutAcks()
+	s.timeoutRunning()
+	if got := stateOf(t, s, "t1"); got != TaskRunning {
+		t.Fatalf("task is %s within the ack timeout, want running", got)
+	}
+
+	c.Advance(2 * time.Second)
+	s.timeoutAcks()
+	task, ok := queuedTask(s, "t1")
+	if !ok {
+		t.Fatalf("task is %s after the ack timeout, want queued for a retry", stateOf(t, s, "t1"))
+	}
+	if !slices.Equal(task.Agents, []string{"dev-canary"}) {
+		t.Errorf("retry routed to %v, want away from the silent instance", task.Agents)
+	}
+	if len(pool.asked) != 1 || !slices.Equal(pool.asked[0], []string{"dev-1"}) {
+		t.Errorf("reroute asked to exclude %v, want [dev-1]", pool.asked)
+	}
+}
+
+func TestAcknowledgedDispatchKeepsRunning(t *testing.T) {
+	s, c, _ := newAckScheduler(t)
+
+	if err := s.Acknowledge("review", "t1"); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("ack by another agent: err = %v, want ErrNotOwner", err)
+	}
+	if err := s.Acknowledge("dev", "nope"); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("ack of an unknown task: err = %v, want ErrUnknownTask", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Acknowledge("dev", "t1"); err != nil {
+			t.Fatalf("ack %d: %v", i+1, err)
+		}
+	}
+
+	c.Advance(time.Minute)
+	s.timeoutAcks()
+	s.timeoutRunning()
+	if got := stateOf(t, s, "t1"); got != TaskRunning {
+		t.Errorf("acknowledged task is %s, want running until its own timeout", got)
+	}
+
+	c.Advance(10 * time.Minute)
+	s.timeoutRunning()
+	if got := stateOf(t, s, "t1"); got == TaskRunning {
+		t.Error("acknowledged task outlived its run timeout")
+	}
+}
+
+func TestAckTimeoutResolution(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.AckTimeout = 5
+	cfg.Agents.Policies = map[string]config.AgentPolicy{
+		"dev":      {AckTimeout: 20},
+		"security": {AckTimeout: 2},
+	}
+	s, _ := newTestScheduler(t, cfg)
+
+	tests := []struct {
+		agents []string
+		want   time.Duration
+	}{
+		{nil, 5 * time.Second},
+		{[]string{"review"}, 5 * time.Second},
+		{[]string{"security"}, 2 * time.Second},
+		{[]string{"security", "dev"}, 20 * time.Second},
+		{[]string{"security", "review"}, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := s.ackTimeout(tt.agents); got != tt.want {
+			t.Errorf("ackTimeout(%v) = %v, want %v", tt.agents, got, tt.want)
+		}
+	}
+
+	cfg.Orchestrator.AckTimeout = 0
+	if got := s.ackTimeout([]string{"dev", "review"}); got != 0 {
+		t.Errorf("with review expecting no ack: %v, want unbounded", got)
+	}
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// baselineDrift is how far the latency baseline moves toward each window's
+// mean when it is not a new low, so a lasting change in downstream speed
+// is eventually accepted as normal
+const baselineDrift = 0.05
+
+// adaptiveLimit is an AIMD concurrency limit driven by run latency. The
+// baseline is the lowest window mean seen, drifting slowly upward.
+type adaptiveLimit struct {
+	min, max  int
+	limit     int
+	window    int
+	tolerance float64
+	backoff   float64
+	baseline  time.Duration
+	sum       time.Duration // of the current window's runs
+	count     int
+}
+
+// newAdaptiveLimit returns nil when adaptive concurrency is off. It starts
+// at the static limit, within its bounds.
+func newAdaptiveLimit(cfg config.AdaptiveConcurrencyConfig, maxConcurrent int) *adaptiveLimit {
+	if !cfg.Enabled {
+		return nil
+	}
+	a := &adaptiveLimit{
+		min:       max(cfg.Min, 1),
+		max:       cfg.Max,
+		window:    max(cfg.Window, 1),
+		tolerance: cfg.Tolerance,
+		backoff:   cfg.Backoff,
+	}
+	if a.max <= 0 {
+		a.max = maxConcurrent
+	}
+	a.max = max(a.max, a.min)
+	if a.tolerance <= 1 {
+		a.tolerance = 1.5
+	}
+	if a.backoff <= 0 || a.backoff >= 1 {
+		a.backoff = 0.9
+	}
+	a.limit = min(max(maxConcurrent, a.min), a.max)
+	return a
+}
+
+// observe records one run's latency, returning whether the limit changed
+func (a *adaptiveLimit) observe(latency time.Duration) bool {
+	a.sum += latency
+	a.count++
+	if a.count < a.window {
+		return false
+	}
+	mean := a.sum / time.Duration(a.count)
+	a.sum, a.count = 0, 0
+
+	previous := a.limit
+	switch {
+	case a.baseline == 0 || mean < a.baseline:
+		a.baseline = mean
+		a.limit = min(a.limit+1, a.max)
+	case float64(mean) > float64(a.baseline)*a.tolerance:
+		// Always shrink by at least one, however small the limit
+		a.limit = max(min(int(float64(a.limit)*a.backoff), a.limit-1), a.min)
+		a.baseline += time.Duration(float64(mean-a.baseline) * baselineDrift)
+	default:
+		a.limit = min(a.limit+1, a.max)
+		a.baseline += time.Duration(float64(mean-a.baseline) * baselineDrift)
+	}
+	return a.limit != previous
+}
+
+// limit is how many tasks may run at once. Callers hold s.mu.
+func (s *Scheduler) limit() int {
+	if s.adaptive == nil {
+		return s.maxConcurrent
+	}
+	return s.adaptive.limit
+}
+
+// observeLatency feeds a finished run to the adaptive limit. Callers hold
+// s.mu.
+func (s *Scheduler) observeLatency(latency time.Duration) {
+	if s.adaptive == nil || !s.adaptive.observe(latency) {
+		return
+	}
+	s.logger.Info("Concurrency limit adjusted",
+		zap.Int("limit", s.adaptive.limit),
+		zap.Duration("baseline", s.adaptive.baseline),
+	)
+	s.notify()
+}
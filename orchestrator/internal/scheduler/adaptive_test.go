@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// adaptiveRuns runs windows of tasks that each take latency, reporting the
+// limit after every window
+type adaptiveRuns struct {
+	t    *testing.T
+	s    *Scheduler
+	c    *ManualClock
+	runs int
+}
+
+func newAdaptiveRuns(t *testing.T) *adaptiveRuns {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 8
+	cfg.Orchestrator.AdaptiveConcurrency = config.AdaptiveConcurrencyConfig{Enabled: true, Min: 2, Window: 2}
+	s, c := newTestScheduler(t, cfg)
+	return &adaptiveRuns{t: t, s: s, c: c}
+}
+
+// limit is the concurrency limit the scheduler reports
+func (a *adaptiveRuns) limit() int {
+	return a.s.GetStatus()["limit"].(int)
+}
+
+// window runs two tasks side by side, both finishing after latency
+func (a *adaptiveRuns) window(latency time.Duration) int {
+	a.t.Helper()
+	ids := []string{fmt.Sprintf("run-%d", a.runs), fmt.Sprintf("run-%d", a.runs+1)}
+	a.runs += 2
+	for _, id := range ids {
+		mustSchedule(a.t, a.s, &ScheduledTask{ID: id})
+	}
+	a.s.processQueue()
+	a.c.Advance(latency)
+	for _, id := range ids {
+		a.s.completeTask(id, nil, nil)
+	}
+	return a.limit()
+}
+
+func TestRisingLatencyLowersLimitAndRecoveryRaisesIt(t *testing.T) {
+	a := newAdaptiveRuns(t)
+	if got := a.limit(); got != 8 {
+		t.Fatalf("starting limit = %d, want max_concurrent_tasks", got)
+	}
+	if got := a.window(100 * time.Millisecond); got != 8 {
+		t.Fatalf("limit after the baseline window = %d, want it held at the max", got)
+	}
+
+	// Each slow window cuts the limit, down to the minimum
+	prev := 8
+	for i := 0; i < 10; i++ {
+		got := a.window(time.Second)
+		if got >= prev && got != 2 {
+			t.Fatalf("slow window %d: limit %d, want below %d", i, got, prev)
+		}
+		prev = got
+	}
+	if prev != 2 {
+		t.Fatalf("limit under sustained slowness = %d, want the minimum 2", prev)
+	}
+
+	// The lower limit holds back dispatch
+	for i := 0; i < 4; i++ {
+		mustSchedule(t, a.s, &ScheduledTask{ID: fmt.Sprintf("held-%d", i)})
+	}
+	for i := 0; i < 4; i += 2 {
+		a.s.processQueue()
+		if got := running(a.s); got != 2 {
+			t.Fatalf("running = %d with the limit at 2", got)
+		}
+		a.c.Advance(100 * time.Millisecond)
+		a.s.completeTask(fmt.Sprintf("held-%d", i), nil, nil)
+		a.s.completeTask(fmt.Sprintf("held-%d", i+1), nil, nil)
+	}
+
+	// Latency back to normal grows the limit one window at a time
+	prev = a.limit()
+	for i := 0; i < 10; i++ {
+		got := a.window(100 * time.Millisecond)
+		if got != min(prev+1, 8) {
+			t.Fatalf("fast window %d: limit %d, want %d", i, got, min(prev+1, 8))
+		}
+		prev = got
+	}
+	if prev != 8 {
+		t.Errorf("recovered limit = %d, want the maximum 8", prev)
+	}
+}
+
+func TestAdaptiveLimitOffKeepsStaticCap(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 3
+	s, c := newTestScheduler(t, cfg)
+	for i := 0; i < 4; i++ {
+		mustSchedule(t, s, &ScheduledTask{ID: fmt.Sprintf("t-%d", i)})
+		s.processQueue()
+		c.Advance(time.Minute)
+		s.completeTask(fmt.Sprintf("t-%d", i), nil, nil)
+	}
+	if got := s.GetStatus()["limit"]; got != 3 {
+		t.Errorf("limit = %v, want the static 3", got)
+	}
+}
+
+func TestAdaptiveLimitBounds(t *testing.T) {
+	a := newAdaptiveLimit(config.AdaptiveConcurrencyConfig{Enabled: true, Min: 4, Max: 6, Window: 1}, 10)
+	if a.limit != 6 || a.tolerance != 1.5 || a.backoff != 0.9 {
+		t.Fatalf("limit %d, tolerance %v, backoff %v; want 6 with defaults", a.limit, a.tolerance, a.backoff)
+	}
+	a.observe(time.Second)
+	for i := 0; i < 5; i++ {
+		a.observe(time.Hour)
+	}
+	if a.limit != 4 {
+		t.Errorf("limit = %d, want held at the minimum 4", a.limit)
+	}
+	if newAdaptiveLimit(config.AdaptiveConcurrencyConfig{}, 10) != nil {
+		t.Error("disabled limiter created")
+	}
+}
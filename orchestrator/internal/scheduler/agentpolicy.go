@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AgentPolicy resolves the run timeout and retry policy for a task routed
+// to the given agents from their configured policies. A zero timeout
+// means no limit. With several agents the most lenient setting wins: the
+// longest timeout, the most retries and the longest backoffs. Retry
+// categories come from the first agent that lists them.
+func (s *Scheduler) AgentPolicy(agents []string) (time.Duration, RetryPolicy) {
+	policies := s.config.Agents.Policies
+
+	// An agent without a timeout leaves the run unbounded
+	var timeout time.Duration
+	for i, name := range agents {
+		agentTimeout := time.Duration(policies[name].Timeout) * time.Second
+		if agentTimeout <= 0 {
+			timeout = 0
+			break
+		}
+		if i == 0 || agentTimeout > timeout {
+			timeout = agentTimeout
+		}
+	}
+
+	var policy RetryPolicy
+	retries, overridden := 0, false
+	for _, name := range agents {
+		ap := policies[name]
+
+		agentRetries := DefaultMaxRetries
+		if ap.MaxRetries != nil {
+			agentRetries = *ap.MaxRetries
+			overridden = true
+		}
+		retries = max(retries, agentRetries)
+
+		policy.InitialBackoff = max(policy.InitialBackoff, time.Duration(ap.InitialBackoff)*time.Second)
+		policy.MaxBackoff = max(policy.MaxBackoff, time.Duration(ap.MaxBackoff)*time.Second)
+		if len(policy.RetryOn) == 0 {
+			policy.RetryOn = ap.RetryOn
+		}
+	}
+
+	// The retry budget stays unset, and so follows the scheduler default,
+	// unless an agent overrides it
+	if overridden {
+		policy.MaxRetries = &retries
+	}
+	return timeout, policy
+}
+
+// resolvePolicy fills in the task's run timeout, and any retry settings
+// it leaves unset, from the policies of the agents it is routed to.
+// Callers hold s.mu.
+func (s *Scheduler) resolvePolicy(task *ScheduledTask) {
+	timeout, policy := s.AgentPolicy(task.Agents)
+	if task.Timeout == 0 {
+		task.Timeout = timeout
+	}
+	if task.Retry.MaxRetries == nil {
+		task.Retry.MaxRetries = policy.MaxRetries
+	}
+	if task.Retry.InitialBackoff == 0 {
+		task.Retry.InitialBackoff = policy.InitialBackoff
+	}
+	if task.Retry.MaxBackoff == 0 {
+		task.Retry.MaxBackoff = policy.MaxBackoff
+	}
+	if len(task.Retry.RetryOn) == 0 {
+		task.Retry.RetryOn = policy.RetryOn
+	}
+
+	s.logger.Debug("Task policy resolved",
+		zap.String("id", task.ID),
+		zap.Strings("agents", task.Agents),
+		zap.Duration("timeout", task.Timeout),
+		zap.Int("max_retries", task.Retry.maxRetries()),
+	)
+}
+
+// timeoutRunning fails runs that have gone on past their task's timeout.
+// The failure is a CategoryTimeout error, so the task's retry policy
+// decides whether it runs again.
+func (s *Scheduler) timeoutRunning() {
+	s.mu.Lock()
+	now := s.clock.Now()
+	overdue := make(map[string]time.Duration)
+	attempts := make(map[string]int)
+	for id, task := range s.running {
+		if task.Timeout > 0 && now.Sub(task.started) >= task.Timeout {
+			overdue[id] = task.Timeout
+			attempts[id] = task.attempt
+		}
+	}
+	s.mu.Unlock()
+
+	// In ID order, so decision logs replay deterministically
+	ids := make([]string, 0, len(overdue))
+	for id := range overdue {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		s.logger.Warn("Task timed out", zap.String("id", id), zap.Duration("timeout", overdue[id]))
+		s.recordTransition(context.Background(), s.completeAttempt(id, attempts[id], nil, &TaskError{
+			Category: CategoryTimeout,
+			Message:  fmt.Sprintf("task ran longer than its %s timeout", overdue[id]),
+		}))
+	}
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// newPolicyScheduler has a slow security agent and a fast explain agent
+func newPolicyScheduler(t *testing.T) (*Scheduler, *ManualClock) {
+	t.Helper()
+	three := 3
+	cfg := testConfig()
+	cfg.Agents.Policies = map[string]config.AgentPolicy{
+		"security": {Timeout: 600, MaxRetries: &three, InitialBackoff: 30},
+		"explain":  {Timeout: 30},
+	}
+	return newTestScheduler(t, cfg)
+}
+
+func TestAgentPolicyResolution(t *testing.T) {
+	s, _ := newPolicyScheduler(t)
+
+	tests := []struct {
+		agents  []string
+		timeout time.Duration
+		retries int
+	}{
+		{[]string{"security"}, 10 * time.Minute, 3},
+		{[]string{"explain"}, 30 * time.Second, DefaultMaxRetries},
+		{[]string{"explain", "security"}, 10 * time.Minute, max(3, DefaultMaxRetries)},
+		{[]string{"security", "review"}, 0, max(3, DefaultMaxRetries)},
+		{nil, 0, DefaultMaxRetries},
+	}
+	for _, tt := range tests {
+		timeout, policy := s.AgentPolicy(tt.agents)
+		if timeout != tt.timeout || policy.maxRetries() != tt.retries {
+			t.Errorf("%v: timeout %s, %d retries; want %s, %d", tt.agents, timeout, policy.maxRetries(), tt.timeout, tt.retries)
+		}
+	}
+}
+
+func TestTaskRoutedToSlowAgentGetsItsTimeout(t *testing.T) {
+	s, c := newPolicyScheduler(t)
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "scan", Type: "security", Agents: []string{"security"}},
+		&ScheduledTask{ID: "quick", Type: "question", Agents: []string{"explain"}},
+		&ScheduledTask{ID: "own", Type: "question", Agents: []string{"explain"}, Timeout: time.Hour},
+	)
+	for id, want := range map[string]time.Duration{"scan": 10 * time.Minute, "quick": 30 * time.Second, "own": time.Hour} {
+		if task, _ := queuedTask(s, id); task.Timeout != want {
+			t.Errorf("%s timeout = %s, want %s", id, task.Timeout, want)
+		}
+	}
+	if task, _ := queuedTask(s, "scan"); task.Retry.InitialBackoff != 30*time.Second {
+		t.Errorf("scan backoff = %s, want the agent's 30s", task.Retry.InitialBackoff)
+	}
+
+	s.processQueue()
+	c.Advance(time.Minute)
+	s.timeoutRunning()
+	if got := stateOf(t, s, "scan"); got != TaskRunning {
+		t.Errorf("slow scan is %s after a minute, want still running", got)
+	}
+	if got := stateOf(t, s, "quick"); got != TaskQueued {
+		t.Errorf("fast task is %s after a minute, want timed out and queued for retry", got)
+	}
+	if got := stateOf(t, s, "own"); got != TaskRunning {
+		t.Errorf("task with its own timeout is %s, want still running", got)
+	}
+
+	c.Advance(9 * time.Minute)
+	s.timeoutRunning()
+	if got := stateOf(t, s, "scan"); got != TaskQueued {
+		t.Errorf("scan is %s past its 10m timeout, want queued for retry", got)
+	}
+}
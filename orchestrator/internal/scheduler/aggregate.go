@@ -0,0 +1,182 @@
+package scheduler
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// defaultFailOn are the finding severities that fail an agent's verdict
+// when the aggregation sets none
+var defaultFailOn = []string{"critical", "high"}
+
+// findingKeys are the result keys agents list their findings under
+var findingKeys = []string{"findings", "issues", "vulnerabilities"}
+
+// collect holds an agent's result of a running task whose type aggregates
+// results. It returns false while other agents' results are outstanding;
+// once every agent has reported it returns their combined result. A task
+// whose type does not aggregate gets r back as it is. Callers hold s.mu.
+func (s *Scheduler) collect(task *ScheduledTask, agent string, r Result) (Result, bool) {
+	ac, ok := s.config.Orchestrator.Aggregation[task.Type]
+	if !ok || len(task.Agents) < 2 || !slices.Contains(task.Agents, agent) {
+		return r, true
+	}
+	if _, reported := task.results[agent]; reported {
+		s.logger.Debug("Ignoring repeated agent result",
+			zap.String("id", task.ID),
+			zap.String("agent", agent),
+		)
+		return r, false
+	}
+
+	if task.results == nil {
+		task.results = make(map[string]Result, len(task.Agents))
+	}
+	task.results[agent] = r
+	s.emit(events.TaskAgentResult, task.ID,
+		fmt.Sprintf("%s %s (%d of %d)", agent, r.Status, len(task.results), len(task.Agents)))
+	if len(task.results) < len(task.Agents) {
+		return r, false
+	}
+
+	combined := aggregate(ac, task.Agents, task.results)
+	combined.TaskID = task.ID
+	task.results = nil
+	return combined, true
+}
+
+// aggregate combines the results of every agent into one. Each agent's
+// result is kept under agents, their findings are merged, tagged with the
+// agent, and passed says whether the agents' verdicts pass the policy.
+// Only when every agent failed does the combined result fail.
+func aggregate(ac config.AggregationConfig, agents []string, results map[string]Result) Result {
+	failOn := ac.FailOn
+	if len(failOn) == 0 {
+		failOn = defaultFailOn
+	}
+
+	perAgent := make(map[string]interface{}, len(agents))
+	findings := []interface{}{}
+	var failures []string
+	var category string
+	var passWeight, totalWeight float64
+	passing, completed := 0, 0
+
+	for _, name := range agents {
+		r := results[name]
+		weight := 1.0
+		if w, ok := ac.Weights[name]; ok && ac.Policy == "weighted" {
+			weight = w
+		}
+		totalWeight += weight
+
+		entry := map[string]interface{}{"status": r.Status}
+		passed := false
+		if r.Status == ResultCompleted {
+			completed++
+			found := findingsOf(name, r.Output)
+			findings = append(findings, found...)
+			passed = verdict(r.Output, found, failOn)
+			entry["result"] = r.Output
+		} else {
+			message := r.Error
+			if message == "" {
+				message = "agent reported failure"
+			}
+			failures = append(failures, name+": "+message)
+			if category == "" {
+				category = r.Category
+			}
+			entry["error"] = message
+		}
+		entry["passed"] = passed
+		perAgent[name] = entry
+
+		if passed {
+			passing++
+			passWeight += weight
+		}
+	}
+
+	if completed == 0 {
+		return Result{
+			Status:   ResultFailed,
+			Error:    "every agent failed: " + strings.Join(failures, "; "),
+			Category: category,
+		}
+	}
+
+	var score float64
+	if totalWeight > 0 {
+		score = passWeight / totalWeight
+	}
+	var passed bool
+	switch ac.Policy {
+	case "majority":
+		passed = passing*2 > len(agents)
+	case "weighted":
+		threshold := ac.Threshold
+		if threshold == 0 {
+			threshold = 0.5
+		}
+		passed = totalWeight > 0 && score >= threshold
+	default: // all
+		passed = passing == len(agents)
+	}
+
+	policy := ac.Policy
+	if policy == "" {
+		policy = "all"
+	}
+	return Result{
+		Status: ResultCompleted,
+		Output: map[string]interface{}{
+			"passed":   passed,
+			"policy":   policy,
+			"score":    score,
+			"findings": findings,
+			"agents":   perAgent,
+		},
+	}
+}
+
+// findingsOf lists the findings in an agent's result, each tagged with
+// the agent that reported it
+func findingsOf(agent string, output map[string]interface{}) []interface{} {
+	var out []interface{}
+	for _, key := range findingKeys {
+		list, _ := output[key].([]interface{})
+		for _, item := range list {
+			finding, ok := item.(map[string]interface{})
+			if ok {
+				finding = maps.Clone(finding)
+			} else {
+				finding = map[string]interface{}{"description": item}
+			}
+			finding["agent"] = agent
+			out = append(out, finding)
+		}
+	}
+	return out
+}
+
+// verdict reports whether an agent's completed result passes: it did not
+// report passed: false and none of its findings has a failing severity
+func verdict(output map[string]interface{}, findings []interface{}, failOn []string) bool {
+	if passed, ok := output["passed"].(bool); ok && !passed {
+		return false
+	}
+	for _, f := range findings {
+		severity, _ := f.(map[string]interface{})["severity"].(string)
+		if slices.Contains(failOn, strings.ToLower(severity)) {
+			return false
+		}
+	}
+	return true
+}
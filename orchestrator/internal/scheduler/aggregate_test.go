@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// clean and flagged are agent results without and with a failing finding
+var (
+	clean   = Result{Status: ResultCompleted, Output: map[string]interface{}{"findings": []interface{}{map[string]interface{}{"severity": "low", "title": "naming"}}}}
+	flagged = Result{Status: ResultCompleted, Output: map[string]interface{}{"vulnerabilities": []interface{}{map[string]interface{}{"severity": "HIGH", "title": "sql injection"}}}}
+)
+
+func TestReviewAggregatesBothAgents(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.Aggregation = map[string]config.AggregationConfig{"code_review": {Policy: "all"}}
+	s, _ := newTestScheduler(t, cfg)
+	transitions := &fakeTransitions{}
+	s.SetTransitions(transitions)
+	mustSchedule(t, s, &ScheduledTask{ID: "pr-1", Type: "code_review", Agents: []string{"review", "security"}})
+	s.processQueue()
+
+	report := func(agent string, r Result) {
+		t.Helper()
+		r.TaskID, r.Attempt = "pr-1", 1
+		if err := s.ReportResult(agent, r); err != nil {
+			t.Fatalf("%s report: %v", agent, err)
+		}
+	}
+	report("review", clean)
+	report("review", flagged) // repeated: ignored
+	if got := stateOf(t, s, "pr-1"); got != TaskRunning {
+		t.Fatalf("task is %s with one agent reported, want running", got)
+	}
+
+	report("security", flagged)
+	recorded := transitions.recorded()
+	if len(recorded) != 1 {
+		t.Fatalf("recorded %d transitions, want the task completed once", len(recorded))
+	}
+	out := recorded[0].Result
+	if out["passed"] != false || out["policy"] != "all" {
+		t.Errorf("verdict = %v under %v, want failed under all", out["passed"], out["policy"])
+	}
+	findings, _ := out["findings"].([]interface{})
+	if len(findings) != 2 {
+		t.Fatalf("findings = %v, want both agents' merged", findings)
+	}
+	for i, agent := range []string{"review", "security"} {
+		if got := findings[i].(map[string]interface{})["agent"]; got != agent {
+			t.Errorf("finding %d tagged %v, want %s", i, got, agent)
+		}
+	}
+	perAgent, _ := out["agents"].(map[string]interface{})
+	if review, _ := perAgent["review"].(map[string]interface{}); review["passed"] != true {
+		t.Errorf("review entry = %v, want passed", review)
+	}
+}
+
+func TestAggregationPolicies(t *testing.T) {
+	two := []string{"review", "security"}
+	tests := []struct {
+		name    string
+		ac      config.AggregationConfig
+		agents  []string
+		results map[string]Result
+		passed  bool
+	}{
+		{"all pass", config.AggregationConfig{}, two, map[string]Result{"review": clean, "security": clean}, true},
+		{"all, one flagged", config.AggregationConfig{Policy: "all"}, two, map[string]Result{"review": clean, "security": flagged}, false},
+		{"majority of two, one flagged", config.AggregationConfig{Policy: "majority"}, two, map[string]Result{"review": clean, "security": flagged}, false},
+		{"majority of three", config.AggregationConfig{Policy: "majority"}, []string{"review", "security", "qa"},
+			map[string]Result{"review": clean, "security": flagged, "qa": clean}, true},
+		{"weighted 3:1", config.AggregationConfig{Policy: "weighted", Weights: map[string]float64{"review": 3}}, two,
+			map[string]Result{"review": clean, "security": flagged}, true},
+		{"weighted under threshold", config.AggregationConfig{Policy: "weighted", Weights: map[string]float64{"review": 3}, Threshold: 0.8}, two,
+			map[string]Result{"review": clean, "security": flagged}, false},
+		{"custom fail_on", config.AggregationConfig{FailOn: []string{"critical"}}, two, map[string]Result{"review": clean, "security": flagged}, true},
+		{"agent failed", config.AggregationConfig{}, two, map[string]Result{"review": clean, "security": {Status: ResultFailed, Error: "crashed"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := aggregate(tt.ac, tt.agents, tt.results)
+			if got.Status != ResultCompleted {
+				t.Fatalf("status = %s, want completed", got.Status)
+			}
+			if got.Output["passed"] != tt.passed {
+				t.Errorf("passed = %v (score %v), want %v", got.Output["passed"], got.Output["score"], tt.passed)
+			}
+		})
+	}
+}
+
+func TestEveryAgentFailingFailsTask(t *testing.T) {
+	got := aggregate(config.AggregationConfig{}, []string{"review", "security"}, map[string]Result{
+		"review":   {Status: ResultFailed, Error: "timed out", Category: CategoryTimeout},
+		"security": {Status: ResultFailed},
+	})
+	if got.Status != ResultFailed || got.Category != CategoryTimeout {
+		t.Fatalf("result = %+v, want failed with the first agent's category", got)
+	}
+	if !strings.Contains(got.Error, "review: timed out") || !strings.Contains(got.Error, "security: agent reported failure") {
+		t.Errorf("error = %q, want both agents' failures", got.Error)
+	}
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+	"go.uber.org/zap"
+)
+
+// ErrTaskActive is returned when archiving a task that has not finished
+var ErrTaskActive = errors.New("task is still active")
+
+// Archive soft-deletes a finished task in the store. Queued, waiting and
+// running tasks must be cancelled first.
+func (s *Scheduler) Archive(ctx context.Context, st store.Store, taskID string) error {
+	if task, ok := s.Task(taskID); ok && task.State != TaskCompleted {
+		return fmt.Errorf("%w: %s", ErrTaskActive, taskID)
+	}
+	return st.Archive(ctx, taskID)
+}
+
+// SweepArchive periodically moves tasks past the retention window into
+// the store's cold tier until ctx is cancelled
+func (s *Scheduler) SweepArchive(ctx context.Context, st store.Store, retention, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweepArchive(ctx, st, retention, batchSize)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepArchive moves batches until one comes back short
+func (s *Scheduler) sweepArchive(ctx context.Context, st store.Store, retention time.Duration, batchSize int) {
+	s.mu.Lock()
+	cutoff := s.clock.Now().Add(-retention)
+	s.mu.Unlock()
+
+	total := 0
+	for {
+		n, err := st.MoveArchived(ctx, cutoff, batchSize)
+		total += n
+		if err != nil {
+			s.logger.Warn("Archival sweep failed", zap.Int("moved", total), zap.Error(err))
+			return
+		}
+		if n == 0 || n < batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		s.logger.Info("Archived tasks", zap.Int("moved", total), zap.Time("cutoff", cutoff))
+	}
+}
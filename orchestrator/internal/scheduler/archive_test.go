@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+// listed returns the IDs ListTasks reports
+func listed(t *testing.T, st store.Store, includeArchived bool) map[string]bool {
+	t.Helper()
+	tasks, err := st.ListTasks(context.Background(), store.ListOptions{IncludeArchived: includeArchived})
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	ids := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		ids[task.ID] = true
+	}
+	return ids
+}
+
+func TestArchivedTasksHiddenUnlessIncluded(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	st := store.NewMemory()
+	for _, id := range []string{"kept", "gone"} {
+		st.AddFinished(store.TaskRecord{ID: id, Status: store.StatusCompleted, CreatedAt: epoch, CompletedAt: epoch})
+	}
+
+	if err := s.Archive(context.Background(), st, "gone"); err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if ids := listed(t, st, false); !ids["kept"] || ids["gone"] {
+		t.Errorf("default list = %v, want only kept", ids)
+	}
+	if ids := listed(t, st, true); !ids["kept"] || !ids["gone"] {
+		t.Errorf("list with archived = %v, want both", ids)
+	}
+	if err := s.Archive(context.Background(), st, "gone"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("archiving twice = %v, want ErrNotFound", err)
+	}
+}
+
+func TestArchiveRefusesActiveTask(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	mustSchedule(t, s, &ScheduledTask{ID: "busy"})
+	s.processQueue()
+
+	if err := s.Archive(context.Background(), store.NewMemory(), "busy"); !errors.Is(err, ErrTaskActive) {
+		t.Errorf("Archive = %v, want ErrTaskActive", err)
+	}
+}
+
+func TestSweepMovesOldTasksToColdTier(t *testing.T) {
+	s, c := newTestScheduler(t, testConfig())
+	st := store.NewMemory()
+	for i, id := range []string{"old-1", "old-2", "old-3", "recent"} {
+		at := epoch.Add(time.Duration(i) * time.Minute)
+		if id == "recent" {
+			at = epoch.Add(48 * time.Hour)
+		}
+		st.AddFinished(store.TaskRecord{ID: id, Status: store.StatusCompleted, CreatedAt: at, CompletedAt: at})
+	}
+	c.Advance(72 * time.Hour)
+
+	// Batches of two, so the sweep needs more than one
+	s.sweepArchive(context.Background(), st, 36*time.Hour, 2)
+
+	if ids := listed(t, st, false); len(ids) != 1 || !ids["recent"] {
+		t.Errorf("default list = %v, want only the recent task", ids)
+	}
+	if ids := listed(t, st, true); len(ids) != 4 {
+		t.Errorf("list with archived = %v, want all four", ids)
+	}
+	task, err := st.Task(context.Background(), "old-2")
+	if err != nil || !task.Archived {
+		t.Errorf("Task(old-2) = %+v, %v; want it archived", task, err)
+	}
+}
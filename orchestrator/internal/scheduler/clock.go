@@ -0,0 +1,19 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/clock"
+)
+
+// Clock is the scheduler's source of time. Injecting a ManualClock makes
+// scheduling sequences reproducible.
+type Clock = clock.Clock
+
+// ManualClock only moves when advanced
+type ManualClock = clock.Manual
+
+// NewManualClock creates a clock frozen at start
+func NewManualClock(start time.Time) *ManualClock {
+	return clock.NewManual(start)
+}
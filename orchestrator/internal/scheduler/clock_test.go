@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+func TestFakeClockDecidesDeadlinesExactly(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	cfg.Orchestrator.DefaultDeadline = true
+	cfg.Orchestrator.TaskTimeout = 60
+	s, c := newTestScheduler(t, cfg)
+	mustSchedule(t, s, &ScheduledTask{ID: "blocker", Type: "review"})
+	s.processQueue()
+
+	c.Advance(5 * time.Second)
+	mustSchedule(t, s, &ScheduledTask{ID: "late", Type: "review"})
+	task, _ := queuedTask(s, "late")
+	if want := epoch.Add(5 * time.Second); !task.ScheduledAt.Equal(want) {
+		t.Errorf("scheduled at %s, want the fake clock's %s", task.ScheduledAt, want)
+	}
+	if want := epoch.Add(65 * time.Second); !task.Deadline.Equal(want) {
+		t.Errorf("deadline %s, want %s", task.Deadline, want)
+	}
+
+	// At the deadline itself the task may still run; a moment later it
+	// has expired, however long the test itself takes
+	c.Advance(60 * time.Second)
+	s.completeTask("blocker", nil, nil)
+	s.processQueue()
+	if got := stateOf(t, s, "late"); got != TaskRunning {
+		t.Fatalf("task is %s at its deadline, want running", got)
+	}
+
+	s.completeTask("late", nil, nil)
+	c.Advance(time.Nanosecond)
+	mustSchedule(t, s, &ScheduledTask{ID: "expired", Type: "review", Deadline: c.Now()})
+	c.Advance(time.Nanosecond)
+	s.processQueue()
+	if got := counts(s, "review"); got.Failed != 1 || got.Completed != 2 {
+		t.Errorf("tally = %+v, want the task past its deadline failed", got)
+	}
+}
+
+func TestFakeClockStampsLogLines(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.TaskLogs = config.TaskLogConfig{MaxLines: 10, MaxBytes: 1 << 10, MaxTasks: 10}
+	s, c := newTestScheduler(t, cfg)
+	c.Advance(time.Hour)
+	s.AppendLog("t1", "dev", "info", "compiling")
+
+	logs := s.Logs("t1")
+	if len(logs) != 1 || !logs[0].Time.Equal(epoch.Add(time.Hour)) {
+		t.Errorf("logs = %+v, want stamped by the fake clock", logs)
+	}
+}
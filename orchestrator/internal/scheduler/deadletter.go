@@ -0,0 +1,314 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// deadLetterKey is the Redis hash of dead letters by task ID
+const deadLetterKey = "odin:deadletter"
+
+// Dead-letter errors
+var (
+	ErrNoDeadLetterQueue  = errors.New("dead-letter queue is not enabled")
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+)
+
+// DeadLetter is a task that failed for good, kept with enough of it to
+// be submitted again
+type DeadLetter struct {
+	Task     LeasedTask `json:"task"`
+	Error    string     `json:"error"`
+	Category string     `json:"category"`
+	FailedAt time.Time  `json:"failed_at"`
+}
+
+// DeadLetterFilter selects dead letters; zero fields match every one
+type DeadLetterFilter struct {
+	Type     string    `json:"type,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Error    string    `json:"error,omitempty"` // case-insensitive substring
+	Since    time.Time `json:"since,omitempty"`
+	Until    time.Time `json:"until,omitempty"`
+}
+
+// Match reports whether a dead letter passes the filter
+func (f DeadLetterFilter) Match(d DeadLetter) bool {
+	switch {
+	case f.Type != "" && d.Task.Type != f.Type:
+		return false
+	case f.Category != "" && d.Category != f.Category:
+		return false
+	case f.Error != "" && !strings.Contains(strings.ToLower(d.Error), strings.ToLower(f.Error)):
+		return false
+	case !f.Since.IsZero() && d.FailedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !d.FailedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// DeadLetterQueue keeps tasks that failed for good until they are
+// retried or purged
+type DeadLetterQueue interface {
+	Put(ctx context.Context, d DeadLetter) error
+
+	// All returns every dead letter, in no particular order
+	All(ctx context.Context) ([]DeadLetter, error)
+
+	// Remove deletes dead letters by task ID and returns how many existed
+	Remove(ctx context.Context, ids ...string) (int, error)
+}
+
+// RedisDeadLetterQueue keeps dead letters in a Redis hash, shared by
+// every instance
+type RedisDeadLetterQueue struct {
+	client *redis.Client
+}
+
+// NewRedisDeadLetterQueue creates a Redis-backed dead-letter queue
+func NewRedisDeadLetterQueue(client *redis.Client) *RedisDeadLetterQueue {
+	return &RedisDeadLetterQueue{client: client}
+}
+
+// Put stores a dead letter under its task ID, replacing an earlier one
+func (q *RedisDeadLetterQueue) Put(ctx context.Context, d DeadLetter) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return q.client.HSet(ctx, deadLetterKey, d.Task.ID, data).Err()
+}
+
+// All reads every dead letter, skipping unreadable entries
+func (q *RedisDeadLetterQueue) All(ctx context.Context) ([]DeadLetter, error) {
+	raw, err := q.client.HGetAll(ctx, deadLetterKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	out := make([]DeadLetter, 0, len(raw))
+	for _, data := range raw {
+		var d DeadLetter
+		if err := json.Unmarshal([]byte(data), &d); err == nil && d.Task.ID != "" {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// Remove deletes dead letters from the hash
+func (q *RedisDeadLetterQueue) Remove(ctx context.Context, ids ...string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	n, err := q.client.HDel(ctx, deadLetterKey, ids...).Result()
+	return int(n), err
+}
+
+// MemoryDeadLetterQueue keeps dead letters in process memory
+type MemoryDeadLetterQueue struct {
+	mu      sync.Mutex
+	letters map[string]DeadLetter
+}
+
+// NewMemoryDeadLetterQueue creates an empty in-memory dead-letter queue
+func NewMemoryDeadLetterQueue() *MemoryDeadLetterQueue {
+	return &MemoryDeadLetterQueue{letters: make(map[string]DeadLetter)}
+}
+
+// Put stores a dead letter under its task ID
+func (q *MemoryDeadLetterQueue) Put(ctx context.Context, d DeadLetter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.letters[d.Task.ID] = d
+	return nil
+}
+
+// All returns every dead letter
+func (q *MemoryDeadLetterQueue) All(ctx context.Context) ([]DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make([]DeadLetter, 0, len(q.letters))
+	for _, d := range q.letters {
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// Remove deletes dead letters by task ID
+func (q *MemoryDeadLetterQueue) Remove(ctx context.Context, ids ...string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := 0
+	for _, id := range ids {
+		if _, ok := q.letters[id]; ok {
+			delete(q.letters, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// SetDeadLetters keeps every task that fails for good in q
+func (s *Scheduler) SetDeadLetters(q DeadLetterQueue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deadLetters = q
+}
+
+// deadLetter queues a task that failed for good. The write happens in
+// the background; a failure to write is logged. Callers hold s.mu.
+func (s *Scheduler) deadLetter(task *ScheduledTask, err error) {
+	q := s.deadLetters
+	if q == nil {
+		return
+	}
+	d := DeadLetter{
+		Task:     leasedTask(task),
+		Error:    err.Error(),
+		Category: categoryOf(err),
+		FailedAt: s.clock.Now(),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := q.Put(ctx, d); err != nil {
+			s.logger.Warn("Failed to dead-letter task", zap.String("id", d.Task.ID), zap.Error(err))
+		}
+	}()
+}
+
+// deadLetterQueue returns the dead-letter queue, or ErrNoDeadLetterQueue
+func (s *Scheduler) deadLetterQueue() (DeadLetterQueue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.deadLetters == nil {
+		return nil, ErrNoDeadLetterQueue
+	}
+	return s.deadLetters, nil
+}
+
+// DeadLetters returns the dead letters matching f, oldest failure first
+func (s *Scheduler) DeadLetters(ctx context.Context, f DeadLetterFilter) ([]DeadLetter, error) {
+	q, err := s.deadLetterQueue()
+	if err != nil {
+		return nil, err
+	}
+	all, err := q.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]DeadLetter, 0, len(all))
+	for _, d := range all {
+		if f.Match(d) {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].FailedAt.Equal(out[j].FailedAt) {
+			return out[i].FailedAt.Before(out[j].FailedAt)
+		}
+		return out[i].Task.ID < out[j].Task.ID
+	})
+	return out, nil
+}
+
+// DeadLetter returns the dead letter of one task
+func (s *Scheduler) DeadLetter(ctx context.Context, id string) (DeadLetter, error) {
+	letters, err := s.DeadLetters(ctx, DeadLetterFilter{})
+	if err != nil {
+		return DeadLetter{}, err
+	}
+	for _, d := range letters {
+		if d.Task.ID == id {
+			return d, nil
+		}
+	}
+	return DeadLetter{}, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+}
+
+// RetryDeadLetter submits a dead-lettered task again under its own ID,
+// with its retry count reset and its deadline cleared, and removes it
+// from the queue
+func (s *Scheduler) RetryDeadLetter(ctx context.Context, id string) error {
+	d, err := s.DeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.retryDeadLetter(ctx, d)
+}
+
+// RetryDeadLetters retries every dead letter matching f, oldest first,
+// and returns the IDs retried. It stops at the first task that cannot be
+// scheduled.
+func (s *Scheduler) RetryDeadLetters(ctx context.Context, f DeadLetterFilter) ([]string, error) {
+	letters, err := s.DeadLetters(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	retried := make([]string, 0, len(letters))
+	for _, d := range letters {
+		if err := s.retryDeadLetter(ctx, d); err != nil {
+			return retried, err
+		}
+		retried = append(retried, d.Task.ID)
+	}
+	return retried, nil
+}
+
+func (s *Scheduler) retryDeadLetter(ctx context.Context, d DeadLetter) error {
+	if s.known(d.Task.ID) {
+		return fmt.Errorf("%w: %s", ErrTaskActive, d.Task.ID)
+	}
+	task := fromLeased(d.Task)
+	task.Retries = 0
+	task.Excluded = nil
+	task.Deadline = time.Time{}
+	if err := s.Schedule(task); err != nil {
+		return err
+	}
+
+	q, err := s.deadLetterQueue()
+	if err != nil {
+		return err
+	}
+	if _, err := q.Remove(ctx, d.Task.ID); err != nil {
+		// The task runs either way; its letter is left to purge
+		s.logger.Warn("Failed to remove retried dead letter", zap.String("id", d.Task.ID), zap.Error(err))
+	}
+	s.logger.Info("Dead letter retried", zap.String("id", d.Task.ID))
+	return nil
+}
+
+// PurgeDeadLetters deletes the dead letters matching f and returns how
+// many were deleted
+func (s *Scheduler) PurgeDeadLetters(ctx context.Context, f DeadLetterFilter) (int, error) {
+	letters, err := s.DeadLetters(ctx, f)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]string, len(letters))
+	for i, d := range letters {
+		ids[i] = d.Task.ID
+	}
+	q, err := s.deadLetterQueue()
+	if err != nil {
+		return 0, err
+	}
+	return q.Remove(ctx, ids...)
+}
@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newDeadLetterScheduler has four dead letters, an hour apart:
+//
+//	a  code_write  timeout  provider timed out
+//	b  code_write  invalid  schema mismatch
+//	c  question    timeout  Timed out waiting for retrieval
+//	d  question    budget   over budget
+func newDeadLetterScheduler(t *testing.T) (*Scheduler, *MemoryDeadLetterQueue) {
+	t.Helper()
+	s, _ := newTestScheduler(t, testConfig())
+	dlq := NewMemoryDeadLetterQueue()
+	s.SetDeadLetters(dlq)
+
+	letters := []DeadLetter{
+		{Task: LeasedTask{ID: "a", Type: "code_write"}, Category: CategoryTimeout, Error: "provider timed out"},
+		{Task: LeasedTask{ID: "b", Type: "code_write"}, Category: CategoryInvalid, Error: "schema mismatch"},
+		{Task: LeasedTask{ID: "c", Type: "question"}, Category: CategoryTimeout, Error: "Timed out waiting for retrieval"},
+		{Task: LeasedTask{ID: "d", Type: "question"}, Category: CategoryBudget, Error: "over budget"},
+	}
+	for i, d := range letters {
+		d.FailedAt = epoch.Add(time.Duration(i) * time.Hour)
+		d.Task.Retries = 3
+		d.Task.Deadline = epoch
+		if err := dlq.Put(context.Background(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, dlq
+}
+
+// letterIDs lists the dead letters matching f
+func letterIDs(t *testing.T, s *Scheduler, f DeadLetterFilter) []string {
+	t.Helper()
+	letters, err := s.DeadLetters(context.Background(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(letters))
+	for i, d := range letters {
+		ids[i] = d.Task.ID
+	}
+	return ids
+}
+
+func TestDeadLettersFiltered(t *testing.T) {
+	s, _ := newDeadLetterScheduler(t)
+
+	tests := []struct {
+		name   string
+		filter DeadLetterFilter
+		want   []string
+	}{
+		{"all, oldest first", DeadLetterFilter{}, []string{"a", "b", "c", "d"}},
+		{"by type", DeadLetterFilter{Type: "code_write"}, []string{"a", "b"}},
+		{"by category", DeadLetterFilter{Category: CategoryTimeout}, []string{"a", "c"}},
+		{"by error text", DeadLetterFilter{Error: "TIMED OUT"}, []string{"a", "c"}},
+		{"since", DeadLetterFilter{Since: epoch.Add(time.Hour)}, []string{"b", "c", "d"}},
+		{"until", DeadLetterFilter{Until: epoch.Add(2 * time.Hour)}, []string{"a", "b"}},
+		{"combined", DeadLetterFilter{Type: "question", Category: CategoryTimeout}, []string{"c"}},
+		{"none match", DeadLetterFilter{Type: "test"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := letterIDs(t, s, tt.filter); !slices.Equal(got, tt.want) {
+				t.Errorf("dead letters = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkRetryResubmitsMatchingLetters(t *testing.T) {
+	s, _ := newDeadLetterScheduler(t)
+
+	retried, err := s.RetryDeadLetters(context.Background(), DeadLetterFilter{Category: CategoryTimeout})
+	if err != nil {
+		t.Fatalf("RetryDeadLetters: %v", err)
+	}
+	if !slices.Equal(retried, []string{"a", "c"}) {
+		t.Errorf("retried %v, want a and c", retried)
+	}
+	for _, id := range retried {
+		task, ok := queuedTask(s, id)
+		if !ok {
+			t.Fatalf("%s not queued after its retry", id)
+		}
+		if task.Retries != 0 || !task.Deadline.IsZero() {
+			t.Errorf("%s resubmitted with %d retries and deadline %v, want both reset", id, task.Retries, task.Deadline)
+		}
+	}
+	if got := letterIDs(t, s, DeadLetterFilter{}); !slices.Equal(got, []string{"b", "d"}) {
+		t.Errorf("left in the queue: %v, want b and d", got)
+	}
+}
+
+func TestRetryOfActiveTaskRefused(t *testing.T) {
+	s, dlq := newDeadLetterScheduler(t)
+	if err := s.RetryDeadLetter(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A letter for a task that is queued again is not scheduled twice
+	dlq.Put(context.Background(), DeadLetter{Task: LeasedTask{ID: "a", Type: "code_write"}, FailedAt: epoch})
+	if err := s.RetryDeadLetter(context.Background(), "a"); !errors.Is(err, ErrTaskActive) {
+		t.Errorf("retry of a queued task: %v, want ErrTaskActive", err)
+	}
+	if err := s.RetryDeadLetter(context.Background(), "ghost"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("retry of an unknown letter: %v, want ErrDeadLetterNotFound", err)
+	}
+}
+
+func TestPurgeDeletesMatchingLetters(t *testing.T) {
+	s, _ := newDeadLetterScheduler(t)
+
+	n, err := s.PurgeDeadLetters(context.Background(), DeadLetterFilter{Type: "question"})
+	if err != nil || n != 2 {
+		t.Fatalf("PurgeDeadLetters = %d, %v; want 2 deleted", n, err)
+	}
+	if got := letterIDs(t, s, DeadLetterFilter{}); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("left in the queue: %v", got)
+	}
+
+	bare, _ := newTestScheduler(t, testConfig())
+	if _, err := bare.DeadLetters(context.Background(), DeadLetterFilter{}); !errors.Is(err, ErrNoDeadLetterQueue) {
+		t.Errorf("without a queue: %v, want ErrNoDeadLetterQueue", err)
+	}
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultDeadlineDerivedFromTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	cfg.Orchestrator.TaskTimeout = 60
+	cfg.Orchestrator.DefaultDeadline = true
+	s, c := newTestScheduler(t, cfg)
+
+	explicit := epoch.Add(time.Hour)
+	mustSchedule(t, s, &ScheduledTask{ID: "blocker"})
+	s.processQueue()
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "derived", Type: "late"},
+		&ScheduledTask{ID: "explicit", Type: "late", Deadline: explicit},
+	)
+
+	derived, _ := queuedTask(s, "derived")
+	if want := epoch.Add(time.Minute); !derived.Deadline.Equal(want) {
+		t.Errorf("derived deadline = %v, want %v", derived.Deadline, want)
+	}
+	if task, _ := queuedTask(s, "explicit"); !task.Deadline.Equal(explicit) {
+		t.Errorf("explicit deadline replaced with %v", task.Deadline)
+	}
+
+	// Still queued past its deadline, the task expires when its turn comes
+	c.Advance(61 * time.Second)
+	s.completeTask("blocker", nil, nil)
+	s.processQueue()
+
+	if got := counts(s, "late"); got.Failed != 1 {
+		t.Errorf("tally = %+v, want the derived task expired", got)
+	}
+	if got := stateOf(t, s, "explicit"); got != TaskRunning {
+		t.Errorf("task with a later deadline is %s, want running", got)
+	}
+}
+
+func TestNoDefaultDeadlineUnlessEnabled(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	cfg.Orchestrator.TaskTimeout = 60
+	s, c := newTestScheduler(t, cfg)
+
+	mustSchedule(t, s, &ScheduledTask{ID: "blocker"})
+	s.processQueue()
+	mustSchedule(t, s, &ScheduledTask{ID: "open"})
+	if task, _ := queuedTask(s, "open"); !task.Deadline.IsZero() {
+		t.Fatalf("deadline = %v with the option off, want none", task.Deadline)
+	}
+
+	c.Advance(time.Hour)
+	s.completeTask("blocker", nil, nil)
+	s.processQueue()
+	if got := stateOf(t, s, "open"); got != TaskRunning {
+		t.Errorf("task is %s, want running", got)
+	}
+}
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// dedupPrefix namespaces completion delivery IDs in Redis
+const dedupPrefix = "odin:completion:"
+
+// Deduper remembers completion delivery IDs for a limited time so a
+// redelivered completion can be recognised
+type Deduper interface {
+	// FirstDelivery records the ID and reports whether it was unseen
+	FirstDelivery(ctx context.Context, deliveryID string) (bool, error)
+}
+
+// RedisDeduper tracks delivery IDs as expiring Redis keys, shared by
+// every orchestrator instance
+type RedisDeduper struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisDeduper creates a Redis-backed deduper
+func NewRedisDeduper(client *redis.Client, ttl time.Duration) *RedisDeduper {
+	return &RedisDeduper{client: client, ttl: ttl}
+}
+
+// FirstDelivery uses SET NX so only one caller ever wins a delivery ID
+func (d *RedisDeduper) FirstDelivery(ctx context.Context, deliveryID string) (bool, error) {
+	return d.client.SetNX(ctx, dedupPrefix+deliveryID, 1, d.ttl).Result()
+}
+
+// MemoryDeduper tracks delivery IDs in process memory
+type MemoryDeduper struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	clock     Clock
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+// NewMemoryDeduper creates an in-memory deduper expiring IDs on clock
+func NewMemoryDeduper(ttl time.Duration, c Clock) *MemoryDeduper {
+	return &MemoryDeduper{ttl: ttl, clock: c, seen: make(map[string]time.Time)}
+}
+
+// SetClock replaces the deduper's time source
+func (d *MemoryDeduper) SetClock(c Clock) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.clock = c
+}
+
+// FirstDelivery records the ID, pruning expired entries periodically
+func (d *MemoryDeduper) FirstDelivery(ctx context.Context, deliveryID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	if now.Sub(d.lastPrune) > d.ttl/2 {
+		for id, expires := range d.seen {
+			if now.After(expires) {
+				delete(d.seen, id)
+			}
+		}
+		d.lastPrune = now
+	}
+
+	if expires, dup := d.seen[deliveryID]; dup && now.Before(expires) {
+		return false, nil
+	}
+	d.seen[deliveryID] = now.Add(d.ttl)
+	return true, nil
+}
+
+// firstDelivery reports whether a completion or result with the given
+// delivery ID should be processed. One without an ID always is, as is
+// every delivery while the deduper is unavailable.
+func (s *Scheduler) firstDelivery(ctx context.Context, taskID, deliveryID string) bool {
+	if deliveryID == "" {
+		return true
+	}
+	s.mu.Lock()
+	deduper := s.deduper
+	s.mu.Unlock()
+
+	first, err := deduper.FirstDelivery(ctx, deliveryID)
+	if err != nil {
+		// completeTask is idempotent, so processing without the
+		// dedup record only risks a no-op
+		s.logger.Warn("Completion dedup unavailable",
+			zap.String("delivery_id", deliveryID),
+			zap.Error(err),
+		)
+		return true
+	}
+	if !first {
+		s.logger.Debug("Duplicate completion delivery ignored",
+			zap.String("id", taskID),
+			zap.String("delivery_id", deliveryID),
+		)
+	}
+	return first
+}
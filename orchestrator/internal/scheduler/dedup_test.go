@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+// fakeTransitions records transitions in memory
+type fakeTransitions struct {
+	mu   sync.Mutex
+	seen []store.Transition
+}
+
+func (f *fakeTransitions) RecordTransition(ctx context.Context, t store.Transition) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seen = append(f.seen, t)
+	return nil
+}
+
+func (f *fakeTransitions) recorded() []store.Transition {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]store.Transition(nil), f.seen...)
+}
+
+func TestRedeliveredCompletionTransitionsOnce(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.CompletionDedupTTL = 60
+	s, _ := newTestScheduler(t, cfg)
+	transitions := &fakeTransitions{}
+	s.SetTransitions(transitions)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "question"})
+	s.processQueue()
+
+	c := Completion{TaskID: "t1", DeliveryID: "d-1", Attempt: 1, Output: map[string]interface{}{"answer": "yes"}}
+	processed, err := s.HandleCompletion(context.Background(), c)
+	if err != nil || !processed {
+		t.Fatalf("first delivery: processed=%v err=%v", processed, err)
+	}
+	processed, err = s.HandleCompletion(context.Background(), c)
+	if err != nil {
+		t.Fatalf("redelivery should be acknowledged, got %v", err)
+	}
+	if processed {
+		t.Error("redelivery was processed")
+	}
+
+	if got := transitions.recorded(); len(got) != 1 || got[0].Status != store.StatusCompleted {
+		t.Errorf("transitions = %+v, want a single completion", got)
+	}
+	if got := counts(s, "question"); got.Completed != 1 {
+		t.Errorf("tally = %+v, want one completion", got)
+	}
+}
+
+func TestConcurrentRedeliveriesProcessOnce(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.CompletionDedupTTL = 60
+	s, _ := newTestScheduler(t, cfg)
+	transitions := &fakeTransitions{}
+	s.SetTransitions(transitions)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "question"})
+	s.processQueue()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		processed int
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := s.HandleCompletion(context.Background(), Completion{TaskID: "t1", DeliveryID: "d-1", Attempt: 1})
+			if err != nil {
+				t.Errorf("HandleCompletion: %v", err)
+			}
+			if ok {
+				mu.Lock()
+				processed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if processed != 1 {
+		t.Errorf("%d deliveries processed, want 1", processed)
+	}
+	if got := transitions.recorded(); len(got) != 1 {
+		t.Errorf("%d transitions recorded, want 1", len(got))
+	}
+}
+
+func TestMemoryDeduperExpiresOnClock(t *testing.T) {
+	c := NewManualClock(epoch)
+	d := NewMemoryDeduper(time.Minute, c)
+	first := func() bool {
+		t.Helper()
+		ok, err := d.FirstDelivery(context.Background(), "d-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if !first() {
+		t.Fatal("first delivery reported as a duplicate")
+	}
+	c.Advance(59 * time.Second)
+	if first() {
+		t.Fatal("redelivery within the TTL reported as first")
+	}
+	c.Advance(2 * time.Second)
+	if !first() {
+		t.Error("delivery after the TTL reported as a duplicate")
+	}
+}
+
+func TestSchedulerDeduperFollowsItsClock(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.CompletionDedupTTL = 60
+	s, c := newTestScheduler(t, cfg)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1"}, &ScheduledTask{ID: "t2"})
+	s.processQueue()
+
+	deliver := func(id string) bool {
+		t.Helper()
+		ok, err := s.HandleCompletion(context.Background(), Completion{TaskID: id, DeliveryID: "d-1", Attempt: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	if !deliver("t1") {
+		t.Fatal("first delivery not processed")
+	}
+	if deliver("t2") {
+		t.Fatal("a delivery ID reused within the TTL was processed")
+	}
+	c.Advance(61 * time.Second)
+	if !deliver("t2") {
+		t.Error("a delivery ID reused after the TTL, by the scheduler's clock, was ignored")
+	}
+}
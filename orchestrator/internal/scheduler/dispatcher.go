@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"time"
+)
+
+// Dispatched is a task the scheduler has started, as handed to its
+// agents. Payload is the task as submitted; Input holds what the
+// scheduler added to its input since, such as an earlier stage's output.
+type Dispatched struct {
+	TaskID  string
+	Attempt int
+	Agents  []string
+	Input   map[string]interface{}
+	Payload json.RawMessage
+
+	// Instances is the instance of each agent the dispatch is meant for;
+	// agents report Attempt and their instance with the result
+	Instances map[string]string
+}
+
+// DispatchFunc delivers a dispatched task to its agents;
+// router.Router.Dispatch is one
+type DispatchFunc func(ctx context.Context, d Dispatched) error
+
+// SetDispatcher installs what hands dispatched tasks to their agents.
+// Without one, tasks are started but never delivered.
+func (s *Scheduler) SetDispatcher(fn DispatchFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dispatcher = fn
+}
+
+// dispatched describes a task being started. Callers hold s.mu.
+func dispatched(task *ScheduledTask) Dispatched {
+	return Dispatched{
+		TaskID:    task.ID,
+		Attempt:   task.attempt,
+		Agents:    append([]string(nil), task.Agents...),
+		Input:     maps.Clone(task.Input),
+		Payload:   task.Payload,
+		Instances: maps.Clone(task.instances),
+	}
+}
+
+// handOut hands a started task to its agents. A task that cannot be
+// delivered fails its run as unavailable, to be retried like one its
+// agent never picked up.
+func (s *Scheduler) handOut(d Dispatched) {
+	s.mu.Lock()
+	fn := s.dispatcher
+	s.mu.Unlock()
+
+	if fn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := fn(ctx, d); err != nil {
+		failure := &TaskError{Category: CategoryUnavailable, Message: "dispatch failed: " + err.Error()}
+		s.recordTransition(context.Background(), s.completeAttempt(d.TaskID, d.Attempt, nil, failure))
+	}
+}
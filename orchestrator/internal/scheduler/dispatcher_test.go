@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// dispatchLog records what the scheduler hands out, failing each
+// delivery with err
+type dispatchLog struct {
+	err error
+
+	mu   sync.Mutex
+	sent []Dispatched
+}
+
+func (l *dispatchLog) dispatch(ctx context.Context, d Dispatched) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sent = append(l.sent, d)
+	return l.err
+}
+
+func (l *dispatchLog) delivered() []Dispatched {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Dispatched(nil), l.sent...)
+}
+
+func TestDispatchDeliversPayload(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	log := &dispatchLog{}
+	s.SetDispatcher(log.dispatch)
+
+	payload := json.RawMessage(`{"prompt": "review this"}`)
+	mustSchedule(t, s, &ScheduledTask{ID: "t", Agents: []string{"review"}, Payload: payload})
+	s.processQueue()
+	waitUntil(t, "the dispatch", func() bool { return len(log.delivered()) == 1 })
+
+	d := log.delivered()[0]
+	if d.TaskID != "t" || d.Attempt != 1 || string(d.Payload) != string(payload) {
+		t.Errorf("dispatched = %+v, want attempt 1 of t with its payload", d)
+	}
+	if len(d.Agents) != 1 || d.Agents[0] != "review" {
+		t.Errorf("dispatched to %v, want review", d.Agents)
+	}
+}
+
+func TestFailedDispatchRetriesTask(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	s.SetDispatcher((&dispatchLog{err: errors.New("stream down")}).dispatch)
+
+	mustSchedule(t, s, &ScheduledTask{ID: "t"})
+	s.processQueue()
+	waitUntil(t, "the task to be requeued", func() bool { return stateOf(t, s, "t") == TaskQueued })
+}
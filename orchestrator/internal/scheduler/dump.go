@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"maps"
+	"sort"
+	"time"
+)
+
+// TypeCounts tallies the tasks of one type. Queued, Running, Waiting and
+// Suspended are current; the rest count tasks finished since the
+// scheduler started.
+type TypeCounts struct {
+	Queued    int `json:"queued"`
+	Running   int `json:"running"`
+	Waiting   int `json:"waiting"`
+	Suspended int `json:"suspended"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+}
+
+// DumpTask is the full debugging view of a task held in memory
+type DumpTask struct {
+	TaskSnapshot
+	Type         string                 `json:"type,omitempty"`
+	Agents       []string               `json:"agents,omitempty"`
+	Dependencies []string               `json:"dependencies,omitempty"`
+	Unmet        int                    `json:"unmet,omitempty"`
+	Deadline     time.Time              `json:"deadline,omitempty"`
+	StartedAt    time.Time              `json:"started_at,omitempty"`
+	Excluded     map[string]time.Time   `json:"excluded,omitempty"`
+	Input        map[string]interface{} `json:"input,omitempty"`
+	Timeout      time.Duration          `json:"timeout,omitempty"`
+	MaxRetries   int                    `json:"max_retries"`
+}
+
+// Dump is a consistent snapshot of the scheduler's internals
+type Dump struct {
+	Taken         time.Time              `json:"taken"`
+	MaxConcurrent int                    `json:"max_concurrent"`
+	Queued        []DumpTask             `json:"queued"`  // in dispatch order
+	Running       []DumpTask             `json:"running"` // oldest start first
+	Waiting       []DumpTask             `json:"waiting"` // by ID
+	Reserved      [][]DumpTask           `json:"reserved"`
+	Completed     int                    `json:"completed"`
+	ByType        map[string]*TypeCounts `json:"by_type"`
+
+	// Suspended holds tasks awaiting an approval, by ID
+	Suspended []DumpTask `json:"suspended"`
+}
+
+// Dump snapshots every task the scheduler holds, all under one lock so the
+// sections agree with each other. Inputs are returned as they are; callers
+// exposing the dump redact them.
+func (s *Scheduler) Dump() Dump {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := Dump{
+		Taken:         s.clock.Now(),
+		MaxConcurrent: s.maxConcurrent,
+		Queued:        make([]DumpTask, 0, s.queue.Len()),
+		Running:       make([]DumpTask, 0, len(s.running)),
+		Waiting:       make([]DumpTask, 0, len(s.waiting)),
+		Reserved:      make([][]DumpTask, 0, len(s.reservations)),
+		Completed:     len(s.completed),
+		ByType:        make(map[string]*TypeCounts),
+		Suspended:     make([]DumpTask, 0, len(s.suspended)),
+	}
+	counts := func(taskType string) *TypeCounts {
+		c, ok := d.ByType[taskType]
+		if !ok {
+			c = &TypeCounts{}
+			d.ByType[taskType] = c
+		}
+		return c
+	}
+	for taskType, c := range s.finished {
+		*counts(taskType) = *c
+	}
+
+	// Sort a copy: the queue's own Swap would rewrite heap indexes
+	queued := append(TaskQueue{}, s.queue...)
+	sort.Slice(queued, func(i, j int) bool { return s.less(queued[i], queued[j]) })
+	for _, task := range queued {
+		d.Queued = append(d.Queued, dumpTask(task))
+		counts(task.Type).Queued++
+	}
+
+	for _, task := range s.running {
+		d.Running = append(d.Running, dumpTask(task))
+		counts(task.Type).Running++
+	}
+	sort.Slice(d.Running, func(i, j int) bool { return d.Running[i].StartedAt.Before(d.Running[j].StartedAt) })
+
+	for _, task := range s.waiting {
+		d.Waiting = append(d.Waiting, dumpTask(task))
+		counts(task.Type).Waiting++
+	}
+	sort.Slice(d.Waiting, func(i, j int) bool { return d.Waiting[i].ID < d.Waiting[j].ID })
+
+	for _, res := range s.reservations {
+		group := make([]DumpTask, 0, len(res.tasks))
+		for _, task := range res.tasks {
+			group = append(group, dumpTask(task))
+			counts(task.Type).Queued++
+		}
+		d.Reserved = append(d.Reserved, group)
+	}
+
+	for _, task := range s.suspended {
+		d.Suspended = append(d.Suspended, dumpTask(task))
+		counts(task.Type).Suspended++
+	}
+	sort.Slice(d.Suspended, func(i, j int) bool { return d.Suspended[i].ID < d.Suspended[j].ID })
+
+	return d
+}
+
+func dumpTask(task *ScheduledTask) DumpTask {
+	d := DumpTask{
+		TaskSnapshot: snapshot(task),
+		Type:         task.Type,
+		Agents:       task.Agents,
+		Dependencies: task.Dependencies,
+		Unmet:        task.unmet,
+		Deadline:     task.Deadline,
+		Excluded:     maps.Clone(task.Excluded),
+		Input:        maps.Clone(task.Input),
+		Timeout:      task.Timeout,
+		MaxRetries:   task.Retry.maxRetries(),
+	}
+	if task.State == TaskRunning {
+		d.StartedAt = task.started
+	}
+	return d
+}
+
+// tally counts a task that has just finished. Callers hold s.mu.
+func (s *Scheduler) tally(task *ScheduledTask) {
+	c, ok := s.finished[task.Type]
+	if !ok {
+		c = &TypeCounts{}
+		s.finished[task.Type] = c
+	}
+	switch task.State {
+	case TaskCompleted:
+		c.Completed++
+	case TaskFailed:
+		c.Failed++
+	case TaskCancelled:
+		c.Cancelled++
+	}
+}
@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+// ids lists the IDs of dumped tasks in order
+func ids(tasks []DumpTask) []string {
+	out := make([]string, len(tasks))
+	for i, task := range tasks {
+		out[i] = task.ID
+	}
+	return out
+}
+
+func TestDumpReflectsSchedulerState(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 2
+	s, c := newTestScheduler(t, cfg)
+
+	mustSchedule(t, s, &ScheduledTask{ID: "first", Type: "question"})
+	s.processQueue()
+	c.Advance(time.Second)
+	mustSchedule(t, s, &ScheduledTask{ID: "second", Type: "analysis"})
+	s.processQueue()
+	c.Advance(time.Second)
+
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "low", Type: "question", Priority: PriorityLow},
+		&ScheduledTask{ID: "high", Type: "question", Priority: PriorityHigh, Input: map[string]interface{}{"q": "why"}},
+		&ScheduledTask{ID: "after", Type: "analysis", Dependencies: []string{"low"}},
+	)
+
+	d := s.Dump()
+	if !d.Taken.Equal(c.Now()) || d.MaxConcurrent != 2 {
+		t.Errorf("taken %v with max %d, want now and 2", d.Taken, d.MaxConcurrent)
+	}
+	if got := ids(d.Running); len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("running = %v, want [first second] oldest first", got)
+	}
+	if !d.Running[0].StartedAt.Equal(epoch) || !d.Running[1].StartedAt.Equal(epoch.Add(time.Second)) {
+		t.Errorf("start times = %v, %v", d.Running[0].StartedAt, d.Running[1].StartedAt)
+	}
+	if got := ids(d.Queued); len(got) != 2 || got[0] != "high" || got[1] != "low" {
+		t.Errorf("queued = %v, want [high low] in dispatch order", got)
+	}
+	if d.Queued[0].Input["q"] != "why" {
+		t.Errorf("queued input = %v, want it kept", d.Queued[0].Input)
+	}
+	if len(d.Waiting) != 1 || d.Waiting[0].ID != "after" || d.Waiting[0].Unmet != 1 {
+		t.Errorf("waiting = %+v, want after with one unmet dependency", d.Waiting)
+	}
+
+	want := map[string]TypeCounts{
+		"question": {Queued: 2, Running: 1},
+		"analysis": {Running: 1, Waiting: 1},
+	}
+	for taskType, counts := range want {
+		if got := d.ByType[taskType]; got == nil || *got != counts {
+			t.Errorf("%s counts = %+v, want %+v", taskType, got, counts)
+		}
+	}
+
+	s.completeTask("first", nil, nil)
+	d = s.Dump()
+	if d.Completed != 1 || d.ByType["question"].Completed != 1 || d.ByType["question"].Running != 0 {
+		t.Errorf("after completion: completed %d, question %+v", d.Completed, *d.ByType["question"])
+	}
+}
+
+func TestDumpLeavesQueueOrderIntact(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "a", Priority: PriorityLow},
+		&ScheduledTask{ID: "b", Priority: PriorityCritical},
+		&ScheduledTask{ID: "c", Priority: PriorityNormal},
+	)
+
+	s.Dump()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, task := range s.queue {
+		if task.index != i {
+			t.Errorf("%s has heap index %d at position %d", task.ID, task.index, i)
+		}
+	}
+}
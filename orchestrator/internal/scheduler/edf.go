@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"container/heap"
+
+	"go.uber.org/zap"
+)
+
+// Queue orderings, chosen by orchestrator.scheduling
+const (
+	SchedulePriority = "priority" // highest effective priority first
+	ScheduleEDF      = "edf"      // earliest deadline first
+)
+
+// deadlineQueue orders the task queue earliest deadline first. Tasks
+// without a deadline sort after those with one, and ties fall back to
+// priority order.
+type deadlineQueue struct{ *TaskQueue }
+
+func (q deadlineQueue) Less(i, j int) bool {
+	return byDeadline((*q.TaskQueue)[i], (*q.TaskQueue)[j])
+}
+
+func byDeadline(a, b *ScheduledTask) bool {
+	switch {
+	case a.Deadline.IsZero() != b.Deadline.IsZero():
+		return !a.Deadline.IsZero()
+	case !a.Deadline.Equal(b.Deadline):
+		return a.Deadline.Before(b.Deadline)
+	}
+	return byPriority(a, b)
+}
+
+// edfScheduling reports whether the configured ordering is earliest
+// deadline first, warning of an unknown one
+func edfScheduling(name string, logger *zap.Logger) bool {
+	switch name {
+	case "", SchedulePriority:
+		return false
+	case ScheduleEDF:
+		return true
+	}
+	logger.Warn("Unknown scheduling mode, using priority", zap.String("scheduling", name))
+	return false
+}
+
+// tasks is the queue as a heap under the configured ordering. Callers
+// hold s.mu.
+func (s *Scheduler) tasks() heap.Interface {
+	if s.edf {
+		return deadlineQueue{&s.queue}
+	}
+	return &s.queue
+}
+
+// less reports whether a dispatches before b under the configured
+// ordering
+func (s *Scheduler) less(a, b *ScheduledTask) bool {
+	if s.edf {
+		return byDeadline(a, b)
+	}
+	return byPriority(a, b)
+}
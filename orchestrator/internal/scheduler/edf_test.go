@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// dispatchOrder runs queued tasks one at a time, returning the order they
+// were dispatched in
+func dispatchOrder(t *testing.T, s *Scheduler, n int) []string {
+	t.Helper()
+	var order []string
+	for i := 0; i < n; i++ {
+		s.processQueue()
+		ids := runningIDs(s)
+		if len(ids) != 1 {
+			t.Fatalf("running %v, want one task at a time", ids)
+		}
+		order = append(order, ids[0])
+		s.completeTask(ids[0], nil, nil)
+	}
+	return order
+}
+
+// mixed queues tasks whose deadlines and priorities disagree
+func mixed(t *testing.T, s *Scheduler) {
+	t.Helper()
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "critical-open", Priority: PriorityCritical},
+		&ScheduledTask{ID: "low-soon", Priority: PriorityLow, Deadline: epoch.Add(time.Minute)},
+		&ScheduledTask{ID: "high-later", Priority: PriorityHigh, Deadline: epoch.Add(time.Hour)},
+		&ScheduledTask{ID: "normal-later", Priority: PriorityNormal, Deadline: epoch.Add(time.Hour)},
+		&ScheduledTask{ID: "normal-open", Priority: PriorityNormal},
+	)
+}
+
+func TestEDFDispatchesNearestDeadlineFirst(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	cfg.Orchestrator.Scheduling = ScheduleEDF
+	s, _ := newTestScheduler(t, cfg)
+	mixed(t, s)
+	heapOK(t, s, "scheduling")
+
+	want := []string{"low-soon", "high-later", "normal-later", "critical-open", "normal-open"}
+	if got := dispatchOrder(t, s, len(want)); !slices.Equal(got, want) {
+		t.Errorf("dispatched %v, want %v", got, want)
+	}
+}
+
+func TestPriorityModeIgnoresDeadlines(t *testing.T) {
+	for _, mode := range []string{"", SchedulePriority, "fifo"} {
+		cfg := testConfig()
+		cfg.Orchestrator.MaxConcurrentTasks = 1
+		cfg.Orchestrator.Scheduling = mode
+		s, _ := newTestScheduler(t, cfg)
+		mixed(t, s)
+
+		want := []string{"critical-open", "high-later", "normal-later", "normal-open", "low-soon"}
+		if got := dispatchOrder(t, s, len(want)); !slices.Equal(got, want) {
+			t.Errorf("mode %q dispatched %v, want %v", mode, got, want)
+		}
+	}
+}
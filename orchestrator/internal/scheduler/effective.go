@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// priorityBands names the priority levels
+var priorityBands = map[TaskPriority]string{
+	PriorityLow:      "low",
+	PriorityNormal:   "normal",
+	PriorityHigh:     "high",
+	PriorityCritical: "critical",
+}
+
+// EffectiveConfig is the fully resolved settings a task was dispatched
+// under, after task overrides, agent policies and defaults. It is
+// recorded at each dispatch, so it stays accurate after the task finishes
+// or the configuration changes.
+type EffectiveConfig struct {
+	TaskID            string        `json:"task_id"`
+	Type              string        `json:"type,omitempty"`
+	DispatchedAt      time.Time     `json:"dispatched_at"`
+	Attempt           int           `json:"attempt"` // 1 for the first run
+	Priority          TaskPriority  `json:"priority"`
+	EffectivePriority TaskPriority  `json:"effective_priority"`
+	Band              string        `json:"band"`    // of the effective priority
+	Timeout           time.Duration `json:"timeout"` // 0 = no limit
+	AckTimeout        time.Duration `json:"ack_timeout,omitempty"`
+	Deadline          time.Time     `json:"deadline,omitempty"`
+	MaxQueueTime      time.Duration `json:"max_queue_time,omitempty"`
+	Retry             RetryPolicy   `json:"retry"`
+	Agents            []string      `json:"agents"`
+	Excluded          []string      `json:"excluded,omitempty"`
+
+	// Submitted holds settings resolved outside the scheduler when the
+	// task was submitted, such as its provider chain and sandbox
+	Submitted map[string]interface{} `json:"submitted,omitempty"`
+}
+
+// recordEffective keeps the settings a task is being dispatched under,
+// dropping the least recently dispatched task's once more than
+// orchestrator.effective_configs are held. Callers hold s.mu.
+func (s *Scheduler) recordEffective(task *ScheduledTask) {
+	priority := task.EffectivePriority()
+	ec := EffectiveConfig{
+		TaskID:            task.ID,
+		Type:              task.Type,
+		DispatchedAt:      task.started,
+		Attempt:           task.Retries + 1,
+		Priority:          task.Priority,
+		EffectivePriority: priority,
+		Band:              priorityBands[priority],
+		Timeout:           task.Timeout,
+		AckTimeout:        task.ackTimeout,
+		Deadline:          task.Deadline,
+		MaxQueueTime:      task.MaxQueueTime,
+		Retry:             task.Retry.resolved(),
+		Agents:            slices.Clone(task.Agents),
+		Excluded:          s.excluded(task),
+		Submitted:         maps.Clone(task.Settings),
+	}
+	if len(ec.Excluded) == 0 {
+		ec.Excluded = nil
+	}
+
+	if _, ok := s.effective[task.ID]; ok {
+		s.effectiveOrder = slices.DeleteFunc(s.effectiveOrder, func(id string) bool { return id == task.ID })
+	}
+	s.effective[task.ID] = ec
+	s.effectiveOrder = append(s.effectiveOrder, task.ID)
+	for len(s.effectiveOrder) > max(s.config.Orchestrator.EffectiveConfigs, 1) {
+		delete(s.effective, s.effectiveOrder[0])
+		s.effectiveOrder = s.effectiveOrder[1:]
+	}
+}
+
+// EffectiveConfig returns the settings a task was last dispatched under.
+// Tasks not yet dispatched, or dispatched too long ago, have none.
+func (s *Scheduler) EffectiveConfig(taskID string) (EffectiveConfig, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ec, ok := s.effective[taskID]
+	return ec, ok
+}
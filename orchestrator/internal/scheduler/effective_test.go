@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+func TestEffectiveConfigReflectsLayeredOverrides(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	cfg.Agents.Policies = map[string]config.AgentPolicy{
+		"security": {Timeout: 600, InitialBackoff: 30, RetryOn: []string{CategoryTimeout}},
+	}
+	s, _ := newTestScheduler(t, cfg)
+	mustSchedule(t, s, &ScheduledTask{ID: "blocker"})
+	s.processQueue()
+
+	one := 1
+	mustSchedule(t, s,
+		&ScheduledTask{
+			ID:       "scan",
+			Type:     "security",
+			Priority: PriorityLow,
+			Agents:   []string{"security"},
+			Retry:    RetryPolicy{MaxRetries: &one},
+			Settings: map[string]interface{}{"traced": true},
+		},
+		// A critical dependent lends the scan its priority
+		&ScheduledTask{ID: "report", Priority: PriorityCritical, Dependencies: []string{"scan"}},
+	)
+	if _, ok := s.EffectiveConfig("scan"); ok {
+		t.Fatal("effective config recorded before dispatch")
+	}
+
+	s.completeTask("blocker", nil, nil)
+	s.processQueue()
+	ec, ok := s.EffectiveConfig("scan")
+	if !ok {
+		t.Fatal("no effective config recorded at dispatch")
+	}
+
+	if ec.Priority != PriorityLow || ec.EffectivePriority != PriorityCritical || ec.Band != "critical" {
+		t.Errorf("priority %d, effective %d (%s); want low raised to critical", ec.Priority, ec.EffectivePriority, ec.Band)
+	}
+	if ec.Timeout != 10*time.Minute {
+		t.Errorf("timeout = %s, want the agent's 10m", ec.Timeout)
+	}
+	if ec.Retry.MaxRetries == nil || *ec.Retry.MaxRetries != 1 {
+		t.Errorf("max retries = %v, want the task's own 1", ec.Retry.MaxRetries)
+	}
+	if ec.Retry.InitialBackoff != 30*time.Second || !slices.Equal(ec.Retry.RetryOn, []string{CategoryTimeout}) {
+		t.Errorf("retry = %+v, want the agent's backoff and categories", ec.Retry)
+	}
+	if !slices.Equal(ec.Agents, []string{"security"}) || ec.Attempt != 1 || !ec.DispatchedAt.Equal(epoch) {
+		t.Errorf("effective config = %+v, want the first dispatch to security", ec)
+	}
+	if ec.Submitted["traced"] != true {
+		t.Errorf("submitted settings = %v, want them kept", ec.Submitted)
+	}
+
+	// The record outlives the task
+	s.completeTask("scan", nil, nil)
+	if _, ok := s.EffectiveConfig("scan"); !ok {
+		t.Error("effective config dropped when the task finished")
+	}
+}
+
+func TestEffectiveConfigsBounded(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.EffectiveConfigs = 2
+	s, _ := newTestScheduler(t, cfg)
+	for _, id := range []string{"t1", "t2", "t3"} {
+		mustSchedule(t, s, &ScheduledTask{ID: id})
+		s.processQueue()
+	}
+
+	if _, ok := s.EffectiveConfig("t1"); ok {
+		t.Error("oldest record kept past the limit")
+	}
+	for _, id := range []string{"t2", "t3"} {
+		if _, ok := s.EffectiveConfig(id); !ok {
+			t.Errorf("%s record dropped", id)
+		}
+	}
+}
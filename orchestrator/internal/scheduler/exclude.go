@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Reroute picks the agents for a retry of a task of the given type, or
+// matching the given capabilities if it has any, avoiding the listed
+// agent instances
+type Reroute func(taskType string, caps map[string]float64, exclude []string) ([]string, error)
+
+// SetReroute installs the router used to move a retried task off the
+// instances that failed it. Without one, retries keep their agents.
+func (s *Scheduler) SetReroute(fn Reroute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reroute = fn
+}
+
+// exclude keeps an agent instance off the task's retries for the
+// configured cooldown. Callers hold s.mu.
+func (s *Scheduler) exclude(task *ScheduledTask, instance string) {
+	if s.exclusion <= 0 {
+		return
+	}
+	if task.Excluded == nil {
+		task.Excluded = make(map[string]time.Time)
+	}
+	task.Excluded[instance] = s.clock.Now().Add(s.exclusion)
+}
+
+// excluded returns the instances still excluded from the task, dropping
+// exclusions that have lapsed. Callers hold s.mu.
+func (s *Scheduler) excluded(task *ScheduledTask) []string {
+	now := s.clock.Now()
+	out := make([]string, 0, len(task.Excluded))
+	for instance, until := range task.Excluded {
+		if !now.Before(until) {
+			delete(task.Excluded, instance)
+			continue
+		}
+		out = append(out, instance)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// rerouteRetry routes a retried task away from the instances that failed
+// it. When no other instance is available the task keeps its agents and
+// waits for one, subject to the no-route grace period. Callers hold s.mu.
+func (s *Scheduler) rerouteRetry(task *ScheduledTask) {
+	exclude := s.excluded(task)
+	if s.reroute == nil || task.Type == "" || len(exclude) == 0 {
+		return
+	}
+
+	agents, err := s.reroute(task.Type, task.Capabilities, exclude)
+	if err != nil {
+		s.logger.Warn("No alternative instance for retry",
+			zap.String("id", task.ID),
+			zap.Strings("excluded", exclude),
+			zap.Error(err),
+		)
+		return
+	}
+	task.Agents = agents
+	s.logger.Info("Rerouted retry",
+		zap.String("id", task.ID),
+		zap.Strings("agents", agents),
+		zap.Strings("excluded", exclude),
+	)
+}
@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+// instancePool reroutes onto whichever agent has an instance not excluded,
+// recording the exclusions it was asked to honour. A task with
+// capabilities goes only to the capable agents.
+type instancePool struct {
+	instances map[string]string // agent -> instance ID
+	capable   []string
+	asked     [][]string
+	caps      []map[string]float64
+}
+
+func (p *instancePool) reroute(taskType string, caps map[string]float64, exclude []string) ([]string, error) {
+	p.asked = append(p.asked, exclude)
+	p.caps = append(p.caps, caps)
+	candidates := []string{"dev", "dev-canary"}
+	if len(caps) > 0 {
+		candidates = p.capable
+	}
+	var agents []string
+	for _, agent := range candidates {
+		if !slices.Contains(exclude, p.instances[agent]) {
+			agents = append(agents, agent)
+		}
+	}
+	if len(agents) == 0 {
+		return nil, errors.New("no available agents")
+	}
+	return agents[:1], nil
+}
+
+func newExcludingScheduler(t *testing.T) (*Scheduler, *ManualClock, *instancePool) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Orchestrator.RetryExclusion = 60
+	s, c := newTestScheduler(t, cfg)
+	pool := &instancePool{instances: map[string]string{"dev": "dev-1", "dev-canary": "dev-canary-1"}}
+	s.SetReroute(pool.reroute)
+	return s, c, pool
+}
+
+// failOn reports the running attempt of a task as failed by an instance
+func failOn(t *testing.T, s *Scheduler, agent, id, instance string, attempt int) {
+	t.Helper()
+	err := s.ReportResult(agent, Result{TaskID: id, Status: ResultFailed, Attempt: attempt, Instance: instance})
+	if err != nil {
+		t.Fatalf("ReportResult: %v", err)
+	}
+}
+
+func TestRetryAvoidsFailedInstance(t *testing.T) {
+	s, _, pool := newExcludingScheduler(t)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "code_write", Agents: []string{"dev"}})
+	s.processQueue()
+
+	failOn(t, s, "dev", "t1", "dev-1", 1)
+	task, ok := queuedTask(s, "t1")
+	if !ok {
+		t.Fatal("failed task not queued for a retry")
+	}
+	if !slices.Equal(task.Agents, []string{"dev-canary"}) {
+		t.Errorf("retry routed to %v, want the other instance's agent", task.Agents)
+	}
+	if len(pool.asked) != 1 || !slices.Equal(pool.asked[0], []string{"dev-1"}) {
+		t.Errorf("reroute asked to exclude %v, want [dev-1]", pool.asked)
+	}
+}
+
+func TestCapabilityRoutedRetryKeepsItsCapabilities(t *testing.T) {
+	s, _, pool := newExcludingScheduler(t)
+	pool.instances["py312"], pool.instances["py313"] = "py312-1", "py313-1"
+	pool.capable = []string{"py312", "py313"}
+	caps := map[string]float64{"python>=3.12": 1}
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "code_write", Agents: []string{"py312"}, Capabilities: caps})
+	s.processQueue()
+
+	failOn(t, s, "py312", "t1", "py312-1", 1)
+	task, ok := queuedTask(s, "t1")
+	if !ok {
+		t.Fatal("failed task not queued for a retry")
+	}
+	if !slices.Equal(task.Agents, []string{"py313"}) {
+		t.Errorf("retry routed to %v, want the other capable agent rather than the type's route", task.Agents)
+	}
+	if len(pool.caps) != 1 || !maps.Equal(pool.caps[0], caps) {
+		t.Errorf("reroute asked with capabilities %v, want %v", pool.caps, caps)
+	}
+}
+
+func TestRetryKeepsAgentsWithoutAlternative(t *testing.T) {
+	s, _, pool := newExcludingScheduler(t)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "code_write", Agents: []string{"dev"}})
+	s.processQueue()
+
+	failOn(t, s, "dev", "t1", "dev-1", 1)
+	s.processQueue()
+	failOn(t, s, "dev-canary", "t1", "dev-canary-1", 2)
+
+	// Both instances are excluded; the task waits on its last agents
+	task, ok := queuedTask(s, "t1")
+	if !ok {
+		t.Fatal("failed task not queued for a retry")
+	}
+	if !slices.Equal(task.Agents, []string{"dev-canary"}) {
+		t.Errorf("retry agents = %v, want them kept", task.Agents)
+	}
+	if got := pool.asked[len(pool.asked)-1]; !slices.Equal(got, []string{"dev-1", "dev-canary-1"}) {
+		t.Errorf("reroute asked to exclude %v, want both instances", got)
+	}
+}
+
+func TestExclusionLapsesAfterCooldown(t *testing.T) {
+	s, c, pool := newExcludingScheduler(t)
+	mustSchedule(t, s, &ScheduledTask{
+		ID:     "t1",
+		Type:   "code_write",
+		Agents: []string{"dev"},
+		Retry:  RetryPolicy{InitialBackoff: 2 * time.Minute},
+	})
+	s.processQueue()
+	failOn(t, s, "dev", "t1", "dev-1", 1)
+
+	// The retry comes due after the exclusion has run out
+	c.Advance(2 * time.Minute)
+	s.processQueue()
+	asked := len(pool.asked)
+	if err := s.ReportResult("dev-canary", Result{TaskID: "t1", Status: ResultFailed, Attempt: 2}); err != nil {
+		t.Fatalf("ReportResult: %v", err)
+	}
+	if len(pool.asked) != asked {
+		t.Errorf("rerouted with %v though every exclusion lapsed", pool.asked[len(pool.asked)-1])
+	}
+	task, ok := queuedTask(s, "t1")
+	if !ok {
+		t.Fatal("failed task not queued for a retry")
+	}
+	if len(task.Excluded) != 0 {
+		t.Errorf("exclusions = %v, want the lapsed one dropped", task.Excluded)
+	}
+}
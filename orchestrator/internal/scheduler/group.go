@@ -0,0 +1,297 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// Group states
+const (
+	GroupRunning   = "running"
+	GroupCompleted = "completed"
+	GroupFailed    = "failed"
+)
+
+// Input keys the scheduler sets on a compensating task
+const (
+	// InputCompensates is the ID of the completed task being undone
+	InputCompensates = "compensates"
+	// InputCompensatedOutput is that task's output
+	InputCompensatedOutput = "compensated_output"
+)
+
+// ErrGroupNotFound is returned for unknown group IDs
+var ErrGroupNotFound = errors.New("group not found")
+
+// GroupMember is one task of an all-or-nothing group
+type GroupMember struct {
+	Task *ScheduledTask
+
+	// Compensate undoes Task's effects. It is scheduled if Task completed
+	// but the group then failed; nil for none.
+	Compensate *ScheduledTask
+}
+
+// Group is a set of tasks that succeed or fail together. When any member
+// fails permanently or is cancelled, the members still queued or running
+// are cancelled and the compensating tasks of the members that completed
+// are scheduled.
+type Group struct {
+	ID      string
+	Name    string
+	Members []GroupMember
+}
+
+// GroupMemberStatus is a point-in-time view of one group member
+type GroupMemberStatus struct {
+	TaskID string    `json:"task_id"`
+	State  TaskState `json:"state"`
+
+	// Compensation is the member's compensating task, once scheduled
+	Compensation *CompensationStatus `json:"compensation,omitempty"`
+}
+
+// CompensationStatus is a point-in-time view of a compensating task
+type CompensationStatus struct {
+	TaskID string    `json:"task_id"`
+	State  TaskState `json:"state"`
+}
+
+// GroupStatus reports overall progress of a group
+type GroupStatus struct {
+	ID        string              `json:"id"`
+	Name      string              `json:"name"`
+	State     string              `json:"state"`
+	Reason    string              `json:"reason,omitempty"` // why the group failed
+	Completed int                 `json:"completed"`
+	Total     int                 `json:"total"`
+	Members   []GroupMemberStatus `json:"members"`
+}
+
+// groupRun tracks a scheduled group
+type groupRun struct {
+	group       *Group
+	state       string
+	reason      string
+	outputs     []map[string]interface{}
+	compensated []bool // compensation scheduled, per member
+}
+
+// memberRef locates a task within a group
+type memberRef struct {
+	run   *groupRun
+	index int
+}
+
+// ScheduleGroup queues every member of an all-or-nothing group. Members
+// may depend on each other. Either every member is queued or none is.
+func (s *Scheduler) ScheduleGroup(g *Group) error {
+	if len(g.Members) == 0 {
+		return fmt.Errorf("group has no tasks")
+	}
+	for i, m := range g.Members {
+		if m.Task == nil {
+			return fmt.Errorf("group member %d has no task", i)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.groups[g.ID]; exists {
+		return fmt.Errorf("group %s already scheduled", g.ID)
+	}
+	tasks := make([]*ScheduledTask, 0, len(g.Members))
+	for _, m := range g.Members {
+		tasks = append(tasks, m.Task)
+	}
+	if err := s.checkPinned(tasks); err != nil {
+		return err
+	}
+	if s.maxQueued > 0 && s.queue.Len()+len(g.Members) > s.maxQueued {
+		return ErrQueueFull
+	}
+	run := &groupRun{
+		group:       g,
+		state:       GroupRunning,
+		outputs:     make([]map[string]interface{}, len(g.Members)),
+		compensated: make([]bool, len(g.Members)),
+	}
+	s.groups[g.ID] = run
+
+	now := s.clock.Now()
+	for i, m := range g.Members {
+		task := m.Task
+		task.ScheduledAt = now
+		task.State = TaskQueued
+		task.submitted = now
+		s.defaultDeadline(task)
+		s.resolvePolicy(task)
+		s.members[task.ID] = memberRef{run: run, index: i}
+		s.enqueue(task)
+		s.emit(events.TaskScheduled, task.ID, "group "+g.ID)
+	}
+
+	s.logger.Debug("Group scheduled",
+		zap.String("id", g.ID),
+		zap.Int("tasks", len(g.Members)),
+	)
+	return nil
+}
+
+// memberFinished completes the group a finished task belongs to once
+// every member has completed, or fails it as soon as one has not. Callers
+// hold s.mu.
+func (s *Scheduler) memberFinished(task *ScheduledTask, output map[string]interface{}, errMsg string) {
+	ref, ok := s.members[task.ID]
+	if !ok || ref.run.state != GroupRunning {
+		return
+	}
+	run := ref.run
+
+	switch task.State {
+	case TaskCompleted:
+		run.outputs[ref.index] = output
+		for _, m := range run.group.Members {
+			if m.Task.State != TaskCompleted {
+				return
+			}
+		}
+		s.finishGroup(run, GroupCompleted)
+
+	case TaskFailed, TaskCancelled:
+		run.reason = fmt.Sprintf("task %s %s", task.ID, task.State)
+		if errMsg != "" {
+			run.reason += ": " + errMsg
+		}
+		s.failGroup(run)
+	}
+}
+
+// failGroup cancels the members still queued or running, then schedules
+// the compensating tasks of the members that completed. Callers hold
+// s.mu.
+func (s *Scheduler) failGroup(run *groupRun) {
+	g := run.group
+	run.state = GroupFailed
+	s.logger.Warn("Group failed, rolling back",
+		zap.String("id", g.ID),
+		zap.String("reason", run.reason),
+	)
+
+	var cancelled []*ScheduledTask
+	for _, m := range g.Members {
+		task := m.Task
+		switch task.State {
+		case TaskRunning:
+			// TODO: Signal cancellation to agent
+			delete(s.running, task.ID)
+			s.currentCount--
+			s.notify()
+		case TaskQueued:
+			if !s.unpark(task) && !s.removeQueued(task) {
+				continue
+			}
+		case TaskSuspended:
+			delete(s.suspended, task.ID)
+		case TaskAwaiting:
+		default:
+			continue
+		}
+		task.State = TaskCancelled
+		s.emit(events.TaskCancelled, task.ID, "group "+g.ID+" failed")
+		cancelled = append(cancelled, task)
+	}
+	// Settled once all are cancelled, so no member fails for another
+	for _, task := range cancelled {
+		s.settle(task)
+	}
+
+	now := s.clock.Now()
+	for i, m := range g.Members {
+		if m.Task.State != TaskCompleted || m.Compensate == nil {
+			continue
+		}
+		comp := m.Compensate
+		setInput(comp, InputCompensates, m.Task.ID)
+		setInput(comp, InputCompensatedOutput, run.outputs[i])
+		comp.ScheduledAt = now
+		comp.State = TaskQueued
+		comp.submitted = now
+		s.defaultDeadline(comp)
+		s.resolvePolicy(comp)
+		// Compensation is never refused for a full queue
+		s.enqueue(comp)
+		run.compensated[i] = true
+		s.emit(events.TaskScheduled, comp.ID, "compensates "+m.Task.ID)
+		s.logger.Info("Compensation scheduled",
+			zap.String("group", g.ID),
+			zap.String("id", comp.ID),
+			zap.String("compensates", m.Task.ID),
+		)
+	}
+
+	s.finishGroup(run, GroupFailed)
+}
+
+func (s *Scheduler) finishGroup(run *groupRun, state string) {
+	run.state = state
+	t := events.GroupCompleted
+	if state != GroupCompleted {
+		t = events.GroupFailed
+	}
+	s.events.Publish(events.Event{Type: t, Message: state, Data: map[string]interface{}{"group_id": run.group.ID}})
+	s.logger.Info("Group finished",
+		zap.String("id", run.group.ID),
+		zap.String("state", state),
+	)
+}
+
+// Group returns the status of a group
+func (s *Scheduler) Group(id string) (GroupStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.groups[id]
+	if !ok {
+		return GroupStatus{}, fmt.Errorf("%w: %s", ErrGroupNotFound, id)
+	}
+	return run.status(), nil
+}
+
+// Groups returns the status of every known group
+func (s *Scheduler) Groups() []GroupStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]GroupStatus, 0, len(s.groups))
+	for _, run := range s.groups {
+		out = append(out, run.status())
+	}
+	return out
+}
+
+func (run *groupRun) status() GroupStatus {
+	g := run.group
+	st := GroupStatus{
+		ID:      g.ID,
+		Name:    g.Name,
+		State:   run.state,
+		Reason:  run.reason,
+		Total:   len(g.Members),
+		Members: make([]GroupMemberStatus, len(g.Members)),
+	}
+	for i, m := range g.Members {
+		if m.Task.State == TaskCompleted {
+			st.Completed++
+		}
+		st.Members[i] = GroupMemberStatus{TaskID: m.Task.ID, State: m.Task.State}
+		if run.compensated[i] {
+			st.Members[i].Compensation = &CompensationStatus{TaskID: m.Compensate.ID, State: m.Compensate.State}
+		}
+	}
+	return st
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+)
+
+// migration is a four-step group; only the first step can be undone
+func migration() *Group {
+	final := RetryPolicy{RetryOn: []string{CategoryTimeout}}
+	return &Group{ID: "g1", Name: "migrate", Members: []GroupMember{
+		{
+			Task:       &ScheduledTask{ID: "schema", Type: "code_write", Retry: final},
+			Compensate: &ScheduledTask{ID: "undo-schema", Type: "code_write"},
+		},
+		{Task: &ScheduledTask{ID: "backfill", Type: "code_write", Retry: final}},
+		{Task: &ScheduledTask{ID: "reindex", Type: "code_write", Retry: final}},
+		{Task: &ScheduledTask{ID: "announce", Type: "question", Retry: final}},
+	}}
+}
+
+func TestFailedMemberCancelsSiblingsAndCompensates(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 3
+	s, _ := newTestScheduler(t, cfg)
+	bus := events.New(100)
+	s.SetEvents(bus)
+	if err := s.ScheduleGroup(migration()); err != nil {
+		t.Fatalf("ScheduleGroup: %v", err)
+	}
+	s.processQueue()
+
+	output := map[string]interface{}{"version": 7.0}
+	s.completeTask("schema", output, nil)
+	s.completeTask("backfill", nil, errors.New("constraint violated"))
+
+	st, err := s.Group("g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reindex was running and announce still queued
+	for _, m := range st.Members[2:] {
+		if m.State != TaskCancelled {
+			t.Errorf("%s is %s, want cancelled", m.TaskID, m.State)
+		}
+	}
+	if _, ok := s.Task("announce"); ok {
+		t.Error("cancelled member still queued")
+	}
+	if got := running(s); got != 0 {
+		t.Errorf("%d slots still held by the group", got)
+	}
+
+	undo, ok := queuedTask(s, "undo-schema")
+	if !ok {
+		t.Fatal("compensation not scheduled for the completed member")
+	}
+	if undo.Input[InputCompensates] != "schema" || !reflect.DeepEqual(undo.Input[InputCompensatedOutput], output) {
+		t.Errorf("compensation input = %v, want the undone task and its output", undo.Input)
+	}
+
+	if st.State != GroupFailed || !strings.HasPrefix(st.Reason, "task backfill failed") {
+		t.Errorf("group = %s (%q), want failed by backfill", st.State, st.Reason)
+	}
+	if c := st.Members[0].Compensation; c == nil || c.TaskID != "undo-schema" {
+		t.Errorf("schema compensation = %+v, want undo-schema", c)
+	}
+	if st.Members[1].Compensation != nil {
+		t.Error("failed member compensated")
+	}
+
+	failed := 0
+	for _, e := range bus.Since(0) {
+		if e.Type == events.GroupFailed && e.Data["group_id"] == "g1" {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("%d group.failed events, want 1", failed)
+	}
+}
+
+func TestGroupCompletesWhenEveryMemberDoes(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	if err := s.ScheduleGroup(migration()); err != nil {
+		t.Fatalf("ScheduleGroup: %v", err)
+	}
+	s.processQueue()
+	for _, id := range []string{"schema", "backfill", "reindex", "announce"} {
+		s.completeTask(id, nil, nil)
+	}
+
+	st, _ := s.Group("g1")
+	if st.State != GroupCompleted || st.Completed != 4 {
+		t.Errorf("group = %+v, want completed", st)
+	}
+	if _, ok := queuedTask(s, "undo-schema"); ok {
+		t.Error("compensation scheduled for a group that succeeded")
+	}
+}
+
+func TestCancellingMemberFailsGroup(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	s, _ := newTestScheduler(t, cfg)
+	if err := s.ScheduleGroup(migration()); err != nil {
+		t.Fatalf("ScheduleGroup: %v", err)
+	}
+	s.processQueue()
+	s.completeTask("schema", nil, nil)
+
+	s.Cancel("announce")
+	st, _ := s.Group("g1")
+	if st.State != GroupFailed {
+		t.Errorf("group is %s after a member was cancelled, want failed", st.State)
+	}
+	if _, ok := queuedTask(s, "undo-schema"); !ok {
+		t.Error("compensation not scheduled")
+	}
+}
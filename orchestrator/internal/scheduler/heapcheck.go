@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// errHeapCorrupt reports a task queue whose heap invariants do not hold
+var errHeapCorrupt = errors.New("task queue corrupt")
+
+// verifyHeap checks the queue's invariants: every task's index matches
+// its position, and no task orders before its parent. Callers hold s.mu.
+func (s *Scheduler) verifyHeap() error {
+	for i, task := range s.queue {
+		if task.index != i {
+			return fmt.Errorf("%w: task %s at position %d has index %d", errHeapCorrupt, task.ID, i, task.index)
+		}
+		if parent := (i - 1) / 2; i > 0 && s.less(task, s.queue[parent]) {
+			return fmt.Errorf("%w: task %s at position %d orders before its parent %s",
+				errHeapCorrupt, task.ID, i, s.queue[parent].ID)
+		}
+	}
+	return nil
+}
+
+// checkHeap verifies the queue when orchestrator.check_invariants is on,
+// rebuilding it if it is corrupt. Callers hold s.mu.
+func (s *Scheduler) checkHeap(after string) {
+	if !s.invariants {
+		return
+	}
+	if err := s.verifyHeap(); err != nil {
+		s.logger.Error("Rebuilding task queue", zap.String("after", after), zap.Error(err))
+		s.repairHeap()
+	}
+}
+
+// repairHeap restores every index from the task's position and re-heapifies.
+// Callers hold s.mu.
+func (s *Scheduler) repairHeap() {
+	for i, task := range s.queue {
+		task.index = i
+	}
+	heap.Init(s.tasks())
+}
+
+// removeQueued takes a task out of the queue by its stored index,
+// reporting whether it was queued. A stale index is never trusted: the
+// task is then looked up by position and the queue repaired, so a bug
+// elsewhere cannot remove the wrong task. Callers hold s.mu.
+func (s *Scheduler) removeQueued(task *ScheduledTask) bool {
+	if i := task.index; i >= 0 && i < s.queue.Len() && s.queue[i] == task {
+		heap.Remove(s.tasks(), i)
+		delete(s.queued, task.ID)
+		s.checkHeap("remove")
+		return true
+	}
+
+	for i, queued := range s.queue {
+		if queued == task {
+			s.logger.Error("Queued task has a stale heap index, repairing",
+				zap.String("id", task.ID),
+				zap.Int("index", task.index),
+				zap.Int("position", i),
+			)
+			s.repairHeap()
+			heap.Remove(s.tasks(), task.index)
+			delete(s.queued, task.ID)
+			return true
+		}
+	}
+	return false
+}
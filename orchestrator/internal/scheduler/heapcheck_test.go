@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// heapOK verifies the queue's invariants under the scheduler's lock
+func heapOK(t *testing.T, s *Scheduler, after string) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.verifyHeap(); err != nil {
+		t.Fatalf("after %s: %v", after, err)
+	}
+}
+
+func TestHeapInvariantsHoldUnderMixedOperations(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 3
+	s, _ := newTestScheduler(t, cfg)
+	rng := rand.New(rand.NewSource(1))
+
+	var ids []string
+	for i := 0; i < 400; i++ {
+		switch op := rng.Intn(10); {
+		case op < 5 || len(ids) == 0:
+			task := &ScheduledTask{ID: fmt.Sprintf("t%d", i), Priority: TaskPriority(rng.Intn(4))}
+			// A dependent boosts a queued prerequisite to its priority
+			if len(ids) > 0 && rng.Intn(3) == 0 {
+				task.Dependencies = []string{ids[rng.Intn(len(ids))]}
+				task.Priority = PriorityCritical
+			}
+			mustSchedule(t, s, task)
+			ids = append(ids, task.ID)
+			heapOK(t, s, "push "+task.ID)
+		case op < 7:
+			id := ids[rng.Intn(len(ids))]
+			s.Cancel(id)
+			heapOK(t, s, "cancel "+id)
+		default:
+			// Finish whatever runs, then pop the next batch
+			for _, id := range runningIDs(s) {
+				s.completeTask(id, nil, nil)
+			}
+			s.processQueue()
+			heapOK(t, s, "pop")
+		}
+	}
+}
+
+func TestStaleIndexDoesNotRemoveWrongTask(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	s, _ := newTestScheduler(t, cfg)
+	mustSchedule(t, s, &ScheduledTask{ID: "blocker"})
+	s.processQueue()
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "a", Priority: PriorityLow},
+		&ScheduledTask{ID: "b", Priority: PriorityNormal},
+		&ScheduledTask{ID: "c", Priority: PriorityHigh},
+	)
+
+	// Corrupt one index so it points at another task's slot
+	s.mu.Lock()
+	a := s.queued["a"]
+	for i, task := range s.queue {
+		if task != a {
+			a.index = i
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if !s.Cancel("a") {
+		t.Fatal("Cancel did not find the task")
+	}
+	for _, id := range []string{"b", "c"} {
+		if got := stateOf(t, s, id); got != TaskQueued {
+			t.Errorf("%s is %s, want untouched by the cancel", id, got)
+		}
+	}
+	heapOK(t, s, "repair")
+}
+
+func TestInvariantCheckRebuildsCorruptQueue(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	cfg.Orchestrator.CheckInvariants = true
+	s, _ := newTestScheduler(t, cfg)
+	mustSchedule(t, s, &ScheduledTask{ID: "blocker"})
+	s.processQueue()
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "low", Priority: PriorityLow},
+		&ScheduledTask{ID: "high", Priority: PriorityHigh},
+	)
+
+	// Break the ordering behind the heap's back
+	s.mu.Lock()
+	s.queue[0], s.queue[1] = s.queue[1], s.queue[0]
+	s.mu.Unlock()
+
+	s.processQueue()
+	heapOK(t, s, "dispatch pass")
+
+	s.completeTask("blocker", nil, nil)
+	s.processQueue()
+	if got := stateOf(t, s, "high"); got != TaskRunning {
+		t.Errorf("high priority task is %s, want dispatched first", got)
+	}
+}
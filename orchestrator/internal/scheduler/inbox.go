@@ -0,0 +1,231 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Inbox keys in Redis
+const (
+	inboxKey  = "odin:inbox"      // hash of unacknowledged results by task ID
+	inboxDead = "odin:inbox:dead" // hash of unreadable results by task ID
+)
+
+// InboxEntry is a task result persisted before it is processed, with
+// enough of the task to finish it on an instance that never ran it
+type InboxEntry struct {
+	Task     LeasedTask             `json:"task"`
+	Output   map[string]interface{} `json:"output,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Category string                 `json:"category,omitempty"`
+	Received time.Time              `json:"received"`
+}
+
+// err rebuilds the task error the result carried
+func (e InboxEntry) err() error {
+	if e.Error == "" {
+		return nil
+	}
+	return &TaskError{Category: e.Category, Message: e.Error}
+}
+
+// ResultInbox durably holds task results between receipt and processing.
+// A result is put before the scheduler acts on it and acked after, so a
+// crash in between leaves it to be processed again on restart.
+type ResultInbox interface {
+	// Put persists a result, replacing any earlier one for the task
+	Put(ctx context.Context, entry InboxEntry) error
+
+	// Ack removes a processed result
+	Ack(ctx context.Context, taskID string) error
+
+	// Pending returns every result not yet acked
+	Pending(ctx context.Context) ([]InboxEntry, error)
+}
+
+// RedisResultInbox keeps results in a Redis hash, shared by every instance
+type RedisResultInbox struct {
+	client *redis.Client
+}
+
+// NewRedisResultInbox creates a Redis-backed result inbox
+func NewRedisResultInbox(client *redis.Client) *RedisResultInbox {
+	return &RedisResultInbox{client: client}
+}
+
+// Put stores the result under its task ID
+func (i *RedisResultInbox) Put(ctx context.Context, entry InboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return i.client.HSet(ctx, inboxKey, entry.Task.ID, data).Err()
+}
+
+// Ack deletes the result for a task
+func (i *RedisResultInbox) Ack(ctx context.Context, taskID string) error {
+	return i.client.HDel(ctx, inboxKey, taskID).Err()
+}
+
+// Pending reads every stored result, moving unreadable ones to the
+// dead-letter hash
+func (i *RedisResultInbox) Pending(ctx context.Context) ([]InboxEntry, error) {
+	raw, err := i.client.HGetAll(ctx, inboxKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result inbox: %w", err)
+	}
+
+	entries := make([]InboxEntry, 0, len(raw))
+	for id, data := range raw {
+		var entry InboxEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil || entry.Task.ID == "" {
+			if err == nil {
+				err = errors.New("missing task")
+			}
+			if qerr := i.quarantine(ctx, id, data, err); qerr != nil {
+				return entries, qerr
+			}
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// quarantine moves an unreadable result out of the inbox so it is not
+// reprocessed on every start
+func (i *RedisResultInbox) quarantine(ctx context.Context, id, raw string, reason error) error {
+	entry, err := json.Marshal(map[string]interface{}{
+		"result": raw,
+		"reason": reason.Error(),
+		"at":     time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = i.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, inboxDead, id, entry)
+		pipe.HDel(ctx, inboxKey, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to quarantine result %s: %w", id, err)
+	}
+	return nil
+}
+
+// SetInbox makes results durable: each is persisted before it is
+// processed and acknowledged after
+func (s *Scheduler) SetInbox(inbox ResultInbox) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inbox = inbox
+}
+
+// deliver completes a dispatch of a running task through the inbox when
+// one is set.
+// Failing to persist the result fails delivery, so the agent can report
+// again; failing to ack only risks processing the result twice. A result
+// whose transition could not be recorded is left unacked, so a restart
+// records it again.
+func (s *Scheduler) deliver(ctx context.Context, taskID string, attempt int, output map[string]interface{}, err error) error {
+	s.mu.Lock()
+	inbox := s.inbox
+	task, running := s.running[taskID]
+	var entry InboxEntry
+	if inbox != nil && running {
+		entry = InboxEntry{Task: leasedTask(task), Output: output, Received: s.clock.Now()}
+		var taskErr *TaskError
+		if errors.As(err, &taskErr) {
+			entry.Error, entry.Category = taskErr.Message, taskErr.Category
+		} else if err != nil {
+			entry.Error = err.Error()
+		}
+	}
+	s.mu.Unlock()
+
+	if inbox == nil || !running {
+		s.recordTransition(ctx, s.completeAttempt(taskID, attempt, output, err))
+		return nil
+	}
+
+	if perr := inbox.Put(ctx, entry); perr != nil {
+		return fmt.Errorf("failed to persist result for %s: %w", taskID, perr)
+	}
+	if s.recordTransition(ctx, s.completeAttempt(taskID, attempt, output, err)) != nil {
+		return nil
+	}
+	if aerr := inbox.Ack(ctx, taskID); aerr != nil {
+		s.logger.Warn("Failed to acknowledge result", zap.String("id", taskID), zap.Error(aerr))
+	}
+	return nil
+}
+
+// RecoverResults processes results left unacknowledged by a crash. Run it
+// at startup, before the scheduler starts: a task this instance no longer
+// knows is rebuilt from the entry and completed with its result. It
+// returns how many results were processed.
+func (s *Scheduler) RecoverResults(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	inbox := s.inbox
+	s.mu.Unlock()
+
+	if inbox == nil {
+		return 0, nil
+	}
+	entries, err := inbox.Pending(ctx)
+	if err != nil && len(entries) == 0 {
+		return 0, err
+	}
+
+	processed := 0
+	for _, entry := range entries {
+		id := entry.Task.ID
+		if s.adopt(entry.Task) {
+			s.logger.Info("Reprocessing unacknowledged result", zap.String("id", id))
+			t := s.completeTask(id, entry.Output, entry.err())
+			processed++
+			if s.recordTransition(ctx, t) != nil {
+				continue // Left for the next restart to record
+			}
+		} else {
+			s.logger.Warn("Dropping unacknowledged result for a task already rescheduled",
+				zap.String("id", id),
+			)
+		}
+		if aerr := inbox.Ack(ctx, id); aerr != nil {
+			s.logger.Warn("Failed to acknowledge result", zap.String("id", id), zap.Error(aerr))
+		}
+	}
+	return processed, err
+}
+
+// adopt installs a task from an inbox entry as running so its result can
+// be processed, unless the task is already known here. The task's ID is
+// remembered so an expired lease on it is not requeued.
+func (s *Scheduler) adopt(lt LeasedTask) bool {
+	if s.known(lt.ID) {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task := fromLeased(lt)
+	task.State = TaskRunning
+	task.index = -1
+	task.started = s.clock.Now()
+	task.acked = true // its result is already in
+	s.running[task.ID] = task
+	s.currentCount++
+	s.recovered[task.ID] = true
+	return true
+}
@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+// memoryInbox is a ResultInbox that outlives the schedulers using it, as
+// Redis would across a restart
+type memoryInbox struct {
+	mu      sync.Mutex
+	entries map[string]InboxEntry
+	putErr  error
+}
+
+func newMemoryInbox() *memoryInbox {
+	return &memoryInbox{entries: make(map[string]InboxEntry)}
+}
+
+func (i *memoryInbox) Put(ctx context.Context, entry InboxEntry) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.putErr != nil {
+		return i.putErr
+	}
+	i.entries[entry.Task.ID] = entry
+	return nil
+}
+
+func (i *memoryInbox) Ack(ctx context.Context, taskID string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.entries, taskID)
+	return nil
+}
+
+func (i *memoryInbox) Pending(ctx context.Context) ([]InboxEntry, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	out := make([]InboxEntry, 0, len(i.entries))
+	for _, entry := range i.entries {
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Task.ID < out[b].Task.ID })
+	return out, nil
+}
+
+// brokenTransitions fails every write, as a database that is down would
+type brokenTransitions struct{}
+
+func (brokenTransitions) RecordTransition(ctx context.Context, t store.Transition) error {
+	return errors.New("connection refused")
+}
+
+func TestUnackedResultReprocessedAfterRestart(t *testing.T) {
+	inbox := newMemoryInbox()
+
+	// The first instance persists the result but crashes, here by losing
+	// its database, before recording it
+	first, _ := newTestScheduler(t, testConfig())
+	first.SetInbox(inbox)
+	first.SetTransitions(brokenTransitions{})
+	mustSchedule(t, first, &ScheduledTask{ID: "t1", Type: "question", Agents: []string{"explain"}})
+	first.processQueue()
+	c := Completion{TaskID: "t1", Attempt: 1, Output: map[string]interface{}{"answer": "42"}}
+	if _, err := first.HandleCompletion(context.Background(), c); err != nil {
+		t.Fatalf("HandleCompletion: %v", err)
+	}
+	if pending, _ := inbox.Pending(context.Background()); len(pending) != 1 {
+		t.Fatalf("inbox holds %d results, want the unrecorded one kept", len(pending))
+	}
+
+	// A fresh instance never saw the task
+	second, _ := newTestScheduler(t, testConfig())
+	second.SetInbox(inbox)
+	transitions := &fakeTransitions{}
+	second.SetTransitions(transitions)
+	n, err := second.RecoverResults(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("RecoverResults = %d, %v; want 1 result reprocessed", n, err)
+	}
+
+	got := transitions.recorded()
+	if len(got) != 1 || got[0].TaskID != "t1" || got[0].Status != store.StatusCompleted || got[0].Result["answer"] != "42" {
+		t.Errorf("transitions = %+v, want t1 completed with its answer", got)
+	}
+	if pending, _ := inbox.Pending(context.Background()); len(pending) != 0 {
+		t.Errorf("inbox still holds %d results after recovery", len(pending))
+	}
+	if got := counts(second, "question"); got.Completed != 1 {
+		t.Errorf("tally = %+v, want the recovered task completed", got)
+	}
+}
+
+func TestProcessedResultIsAcked(t *testing.T) {
+	inbox := newMemoryInbox()
+	s, _ := newTestScheduler(t, testConfig())
+	s.SetInbox(inbox)
+	s.SetTransitions(&fakeTransitions{})
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "question"})
+	s.processQueue()
+
+	if _, err := s.HandleCompletion(context.Background(), Completion{TaskID: "t1", Attempt: 1}); err != nil {
+		t.Fatalf("HandleCompletion: %v", err)
+	}
+	if pending, _ := inbox.Pending(context.Background()); len(pending) != 0 {
+		t.Errorf("inbox holds %d results after processing, want none", len(pending))
+	}
+}
+
+func TestUnpersistedResultIsRefused(t *testing.T) {
+	inbox := newMemoryInbox()
+	inbox.putErr = errors.New("redis down")
+	s, _ := newTestScheduler(t, testConfig())
+	s.SetInbox(inbox)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "question"})
+	s.processQueue()
+
+	if _, err := s.HandleCompletion(context.Background(), Completion{TaskID: "t1", Attempt: 1}); err == nil {
+		t.Fatal("completion accepted without persisting it")
+	}
+	if got := stateOf(t, s, "t1"); got != TaskRunning {
+		t.Errorf("task is %s, want still running for the agent to report again", got)
+	}
+}
+
+func TestRecoveryDropsResultForRescheduledTask(t *testing.T) {
+	inbox := newMemoryInbox()
+	if err := inbox.Put(context.Background(), InboxEntry{Task: LeasedTask{ID: "t1", Type: "question"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := newTestScheduler(t, testConfig())
+	s.SetInbox(inbox)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "question"})
+
+	n, err := s.RecoverResults(context.Background())
+	if err != nil || n != 0 {
+		t.Fatalf("RecoverResults = %d, %v; want nothing reprocessed", n, err)
+	}
+	if got := stateOf(t, s, "t1"); got != TaskQueued {
+		t.Errorf("rescheduled task is %s, want left queued", got)
+	}
+	if pending, _ := inbox.Pending(context.Background()); len(pending) != 0 {
+		t.Errorf("stale result kept in the inbox")
+	}
+}
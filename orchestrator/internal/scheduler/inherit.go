@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"container/heap"
+
+	"go.uber.org/zap"
+)
+
+// Priority inheritance: a task scheduled with unmet dependencies lends its
+// priority to them, so a critical task is not held back by a low-priority
+// prerequisite sitting behind other work. Boosts only ever raise a task's
+// effective priority and are kept if the dependent is later cancelled.
+// Methods here expect s.mu held.
+
+// EffectivePriority is the task's own priority or the highest priority
+// inherited from a dependent, whichever is greater
+func (t *ScheduledTask) EffectivePriority() TaskPriority {
+	if t.inherited > t.Priority {
+		return t.inherited
+	}
+	return t.Priority
+}
+
+// inherit raises the pending dependencies of task, and their own pending
+// dependencies in turn, to the task's effective priority
+func (s *Scheduler) inherit(task *ScheduledTask) {
+	priority := task.EffectivePriority()
+	seen := map[string]bool{task.ID: true}
+
+	var raise func(deps []string)
+	raise = func(deps []string) {
+		for _, id := range deps {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			dep := s.pending(id)
+			if dep == nil || dep.EffectivePriority() >= priority {
+				continue
+			}
+			dep.inherited = priority
+			if dep.index >= 0 && dep.index < s.queue.Len() && s.queue[dep.index] == dep {
+				heap.Fix(s.tasks(), dep.index)
+			}
+			s.logger.Debug("Priority inherited",
+				zap.String("id", dep.ID),
+				zap.String("from", task.ID),
+				zap.Int("priority", int(priority)),
+			)
+			raise(dep.Dependencies)
+		}
+	}
+	raise(task.Dependencies)
+}
+
+// pending returns a queued or waiting task by ID
+func (s *Scheduler) pending(id string) *ScheduledTask {
+	if task, ok := s.waiting[id]; ok {
+		return task
+	}
+	return s.queued[id]
+}
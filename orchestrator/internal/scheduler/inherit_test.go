@@ -0,0 +1,75 @@
+package scheduler
+
+import "testing"
+
+// effective returns a pending task's effective priority
+func effective(t *testing.T, s *Scheduler, id string) TaskPriority {
+	t.Helper()
+	task, ok := s.Task(id)
+	if !ok {
+		t.Fatalf("task %s not found", id)
+	}
+	return task.EffectivePriority
+}
+
+func TestCriticalDependentRaisesPendingDependency(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	s, _ := newTestScheduler(t, cfg)
+
+	mustSchedule(t, s, &ScheduledTask{ID: "blocker"})
+	s.processQueue()
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "prereq", Priority: PriorityLow},
+		&ScheduledTask{ID: "busywork", Priority: PriorityNormal},
+	)
+	if got := effective(t, s, "prereq"); got != PriorityLow {
+		t.Fatalf("effective priority before = %d, want low", got)
+	}
+
+	mustSchedule(t, s, &ScheduledTask{ID: "urgent", Priority: PriorityCritical, Dependencies: []string{"prereq"}})
+	if got := effective(t, s, "prereq"); got != PriorityCritical {
+		t.Errorf("effective priority = %d, want critical inherited", got)
+	}
+	if got := effective(t, s, "busywork"); got != PriorityNormal {
+		t.Errorf("unrelated task raised to %d", got)
+	}
+
+	// The raised prerequisite goes before the normal-priority work
+	s.completeTask("blocker", nil, nil)
+	s.processQueue()
+	if got := stateOf(t, s, "prereq"); got != TaskRunning {
+		t.Errorf("prerequisite is %s, want dispatched first", got)
+	}
+}
+
+func TestInheritanceIsTransitiveAndOnlyRaises(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	s, _ := newTestScheduler(t, cfg)
+
+	mustSchedule(t, s, &ScheduledTask{ID: "blocker"})
+	s.processQueue()
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "root", Priority: PriorityLow},
+		&ScheduledTask{ID: "mid", Priority: PriorityLow, Dependencies: []string{"root"}},
+		&ScheduledTask{ID: "high", Priority: PriorityHigh},
+		&ScheduledTask{ID: "top", Priority: PriorityNormal, Dependencies: []string{"mid", "high"}},
+	)
+
+	if got := effective(t, s, "root"); got != PriorityNormal {
+		t.Errorf("root = %d, want normal inherited through mid", got)
+	}
+	if got := effective(t, s, "mid"); got != PriorityNormal {
+		t.Errorf("mid = %d, want normal", got)
+	}
+	if got := effective(t, s, "high"); got != PriorityHigh {
+		t.Errorf("high = %d, want it not lowered", got)
+	}
+
+	// Cancelling the dependent keeps the boost
+	s.Cancel("top")
+	if got := effective(t, s, "root"); got != PriorityNormal {
+		t.Errorf("root after cancel = %d, want the boost kept", got)
+	}
+}
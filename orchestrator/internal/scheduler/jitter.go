@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"math/rand"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Retry jitter strategies, chosen by orchestrator.retry_jitter
+const (
+	JitterAdditive     = "additive"     // the backoff plus up to 25%
+	JitterFull         = "full"         // anywhere up to the backoff
+	JitterEqual        = "equal"        // half the backoff plus up to the other half
+	JitterDecorrelated = "decorrelated" // up to 3x the previous delay, capped by the policy
+	JitterNone         = "none"         // exactly the backoff
+)
+
+// jitterFunc computes a retry delay from the policy's backoff for the
+// retry and the task's previous retry delay (0 before its first retry).
+// It draws only from rng, so a seeded source gives repeatable delays.
+type jitterFunc func(rng *rand.Rand, policy RetryPolicy, backoff, prev time.Duration) time.Duration
+
+var jitterStrategies = map[string]jitterFunc{
+	JitterAdditive:     additiveJitter,
+	JitterFull:         fullJitter,
+	JitterEqual:        equalJitter,
+	JitterDecorrelated: decorrelatedJitter,
+	JitterNone:         noJitter,
+}
+
+// jitterStrategy looks up a strategy by name, falling back to additive
+func jitterStrategy(name string, logger *zap.Logger) jitterFunc {
+	if name == "" {
+		return additiveJitter
+	}
+	fn, ok := jitterStrategies[name]
+	if !ok {
+		logger.Warn("Unknown retry jitter, using additive", zap.String("jitter", name))
+		return additiveJitter
+	}
+	return fn
+}
+
+// between returns a uniformly random duration in [lo, hi]
+func between(rng *rand.Rand, lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(rng.Int63n(int64(hi-lo)+1))
+}
+
+func additiveJitter(rng *rand.Rand, _ RetryPolicy, backoff, _ time.Duration) time.Duration {
+	return between(rng, backoff, backoff+backoff/4)
+}
+
+func fullJitter(rng *rand.Rand, _ RetryPolicy, backoff, _ time.Duration) time.Duration {
+	return between(rng, 0, backoff)
+}
+
+func equalJitter(rng *rand.Rand, _ RetryPolicy, backoff, _ time.Duration) time.Duration {
+	return between(rng, backoff/2, backoff)
+}
+
+// decorrelatedJitter grows from the task's previous delay rather than the
+// retry count, between the initial backoff and the maximum
+func decorrelatedJitter(rng *rand.Rand, policy RetryPolicy, _, prev time.Duration) time.Duration {
+	r := policy.resolved()
+	return min(between(rng, r.InitialBackoff, max(prev*3, r.InitialBackoff)), r.MaxBackoff)
+}
+
+func noJitter(_ *rand.Rand, _ RetryPolicy, backoff, _ time.Duration) time.Duration {
+	return backoff
+}
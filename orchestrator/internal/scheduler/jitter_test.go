@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+// delays runs a strategy over six retries with a seeded source, checking
+// each delay against the bounds for its backoff and previous delay
+func delays(t *testing.T, name string, seed int64, lo, hi func(backoff, prev time.Duration) time.Duration) []time.Duration {
+	t.Helper()
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 30 * time.Second}
+	rng := rand.New(rand.NewSource(seed))
+	fn := jitterStrategies[name]
+
+	var prev time.Duration
+	var out []time.Duration
+	for retry := 1; retry <= 6; retry++ {
+		backoff := policy.backoff(retry)
+		delay := fn(rng, policy, backoff, prev)
+		if delay < lo(backoff, prev) || delay > hi(backoff, prev) {
+			t.Errorf("%s retry %d: delay %v outside [%v, %v]", name, retry, delay, lo(backoff, prev), hi(backoff, prev))
+		}
+		out = append(out, delay)
+		prev = delay
+	}
+	return out
+}
+
+func TestJitterStrategyBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		lo, hi func(backoff, prev time.Duration) time.Duration
+	}{
+		{JitterAdditive,
+			func(b, _ time.Duration) time.Duration { return b },
+			func(b, _ time.Duration) time.Duration { return b + b/4 }},
+		{JitterFull,
+			func(_, _ time.Duration) time.Duration { return 0 },
+			func(b, _ time.Duration) time.Duration { return b }},
+		{JitterEqual,
+			func(b, _ time.Duration) time.Duration { return b / 2 },
+			func(b, _ time.Duration) time.Duration { return b }},
+		{JitterDecorrelated,
+			func(_, _ time.Duration) time.Duration { return time.Second },
+			func(_, prev time.Duration) time.Duration { return min(max(prev*3, time.Second), 30*time.Second) }},
+		{JitterNone,
+			func(b, _ time.Duration) time.Duration { return b },
+			func(b, _ time.Duration) time.Duration { return b }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := delays(t, tt.name, 42, tt.lo, tt.hi)
+			if again := delays(t, tt.name, 42, tt.lo, tt.hi); !slices.Equal(first, again) {
+				t.Errorf("same seed gave %v then %v", first, again)
+			}
+		})
+	}
+}
+
+func TestSchedulerAppliesConfiguredJitter(t *testing.T) {
+	tests := []struct {
+		jitter string
+		lo, hi time.Duration
+	}{
+		{JitterNone, time.Second, time.Second},
+		{JitterFull, 0, time.Second},
+		{"unknown", time.Second, time.Second + time.Second/4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.jitter, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Orchestrator.RetryJitter = tt.jitter
+			s, c := newTestScheduler(t, cfg)
+			s.SetRand(rand.New(rand.NewSource(7)))
+
+			mustSchedule(t, s, &ScheduledTask{ID: "flaky", Retry: RetryPolicy{InitialBackoff: time.Second}})
+			s.processQueue()
+			s.completeTask("flaky", nil, errors.New("boom"))
+
+			task, ok := s.Task("flaky")
+			if !ok {
+				t.Fatal("retried task not found")
+			}
+			if delay := task.ScheduledAt.Sub(c.Now()); delay < tt.lo || delay > tt.hi {
+				t.Errorf("retry delayed %v, want within [%v, %v]", delay, tt.lo, tt.hi)
+			}
+		})
+	}
+}
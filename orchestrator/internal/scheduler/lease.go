@@ -0,0 +1,429 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/schema"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Lease keys in Redis
+const (
+	leasePrefix = "odin:lease:"
+	leaseIndex  = "odin:leases"      // sorted set of task IDs by lease expiry
+	leaseDead   = "odin:leases:dead" // hash of unreadable tasks by ID
+)
+
+// LeasedTask is what a lease carries so another instance can requeue
+// the task after its owner dies
+type LeasedTask struct {
+	Version      int                    `json:"v"`
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name,omitempty"`
+	Type         string                 `json:"type,omitempty"`
+	Priority     TaskPriority           `json:"priority"`
+	Retries      int                    `json:"retries"`
+	Retry        RetryPolicy            `json:"retry"`
+	Dependencies []string               `json:"dependencies,omitempty"`
+	Input        map[string]interface{} `json:"input,omitempty"`
+	Deadline     time.Time              `json:"deadline"`
+	Agents       []string               `json:"agents,omitempty"`
+	Traced       bool                   `json:"traced,omitempty"`
+	Excluded     map[string]time.Time   `json:"excluded,omitempty"`
+	Timeout      time.Duration          `json:"timeout,omitempty"`
+
+	// Optional, so leases written before they existed still decode
+	MaxQueueTime time.Duration          `json:"max_queue_time,omitempty"`
+	Settings     map[string]interface{} `json:"settings,omitempty"`
+	ResultSchema *schema.Schema         `json:"result_schema,omitempty"`
+	Capabilities map[string]float64     `json:"capabilities,omitempty"`
+
+	// Suspended marks a task awaiting approval, which is taken over still
+	// suspended, its SuspendTimeout (0 = forever) starting over
+	Suspended      bool          `json:"suspended,omitempty"`
+	SuspendTimeout time.Duration `json:"suspend_timeout,omitempty"`
+
+	// PinnedInstance is also kept beside the lease, where reclaim checks it
+	PinnedInstance string `json:"pinned_instance,omitempty"`
+
+	// Payload is the task as submitted, which its agents are sent
+	Payload json.RawMessage `json:"payload,omitempty"`
+
+	// Attempt is the task's latest dispatch, so the next one is numbered
+	// past any result still on its way
+	Attempt int `json:"attempt,omitempty"`
+}
+
+// LeaseStore records which instance owns each in-flight task
+type LeaseStore interface {
+	// Hold takes or extends owner's lease on each task. Tasks leased to a
+	// live owner other than this one are skipped.
+	Hold(ctx context.Context, owner string, tasks []LeasedTask, ttl time.Duration) error
+
+	// Release drops owner's lease on a task, unless it has since been
+	// taken for a later attempt than the one given
+	Release(ctx context.Context, owner, taskID string, attempt int) error
+
+	// Reclaim takes over tasks whose lease has expired and returns them.
+	// A task pinned to another instance is only taken once its lease has
+	// been expired for unpinAfter (0 = never).
+	Reclaim(ctx context.Context, owner string, ttl, unpinAfter time.Duration) ([]LeasedTask, error)
+}
+
+// holdScript takes or extends a lease unless a different owner's lease is live
+var holdScript = redis.NewScript(`
+local owner = redis.call('HGET', KEYS[1], 'owner')
+local expiry = tonumber(redis.call('ZSCORE', KEYS[2], ARGV[1]) or '0')
+if owner and owner ~= ARGV[2] and expiry > tonumber(ARGV[3]) then
+  return 0
+end
+redis.call('HSET', KEYS[1], 'owner', ARGV[2], 'task', ARGV[4], 'pinned', ARGV[6], 'attempt', ARGV[7])
+redis.call('ZADD', KEYS[2], ARGV[5], ARGV[1])
+return 1
+`)
+
+// releaseScript drops a lease only if the caller still owns it for the
+// given attempt or an earlier one
+var releaseScript = redis.NewScript(`
+local attempt = tonumber(redis.call('HGET', KEYS[1], 'attempt') or '0')
+if redis.call('HGET', KEYS[1], 'owner') == ARGV[2] and attempt <= tonumber(ARGV[3]) then
+  redis.call('DEL', KEYS[1])
+  redis.call('ZREM', KEYS[2], ARGV[1])
+  return 1
+end
+return 0
+`)
+
+// reclaimScript transfers an expired lease to a new owner and returns
+// the task, or nil if someone renewed or reclaimed it first, or it is
+// pinned to another instance that has not been gone for long enough
+var reclaimScript = redis.NewScript(`
+local expiry = tonumber(redis.call('ZSCORE', KEYS[2], ARGV[1]) or '0')
+if expiry == 0 or expiry > tonumber(ARGV[3]) then
+  return false
+end
+local pinned = redis.call('HGET', KEYS[1], 'pinned')
+if pinned and pinned ~= '' and pinned ~= ARGV[2] then
+  local unpin = tonumber(ARGV[5])
+  if unpin == 0 or expiry > tonumber(ARGV[3]) - unpin then
+    return false
+  end
+end
+local task = redis.call('HGET', KEYS[1], 'task')
+if not task then
+  redis.call('ZREM', KEYS[2], ARGV[1])
+  return false
+end
+redis.call('HSET', KEYS[1], 'owner', ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[4], ARGV[1])
+return task
+`)
+
+// RedisLeaseStore keeps leases in Redis, shared by every instance
+type RedisLeaseStore struct {
+	client *redis.Client
+}
+
+// NewRedisLeaseStore creates a Redis-backed lease store
+func NewRedisLeaseStore(client *redis.Client) *RedisLeaseStore {
+	return &RedisLeaseStore{client: client}
+}
+
+// Hold takes or extends leases one task at a time
+func (l *RedisLeaseStore) Hold(ctx context.Context, owner string, tasks []LeasedTask, ttl time.Duration) error {
+	now := time.Now()
+	expiry := now.Add(ttl).UnixMilli()
+
+	for _, task := range tasks {
+		data, err := encodeLeasedTask(task)
+		if err != nil {
+			return err
+		}
+		keys := []string{leasePrefix + task.ID, leaseIndex}
+		args := []interface{}{task.ID, owner, now.UnixMilli(), data, expiry, task.PinnedInstance, task.Attempt}
+		if err := holdScript.Run(ctx, l.client, keys, args...).Err(); err != nil {
+			return fmt.Errorf("failed to hold lease on %s: %w", task.ID, err)
+		}
+	}
+	return nil
+}
+
+// Release drops owner's lease on a task held for attempt or earlier
+func (l *RedisLeaseStore) Release(ctx context.Context, owner, taskID string, attempt int) error {
+	keys := []string{leasePrefix + taskID, leaseIndex}
+	return releaseScript.Run(ctx, l.client, keys, taskID, owner, attempt).Err()
+}
+
+// Reclaim takes over up to 100 expired leases per call
+func (l *RedisLeaseStore) Reclaim(ctx context.Context, owner string, ttl, unpinAfter time.Duration) ([]LeasedTask, error) {
+	now := time.Now()
+	ids, err := l.client.ZRangeByScore(ctx, leaseIndex, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: 100,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired leases: %w", err)
+	}
+
+	expiry := now.Add(ttl).UnixMilli()
+	reclaimed := make([]LeasedTask, 0, len(ids))
+	for _, id := range ids {
+		keys := []string{leasePrefix + id, leaseIndex}
+		raw, err := reclaimScript.Run(ctx, l.client, keys, id, owner, now.UnixMilli(), expiry, unpinAfter.Milliseconds()).Text()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return reclaimed, fmt.Errorf("failed to reclaim %s: %w", id, err)
+		}
+
+		task, err := decodeLeasedTask([]byte(raw))
+		if err != nil {
+			if qerr := l.quarantine(ctx, id, raw, err); qerr != nil {
+				return reclaimed, qerr
+			}
+			continue
+		}
+		reclaimed = append(reclaimed, task)
+	}
+	return reclaimed, nil
+}
+
+// quarantine moves an unreadable lease to the dead-letter hash so it is
+// neither retried forever nor lost
+func (l *RedisLeaseStore) quarantine(ctx context.Context, id, raw string, reason error) error {
+	entry, err := json.Marshal(map[string]interface{}{
+		"task":   raw,
+		"reason": reason.Error(),
+		"at":     time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, leaseDead, id, entry)
+		pipe.Del(ctx, leasePrefix+id)
+		pipe.ZRem(ctx, leaseIndex, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to quarantine lease %s: %w", id, err)
+	}
+	return nil
+}
+
+// SetLeases enables task leases under the given instance owner ID
+func (s *Scheduler) SetLeases(store LeaseStore, owner string, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leases = store
+	s.owner = owner
+	s.leaseTTL = ttl
+}
+
+func leasedTask(task *ScheduledTask) LeasedTask {
+	lt := LeasedTask{
+		ID:           task.ID,
+		Name:         task.Name,
+		Type:         task.Type,
+		Priority:     task.Priority,
+		Retries:      task.Retries,
+		Retry:        task.Retry,
+		Dependencies: task.Dependencies,
+		Input:        task.Input,
+		Deadline:     task.Deadline,
+		Agents:       task.Agents,
+		Traced:       task.Traced,
+		Excluded:     maps.Clone(task.Excluded),
+		Timeout:      task.Timeout,
+		MaxQueueTime: task.MaxQueueTime,
+		Settings:     task.Settings,
+		ResultSchema: task.ResultSchema,
+		Capabilities: task.Capabilities,
+
+		PinnedInstance: task.PinnedInstance,
+		Payload:        task.Payload,
+		Attempt:        task.attempt,
+	}
+	if task.State == TaskSuspended {
+		lt.Suspended = true
+		lt.SuspendTimeout = task.suspendTimeout
+	}
+	return lt
+}
+
+// fromLeased rebuilds a task from what its lease carried
+func fromLeased(lt LeasedTask) *ScheduledTask {
+	return &ScheduledTask{
+		ID:           lt.ID,
+		Name:         lt.Name,
+		Type:         lt.Type,
+		Priority:     lt.Priority,
+		Retries:      lt.Retries,
+		Retry:        lt.Retry,
+		Dependencies: lt.Dependencies,
+		Input:        lt.Input,
+		Deadline:     lt.Deadline,
+		Agents:       lt.Agents,
+		Traced:       lt.Traced,
+		Excluded:     lt.Excluded,
+		Timeout:      lt.Timeout,
+		MaxQueueTime: lt.MaxQueueTime,
+		Settings:     lt.Settings,
+		ResultSchema: lt.ResultSchema,
+		Capabilities: lt.Capabilities,
+
+		PinnedInstance: lt.PinnedInstance,
+		Payload:        lt.Payload,
+		attempt:        lt.Attempt,
+		suspendTimeout: lt.SuspendTimeout,
+	}
+}
+
+// leaseLoop renews leases on running tasks and requeues tasks whose owner
+// stopped renewing
+func (s *Scheduler) leaseLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.leaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.renewLeases(ctx)
+			s.reclaimLeases(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) renewLeases(ctx context.Context) {
+	s.mu.Lock()
+	held := make([]LeasedTask, 0, len(s.running)+len(s.suspended))
+	for _, task := range s.running {
+		held = append(held, leasedTask(task))
+	}
+	for _, task := range s.suspended {
+		held = append(held, leasedTask(task))
+	}
+	s.mu.Unlock()
+
+	if len(held) == 0 {
+		return
+	}
+	if err := s.leases.Hold(ctx, s.owner, held, s.leaseTTL); err != nil {
+		s.logger.Warn("Lease renewal failed", zap.Error(err))
+	}
+}
+
+func (s *Scheduler) reclaimLeases(ctx context.Context) {
+	unpinAfter := time.Duration(s.config.Orchestrator.Leases.UnpinAfter) * time.Second
+	reclaimed, err := s.leases.Reclaim(ctx, s.owner, s.leaseTTL, unpinAfter)
+	if err != nil {
+		s.logger.Warn("Lease reclaim failed", zap.Error(err))
+	}
+
+	for _, lt := range reclaimed {
+		if s.known(lt.ID) {
+			continue
+		}
+		if s.wasRecovered(lt.ID) {
+			// Its result was already processed from the inbox
+			go s.releaseLease(lt.ID, lt.Attempt)
+			continue
+		}
+		task := fromLeased(lt)
+		if task.PinnedInstance != "" && task.PinnedInstance != s.owner {
+			// Its instance has been gone longer than unpin_after
+			s.logger.Warn("Unpinned task from an instance that is gone",
+				zap.String("id", lt.ID),
+				zap.String("instance", task.PinnedInstance),
+			)
+			task.PinnedInstance = ""
+		}
+		if lt.Suspended {
+			s.mu.Lock()
+			s.resuspend(task)
+			s.mu.Unlock()
+			s.logger.Info("Reclaimed suspended task from expired lease", zap.String("id", lt.ID))
+			continue
+		}
+		if err := s.Schedule(task); err != nil {
+			s.logger.Warn("Could not requeue reclaimed task",
+				zap.String("id", lt.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		s.logger.Info("Reclaimed task from expired lease", zap.String("id", lt.ID))
+	}
+}
+
+// known reports whether the task is already queued, waiting, running or
+// suspended here
+func (s *Scheduler) known(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.running[id]; ok {
+		return true
+	}
+	if _, ok := s.suspended[id]; ok {
+		return true
+	}
+	if _, ok := s.waiting[id]; ok {
+		return true
+	}
+	if _, ok := s.queued[id]; ok {
+		return true
+	}
+	res, _ := s.reserved(id)
+	return res != nil
+}
+
+// wasRecovered reports whether the task was finished from the result inbox
+func (s *Scheduler) wasRecovered(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.recovered[id]
+}
+
+// holdLease takes the lease on a task as it starts running
+func (s *Scheduler) holdLease(task LeasedTask) {
+	s.mu.Lock()
+	leases, owner, ttl := s.leases, s.owner, s.leaseTTL
+	s.mu.Unlock()
+
+	if leases == nil {
+		return
+	}
+	if err := leases.Hold(context.Background(), owner, []LeasedTask{task}, ttl); err != nil {
+		s.logger.Warn("Failed to take task lease", zap.String("id", task.ID), zap.Error(err))
+	}
+}
+
+// releaseLease drops the lease on a task whose given attempt finished.
+// Run in the background, it may land after the task has been dispatched
+// again, whose lease it then leaves alone.
+func (s *Scheduler) releaseLease(taskID string, attempt int) {
+	s.mu.Lock()
+	leases, owner := s.leases, s.owner
+	s.mu.Unlock()
+
+	if leases == nil {
+		return
+	}
+	if err := leases.Release(context.Background(), owner, taskID, attempt); err != nil {
+		s.logger.Warn("Failed to release task lease", zap.String("id", taskID), zap.Error(err))
+	}
+}
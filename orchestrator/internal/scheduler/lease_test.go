@@ -0,0 +1,320 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeLeases is an in-memory LeaseStore shared by the schedulers of a
+// test, expiring leases on a manual clock
+type fakeLeases struct {
+	clock *ManualClock
+
+	mu     sync.Mutex
+	leases map[string]*fakeLease
+}
+
+type fakeLease struct {
+	owner  string
+	task   LeasedTask
+	expiry time.Time
+}
+
+func newFakeLeases(c *ManualClock) *fakeLeases {
+	return &fakeLeases{clock: c, leases: make(map[string]*fakeLease)}
+}
+
+func (f *fakeLeases) Hold(ctx context.Context, owner string, tasks []LeasedTask, ttl time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.clock.Now()
+	for _, task := range tasks {
+		if l, ok := f.leases[task.ID]; ok && l.owner != owner && l.expiry.After(now) {
+			continue
+		}
+		f.leases[task.ID] = &fakeLease{owner: owner, task: task, expiry: now.Add(ttl)}
+	}
+	return nil
+}
+
+func (f *fakeLeases) Release(ctx context.Context, owner, taskID string, attempt int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if l, ok := f.leases[taskID]; ok && l.owner == owner && l.task.Attempt <= attempt {
+		delete(f.leases, taskID)
+	}
+	return nil
+}
+
+func (f *fakeLeases) Reclaim(ctx context.Context, owner string, ttl, unpinAfter time.Duration) ([]LeasedTask, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.clock.Now()
+	var out []LeasedTask
+	for _, l := range f.leases {
+		if l.expiry.After(now) {
+			continue
+		}
+		if pinned := l.task.PinnedInstance; pinned != "" && pinned != owner {
+			if unpinAfter == 0 || l.expiry.After(now.Add(-unpinAfter)) {
+				continue
+			}
+		}
+		l.owner = owner
+		l.expiry = now.Add(ttl)
+		out = append(out, l.task)
+	}
+	return out, nil
+}
+
+// owner returns who holds the lease on a task, if anyone
+func (f *fakeLeases) owner(id string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if l, ok := f.leases[id]; ok {
+		return l.owner
+	}
+	return ""
+}
+
+// suspended reports whether a task's lease records it as suspended
+func (f *fakeLeases) suspended(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	l, ok := f.leases[id]
+	return ok && l.task.Suspended
+}
+
+// queuedTask returns a task sitting in the queue
+func queuedTask(s *Scheduler, id string) (*ScheduledTask, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.queued[id]
+	return task, ok
+}
+
+// newLeasedScheduler creates a scheduler leasing its tasks as owner
+func newLeasedScheduler(t *testing.T, leases *fakeLeases, owner string) *Scheduler {
+	t.Helper()
+	s, _ := newTestScheduler(t, testConfig())
+	s.SetClock(leases.clock)
+	s.SetLeases(leases, owner, 30*time.Second)
+	return s
+}
+
+func TestDeadOwnersTaskIsReclaimedAndRequeued(t *testing.T) {
+	c := NewManualClock(epoch)
+	leases := newFakeLeases(c)
+	dead := newLeasedScheduler(t, leases, "one")
+	survivor := newLeasedScheduler(t, leases, "two")
+
+	mustSchedule(t, dead, &ScheduledTask{ID: "stranded", Type: "review", Input: map[string]interface{}{"pr": "7"}})
+	dead.processQueue()
+	waitUntil(t, "the lease to be taken", func() bool { return leases.owner("stranded") == "one" })
+
+	// While the owner's lease is live, nobody else may take the task
+	survivor.reclaimLeases(context.Background())
+	if _, ok := survivor.Task("stranded"); ok {
+		t.Fatal("a live lease was reclaimed")
+	}
+
+	// The owner dies and stops renewing
+	c.Advance(31 * time.Second)
+	survivor.reclaimLeases(context.Background())
+
+	task, ok := queuedTask(survivor, "stranded")
+	if !ok {
+		t.Fatal("reclaimed task was not requeued")
+	}
+	if task.Type != "review" || task.Input["pr"] != "7" || task.attempt != 1 {
+		t.Errorf("reclaimed task = %+v, want it as leased after its first dispatch", task)
+	}
+	if got := leases.owner("stranded"); got != "two" {
+		t.Errorf("lease owner = %q, want the survivor", got)
+	}
+}
+
+func TestLateReleaseKeepsRetryLease(t *testing.T) {
+	leases := newFakeLeases(NewManualClock(epoch))
+	s := newLeasedScheduler(t, leases, "one")
+	mustSchedule(t, s, &ScheduledTask{ID: "flaky", Retry: Retries(1)})
+	s.processQueue()
+	waitUntil(t, "the lease to be taken", func() bool { return leases.owner("flaky") == "one" })
+
+	if err := s.ReportResult("", Result{TaskID: "flaky", Status: ResultFailed, Attempt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	s.processQueue()
+	waitUntil(t, "the retry's lease", func() bool {
+		leases.mu.Lock()
+		defer leases.mu.Unlock()
+		l, ok := leases.leases["flaky"]
+		return ok && l.task.Attempt == 2
+	})
+
+	// The first attempt's release arrives after the retry took the lease
+	s.releaseLease("flaky", 1)
+	if got := leases.owner("flaky"); got != "one" {
+		t.Errorf("lease owner = %q after a late release, want the retry's lease kept", got)
+	}
+}
+
+func TestPinnedTaskWaitsForUnpinAfter(t *testing.T) {
+	c := NewManualClock(epoch)
+	leases := newFakeLeases(c)
+	leases.Hold(context.Background(), "one", []LeasedTask{{ID: "pinned", PinnedInstance: "one"}}, 30*time.Second)
+
+	survivor := newLeasedScheduler(t, leases, "two")
+	survivor.config.Orchestrator.Leases.UnpinAfter = 60
+
+	c.Advance(time.Minute)
+	survivor.reclaimLeases(context.Background())
+	if _, ok := survivor.Task("pinned"); ok {
+		t.Fatal("a pinned task was reclaimed before unpin_after")
+	}
+
+	c.Advance(time.Minute)
+	survivor.reclaimLeases(context.Background())
+	task, ok := queuedTask(survivor, "pinned")
+	if !ok {
+		t.Fatal("pinned task was not reclaimed after unpin_after")
+	}
+	if task.PinnedInstance != "" {
+		t.Errorf("reclaimed task still pinned to %q", task.PinnedInstance)
+	}
+}
+
+// newRedisLeases returns a Redis lease store over miniredis, and a client
+// for inspecting what it wrote
+func newRedisLeases(t *testing.T) (*RedisLeaseStore, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisLeaseStore(client), client
+}
+
+// leaseOwner returns who holds the Redis lease on a task, "" for nobody
+func leaseOwner(t *testing.T, client *redis.Client, id string) string {
+	t.Helper()
+	owner, err := client.HGet(context.Background(), leasePrefix+id, "owner").Result()
+	if err == redis.Nil {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return owner
+}
+
+func TestRedisLeaseHoldAndRelease(t *testing.T) {
+	ctx := context.Background()
+	leases, client := newRedisLeases(t)
+	task := LeasedTask{Version: LeaseFormatVersion, ID: "t1", Type: "review", Attempt: 1}
+
+	if err := leases.Hold(ctx, "one", []LeasedTask{task}, 30*time.Second); err != nil {
+		t.Fatalf("Hold: %v", err)
+	}
+	// A live lease is not taken over or released by another owner
+	if err := leases.Hold(ctx, "two", []LeasedTask{task}, 30*time.Second); err != nil {
+		t.Fatalf("Hold by another owner: %v", err)
+	}
+	if err := leases.Release(ctx, "two", "t1", 1); err != nil {
+		t.Fatalf("Release by another owner: %v", err)
+	}
+	if got := leaseOwner(t, client, "t1"); got != "one" {
+		t.Fatalf("owner = %q, want one", got)
+	}
+
+	// The retry is dispatched before the first attempt's release lands
+	task.Attempt = 2
+	if err := leases.Hold(ctx, "one", []LeasedTask{task}, 30*time.Second); err != nil {
+		t.Fatalf("Hold for the retry: %v", err)
+	}
+	if err := leases.Release(ctx, "one", "t1", 1); err != nil {
+		t.Fatalf("late Release: %v", err)
+	}
+	if got := leaseOwner(t, client, "t1"); got != "one" {
+		t.Fatalf("a late release dropped the retry's lease; owner = %q", got)
+	}
+
+	if err := leases.Release(ctx, "one", "t1", 2); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if got := leaseOwner(t, client, "t1"); got != "" {
+		t.Errorf("owner = %q after release, want none", got)
+	}
+	if n := client.ZCard(ctx, leaseIndex).Val(); n != 0 {
+		t.Errorf("%d leases left in the index, want 0", n)
+	}
+}
+
+func TestRedisLeaseReclaim(t *testing.T) {
+	ctx := context.Background()
+	leases, client := newRedisLeases(t)
+	live := LeasedTask{Version: LeaseFormatVersion, ID: "live", Attempt: 1}
+	expired := LeasedTask{Version: LeaseFormatVersion, ID: "expired", Type: "review", Attempt: 3, Input: map[string]interface{}{"pr": "7"}}
+	pinned := LeasedTask{Version: LeaseFormatVersion, ID: "pinned", PinnedInstance: "one", Attempt: 1}
+
+	if err := leases.Hold(ctx, "one", []LeasedTask{live}, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// A negative TTL writes leases that have already expired
+	if err := leases.Hold(ctx, "one", []LeasedTask{expired, pinned}, -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := leases.Reclaim(ctx, "two", 30*time.Second, 0)
+	if err != nil {
+		t.Fatalf("Reclaim: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "expired" || got[0].Attempt != 3 || got[0].Input["pr"] != "7" {
+		t.Fatalf("reclaimed %+v, want only the expired task as leased", got)
+	}
+	if owner := leaseOwner(t, client, "expired"); owner != "two" {
+		t.Errorf("expired task owner = %q, want two", owner)
+	}
+	if owner := leaseOwner(t, client, "live"); owner != "one" {
+		t.Errorf("live task owner = %q, want one", owner)
+	}
+
+	// The pinned task is released from its instance after unpin_after
+	if got, _ := leases.Reclaim(ctx, "two", 30*time.Second, 2*time.Minute); len(got) != 0 {
+		t.Errorf("reclaimed %+v before unpin_after, want nothing", got)
+	}
+	got, err = leases.Reclaim(ctx, "two", 30*time.Second, 30*time.Second)
+	if err != nil || len(got) != 1 || got[0].ID != "pinned" {
+		t.Errorf("Reclaim after unpin_after = %+v, %v; want the pinned task", got, err)
+	}
+}
+
+func TestRedisLeaseQuarantinesUnreadableTask(t *testing.T) {
+	ctx := context.Background()
+	leases, client := newRedisLeases(t)
+	if err := leases.Hold(ctx, "one", []LeasedTask{{Version: LeaseFormatVersion, ID: "bad"}}, -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	client.HSet(ctx, leasePrefix+"bad", "task", "not json")
+
+	got, err := leases.Reclaim(ctx, "two", 30*time.Second, 0)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("Reclaim = %+v, %v; want nothing reclaimed", got, err)
+	}
+	if !client.HExists(ctx, leaseDead, "bad").Val() {
+		t.Error("unreadable lease not moved to the dead-letter hash")
+	}
+	if n := client.Exists(ctx, leasePrefix+"bad").Val(); n != 0 {
+		t.Error("unreadable lease left in place")
+	}
+}
@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// LeaseFormatVersion is the layout version written into persisted leases.
+// Bump it whenever LeasedTask changes incompatibly, and add an upgrade
+// from the previous version to leaseUpgrades. Optional fields that read as
+// their zero value from older entries (such as agents) need no bump.
+//
+//	1: unversioned layout, as first shipped
+//	2: adds deadline
+const LeaseFormatVersion = 2
+
+// ErrLeaseFormat is returned for a persisted task that can't be decoded
+var ErrLeaseFormat = errors.New("unreadable leased task")
+
+// leaseUpgrades maps a version to the function rewriting a task of that
+// version into the next one
+var leaseUpgrades = map[int]func(map[string]interface{}) error{
+	1: func(task map[string]interface{}) error {
+		// Deadlines weren't persisted; a reclaimed v1 task has none
+		return nil
+	},
+}
+
+// encodeLeasedTask serializes a task at the current format version
+func encodeLeasedTask(task LeasedTask) ([]byte, error) {
+	task.Version = LeaseFormatVersion
+	return json.Marshal(task)
+}
+
+// decodeLeasedTask reads a task written by this or any earlier version,
+// upgrading it step by step. Newer or malformed entries are rejected with
+// ErrLeaseFormat so the caller can set them aside.
+func decodeLeasedTask(raw []byte) (LeasedTask, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return LeasedTask{}, fmt.Errorf("%w: %v", ErrLeaseFormat, err)
+	}
+
+	version := 1
+	if v, ok := fields["v"]; ok {
+		n, ok := v.(float64)
+		if !ok || n < 1 || n != float64(int(n)) {
+			return LeasedTask{}, fmt.Errorf("%w: invalid version %v", ErrLeaseFormat, v)
+		}
+		version = int(n)
+	}
+	if version > LeaseFormatVersion {
+		return LeasedTask{}, fmt.Errorf("%w: version %d is newer than %d", ErrLeaseFormat, version, LeaseFormatVersion)
+	}
+
+	for ; version < LeaseFormatVersion; version++ {
+		if err := leaseUpgrades[version](fields); err != nil {
+			return LeasedTask{}, fmt.Errorf("%w: upgrade from version %d: %v", ErrLeaseFormat, version, err)
+		}
+	}
+	fields["v"] = LeaseFormatVersion
+
+	upgraded, err := json.Marshal(fields)
+	if err != nil {
+		return LeasedTask{}, fmt.Errorf("%w: %v", ErrLeaseFormat, err)
+	}
+	var task LeasedTask
+	if err := json.Unmarshal(upgraded, &task); err != nil {
+		return LeasedTask{}, fmt.Errorf("%w: %v", ErrLeaseFormat, err)
+	}
+	if task.ID == "" {
+		return LeasedTask{}, fmt.Errorf("%w: missing id", ErrLeaseFormat)
+	}
+	return task, nil
+}
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLeasedTaskRoundTrips(t *testing.T) {
+	in := LeasedTask{
+		ID:       "t-1",
+		Type:     "review",
+		Priority: PriorityHigh,
+		Retries:  2,
+		Deadline: epoch.Add(time.Hour),
+		Agents:   []string{"review"},
+		Input:    map[string]interface{}{"pr": "7"},
+		Attempt:  3,
+	}
+	raw, err := encodeLeasedTask(in)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	out, err := decodeLeasedTask(raw)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Version != LeaseFormatVersion || out.ID != "t-1" || !out.Deadline.Equal(in.Deadline) || out.Attempt != 3 || out.Input["pr"] != "7" {
+		t.Errorf("decoded %+v, want %+v at version %d", out, in, LeaseFormatVersion)
+	}
+}
+
+func TestPriorVersionLeaseIsUpgraded(t *testing.T) {
+	// As written before leases carried a version or a deadline
+	v1 := `{"id": "old", "type": "review", "priority": 2, "retries": 1, "agents": ["review"]}`
+
+	task, err := decodeLeasedTask([]byte(v1))
+	if err != nil {
+		t.Fatalf("decode v1: %v", err)
+	}
+	if task.Version != LeaseFormatVersion || task.ID != "old" || task.Priority != PriorityHigh || task.Retries != 1 {
+		t.Errorf("upgraded %+v, want the v1 fields at version %d", task, LeaseFormatVersion)
+	}
+	if !task.Deadline.IsZero() {
+		t.Errorf("deadline = %v, want none for a v1 task", task.Deadline)
+	}
+}
+
+func TestUnreadableLeaseIsRejectedNotFatal(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"not json", `{"id": "t-1",`},
+		{"newer version", `{"v": 99, "id": "t-1"}`},
+		{"bad version", `{"v": "two", "id": "t-1"}`},
+		{"fractional version", `{"v": 1.5, "id": "t-1"}`},
+		{"missing id", `{"v": 2, "type": "review"}`},
+		{"wrong field type", `{"v": 2, "id": "t-1", "priority": "urgent"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeLeasedTask([]byte(tt.raw)); !errors.Is(err, ErrLeaseFormat) {
+				t.Errorf("decode = %v, want ErrLeaseFormat", err)
+			}
+		})
+	}
+}
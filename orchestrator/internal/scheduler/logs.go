@@ -0,0 +1,197 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/stream"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// LogChannel is the stream agents publish task log lines to
+const LogChannel = "task_logs"
+
+// LogLine is one line of agent output for a task
+type LogLine struct {
+	Seq   uint64    `json:"seq"`
+	Time  time.Time `json:"time"`
+	Agent string    `json:"agent,omitempty"`
+	Level string    `json:"level,omitempty"`
+	Line  string    `json:"line"`
+}
+
+// logEntry is the payload of a task_logs message
+type logEntry struct {
+	TaskID string `json:"task_id"`
+	Line   string `json:"line"`
+	Level  string `json:"level"`
+}
+
+// taskLog is the retained tail of one task's output
+type taskLog struct {
+	lines []LogLine
+	bytes int
+	seq   uint64
+}
+
+// logBuffer keeps a bounded recent window of log lines per task and fans
+// new lines out to tailers. When more than maxTasks tasks have logs, the
+// one least recently written to is dropped.
+type logBuffer struct {
+	mu       sync.Mutex
+	maxLines int
+	maxBytes int
+	maxTasks int
+	tasks    map[string]*taskLog
+	order    []string // task IDs, least recently written first
+	subs     map[string]map[int]chan LogLine
+	nextSub  int
+}
+
+func newLogBuffer(cfg config.TaskLogConfig) *logBuffer {
+	return &logBuffer{
+		maxLines: cfg.MaxLines,
+		maxBytes: cfg.MaxBytes,
+		maxTasks: cfg.MaxTasks,
+		tasks:    make(map[string]*taskLog),
+		subs:     make(map[string]map[int]chan LogLine),
+	}
+}
+
+func (b *logBuffer) append(taskID string, line LogLine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tl, ok := b.tasks[taskID]
+	if !ok {
+		tl = &taskLog{}
+		b.tasks[taskID] = tl
+	}
+	b.touch(taskID)
+
+	if len(line.Line) > b.maxBytes {
+		line.Line = line.Line[:b.maxBytes]
+	}
+	tl.seq++
+	line.Seq = tl.seq
+	tl.lines = append(tl.lines, line)
+	tl.bytes += len(line.Line)
+	for len(tl.lines) > b.maxLines || tl.bytes > b.maxBytes {
+		tl.bytes -= len(tl.lines[0].Line)
+		tl.lines = tl.lines[1:]
+	}
+
+	for _, ch := range b.subs[taskID] {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// touch moves a task to the most recent end of the eviction order and
+// drops the oldest tasks beyond the limit
+func (b *logBuffer) touch(taskID string) {
+	for i, id := range b.order {
+		if id == taskID {
+			b.order = append(b.order[:i], b.order[i+1:]...)
+			break
+		}
+	}
+	b.order = append(b.order, taskID)
+
+	for len(b.order) > b.maxTasks {
+		delete(b.tasks, b.order[0])
+		b.order = b.order[1:]
+	}
+}
+
+func (b *logBuffer) tail(taskID string) []LogLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tl, ok := b.tasks[taskID]
+	if !ok {
+		return []LogLine{}
+	}
+	return append([]LogLine(nil), tl.lines...)
+}
+
+func (b *logBuffer) subscribe(taskID string, buffer int) ([]LogLine, <-chan LogLine, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lines []LogLine
+	if tl, ok := b.tasks[taskID]; ok {
+		lines = append(lines, tl.lines...)
+	}
+
+	id := b.nextSub
+	b.nextSub++
+	ch := make(chan LogLine, buffer)
+	if b.subs[taskID] == nil {
+		b.subs[taskID] = make(map[int]chan LogLine)
+	}
+	b.subs[taskID][id] = ch
+
+	var once sync.Once
+	return lines, ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs[taskID], id)
+			if len(b.subs[taskID]) == 0 {
+				delete(b.subs, taskID)
+			}
+			close(ch)
+		})
+	}
+}
+
+// AppendLog records a line of agent output for a task
+func (s *Scheduler) AppendLog(taskID, agent, level, line string) {
+	s.mu.Lock()
+	now := s.clock.Now()
+	s.mu.Unlock()
+
+	s.logs.append(taskID, LogLine{Time: now, Agent: agent, Level: level, Line: line})
+}
+
+// Logs returns the buffered tail of a task's output, oldest first
+func (s *Scheduler) Logs(taskID string) []LogLine {
+	return s.logs.tail(taskID)
+}
+
+// TailLogs returns the buffered tail of a task's output and a channel of
+// lines appended after it. Slow readers miss lines rather than blocking
+// agents. The returned function stops the tail and closes the channel.
+func (s *Scheduler) TailLogs(taskID string, buffer int) ([]LogLine, <-chan LogLine, func()) {
+	return s.logs.subscribe(taskID, buffer)
+}
+
+// ConsumeLogs buffers log lines agents publish to the task_logs stream
+// until ctx is cancelled, reconnecting after read errors
+func (s *Scheduler) ConsumeLogs(ctx context.Context, consumer *stream.Consumer) {
+	for {
+		err := consumer.Consume(ctx, LogChannel, func(msg stream.Message) {
+			var entry logEntry
+			if err := json.Unmarshal(msg.Payload, &entry); err != nil || entry.TaskID == "" {
+				return
+			}
+			s.AppendLog(entry.TaskID, msg.Source, entry.Level, entry.Line)
+		})
+		if err == nil {
+			return
+		}
+		s.logger.Warn("Task log stream failed, retrying", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
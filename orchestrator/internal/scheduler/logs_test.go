@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// lines returns the text of each log line
+func lines(logs []LogLine) []string {
+	out := make([]string, len(logs))
+	for i, l := range logs {
+		out[i] = l.Line
+	}
+	return out
+}
+
+func TestLogLinesKeptInOrderWithinCaps(t *testing.T) {
+	b := newLogBuffer(config.TaskLogConfig{MaxLines: 3, MaxBytes: 1 << 10, MaxTasks: 10})
+	for i := 1; i <= 5; i++ {
+		b.append("t", LogLine{Line: fmt.Sprintf("line %d", i)})
+	}
+
+	tail := b.tail("t")
+	if got := strings.Join(lines(tail), ","); got != "line 3,line 4,line 5" {
+		t.Errorf("tail = %s, want the last three lines", got)
+	}
+	if tail[0].Seq != 3 || tail[2].Seq != 5 {
+		t.Errorf("sequence numbers %d..%d, want 3..5", tail[0].Seq, tail[2].Seq)
+	}
+}
+
+func TestLogBytesCapTrimsOldLinesAndLongOnes(t *testing.T) {
+	b := newLogBuffer(config.TaskLogConfig{MaxLines: 100, MaxBytes: 10, MaxTasks: 10})
+	b.append("t", LogLine{Line: "aaaa"})
+	b.append("t", LogLine{Line: "bbbb"})
+	b.append("t", LogLine{Line: "cccc"})
+	if got := strings.Join(lines(b.tail("t")), ","); got != "bbbb,cccc" {
+		t.Errorf("tail = %s, want the oldest line dropped", got)
+	}
+
+	b.append("t", LogLine{Line: strings.Repeat("x", 50)})
+	if got := lines(b.tail("t")); len(got) != 1 || len(got[0]) != 10 {
+		t.Errorf("tail = %q, want one line cut to the byte cap", got)
+	}
+}
+
+func TestLeastRecentTaskLogIsDropped(t *testing.T) {
+	b := newLogBuffer(config.TaskLogConfig{MaxLines: 10, MaxBytes: 1 << 10, MaxTasks: 2})
+	b.append("a", LogLine{Line: "1"})
+	b.append("b", LogLine{Line: "1"})
+	b.append("a", LogLine{Line: "2"})
+	b.append("c", LogLine{Line: "1"})
+
+	if len(b.tail("b")) != 0 {
+		t.Error("least recently written task was kept")
+	}
+	if len(b.tail("a")) != 2 || len(b.tail("c")) != 1 {
+		t.Error("recent task logs were dropped")
+	}
+}
+
+func TestTailLogsReplaysThenFollows(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.TaskLogs = config.TaskLogConfig{MaxLines: 10, MaxBytes: 1 << 10, MaxTasks: 10}
+	s, _ := newTestScheduler(t, cfg)
+	s.AppendLog("t", "dev", "info", "starting")
+
+	tail, live, stop := s.TailLogs("t", 4)
+	if got := lines(tail); len(got) != 1 || got[0] != "starting" {
+		t.Fatalf("tail = %q, want the buffered line", got)
+	}
+	s.AppendLog("t", "dev", "info", "working")
+	s.AppendLog("other", "dev", "info", "elsewhere")
+	if line := <-live; line.Line != "working" || line.Agent != "dev" || line.Seq != 2 {
+		t.Errorf("live line = %+v, want working from dev", line)
+	}
+
+	stop()
+	if _, ok := <-live; ok {
+		t.Error("channel still open after stop")
+	}
+	stop() // stopping twice is harmless
+}
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// ErrNoRoute fails a queued task none of whose agents stayed routable
+var ErrNoRoute = errors.New("no routable agent for task")
+
+// RouteCheck reports whether any of the named agents can take work
+type RouteCheck func(agents []string) bool
+
+// SetRouteCheck installs the check used to find queued tasks whose agents
+// have all gone away. Without one, tasks are never failed for lack of a
+// route.
+func (s *Scheduler) SetRouteCheck(check RouteCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.routable = check
+}
+
+// failUnroutable fails queued tasks that have had no routable agent for
+// longer than the grace period. A task whose agents come back before then
+// starts its grace period over the next time they all go away.
+func (s *Scheduler) failUnroutable() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.routable == nil || s.noRouteGrace <= 0 {
+		return
+	}
+
+	now := s.clock.Now()
+	var orphans []*ScheduledTask
+	for _, task := range s.queue {
+		if len(task.Agents) == 0 || s.routable(task.Agents) {
+			task.orphaned = time.Time{}
+			continue
+		}
+		if task.orphaned.IsZero() {
+			task.orphaned = now
+			continue
+		}
+		if now.Sub(task.orphaned) >= s.noRouteGrace {
+			orphans = append(orphans, task)
+		}
+	}
+
+	for _, task := range orphans {
+		s.removeQueued(task)
+		task.State = TaskFailed
+		s.record(DecisionNoRoute, task)
+
+		err := fmt.Errorf("%w: %v unavailable for %s", ErrNoRoute, task.Agents, now.Sub(task.orphaned).Round(time.Second))
+		s.logger.Error("Task has no route, failing",
+			zap.String("id", task.ID),
+			zap.Strings("agents", task.Agents),
+			zap.Error(err),
+		)
+		s.emit(events.TaskFailed, task.ID, err.Error())
+		s.stageFinished(task, nil, err.Error())
+		s.settle(task)
+	}
+}
@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+)
+
+// agentSet is a RouteCheck over agents that can be taken offline
+type agentSet struct {
+	mu      sync.Mutex
+	offline map[string]bool
+}
+
+func (a *agentSet) routable(agents []string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, name := range agents {
+		if !a.offline[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *agentSet) setOffline(name string, offline bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.offline[name] = offline
+}
+
+// newNoRouteScheduler runs one task at a time, failing unroutable tasks
+// after a minute
+func newNoRouteScheduler(t *testing.T) (*Scheduler, *ManualClock, *agentSet, *events.Bus) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	cfg.Orchestrator.NoRouteGrace = 60
+	s, c := newTestScheduler(t, cfg)
+	agents := &agentSet{offline: make(map[string]bool)}
+	s.SetRouteCheck(agents.routable)
+	bus := events.New(100)
+	s.SetEvents(bus)
+
+	mustSchedule(t, s, &ScheduledTask{ID: "blocker"})
+	s.processQueue()
+	return s, c, agents, bus
+}
+
+func TestQueuedTaskFailsWithNoRouteAfterGrace(t *testing.T) {
+	s, c, agents, bus := newNoRouteScheduler(t)
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "stuck", Type: "review", Agents: []string{"review", "security"}},
+		&ScheduledTask{ID: "fine", Type: "question", Agents: []string{"explain"}},
+	)
+	agents.setOffline("review", true)
+	agents.setOffline("security", true)
+
+	s.failUnroutable() // starts the grace period
+	c.Advance(59 * time.Second)
+	s.failUnroutable()
+	if got := stateOf(t, s, "stuck"); got != TaskQueued {
+		t.Fatalf("task is %s within the grace period, want queued", got)
+	}
+
+	c.Advance(time.Second)
+	s.failUnroutable()
+	if got := counts(s, "review"); got.Failed != 1 {
+		t.Fatalf("tally = %+v, want the task failed", got)
+	}
+	if got := stateOf(t, s, "fine"); got != TaskQueued {
+		t.Errorf("routable task is %s, want still queued", got)
+	}
+
+	var failure string
+	for _, e := range bus.Since(0) {
+		if e.Type == events.TaskFailed && e.TaskID == "stuck" {
+			failure = e.Message
+		}
+	}
+	if !strings.HasPrefix(failure, ErrNoRoute.Error()) {
+		t.Errorf("failure = %q, want ErrNoRoute", failure)
+	}
+}
+
+func TestAgentReturningRestartsGrace(t *testing.T) {
+	s, c, agents, _ := newNoRouteScheduler(t)
+	mustSchedule(t, s, &ScheduledTask{ID: "flaky", Agents: []string{"review"}})
+
+	agents.setOffline("review", true)
+	s.failUnroutable()
+	c.Advance(50 * time.Second)
+
+	// Back briefly, then gone again: the grace period starts over
+	agents.setOffline("review", false)
+	s.failUnroutable()
+	agents.setOffline("review", true)
+	s.failUnroutable()
+	c.Advance(50 * time.Second)
+	s.failUnroutable()
+
+	if got := stateOf(t, s, "flaky"); got != TaskQueued {
+		t.Errorf("task is %s, want queued with its grace period restarted", got)
+	}
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// ErrPinnedElsewhere is returned for a task pinned to another instance
+// that cannot be handed off to it
+var ErrPinnedElsewhere = errors.New("task is pinned to another instance")
+
+// instance is this orchestrator's ID for pinning: its lease owner, or the
+// configured instance ID when leases are off. Callers hold s.mu.
+func (s *Scheduler) instance() string {
+	if s.owner != "" {
+		return s.owner
+	}
+	return s.config.Orchestrator.InstanceID
+}
+
+// pinnedElsewhere reports whether only another instance may dispatch the
+// task. Callers hold s.mu.
+func (s *Scheduler) pinnedElsewhere(task *ScheduledTask) bool {
+	return task.PinnedInstance != "" && task.PinnedInstance != s.instance()
+}
+
+// checkPinned refuses tasks pinned to another instance where they must be
+// scheduled together here, as in a group, pipeline or reservation.
+// Callers hold s.mu.
+func (s *Scheduler) checkPinned(tasks []*ScheduledTask) error {
+	for _, task := range tasks {
+		if s.pinnedElsewhere(task) {
+			return fmt.Errorf("%w: %s is pinned to %s", ErrPinnedElsewhere, task.ID, task.PinnedInstance)
+		}
+	}
+	return nil
+}
+
+// handOff passes a task pinned to another instance through the shared
+// lease store, as a lease owned by that instance that has already expired.
+// The pinned instance reclaims and queues it on its next lease sweep;
+// other instances leave it alone unless orchestrator.leases.unpin_after
+// passes first.
+func (s *Scheduler) handOff(task *ScheduledTask) error {
+	s.mu.Lock()
+	leases := s.leases
+	task.State = TaskQueued
+	lease := leasedTask(task)
+	s.mu.Unlock()
+
+	if leases == nil {
+		return fmt.Errorf("%w: %s is pinned to %s and leases are disabled", ErrPinnedElsewhere, task.ID, task.PinnedInstance)
+	}
+	if err := leases.Hold(context.Background(), task.PinnedInstance, []LeasedTask{lease}, 0); err != nil {
+		return fmt.Errorf("failed to hand %s off to %s: %w", task.ID, task.PinnedInstance, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logger.Info("Task handed off to its pinned instance",
+		zap.String("id", task.ID),
+		zap.String("instance", task.PinnedInstance),
+	)
+	s.emit(events.TaskScheduled, task.ID, "pinned to "+task.PinnedInstance)
+	return nil
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPinnedTaskDispatchedOnlyByItsInstance(t *testing.T) {
+	c := NewManualClock(epoch)
+	leases := newFakeLeases(c)
+	one := newLeasedScheduler(t, leases, "one")
+	two := newLeasedScheduler(t, leases, "two")
+
+	mustSchedule(t, one,
+		&ScheduledTask{ID: "local", PinnedInstance: "one"},
+		&ScheduledTask{ID: "remote", PinnedInstance: "two"},
+		&ScheduledTask{ID: "anywhere"},
+	)
+	if _, ok := queuedTask(one, "remote"); ok {
+		t.Fatal("a task pinned to another instance was queued")
+	}
+	if got := leases.owner("remote"); got != "two" {
+		t.Fatalf("remote task handed to %q, want two", got)
+	}
+
+	one.processQueue()
+	for _, id := range []string{"local", "anywhere"} {
+		if got := stateOf(t, one, id); got != TaskRunning {
+			t.Errorf("%s is %s on its submitting instance, want running", id, got)
+		}
+	}
+
+	// Another instance sweeping the lease store leaves the task alone
+	three := newLeasedScheduler(t, leases, "three")
+	three.reclaimLeases(context.Background())
+	if _, ok := three.Task("remote"); ok {
+		t.Error("a third instance took a task pinned to two")
+	}
+
+	two.reclaimLeases(context.Background())
+	two.processQueue()
+	if got := stateOf(t, two, "remote"); got != TaskRunning {
+		t.Errorf("remote is %s on its pinned instance, want running", got)
+	}
+	if _, ok := one.Task("remote"); ok {
+		t.Error("the submitting instance still tracks the handed-off task")
+	}
+}
+
+func TestPinnedElsewhereWithoutLeasesRefused(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.InstanceID = "one"
+	s, _ := newTestScheduler(t, cfg)
+
+	if err := s.Schedule(&ScheduledTask{ID: "remote", PinnedInstance: "two"}); !errors.Is(err, ErrPinnedElsewhere) {
+		t.Errorf("err = %v, want ErrPinnedElsewhere with no lease store to hand off through", err)
+	}
+	mustSchedule(t, s, &ScheduledTask{ID: "local", PinnedInstance: "one"})
+	s.processQueue()
+	if got := stateOf(t, s, "local"); got != TaskRunning {
+		t.Errorf("task pinned to the configured instance is %s, want running", got)
+	}
+}
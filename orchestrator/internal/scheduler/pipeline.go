@@ -0,0 +1,368 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// Pipeline failure policies
+const (
+	// PolicyFailFast cancels the remaining stages when a stage fails
+	PolicyFailFast = "fail_fast"
+	// PolicyContinue runs the next stage anyway, passing it the error
+	PolicyContinue = "continue"
+)
+
+// Pipeline states
+const (
+	PipelineRunning   = "running"
+	PipelineCompleted = "completed"
+	PipelineFailed    = "failed"
+	PipelineCancelled = "cancelled"
+)
+
+// Input keys the scheduler sets on a stage from the stage before it
+const (
+	InputPreviousOutput = "previous_output"
+	InputPreviousError  = "previous_error"
+
+	// InputTimeout is the seconds of pipeline budget left when the stage
+	// was dispatched, its effective timeout
+	InputTimeout = "timeout"
+)
+
+// ErrPipelineNotFound is returned for unknown pipeline IDs
+var ErrPipelineNotFound = errors.New("pipeline not found")
+
+// Stage is one step of a pipeline
+type Stage struct {
+	Name string
+	Task *ScheduledTask
+}
+
+// Pipeline is an ordered list of stages where each stage runs after the
+// previous one and receives its output
+type Pipeline struct {
+	ID     string
+	Name   string
+	Policy string
+	Stages []Stage
+
+	// Budget bounds the whole pipeline: every stage must finish within it
+	// of scheduling, and the pipeline fails once it runs out (0 = none)
+	Budget time.Duration
+}
+
+// StageStatus is a point-in-time view of one stage
+type StageStatus struct {
+	Name   string                 `json:"name"`
+	TaskID string                 `json:"task_id"`
+	State  TaskState              `json:"state"`
+	Output map[string]interface{} `json:"output,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// PipelineStatus reports overall progress of a pipeline
+type PipelineStatus struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Policy    string        `json:"policy"`
+	State     string        `json:"state"`
+	Completed int           `json:"completed"`
+	Total     int           `json:"total"`
+	Deadline  time.Time     `json:"deadline,omitempty"`
+	Stages    []StageStatus `json:"stages"`
+}
+
+// pipelineRun tracks a scheduled pipeline
+type pipelineRun struct {
+	pipeline *Pipeline
+	state    string
+	outputs  []map[string]interface{}
+	errors   []string
+	deadline time.Time // end of the budget; zero without one
+}
+
+// stageRef locates a task within a pipeline
+type stageRef struct {
+	run   *pipelineRun
+	index int
+}
+
+// SchedulePipeline chains the stages with dependencies and queues them all.
+// Either every stage is queued or none is.
+func (s *Scheduler) SchedulePipeline(p *Pipeline) error {
+	if len(p.Stages) == 0 {
+		return fmt.Errorf("pipeline has no stages")
+	}
+	switch p.Policy {
+	case "":
+		p.Policy = PolicyFailFast
+	case PolicyFailFast, PolicyContinue:
+	default:
+		return fmt.Errorf("unknown pipeline policy: %s", p.Policy)
+	}
+	if p.Budget < 0 {
+		return fmt.Errorf("pipeline budget must not be negative")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.pipelines[p.ID]; exists {
+		return fmt.Errorf("pipeline %s already scheduled", p.ID)
+	}
+	tasks := make([]*ScheduledTask, 0, len(p.Stages))
+	for _, stage := range p.Stages {
+		tasks = append(tasks, stage.Task)
+	}
+	if err := s.checkPinned(tasks); err != nil {
+		return err
+	}
+	if s.maxQueued > 0 && s.queue.Len()+len(p.Stages) > s.maxQueued {
+		return ErrQueueFull
+	}
+	run := &pipelineRun{
+		pipeline: p,
+		state:    PipelineRunning,
+		outputs:  make([]map[string]interface{}, len(p.Stages)),
+		errors:   make([]string, len(p.Stages)),
+	}
+	s.pipelines[p.ID] = run
+
+	now := s.clock.Now()
+	if p.Budget > 0 {
+		run.deadline = now.Add(p.Budget)
+	}
+	for i, stage := range p.Stages {
+		task := stage.Task
+		if i > 0 {
+			task.Dependencies = append(task.Dependencies, p.Stages[i-1].Task.ID)
+		}
+		task.ScheduledAt = now
+		task.State = TaskQueued
+		task.submitted = now
+		s.defaultDeadline(task)
+		s.resolvePolicy(task)
+		if !run.deadline.IsZero() && (task.Deadline.IsZero() || task.Deadline.After(run.deadline)) {
+			task.Deadline = run.deadline
+		}
+		s.stages[task.ID] = stageRef{run: run, index: i}
+		s.enqueue(task)
+		s.emit(events.TaskScheduled, task.ID, "pipeline "+p.ID)
+	}
+
+	s.logger.Debug("Pipeline scheduled",
+		zap.String("id", p.ID),
+		zap.Int("stages", len(p.Stages)),
+	)
+	return nil
+}
+
+// stageFinished advances the pipeline, or settles the group, a finished
+// task belongs to. Callers hold s.mu.
+func (s *Scheduler) stageFinished(task *ScheduledTask, output map[string]interface{}, errMsg string) {
+	s.memberFinished(task, output, errMsg)
+	s.childFinished(task)
+
+	ref, ok := s.stages[task.ID]
+	if !ok || ref.run.state != PipelineRunning {
+		return
+	}
+	run := ref.run
+	p := run.pipeline
+	run.outputs[ref.index] = output
+	run.errors[ref.index] = errMsg
+
+	last := ref.index == len(p.Stages)-1
+
+	switch task.State {
+	case TaskCompleted:
+		if last {
+			s.finishPipeline(run, PipelineCompleted)
+			return
+		}
+		next := p.Stages[ref.index+1].Task
+		setInput(next, InputPreviousOutput, output)
+
+	case TaskCancelled:
+		s.cancelStages(run, ref.index+1)
+		s.finishPipeline(run, PipelineCancelled)
+
+	case TaskFailed:
+		if p.Policy == PolicyContinue && !last {
+			next := p.Stages[ref.index+1].Task
+			setInput(next, InputPreviousError, errMsg)
+			next.Dependencies = removeDependency(next.Dependencies, task.ID)
+			s.dropDependency(next, task.ID)
+			return
+		}
+		s.cancelStages(run, ref.index+1)
+		s.finishPipeline(run, PipelineFailed)
+	}
+}
+
+// cancelStages removes queued stages from index onwards
+func (s *Scheduler) cancelStages(run *pipelineRun, from int) {
+	var cancelled []*ScheduledTask
+	for _, stage := range run.pipeline.Stages[from:] {
+		task := stage.Task
+		if task.State != TaskQueued {
+			continue
+		}
+		if !s.unpark(task) && !s.removeQueued(task) {
+			continue
+		}
+		task.State = TaskCancelled
+		s.emit(events.TaskCancelled, task.ID, "pipeline "+run.pipeline.ID+" stopped")
+		cancelled = append(cancelled, task)
+	}
+	// Settled once all are cancelled, so no stage fails for the one before
+	for _, task := range cancelled {
+		s.settle(task)
+	}
+}
+
+func (s *Scheduler) finishPipeline(run *pipelineRun, state string) {
+	run.state = state
+	t := events.PipelineCompleted
+	if state != PipelineCompleted {
+		t = events.PipelineFailed
+	}
+	s.events.Publish(events.Event{Type: t, Message: state, Data: map[string]interface{}{"pipeline_id": run.pipeline.ID}})
+	s.logger.Info("Pipeline finished",
+		zap.String("id", run.pipeline.ID),
+		zap.String("state", state),
+	)
+}
+
+// Pipeline returns the status of a pipeline
+func (s *Scheduler) Pipeline(id string) (PipelineStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.pipelines[id]
+	if !ok {
+		return PipelineStatus{}, fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+	}
+	return run.status(), nil
+}
+
+// Pipelines returns the status of every known pipeline
+func (s *Scheduler) Pipelines() []PipelineStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]PipelineStatus, 0, len(s.pipelines))
+	for _, run := range s.pipelines {
+		out = append(out, run.status())
+	}
+	return out
+}
+
+func (run *pipelineRun) status() PipelineStatus {
+	p := run.pipeline
+	st := PipelineStatus{
+		ID:       p.ID,
+		Name:     p.Name,
+		Policy:   p.Policy,
+		State:    run.state,
+		Total:    len(p.Stages),
+		Deadline: run.deadline,
+		Stages:   make([]StageStatus, len(p.Stages)),
+	}
+	for i, stage := range p.Stages {
+		if stage.Task.State == TaskCompleted {
+			st.Completed++
+		}
+		st.Stages[i] = StageStatus{
+			Name:   stage.Name,
+			TaskID: stage.Task.ID,
+			State:  stage.Task.State,
+			Output: run.outputs[i],
+			Error:  run.errors[i],
+		}
+	}
+	return st
+}
+
+// stageDispatched hands a pipeline stage the budget it has left as its
+// timeout. Callers hold s.mu.
+func (s *Scheduler) stageDispatched(task *ScheduledTask) {
+	ref, ok := s.stages[task.ID]
+	if !ok || ref.run.deadline.IsZero() {
+		return
+	}
+	remaining := ref.run.deadline.Sub(s.clock.Now())
+	setInput(task, InputTimeout, max(remaining, 0).Seconds())
+}
+
+// enforceBudgets fails pipelines that have run out of budget: the stage
+// in progress fails, whether queued or running, and the stages after it
+// are cancelled regardless of the failure policy
+func (s *Scheduler) enforceBudgets() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	for _, run := range s.pipelines {
+		if run.state != PipelineRunning || run.deadline.IsZero() || now.Before(run.deadline) {
+			continue
+		}
+
+		for i, stage := range run.pipeline.Stages {
+			task := stage.Task
+			switch task.State {
+			case TaskRunning:
+				delete(s.running, task.ID)
+				s.currentCount--
+				s.notify()
+			case TaskQueued:
+				if !s.unpark(task) {
+					s.removeQueued(task)
+				}
+			case TaskSuspended:
+				delete(s.suspended, task.ID)
+			case TaskAwaiting:
+			default:
+				continue
+			}
+
+			message := fmt.Sprintf("pipeline budget of %s exhausted", run.pipeline.Budget)
+			task.State = TaskFailed
+			s.record(DecisionExpire, task)
+			s.logger.Warn("Pipeline out of budget",
+				zap.String("pipeline", run.pipeline.ID),
+				zap.String("stage", stage.Name),
+				zap.String("id", task.ID),
+			)
+			s.emit(events.TaskExpired, task.ID, message)
+			run.errors[i] = message
+			s.cancelStages(run, i+1)
+			s.finishPipeline(run, PipelineFailed)
+			s.settle(task)
+			break
+		}
+	}
+}
+
+func setInput(task *ScheduledTask, key string, value interface{}) {
+	if task.Input == nil {
+		task.Input = make(map[string]interface{})
+	}
+	task.Input[key] = value
+}
+
+func removeDependency(deps []string, id string) []string {
+	out := deps[:0]
+	for _, dep := range deps {
+		if dep != id {
+			out = append(out, dep)
+		}
+	}
+	return out
+}
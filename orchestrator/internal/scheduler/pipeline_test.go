@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// threeStages builds a retrieval, dev, review pipeline that never retries
+func threeStages(policy string) *Pipeline {
+	p := &Pipeline{ID: "p1", Name: "feature", Policy: policy}
+	for _, name := range []string{"retrieval", "dev", "review"} {
+		p.Stages = append(p.Stages, Stage{
+			Name: name,
+			Task: &ScheduledTask{ID: "p1-" + name, Type: name, Retry: Retries(0)},
+		})
+	}
+	return p
+}
+
+// runningIDs returns the IDs of the running tasks
+func runningIDs(s *Scheduler) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.running))
+	for id := range s.running {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestPipelineRunsInOrderThreadingOutputs(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	p := threeStages("")
+	if err := s.SchedulePipeline(p); err != nil {
+		t.Fatalf("SchedulePipeline: %v", err)
+	}
+
+	var previous map[string]interface{}
+	for i, stage := range p.Stages {
+		s.processQueue()
+		if got := runningIDs(s); len(got) != 1 || got[0] != stage.Task.ID {
+			t.Fatalf("stage %d: running %v, want only %s", i, got, stage.Task.ID)
+		}
+		if i > 0 && !reflect.DeepEqual(stage.Task.Input[InputPreviousOutput], previous) {
+			t.Errorf("stage %s input = %v, want the output of the stage before", stage.Name, stage.Task.Input)
+		}
+
+		status, _ := s.Pipeline("p1")
+		if status.State != PipelineRunning || status.Completed != i || status.Total != 3 {
+			t.Errorf("status before stage %d finished = %+v", i, status)
+		}
+
+		previous = map[string]interface{}{"stage": stage.Name}
+		s.completeTask(stage.Task.ID, previous, nil)
+	}
+
+	status, err := s.Pipeline("p1")
+	if err != nil {
+		t.Fatalf("Pipeline: %v", err)
+	}
+	if status.State != PipelineCompleted || status.Completed != 3 {
+		t.Errorf("final status = %+v, want completed 3 of 3", status)
+	}
+	if got := status.Stages[2].Output["stage"]; got != "review" {
+		t.Errorf("last stage output = %v", status.Stages[2].Output)
+	}
+}
+
+func TestPipelineStageFailureFailsFast(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	p := threeStages(PolicyFailFast)
+	if err := s.SchedulePipeline(p); err != nil {
+		t.Fatalf("SchedulePipeline: %v", err)
+	}
+
+	s.processQueue()
+	s.completeTask("p1-retrieval", map[string]interface{}{"docs": 3}, nil)
+	s.processQueue()
+	s.completeTask("p1-dev", nil, errors.New("compile error"))
+
+	status, _ := s.Pipeline("p1")
+	if status.State != PipelineFailed {
+		t.Fatalf("state = %s, want failed", status.State)
+	}
+	if status.Stages[1].Error != "compile error" || status.Stages[2].State != TaskCancelled {
+		t.Errorf("stages = %+v, want dev failed and review cancelled", status.Stages)
+	}
+	s.processQueue()
+	if got := runningIDs(s); len(got) != 0 {
+		t.Errorf("running %v after the pipeline failed", got)
+	}
+}
+
+func TestPipelineContinuePassesTheError(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	p := threeStages(PolicyContinue)
+	if err := s.SchedulePipeline(p); err != nil {
+		t.Fatalf("SchedulePipeline: %v", err)
+	}
+
+	s.processQueue()
+	s.completeTask("p1-retrieval", nil, errors.New("index offline"))
+	s.processQueue()
+	if got := runningIDs(s); len(got) != 1 || got[0] != "p1-dev" {
+		t.Fatalf("running %v, want dev to run after retrieval failed", got)
+	}
+	if got := p.Stages[1].Task.Input[InputPreviousError]; got != "index offline" {
+		t.Errorf("dev input = %v, want the retrieval error", p.Stages[1].Task.Input)
+	}
+}
+
+func TestPipelineStagesShareBudget(t *testing.T) {
+	s, c := newTestScheduler(t, testConfig())
+	p := threeStages(PolicyFailFast)
+	p.Budget = 10 * time.Minute
+	if err := s.SchedulePipeline(p); err != nil {
+		t.Fatalf("SchedulePipeline: %v", err)
+	}
+
+	s.processQueue()
+	if got := p.Stages[0].Task.Input[InputTimeout]; got != 600.0 {
+		t.Errorf("first stage timeout = %v, want the whole budget", got)
+	}
+
+	// A slow first stage leaves less time for the next
+	c.Advance(4 * time.Minute)
+	s.completeTask("p1-retrieval", nil, nil)
+	s.processQueue()
+	if got := p.Stages[1].Task.Input[InputTimeout]; got != 360.0 {
+		t.Errorf("second stage timeout = %v, want the 360s left", got)
+	}
+
+	c.Advance(5 * time.Minute)
+	s.enforceBudgets()
+	if status, _ := s.Pipeline("p1"); status.State != PipelineRunning {
+		t.Fatalf("pipeline %s within its budget", status.State)
+	}
+
+	c.Advance(time.Minute)
+	s.enforceBudgets()
+	status, _ := s.Pipeline("p1")
+	if status.State != PipelineFailed {
+		t.Fatalf("pipeline %s past its budget, want failed", status.State)
+	}
+	if got := status.Stages[1]; got.State != TaskFailed || !strings.Contains(got.Error, "budget") {
+		t.Errorf("running stage = %+v, want failed on the budget", got)
+	}
+	if got := status.Stages[2].State; got != TaskCancelled {
+		t.Errorf("last stage is %s, want cancelled", got)
+	}
+	if n := running(s); n != 0 {
+		t.Errorf("%d tasks still running", n)
+	}
+}
+
+func TestPipelineWithinBudgetCompletes(t *testing.T) {
+	s, c := newTestScheduler(t, testConfig())
+	p := threeStages("")
+	p.Budget = 10 * time.Minute
+	if err := s.SchedulePipeline(p); err != nil {
+		t.Fatalf("SchedulePipeline: %v", err)
+	}
+
+	for _, stage := range p.Stages {
+		s.processQueue()
+		c.Advance(3 * time.Minute)
+		s.enforceBudgets()
+		s.completeTask(stage.Task.ID, nil, nil)
+	}
+	if status, _ := s.Pipeline("p1"); status.State != PipelineCompleted {
+		t.Errorf("pipeline %s after 9 of 10 minutes, want completed", status.State)
+	}
+}
+
+func TestNegativePipelineBudgetRejected(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	p := threeStages("")
+	p.Budget = -time.Second
+	if err := s.SchedulePipeline(p); err == nil {
+		t.Error("pipeline with a negative budget scheduled")
+	}
+}
@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SetBusyCheck installs the check for agents saturated with work, which
+// holds tasks with a Fallback for their preferred agents. Without one,
+// tasks are dispatched to the agents they were routed to.
+func (s *Scheduler) SetBusyCheck(check RouteCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.busy = check
+}
+
+// holdForPreferred reports whether a task taken off the queue should go
+// back to wait for its saturated agents. It is held while
+// agents.prefer_wait has not passed since it was queued, then moved to
+// its Fallback, if that can still take work. Callers hold s.mu.
+func (s *Scheduler) holdForPreferred(task *ScheduledTask) bool {
+	if len(task.Fallback) == 0 {
+		return false
+	}
+	if s.busy == nil || !s.busy(task.Agents) {
+		task.Fallback = nil
+		return false
+	}
+
+	window := time.Duration(s.config.Agents.PreferWait) * time.Second
+	waited := s.clock.Now().Sub(task.ScheduledAt)
+	if waited < window {
+		return true
+	}
+
+	if s.routable == nil || s.routable(task.Fallback) {
+		s.logger.Info("Falling back from saturated agents",
+			zap.String("id", task.ID),
+			zap.Strings("preferred", task.Agents),
+			zap.Strings("fallback", task.Fallback),
+			zap.Duration("waited", waited),
+		)
+		task.Agents = task.Fallback
+	}
+	task.Fallback = nil
+	return false
+}
@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// newPreferScheduler holds tasks for saturated agents up to 10s, with
+// oracle_code saturated
+func newPreferScheduler(t *testing.T) (*Scheduler, *ManualClock, *agentSet) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Agents.PreferWait = 10
+	s, c := newTestScheduler(t, cfg)
+	// An agent marked offline in the set is one at capacity
+	saturated := &agentSet{offline: map[string]bool{"oracle_code": true}}
+	s.SetBusyCheck(func(agents []string) bool { return !saturated.routable(agents) })
+
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Agents: []string{"oracle_code"}, Fallback: []string{"dev"}})
+	return s, c, saturated
+}
+
+// agentsOf returns the agents a running task was dispatched to
+func agentsOf(t *testing.T, s *Scheduler, id string) []string {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.running[id]
+	if !ok {
+		t.Fatalf("task %s not running", id)
+	}
+	return task.Agents
+}
+
+func TestTaskFallsBackAfterPreferWait(t *testing.T) {
+	s, c, _ := newPreferScheduler(t)
+	mustSchedule(t, s, &ScheduledTask{ID: "other"})
+
+	c.Advance(9 * time.Second)
+	s.processQueue()
+	if got := stateOf(t, s, "t1"); got != TaskQueued {
+		t.Fatalf("task is %s within the window, want held for its preferred agent", got)
+	}
+	if got := stateOf(t, s, "other"); got != TaskRunning {
+		t.Errorf("task behind a held one is %s, want running", got)
+	}
+
+	c.Advance(2 * time.Second)
+	s.processQueue()
+	if got := stateOf(t, s, "t1"); got != TaskRunning {
+		t.Fatalf("task is %s after the window, want dispatched to its fallback", got)
+	}
+	if got := agentsOf(t, s, "t1"); !slices.Equal(got, []string{"dev"}) {
+		t.Errorf("dispatched to %v, want [dev]", got)
+	}
+}
+
+func TestTaskWaitsForPreferredAgentToFreeUp(t *testing.T) {
+	s, c, saturated := newPreferScheduler(t)
+
+	c.Advance(5 * time.Second)
+	s.processQueue()
+	saturated.setOffline("oracle_code", false)
+	s.processQueue()
+
+	if got := stateOf(t, s, "t1"); got != TaskRunning {
+		t.Fatalf("task is %s once its preferred agent freed up, want running", got)
+	}
+	if got := agentsOf(t, s, "t1"); !slices.Equal(got, []string{"oracle_code"}) {
+		t.Errorf("dispatched to %v, want the preferred agent", got)
+	}
+}
+
+func TestUnroutableFallbackKeepsPreferredAgents(t *testing.T) {
+	s, c, _ := newPreferScheduler(t)
+	down := &agentSet{offline: map[string]bool{"dev": true}}
+	s.SetRouteCheck(down.routable)
+
+	c.Advance(11 * time.Second)
+	s.processQueue()
+	if got := agentsOf(t, s, "t1"); !slices.Equal(got, []string{"oracle_code"}) {
+		t.Errorf("dispatched to %v, want the preferred agent with the fallback down", got)
+	}
+}
@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// QueueStateVersion is the newest queue state format this build writes
+const QueueStateVersion = 1
+
+// Queue state errors
+var (
+	ErrQueueState = errors.New("unreadable queue state")
+	ErrNotEmpty   = errors.New("scheduler already holds tasks")
+)
+
+// SavedTask is a task as kept in a saved queue
+type SavedTask struct {
+	LeasedTask
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Submitted   time.Time `json:"submitted"`
+}
+
+// SavedReservation is a pending reservation as kept in a saved queue
+type SavedReservation struct {
+	Tasks    []SavedTask `json:"tasks"`
+	Deadline time.Time   `json:"deadline,omitempty"` // zero waits indefinitely
+}
+
+// QueueState is everything needed to resume scheduling on another
+// instance, independent of Redis. Tasks that were running are restored to
+// the queue and run again.
+type QueueState struct {
+	Version   int                `json:"v"`
+	Saved     time.Time          `json:"saved"`
+	Queued    []SavedTask        `json:"queued"`  // in dispatch order
+	Running   []SavedTask        `json:"running"` // oldest start first
+	Waiting   []SavedTask        `json:"waiting"` // by ID
+	Reserved  []SavedReservation `json:"reserved"`
+	Completed []string           `json:"completed"` // satisfies dependencies after restore
+
+	// Suspended tasks still await approval after restore, their suspend
+	// timeout starting over
+	Suspended []SavedTask `json:"suspended,omitempty"`
+}
+
+// Drain suspends dispatch and returns the scheduler's state, so the
+// instance can be stopped and the state restored elsewhere. Running tasks
+// are left to finish but are saved too, since their results may never be
+// reported. Pipelines and all-or-nothing groups are not saved; their
+// tasks are restored as independent tasks.
+func (s *Scheduler) Drain() QueueState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.drained = true
+	state := QueueState{
+		Version:   QueueStateVersion,
+		Saved:     s.clock.Now(),
+		Queued:    make([]SavedTask, 0, s.queue.Len()),
+		Running:   make([]SavedTask, 0, len(s.running)),
+		Waiting:   make([]SavedTask, 0, len(s.waiting)),
+		Reserved:  make([]SavedReservation, 0, len(s.reservations)),
+		Completed: make([]string, 0, len(s.completed)),
+	}
+
+	// Sort a copy: the queue's own Swap would rewrite heap indexes
+	queued := append(TaskQueue{}, s.queue...)
+	sort.Slice(queued, func(i, j int) bool { return s.less(queued[i], queued[j]) })
+	for _, task := range queued {
+		state.Queued = append(state.Queued, savedTask(task))
+	}
+
+	running := make([]*ScheduledTask, 0, len(s.running))
+	for _, task := range s.running {
+		running = append(running, task)
+	}
+	sort.Slice(running, func(i, j int) bool { return running[i].started.Before(running[j].started) })
+	for _, task := range running {
+		state.Running = append(state.Running, savedTask(task))
+	}
+
+	for _, task := range s.waiting {
+		state.Waiting = append(state.Waiting, savedTask(task))
+	}
+	sort.Slice(state.Waiting, func(i, j int) bool { return state.Waiting[i].ID < state.Waiting[j].ID })
+
+	for _, res := range s.reservations {
+		saved := SavedReservation{Deadline: res.deadline}
+		for _, task := range res.tasks {
+			saved.Tasks = append(saved.Tasks, savedTask(task))
+		}
+		state.Reserved = append(state.Reserved, saved)
+	}
+
+	for id := range s.completed {
+		state.Completed = append(state.Completed, id)
+	}
+	slices.Sort(state.Completed)
+
+	for _, task := range s.suspended {
+		state.Suspended = append(state.Suspended, savedTask(task))
+	}
+	sort.Slice(state.Suspended, func(i, j int) bool { return state.Suspended[i].ID < state.Suspended[j].ID })
+
+	s.logger.Info("Scheduler drained",
+		zap.Int("queued", len(state.Queued)),
+		zap.Int("running", len(state.Running)),
+		zap.Int("waiting", len(state.Waiting)),
+	)
+	return state
+}
+
+// Resume restarts dispatch after Drain
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.drained = false
+	s.notify()
+}
+
+// Restore loads a drained scheduler's state into this one, which must not
+// hold any tasks yet. Queued tasks keep their dispatch order; tasks that
+// were running are queued again without counting a retry.
+func (s *Scheduler) Restore(state QueueState) error {
+	if state.Version < 1 || state.Version > QueueStateVersion {
+		return fmt.Errorf("%w: version %d, this build reads up to %d", ErrQueueState, state.Version, QueueStateVersion)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.queue.Len()+len(s.running)+len(s.waiting)+len(s.reservations)+len(s.suspended) > 0 {
+		return ErrNotEmpty
+	}
+
+	for _, id := range state.Completed {
+		s.completed[id] = true
+	}
+
+	now := s.clock.Now()
+	for _, saved := range state.Running {
+		task := restoredTask(saved, now)
+		task.ran = true
+		s.push(task)
+		s.record(DecisionRequeue, task)
+		s.emit(events.TaskRequeued, task.ID, "restored")
+	}
+	for _, saved := range state.Queued {
+		s.push(restoredTask(saved, now))
+	}
+	for _, saved := range state.Waiting {
+		s.enqueue(restoredTask(saved, now))
+	}
+	for _, saved := range state.Reserved {
+		res := &reservation{made: now, deadline: saved.Deadline}
+		for _, st := range saved.Tasks {
+			task := restoredTask(st, now)
+			task.index = -1
+			res.tasks = append(res.tasks, task)
+		}
+		s.reservations = append(s.reservations, res)
+	}
+	for _, saved := range state.Suspended {
+		s.resuspend(restoredTask(saved, now))
+	}
+	s.notify()
+
+	s.logger.Info("Scheduler restored",
+		zap.Time("saved", state.Saved),
+		zap.Int("queued", len(state.Queued)),
+		zap.Int("requeued", len(state.Running)),
+		zap.Int("waiting", len(state.Waiting)),
+		zap.Int("reservations", len(state.Reserved)),
+		zap.Int("suspended", len(state.Suspended)),
+	)
+	return nil
+}
+
+func savedTask(task *ScheduledTask) SavedTask {
+	lt := leasedTask(task)
+	lt.Version = LeaseFormatVersion
+	return SavedTask{LeasedTask: lt, ScheduledAt: task.ScheduledAt, Submitted: task.submitted}
+}
+
+// restoredTask rebuilds a saved task as queued
+func restoredTask(saved SavedTask, now time.Time) *ScheduledTask {
+	task := fromLeased(saved.LeasedTask)
+	task.State = TaskQueued
+	task.ScheduledAt = saved.ScheduledAt
+	if task.ScheduledAt.IsZero() {
+		task.ScheduledAt = now
+	}
+	task.submitted = saved.Submitted
+	if task.submitted.IsZero() {
+		task.submitted = task.ScheduledAt
+	}
+	// A task is only retried after running, and MaxQueueTime no longer applies
+	task.ran = task.Retries > 0
+	return task
+}
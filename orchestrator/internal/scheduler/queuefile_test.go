@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// savedIDs lists the IDs of saved tasks in order
+func savedIDs(tasks []SavedTask) []string {
+	out := make([]string, len(tasks))
+	for i, task := range tasks {
+		out[i] = task.ID
+	}
+	return out
+}
+
+// roundTrip writes a queue state to a file and reads it back
+func roundTrip(t *testing.T, state QueueState) QueueState {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "queue.json")
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded QueueState
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+func TestDumpRestoreReproducesQueue(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 2
+	old, c := newTestScheduler(t, cfg)
+
+	mustSchedule(t, old, &ScheduledTask{ID: "done"})
+	old.processQueue()
+	old.completeTask("done", nil, nil)
+
+	// Two running, four queued at mixed priorities, one waiting
+	for _, id := range []string{"run1", "run2"} {
+		c.Advance(time.Second)
+		mustSchedule(t, old, &ScheduledTask{ID: id, Type: "review", Priority: PriorityNormal})
+		old.processQueue()
+	}
+	for _, task := range []*ScheduledTask{
+		{ID: "normal", Priority: PriorityNormal},
+		{ID: "high1", Priority: PriorityHigh},
+		{ID: "low", Priority: PriorityLow},
+		{ID: "high2", Priority: PriorityHigh},
+		{ID: "after", Priority: PriorityNormal, Dependencies: []string{"run1"}},
+	} {
+		c.Advance(time.Second)
+		mustSchedule(t, old, task)
+	}
+
+	state := old.Drain()
+	if got := savedIDs(state.Queued); !slices.Equal(got, []string{"high1", "high2", "normal", "low"}) {
+		t.Fatalf("saved queue = %v, want dispatch order", got)
+	}
+	if got := savedIDs(state.Running); !slices.Equal(got, []string{"run1", "run2"}) {
+		t.Errorf("saved running = %v", got)
+	}
+
+	// A drained scheduler dispatches nothing more
+	old.completeTask("run2", nil, nil)
+	old.processQueue()
+	if got := running(old); got != 1 {
+		t.Errorf("drained scheduler dispatched: %d running", got)
+	}
+
+	fresh, _ := newTestScheduler(t, cfg)
+	if err := fresh.Restore(roundTrip(t, state)); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	// Running tasks come back queued, in their original place by priority
+	// and submission time, without a retry counted
+	restored := fresh.Drain()
+	want := []string{"high1", "high2", "run1", "run2", "normal", "low"}
+	if got := savedIDs(restored.Queued); !slices.Equal(got, want) {
+		t.Errorf("restored queue = %v, want %v", got, want)
+	}
+	for _, task := range restored.Queued {
+		if task.Retries != 0 {
+			t.Errorf("%s restored with %d retries", task.ID, task.Retries)
+		}
+	}
+	if !waiting(fresh, "after") {
+		t.Error("waiting task not restored as waiting")
+	}
+
+	// Completed tasks still satisfy dependencies
+	mustSchedule(t, fresh, &ScheduledTask{ID: "needs-done", Dependencies: []string{"done"}})
+	if waiting(fresh, "needs-done") {
+		t.Error("dependency on a task completed before the dump is unmet")
+	}
+}
+
+func TestRestoreRefusesBusySchedulerAndUnknownVersion(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	mustSchedule(t, s, &ScheduledTask{ID: "t1"})
+
+	if err := s.Restore(QueueState{Version: QueueStateVersion}); !errors.Is(err, ErrNotEmpty) {
+		t.Errorf("restore into a busy scheduler: err = %v, want ErrNotEmpty", err)
+	}
+
+	empty, _ := newTestScheduler(t, testConfig())
+	if err := empty.Restore(QueueState{Version: QueueStateVersion + 1}); !errors.Is(err, ErrQueueState) {
+		t.Errorf("restore of a newer format: err = %v, want ErrQueueState", err)
+	}
+}
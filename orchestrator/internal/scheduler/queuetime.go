@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// ErrQueueTimeout fails a task still queued past its MaxQueueTime
+var ErrQueueTimeout = errors.New("task exceeded its queue time")
+
+// expireQueued fails tasks, ready or waiting on dependencies, that have
+// gone longer than their MaxQueueTime since submission without being
+// dispatched. A task that has started running is no longer bounded by it.
+func (s *Scheduler) expireQueued() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	stale := func(task *ScheduledTask) bool {
+		return task.MaxQueueTime > 0 && !task.ran && now.Sub(task.submitted) >= task.MaxQueueTime
+	}
+
+	var expired []*ScheduledTask
+	for _, task := range s.queue {
+		if stale(task) {
+			expired = append(expired, task)
+		}
+	}
+	for _, task := range s.waiting {
+		if stale(task) {
+			expired = append(expired, task)
+		}
+	}
+
+	for _, task := range expired {
+		if !s.unpark(task) {
+			s.removeQueued(task)
+		}
+		task.State = TaskFailed
+		s.record(DecisionExpire, task)
+
+		err := fmt.Errorf("%w: queued for %s, limit %s", ErrQueueTimeout,
+			now.Sub(task.submitted).Round(time.Second), task.MaxQueueTime)
+		s.logger.Warn("Task queued too long, failing",
+			zap.String("id", task.ID),
+			zap.Error(err),
+		)
+		s.emit(events.TaskFailed, task.ID, err.Error())
+		s.stageFinished(task, nil, err.Error())
+		s.settle(task)
+	}
+}
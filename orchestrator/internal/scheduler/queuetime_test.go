@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+)
+
+// newSaturatedScheduler runs one task at a time with a blocker holding
+// the only slot
+func newSaturatedScheduler(t *testing.T) (*Scheduler, *ManualClock, *events.Bus) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	s, c := newTestScheduler(t, cfg)
+	bus := events.New(100)
+	s.SetEvents(bus)
+
+	mustSchedule(t, s, &ScheduledTask{ID: "blocker"})
+	s.processQueue()
+	return s, c, bus
+}
+
+func TestTaskPastQueueTimeFailsAndIsRemoved(t *testing.T) {
+	s, c, bus := newSaturatedScheduler(t)
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "stale", Type: "review", MaxQueueTime: 10 * time.Second},
+		&ScheduledTask{ID: "patient", Type: "review"},
+	)
+
+	c.Advance(9 * time.Second)
+	s.expireQueued()
+	if got := stateOf(t, s, "stale"); got != TaskQueued {
+		t.Fatalf("task is %s within its queue time, want queued", got)
+	}
+
+	c.Advance(time.Second)
+	s.expireQueued()
+	if _, ok := queuedTask(s, "stale"); ok {
+		t.Error("expired task still in the queue")
+	}
+	if got := counts(s, "review"); got.Failed != 1 {
+		t.Errorf("tally = %+v, want the task failed", got)
+	}
+	if got := stateOf(t, s, "patient"); got != TaskQueued {
+		t.Errorf("task without a limit is %s, want still queued", got)
+	}
+
+	var failure string
+	for _, e := range bus.Since(0) {
+		if e.Type == events.TaskFailed && e.TaskID == "stale" {
+			failure = e.Message
+		}
+	}
+	if !strings.HasPrefix(failure, ErrQueueTimeout.Error()) {
+		t.Errorf("failure = %q, want ErrQueueTimeout", failure)
+	}
+
+	// The freed slot goes to the task that is still wanted
+	s.completeTask("blocker", nil, nil)
+	s.processQueue()
+	if got := stateOf(t, s, "patient"); got != TaskRunning {
+		t.Errorf("remaining task is %s, want running", got)
+	}
+}
+
+func TestWaitingTaskPastQueueTimeFails(t *testing.T) {
+	s, c, _ := newSaturatedScheduler(t)
+	mustSchedule(t, s, &ScheduledTask{ID: "after", Type: "review", Dependencies: []string{"blocker"}, MaxQueueTime: time.Minute})
+	if !waiting(s, "after") {
+		t.Fatal("dependent task is not waiting")
+	}
+
+	c.Advance(time.Minute)
+	s.expireQueued()
+	if waiting(s, "after") {
+		t.Error("expired task still waiting on its dependency")
+	}
+	if got := counts(s, "review"); got.Failed != 1 {
+		t.Errorf("tally = %+v, want the task failed", got)
+	}
+}
+
+func TestQueueTimeStopsOnceDispatched(t *testing.T) {
+	s, c := newTestScheduler(t, testConfig())
+	mustSchedule(t, s, &ScheduledTask{ID: "slow", Type: "review", MaxQueueTime: time.Second})
+	s.processQueue()
+
+	c.Advance(time.Hour)
+	s.expireQueued()
+	if got := stateOf(t, s, "slow"); got != TaskRunning {
+		t.Errorf("running task is %s, want its queue time no longer applied", got)
+	}
+}
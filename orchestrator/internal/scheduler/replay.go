@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Decision actions recorded by the scheduler
+const (
+	DecisionDispatch = "dispatch"
+	DecisionWait     = "wait"
+	DecisionExpire   = "expire"
+	DecisionRetry    = "retry"
+	DecisionNoRoute  = "no_route"
+	DecisionRequeue  = "requeue"
+	DecisionSuspend  = "suspend"
+	DecisionResume   = "resume"
+)
+
+// Decision is one choice the scheduler made about a task. A sequence of
+// decisions is enough to reconstruct dispatch order after the fact.
+type Decision struct {
+	Seq      uint64       `json:"seq"`
+	Time     time.Time    `json:"time"`
+	Action   string       `json:"action"`
+	TaskID   string       `json:"task_id"`
+	Priority TaskPriority `json:"priority"`
+	Retries  int          `json:"retries"`
+}
+
+// Recorder receives every scheduling decision in order
+type Recorder interface {
+	Record(d Decision)
+}
+
+// JSONRecorder writes decisions as JSON lines
+type JSONRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONRecorder creates a recorder writing to w
+func NewJSONRecorder(w io.Writer) *JSONRecorder {
+	return &JSONRecorder{enc: json.NewEncoder(w)}
+}
+
+// Record appends a decision to the log. The first write error is kept
+// and later decisions are dropped.
+func (r *JSONRecorder) Record(d Decision) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(d)
+}
+
+// Err returns the first write error, if any
+func (r *JSONRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// ReadDecisions loads a decision log written by JSONRecorder
+func ReadDecisions(r io.Reader) ([]Decision, error) {
+	var out []Decision
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var d Decision
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			return nil, fmt.Errorf("decision log line %d: %w", line, err)
+		}
+		out = append(out, d)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Replayer checks a live run against a recorded decision log and keeps
+// the first point where the two diverge
+type Replayer struct {
+	mu       sync.Mutex
+	expected []Decision
+	pos      int
+	err      error
+}
+
+// NewReplayer creates a recorder that verifies decisions against expected
+func NewReplayer(expected []Decision) *Replayer {
+	return &Replayer{expected: expected}
+}
+
+// Record compares the decision with the next recorded one
+func (r *Replayer) Record(d Decision) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	if r.pos >= len(r.expected) {
+		r.err = fmt.Errorf("decision %d: unexpected %s %s beyond end of log", d.Seq, d.Action, d.TaskID)
+		return
+	}
+
+	want := r.expected[r.pos]
+	r.pos++
+	// Times are only comparable under an injected clock, so order and
+	// outcome are what must match
+	if want.Action != d.Action || want.TaskID != d.TaskID || want.Retries != d.Retries {
+		r.err = fmt.Errorf("decision %d: got %s %s (retry %d), recorded %s %s (retry %d)",
+			d.Seq, d.Action, d.TaskID, d.Retries, want.Action, want.TaskID, want.Retries)
+	}
+}
+
+// Err reports the first divergence, or an error if the run stopped short
+// of the recorded log
+func (r *Replayer) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+	if r.pos < len(r.expected) {
+		return fmt.Errorf("replay stopped after %d of %d decisions", r.pos, len(r.expected))
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// decisionLog keeps every decision in memory
+type decisionLog struct {
+	mu        sync.Mutex
+	decisions []Decision
+}
+
+func (l *decisionLog) Record(d Decision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.decisions = append(l.decisions, d)
+}
+
+// dispatches returns the IDs of the tasks dispatched, in order
+func (l *decisionLog) dispatches() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var ids []string
+	for _, d := range l.decisions {
+		if d.Action == DecisionDispatch {
+			ids = append(ids, d.TaskID)
+		}
+	}
+	return ids
+}
+
+// runFlaky runs tasks that each fail their first run, two at a time, so
+// the order they are retried in comes down to their jittered delays
+func runFlaky(t *testing.T, seed int64, rec Recorder) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 2
+	cfg.Orchestrator.RetryJitter = JitterFull
+	s, c := newTestScheduler(t, cfg)
+	s.SetRand(rand.New(rand.NewSource(seed)))
+	s.SetRecorder(rec)
+
+	for i := 0; i < 6; i++ {
+		mustSchedule(t, s, &ScheduledTask{ID: fmt.Sprintf("t%d", i), Priority: TaskPriority(i % 2)})
+	}
+	for round := 0; round < 50; round++ {
+		s.processQueue()
+		s.mu.Lock()
+		var ids []string
+		for id := range s.running {
+			ids = append(ids, id)
+		}
+		retries := make(map[string]int, len(ids))
+		for _, id := range ids {
+			retries[id] = s.running[id].Retries
+		}
+		idle := len(ids) == 0 && s.queue.Len() == 0
+		s.mu.Unlock()
+		if idle {
+			return
+		}
+
+		slices.Sort(ids)
+		for _, id := range ids {
+			var err error
+			if retries[id] == 0 {
+				err = errors.New("flaky")
+			}
+			s.completeTask(id, nil, err)
+		}
+		c.Advance(time.Second)
+	}
+	t.Fatal("tasks did not finish in 50 rounds")
+}
+
+func TestSameSeedAndClockGiveSameDispatchOrder(t *testing.T) {
+	first, second := &decisionLog{}, &decisionLog{}
+	runFlaky(t, 42, first)
+	runFlaky(t, 42, second)
+
+	if len(first.dispatches()) != 12 {
+		t.Fatalf("got %d dispatches, want a run and a retry of each of 6 tasks", len(first.dispatches()))
+	}
+	if !slices.Equal(first.decisions, second.decisions) {
+		t.Errorf("runs diverged:\n%v\n%v", first.decisions, second.decisions)
+	}
+}
+
+func TestReplayerFollowsRecordedLog(t *testing.T) {
+	var buf bytes.Buffer
+	rec := NewJSONRecorder(&buf)
+	runFlaky(t, 7, rec)
+	if err := rec.Err(); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+
+	recorded, err := ReadDecisions(&buf)
+	if err != nil {
+		t.Fatalf("ReadDecisions: %v", err)
+	}
+	replay := NewReplayer(recorded)
+	runFlaky(t, 7, replay)
+	if err := replay.Err(); err != nil {
+		t.Errorf("replay with the same seed: %v", err)
+	}
+}
+
+func TestReplayerReportsDivergence(t *testing.T) {
+	replay := NewReplayer([]Decision{
+		{Action: DecisionDispatch, TaskID: "a"},
+		{Action: DecisionDispatch, TaskID: "b"},
+	})
+	replay.Record(Decision{Seq: 1, Action: DecisionDispatch, TaskID: "a"})
+	replay.Record(Decision{Seq: 2, Action: DecisionDispatch, TaskID: "c"})
+	if err := replay.Err(); err == nil {
+		t.Error("replay accepted a different task")
+	}
+
+	short := NewReplayer([]Decision{{Action: DecisionDispatch, TaskID: "a"}})
+	if err := short.Err(); err == nil {
+		t.Error("replay that stopped short reported no error")
+	}
+}
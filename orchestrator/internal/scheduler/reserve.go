@@ -0,0 +1,196 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// Reservation errors
+var (
+	ErrInvalidReservation = errors.New("invalid reservation")
+	ErrReservationTimeout = errors.New("reservation not filled in time")
+)
+
+// reservation is a group of tasks that start together once enough
+// concurrency slots are free for all of them
+type reservation struct {
+	tasks    []*ScheduledTask
+	made     time.Time
+	deadline time.Time // zero waits indefinitely
+}
+
+// Reserve schedules tasks as a group that is dispatched in a single pass
+// once enough concurrency slots are free for all of them, rather than
+// trickling out as slots free up. While a reservation is pending, slots
+// freed by finishing tasks are held for it instead of going to the queue,
+// so a stream of smaller tasks cannot starve it; reservations are filled
+// in the order they were made. Running tasks always give their slots back,
+// so a reservation no larger than the concurrency limit is eventually
+// filled. Tasks with dependencies cannot be reserved, since holding slots
+// while a dependency waits for one would deadlock. A reservation not
+// filled within timeout (0 = no limit) fails its tasks with
+// ErrReservationTimeout. A reserved task's MaxQueueTime is not applied;
+// the timeout bounds the wait instead.
+func (s *Scheduler) Reserve(tasks []*ScheduledTask, timeout time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case len(tasks) == 0:
+		return fmt.Errorf("%w: no tasks", ErrInvalidReservation)
+	case len(tasks) > s.maxConcurrent:
+		return fmt.Errorf("%w: %d tasks exceed the concurrency limit of %d",
+			ErrInvalidReservation, len(tasks), s.maxConcurrent)
+	case timeout < 0:
+		return fmt.Errorf("%w: negative timeout", ErrInvalidReservation)
+	}
+	for _, task := range tasks {
+		if len(task.Dependencies) > 0 {
+			return fmt.Errorf("%w: task %s has dependencies", ErrInvalidReservation, task.ID)
+		}
+	}
+	if err := s.checkPinned(tasks); err != nil {
+		return err
+	}
+	if s.maxQueued > 0 && s.queue.Len()+s.reservedCount()+len(tasks) > s.maxQueued {
+		return ErrQueueFull
+	}
+
+	now := s.clock.Now()
+	res := &reservation{tasks: slices.Clone(tasks), made: now}
+	if timeout > 0 {
+		res.deadline = now.Add(timeout)
+	}
+	for _, task := range res.tasks {
+		task.ScheduledAt = now
+		task.State = TaskQueued
+		task.index = -1
+		if task.submitted.IsZero() {
+			task.submitted = now
+		}
+		s.defaultDeadline(task)
+		s.resolvePolicy(task)
+		s.emit(events.TaskScheduled, task.ID, "")
+	}
+	s.reservations = append(s.reservations, res)
+	s.notify()
+
+	s.logger.Debug("Slots reserved",
+		zap.Int("tasks", len(res.tasks)),
+		zap.Duration("timeout", timeout),
+	)
+	return nil
+}
+
+// releaseReservations dispatches pending reservations, oldest first, for
+// as long as enough slots are free for the next one. It reports whether
+// every reservation was released, leaving the remaining slots to the
+// queue. Callers hold s.mu.
+func (s *Scheduler) releaseReservations() bool {
+	for len(s.reservations) > 0 {
+		res := s.reservations[0]
+		if len(res.tasks) > s.maxConcurrent {
+			// Cannot ever fit; fail it rather than hold slots forever
+			s.reservations = s.reservations[1:]
+			s.failReservation(res, fmt.Errorf("%w: %d tasks exceed the concurrency limit of %d",
+				ErrInvalidReservation, len(res.tasks), s.maxConcurrent))
+			continue
+		}
+		// An adaptive limit below the group's size would never rise to it
+		// with nothing running, so the group may exceed it
+		if max(s.limit(), len(res.tasks))-s.currentCount < len(res.tasks) {
+			return false
+		}
+
+		s.reservations = s.reservations[1:]
+		now := s.clock.Now()
+		for _, task := range res.tasks {
+			if !task.Deadline.IsZero() && now.After(task.Deadline) {
+				s.expire(task)
+				continue
+			}
+			s.dispatch(task)
+		}
+		s.logger.Info("Reservation released",
+			zap.Int("tasks", len(res.tasks)),
+			zap.Duration("waited", now.Sub(res.made)),
+		)
+	}
+	return true
+}
+
+// expireReservations fails reservations still unfilled past their
+// timeout, giving their held slots back to the queue
+func (s *Scheduler) expireReservations() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	kept := s.reservations[:0]
+	expired := false
+	for _, res := range s.reservations {
+		if res.deadline.IsZero() || now.Before(res.deadline) {
+			kept = append(kept, res)
+			continue
+		}
+		expired = true
+		s.failReservation(res, fmt.Errorf("%w: waited %s for %d slots", ErrReservationTimeout,
+			now.Sub(res.made).Round(time.Second), len(res.tasks)))
+	}
+	clear(s.reservations[len(kept):])
+	s.reservations = kept
+	if expired {
+		s.notify()
+	}
+}
+
+// failReservation fails every task of a reservation already removed from
+// the pending list. Callers hold s.mu.
+func (s *Scheduler) failReservation(res *reservation, err error) {
+	s.logger.Warn("Reservation failed", zap.Int("tasks", len(res.tasks)), zap.Error(err))
+	for _, task := range res.tasks {
+		task.State = TaskFailed
+		s.record(DecisionExpire, task)
+		s.emit(events.TaskFailed, task.ID, err.Error())
+		s.stageFinished(task, nil, err.Error())
+		s.settle(task)
+	}
+}
+
+// reserved finds a task held in a reservation, returning the reservation
+// and the task's position in it. Callers hold s.mu.
+func (s *Scheduler) reserved(taskID string) (*reservation, int) {
+	for _, res := range s.reservations {
+		for i, task := range res.tasks {
+			if task.ID == taskID {
+				return res, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// dropReserved removes one task from a reservation, and the reservation
+// once it is empty. The smaller group may now fit. Callers hold s.mu.
+func (s *Scheduler) dropReserved(res *reservation, i int) {
+	res.tasks = slices.Delete(res.tasks, i, i+1)
+	if len(res.tasks) == 0 {
+		s.reservations = slices.DeleteFunc(s.reservations, func(r *reservation) bool { return r == res })
+	}
+	s.notify()
+}
+
+// reservedCount is the number of tasks held in reservations. Callers hold
+// s.mu.
+func (s *Scheduler) reservedCount() int {
+	n := 0
+	for _, res := range s.reservations {
+		n += len(res.tasks)
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// matrix is a three-task test matrix to reserve
+func matrix() []*ScheduledTask {
+	return []*ScheduledTask{
+		{ID: "go1.21", Type: "test"},
+		{ID: "go1.22", Type: "test"},
+		{ID: "go1.23", Type: "test"},
+	}
+}
+
+// newBusyScheduler has four slots, three of them taken
+func newBusyScheduler(t *testing.T) (*Scheduler, *ManualClock) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 4
+	s, c := newTestScheduler(t, cfg)
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "busy1", Type: "review"},
+		&ScheduledTask{ID: "busy2", Type: "review"},
+		&ScheduledTask{ID: "busy3", Type: "review"},
+	)
+	s.processQueue()
+	return s, c
+}
+
+func TestReservationReleasesTasksTogether(t *testing.T) {
+	s, _ := newBusyScheduler(t)
+	if err := s.Reserve(matrix(), 0); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	mustSchedule(t, s, &ScheduledTask{ID: "latecomer", Type: "review"})
+
+	// One slot is free but the group needs three; it holds it, and the
+	// task queued behind it does not take it either
+	s.processQueue()
+	if got := running(s); got != 3 {
+		t.Fatalf("running = %d, want the free slot held", got)
+	}
+
+	s.completeTask("busy1", nil, nil)
+	s.processQueue()
+	if got := stateOf(t, s, "go1.21"); got != TaskQueued {
+		t.Fatalf("reserved task is %s with two slots free, want queued", got)
+	}
+
+	s.completeTask("busy2", nil, nil)
+	s.processQueue()
+	for _, task := range matrix() {
+		if got := stateOf(t, s, task.ID); got != TaskRunning {
+			t.Errorf("%s is %s, want the group started together", task.ID, got)
+		}
+	}
+	if got := stateOf(t, s, "latecomer"); got != TaskQueued {
+		t.Errorf("latecomer is %s, want queued behind the group", got)
+	}
+
+	// With the reservation filled, the queue gets freed slots again
+	s.completeTask("busy3", nil, nil)
+	s.processQueue()
+	if got := stateOf(t, s, "latecomer"); got != TaskRunning {
+		t.Errorf("latecomer is %s, want running", got)
+	}
+}
+
+func TestUnfilledReservationTimesOut(t *testing.T) {
+	s, c := newBusyScheduler(t)
+	if err := s.Reserve(matrix(), 30*time.Second); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	mustSchedule(t, s, &ScheduledTask{ID: "latecomer", Type: "review"})
+
+	c.Advance(29 * time.Second)
+	s.expireReservations()
+	if got := stateOf(t, s, "go1.21"); got != TaskQueued {
+		t.Fatalf("reserved task is %s before the timeout, want queued", got)
+	}
+
+	c.Advance(time.Second)
+	s.expireReservations()
+	if got := counts(s, "test"); got.Failed != 3 {
+		t.Errorf("tally = %+v, want the whole group failed", got)
+	}
+	s.processQueue()
+	if got := stateOf(t, s, "latecomer"); got != TaskRunning {
+		t.Errorf("latecomer is %s, want the held slot given back", got)
+	}
+}
+
+func TestInvalidReservationsRejected(t *testing.T) {
+	s, _ := newBusyScheduler(t)
+
+	tooMany := append(matrix(), &ScheduledTask{ID: "go1.24"}, &ScheduledTask{ID: "tip"})
+	if err := s.Reserve(tooMany, 0); !errors.Is(err, ErrInvalidReservation) {
+		t.Errorf("group over the concurrency limit: err = %v", err)
+	}
+	if err := s.Reserve([]*ScheduledTask{{ID: "after", Dependencies: []string{"busy1"}}}, 0); !errors.Is(err, ErrInvalidReservation) {
+		t.Errorf("group with dependencies: err = %v", err)
+	}
+	if err := s.Reserve(nil, 0); !errors.Is(err, ErrInvalidReservation) {
+		t.Errorf("empty group: err = %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// ReasonAgentRestart is the requeue reason for tasks taken off an agent
+// that is being restarted
+const ReasonAgentRestart = "agent-restart"
+
+// RequeueAgent takes every running task routed to the named agent off it
+// and queues it again, returning the requeued task IDs. The interrupted
+// run does not count against the task's retry budget. instance, when
+// set, is excluded from the tasks for the retry cooldown so they are
+// rerouted to another instance where one exists; with exclusion disabled,
+// or no other instance, they wait for the agent to come back. Callers
+// drain the agent first so nothing new is routed to it meanwhile.
+func (s *Scheduler) RequeueAgent(agent, instance, reason string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tasks []*ScheduledTask
+	for _, task := range s.running {
+		if slices.Contains(task.Agents, agent) {
+			tasks = append(tasks, task)
+		}
+	}
+	// In ID order, so decision logs replay deterministically
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+
+	ids := make([]string, 0, len(tasks))
+	now := s.clock.Now()
+	for _, task := range tasks {
+		delete(s.running, task.ID)
+		s.currentCount--
+		go s.releaseLease(task.ID, task.attempt)
+
+		s.span(task, "run", task.started, now, map[string]string{
+			"outcome": "requeued",
+			"reason":  reason,
+		})
+		if instance != "" {
+			s.exclude(task, instance)
+		}
+		task.State = TaskQueued
+		task.ScheduledAt = now
+		s.rerouteRetry(task)
+		s.push(task)
+		s.record(DecisionRequeue, task)
+		s.emit(events.TaskRequeued, task.ID, reason)
+		ids = append(ids, task.ID)
+	}
+
+	s.logger.Info("Agent tasks requeued",
+		zap.String("agent", agent),
+		zap.String("reason", reason),
+		zap.Strings("tasks", ids),
+	)
+	return ids
+}
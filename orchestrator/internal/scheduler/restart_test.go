@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+)
+
+func TestRestartRequeuesInFlightTasksElsewhere(t *testing.T) {
+	s, _, _ := newExcludingScheduler(t)
+	bus := events.New(100)
+	s.SetEvents(bus)
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "t1", Type: "code_write", Agents: []string{"dev"}},
+		&ScheduledTask{ID: "t2", Type: "code_write", Agents: []string{"dev"}},
+		&ScheduledTask{ID: "other", Type: "review", Agents: []string{"review"}},
+	)
+	s.processQueue()
+
+	requeued := s.RequeueAgent("dev", "dev-1", ReasonAgentRestart)
+	if !slices.Equal(requeued, []string{"t1", "t2"}) {
+		t.Fatalf("requeued %v, want the dev agent's tasks", requeued)
+	}
+	if got := stateOf(t, s, "other"); got != TaskRunning {
+		t.Errorf("task on another agent is %s, want left running", got)
+	}
+	for _, id := range requeued {
+		task, ok := queuedTask(s, id)
+		if !ok {
+			t.Fatalf("%s not queued", id)
+		}
+		if !slices.Equal(task.Agents, []string{"dev-canary"}) {
+			t.Errorf("%s routed to %v, want the other instance", id, task.Agents)
+		}
+	}
+
+	n := 0
+	for _, e := range bus.Since(0) {
+		if e.Type == events.TaskRequeued && e.Message == ReasonAgentRestart {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Errorf("%d requeue events, want 2 with the restart reason", n)
+	}
+
+	// They are dispatched again, and the restart did not use a retry
+	s.processQueue()
+	for _, id := range requeued {
+		snap, _ := s.Task(id)
+		if snap.State != TaskRunning || snap.Retries != 0 {
+			t.Errorf("%s is %s after %d retries, want running with its budget intact", id, snap.State, snap.Retries)
+		}
+	}
+}
+
+func TestLateResultFromRestartedAgentRejected(t *testing.T) {
+	s, _, _ := newExcludingScheduler(t)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "code_write", Agents: []string{"dev"}})
+	s.processQueue()
+
+	s.RequeueAgent("dev", "dev-1", ReasonAgentRestart)
+	s.processQueue()
+
+	// The interrupted run reports after all
+	if err := s.ReportResult("dev", Result{TaskID: "t1", Status: ResultCompleted, Attempt: 1, Instance: "dev-1"}); err == nil {
+		t.Error("result from the interrupted run accepted")
+	}
+	if got := stateOf(t, s, "t1"); got != TaskRunning {
+		t.Errorf("task is %s, want still running on the other instance", got)
+	}
+}
+
+func TestRestartWithNothingRunning(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	if got := s.RequeueAgent("dev", "dev-1", ReasonAgentRestart); len(got) != 0 {
+		t.Errorf("requeued %v from an idle agent", got)
+	}
+}
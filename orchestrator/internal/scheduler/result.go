@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"github.com/krigsexe/odin/orchestrator/internal/schema"
+	"go.uber.org/zap"
+)
+
+// Result reporting errors
+var (
+	ErrUnknownTask   = errors.New("task is not running")
+	ErrNotOwner      = errors.New("agent does not own task")
+	ErrInvalidResult = errors.New("invalid result")
+	ErrStaleResult   = errors.New("result is for an earlier dispatch")
+)
+
+// Result statuses an agent may report
+const (
+	ResultCompleted = "completed"
+	ResultFailed    = "failed"
+)
+
+// Result is an agent's report of how a dispatched task ended
+type Result struct {
+	TaskID   string                 `json:"task_id"`
+	Status   string                 `json:"status"`
+	Output   map[string]interface{} `json:"result,omitempty"`
+	Usage    llm.Usage              `json:"usage"`
+	Error    string                 `json:"error,omitempty"`
+	Category string                 `json:"category,omitempty"`
+
+	// Instance is the discovery ID of the agent process that ran the
+	// task. A failed instance is kept off the task's retries for a while.
+	Instance string `json:"instance,omitempty"`
+
+	// Cost is what the agent's own LLM calls for the run cost, charged
+	// with Usage to the task's budget
+	Cost float64 `json:"cost,omitempty"`
+
+	// Attempt is the dispatch the result is for, as it was sent. A result
+	// for any other dispatch, or from an instance other than the one the
+	// dispatch went to, is refused.
+	Attempt int `json:"attempt"`
+
+	// DeliveryID identifies this delivery of the result. A redelivery
+	// with the same ID is acknowledged but not processed again.
+	DeliveryID string `json:"delivery_id,omitempty"`
+}
+
+// ReportResult completes a running task on behalf of the agent that ran
+// it. Only an agent the task was routed to may report, for the dispatch
+// now running; a task routed to no particular agent accepts any. A task
+// whose type aggregates results completes once every agent it was routed
+// to has reported. A redelivered result is acknowledged with a nil error
+// and ignored.
+func (s *Scheduler) ReportResult(agent string, r Result) error {
+	if r.Status != ResultCompleted && r.Status != ResultFailed {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidResult, r.Status)
+	}
+	if !s.firstDelivery(context.Background(), r.TaskID, r.DeliveryID) {
+		return nil
+	}
+
+	s.mu.Lock()
+	task, exists := s.running[r.TaskID]
+	if !exists || task.State != TaskRunning {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownTask, r.TaskID)
+	}
+	if !task.ownedBy(agent) {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s does not own %s", ErrNotOwner, agent, r.TaskID)
+	}
+	if err := task.checkAttempt(agent, r); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	attempt := task.attempt
+	if r.Status == ResultFailed && r.Instance != "" {
+		s.exclude(task, r.Instance)
+	}
+	s.charge(task, &r)
+	var tokens int
+	var cost float64
+	if s.spend != nil {
+		tokens, cost = s.spend.TaskSpend(r.TaskID)
+	}
+	combined, ready := s.collect(task, agent, r)
+	s.mu.Unlock()
+
+	s.logger.Info("Agent reported result",
+		zap.String("id", r.TaskID),
+		zap.String("agent", agent),
+		zap.String("status", r.Status),
+		zap.Int("tokens", r.Usage.TotalTokens),
+		zap.Int("task_tokens", tokens),
+		zap.Float64("task_cost", cost),
+		zap.Bool("held", !ready),
+	)
+	if !ready {
+		return nil
+	}
+	return s.deliver(context.Background(), r.TaskID, attempt, combined.Output, combined.err())
+}
+
+// err is the task error a failed result carries, nil for a completed one
+func (r Result) err() error {
+	if r.Status != ResultFailed {
+		return nil
+	}
+	message := r.Error
+	if message == "" {
+		message = "agent reported failure"
+	}
+	return &TaskError{Category: r.Category, Message: message}
+}
+
+// checkAttempt refuses a result that is not for the task's running
+// dispatch: a late result from a run that timed out or was requeued, or
+// from an instance the agent has since replaced
+func (t *ScheduledTask) checkAttempt(agent string, r Result) error {
+	if r.Attempt != t.attempt {
+		return fmt.Errorf("%w: %s reported attempt %d of %s, which is on attempt %d",
+			ErrStaleResult, agent, r.Attempt, t.ID, t.attempt)
+	}
+	if instance := t.instances[agent]; instance != "" && r.Instance != instance {
+		return fmt.Errorf("%w: attempt %d of %s went to instance %s of %s, not %q",
+			ErrStaleResult, t.attempt, t.ID, instance, agent, r.Instance)
+	}
+	return nil
+}
+
+// ownedBy reports whether an agent may report the task's result
+func (t *ScheduledTask) ownedBy(agent string) bool {
+	if len(t.Agents) == 0 {
+		return true
+	}
+	for _, a := range t.Agents {
+		if a == agent {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectResult records each way a result failed its task's schema in the
+// task's log, where the attempt's output can be inspected. Callers hold
+// s.mu.
+func (s *Scheduler) rejectResult(task *ScheduledTask, violations []schema.Violation) {
+	now := s.clock.Now()
+	for _, v := range violations {
+		s.logs.append(task.ID, LogLine{Time: now, Level: "error", Line: "result schema: " + v.String()})
+	}
+	s.logger.Warn("Result does not match schema",
+		zap.String("id", task.ID),
+		zap.Int("violations", len(violations)),
+	)
+}
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/schema"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// scoreSchema requires an integer score from 0 to 10
+func scoreSchema(t *testing.T) *schema.Schema {
+	t.Helper()
+	s, err := schema.Compile(map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"score"},
+		"properties": map[string]interface{}{
+			"score": map[string]interface{}{"type": "integer", "minimum": 0.0, "maximum": 10.0},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestConformingResultCompletes(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	mustSchedule(t, s, &ScheduledTask{ID: "graded", Type: "review", ResultSchema: scoreSchema(t)})
+	s.processQueue()
+	s.completeTask("graded", map[string]interface{}{"score": 7.0}, nil)
+
+	if got := stateOf(t, s, "graded"); got != TaskCompleted {
+		t.Errorf("task is %s, want completed", got)
+	}
+}
+
+func TestMalformedResultRetriedThenFailed(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.TaskLogs = config.TaskLogConfig{MaxLines: 10, MaxBytes: 1 << 10, MaxTasks: 10}
+	s, _ := newTestScheduler(t, cfg)
+	dlq := NewMemoryDeadLetterQueue()
+	s.SetDeadLetters(dlq)
+	mustSchedule(t, s, &ScheduledTask{ID: "graded", Type: "review", ResultSchema: scoreSchema(t), Retry: Retries(1)})
+
+	bad := map[string]interface{}{"score": 12.5}
+	s.processQueue()
+	s.completeTask("graded", bad, nil)
+	if got := stateOf(t, s, "graded"); got != TaskQueued {
+		t.Fatalf("after a malformed result the task is %s, want queued for a retry", got)
+	}
+	want := []string{
+		"result schema: $.score: expected integer, got number",
+	}
+	if got := lines(s.Logs("graded")); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+
+	s.processQueue()
+	s.completeTask("graded", map[string]interface{}{}, nil)
+	if got := counts(s, "review"); got.Failed != 1 {
+		t.Fatalf("tally = %+v, want the task failed once retries ran out", got)
+	}
+
+	var letters []DeadLetter
+	waitUntil(t, "the dead letter", func() bool {
+		letters, _ = dlq.All(context.Background())
+		return len(letters) > 0
+	})
+	if letters[0].Category != CategoryInvalid {
+		t.Errorf("category = %q, want %q", letters[0].Category, CategoryInvalid)
+	}
+	if want := `result does not match schema: $: missing required property "score"`; !strings.Contains(letters[0].Error, want) {
+		t.Errorf("error = %q, want it to contain %q", letters[0].Error, want)
+	}
+}
@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+)
+
+// Error categories agents report with failed completions
+const (
+	CategoryTimeout     = "timeout"
+	CategoryRateLimit   = "rate_limit"
+	CategoryUnavailable = "unavailable"
+	CategoryInvalid     = "invalid"
+	CategoryInternal    = "internal"
+	CategoryBudget      = "budget"
+	CategoryDependency  = "dependency" // a dependency failed; set by the scheduler
+	CategoryUnknown     = "unknown"
+)
+
+// DefaultMaxRetries applies when a task's policy leaves MaxRetries unset
+const DefaultMaxRetries = 3
+
+// TaskError is a task failure with the category its agent reported
+type TaskError struct {
+	Category string
+	Message  string
+}
+
+func (e *TaskError) Error() string {
+	return e.Message
+}
+
+// categoryOf returns the category of a task failure
+func categoryOf(err error) string {
+	var taskErr *TaskError
+	if errors.As(err, &taskErr) && taskErr.Category != "" {
+		return taskErr.Category
+	}
+	if errors.Is(err, llm.ErrBudgetExceeded) {
+		return CategoryBudget
+	}
+	return CategoryUnknown
+}
+
+// RetryPolicy controls how a failed task is retried. The zero value means
+// "use the defaults" for every field; MaxRetries is a pointer so that an
+// explicit 0 (never retry) can be told apart from unset.
+type RetryPolicy struct {
+	MaxRetries     *int          `json:"max_retries,omitempty" yaml:"max_retries"`
+	InitialBackoff time.Duration `json:"initial_backoff,omitempty" yaml:"initial_backoff"`
+	Multiplier     float64       `json:"multiplier,omitempty" yaml:"multiplier"`
+	MaxBackoff     time.Duration `json:"max_backoff,omitempty" yaml:"max_backoff"`
+
+	// RetryOn lists retryable error categories; empty retries every failure
+	RetryOn []string `json:"retry_on,omitempty" yaml:"retry_on"`
+
+	// Priority moves the task's priority on each retry; nil keeps it
+	Priority *PriorityAdjustment `json:"priority,omitempty" yaml:"priority"`
+}
+
+// PriorityAdjustment changes a task's priority each time it is retried,
+// keeping it between PriorityLow and PriorityCritical
+type PriorityAdjustment struct {
+	// Step is added on each retry after a failure in one of the On
+	// categories (empty = any); negative demotes
+	Step int      `json:"step,omitempty" yaml:"step"`
+	On   []string `json:"on,omitempty" yaml:"on"`
+
+	// UrgentWithin promotes a retry to PriorityCritical instead once the
+	// task's deadline is this close when the retry comes due
+	UrgentWithin time.Duration `json:"urgent_within,omitempty" yaml:"urgent_within"`
+}
+
+// Retries returns a policy with MaxRetries set to n
+func Retries(n int) RetryPolicy {
+	return RetryPolicy{MaxRetries: &n}
+}
+
+// maxRetries resolves the retry budget
+func (p RetryPolicy) maxRetries() int {
+	if p.MaxRetries == nil {
+		return DefaultMaxRetries
+	}
+	if *p.MaxRetries < 0 {
+		return 0
+	}
+	return *p.MaxRetries
+}
+
+// resolved returns the policy with every default filled in
+func (p RetryPolicy) resolved() RetryPolicy {
+	retries := p.maxRetries()
+	out := p
+	out.MaxRetries = &retries
+	if out.InitialBackoff <= 0 {
+		out.InitialBackoff = time.Second
+	}
+	if out.Multiplier <= 0 {
+		out.Multiplier = 2
+	}
+	if out.MaxBackoff <= 0 {
+		out.MaxBackoff = time.Minute
+	}
+	return out
+}
+
+// backoff returns the delay before the given retry (1-based), before jitter
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	r := p.resolved()
+	delay, multiplier, limit := r.InitialBackoff, r.Multiplier, r.MaxBackoff
+
+	for i := 1; i < retry && delay < limit; i++ {
+		delay = time.Duration(float64(delay) * multiplier)
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// retryable reports whether a failure falls in a retryable category. A
+// task over its budget is never retried; its spend carries over.
+func (p RetryPolicy) retryable(err error) bool {
+	category := categoryOf(err)
+	if category == CategoryBudget {
+		return false
+	}
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+	for _, c := range p.RetryOn {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// retryPriority returns the priority a task retried after err runs at.
+// due is when the retry comes due; a zero deadline never counts as near.
+func (p RetryPolicy) retryPriority(current TaskPriority, deadline, due time.Time, err error) TaskPriority {
+	adj := p.Priority
+	if adj == nil {
+		return current
+	}
+	if adj.UrgentWithin > 0 && !deadline.IsZero() && deadline.Sub(due) <= adj.UrgentWithin {
+		return PriorityCritical
+	}
+	if len(adj.On) > 0 && !slices.Contains(adj.On, categoryOf(err)) {
+		return current
+	}
+	return min(max(current+TaskPriority(adj.Step), PriorityLow), PriorityCritical)
+}
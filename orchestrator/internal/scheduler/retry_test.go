@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyExplicitZeroIsNotUnset(t *testing.T) {
+	var unset, zero RetryPolicy
+	if err := json.Unmarshal([]byte(`{"initial_backoff": 1000000000}`), &unset); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"max_retries": 0}`), &zero); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := unset.maxRetries(); got != DefaultMaxRetries {
+		t.Errorf("unset max_retries resolves to %d, want the default %d", got, DefaultMaxRetries)
+	}
+	if zero.MaxRetries == nil {
+		t.Fatal("explicit max_retries: 0 decoded as unset")
+	}
+	if got := zero.maxRetries(); got != 0 {
+		t.Errorf("explicit max_retries: 0 resolves to %d, want 0", got)
+	}
+	if got := Retries(-2).maxRetries(); got != 0 {
+		t.Errorf("negative max_retries resolves to %d, want 0", got)
+	}
+}
+
+func TestExplicitZeroRetriesFailsAtOnce(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "never", Type: "once", Retry: Retries(0)},
+		&ScheduledTask{ID: "default", Type: "again"},
+	)
+	s.processQueue()
+	s.completeTask("never", nil, errors.New("boom"))
+	s.completeTask("default", nil, errors.New("boom"))
+
+	if got := counts(s, "once"); got.Failed != 1 {
+		t.Errorf("task with no retries: tally %+v, want failed", got)
+	}
+	if got := stateOf(t, s, "default"); got != TaskQueued {
+		t.Errorf("task with the default policy is %s, want queued for a retry", got)
+	}
+}
+
+func TestRetryPolicyRetryOnAndBackoff(t *testing.T) {
+	p := RetryPolicy{RetryOn: []string{CategoryTimeout}, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	if !p.retryable(&TaskError{Category: CategoryTimeout}) {
+		t.Error("timeout not retried though listed")
+	}
+	if p.retryable(&TaskError{Category: CategoryInvalid}) {
+		t.Error("invalid retried though not listed")
+	}
+	if (RetryPolicy{}).retryable(&TaskError{Category: CategoryBudget}) {
+		t.Error("a task over budget was retried")
+	}
+
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := p.backoff(retry); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", retry, got, want)
+		}
+	}
+}
+
+// priorityOf returns a task's priority as the scheduler holds it
+func priorityOf(t *testing.T, s *Scheduler, id string) TaskPriority {
+	t.Helper()
+	task, ok := s.Task(id)
+	if !ok {
+		t.Fatalf("task %s not found", id)
+	}
+	return task.Priority
+}
+
+func TestRetryCarriesPriorityAdjustment(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	s, c := newTestScheduler(t, cfg)
+	policy := Retries(5)
+	policy.InitialBackoff = time.Second
+	policy.Priority = &PriorityAdjustment{Step: -1, On: []string{CategoryTimeout}}
+	mustSchedule(t, s, &ScheduledTask{ID: "flaky", Priority: PriorityHigh, Retry: policy})
+
+	fail := func(category string, want TaskPriority) {
+		t.Helper()
+		s.processQueue()
+		s.completeTask("flaky", nil, &TaskError{Category: category, Message: "failed"})
+		if got := priorityOf(t, s, "flaky"); got != want {
+			t.Fatalf("after a %s failure priority = %d, want %d", category, got, want)
+		}
+		c.Advance(time.Minute)
+	}
+	fail(CategoryTimeout, PriorityNormal)
+	fail(CategoryInvalid, PriorityNormal) // not listed: kept
+	fail(CategoryTimeout, PriorityLow)
+	fail(CategoryTimeout, PriorityLow) // never below low
+
+	// The demoted retry queues behind fresh work
+	mustSchedule(t, s, &ScheduledTask{ID: "fresh", Priority: PriorityNormal})
+	if got := dispatchOrder(t, s, 2); got[0] != "fresh" || got[1] != "flaky" {
+		t.Errorf("dispatch order = %v, want fresh ahead of the demoted retry", got)
+	}
+}
+
+func TestRetryPriority(t *testing.T) {
+	deadline := epoch.Add(time.Hour)
+	promote := RetryPolicy{Priority: &PriorityAdjustment{Step: 1}}
+	urgent := RetryPolicy{Priority: &PriorityAdjustment{Step: -1, UrgentWithin: 10 * time.Minute}}
+	timeout := &TaskError{Category: CategoryTimeout}
+
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		current  TaskPriority
+		deadline time.Time
+		due      time.Time
+		want     TaskPriority
+	}{
+		{"no adjustment", RetryPolicy{}, PriorityNormal, deadline, epoch, PriorityNormal},
+		{"promoted", promote, PriorityNormal, time.Time{}, epoch, PriorityHigh},
+		{"never above critical", promote, PriorityCritical, time.Time{}, epoch, PriorityCritical},
+		{"deadline far", urgent, PriorityNormal, deadline, epoch, PriorityLow},
+		{"deadline near", urgent, PriorityLow, deadline, deadline.Add(-5 * time.Minute), PriorityCritical},
+		{"no deadline", urgent, PriorityNormal, time.Time{}, epoch, PriorityLow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.retryPriority(tt.current, tt.deadline, tt.due, timeout); got != tt.want {
+				t.Errorf("retryPriority = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,13 +9,26 @@ package scheduler
 import (
 	"container/heap"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/krigsexe/odin/orchestrator/internal/clock"
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"github.com/krigsexe/odin/orchestrator/internal/schema"
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+	"github.com/krigsexe/odin/orchestrator/internal/trace"
 	"github.com/krigsexe/odin/orchestrator/pkg/config"
 	"go.uber.org/zap"
 )
 
+// ErrQueueFull is returned when the queue is at its configured bound
+var ErrQueueFull = errors.New("task queue is full")
+
 // TaskPriority levels
 type TaskPriority int
 
@@ -26,16 +39,144 @@ const (
 	PriorityCritical TaskPriority = 3
 )
 
+// TaskState tracks where a task is in its lifecycle
+type TaskState int
+
+const (
+	TaskQueued TaskState = iota
+	TaskRunning
+	TaskCompleted
+	TaskFailed
+	TaskCancelled
+	TaskSuspended // awaiting an external approval
+	TaskAwaiting  // finished its own run, awaiting the subtasks it waits for
+)
+
+var taskStateNames = map[TaskState]string{
+	TaskQueued:    "queued",
+	TaskRunning:   "running",
+	TaskCompleted: "completed",
+	TaskFailed:    "failed",
+	TaskCancelled: "cancelled",
+	TaskSuspended: "suspended",
+	TaskAwaiting:  "awaiting_children",
+}
+
+func (st TaskState) String() string {
+	if name, ok := taskStateNames[st]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the state by name
+func (st TaskState) MarshalText() ([]byte, error) {
+	return []byte(st.String()), nil
+}
+
+// UnmarshalText decodes a state name
+func (st *TaskState) UnmarshalText(text []byte) error {
+	for state, name := range taskStateNames {
+		if name == string(text) {
+			*st = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown task state: %s", text)
+}
+
 // ScheduledTask is a task with scheduling metadata
 type ScheduledTask struct {
-	ID          string
-	Priority    TaskPriority
-	ScheduledAt time.Time
-	Deadline    time.Time
-	Retries     int
-	MaxRetries  int
+	ID           string
+	Name         string
+	Type         string
+	Priority     TaskPriority
+	ScheduledAt  time.Time
+	Deadline     time.Time
+	Retries      int
+	Retry        RetryPolicy
 	Dependencies []string
-	index       int // For heap
+	State        TaskState
+
+	// Input carries values handed over from earlier pipeline stages
+	Input map[string]interface{}
+
+	// Agents the task was routed to; only they may report its result
+	Agents []string
+
+	// Capabilities the task was routed by, in place of its type's route;
+	// a retry that moves off a failed instance is routed by them again
+	Capabilities map[string]float64
+
+	// Traced records debug spans for the task, as decided at submit
+	Traced bool
+
+	// Excluded holds agent instances that failed the task, with when each
+	// may be routed the task again
+	Excluded map[string]time.Time
+
+	// MaxQueueTime fails the task if it has not been dispatched this long
+	// after submission (0 = no limit)
+	MaxQueueTime time.Duration
+
+	// Timeout fails a run that goes on this long, as a retryable timeout.
+	// Left zero, it is resolved from the agents' policies when scheduled.
+	Timeout time.Duration
+
+	// Settings are resolved outside the scheduler at submission, such as
+	// the provider chain and sandbox, and reported in its EffectiveConfig
+	Settings map[string]interface{}
+
+	// ResultSchema rejects a result that does not match it; nil for none
+	ResultSchema *schema.Schema
+
+	// PinnedInstance is the only orchestrator instance that may dispatch
+	// the task; empty for any
+	PinnedInstance string
+
+	// Fallback takes the task instead of Agents if they are still
+	// saturated once agents.prefer_wait has passed; nil for none
+	Fallback []string
+
+	// Parent is the task whose agent submitted this one as a subtask;
+	// empty for a top-level task
+	Parent string
+
+	// Payload is the task as submitted, published to its agents when it
+	// is dispatched
+	Payload json.RawMessage
+
+	index     int          // For heap
+	order     uint64       // Push order, breaks ties deterministically
+	unmet     int          // Dependencies still outstanding while waiting
+	inherited TaskPriority // Highest priority lent by a dependent
+	started   time.Time    // Last dispatch, for the run span
+	orphaned  time.Time    // When the task was first seen with no live agent
+	starved   bool         // Starvation already reported for this wait
+	submitted time.Time    // First scheduled, for MaxQueueTime
+	ran       bool         // Has been dispatched at least once
+
+	// attempt numbers the task's dispatches, requeues included, and
+	// instances holds the agent instance each agent was running then, ""
+	// for unknown. A result must name both to be accepted.
+	attempt   int
+	instances map[string]string
+
+	// lastDelay is the delay before the latest retry, for decorrelated jitter
+	lastDelay time.Duration
+
+	// results are this run's agent results held for aggregation
+	results map[string]Result
+
+	// ackTimeout bounds the wait for an agent to acknowledge the latest
+	// dispatch, resolved when dispatched (0 = none); acked once it has
+	ackTimeout time.Duration
+	acked      bool
+
+	// suspendedAt is when the task was last suspended for approval, and
+	// suspendTimeout how long it may wait for a decision (0 = forever)
+	suspendedAt    time.Time
+	suspendTimeout time.Duration
 }
 
 // TaskQueue is a priority queue of tasks
@@ -43,12 +184,18 @@ type TaskQueue []*ScheduledTask
 
 func (pq TaskQueue) Len() int { return len(pq) }
 
-func (pq TaskQueue) Less(i, j int) bool {
-	// Higher priority first, then earlier scheduled time
-	if pq[i].Priority != pq[j].Priority {
-		return pq[i].Priority > pq[j].Priority
+func (pq TaskQueue) Less(i, j int) bool { return byPriority(pq[i], pq[j]) }
+
+// byPriority orders higher effective priority first, then earlier
+// scheduled time
+func byPriority(a, b *ScheduledTask) bool {
+	if pa, pb := a.EffectivePriority(), b.EffectivePriority(); pa != pb {
+		return pa > pb
 	}
-	return pq[i].ScheduledAt.Before(pq[j].ScheduledAt)
+	if !a.ScheduledAt.Equal(b.ScheduledAt) {
+		return a.ScheduledAt.Before(b.ScheduledAt)
+	}
+	return a.order < b.order
 }
 
 func (pq TaskQueue) Swap(i, j int) {
@@ -76,14 +223,82 @@ func (pq *TaskQueue) Pop() interface{} {
 
 // Scheduler manages task scheduling and execution
 type Scheduler struct {
-	config       *config.Config
-	logger       *zap.Logger
-	queue        TaskQueue
-	mu           sync.Mutex
-	running      map[string]*ScheduledTask
-	completed    map[string]bool
+	config        *config.Config
+	logger        *zap.Logger
+	queue         TaskQueue
+	queued        map[string]*ScheduledTask // Queued tasks by ID
+	mu            sync.Mutex
+	running       map[string]*ScheduledTask
+	completed     map[string]bool
 	maxConcurrent int
-	currentCount int
+	maxQueued     int
+	currentCount  int
+	deduper       Deduper
+	events        *events.Bus
+	clock         Clock
+	rng           *rand.Rand
+	jitter        jitterFunc
+	recorder      Recorder
+	pushes        uint64
+	decisions     uint64
+	pipelines     map[string]*pipelineRun
+	stages        map[string]stageRef
+	waiting       map[string]*ScheduledTask   // Blocked tasks by ID
+	waitingOn     map[string][]*ScheduledTask // Blocked tasks by dependency
+	wake          chan struct{}
+	leases        LeaseStore
+	owner         string
+	leaseTTL      time.Duration
+	logs          *logBuffer
+	traces        *trace.Recorder
+	routable      RouteCheck
+	noRouteGrace  time.Duration
+	starvedAfter  time.Duration
+	reroute       Reroute
+	exclusion     time.Duration
+	transformers  map[string]ResultTransformer // by task type
+	finished      map[string]*TypeCounts       // finished tasks by type
+	inbox         ResultInbox
+	transitions   TransitionStore
+	deadLetters   DeadLetterQueue
+	recovered     map[string]bool // tasks finished from the inbox at startup
+	reservations  []*reservation  // groups held for slots, oldest first
+	invariants    bool            // verify the queue heap after changes
+
+	// All-or-nothing groups, and the group of each member task
+	groups  map[string]*groupRun
+	members map[string]memberRef
+
+	// Dispatch-time settings, and their task IDs least recently dispatched first
+	effective      map[string]EffectiveConfig
+	effectiveOrder []string
+
+	// drained suspends dispatch while the queue is saved for a restart
+	drained bool
+
+	// adaptive moves the concurrency limit with run latency; nil when off
+	adaptive *adaptiveLimit
+
+	// instanceOf finds the instance that failed to acknowledge a dispatch
+	instanceOf InstanceOf
+
+	// busy finds agents at capacity, for tasks waiting on a preferred agent
+	busy RouteCheck
+
+	// edf orders the queue earliest deadline first rather than by priority
+	edf bool
+
+	// Tasks suspended pending an external approval, by ID
+	suspended map[string]*ScheduledTask
+
+	// Subtasks spawned under each parent task, by parent ID
+	families map[string]*family
+
+	// dispatcher delivers started tasks to their agents
+	dispatcher DispatchFunc
+
+	// spend charges reported usage to task budgets; nil when off
+	spend Spend
 }
 
 // New creates a new Scheduler instance
@@ -92,49 +307,214 @@ func New(cfg *config.Config, logger *zap.Logger) *Scheduler {
 		config:        cfg,
 		logger:        logger,
 		queue:         make(TaskQueue, 0),
+		queued:        make(map[string]*ScheduledTask),
 		running:       make(map[string]*ScheduledTask),
 		completed:     make(map[string]bool),
+		pipelines:     make(map[string]*pipelineRun),
+		stages:        make(map[string]stageRef),
+		groups:        make(map[string]*groupRun),
+		members:       make(map[string]memberRef),
+		families:      make(map[string]*family),
+		effective:     make(map[string]EffectiveConfig),
+		waiting:       make(map[string]*ScheduledTask),
+		waitingOn:     make(map[string][]*ScheduledTask),
+		finished:      make(map[string]*TypeCounts),
+		recovered:     make(map[string]bool),
+		wake:          make(chan struct{}, 1),
 		maxConcurrent: cfg.Orchestrator.MaxConcurrentTasks,
+		maxQueued:     cfg.Orchestrator.MaxQueuedTasks,
+		deduper:       NewMemoryDeduper(time.Duration(cfg.Orchestrator.CompletionDedupTTL)*time.Second, clock.Real),
+		clock:         clock.Real,
+		rng:           rand.New(rand.NewSource(time.Now().UnixNano())),
+		jitter:        jitterStrategy(cfg.Orchestrator.RetryJitter, logger),
+		logs:          newLogBuffer(cfg.Orchestrator.TaskLogs),
+		traces:        trace.NewRecorder(cfg.Orchestrator.Tracing),
+		noRouteGrace:  time.Duration(cfg.Orchestrator.NoRouteGrace) * time.Second,
+		starvedAfter:  time.Duration(cfg.Orchestrator.StarvationAfter) * time.Second,
+		exclusion:     time.Duration(cfg.Orchestrator.RetryExclusion) * time.Second,
+		invariants:    cfg.Orchestrator.CheckInvariants,
+		edf:           edfScheduling(cfg.Orchestrator.Scheduling, logger),
+		suspended:     make(map[string]*ScheduledTask),
 	}
-	heap.Init(&s.queue)
+	s.transformers = s.newTransformers(cfg.Orchestrator.ResultTransformers)
+	s.adaptive = newAdaptiveLimit(cfg.Orchestrator.AdaptiveConcurrency, s.maxConcurrent)
+	heap.Init(s.tasks())
 	return s
 }
 
 // Start begins the scheduler
 func (s *Scheduler) Start(ctx context.Context) error {
+	// Dispatch is driven by wakeups on schedule and completion; the ticker
+	// is only a safety net
+	interval := time.Duration(s.config.Orchestrator.TickInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = time.Second
+	}
+
 	s.logger.Info("Starting task scheduler",
 		zap.Int("max_concurrent", s.maxConcurrent),
+		zap.Duration("tick", interval),
 	)
 
-	ticker := time.NewTicker(100 * time.Millisecond)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	if s.leases != nil && s.leaseTTL > 0 {
+		go s.leaseLoop(ctx)
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			s.logger.Info("Scheduler shutting down")
 			return nil
 		case <-ticker.C:
+			s.failUnroutable()
+			s.enforceBudgets()
+			s.expireQueued()
+			s.expireReservations()
+			s.timeoutRunning()
+			s.timeoutAcks()
+			s.timeoutSuspended()
+			s.reportStarved()
+			s.processQueue()
+		case <-s.wake:
 			s.processQueue()
 		}
 	}
 }
 
-// Schedule adds a task to the queue
-func (s *Scheduler) Schedule(task *ScheduledTask) {
+// Schedule adds a task to the queue, failing with ErrQueueFull when the
+// queue is bounded and at capacity. A task pinned to another instance is
+// handed off to it instead.
+func (s *Scheduler) Schedule(task *ScheduledTask) error {
 	s.mu.Lock()
+	if s.pinnedElsewhere(task) {
+		s.mu.Unlock()
+		return s.handOff(task)
+	}
 	defer s.mu.Unlock()
 
-	task.ScheduledAt = time.Now()
-	if task.MaxRetries == 0 {
-		task.MaxRetries = 3
+	return s.schedule(task)
+}
+
+// schedule queues a task on this instance. Callers hold s.mu.
+func (s *Scheduler) schedule(task *ScheduledTask) error {
+	if s.maxQueued > 0 && s.queue.Len() >= s.maxQueued {
+		return ErrQueueFull
+	}
+
+	task.ScheduledAt = s.clock.Now()
+	task.State = TaskQueued
+	if task.submitted.IsZero() {
+		task.submitted = task.ScheduledAt
 	}
+	s.defaultDeadline(task)
+	s.resolvePolicy(task)
 
-	heap.Push(&s.queue, task)
+	s.enqueue(task)
 	s.logger.Debug("Task scheduled",
 		zap.String("id", task.ID),
+		zap.String("name", task.Name),
 		zap.Int("priority", int(task.Priority)),
 	)
+	s.emit(events.TaskScheduled, task.ID, "")
+	return nil
+}
+
+// defaultDeadline gives a task without a deadline one task timeout after
+// it was scheduled, when orchestrator.default_deadline is on
+func (s *Scheduler) defaultDeadline(task *ScheduledTask) {
+	timeout := time.Duration(s.config.Orchestrator.TaskTimeout) * time.Second
+	if !task.Deadline.IsZero() || !s.config.Orchestrator.DefaultDeadline || timeout <= 0 {
+		return
+	}
+	task.Deadline = task.ScheduledAt.Add(timeout)
+}
+
+// SetClock replaces the scheduler's time source, and that of its
+// in-memory deduper
+func (s *Scheduler) SetClock(c Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock = c
+	if d, ok := s.deduper.(*MemoryDeduper); ok {
+		d.SetClock(c)
+	}
+}
+
+// SetRand replaces the random source used for retry jitter. Pass a seeded
+// source to make runs repeatable.
+func (s *Scheduler) SetRand(r *rand.Rand) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rng = r
+}
+
+// SetRecorder attaches a recorder that receives every scheduling decision
+func (s *Scheduler) SetRecorder(r Recorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recorder = r
+}
+
+// push adds a task to the queue, stamping its tie-break order, and wakes
+// the dispatch loop
+func (s *Scheduler) push(task *ScheduledTask) {
+	s.pushes++
+	task.order = s.pushes
+	heap.Push(s.tasks(), task)
+	s.queued[task.ID] = task
+	s.notify()
+}
+
+// notify wakes the dispatch loop. Wakeups coalesce: any number of calls
+// before the loop runs cause a single pass.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// record passes a decision to the recorder, if any. Callers hold s.mu.
+func (s *Scheduler) record(action string, task *ScheduledTask) {
+	if s.recorder == nil {
+		return
+	}
+	s.decisions++
+	s.recorder.Record(Decision{
+		Seq:      s.decisions,
+		Time:     s.clock.Now(),
+		Action:   action,
+		TaskID:   task.ID,
+		Priority: task.Priority,
+		Retries:  task.Retries,
+	})
+}
+
+// retryDelay applies the configured jitter strategy to the backoff before
+// a task's latest retry. Callers hold s.mu.
+func (s *Scheduler) retryDelay(task *ScheduledTask) time.Duration {
+	delay := s.jitter(s.rng, task.Retry, task.Retry.backoff(task.Retries), task.lastDelay)
+	task.lastDelay = delay
+	return delay
+}
+
+// SetEvents attaches an event bus that task transitions are published to
+func (s *Scheduler) SetEvents(bus *events.Bus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = bus
+}
+
+// emit publishes a task event; the bus tolerates being unset
+func (s *Scheduler) emit(t events.Type, taskID, message string) {
+	s.events.Publish(events.Event{Type: t, TaskID: taskID, Message: message})
 }
 
 // processQueue dispatches tasks from the queue
@@ -142,88 +522,309 @@ func (s *Scheduler) processQueue() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.drained {
+		return
+	}
+
+	// A pending reservation holds every free slot until it is filled
+	if !s.releaseReservations() {
+		return
+	}
+
 	// Check if we can run more tasks
-	for s.currentCount < s.maxConcurrent && s.queue.Len() > 0 {
-		task := heap.Pop(&s.queue).(*ScheduledTask)
-
-		// Check dependencies
-		if !s.dependenciesMet(task) {
-			// Re-queue with slight delay
-			task.ScheduledAt = time.Now().Add(100 * time.Millisecond)
-			heap.Push(&s.queue, task)
-			continue
-		}
+	var held []*ScheduledTask
+	for s.currentCount < s.limit() && s.queue.Len() > 0 {
+		// Only tasks with met dependencies are ever queued
+		task := heap.Pop(s.tasks()).(*ScheduledTask)
+		delete(s.queued, task.ID)
 
 		// Check deadline
-		if !task.Deadline.IsZero() && time.Now().After(task.Deadline) {
-			s.logger.Warn("Task expired",
-				zap.String("id", task.ID),
-			)
+		if !task.Deadline.IsZero() && s.clock.Now().After(task.Deadline) {
+			s.expire(task)
 			continue
 		}
 
-		// Dispatch task
-		s.running[task.ID] = task
-		s.currentCount++
+		if s.holdForPreferred(task) {
+			held = append(held, task)
+			continue
+		}
 
-		go s.executeTask(task)
+		s.dispatch(task)
 	}
+	// Held tasks keep their place, and are looked at again next tick
+	for _, task := range held {
+		heap.Push(s.tasks(), task)
+		s.queued[task.ID] = task
+	}
+	s.checkHeap("dispatch")
 }
 
-// dependenciesMet checks if all task dependencies are completed
-func (s *Scheduler) dependenciesMet(task *ScheduledTask) bool {
-	for _, depID := range task.Dependencies {
-		if !s.completed[depID] {
-			return false
-		}
-	}
-	return true
+// expire fails a task whose deadline passed before it could be
+// dispatched. Callers hold s.mu.
+func (s *Scheduler) expire(task *ScheduledTask) {
+	task.State = TaskFailed
+	s.record(DecisionExpire, task)
+	s.logger.Warn("Task expired",
+		zap.String("id", task.ID),
+	)
+	s.emit(events.TaskExpired, task.ID, "deadline passed")
+	s.stageFinished(task, nil, "deadline passed")
+	s.settle(task)
 }
 
-// executeTask runs a task (placeholder)
-func (s *Scheduler) executeTask(task *ScheduledTask) {
-	s.logger.Info("Executing task", zap.String("id", task.ID))
+// dispatch starts a task taken off the queue. Callers hold s.mu.
+func (s *Scheduler) dispatch(task *ScheduledTask) {
+	task.State = TaskRunning
+	task.started = s.clock.Now()
+	task.starved = false
+	task.ran = true
+	task.results = nil
+	task.ackTimeout = s.ackTimeout(task.Agents)
+	task.acked = false
+	task.attempt++
+	task.instances = s.instancesOf(task.Agents)
+	s.stageDispatched(task)
+	s.running[task.ID] = task
+	s.currentCount++
+	s.recordEffective(task)
+	s.record(DecisionDispatch, task)
+	s.span(task, "queue", task.ScheduledAt, task.started, map[string]string{
+		"retries": strconv.Itoa(task.Retries),
+	})
+	s.emit(events.TaskDispatched, task.ID, "")
+
+	go s.executeTask(leasedTask(task), dispatched(task))
+}
 
-	// TODO: Actually dispatch to agent via message bus
+// executeTask hands a started task to its agents. It completes when they
+// report a result, or fails when they do not acknowledge or finish it in
+// time.
+func (s *Scheduler) executeTask(lease LeasedTask, d Dispatched) {
+	s.logger.Info("Executing task", zap.String("id", d.TaskID))
 
-	// Simulate execution
-	time.Sleep(100 * time.Millisecond)
+	// Own the task before handing it out, so it can be reclaimed if this
+	// instance dies while it runs
+	s.holdLease(lease)
+	s.handOut(d)
+}
 
-	s.completeTask(task.ID, nil)
+// completeTask marks a task as completed. It is idempotent: only the first
+// completion of a running task takes effect, later ones are ignored. It
+// returns the transition to record for the run, nil when there is none.
+func (s *Scheduler) completeTask(taskID string, output map[string]interface{}, err error) *store.Transition {
+	return s.completeAttempt(taskID, 0, output, err)
 }
 
-// completeTask marks a task as completed
-func (s *Scheduler) completeTask(taskID string, err error) {
+// completeAttempt is completeTask for one dispatch of the task: when the
+// task has been dispatched again since, the completion is ignored.
+// Attempt 0 completes whichever dispatch is running.
+func (s *Scheduler) completeAttempt(taskID string, attempt int, output map[string]interface{}, err error) *store.Transition {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	task, exists := s.running[taskID]
-	if !exists {
-		return
+	if !exists || task.State != TaskRunning {
+		s.logger.Debug("Ignoring completion for task not running",
+			zap.String("id", taskID),
+		)
+		return nil
 	}
+	if attempt != 0 && attempt != task.attempt {
+		s.logger.Debug("Ignoring completion for an earlier attempt",
+			zap.String("id", taskID),
+			zap.Int("attempt", attempt),
+			zap.Int("running", task.attempt),
+		)
+		return nil
+	}
+	retries := task.Retries
 
 	delete(s.running, taskID)
 	s.currentCount--
+	s.notify()
+	go s.releaseLease(taskID, task.attempt)
+	s.observeLatency(s.clock.Now().Sub(task.started))
+
+	if transform, ok := s.transformers[task.Type]; ok && err == nil {
+		transformed, terr := transform(output)
+		if terr != nil {
+			err = &TaskError{Category: CategoryInvalid, Message: "result rejected: " + terr.Error()}
+		} else {
+			output = transformed
+		}
+	}
+
+	if task.ResultSchema != nil && err == nil {
+		if violations := task.ResultSchema.Validate(output); len(violations) > 0 {
+			s.rejectResult(task, violations)
+			err = &TaskError{Category: CategoryInvalid, Message: "result does not match schema: " + schema.Join(violations)}
+		}
+	}
 
 	if err != nil {
 		// Handle retry
-		if task.Retries < task.MaxRetries {
+		if task.Retries < task.Retry.maxRetries() && task.Retry.retryable(err) {
 			task.Retries++
-			task.ScheduledAt = time.Now().Add(time.Duration(task.Retries) * time.Second)
-			heap.Push(&s.queue, task)
+			task.State = TaskQueued
+			task.ScheduledAt = s.clock.Now().Add(s.retryDelay(task))
+			if p := task.Retry.retryPriority(task.Priority, task.Deadline, task.ScheduledAt, err); p != task.Priority {
+				s.logger.Info("Retry priority adjusted",
+					zap.String("id", taskID),
+					zap.Int("from", int(task.Priority)),
+					zap.Int("to", int(p)),
+				)
+				task.Priority = p
+			}
+			s.rerouteRetry(task)
+			s.push(task)
+			s.record(DecisionRetry, task)
+			s.span(task, "run", task.started, s.clock.Now(), map[string]string{
+				"outcome":  "retrying",
+				"category": categoryOf(err),
+			})
 			s.logger.Warn("Task failed, retrying",
 				zap.String("id", taskID),
 				zap.Int("retry", task.Retries),
 			)
-			return
+			s.emit(events.TaskRetrying, taskID, err.Error())
+			return s.transition(task, retries, output, err)
 		}
+		task.State = TaskFailed
+		s.span(task, "run", task.started, s.clock.Now(), map[string]string{
+			"outcome":  "failed",
+			"category": categoryOf(err),
+		})
 		s.logger.Error("Task failed permanently",
 			zap.String("id", taskID),
+			zap.String("category", categoryOf(err)),
+			zap.Int("retries", task.Retries),
 			zap.Error(err),
 		)
+		s.emit(events.TaskFailed, taskID, err.Error())
+		s.deadLetter(task, err)
+		s.stageFinished(task, output, err.Error())
+		s.settle(task)
+	} else if s.awaitChildren(task, retries, output) {
+		return nil
 	} else {
+		task.State = TaskCompleted
+		s.span(task, "run", task.started, s.clock.Now(), map[string]string{"outcome": "completed"})
 		s.completed[taskID] = true
+		s.dependencyMet(taskID)
 		s.logger.Info("Task completed", zap.String("id", taskID))
+		s.emit(events.TaskCompleted, taskID, "")
+		s.stageFinished(task, output, "")
+		s.settle(task)
+	}
+	return s.transition(task, retries, output, err)
+}
+
+// settle does the bookkeeping for a task that has finished for good: its
+// lease is dropped so no instance reclaims it, its spend is forgotten, it
+// is counted, and the tasks waiting on it fail unless it completed.
+// Callers hold s.mu, after the task's pipeline and group have seen it.
+func (s *Scheduler) settle(task *ScheduledTask) {
+	go s.releaseLease(task.ID, task.attempt)
+	if s.spend != nil {
+		s.spend.ReleaseTask(task.ID)
+	}
+	s.tally(task)
+	if task.State != TaskCompleted {
+		s.dependencyFailed(task)
+	}
+}
+
+// SetDeduper replaces the completion delivery deduper
+func (s *Scheduler) SetDeduper(d Deduper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deduper = d
+}
+
+// Completion is an agent's report that it finished a task
+type Completion struct {
+	TaskID     string                 `json:"task_id"`
+	DeliveryID string                 `json:"delivery_id"`
+	Output     map[string]interface{} `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Category   string                 `json:"category,omitempty"`
+
+	// Agent that ran the task. Results of a task type that aggregates
+	// are only collected from completions that name their agent.
+	Agent string `json:"agent,omitempty"`
+
+	// Attempt is the dispatch the completion is for, as it was sent
+	Attempt int `json:"attempt"`
+}
+
+// HandleCompletion processes an agent completion at most once per delivery
+// ID. Redeliveries are acknowledged (nil error) but ignored; the returned
+// bool reports whether this delivery was processed.
+func (s *Scheduler) HandleCompletion(ctx context.Context, c Completion) (bool, error) {
+	if !s.firstDelivery(ctx, c.TaskID, c.DeliveryID) {
+		return false, nil
+	}
+
+	r := Result{TaskID: c.TaskID, Status: ResultCompleted, Output: c.Output}
+	if c.Error != "" {
+		r.Status, r.Error, r.Category = ResultFailed, c.Error, c.Category
+	}
+	s.mu.Lock()
+	ready := true
+	if task, running := s.running[c.TaskID]; running && task.State == TaskRunning {
+		if c.Attempt != task.attempt {
+			s.mu.Unlock()
+			s.logger.Warn("Stale completion ignored",
+				zap.String("id", c.TaskID),
+				zap.Int("attempt", c.Attempt),
+				zap.Int("running", task.attempt),
+			)
+			return false, nil
+		}
+		if c.Agent != "" {
+			r, ready = s.collect(task, c.Agent, r)
+		}
+	}
+	s.mu.Unlock()
+	if !ready {
+		return true, nil
+	}
+	if err := s.deliver(ctx, c.TaskID, c.Attempt, r.Output, r.err()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Backfill seeds the completed set from the store so that dependencies
+// which finished before a restart still count as met. Pages are applied
+// one at a time, holding the lock only while merging each page.
+func (s *Scheduler) Backfill(ctx context.Context, st store.Store, window time.Duration, pageSize int) (int, error) {
+	s.mu.Lock()
+	now := s.clock.Now()
+	s.mu.Unlock()
+
+	page := store.Page{AfterTime: now.Add(-window), Limit: pageSize}
+	total := 0
+
+	for {
+		refs, err := st.ListCompleted(ctx, page)
+		if err != nil {
+			return total, err
+		}
+
+		s.mu.Lock()
+		for _, ref := range refs {
+			s.completed[ref.ID] = true
+			s.dependencyMet(ref.ID)
+		}
+		s.mu.Unlock()
+
+		total += len(refs)
+		if len(refs) < pageSize {
+			return total, nil
+		}
+		page = page.Next(refs)
 	}
 }
 
@@ -233,10 +834,106 @@ func (s *Scheduler) GetStatus() map[string]interface{} {
 	defer s.mu.Unlock()
 
 	return map[string]interface{}{
-		"queued":        s.queue.Len(),
-		"running":       s.currentCount,
-		"completed":     len(s.completed),
+		"queued":         s.queue.Len(),
+		"reserved":       s.reservedCount(),
+		"waiting":        len(s.waiting),
+		"suspended":      len(s.suspended),
+		"awaiting":       s.awaitingCount(),
+		"running":        s.currentCount,
+		"completed":      len(s.completed),
 		"max_concurrent": s.maxConcurrent,
+		"limit":          s.limit(),
+		"drained":        s.drained,
+	}
+}
+
+// TaskSnapshot is a point-in-time view of a queued or running task
+type TaskSnapshot struct {
+	ID                string       `json:"id"`
+	Name              string       `json:"name,omitempty"`
+	Priority          TaskPriority `json:"priority"`
+	EffectivePriority TaskPriority `json:"effective_priority"`
+	State             TaskState    `json:"state"`
+	Retries           int          `json:"retries"`
+	ScheduledAt       time.Time    `json:"scheduled_at"`
+	Traced            bool         `json:"traced,omitempty"`
+	Parent            string       `json:"parent,omitempty"`
+}
+
+// List returns running tasks, then queued tasks, then tasks waiting on
+// dependencies, then tasks held in reservations, then suspended tasks,
+// then tasks awaiting their subtasks
+func (s *Scheduler) List() []TaskSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]TaskSnapshot, 0, len(s.running)+s.queue.Len()+len(s.waiting))
+	for _, task := range s.running {
+		out = append(out, snapshot(task))
+	}
+	for _, task := range s.queue {
+		out = append(out, snapshot(task))
+	}
+	for _, task := range s.waiting {
+		out = append(out, snapshot(task))
+	}
+	for _, res := range s.reservations {
+		for _, task := range res.tasks {
+			out = append(out, snapshot(task))
+		}
+	}
+	for _, task := range s.suspended {
+		out = append(out, snapshot(task))
+	}
+	for _, fam := range s.families {
+		if fam.parent.State == TaskAwaiting {
+			out = append(out, snapshot(fam.parent))
+		}
+	}
+	return out
+}
+
+// Task returns a snapshot of a known task. Completed tasks are no longer
+// held in memory, so only their ID and state are reported.
+func (s *Scheduler) Task(taskID string) (TaskSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if task, ok := s.running[taskID]; ok {
+		return snapshot(task), true
+	}
+	if task, ok := s.waiting[taskID]; ok {
+		return snapshot(task), true
+	}
+	if task, ok := s.queued[taskID]; ok {
+		return snapshot(task), true
+	}
+	if res, i := s.reserved(taskID); res != nil {
+		return snapshot(res.tasks[i]), true
+	}
+	if task, ok := s.suspended[taskID]; ok {
+		return snapshot(task), true
+	}
+	if fam, ok := s.families[taskID]; ok && fam.parent.State == TaskAwaiting {
+		return snapshot(fam.parent), true
+	}
+	if s.completed[taskID] {
+		return TaskSnapshot{ID: taskID, State: TaskCompleted}, true
+	}
+	return TaskSnapshot{}, false
+}
+
+func snapshot(task *ScheduledTask) TaskSnapshot {
+	return TaskSnapshot{
+		ID:                task.ID,
+		Name:              task.Name,
+		Priority:          task.Priority,
+		EffectivePriority: task.EffectivePriority(),
+		State:             task.State,
+		Retries:           task.Retries,
+		ScheduledAt:       task.ScheduledAt,
+		Traced:            task.Traced,
+		Parent:            task.Parent,
 	}
 }
 
@@ -246,19 +943,67 @@ func (s *Scheduler) Cancel(taskID string) bool {
 	defer s.mu.Unlock()
 
 	// Check if running
-	if _, exists := s.running[taskID]; exists {
+	if task, exists := s.running[taskID]; exists {
 		// TODO: Signal cancellation to agent
+		task.State = TaskCancelled
 		delete(s.running, taskID)
 		s.currentCount--
+		s.notify()
+		s.emit(events.TaskCancelled, taskID, "")
+		s.stageFinished(task, nil, "")
+		s.settle(task)
+		return true
+	}
+
+	// Check waiting
+	if task, exists := s.waiting[taskID]; exists {
+		s.unpark(task)
+		task.State = TaskCancelled
+		s.emit(events.TaskCancelled, taskID, "")
+		s.stageFinished(task, nil, "")
+		s.settle(task)
 		return true
 	}
 
 	// Check queue
-	for i, task := range s.queue {
-		if task.ID == taskID {
-			heap.Remove(&s.queue, i)
-			return true
-		}
+	if task, exists := s.queued[taskID]; exists {
+		s.removeQueued(task)
+		task.State = TaskCancelled
+		s.emit(events.TaskCancelled, taskID, "")
+		s.stageFinished(task, nil, "")
+		s.settle(task)
+		return true
+	}
+
+	// Check reservations
+	if res, i := s.reserved(taskID); res != nil {
+		task := res.tasks[i]
+		s.dropReserved(res, i)
+		task.State = TaskCancelled
+		s.emit(events.TaskCancelled, taskID, "")
+		s.stageFinished(task, nil, "")
+		s.settle(task)
+		return true
+	}
+
+	// Check suspended
+	if task, exists := s.suspended[taskID]; exists {
+		delete(s.suspended, taskID)
+		task.State = TaskCancelled
+		s.emit(events.TaskCancelled, taskID, "")
+		s.stageFinished(task, nil, "")
+		s.settle(task)
+		return true
+	}
+
+	// Check awaiting subtasks
+	if fam, exists := s.families[taskID]; exists && fam.parent.State == TaskAwaiting {
+		task := fam.parent
+		task.State = TaskCancelled
+		s.emit(events.TaskCancelled, taskID, "")
+		s.stageFinished(task, nil, "")
+		s.settle(task)
+		return true
 	}
 
 	return false
@@ -0,0 +1,195 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// epoch is where test clocks start
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Orchestrator.MaxConcurrentTasks = 10
+	cfg.Orchestrator.TickInterval = 10
+	return cfg
+}
+
+// newTestScheduler creates a scheduler on a manual clock started at epoch
+func newTestScheduler(t *testing.T, cfg *config.Config) (*Scheduler, *ManualClock) {
+	t.Helper()
+	s := New(cfg, zap.NewNop())
+	c := NewManualClock(epoch)
+	s.SetClock(c)
+	return s, c
+}
+
+// mustSchedule schedules each task, failing the test on error
+func mustSchedule(t *testing.T, s *Scheduler, tasks ...*ScheduledTask) {
+	t.Helper()
+	for _, task := range tasks {
+		if err := s.Schedule(task); err != nil {
+			t.Fatalf("Schedule %s: %v", task.ID, err)
+		}
+	}
+}
+
+// stateOf returns the state of a task the scheduler still holds
+func stateOf(t *testing.T, s *Scheduler, id string) TaskState {
+	t.Helper()
+	snap, ok := s.Task(id)
+	if !ok {
+		t.Fatalf("task %s not found", id)
+	}
+	return snap.State
+}
+
+// counts returns what has been tallied for a task type
+func counts(s *Scheduler, taskType string) TypeCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.finished[taskType]; ok {
+		return *c
+	}
+	return TypeCounts{}
+}
+
+func running(s *Scheduler) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.currentCount
+}
+
+// waiting reports whether a task is blocked on its dependencies
+func waiting(s *Scheduler, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.waiting[id]
+	return ok
+}
+
+func TestConcurrentCompletionsTakeEffectOnce(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "question"})
+	s.processQueue()
+	if got := running(s); got != 1 {
+		t.Fatalf("running = %d after dispatch, want 1", got)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.completeTask("t1", map[string]interface{}{"answer": 42}, nil)
+		}()
+	}
+	wg.Wait()
+
+	if got := running(s); got != 0 {
+		t.Errorf("running = %d after double completion, want 0", got)
+	}
+	if got := counts(s, "question"); got.Completed != 1 || got.Failed != 0 {
+		t.Errorf("tally = %+v, want one completion", got)
+	}
+	if got := stateOf(t, s, "t1"); got != TaskCompleted {
+		t.Errorf("state = %s, want completed", got)
+	}
+}
+
+func TestCompletionRacingFailureCountsOnce(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "question", Retry: Retries(0)})
+	s.processQueue()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.completeTask("t1", nil, nil)
+	}()
+	go func() {
+		defer wg.Done()
+		s.completeTask("t1", nil, errors.New("agent crashed"))
+	}()
+	wg.Wait()
+
+	if got := running(s); got != 0 {
+		t.Errorf("running = %d, want 0", got)
+	}
+	if got := counts(s, "question"); got.Completed+got.Failed != 1 {
+		t.Errorf("tally = %+v, want exactly one outcome", got)
+	}
+}
+
+func TestBackfilledDependencyLetsDependentDispatch(t *testing.T) {
+	st := store.NewMemory()
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		st.MarkCompleted(id, epoch.Add(-time.Duration(i+1)*time.Minute))
+	}
+	st.MarkCompleted("ancient", epoch.Add(-48*time.Hour))
+
+	// A fresh scheduler, as after a restart
+	s, _ := newTestScheduler(t, testConfig())
+	n, err := s.Backfill(context.Background(), st, 24*time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Backfill: %v", err)
+	}
+	if n != 5 {
+		t.Errorf("backfilled %d tasks, want the 5 inside the window", n)
+	}
+
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "after-e", Dependencies: []string{"e"}},
+		&ScheduledTask{ID: "after-ancient", Dependencies: []string{"ancient"}},
+	)
+	s.processQueue()
+	if got := stateOf(t, s, "after-e"); got != TaskRunning {
+		t.Errorf("dependent of a backfilled task is %s, want running", got)
+	}
+	if !waiting(s, "after-ancient") {
+		t.Error("dependent of a task outside the window is not waiting")
+	}
+}
+
+// startNoTick starts the scheduler with a ticker that never fires in the
+// test, so only wakeups can dispatch
+func startNoTick(t *testing.T, cfg *config.Config) *Scheduler {
+	t.Helper()
+	cfg.Orchestrator.TickInterval = int(time.Hour / time.Millisecond)
+	s, _ := newTestScheduler(t, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.Start(ctx)
+	return s
+}
+
+func TestScheduledTaskDispatchesWithoutTick(t *testing.T) {
+	s := startNoTick(t, testConfig())
+	mustSchedule(t, s, &ScheduledTask{ID: "now"})
+	waitUntil(t, "the task to dispatch", func() bool { return stateOf(t, s, "now") == TaskRunning })
+}
+
+func TestFreedSlotDispatchesWithoutTick(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	s := startNoTick(t, cfg)
+	mustSchedule(t, s, &ScheduledTask{ID: "first"}, &ScheduledTask{ID: "second"})
+	waitUntil(t, "first to dispatch", func() bool { return stateOf(t, s, "first") == TaskRunning })
+	if got := stateOf(t, s, "second"); got != TaskQueued {
+		t.Fatalf("second is %s with the only slot taken, want queued", got)
+	}
+
+	s.completeTask("first", nil, nil)
+	waitUntil(t, "second to dispatch", func() bool { return stateOf(t, s, "second") == TaskRunning })
+}
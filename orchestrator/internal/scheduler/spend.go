@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"encoding/json"
+
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+)
+
+// Spend tracks what each task has spent on LLM calls, across its retries;
+// llm.Client satisfies it
+type Spend interface {
+	ChargeTask(taskID string, usage llm.Usage, cost float64, budget *llm.Budget) error
+	TaskSpend(taskID string) (int, float64)
+	ReleaseTask(taskID string)
+}
+
+// SetSpend charges the usage agents report with their results to each
+// task's budget, and forgets a task's spend once it has finished for good
+func (s *Scheduler) SetSpend(sp Spend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.spend = sp
+}
+
+// charge adds the usage a result reports to its task's spend. A failed
+// result that overruns the budget fails the task for good, as a call made
+// here would have. Callers hold s.mu.
+func (s *Scheduler) charge(task *ScheduledTask, r *Result) {
+	if s.spend == nil || (r.Usage.TotalTokens == 0 && r.Cost == 0) {
+		return
+	}
+	err := s.spend.ChargeTask(task.ID, r.Usage, r.Cost, taskBudget(task))
+	if err == nil || r.Status != ResultFailed {
+		return
+	}
+	r.Category = CategoryBudget
+	r.Error = err.Error()
+}
+
+// taskBudget returns the budget a task was submitted with, nil for none.
+// A task restored from a lease or saved queue has it decoded as a map.
+func taskBudget(task *ScheduledTask) *llm.Budget {
+	switch b := task.Settings["budget"].(type) {
+	case *llm.Budget:
+		return b
+	case map[string]interface{}:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil
+		}
+		var budget llm.Budget
+		if json.Unmarshal(data, &budget) != nil {
+			return nil
+		}
+		return &budget
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/llm"
+	"go.uber.org/zap"
+)
+
+func TestTaskOverBudgetFailsWithBudgetCategory(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	spend := llm.New(testConfig(), zap.NewNop())
+	s.SetSpend(spend)
+	dlq := NewMemoryDeadLetterQueue()
+	s.SetDeadLetters(dlq)
+
+	mustSchedule(t, s, &ScheduledTask{
+		ID:       "runaway",
+		Type:     "code_write",
+		Agents:   []string{"dev"},
+		Settings: map[string]interface{}{"budget": &llm.Budget{MaxTokens: 100}},
+	})
+	s.processQueue()
+
+	// The first run fails within budget and is retried
+	if err := s.ReportResult("dev", Result{TaskID: "runaway", Status: ResultFailed, Attempt: 1, Usage: llm.Usage{TotalTokens: 60}}); err != nil {
+		t.Fatalf("first report: %v", err)
+	}
+	if got := stateOf(t, s, "runaway"); got != TaskQueued {
+		t.Fatalf("after a failure within budget the task is %s, want queued", got)
+	}
+
+	// The retry's usage counts on top, overrunning the cap
+	s.processQueue()
+	if err := s.ReportResult("dev", Result{TaskID: "runaway", Status: ResultFailed, Attempt: 2, Usage: llm.Usage{TotalTokens: 60}}); err != nil {
+		t.Fatalf("second report: %v", err)
+	}
+	if got := counts(s, "code_write"); got.Failed != 1 {
+		t.Fatalf("tally = %+v, want the task failed for good", got)
+	}
+
+	var letters []DeadLetter
+	waitUntil(t, "the dead letter", func() bool {
+		letters, _ = dlq.All(context.Background())
+		return len(letters) > 0
+	})
+	if letters[0].Category != CategoryBudget {
+		t.Errorf("category = %q, want %q", letters[0].Category, CategoryBudget)
+	}
+	if tokens, _ := spend.TaskSpend("runaway"); tokens != 0 {
+		t.Errorf("spend still held for a finished task: %d tokens", tokens)
+	}
+}
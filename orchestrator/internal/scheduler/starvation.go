@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// reportStarved publishes task.starved once for each queued task that has
+// been ready to run for longer than the starvation threshold. A task is
+// reported again only after it has been dispatched and queued anew.
+func (s *Scheduler) reportStarved() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.starvedAfter <= 0 {
+		return
+	}
+
+	now := s.clock.Now()
+	for _, task := range s.queue {
+		if task.starved {
+			continue
+		}
+		waited := now.Sub(task.ScheduledAt)
+		if waited < s.starvedAfter {
+			continue
+		}
+
+		task.starved = true
+		s.logger.Warn("Task starved in queue",
+			zap.String("id", task.ID),
+			zap.Duration("waited", waited),
+		)
+		s.emit(events.TaskStarved, task.ID, fmt.Sprintf("queued for %s", waited.Round(time.Second)))
+	}
+}
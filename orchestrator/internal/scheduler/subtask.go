@@ -0,0 +1,237 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// ErrNoSubtasks is returned for a task that has spawned no subtasks
+var ErrNoSubtasks = errors.New("task has no subtasks")
+
+// CategorySubtask is the error category of a parent failed by a subtask
+// it waited for
+const CategorySubtask = "subtask"
+
+// SubtaskStatus is a point-in-time view of one subtask
+type SubtaskStatus struct {
+	TaskID string    `json:"task_id"`
+	Type   string    `json:"type"`
+	State  TaskState `json:"state"`
+	Wait   bool      `json:"wait"` // the parent's completion waits for it
+}
+
+// SubtasksStatus reports a parent task's progress through its subtasks
+type SubtasksStatus struct {
+	ParentID    string          `json:"parent_id"`
+	ParentState TaskState       `json:"parent_state"`
+	Completed   int             `json:"completed"`
+	Failed      int             `json:"failed"` // failed or cancelled
+	Total       int             `json:"total"`
+	Subtasks    []SubtaskStatus `json:"subtasks"`
+}
+
+// family tracks the subtasks spawned under one parent
+type family struct {
+	parent   *ScheduledTask
+	children []*ScheduledTask
+	wait     map[string]bool // subtasks the parent's completion waits for
+
+	// output and attempt of the parent's run, held while it awaits
+	output  map[string]interface{}
+	attempt int
+}
+
+// pending counts the awaited subtasks that have not finished
+func (f *family) pending() int {
+	n := 0
+	for _, child := range f.children {
+		if f.wait[child.ID] && !finished(child.State) {
+			n++
+		}
+	}
+	return n
+}
+
+// failure describes the first awaited subtask that failed or was
+// cancelled; empty when none has
+func (f *family) failure() string {
+	for _, child := range f.children {
+		if f.wait[child.ID] && (child.State == TaskFailed || child.State == TaskCancelled) {
+			return fmt.Sprintf("subtask %s %s", child.ID, child.State)
+		}
+	}
+	return ""
+}
+
+func finished(st TaskState) bool {
+	return st == TaskCompleted || st == TaskFailed || st == TaskCancelled
+}
+
+// SubmitSubtask schedules a task on behalf of the agent running its
+// parent. The subtask lends from the parent: it runs at no less than the
+// parent's effective priority, by the parent's deadline at the latest,
+// and is traced when the parent is. With wait set, the parent's own
+// success does not complete it: the parent awaits every such subtask and
+// fails if one of them fails or is cancelled. Only an agent the parent
+// was routed to may submit its subtasks.
+func (s *Scheduler) SubmitSubtask(agent, parentID string, task *ScheduledTask, wait bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parent, exists := s.running[parentID]
+	if !exists || parent.State != TaskRunning {
+		return fmt.Errorf("%w: %s", ErrUnknownTask, parentID)
+	}
+	if !parent.ownedBy(agent) {
+		return fmt.Errorf("%w: %s does not own %s", ErrNotOwner, agent, parentID)
+	}
+	if err := s.checkPinned([]*ScheduledTask{task}); err != nil {
+		return err
+	}
+
+	task.Parent = parentID
+	task.inherited = max(task.inherited, parent.EffectivePriority())
+	if !parent.Deadline.IsZero() && (task.Deadline.IsZero() || task.Deadline.After(parent.Deadline)) {
+		task.Deadline = parent.Deadline
+	}
+	task.Traced = task.Traced || parent.Traced
+	if err := s.schedule(task); err != nil {
+		return err
+	}
+
+	fam, ok := s.families[parentID]
+	if !ok {
+		fam = &family{parent: parent, wait: make(map[string]bool)}
+		s.families[parentID] = fam
+	}
+	fam.children = append(fam.children, task)
+	if wait {
+		fam.wait[task.ID] = true
+	}
+
+	s.logger.Info("Subtask submitted",
+		zap.String("id", task.ID),
+		zap.String("parent", parentID),
+		zap.String("agent", agent),
+		zap.Bool("wait", wait),
+	)
+	return nil
+}
+
+// awaitChildren holds the completion of a parent whose agent succeeded
+// while subtasks it waits for are unfinished, and reports whether it did.
+// A parent whose awaited subtask already failed fails now. Callers hold
+// s.mu.
+func (s *Scheduler) awaitChildren(task *ScheduledTask, attempt int, output map[string]interface{}) bool {
+	fam, ok := s.families[task.ID]
+	if !ok {
+		return false
+	}
+	failure := fam.failure()
+	if failure == "" && fam.pending() == 0 {
+		return false
+	}
+
+	task.State = TaskAwaiting
+	fam.output = output
+	fam.attempt = attempt
+	s.span(task, "run", task.started, s.clock.Now(), map[string]string{"outcome": "awaiting_children"})
+	if failure != "" {
+		s.finishParent(fam, failure)
+		return true
+	}
+	s.logger.Info("Task awaiting subtasks",
+		zap.String("id", task.ID),
+		zap.Int("pending", fam.pending()),
+	)
+	return true
+}
+
+// childFinished finishes the parent of a finished subtask once every
+// subtask it awaits has completed, or fails it as soon as one has not.
+// Callers hold s.mu.
+func (s *Scheduler) childFinished(task *ScheduledTask) {
+	fam, ok := s.families[task.Parent]
+	if !ok || !fam.wait[task.ID] || fam.parent.State != TaskAwaiting {
+		return
+	}
+	if failure := fam.failure(); failure != "" {
+		s.finishParent(fam, failure)
+	} else if fam.pending() == 0 {
+		s.finishParent(fam, "")
+	}
+}
+
+// finishParent ends an awaiting parent with its held output, or fails it
+// for good with failure: retrying the parent would not bring a failed
+// subtask back. Callers hold s.mu.
+func (s *Scheduler) finishParent(fam *family, failure string) {
+	task := fam.parent
+	var err error
+	if failure != "" {
+		err = &TaskError{Category: CategorySubtask, Message: failure}
+		task.State = TaskFailed
+		s.logger.Error("Task failed on a subtask",
+			zap.String("id", task.ID),
+			zap.String("reason", failure),
+		)
+		s.emit(events.TaskFailed, task.ID, failure)
+		s.deadLetter(task, err)
+		s.stageFinished(task, fam.output, failure)
+		s.settle(task)
+	} else {
+		task.State = TaskCompleted
+		s.completed[task.ID] = true
+		s.dependencyMet(task.ID)
+		s.logger.Info("Task completed with its subtasks", zap.String("id", task.ID))
+		s.emit(events.TaskCompleted, task.ID, "")
+		s.stageFinished(task, fam.output, "")
+		s.settle(task)
+	}
+
+	if t := s.transition(task, fam.attempt, fam.output, err); t != nil {
+		go s.recordTransition(context.Background(), t)
+	}
+}
+
+// Subtasks reports a parent task's progress through its subtasks
+func (s *Scheduler) Subtasks(parentID string) (SubtasksStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fam, ok := s.families[parentID]
+	if !ok {
+		return SubtasksStatus{}, fmt.Errorf("%w: %s", ErrNoSubtasks, parentID)
+	}
+	st := SubtasksStatus{
+		ParentID:    parentID,
+		ParentState: fam.parent.State,
+		Total:       len(fam.children),
+		Subtasks:    make([]SubtaskStatus, len(fam.children)),
+	}
+	for i, child := range fam.children {
+		switch child.State {
+		case TaskCompleted:
+			st.Completed++
+		case TaskFailed, TaskCancelled:
+			st.Failed++
+		}
+		st.Subtasks[i] = SubtaskStatus{TaskID: child.ID, Type: child.Type, State: child.State, Wait: fam.wait[child.ID]}
+	}
+	return st, nil
+}
+
+// awaitingCount counts parents awaiting their subtasks. Callers hold s.mu.
+func (s *Scheduler) awaitingCount() int {
+	n := 0
+	for _, fam := range s.families {
+		if fam.parent.State == TaskAwaiting {
+			n++
+		}
+	}
+	return n
+}
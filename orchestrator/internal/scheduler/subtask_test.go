@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newParentScheduler runs "build", a high-priority code_write on dev due
+// within the hour
+func newParentScheduler(t *testing.T) (*Scheduler, *MemoryDeadLetterQueue) {
+	t.Helper()
+	s, _ := newTestScheduler(t, testConfig())
+	dlq := NewMemoryDeadLetterQueue()
+	s.SetDeadLetters(dlq)
+	mustSchedule(t, s, &ScheduledTask{
+		ID:       "build",
+		Type:     "code_write",
+		Priority: PriorityHigh,
+		Agents:   []string{"dev"},
+		Deadline: epoch.Add(time.Hour),
+	})
+	s.processQueue()
+	return s, dlq
+}
+
+// progress returns a parent's subtask status
+func progress(t *testing.T, s *Scheduler, parentID string) SubtasksStatus {
+	t.Helper()
+	st, err := s.Subtasks(parentID)
+	if err != nil {
+		t.Fatalf("Subtasks: %v", err)
+	}
+	return st
+}
+
+func TestSubtaskCreatedUnderParent(t *testing.T) {
+	s, _ := newParentScheduler(t)
+
+	child := &ScheduledTask{ID: "tests", Type: "test", Agents: []string{"test"}, Deadline: epoch.Add(2 * time.Hour)}
+	if err := s.SubmitSubtask("dev", "build", child, true); err != nil {
+		t.Fatalf("SubmitSubtask: %v", err)
+	}
+
+	snap, ok := s.Task("tests")
+	if !ok || snap.Parent != "build" || snap.State != TaskQueued {
+		t.Fatalf("subtask = %+v, want queued under build", snap)
+	}
+	if snap.EffectivePriority != PriorityHigh {
+		t.Errorf("subtask effective priority = %d, want the parent's high", snap.EffectivePriority)
+	}
+	if task, _ := queuedTask(s, "tests"); !task.Deadline.Equal(epoch.Add(time.Hour)) {
+		t.Errorf("subtask deadline = %v, want capped at the parent's", task.Deadline)
+	}
+
+	st := progress(t, s, "build")
+	if st.Total != 1 || st.ParentState != TaskRunning || !st.Subtasks[0].Wait {
+		t.Errorf("progress = %+v, want one awaited subtask of a running parent", st)
+	}
+}
+
+func TestParentCompletesAfterAwaitedSubtasks(t *testing.T) {
+	s, _ := newParentScheduler(t)
+	mustSubmit := func(id string, wait bool) {
+		t.Helper()
+		if err := s.SubmitSubtask("dev", "build", &ScheduledTask{ID: id, Type: "test", Agents: []string{"test"}}, wait); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustSubmit("unit", true)
+	mustSubmit("lint", false)
+
+	// The parent's own success waits for its awaited subtask
+	if err := s.ReportResult("dev", Result{TaskID: "build", Status: ResultCompleted, Attempt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := stateOf(t, s, "build"); got != TaskAwaiting {
+		t.Fatalf("parent is %s, want awaiting its subtasks", got)
+	}
+
+	s.processQueue()
+	if err := s.ReportResult("test", Result{TaskID: "unit", Status: ResultCompleted, Attempt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := counts(s, "code_write"); got.Completed != 1 {
+		t.Fatalf("tally = %+v, want the parent completed with its subtask", got)
+	}
+	st := progress(t, s, "build")
+	if st.ParentState != TaskCompleted || st.Completed != 1 || st.Total != 2 {
+		t.Errorf("progress = %+v, want completed with 1 of 2 subtasks done", st)
+	}
+	if got := stateOf(t, s, "lint"); got != TaskRunning {
+		t.Errorf("unawaited subtask is %s, want left running", got)
+	}
+}
+
+func TestFailedSubtaskFailsParent(t *testing.T) {
+	s, dlq := newParentScheduler(t)
+	if err := s.SubmitSubtask("dev", "build", &ScheduledTask{ID: "unit", Type: "test"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReportResult("dev", Result{TaskID: "build", Status: ResultCompleted, Attempt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Cancel("unit")
+	if got := counts(s, "code_write"); got.Failed != 1 {
+		t.Fatalf("tally = %+v, want the parent failed", got)
+	}
+	if st := progress(t, s, "build"); st.ParentState != TaskFailed || st.Failed != 1 {
+		t.Errorf("progress = %+v, want a failed parent and subtask", st)
+	}
+	var letters []DeadLetter
+	waitUntil(t, "the dead letter", func() bool {
+		letters, _ = dlq.All(context.Background())
+		return len(letters) > 0
+	})
+	if letters[0].Task.ID != "build" || letters[0].Category != CategorySubtask {
+		t.Errorf("dead letter = %+v, want the parent with category subtask", letters[0])
+	}
+}
+
+func TestSubtaskRefusals(t *testing.T) {
+	s, _ := newParentScheduler(t)
+
+	if err := s.SubmitSubtask("qa", "build", &ScheduledTask{ID: "x"}, false); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("non-owner: %v, want ErrNotOwner", err)
+	}
+	if err := s.SubmitSubtask("dev", "ghost", &ScheduledTask{ID: "y"}, false); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("unknown parent: %v, want ErrUnknownTask", err)
+	}
+	if _, err := s.Subtasks("build"); !errors.Is(err, ErrNoSubtasks) {
+		t.Errorf("parent without subtasks: %v, want ErrNoSubtasks", err)
+	}
+}
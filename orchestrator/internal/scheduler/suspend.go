@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// InputApproval is the input key holding the Approval a resumed task was
+// approved with
+const InputApproval = "approval"
+
+// Suspension errors
+var (
+	ErrNotSuspended   = errors.New("task is not suspended")
+	ErrSuspendTimeout = errors.New("task was not approved in time")
+	ErrRejected       = errors.New("task was rejected")
+)
+
+// Approval is an external decision on a suspended task
+type Approval struct {
+	Approved bool   `json:"approved"`
+	By       string `json:"by,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Suspend parks a running task until an external decision arrives, as
+// when its agent needs a human to approve before going on. The task
+// gives up its concurrency slot while suspended but keeps its lease, so
+// another instance takes it over, still suspended, if this one dies. It
+// fails if no decision arrives within timeout, or
+// orchestrator.suspend_timeout when timeout is 0 (0 for both = wait
+// forever). Only an agent the task was routed to may suspend it.
+func (s *Scheduler) Suspend(agent, taskID, reason string, timeout time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.running[taskID]
+	if !exists || task.State != TaskRunning {
+		return fmt.Errorf("%w: %s", ErrUnknownTask, taskID)
+	}
+	if !task.ownedBy(agent) {
+		return fmt.Errorf("%w: %s does not own %s", ErrNotOwner, agent, taskID)
+	}
+	if timeout <= 0 {
+		timeout = time.Duration(s.config.Orchestrator.SuspendTimeout) * time.Second
+	}
+
+	now := s.clock.Now()
+	delete(s.running, taskID)
+	s.currentCount--
+	s.notify()
+
+	s.span(task, "run", task.started, now, map[string]string{"outcome": "suspended"})
+	task.State = TaskSuspended
+	task.suspendedAt = now
+	task.suspendTimeout = timeout
+	s.suspended[taskID] = task
+	go s.holdLease(leasedTask(task))
+	s.record(DecisionSuspend, task)
+	s.emit(events.TaskSuspended, taskID, reason)
+	s.logger.Info("Task suspended for approval",
+		zap.String("id", taskID),
+		zap.String("agent", agent),
+		zap.String("reason", reason),
+		zap.Duration("timeout", timeout),
+	)
+	return nil
+}
+
+// ResumeTask applies an external decision to a suspended task. An
+// approved task is queued again, ahead of the queue limit, with the
+// approval in its input under InputApproval; a rejected one fails for
+// good.
+func (s *Scheduler) ResumeTask(taskID string, d Approval) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.suspended[taskID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrNotSuspended, taskID)
+	}
+	delete(s.suspended, taskID)
+
+	now := s.clock.Now()
+	s.span(task, "suspended", task.suspendedAt, now, map[string]string{
+		"approved": fmt.Sprint(d.Approved),
+		"by":       d.By,
+	})
+
+	if !d.Approved {
+		message := ErrRejected.Error()
+		if d.By != "" {
+			message += " by " + d.By
+		}
+		if d.Comment != "" {
+			message += ": " + d.Comment
+		}
+		s.failSuspended(task, message)
+		return nil
+	}
+
+	// Queued tasks are not leased; it is leased again when dispatched
+	go s.releaseLease(taskID, task.attempt)
+	setInput(task, InputApproval, d)
+	task.State = TaskQueued
+	task.ScheduledAt = now
+	s.push(task)
+	s.record(DecisionResume, task)
+	s.emit(events.TaskResumed, taskID, "approved")
+	s.logger.Info("Task approved, resuming",
+		zap.String("id", taskID),
+		zap.String("by", d.By),
+	)
+	return nil
+}
+
+// resuspend parks a task taken over from a saved queue or an expired
+// lease as suspended, its suspend timeout starting over. Callers hold s.mu.
+func (s *Scheduler) resuspend(task *ScheduledTask) {
+	task.State = TaskSuspended
+	task.ran = true
+	task.suspendedAt = s.clock.Now()
+	s.suspended[task.ID] = task
+}
+
+// timeoutSuspended fails suspended tasks that have waited longer than
+// their suspend timeout for a decision
+func (s *Scheduler) timeoutSuspended() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	var overdue []*ScheduledTask
+	for _, task := range s.suspended {
+		if task.suspendTimeout > 0 && now.Sub(task.suspendedAt) >= task.suspendTimeout {
+			overdue = append(overdue, task)
+		}
+	}
+	// In ID order, so decision logs replay deterministically
+	sort.Slice(overdue, func(i, j int) bool { return overdue[i].ID < overdue[j].ID })
+
+	for _, task := range overdue {
+		delete(s.suspended, task.ID)
+		s.record(DecisionExpire, task)
+		s.failSuspended(task, fmt.Sprintf("%v: no decision within %s", ErrSuspendTimeout, task.suspendTimeout))
+	}
+}
+
+// failSuspended fails a task taken out of the suspended set. It is not
+// retried: an unanswered or rejected approval would only be asked again.
+// Callers hold s.mu.
+func (s *Scheduler) failSuspended(task *ScheduledTask, message string) {
+	task.State = TaskFailed
+	s.logger.Warn("Suspended task failed",
+		zap.String("id", task.ID),
+		zap.String("reason", message),
+	)
+	s.emit(events.TaskFailed, task.ID, message)
+	s.stageFinished(task, nil, message)
+	s.settle(task)
+}
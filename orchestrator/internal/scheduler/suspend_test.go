@@ -0,0 +1,188 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+)
+
+// newSuspendScheduler runs one task at a time, with "gated" running on
+// approbation and "next" queued behind it
+func newSuspendScheduler(t *testing.T) (*Scheduler, *ManualClock, *events.Bus) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = 1
+	cfg.Orchestrator.SuspendTimeout = 3600
+	s, c := newTestScheduler(t, cfg)
+	bus := events.New(100)
+	s.SetEvents(bus)
+
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "gated", Type: "code_write", Priority: PriorityHigh, Agents: []string{"approbation"}},
+		&ScheduledTask{ID: "next", Type: "question", Agents: []string{"explain"}},
+	)
+	s.processQueue()
+	if got := stateOf(t, s, "gated"); got != TaskRunning {
+		t.Fatalf("gated is %s, want running", got)
+	}
+	return s, c, bus
+}
+
+// failure returns the message a task failed with
+func failure(bus *events.Bus, id string) string {
+	for _, e := range bus.Since(0) {
+		if e.Type == events.TaskFailed && e.TaskID == id {
+			return e.Message
+		}
+	}
+	return ""
+}
+
+func TestSuspendedTaskFreesSlotAndResumesOnApproval(t *testing.T) {
+	s, _, _ := newSuspendScheduler(t)
+
+	if err := s.Suspend("approbation", "gated", "needs sign-off", 0); err != nil {
+		t.Fatalf("Suspend: %v", err)
+	}
+	if got := stateOf(t, s, "gated"); got != TaskSuspended {
+		t.Fatalf("gated is %s, want suspended", got)
+	}
+	if got := running(s); got != 0 {
+		t.Fatalf("%d slots in use while suspended, want 0", got)
+	}
+
+	// The freed slot goes to the next task
+	s.processQueue()
+	if got := stateOf(t, s, "next"); got != TaskRunning {
+		t.Fatalf("next is %s, want running in the freed slot", got)
+	}
+	if err := s.ReportResult("explain", Result{TaskID: "next", Status: ResultCompleted, Attempt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	approval := Approval{Approved: true, By: "ada", Comment: "ship it"}
+	if err := s.ResumeTask("gated", approval); err != nil {
+		t.Fatalf("ResumeTask: %v", err)
+	}
+	if got := stateOf(t, s, "gated"); got != TaskQueued {
+		t.Fatalf("approved task is %s, want queued", got)
+	}
+	s.processQueue()
+	s.mu.Lock()
+	task := s.running["gated"]
+	s.mu.Unlock()
+	if task == nil {
+		t.Fatal("approved task was not dispatched again")
+	}
+	if got := task.Input[InputApproval]; got != approval {
+		t.Errorf("input.approval = %v, want %v", got, approval)
+	}
+
+	if err := s.ReportResult("approbation", Result{TaskID: "gated", Status: ResultCompleted, Attempt: task.attempt}); err != nil {
+		t.Fatal(err)
+	}
+	if got := counts(s, "code_write"); got.Completed != 1 {
+		t.Errorf("tally = %+v, want the task completed", got)
+	}
+}
+
+func TestRejectedTaskFails(t *testing.T) {
+	s, _, bus := newSuspendScheduler(t)
+	if err := s.Suspend("approbation", "gated", "", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ResumeTask("gated", Approval{By: "ada", Comment: "not yet"}); err != nil {
+		t.Fatalf("ResumeTask: %v", err)
+	}
+	if got := counts(s, "code_write"); got.Failed != 1 {
+		t.Fatalf("tally = %+v, want the task failed", got)
+	}
+	if got := failure(bus, "gated"); got != "task was rejected by ada: not yet" {
+		t.Errorf("failure = %q", got)
+	}
+	if err := s.ResumeTask("gated", Approval{Approved: true}); !errors.Is(err, ErrNotSuspended) {
+		t.Errorf("second decision: %v, want ErrNotSuspended", err)
+	}
+}
+
+func TestSuspendedTaskTimesOut(t *testing.T) {
+	s, c, bus := newSuspendScheduler(t)
+	if err := s.Suspend("approbation", "gated", "", 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Advance(9 * time.Minute)
+	s.timeoutSuspended()
+	if got := stateOf(t, s, "gated"); got != TaskSuspended {
+		t.Fatalf("gated is %s before its timeout, want suspended", got)
+	}
+
+	c.Advance(time.Minute)
+	s.timeoutSuspended()
+	if got := counts(s, "code_write"); got.Failed != 1 {
+		t.Fatalf("tally = %+v, want the task failed", got)
+	}
+	if got := failure(bus, "gated"); !strings.HasPrefix(got, ErrSuspendTimeout.Error()) {
+		t.Errorf("failure = %q, want ErrSuspendTimeout", got)
+	}
+}
+
+func TestOnlyOwnerSuspendsRunningTask(t *testing.T) {
+	s, _, _ := newSuspendScheduler(t)
+
+	if err := s.Suspend("dev", "gated", "", 0); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("non-owner: %v, want ErrNotOwner", err)
+	}
+	if err := s.Suspend("explain", "next", "", 0); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("queued task: %v, want ErrUnknownTask", err)
+	}
+	if got := stateOf(t, s, "gated"); got != TaskRunning {
+		t.Errorf("gated is %s after refused suspensions, want running", got)
+	}
+}
+
+func TestSuspendedTaskReclaimedStillSuspended(t *testing.T) {
+	c := NewManualClock(epoch)
+	leases := newFakeLeases(c)
+	dead := newLeasedScheduler(t, leases, "one")
+	survivor := newLeasedScheduler(t, leases, "two")
+
+	mustSchedule(t, dead, &ScheduledTask{ID: "gated", Type: "code_write", Agents: []string{"approbation"}})
+	dead.processQueue()
+	waitUntil(t, "the lease to be taken", func() bool { return leases.owner("gated") == "one" })
+	if err := dead.Suspend("approbation", "gated", "needs sign-off", 10*time.Minute); err != nil {
+		t.Fatalf("Suspend: %v", err)
+	}
+	waitUntil(t, "the lease to record the suspension", func() bool { return leases.suspended("gated") })
+
+	// The owner renews the lease of its suspended task like a running one
+	c.Advance(20 * time.Second)
+	dead.renewLeases(context.Background())
+	c.Advance(20 * time.Second)
+	survivor.reclaimLeases(context.Background())
+	if _, ok := survivor.Task("gated"); ok {
+		t.Fatal("a suspended task with a live lease was reclaimed")
+	}
+
+	// The owner dies; the survivor takes the task over still suspended
+	c.Advance(31 * time.Second)
+	survivor.reclaimLeases(context.Background())
+	if got := stateOf(t, survivor, "gated"); got != TaskSuspended {
+		t.Fatalf("reclaimed task is %s, want suspended", got)
+	}
+	if got := leases.owner("gated"); got != "two" {
+		t.Errorf("lease owner = %q, want the survivor", got)
+	}
+
+	if err := survivor.ResumeTask("gated", Approval{Approved: true, By: "ada"}); err != nil {
+		t.Fatalf("ResumeTask on the survivor: %v", err)
+	}
+	if got := stateOf(t, survivor, "gated"); got != TaskQueued {
+		t.Errorf("approved task is %s, want queued", got)
+	}
+}
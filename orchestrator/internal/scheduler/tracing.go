@@ -0,0 +1,20 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/trace"
+)
+
+// span records a step of a traced task; untraced tasks are skipped
+func (s *Scheduler) span(task *ScheduledTask, name string, start, end time.Time, attrs map[string]string) {
+	if !task.Traced {
+		return
+	}
+	s.traces.Record(trace.Span{TaskID: task.ID, Name: name, Start: start, End: end, Attrs: attrs})
+}
+
+// Trace returns the recorded spans of a traced task
+func (s *Scheduler) Trace(taskID string) []trace.Span {
+	return s.traces.Spans(taskID)
+}
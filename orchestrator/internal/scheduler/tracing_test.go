@@ -0,0 +1,36 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForcedTraceTaskProducesSpans(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.Tracing.MaxTasks = 10
+	s, c := newTestScheduler(t, cfg)
+
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "traced", Traced: true},
+		&ScheduledTask{ID: "plain"},
+	)
+	c.Advance(time.Second)
+	s.processQueue()
+	c.Advance(2 * time.Second)
+	s.completeTask("traced", nil, nil)
+	s.completeTask("plain", nil, nil)
+
+	spans := s.Trace("traced")
+	if len(spans) != 2 || spans[0].Name != "queue" || spans[1].Name != "run" {
+		t.Fatalf("spans = %+v, want queue then run", spans)
+	}
+	if spans[0].Duration() != time.Second || spans[1].Duration() != 2*time.Second {
+		t.Errorf("durations %v and %v, want 1s queued and 2s running", spans[0].Duration(), spans[1].Duration())
+	}
+	if spans[1].Attrs["outcome"] != "completed" {
+		t.Errorf("run outcome = %q, want completed", spans[1].Attrs["outcome"])
+	}
+	if got := s.Trace("plain"); len(got) != 0 {
+		t.Errorf("untraced task has spans %+v", got)
+	}
+}
@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ResultField is the output key agents put their answer under, and the
+// one the built-in transformers rewrite
+const ResultField = "data"
+
+// ResultTransformer post-processes a successful task's output before the
+// task counts as completed. An error fails the task with CategoryInvalid.
+// Transformers run under the scheduler lock, so they must be quick.
+type ResultTransformer func(output map[string]interface{}) (map[string]interface{}, error)
+
+// resultTransformers maps config names to the built-in transformers
+var resultTransformers = map[string]ResultTransformer{
+	"trim":          stringResult(func(s string) (string, error) { return strings.TrimSpace(s), nil }),
+	"extract_code":  stringResult(extractCode),
+	"format_go":     stringResult(formatGo),
+	"validate_json": stringResult(validateJSON),
+}
+
+// RegisterTransformer makes a transformer available to config under name
+func RegisterTransformer(name string, t ResultTransformer) {
+	resultTransformers[name] = t
+}
+
+// NewTransformerChain builds a transformer running the named steps in
+// order, stopping at the first failure
+func NewTransformerChain(names []string) (ResultTransformer, error) {
+	steps := make([]ResultTransformer, 0, len(names))
+	for _, name := range names {
+		t, ok := resultTransformers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown result transformer: %s", name)
+		}
+		steps = append(steps, t)
+	}
+
+	return func(output map[string]interface{}) (map[string]interface{}, error) {
+		var err error
+		for i, step := range steps {
+			if output, err = step(output); err != nil {
+				return nil, fmt.Errorf("%s: %w", names[i], err)
+			}
+		}
+		return output, nil
+	}, nil
+}
+
+// stringResult lifts a string rewrite into a transformer over ResultField.
+// Outputs without a string result pass through unchanged.
+func stringResult(fn func(string) (string, error)) ResultTransformer {
+	return func(output map[string]interface{}) (map[string]interface{}, error) {
+		s, ok := output[ResultField].(string)
+		if !ok {
+			return output, nil
+		}
+		rewritten, err := fn(s)
+		if err != nil {
+			return nil, err
+		}
+
+		out := make(map[string]interface{}, len(output))
+		for k, v := range output {
+			out[k] = v
+		}
+		out[ResultField] = rewritten
+		return out, nil
+	}
+}
+
+var codeBlock = regexp.MustCompile("(?s)```[\\w+.-]*[^\\S\\n]*\\n(.*?)```")
+
+// extractCode replaces a result with the body of its first fenced code
+// block, leaving results without one as they are
+func extractCode(s string) (string, error) {
+	if m := codeBlock.FindStringSubmatch(s); m != nil {
+		return m[1], nil
+	}
+	return s, nil
+}
+
+// formatGo gofmts a Go result, failing when it doesn't parse
+func formatGo(s string) (string, error) {
+	formatted, err := format.Source([]byte(s))
+	if err != nil {
+		return "", fmt.Errorf("invalid Go source: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// validateJSON fails a result that isn't a JSON document
+func validateJSON(s string) (string, error) {
+	if !json.Valid([]byte(s)) {
+		return "", fmt.Errorf("result is not valid JSON")
+	}
+	return s, nil
+}
+
+// SetResultTransformer installs the transformer applied to completed
+// tasks of a type, replacing any configured one; nil removes it
+func (s *Scheduler) SetResultTransformer(taskType string, t ResultTransformer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t == nil {
+		delete(s.transformers, taskType)
+		return
+	}
+	s.transformers[taskType] = t
+}
+
+// newTransformers builds the configured per-type chains, skipping any
+// that name an unknown step
+func (s *Scheduler) newTransformers(cfg map[string][]string) map[string]ResultTransformer {
+	chains := make(map[string]ResultTransformer, len(cfg))
+	for taskType, names := range cfg {
+		chain, err := NewTransformerChain(names)
+		if err != nil {
+			s.logger.Warn("Ignoring result transformers",
+				zap.String("type", taskType),
+				zap.Error(err),
+			)
+			continue
+		}
+		chains[taskType] = chain
+	}
+	return chains
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+func TestExtractCodeTransformerRewritesResult(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.ResultTransformers = map[string][]string{"code_write": {"extract_code", "format_go"}}
+	s, _ := newTestScheduler(t, cfg)
+	transitions := &fakeTransitions{}
+	s.SetTransitions(transitions)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "code_write", Agents: []string{"dev"}})
+	s.processQueue()
+
+	raw := "Here you go:\n```go\npackage main\nfunc main(){}\n```\nEnjoy."
+	err := s.ReportResult("dev", Result{TaskID: "t1", Status: ResultCompleted, Attempt: 1, Output: map[string]interface{}{ResultField: raw}})
+	if err != nil {
+		t.Fatalf("ReportResult: %v", err)
+	}
+	if got := counts(s, "code_write"); got.Completed != 1 {
+		t.Fatalf("tally = %+v, want the task completed", got)
+	}
+
+	var recorded []store.Transition
+	waitUntil(t, "the completion", func() bool {
+		recorded = transitions.recorded()
+		return len(recorded) > 0
+	})
+	if got, want := recorded[0].Result[ResultField], "package main\n\nfunc main() {}\n"; got != want {
+		t.Errorf("stored result = %q, want %q", got, want)
+	}
+}
+
+func TestTransformerFailsTaskOnInvalidOutput(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.ResultTransformers = map[string][]string{"analysis": {"trim", "validate_json"}}
+	s, _ := newTestScheduler(t, cfg)
+	dlq := NewMemoryDeadLetterQueue()
+	s.SetDeadLetters(dlq)
+	mustSchedule(t, s, &ScheduledTask{
+		ID:     "t1",
+		Type:   "analysis",
+		Agents: []string{"analysis"},
+		Retry:  RetryPolicy{RetryOn: []string{CategoryTimeout}},
+	})
+	s.processQueue()
+
+	err := s.ReportResult("analysis", Result{TaskID: "t1", Status: ResultCompleted, Attempt: 1, Output: map[string]interface{}{ResultField: "{not json"}})
+	if err != nil {
+		t.Fatalf("ReportResult: %v", err)
+	}
+	if got := counts(s, "analysis"); got.Failed != 1 || got.Completed != 0 {
+		t.Fatalf("tally = %+v, want the invalid result to fail the task", got)
+	}
+
+	var letters []DeadLetter
+	waitUntil(t, "the dead letter", func() bool {
+		letters, _ = dlq.All(context.Background())
+		return len(letters) > 0
+	})
+	if letters[0].Category != CategoryInvalid || !strings.Contains(letters[0].Error, "validate_json") {
+		t.Errorf("dead letter = %q %q, want an invalid result from validate_json", letters[0].Category, letters[0].Error)
+	}
+}
+
+func TestTransformersApplyPerTaskType(t *testing.T) {
+	cfg := testConfig()
+	cfg.Orchestrator.ResultTransformers = map[string][]string{"analysis": {"validate_json"}}
+	s, _ := newTestScheduler(t, cfg)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "question", Agents: []string{"explain"}})
+	s.processQueue()
+
+	err := s.ReportResult("explain", Result{TaskID: "t1", Status: ResultCompleted, Attempt: 1, Output: map[string]interface{}{ResultField: "plain prose"}})
+	if err != nil {
+		t.Fatalf("ReportResult: %v", err)
+	}
+	if got := counts(s, "question"); got.Completed != 1 {
+		t.Errorf("tally = %+v, want another type's output left alone", got)
+	}
+}
+
+func TestTransformerChainRejectsUnknownStep(t *testing.T) {
+	if _, err := NewTransformerChain([]string{"trim", "summon"}); err == nil {
+		t.Error("chain with an unknown step built")
+	}
+}
+
+func TestSetResultTransformerOverridesConfig(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	s.SetResultTransformer("question", func(output map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("refused")
+	})
+	mustSchedule(t, s, &ScheduledTask{
+		ID:     "t1",
+		Type:   "question",
+		Agents: []string{"explain"},
+		Retry:  RetryPolicy{RetryOn: []string{CategoryTimeout}},
+	})
+	s.processQueue()
+
+	if err := s.ReportResult("explain", Result{TaskID: "t1", Status: ResultCompleted, Attempt: 1}); err != nil {
+		t.Fatalf("ReportResult: %v", err)
+	}
+	if got := counts(s, "question"); got.Failed != 1 {
+		t.Errorf("tally = %+v, want the custom transformer to fail the task", got)
+	}
+}
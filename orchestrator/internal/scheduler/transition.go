@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+	"go.uber.org/zap"
+)
+
+// TransitionStore persists how each run of a task ended;
+// store.PostgresStore satisfies it
+type TransitionStore interface {
+	RecordTransition(ctx context.Context, t store.Transition) error
+}
+
+// SetTransitions records every finished run of a task, with its audit
+// record, in the given store
+func (s *Scheduler) SetTransitions(ts TransitionStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.transitions = ts
+}
+
+// transition describes how a run of task ended: completed, failed for
+// good, or requeued for a retry. attempt is the task's retry count when
+// the run started. Returns nil when no transition store is set. Called
+// with s.mu held.
+func (s *Scheduler) transition(task *ScheduledTask, attempt int, output map[string]interface{}, err error) *store.Transition {
+	if s.transitions == nil {
+		return nil
+	}
+
+	now := s.clock.Now()
+	agent := "orchestrator"
+	if len(task.Agents) > 0 {
+		agent = task.Agents[0]
+	}
+	t := &store.Transition{
+		TaskID:   task.ID,
+		Type:     task.Type,
+		ParentID: task.Parent,
+		Attempt:  attempt,
+		Audit: store.AgentLog{
+			Agent:     agent,
+			Action:    "execute",
+			Success:   err == nil,
+			Duration:  now.Sub(task.started),
+			Output:    output,
+			CreatedAt: now,
+		},
+	}
+	switch {
+	case err == nil:
+		t.Status = store.StatusCompleted
+		t.Result = output
+	case task.State == TaskFailed:
+		t.Status = store.StatusFailed
+		t.Audit.Error = err.Error()
+		// Recorded under the keys postmortem explains failures from
+		t.Result = map[string]interface{}{
+			"error":    err.Error(),
+			"category": categoryOf(err),
+			"agents":   task.Agents,
+			"retries":  task.Retries,
+		}
+	default:
+		t.Status = store.StatusPending
+		t.Audit.Error = err.Error()
+	}
+	return t
+}
+
+// recordTransition persists t, if any. A failure is logged and returned;
+// the run's outcome stands in the scheduler either way.
+func (s *Scheduler) recordTransition(ctx context.Context, t *store.Transition) error {
+	if t == nil {
+		return nil
+	}
+	s.mu.Lock()
+	ts := s.transitions
+	s.mu.Unlock()
+
+	if err := ts.RecordTransition(ctx, *t); err != nil {
+		s.logger.Error("Failed to record task transition",
+			zap.String("id", t.TaskID),
+			zap.String("status", t.Status),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+func TestEachRunRecordsOneTransition(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	transitions := &fakeTransitions{}
+	s.SetTransitions(transitions)
+	mustSchedule(t, s, &ScheduledTask{ID: "t1", Type: "code_write", Agents: []string{"dev"}, Retry: Retries(1)})
+
+	s.processQueue()
+	if err := s.ReportResult("dev", Result{TaskID: "t1", Status: ResultFailed, Error: "compile error", Attempt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	s.processQueue()
+	if err := s.ReportResult("dev", Result{TaskID: "t1", Status: ResultFailed, Error: "compile error", Attempt: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := transitions.recorded()
+	if len(got) != 2 {
+		t.Fatalf("recorded %d transitions, want one per run", len(got))
+	}
+	retried, failed := got[0], got[1]
+	if retried.Status != store.StatusPending || retried.Attempt != 0 || retried.Audit.Success {
+		t.Errorf("first run = %+v, want pending for a retry at attempt 0", retried)
+	}
+	if failed.Status != store.StatusFailed || failed.Attempt != 1 || failed.Audit.Agent != "dev" {
+		t.Errorf("second run = %+v, want failed at attempt 1 by dev", failed)
+	}
+	// Recorded under the keys postmortem explains failures from
+	if failed.Result["error"] == nil || failed.Result["category"] == nil || failed.Result["retries"] != 1 {
+		t.Errorf("failure result = %v", failed.Result)
+	}
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/krigsexe/odin/orchestrator/internal/events"
+	"go.uber.org/zap"
+)
+
+// Tasks with unmet dependencies wait outside the queue, indexed by each
+// dependency they are blocked on, and are queued only once the last of
+// them completes, or fail as soon as one fails or is cancelled. Nothing
+// is polled. All methods here expect s.mu held.
+
+// enqueue queues a task whose dependencies are met, or parks it until
+// they are. A dependency listed more than once is waited on once.
+func (s *Scheduler) enqueue(task *ScheduledTask) {
+	unmet := 0
+	seen := make(map[string]bool, len(task.Dependencies))
+	for _, dep := range task.Dependencies {
+		if seen[dep] || s.completed[dep] {
+			continue
+		}
+		seen[dep] = true
+		s.waitingOn[dep] = append(s.waitingOn[dep], task)
+		unmet++
+	}
+	if unmet == 0 {
+		s.push(task)
+		return
+	}
+
+	task.unmet = unmet
+	s.waiting[task.ID] = task
+	s.record(DecisionWait, task)
+	s.inherit(task)
+}
+
+// dependencyMet releases tasks waiting on a newly completed task
+func (s *Scheduler) dependencyMet(depID string) {
+	waiters := s.waitingOn[depID]
+	delete(s.waitingOn, depID)
+
+	for _, task := range waiters {
+		s.release(task)
+	}
+}
+
+// dependencyFailed fails the tasks waiting on a task that failed or was
+// cancelled, and in turn the tasks waiting on them
+func (s *Scheduler) dependencyFailed(dep *ScheduledTask) {
+	waiters := s.waitingOn[dep.ID]
+	delete(s.waitingOn, dep.ID)
+
+	err := &TaskError{
+		Category: CategoryDependency,
+		Message:  fmt.Sprintf("dependency %s %s", dep.ID, dep.State),
+	}
+	for _, task := range waiters {
+		if !s.unpark(task) {
+			continue
+		}
+		task.State = TaskFailed
+		s.logger.Warn("Task failed with its dependency",
+			zap.String("id", task.ID),
+			zap.String("dependency", dep.ID),
+			zap.String("state", dep.State.String()),
+		)
+		s.emit(events.TaskFailed, task.ID, err.Error())
+		s.deadLetter(task, err)
+		s.stageFinished(task, nil, err.Error())
+		s.settle(task)
+	}
+}
+
+// dropDependency stops a waiting task from waiting on depID, as when a
+// pipeline continues past a failed stage
+func (s *Scheduler) dropDependency(task *ScheduledTask, depID string) {
+	waiters := s.waitingOn[depID]
+	kept := waiters[:0]
+	for _, w := range waiters {
+		if w == task {
+			s.release(task)
+			continue
+		}
+		kept = append(kept, w)
+	}
+	if len(kept) == 0 {
+		delete(s.waitingOn, depID)
+	} else {
+		s.waitingOn[depID] = kept
+	}
+}
+
+// release counts one dependency of a waiting task as met and queues the
+// task when none are left. Tasks no longer waiting (cancelled) are skipped.
+func (s *Scheduler) release(task *ScheduledTask) {
+	if s.waiting[task.ID] != task {
+		return
+	}
+	task.unmet--
+	if task.unmet > 0 {
+		return
+	}
+	delete(s.waiting, task.ID)
+	task.ScheduledAt = s.clock.Now()
+	s.push(task)
+}
+
+// unpark removes a task from the waiting set, reporting whether it was there
+func (s *Scheduler) unpark(task *ScheduledTask) bool {
+	if s.waiting[task.ID] != task {
+		return false
+	}
+	delete(s.waiting, task.ID)
+	return true
+}
@@ -0,0 +1,178 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// waitUntil polls cond until it holds or a second has passed
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBlockedTaskDispatchesOnDependencyCompletion(t *testing.T) {
+	s := startNoTick(t, testConfig())
+
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "dep"},
+		&ScheduledTask{ID: "child", Dependencies: []string{"dep"}},
+	)
+	waitUntil(t, "dep to dispatch", func() bool { return stateOf(t, s, "dep") == TaskRunning })
+
+	// Blocked tasks are parked, not polled through the queue
+	s.mu.Lock()
+	queued := s.queue.Len()
+	s.mu.Unlock()
+	if !waiting(s, "child") || queued != 0 {
+		t.Fatalf("child waiting=%v, queue length %d; want it parked off the queue", waiting(s, "child"), queued)
+	}
+
+	s.completeTask("dep", nil, nil)
+	waitUntil(t, "child to dispatch", func() bool { return stateOf(t, s, "child") == TaskRunning })
+}
+
+func TestDependentsFailTransitively(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "a", Type: "step", Retry: Retries(0)},
+		&ScheduledTask{ID: "b", Type: "step", Dependencies: []string{"a"}},
+		&ScheduledTask{ID: "c", Type: "step", Dependencies: []string{"b"}},
+		&ScheduledTask{ID: "other", Type: "step"},
+	)
+	s.processQueue()
+	s.completeTask("a", nil, errors.New("boom"))
+
+	if waiting(s, "b") || waiting(s, "c") {
+		t.Fatal("dependents still waiting on a failed task")
+	}
+	if got := counts(s, "step"); got.Failed != 3 {
+		t.Errorf("tally = %+v, want a and both dependents failed", got)
+	}
+	if got := stateOf(t, s, "other"); got != TaskRunning {
+		t.Errorf("unrelated task is %s, want running", got)
+	}
+}
+
+func TestDependencyFailureCarriesCategory(t *testing.T) {
+	s, _ := newTestScheduler(t, testConfig())
+	dlq := NewMemoryDeadLetterQueue()
+	s.SetDeadLetters(dlq)
+	mustSchedule(t, s,
+		&ScheduledTask{ID: "a", Retry: Retries(0)},
+		&ScheduledTask{ID: "b", Dependencies: []string{"a"}},
+	)
+	s.processQueue()
+	s.Cancel("a")
+
+	var letters []DeadLetter
+	waitUntil(t, "the dead letter", func() bool {
+		letters, _ = dlq.All(context.Background())
+		return len(letters) > 0
+	})
+	if len(letters) != 1 || letters[0].Task.ID != "b" {
+		t.Fatalf("dead letters = %+v, want the dependent only", letters)
+	}
+	entry := letters[0]
+	if entry.Category != CategoryDependency || !strings.Contains(entry.Error, "dependency a cancelled") {
+		t.Errorf("dead letter = %+v, want a dependency failure naming a", entry)
+	}
+}
+
+// newWideDependent runs n tasks dep0..dep(n-1) and parks "wide" waiting on
+// all of them, dep0 listed twice
+func newWideDependent(tb testing.TB, n int) (*Scheduler, []string) {
+	tb.Helper()
+	cfg := testConfig()
+	cfg.Orchestrator.MaxConcurrentTasks = n
+	s := New(cfg, zap.NewNop())
+	s.SetClock(NewManualClock(epoch))
+
+	deps := make([]string, n)
+	for i := range deps {
+		deps[i] = fmt.Sprintf("dep%d", i)
+		if err := s.Schedule(&ScheduledTask{ID: deps[i]}); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	s.processQueue()
+	wide := &ScheduledTask{ID: "wide", Dependencies: append([]string{deps[0]}, deps...)}
+	if err := s.Schedule(wide); err != nil {
+		tb.Fatal(err)
+	}
+	return s, deps
+}
+
+func TestWideDependentQueuedByLastCompletion(t *testing.T) {
+	s, deps := newWideDependent(t, 5000)
+	unmet := func() int {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.waiting["wide"].unmet
+	}
+	if got := unmet(); got != 5000 {
+		t.Fatalf("unmet = %d, want 5000 with the duplicate counted once", got)
+	}
+
+	for _, id := range deps[:len(deps)-1] {
+		s.completeTask(id, nil, nil)
+	}
+	if !waiting(s, "wide") || unmet() != 1 {
+		t.Fatalf("wide waiting=%v with one dependency left", waiting(s, "wide"))
+	}
+
+	s.completeTask(deps[len(deps)-1], nil, nil)
+	if waiting(s, "wide") {
+		t.Fatal("wide still waiting after its last dependency completed")
+	}
+	s.processQueue()
+	if got := stateOf(t, s, "wide"); got != TaskRunning {
+		t.Errorf("wide is %s, want dispatched", got)
+	}
+}
+
+// BenchmarkWideDependencyRelease completes one dependency of a task
+// waiting on n per iteration; the cost does not grow with n
+func BenchmarkWideDependencyRelease(b *testing.B) {
+	for _, n := range []int{500, 5000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			var s *Scheduler
+			var deps []string
+			for i := 0; i < b.N; i++ {
+				if i%n == 0 {
+					b.StopTimer()
+					s, deps = newWideDependent(b, n)
+					b.StartTimer()
+				}
+				s.completeTask(deps[i%n], nil, nil)
+			}
+		})
+	}
+}
+
+func BenchmarkDependencyRelease(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		s := New(testConfig(), zap.NewNop())
+		s.Schedule(&ScheduledTask{ID: "root"})
+		for j := 0; j < 1000; j++ {
+			s.Schedule(&ScheduledTask{ID: fmt.Sprintf("w%d", j), Dependencies: []string{"root"}})
+		}
+		s.processQueue()
+		b.StartTimer()
+
+		s.completeTask("root", nil, nil)
+	}
+}
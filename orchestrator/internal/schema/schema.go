@@ -0,0 +1,556 @@
+// Package schema validates decoded JSON values against a JSON Schema.
+// It covers the keywords task results are usually described with and
+// rejects a schema using any other validation keyword, rather than
+// silently passing values it cannot check.
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidSchema is returned for a schema that cannot be compiled
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// unsupported are validation keywords this package does not implement
+var unsupported = []string{
+	"$ref", "$dynamicRef", "not", "if", "then", "else",
+	"patternProperties", "propertyNames", "dependentRequired", "dependentSchemas",
+	"prefixItems", "contains", "unevaluatedItems", "unevaluatedProperties",
+}
+
+// typeNames are the JSON Schema type names
+var typeNames = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// Schema is a compiled JSON Schema. The zero value accepts everything.
+type Schema struct {
+	doc interface{} // as written, for encoding
+
+	never      bool // the schema false
+	types      []string
+	enum       []interface{}
+	constant   interface{}
+	hasConst   bool
+	properties map[string]*Schema
+	required   []string
+	additional *Schema // for properties not listed; nil allows any
+	items      *Schema
+	minItems   *int
+	maxItems   *int
+	unique     bool
+	minLength  *int
+	maxLength  *int
+	pattern    *regexp.Regexp
+	minimum    *float64
+	maximum    *float64
+	exclMin    *float64
+	exclMax    *float64
+	multipleOf *float64
+	allOf      []*Schema
+	anyOf      []*Schema
+	oneOf      []*Schema
+}
+
+// Violation is one way a value fails its schema
+type Violation struct {
+	Path    string `json:"path"` // "$" is the value itself, e.g. "$.items[2].name"
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Join renders violations on one line, for error messages
+func Join(violations []Violation) string {
+	parts := make([]string, len(violations))
+	for i, v := range violations {
+		parts[i] = v.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Compile checks and compiles a schema document, as decoded from JSON or
+// YAML: an object, or true/false
+func Compile(doc interface{}) (*Schema, error) {
+	return compile(doc, "$")
+}
+
+func compile(doc interface{}, at string) (*Schema, error) {
+	switch d := doc.(type) {
+	case bool:
+		return &Schema{doc: d, never: !d}, nil
+	case map[string]interface{}:
+		s := &Schema{doc: d}
+		if err := s.compileKeywords(d, at); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("%w: %s must be an object or boolean", ErrInvalidSchema, at)
+}
+
+func (s *Schema) compileKeywords(d map[string]interface{}, at string) error {
+	for _, k := range unsupported {
+		if _, ok := d[k]; ok {
+			return fmt.Errorf("%w: %s uses unsupported keyword %q", ErrInvalidSchema, at, k)
+		}
+	}
+	invalid := func(keyword, want string) error {
+		return fmt.Errorf("%w: %s.%s must be %s", ErrInvalidSchema, at, keyword, want)
+	}
+
+	switch t := d["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return invalid("type", "a type name or list of them")
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return invalid("type", "a type name or list of them")
+	}
+	for _, name := range s.types {
+		if !typeNames[name] {
+			return fmt.Errorf("%w: %s.type: unknown type %q", ErrInvalidSchema, at, name)
+		}
+	}
+
+	if v, ok := d["enum"]; ok {
+		values, ok := v.([]interface{})
+		if !ok || len(values) == 0 {
+			return invalid("enum", "a non-empty list")
+		}
+		s.enum = values
+	}
+	s.constant, s.hasConst = d["const"]
+
+	if v, ok := d["properties"]; ok {
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			return invalid("properties", "an object")
+		}
+		s.properties = make(map[string]*Schema, len(props))
+		for name, sub := range props {
+			compiled, err := compile(sub, at+"."+name)
+			if err != nil {
+				return err
+			}
+			s.properties[name] = compiled
+		}
+	}
+	if v, ok := d["required"]; ok {
+		list, ok := v.([]interface{})
+		if !ok {
+			return invalid("required", "a list of property names")
+		}
+		for _, item := range list {
+			name, ok := item.(string)
+			if !ok {
+				return invalid("required", "a list of property names")
+			}
+			s.required = append(s.required, name)
+		}
+	}
+
+	for _, kw := range []struct {
+		name string
+		dst  **Schema
+	}{{"additionalProperties", &s.additional}, {"items", &s.items}} {
+		v, ok := d[kw.name]
+		if !ok {
+			continue
+		}
+		compiled, err := compile(v, at+"."+kw.name)
+		if err != nil {
+			return err
+		}
+		*kw.dst = compiled
+	}
+
+	for _, kw := range []struct {
+		name string
+		dst  *[]*Schema
+	}{{"allOf", &s.allOf}, {"anyOf", &s.anyOf}, {"oneOf", &s.oneOf}} {
+		v, ok := d[kw.name]
+		if !ok {
+			continue
+		}
+		list, ok := v.([]interface{})
+		if !ok || len(list) == 0 {
+			return invalid(kw.name, "a non-empty list of schemas")
+		}
+		for i, sub := range list {
+			compiled, err := compile(sub, fmt.Sprintf("%s.%s[%d]", at, kw.name, i))
+			if err != nil {
+				return err
+			}
+			*kw.dst = append(*kw.dst, compiled)
+		}
+	}
+
+	for _, kw := range []struct {
+		name string
+		dst  **int
+	}{{"minItems", &s.minItems}, {"maxItems", &s.maxItems}, {"minLength", &s.minLength}, {"maxLength", &s.maxLength}} {
+		v, ok := d[kw.name]
+		if !ok {
+			continue
+		}
+		n, ok := number(v)
+		if !ok || n < 0 || n != math.Trunc(n) {
+			return invalid(kw.name, "a non-negative integer")
+		}
+		count := int(n)
+		*kw.dst = &count
+	}
+
+	for _, kw := range []struct {
+		name string
+		dst  **float64
+	}{{"minimum", &s.minimum}, {"maximum", &s.maximum}, {"exclusiveMinimum", &s.exclMin}, {"exclusiveMaximum", &s.exclMax}, {"multipleOf", &s.multipleOf}} {
+		v, ok := d[kw.name]
+		if !ok {
+			continue
+		}
+		n, ok := number(v)
+		if !ok {
+			return invalid(kw.name, "a number")
+		}
+		*kw.dst = &n
+	}
+	if s.multipleOf != nil && *s.multipleOf <= 0 {
+		return invalid("multipleOf", "greater than 0")
+	}
+
+	if v, ok := d["uniqueItems"]; ok {
+		if s.unique, ok = v.(bool); !ok {
+			return invalid("uniqueItems", "a boolean")
+		}
+	}
+	if v, ok := d["pattern"]; ok {
+		expr, ok := v.(string)
+		if !ok {
+			return invalid("pattern", "a regular expression")
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("%w: %s.pattern: %v", ErrInvalidSchema, at, err)
+		}
+		s.pattern = re
+	}
+	return nil
+}
+
+// MarshalJSON encodes the schema as it was written
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if s.doc == nil {
+		return []byte("true"), nil
+	}
+	return json.Marshal(s.doc)
+}
+
+// UnmarshalJSON compiles a schema document
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	compiled, err := Compile(doc)
+	if err != nil {
+		return err
+	}
+	*s = *compiled
+	return nil
+}
+
+// UnmarshalYAML compiles a schema written in YAML, as in batch files
+func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
+	var doc interface{}
+	if err := node.Decode(&doc); err != nil {
+		return err
+	}
+	compiled, err := Compile(doc)
+	if err != nil {
+		return err
+	}
+	*s = *compiled
+	return nil
+}
+
+// Validate returns every way a decoded JSON value fails the schema, in a
+// stable order, or nil if it conforms
+func (s *Schema) Validate(v interface{}) []Violation {
+	var out []Violation
+	s.validate(v, "$", &out)
+	return out
+}
+
+func (s *Schema) validate(v interface{}, path string, out *[]Violation) {
+	fail := func(format string, args ...interface{}) {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.never {
+		fail("no value is allowed here")
+		return
+	}
+
+	kind := kindOf(v)
+	if len(s.types) > 0 && !s.allowsType(kind) {
+		fail("expected %s, got %s", strings.Join(s.types, " or "), kind)
+		return
+	}
+	if s.enum != nil && !contains(s.enum, v) {
+		fail("must be one of %s", render(s.enum))
+	}
+	if s.hasConst && !equal(s.constant, v) {
+		fail("must be %s", render(s.constant))
+	}
+
+	switch kind {
+	case "object":
+		s.validateObject(asObject(v), path, out)
+	case "array":
+		s.validateArray(asArray(v), path, out)
+	case "string":
+		str := v.(string)
+		length := len([]rune(str))
+		if s.minLength != nil && length < *s.minLength {
+			fail("must be at least %d characters, got %d", *s.minLength, length)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			fail("must be at most %d characters, got %d", *s.maxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			fail("must match %q", s.pattern.String())
+		}
+	case "number", "integer":
+		n, _ := number(v)
+		if s.minimum != nil && n < *s.minimum {
+			fail("must be at least %s, got %s", format(*s.minimum), format(n))
+		}
+		if s.maximum != nil && n > *s.maximum {
+			fail("must be at most %s, got %s", format(*s.maximum), format(n))
+		}
+		if s.exclMin != nil && n <= *s.exclMin {
+			fail("must be greater than %s, got %s", format(*s.exclMin), format(n))
+		}
+		if s.exclMax != nil && n >= *s.exclMax {
+			fail("must be less than %s, got %s", format(*s.exclMax), format(n))
+		}
+		if s.multipleOf != nil {
+			if q := n / *s.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+				fail("must be a multiple of %s, got %s", format(*s.multipleOf), format(n))
+			}
+		}
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(v, path, out)
+	}
+	if len(s.anyOf) > 0 && matching(s.anyOf, v) == 0 {
+		fail("must match at least one schema in anyOf")
+	}
+	if len(s.oneOf) > 0 {
+		if n := matching(s.oneOf, v); n != 1 {
+			fail("must match exactly one schema in oneOf, matched %d", n)
+		}
+	}
+}
+
+func (s *Schema) validateObject(obj map[string]interface{}, path string, out *[]Violation) {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("missing required property %q", name)})
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := path + "." + name
+		if sub, ok := s.properties[name]; ok {
+			sub.validate(obj[name], child, out)
+		} else if s.additional != nil {
+			if s.additional.never {
+				*out = append(*out, Violation{Path: child, Message: "property is not allowed"})
+				continue
+			}
+			s.additional.validate(obj[name], child, out)
+		}
+	}
+}
+
+func (s *Schema) validateArray(arr []interface{}, path string, out *[]Violation) {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("must have at least %d items, got %d", *s.minItems, len(arr))})
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("must have at most %d items, got %d", *s.maxItems, len(arr))})
+	}
+	if s.unique {
+	dupes:
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if equal(arr[i], arr[j]) {
+					*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("items %d and %d are equal", j, i)})
+					break dupes
+				}
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range arr {
+			s.items.validate(item, path+"["+strconv.Itoa(i)+"]", out)
+		}
+	}
+}
+
+// allowsType reports whether a value of the given kind passes "type". An
+// integral number counts as an integer.
+func (s *Schema) allowsType(kind string) bool {
+	for _, t := range s.types {
+		if t == kind || t == "number" && kind == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// matching counts the schemas a value conforms to
+func matching(schemas []*Schema, v interface{}) int {
+	n := 0
+	for _, sub := range schemas {
+		if len(sub.Validate(v)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// kindOf names the JSON type of a decoded value
+func kindOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	if n, ok := number(v); ok {
+		if n == math.Trunc(n) && !math.IsInf(n, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func asObject(v interface{}) map[string]interface{} {
+	obj, _ := v.(map[string]interface{})
+	return obj
+}
+
+func asArray(v interface{}) []interface{} {
+	arr, _ := v.([]interface{})
+	return arr
+}
+
+// number reads any numeric type a JSON or YAML decoder produces
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// equal compares decoded JSON values, numbers by value
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func contains(values []interface{}, v interface{}) bool {
+	for _, candidate := range values {
+		if equal(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func render(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func format(n float64) string {
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
@@ -0,0 +1,143 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+const reviewSchema = `{
+	"type": "object",
+	"required": ["verdict", "score"],
+	"additionalProperties": false,
+	"properties": {
+		"verdict": {"enum": ["approve", "reject"]},
+		"score": {"type": "integer", "minimum": 0, "maximum": 10},
+		"comments": {
+			"type": "array",
+			"maxItems": 2,
+			"items": {"type": "object", "required": ["line"], "properties": {"line": {"type": "integer"}, "text": {"type": "string", "minLength": 1}}}
+		}
+	}
+}`
+
+// decoded parses a JSON document the way agent results arrive
+func decoded(t *testing.T, doc string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func review(t *testing.T) *Schema {
+	t.Helper()
+	var s Schema
+	if err := json.Unmarshal([]byte(reviewSchema), &s); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return &s
+}
+
+func TestConformingValuePasses(t *testing.T) {
+	s := review(t)
+	for _, doc := range []string{
+		`{"verdict": "approve", "score": 7}`,
+		`{"verdict": "reject", "score": 0, "comments": [{"line": 3, "text": "nil check"}]}`,
+	} {
+		if got := s.Validate(decoded(t, doc)); got != nil {
+			t.Errorf("%s: violations %v, want none", doc, got)
+		}
+	}
+}
+
+func TestMalformedValueListsEveryViolation(t *testing.T) {
+	got := review(t).Validate(decoded(t, `{
+		"verdict": "maybe",
+		"score": 7.5,
+		"comments": [{"text": ""}, {"line": 1}, {"line": 2}],
+		"extra": true
+	}`))
+
+	want := []string{
+		`$.comments: must have at most 2 items, got 3`,
+		`$.comments[0]: missing required property "line"`,
+		`$.comments[0].text: must be at least 1 characters, got 0`,
+		`$.extra: property is not allowed`,
+		`$.score: expected integer, got number`,
+		`$.verdict: must be one of ["approve","reject"]`,
+	}
+	var lines []string
+	for _, v := range got {
+		lines = append(lines, v.String())
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("violations:\n%q\nwant:\n%q", lines, want)
+	}
+	if joined := Join(got[:2]); joined != want[0]+"; "+want[1] {
+		t.Errorf("Join = %q", joined)
+	}
+
+	if got := review(t).Validate(decoded(t, `{"score": 3}`)); len(got) != 1 || got[0].Message != `missing required property "verdict"` {
+		t.Errorf("missing property: %v", got)
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	s, err := Compile(decoded(t, `{
+		"anyOf": [{"type": "string"}, {"type": "integer"}],
+		"oneOf": [{"type": "integer", "multipleOf": 2}, {"type": "integer", "multipleOf": 3}, {"type": "string"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value interface{}
+		ok    bool
+	}{
+		{"x", true},
+		{4.0, true},
+		{9.0, true},
+		{6.0, false}, // a multiple of both
+		{5.0, false}, // of neither
+		{true, false},
+	}
+	for _, tt := range tests {
+		if got := s.Validate(tt.value); (len(got) == 0) != tt.ok {
+			t.Errorf("%v: violations %v, want ok=%v", tt.value, got, tt.ok)
+		}
+	}
+}
+
+func TestInvalidSchemasRejected(t *testing.T) {
+	for _, doc := range []string{
+		`{"$ref": "#/defs/x"}`,
+		`{"properties": {"a": {"not": {}}}}`,
+		`{"type": "float"}`,
+		`{"pattern": "("}`,
+		`{"minimum": "zero"}`,
+		`"object"`,
+	} {
+		var s Schema
+		if err := json.Unmarshal([]byte(doc), &s); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("%s: err = %v, want ErrInvalidSchema", doc, err)
+		}
+	}
+}
+
+func TestSchemaRoundTripsAsWritten(t *testing.T) {
+	s := review(t)
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again Schema
+	if err := json.Unmarshal(data, &again); err != nil {
+		t.Fatalf("re-decoding %s: %v", data, err)
+	}
+	if got := again.Validate(decoded(t, `{"verdict": "approve", "score": 11}`)); len(got) != 1 {
+		t.Errorf("round-tripped schema: violations %v, want the maximum enforced", got)
+	}
+}
@@ -0,0 +1,223 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change kinds
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// maxLineDiff bounds the lines-squared work of a line diff; larger texts
+// are reported as replaced whole
+const maxLineDiff = 4_000_000
+
+// resultMetadata are the result fields agents record about the run itself,
+// which differ between runs of the same task
+var resultMetadata = []string{"agents", "cost", "started_at"}
+
+// Change is one difference between two task results
+type Change struct {
+	Path   string      `json:"path"` // dotted field path, [i] for list elements
+	Kind   string      `json:"kind"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+
+	// Lines is a line diff of a modified multi-line string, each line
+	// prefixed with "-", "+" or " "
+	Lines []string `json:"lines,omitempty"`
+}
+
+// ResultDiff compares the results of two runs of a task
+type ResultDiff struct {
+	Base    string   `json:"base"`
+	Other   string   `json:"other"`
+	Changes []Change `json:"changes"`
+}
+
+// Identical reports whether the results matched
+func (d *ResultDiff) Identical() bool {
+	return len(d.Changes) == 0
+}
+
+// DiffOptions tunes CompareResults
+type DiffOptions struct {
+	// IncludeMetadata also compares the run bookkeeping agents record in
+	// results: agents, cost and start time
+	IncludeMetadata bool
+}
+
+// CompareResults diffs the results recorded for two tasks, typically a
+// task and a re-run of it
+func CompareResults(ctx context.Context, st Store, base, other string, opts DiffOptions) (*ResultDiff, error) {
+	before, err := st.Result(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	after, err := st.Result(ctx, other)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.IncludeMetadata {
+		before, after = withoutMetadata(before), withoutMetadata(after)
+	}
+	return &ResultDiff{Base: base, Other: other, Changes: DiffResults(before, after)}, nil
+}
+
+// DiffResults lists the differences between two decoded JSON results,
+// ordered by path
+func DiffResults(before, after map[string]interface{}) []Change {
+	changes := []Change{}
+	diffValue("", before, after, &changes)
+	return changes
+}
+
+func withoutMetadata(result map[string]interface{}) map[string]interface{} {
+	trimmed := make(map[string]interface{}, len(result))
+	for k, v := range result {
+		trimmed[k] = v
+	}
+	for _, k := range resultMetadata {
+		delete(trimmed, k)
+	}
+	return trimmed
+}
+
+// diffValue walks objects and lists together, recording leaves that differ
+func diffValue(path string, before, after interface{}, changes *[]Change) {
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			diffObject(path, b, a, changes)
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok {
+			diffList(path, b, a, changes)
+			return
+		}
+	case string:
+		if a, ok := after.(string); ok {
+			if a != b {
+				change := Change{Path: path, Kind: ChangeModified, Before: b, After: a}
+				if strings.Contains(a, "\n") || strings.Contains(b, "\n") {
+					change.Lines = diffLines(b, a)
+				}
+				*changes = append(*changes, change)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, Change{Path: path, Kind: ChangeModified, Before: before, After: after})
+	}
+}
+
+func diffObject(path string, before, after map[string]interface{}, changes *[]Change) {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		field := k
+		if path != "" {
+			field = path + "." + k
+		}
+		b, inBefore := before[k]
+		a, inAfter := after[k]
+		switch {
+		case !inAfter:
+			*changes = append(*changes, Change{Path: field, Kind: ChangeRemoved, Before: b})
+		case !inBefore:
+			*changes = append(*changes, Change{Path: field, Kind: ChangeAdded, After: a})
+		default:
+			diffValue(field, b, a, changes)
+		}
+	}
+}
+
+// diffList compares lists position by position
+func diffList(path string, before, after []interface{}, changes *[]Change) {
+	for i := 0; i < max(len(before), len(after)); i++ {
+		elem := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(after):
+			*changes = append(*changes, Change{Path: elem, Kind: ChangeRemoved, Before: before[i]})
+		case i >= len(before):
+			*changes = append(*changes, Change{Path: elem, Kind: ChangeAdded, After: after[i]})
+		default:
+			diffValue(elem, before[i], after[i], changes)
+		}
+	}
+}
+
+// diffLines is a longest-common-subsequence line diff
+func diffLines(before, after string) []string {
+	b := strings.Split(before, "\n")
+	a := strings.Split(after, "\n")
+	if len(b)*len(a) > maxLineDiff {
+		lines := make([]string, 0, len(b)+len(a))
+		for _, line := range b {
+			lines = append(lines, "-"+line)
+		}
+		for _, line := range a {
+			lines = append(lines, "+"+line)
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the common subsequence length of b[i:] and a[j:]
+	lcs := make([][]int, len(b)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(a)+1)
+	}
+	for i := len(b) - 1; i >= 0; i-- {
+		for j := len(a) - 1; j >= 0; j-- {
+			if b[i] == a[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(b)+len(a))
+	i, j := 0, 0
+	for i < len(b) && j < len(a) {
+		switch {
+		case b[i] == a[j]:
+			lines = append(lines, " "+b[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "-"+b[i])
+			i++
+		default:
+			lines = append(lines, "+"+a[j])
+			j++
+		}
+	}
+	for ; i < len(b); i++ {
+		lines = append(lines, "-"+b[i])
+	}
+	for ; j < len(a); j++ {
+		lines = append(lines, "+"+a[j])
+	}
+	return lines
+}
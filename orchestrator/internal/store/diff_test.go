@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestIdenticalResultsReportNoDiff(t *testing.T) {
+	st := NewMemory()
+	result := map[string]interface{}{
+		"summary": "added retries",
+		"files":   []interface{}{"a.go", "b.go"},
+		"score":   0.9,
+	}
+	st.SetResult("run-1", result)
+	st.SetResult("run-2", map[string]interface{}{
+		"summary":    "added retries",
+		"files":      []interface{}{"a.go", "b.go"},
+		"score":      0.9,
+		"agents":     []interface{}{"dev-2"},
+		"started_at": "2024-01-02T00:00:00Z",
+	})
+
+	diff, err := CompareResults(context.Background(), st, "run-1", "run-2", DiffOptions{})
+	if err != nil {
+		t.Fatalf("CompareResults: %v", err)
+	}
+	if !diff.Identical() {
+		t.Errorf("changes = %+v, want none with run metadata skipped", diff.Changes)
+	}
+
+	diff, _ = CompareResults(context.Background(), st, "run-1", "run-2", DiffOptions{IncludeMetadata: true})
+	if len(diff.Changes) != 2 {
+		t.Errorf("changes = %+v, want the metadata reported when asked for", diff.Changes)
+	}
+}
+
+func TestChangedResultsReportDifferences(t *testing.T) {
+	before := map[string]interface{}{
+		"code":    "func f() {\n\treturn 1\n}",
+		"files":   []interface{}{"a.go", "b.go"},
+		"review":  map[string]interface{}{"verdict": "lgtm", "nits": 2.0},
+		"dropped": true,
+	}
+	after := map[string]interface{}{
+		"code":   "func f() {\n\treturn 2\n}",
+		"files":  []interface{}{"a.go"},
+		"review": map[string]interface{}{"verdict": "changes", "nits": 2.0},
+		"tests":  "pass",
+	}
+
+	want := []Change{
+		{Path: "code", Kind: ChangeModified, Before: before["code"], After: after["code"],
+			Lines: []string{" func f() {", "-\treturn 1", "+\treturn 2", " }"}},
+		{Path: "dropped", Kind: ChangeRemoved, Before: true},
+		{Path: "files[1]", Kind: ChangeRemoved, Before: "b.go"},
+		{Path: "review.verdict", Kind: ChangeModified, Before: "lgtm", After: "changes"},
+		{Path: "tests", Kind: ChangeAdded, After: "pass"},
+	}
+	if got := DiffResults(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("changes:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestTypeChangeIsModification(t *testing.T) {
+	got := DiffResults(map[string]interface{}{"n": "3"}, map[string]interface{}{"n": 3.0})
+	if len(got) != 1 || got[0].Kind != ChangeModified || got[0].Lines != nil {
+		t.Errorf("changes = %+v, want one modification", got)
+	}
+}
+
+func TestCompareUnknownTask(t *testing.T) {
+	st := NewMemory()
+	st.SetResult("run-1", map[string]interface{}{})
+	if _, err := CompareResults(context.Background(), st, "run-1", "missing", DiffOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,496 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps task state in process memory, mirroring
+// InMemoryStateStore on the Python side
+type MemoryStore struct {
+	mu        sync.RWMutex
+	completed []CompletedRef
+	finished  []TaskRecord
+	archived  map[string]time.Time // soft-deleted, by task ID
+	cold      []TaskRecord         // moved out by MoveArchived
+	notes     map[string][]Note
+	noteSeq   int64
+	results   map[string]map[string]interface{}
+	logs      map[string][]AgentLog
+	outbox    []outboxRecord
+	recorded  map[string]time.Time // transition keys, with when they were relayed
+	parents   map[string]string    // parent task ID, by subtask ID
+}
+
+// outboxRecord is an audit record queued by RecordTransition
+type outboxRecord struct {
+	key    string
+	taskID string
+	log    AgentLog
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *MemoryStore {
+	return &MemoryStore{
+		archived: make(map[string]time.Time),
+		notes:    make(map[string][]Note),
+		results:  make(map[string]map[string]interface{}),
+		logs:     make(map[string][]AgentLog),
+		recorded: make(map[string]time.Time),
+		parents:  make(map[string]string),
+	}
+}
+
+// MarkCompleted records a task as completed at the given time
+func (m *MemoryStore) MarkCompleted(id string, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.completed = append(m.completed, CompletedRef{ID: id, CompletedAt: at})
+	sort.Slice(m.completed, func(i, j int) bool {
+		return refBefore(m.completed[i], m.completed[j].CompletedAt, m.completed[j].ID)
+	})
+}
+
+// AddFinished records a finished task for EachFinished. Completed records
+// also count for ListCompleted.
+func (m *MemoryStore) AddFinished(rec TaskRecord) {
+	if rec.Status == StatusCompleted {
+		m.MarkCompleted(rec.ID, rec.CompletedAt)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.finished = append(m.finished, rec)
+	sort.Slice(m.finished, func(i, j int) bool {
+		a, b := m.finished[i], m.finished[j]
+		if !a.CompletedAt.Equal(b.CompletedAt) {
+			return a.CompletedAt.Before(b.CompletedAt)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// EachFinished calls fn for finished tasks after since
+func (m *MemoryStore) EachFinished(ctx context.Context, since time.Time, fn func(TaskRecord) error) error {
+	m.mu.RLock()
+	records := append([]TaskRecord(nil), m.finished...)
+	m.mu.RUnlock()
+
+	for _, rec := range records {
+		if !rec.CompletedAt.After(since) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachSubmitted calls fn for finished tasks created after since; the
+// memory store holds no unfinished tasks
+func (m *MemoryStore) EachSubmitted(ctx context.Context, since time.Time, fn func(TaskSummary) error) error {
+	m.mu.RLock()
+	tasks := make([]TaskSummary, 0, len(m.finished))
+	for _, rec := range m.finished {
+		if rec.CreatedAt.After(since) {
+			_, archived := m.archived[rec.ID]
+			tasks = append(tasks, summary(rec, archived))
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	for _, t := range tasks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListCompleted pages through completed tasks using a keyset cursor
+func (m *MemoryStore) ListCompleted(ctx context.Context, page Page) ([]CompletedRef, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	refs := make([]CompletedRef, 0, page.Limit)
+	for _, ref := range m.completed {
+		if !refBefore(CompletedRef{ID: page.AfterID, CompletedAt: page.AfterTime}, ref.CompletedAt, ref.ID) {
+			continue
+		}
+		refs = append(refs, ref)
+		if len(refs) == page.Limit {
+			break
+		}
+	}
+	return refs, nil
+}
+
+// ListTasks returns finished tasks newest first
+func (m *MemoryStore) ListTasks(ctx context.Context, opts ListOptions) ([]TaskSummary, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tasks := make([]TaskSummary, 0, len(m.finished))
+	for _, rec := range m.finished {
+		_, archived := m.archived[rec.ID]
+		if archived && !opts.IncludeArchived {
+			continue
+		}
+		tasks = append(tasks, summary(rec, archived))
+	}
+	if opts.IncludeArchived {
+		for _, rec := range m.cold {
+			tasks = append(tasks, summary(rec, true))
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	if opts.Limit > 0 && len(tasks) > opts.Limit {
+		tasks = tasks[:opts.Limit]
+	}
+	return tasks, nil
+}
+
+func summary(rec TaskRecord, archived bool) TaskSummary {
+	completedAt := rec.CompletedAt
+	return TaskSummary{
+		ID:          rec.ID,
+		Type:        rec.Type,
+		Status:      rec.Status,
+		Archived:    archived,
+		CreatedAt:   rec.CreatedAt,
+		CompletedAt: &completedAt,
+	}
+}
+
+// Archive soft-deletes a finished task
+func (m *MemoryStore) Archive(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.archived[id]; ok {
+		return fmt.Errorf("%w: finished task %s", ErrNotFound, id)
+	}
+	for _, rec := range m.finished {
+		if rec.ID == id {
+			m.archived[id] = time.Now()
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: finished task %s", ErrNotFound, id)
+}
+
+// MoveArchived moves old finished tasks to the cold list
+func (m *MemoryStore) MoveArchived(ctx context.Context, before time.Time, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.finished[:0]
+	moved := 0
+	for _, rec := range m.finished {
+		archivedAt, archived := m.archived[rec.ID]
+		old := rec.CompletedAt.Before(before) || (archived && archivedAt.Before(before))
+		if old && moved < limit {
+			m.cold = append(m.cold, rec)
+			delete(m.archived, rec.ID)
+			moved++
+			continue
+		}
+		kept = append(kept, rec)
+	}
+	m.finished = kept
+	return moved, nil
+}
+
+// AddNote appends a note to a task
+func (m *MemoryStore) AddNote(ctx context.Context, taskID, author, text string) (Note, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.noteSeq++
+	note := Note{ID: m.noteSeq, TaskID: taskID, Author: author, Text: text, CreatedAt: time.Now()}
+	m.notes[taskID] = append(m.notes[taskID], note)
+	return note, nil
+}
+
+// Notes returns a task's notes in the order they were added
+func (m *MemoryStore) Notes(ctx context.Context, taskID string) ([]Note, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append(make([]Note, 0, len(m.notes[taskID])), m.notes[taskID]...), nil
+}
+
+// SetResult records a task's result
+func (m *MemoryStore) SetResult(id string, result map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.results[id] = result
+}
+
+// Result returns the result recorded with SetResult
+func (m *MemoryStore) Result(ctx context.Context, id string) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result, ok := m.results[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: result for task %s", ErrNotFound, id)
+	}
+	return result, nil
+}
+
+// Task returns a finished task recorded with AddFinished
+func (m *MemoryStore) Task(ctx context.Context, id string) (TaskSummary, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, rec := range m.finished {
+		if rec.ID == id {
+			_, archived := m.archived[id]
+			return summary(rec, archived), nil
+		}
+	}
+	for _, rec := range m.cold {
+		if rec.ID == id {
+			return summary(rec, true), nil
+		}
+	}
+	return TaskSummary{}, fmt.Errorf("%w: task %s", ErrNotFound, id)
+}
+
+// Children returns the finished subtasks recorded under a parent
+func (m *MemoryStore) Children(ctx context.Context, parentID string) ([]TaskSummary, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []TaskSummary
+	for _, rec := range m.finished {
+		if m.parents[rec.ID] == parentID {
+			_, archived := m.archived[rec.ID]
+			out = append(out, summary(rec, archived))
+		}
+	}
+	for _, rec := range m.cold {
+		if m.parents[rec.ID] == parentID {
+			out = append(out, summary(rec, true))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// AddAgentLog records an agent action against a task
+func (m *MemoryStore) AddAgentLog(taskID string, l AgentLog) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.logs[taskID] = append(m.logs[taskID], l)
+}
+
+// AgentLogs returns the actions recorded with AddAgentLog
+func (m *MemoryStore) AgentLogs(ctx context.Context, taskID string) ([]AgentLog, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append(make([]AgentLog, 0, len(m.logs[taskID])), m.logs[taskID]...), nil
+}
+
+// RecordTransition records a task's result and queues its audit record.
+// A finished task is added as by AddFinished.
+func (m *MemoryStore) RecordTransition(ctx context.Context, t Transition) error {
+	m.mu.Lock()
+	if _, ok := m.recorded[t.key()]; ok {
+		m.mu.Unlock()
+		return nil
+	}
+	m.recorded[t.key()] = time.Time{}
+	m.outbox = append(m.outbox, outboxRecord{key: t.key(), taskID: t.TaskID, log: t.Audit})
+	if t.Result != nil {
+		m.results[t.TaskID] = t.Result
+	}
+	if t.ParentID != "" {
+		m.parents[t.TaskID] = t.ParentID
+	}
+	m.mu.Unlock()
+
+	if t.finished() {
+		m.AddFinished(TaskRecord{
+			ID:          t.TaskID,
+			Type:        t.Type,
+			Status:      t.Status,
+			CreatedAt:   t.Audit.CreatedAt,
+			CompletedAt: t.Audit.CreatedAt,
+		})
+	}
+	return nil
+}
+
+// RelayOutbox moves queued audit records to the agent log
+func (m *MemoryStore) RelayOutbox(ctx context.Context, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := min(limit, len(m.outbox))
+	now := time.Now()
+	for _, rec := range m.outbox[:n] {
+		m.logs[rec.taskID] = append(m.logs[rec.taskID], rec.log)
+		m.recorded[rec.key] = now
+	}
+	m.outbox = m.outbox[n:]
+	return n, nil
+}
+
+// PruneOutbox forgets transitions relayed before the cutoff
+func (m *MemoryStore) PruneOutbox(ctx context.Context, before time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for key, relayed := range m.recorded {
+		if !relayed.IsZero() && relayed.Before(before) {
+			delete(m.recorded, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Prune removes old records of a kind. Tasks and results expire with
+// their task's completion time, audit entries with their own.
+func (m *MemoryStore) Prune(ctx context.Context, kind string, before time.Time, limit int, keep func([]json.RawMessage) error) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expired []interface{}
+	var remove func()
+	switch kind {
+	case KindTasks:
+		ids := make(map[string]bool)
+		for _, rec := range slices.Concat(m.finished, m.cold) {
+			if len(ids) < limit && rec.CompletedAt.Before(before) {
+				ids[rec.ID] = true
+				expired = append(expired, rec)
+			}
+		}
+		remove = func() {
+			gone := func(rec TaskRecord) bool { return ids[rec.ID] }
+			m.finished = slices.DeleteFunc(m.finished, gone)
+			m.cold = slices.DeleteFunc(m.cold, gone)
+			m.completed = slices.DeleteFunc(m.completed, func(ref CompletedRef) bool { return ids[ref.ID] })
+			for id := range ids {
+				delete(m.archived, id)
+				delete(m.notes, id)
+				delete(m.results, id)
+				delete(m.parents, id)
+			}
+		}
+	case KindResults:
+		var ids []string
+		for _, rec := range slices.Concat(m.finished, m.cold) {
+			if result, ok := m.results[rec.ID]; ok && len(ids) < limit && rec.CompletedAt.Before(before) {
+				ids = append(ids, rec.ID)
+				expired = append(expired, map[string]interface{}{"id": rec.ID, "result": result})
+			}
+		}
+		remove = func() {
+			for _, id := range ids {
+				delete(m.results, id)
+			}
+		}
+	case KindAudit:
+		type entry struct {
+			TaskID string `json:"task_id"`
+			AgentLog
+		}
+		taskIDs := make([]string, 0, len(m.logs))
+		for taskID := range m.logs {
+			taskIDs = append(taskIDs, taskID)
+		}
+		sort.Strings(taskIDs)
+		doomed := make(map[string][]int) // expired log indexes by task
+		for _, taskID := range taskIDs {
+			for i, l := range m.logs[taskID] {
+				if len(expired) < limit && l.CreatedAt.Before(before) {
+					doomed[taskID] = append(doomed[taskID], i)
+					expired = append(expired, entry{TaskID: taskID, AgentLog: l})
+				}
+			}
+		}
+		remove = func() {
+			for taskID, indexes := range doomed {
+				logs := m.logs[taskID]
+				kept := make([]AgentLog, 0, len(logs)-len(indexes))
+				for i, l := range logs {
+					if !slices.Contains(indexes, i) {
+						kept = append(kept, l)
+					}
+				}
+				m.logs[taskID] = kept
+			}
+		}
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	if keep != nil {
+		records := make([]json.RawMessage, len(expired))
+		for i, rec := range expired {
+			data, err := json.Marshal(rec)
+			if err != nil {
+				return 0, err
+			}
+			records[i] = data
+		}
+		if err := keep(records); err != nil {
+			return 0, err
+		}
+	}
+	remove()
+	return len(expired), nil
+}
+
+// Close is a no-op for the in-memory store
+func (m *MemoryStore) Close() {}
+
+// refBefore orders refs by (completed_at, id)
+func refBefore(ref CompletedRef, at time.Time, id string) bool {
+	if !ref.CompletedAt.Equal(at) {
+		return ref.CompletedAt.Before(at)
+	}
+	return ref.ID < id
+}
@@ -0,0 +1,222 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// Transient write retries
+const (
+	writeAttempts = 4
+	writeBackoff  = 100 * time.Millisecond
+)
+
+// OutboxRetention is how long relayed outbox records are kept, so a
+// transition recorded again within it is recognised as a duplicate
+const OutboxRetention = 7 * 24 * time.Hour
+
+// Transition is a change of task state together with the audit record
+// that explains it. Recording one commits both or neither.
+type Transition struct {
+	TaskID string
+	Type   string
+	Status string
+
+	// ParentID links a subtask to the task that spawned it; empty for a
+	// top-level task
+	ParentID string
+
+	// Attempt numbers the task's runs from 0; a transition is recorded
+	// once per task and attempt
+	Attempt int
+
+	// Result replaces the task's result; nil keeps the one recorded
+	Result map[string]interface{}
+
+	Audit AgentLog
+}
+
+// key identifies a transition for deduplication
+func (t Transition) key() string {
+	return fmt.Sprintf("%s/%d", t.TaskID, t.Attempt)
+}
+
+// finished reports whether the transition ends the task
+func (t Transition) finished() bool {
+	return t.Status == StatusCompleted || t.Status == StatusFailed || t.Status == StatusCancelled
+}
+
+// RecordTransition updates the task row and writes the audit record to
+// task_outbox in one transaction, retrying transient failures. A
+// transition already in the outbox is skipped, so retrying a commit whose
+// outcome was lost never records it twice.
+func (p *PostgresStore) RecordTransition(ctx context.Context, t Transition) error {
+	record, err := json.Marshal(t.Audit)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	var result []byte
+	if t.Result != nil {
+		if result, err = json.Marshal(t.Result); err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+	}
+
+	err = retryTransient(ctx, func() error {
+		return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+			tag, err := tx.Exec(ctx, `
+				INSERT INTO task_outbox (key, task_id, record)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (key) DO NOTHING`,
+				t.key(), t.TaskID, record,
+			)
+			if err != nil || tag.RowsAffected() == 0 {
+				return err
+			}
+			_, err = tx.Exec(ctx, `
+				INSERT INTO tasks (id, type, status, result, parent_id, updated_at, completed_at)
+				VALUES ($1, $2, $3, $4, NULLIF($6, ''), NOW(), CASE WHEN $5 THEN NOW() END)
+				ON CONFLICT (id) DO UPDATE SET
+					status = EXCLUDED.status,
+					result = COALESCE(EXCLUDED.result, tasks.result),
+					parent_id = COALESCE(EXCLUDED.parent_id, tasks.parent_id),
+					updated_at = NOW(),
+					completed_at = EXCLUDED.completed_at`,
+				t.TaskID, t.Type, t.Status, result, t.finished(), t.ParentID,
+			)
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record transition of %s: %w", t.TaskID, err)
+	}
+	return nil
+}
+
+// RelayOutbox copies up to limit pending audit records into agent_logs
+// and marks them relayed in a single statement, so a crash either relays
+// a record or leaves it pending. It returns how many were relayed.
+func (p *PostgresStore) RelayOutbox(ctx context.Context, limit int) (int, error) {
+	var relayed int64
+	err := retryTransient(ctx, func() error {
+		tag, err := p.pool.Exec(ctx, `
+			WITH batch AS (
+				SELECT id FROM task_outbox
+				WHERE relayed_at IS NULL
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			), relayed AS (
+				UPDATE task_outbox o SET relayed_at = NOW()
+				FROM batch WHERE o.id = batch.id
+				RETURNING o.task_id, o.record, o.created_at
+			)
+			INSERT INTO agent_logs (task_id, agent_name, action, output, duration_ms, success, error, created_at)
+			SELECT task_id,
+			       record->>'agent',
+			       record->>'action',
+			       record->'output',
+			       (record->>'duration')::BIGINT / 1000000,
+			       (record->>'success')::BOOLEAN,
+			       NULLIF(record->>'error', ''),
+			       created_at
+			FROM relayed`,
+			limit,
+		)
+		relayed = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to relay outbox: %w", err)
+	}
+	return int(relayed), nil
+}
+
+// PruneOutbox deletes records relayed before the cutoff
+func (p *PostgresStore) PruneOutbox(ctx context.Context, before time.Time) (int, error) {
+	tag, err := p.pool.Exec(ctx, `DELETE FROM task_outbox WHERE relayed_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune outbox: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// RelayLoop relays the outbox in batches every interval until ctx is
+// cancelled, pruning records past OutboxRetention as it goes. A failed
+// batch stays pending and is picked up by the next round.
+func RelayLoop(ctx context.Context, st Store, interval time.Duration, batchSize int, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		total := 0
+		for {
+			n, err := st.RelayOutbox(ctx, batchSize)
+			total += n
+			if err != nil {
+				logger.Warn("Outbox relay failed", zap.Int("relayed", total), zap.Error(err))
+				break
+			}
+			if n < batchSize {
+				break
+			}
+		}
+		if total > 0 {
+			logger.Debug("Relayed audit records", zap.Int("relayed", total))
+		}
+		if _, err := st.PruneOutbox(ctx, time.Now().Add(-OutboxRetention)); err != nil {
+			logger.Warn("Outbox prune failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// retryTransient runs fn until it succeeds, fails for good or runs out
+// of attempts, backing off between attempts
+func retryTransient(ctx context.Context, fn func() error) error {
+	delay := writeBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == writeAttempts || !transient(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// transient reports whether a failed write may succeed if tried again:
+// lost connections, serialization failures, deadlocks and a server
+// shutting down
+func transient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", "40P01", "57P01", "57P02", "57P03":
+			return true
+		}
+		return pgErr.Code[:2] == "08" // connection exception
+	}
+	var netErr net.Error
+	return pgconn.SafeToRetry(err) || errors.As(err, &netErr)
+}
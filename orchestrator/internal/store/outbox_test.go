@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+func completed(attempt int) Transition {
+	return Transition{
+		TaskID:  "t-1",
+		Type:    "question",
+		Status:  StatusCompleted,
+		Attempt: attempt,
+		Result:  map[string]interface{}{"answer": "42"},
+		Audit:   AgentLog{Agent: "explain", Action: "execute", Success: true},
+	}
+}
+
+func TestTransitionCommitsWithItsAuditRecord(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemory()
+	if err := st.RecordTransition(ctx, completed(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := st.Task(ctx, "t-1")
+	if err != nil || task.Status != StatusCompleted {
+		t.Fatalf("task = %+v, %v; want completed", task, err)
+	}
+	if result, _ := st.Result(ctx, "t-1"); result["answer"] != "42" {
+		t.Errorf("result = %v", result)
+	}
+
+	// The audit record waits in the outbox until relayed
+	if logs, _ := st.AgentLogs(ctx, "t-1"); len(logs) != 0 {
+		t.Fatalf("audit log has %d records before the relay", len(logs))
+	}
+	// Recording the same run again, as after a lost commit, is a no-op
+	if err := st.RecordTransition(ctx, completed(0)); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := st.RelayOutbox(ctx, 10); n != 1 {
+		t.Errorf("relayed %d records, want 1", n)
+	}
+	if n, _ := st.RelayOutbox(ctx, 10); n != 0 {
+		t.Errorf("relayed %d records again", n)
+	}
+	if logs, _ := st.AgentLogs(ctx, "t-1"); len(logs) != 1 || logs[0].Agent != "explain" {
+		t.Errorf("audit log = %+v, want the record once", logs)
+	}
+}
+
+// crashingRelay fails its first relay, as an instance dying mid-batch
+type crashingRelay struct {
+	*MemoryStore
+
+	mu      sync.Mutex
+	crashed bool
+}
+
+func (c *crashingRelay) RelayOutbox(ctx context.Context, limit int) (int, error) {
+	c.mu.Lock()
+	crash := !c.crashed
+	c.crashed = true
+	c.mu.Unlock()
+
+	if crash {
+		return 0, errors.New("connection reset")
+	}
+	return c.MemoryStore.RelayOutbox(ctx, limit)
+}
+
+func TestRelayCrashKeepsRecordPending(t *testing.T) {
+	st := &crashingRelay{MemoryStore: NewMemory()}
+	if err := st.RecordTransition(context.Background(), completed(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RelayLoop(ctx, st, 10*time.Millisecond, 10, zap.NewNop())
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if logs, _ := st.AgentLogs(context.Background(), "t-1"); len(logs) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("record never relayed after the failed batch")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTransientWriteErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, true},
+		{"connection exception", &pgconn.PgError{Code: "08006"}, true},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("refused")}, true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"cancelled", context.Canceled, false},
+		{"other", errors.New("bad input"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transient(tt.err); got != tt.want {
+				t.Errorf("transient = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransientWriteRetried(t *testing.T) {
+	calls := 0
+	err := retryTransient(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("err = %v after %d calls, want success on the second", err, calls)
+	}
+
+	calls = 0
+	err = retryTransient(context.Background(), func() error {
+		calls++
+		return &pgconn.PgError{Code: "23505"}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("err = %v after %d calls, want a permanent error returned at once", err, calls)
+	}
+}
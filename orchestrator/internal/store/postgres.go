@@ -0,0 +1,380 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore reads and writes the tasks table
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgres connects to the database at url
+func NewPostgres(ctx context.Context, url string, maxConns int) (*PostgresStore, error) {
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database url: %w", err)
+	}
+	if maxConns > 0 {
+		cfg.MaxConns = int32(maxConns)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return &PostgresStore{pool: pool}, nil
+}
+
+// ListCompleted pages through completed tasks using a keyset cursor
+func (p *PostgresStore) ListCompleted(ctx context.Context, page Page) ([]CompletedRef, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT id, completed_at FROM tasks
+		WHERE status = $1
+		  AND completed_at IS NOT NULL
+		  AND (completed_at, id) > ($2, $3)
+		ORDER BY completed_at, id
+		LIMIT $4`,
+		StatusCompleted, page.AfterTime, page.AfterID, page.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list completed tasks: %w", err)
+	}
+	defer rows.Close()
+
+	refs := make([]CompletedRef, 0, page.Limit)
+	for rows.Next() {
+		var ref CompletedRef
+		if err := rows.Scan(&ref.ID, &ref.CompletedAt); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// EachFinished streams finished tasks row by row. Priority comes from the
+// task payload; agents, cost and the start time are recorded in the result
+// by the agents, and are zero when absent.
+func (p *PostgresStore) EachFinished(ctx context.Context, since time.Time, fn func(TaskRecord) error) error {
+	rows, err := p.pool.Query(ctx, `
+		SELECT id, type, status,
+		       COALESCE((payload->>'priority')::int, 0),
+		       COALESCE(result->'agents', '[]'::jsonb),
+		       COALESCE((result->>'cost')::float8, 0),
+		       created_at,
+		       (result->>'started_at')::timestamptz,
+		       completed_at
+		FROM tasks
+		WHERE status IN ($1, $2, $3)
+		  AND completed_at IS NOT NULL
+		  AND completed_at > $4
+		ORDER BY completed_at, id`,
+		StatusCompleted, StatusFailed, StatusCancelled, since,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query finished tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			rec       TaskRecord
+			agentsRaw []byte
+			startedAt *time.Time
+		)
+		if err := rows.Scan(&rec.ID, &rec.Type, &rec.Status, &rec.Priority,
+			&agentsRaw, &rec.Cost, &rec.CreatedAt, &startedAt, &rec.CompletedAt); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(agentsRaw, &rec.Agents); err != nil {
+			rec.Agents = nil
+		}
+		if startedAt != nil {
+			rec.WaitTime = startedAt.Sub(rec.CreatedAt)
+			rec.ExecTime = rec.CompletedAt.Sub(*startedAt)
+		} else {
+			rec.ExecTime = rec.CompletedAt.Sub(rec.CreatedAt)
+		}
+
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachSubmitted streams the hot tasks table by creation time
+func (p *PostgresStore) EachSubmitted(ctx context.Context, since time.Time, fn func(TaskSummary) error) error {
+	rows, err := p.pool.Query(ctx, `
+		SELECT id, type, status, archived_at IS NOT NULL, created_at, completed_at
+		FROM tasks
+		WHERE created_at > $1
+		ORDER BY created_at, id`,
+		since,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query submitted tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t TaskSummary
+		if err := rows.Scan(&t.ID, &t.Type, &t.Status, &t.Archived, &t.CreatedAt, &t.CompletedAt); err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ListTasks reads the hot tasks table and, when archived tasks are
+// included, the tasks_archive cold tier
+func (p *PostgresStore) ListTasks(ctx context.Context, opts ListOptions) ([]TaskSummary, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT id, type, status, archived_at IS NOT NULL, created_at, completed_at
+		FROM tasks
+		WHERE $1 OR archived_at IS NULL
+		UNION ALL
+		SELECT id, type, status, TRUE, created_at, completed_at
+		FROM tasks_archive
+		WHERE $1
+		ORDER BY 5 DESC, 1
+		LIMIT $2`,
+		opts.IncludeArchived, opts.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]TaskSummary, 0, opts.Limit)
+	for rows.Next() {
+		var t TaskSummary
+		if err := rows.Scan(&t.ID, &t.Type, &t.Status, &t.Archived, &t.CreatedAt, &t.CompletedAt); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// Archive marks a finished task as archived
+func (p *PostgresStore) Archive(ctx context.Context, id string) error {
+	tag, err := p.pool.Exec(ctx, `
+		UPDATE tasks SET archived_at = NOW()
+		WHERE id = $1
+		  AND status IN ($2, $3, $4)
+		  AND archived_at IS NULL`,
+		id, StatusCompleted, StatusFailed, StatusCancelled,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to archive task: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: finished task %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+// MoveArchived moves one batch of old tasks from tasks to tasks_archive in
+// a single statement. Tasks still referenced by checkpoints or feedback
+// stay in the hot table so those references remain valid.
+func (p *PostgresStore) MoveArchived(ctx context.Context, before time.Time, limit int) (int, error) {
+	tag, err := p.pool.Exec(ctx, `
+		WITH moved AS (
+			DELETE FROM tasks
+			WHERE id IN (
+				SELECT t.id FROM tasks t
+				WHERE ((t.status IN ($1, $2, $3) AND t.completed_at < $4)
+				       OR t.archived_at < $4)
+				  AND NOT EXISTS (SELECT 1 FROM checkpoints c WHERE c.task_id = t.id)
+				  AND NOT EXISTS (SELECT 1 FROM feedback f WHERE f.task_id = t.id)
+				ORDER BY t.completed_at NULLS LAST, t.id
+				LIMIT $5
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, type, status, payload, result, confidence, sources, parent_id,
+			          created_at, updated_at, completed_at, archived_at
+		)
+		INSERT INTO tasks_archive (id, type, status, payload, result, confidence, sources, parent_id,
+		                           created_at, updated_at, completed_at, archived_at)
+		SELECT id, type, status, payload, result, confidence, sources, parent_id,
+		       created_at, updated_at, completed_at, COALESCE(archived_at, NOW())
+		FROM moved`,
+		StatusCompleted, StatusFailed, StatusCancelled, before, limit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move archived tasks: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// AddNote inserts a note into task_notes
+func (p *PostgresStore) AddNote(ctx context.Context, taskID, author, text string) (Note, error) {
+	note := Note{TaskID: taskID, Author: author, Text: text}
+	err := p.pool.QueryRow(ctx, `
+		INSERT INTO task_notes (task_id, author, text)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`,
+		taskID, author, text,
+	).Scan(&note.ID, &note.CreatedAt)
+	if err != nil {
+		return Note{}, fmt.Errorf("failed to add note: %w", err)
+	}
+	return note, nil
+}
+
+// Notes returns a task's notes ordered by insertion
+func (p *PostgresStore) Notes(ctx context.Context, taskID string) ([]Note, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT id, task_id, author, text, created_at FROM task_notes
+		WHERE task_id = $1
+		ORDER BY id`,
+		taskID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := make([]Note, 0)
+	for rows.Next() {
+		var note Note
+		if err := rows.Scan(&note.ID, &note.TaskID, &note.Author, &note.Text, &note.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// Result reads a task's result from the hot table, falling back to
+// tasks_archive
+func (p *PostgresStore) Result(ctx context.Context, id string) (map[string]interface{}, error) {
+	var raw []byte
+	err := p.pool.QueryRow(ctx, `
+		SELECT result FROM tasks WHERE id = $1 AND result IS NOT NULL
+		UNION ALL
+		SELECT result FROM tasks_archive WHERE id = $1 AND result IS NOT NULL
+		LIMIT 1`,
+		id,
+	).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: result for task %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result: %w", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("result for task %s: %w", id, err)
+	}
+	return result, nil
+}
+
+// Task reads one task from the hot table, falling back to tasks_archive
+func (p *PostgresStore) Task(ctx context.Context, id string) (TaskSummary, error) {
+	var t TaskSummary
+	err := p.pool.QueryRow(ctx, `
+		SELECT id, type, status, archived_at IS NOT NULL, created_at, completed_at
+		FROM tasks WHERE id = $1
+		UNION ALL
+		SELECT id, type, status, TRUE, created_at, completed_at
+		FROM tasks_archive WHERE id = $1
+		LIMIT 1`,
+		id,
+	).Scan(&t.ID, &t.Type, &t.Status, &t.Archived, &t.CreatedAt, &t.CompletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return TaskSummary{}, fmt.Errorf("%w: task %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return TaskSummary{}, fmt.Errorf("failed to read task: %w", err)
+	}
+	return t, nil
+}
+
+// Children lists the subtasks recorded under a parent, hot or archived,
+// oldest first
+func (p *PostgresStore) Children(ctx context.Context, parentID string) ([]TaskSummary, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT id, type, status, archived_at IS NOT NULL, created_at, completed_at
+		FROM tasks WHERE parent_id = $1
+		UNION ALL
+		SELECT id, type, status, TRUE, created_at, completed_at
+		FROM tasks_archive WHERE parent_id = $1
+		ORDER BY 5, 1`,
+		parentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subtasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []TaskSummary
+	for rows.Next() {
+		var t TaskSummary
+		if err := rows.Scan(&t.ID, &t.Type, &t.Status, &t.Archived, &t.CreatedAt, &t.CompletedAt); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// AgentLogs reads a task's rows of agent_logs in the order they were
+// written
+func (p *PostgresStore) AgentLogs(ctx context.Context, taskID string) ([]AgentLog, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT agent_name, action, COALESCE(success, FALSE), COALESCE(error, ''),
+		       COALESCE(duration_ms, 0), output, created_at
+		FROM agent_logs
+		WHERE task_id = $1
+		ORDER BY created_at, id`,
+		taskID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := make([]AgentLog, 0)
+	for rows.Next() {
+		var (
+			l          AgentLog
+			durationMS int64
+			output     []byte
+		)
+		if err := rows.Scan(&l.Agent, &l.Action, &l.Success, &l.Error, &durationMS, &output, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		l.Duration = time.Duration(durationMS) * time.Millisecond
+		if output != nil {
+			if err := json.Unmarshal(output, &l.Output); err != nil {
+				l.Output = nil
+			}
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// Ping checks that the database accepts connections
+func (p *PostgresStore) Ping(ctx context.Context) error {
+	return p.pool.Ping(ctx)
+}
+
+// Close releases the connection pool
+func (p *PostgresStore) Close() {
+	p.pool.Close()
+}
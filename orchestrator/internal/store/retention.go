@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Record kinds with their own retention window
+const (
+	KindTasks   = "tasks"
+	KindResults = "results"
+	KindAudit   = "audit"
+)
+
+// Kinds lists the record kinds in the order a sweep prunes them
+var Kinds = []string{KindResults, KindAudit, KindTasks}
+
+// ErrUnknownKind is returned when pruning a record kind that does not exist
+var ErrUnknownKind = errors.New("unknown record kind")
+
+// pruneStatements remove one batch of a kind's expired records and
+// return each as JSON, in the order the tables are pruned. $1 is the
+// cutoff and $2 the batch size. Tasks referenced by checkpoints or
+// feedback are kept, as MoveArchived keeps them hot.
+var pruneStatements = map[string][]string{
+	KindTasks: {`
+		WITH batch AS (
+			SELECT t.id FROM tasks t
+			WHERE t.status IN ('completed', 'failed', 'cancelled') AND t.completed_at < $1
+			  AND NOT EXISTS (SELECT 1 FROM checkpoints c WHERE c.task_id = t.id)
+			  AND NOT EXISTS (SELECT 1 FROM feedback f WHERE f.task_id = t.id)
+			ORDER BY t.completed_at, t.id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), notes AS (
+			DELETE FROM task_notes n USING batch WHERE n.task_id = batch.id
+		)
+		DELETE FROM tasks t USING batch WHERE t.id = batch.id
+		RETURNING to_jsonb(t)`, `
+		WITH batch AS (
+			SELECT id FROM tasks_archive
+			WHERE COALESCE(completed_at, archived_at) < $1
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), notes AS (
+			DELETE FROM task_notes n USING batch WHERE n.task_id = batch.id
+		)
+		DELETE FROM tasks_archive t USING batch WHERE t.id = batch.id
+		RETURNING to_jsonb(t)`,
+	},
+	KindResults: {`
+		WITH batch AS (
+			SELECT id, result FROM tasks
+			WHERE completed_at < $1 AND result IS NOT NULL
+			ORDER BY completed_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), cleared AS (
+			UPDATE tasks t SET result = NULL FROM batch WHERE t.id = batch.id
+		)
+		SELECT jsonb_build_object('id', id, 'result', result) FROM batch`, `
+		WITH batch AS (
+			SELECT id, result FROM tasks_archive
+			WHERE completed_at < $1 AND result IS NOT NULL
+			ORDER BY completed_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), cleared AS (
+			UPDATE tasks_archive t SET result = NULL FROM batch WHERE t.id = batch.id
+		)
+		SELECT jsonb_build_object('id', id, 'result', result) FROM batch`,
+	},
+	KindAudit: {`
+		WITH batch AS (
+			SELECT id FROM agent_logs
+			WHERE created_at < $1
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		DELETE FROM agent_logs l USING batch WHERE l.id = batch.id
+		RETURNING to_jsonb(l)`,
+	},
+}
+
+// Prune removes up to limit records of a kind older than the cutoff,
+// each table's share in its own short transaction. keep, when set, is
+// handed every batch before it is committed; its error rolls the batch
+// back. A batch retried after a transient failure is handed over again.
+func (p *PostgresStore) Prune(ctx context.Context, kind string, before time.Time, limit int, keep func([]json.RawMessage) error) (int, error) {
+	statements, ok := pruneStatements[kind]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+
+	total := 0
+	for _, stmt := range statements {
+		if total >= limit {
+			break
+		}
+		var n int
+		err := retryTransient(ctx, func() error {
+			return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+				rows, err := tx.Query(ctx, stmt, before, limit-total)
+				if err != nil {
+					return err
+				}
+				records, err := pgx.CollectRows(rows, pgx.RowTo[json.RawMessage])
+				if err != nil {
+					return err
+				}
+				n = len(records)
+				if keep == nil || n == 0 {
+					return nil
+				}
+				return keep(records)
+			})
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to prune %s: %w", kind, err)
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,172 @@
+// =============================================================================
+// ODIN v7.0 - Task Store
+// =============================================================================
+// Persistent task records shared with the Python agents' StateStore
+// =============================================================================
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("not found")
+
+// Task status values, matching agents/shared/state_store.py
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// CompletedRef identifies a completed task and when it finished
+type CompletedRef struct {
+	ID          string
+	CompletedAt time.Time
+}
+
+// Page is a keyset cursor over (completed_at, id)
+type Page struct {
+	AfterTime time.Time
+	AfterID   string
+	Limit     int
+}
+
+// Next returns the cursor following the given page of results
+func (p Page) Next(refs []CompletedRef) Page {
+	if len(refs) == 0 {
+		return p
+	}
+	last := refs[len(refs)-1]
+	return Page{AfterTime: last.CompletedAt, AfterID: last.ID, Limit: p.Limit}
+}
+
+// TaskRecord is a finished task as kept for analysis
+type TaskRecord struct {
+	ID          string        `json:"id"`
+	Type        string        `json:"type"`
+	Priority    int           `json:"priority"`
+	Status      string        `json:"status"`
+	Agents      []string      `json:"agents"`
+	Cost        float64       `json:"cost"`
+	WaitTime    time.Duration `json:"wait_time"`
+	ExecTime    time.Duration `json:"exec_time"`
+	CreatedAt   time.Time     `json:"created_at"`
+	CompletedAt time.Time     `json:"completed_at"`
+}
+
+// TaskSummary is a task as shown in listings
+type TaskSummary struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`
+	Archived    bool       `json:"archived"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ListOptions filters ListTasks
+type ListOptions struct {
+	// IncludeArchived adds soft-deleted and cold-storage tasks
+	IncludeArchived bool
+	Limit           int
+}
+
+// Note is an operator annotation on a task. Notes are append-only.
+type Note struct {
+	ID        int64     `json:"id"`
+	TaskID    string    `json:"task_id"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AgentLog is one agent action recorded against a task in the
+// agent_logs audit table
+type AgentLog struct {
+	Agent     string                 `json:"agent"`
+	Action    string                 `json:"action"`
+	Success   bool                   `json:"success"`
+	Error     string                 `json:"error,omitempty"`
+	Duration  time.Duration          `json:"duration"`
+	Output    map[string]interface{} `json:"output,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Store persists task state
+type Store interface {
+	// ListCompleted returns completed tasks finished after the page cursor,
+	// ordered by completion time then ID
+	ListCompleted(ctx context.Context, page Page) ([]CompletedRef, error)
+
+	// EachFinished calls fn for every finished task (completed, failed or
+	// cancelled) that finished after since, in completion order, without
+	// loading the whole result set. Iteration stops at fn's first error.
+	EachFinished(ctx context.Context, since time.Time, fn func(TaskRecord) error) error
+
+	// EachSubmitted calls fn for every task created after since, whatever
+	// its status, in creation order. Iteration stops at fn's first error.
+	EachSubmitted(ctx context.Context, since time.Time, fn func(TaskSummary) error) error
+
+	// ListTasks returns tasks newest first. Archived tasks are left out
+	// unless opts.IncludeArchived is set.
+	ListTasks(ctx context.Context, opts ListOptions) ([]TaskSummary, error)
+
+	// Archive soft-deletes a finished task: it is kept but drops out of
+	// default listings. Returns ErrNotFound for unknown or unfinished tasks.
+	Archive(ctx context.Context, id string) error
+
+	// MoveArchived moves up to limit tasks that finished, or were
+	// soft-deleted, before the cutoff into cold storage and returns how
+	// many were moved
+	MoveArchived(ctx context.Context, before time.Time, limit int) (int, error)
+
+	// AddNote appends a timestamped note to a task
+	AddNote(ctx context.Context, taskID, author, text string) (Note, error)
+
+	// Notes returns a task's notes in the order they were added
+	Notes(ctx context.Context, taskID string) ([]Note, error)
+
+	// Result returns the result recorded for a task, archived or not.
+	// Returns ErrNotFound for unknown tasks and tasks with no result.
+	Result(ctx context.Context, id string) (map[string]interface{}, error)
+
+	// Task returns one task, archived or not. Returns ErrNotFound for
+	// unknown tasks.
+	Task(ctx context.Context, id string) (TaskSummary, error)
+
+	// Children returns the subtasks recorded under a parent task,
+	// archived or not, oldest first
+	Children(ctx context.Context, parentID string) ([]TaskSummary, error)
+
+	// AgentLogs returns the agent actions logged against a task, oldest
+	// first
+	AgentLogs(ctx context.Context, taskID string) ([]AgentLog, error)
+
+	// RecordTransition atomically applies a change of task state and
+	// queues its audit record in the outbox. Recording the same task and
+	// attempt again is a no-op.
+	RecordTransition(ctx context.Context, t Transition) error
+
+	// RelayOutbox moves up to limit queued audit records into the agent
+	// log, each exactly once, and returns how many were moved
+	RelayOutbox(ctx context.Context, limit int) (int, error)
+
+	// PruneOutbox forgets records relayed before the cutoff
+	PruneOutbox(ctx context.Context, before time.Time) (int, error)
+
+	// Prune removes up to limit records of a kind (KindTasks, KindResults
+	// or KindAudit) older than the cutoff and returns how many it removed.
+	// keep, when set, is handed each batch as JSON before it is removed,
+	// and its error leaves the batch in place. Pruning results clears them
+	// from tasks that are kept.
+	Prune(ctx context.Context, kind string, before time.Time, limit int, keep func([]json.RawMessage) error) (int, error)
+
+	Close()
+}
@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+func TestLargePayloadRoundTrips(t *testing.T) {
+	payload := []byte(`{"code": "` + strings.Repeat("func main() {}\n", 2000) + `"}`)
+
+	for _, codec := range []string{CodecGzip, CodecZstd} {
+		t.Run(codec, func(t *testing.T) {
+			c, err := NewCompressor(config.CompressionConfig{Codec: codec, Threshold: 1024})
+			if err != nil {
+				t.Fatalf("NewCompressor: %v", err)
+			}
+			data, used, err := c.Encode(payload)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if used != codec {
+				t.Fatalf("codec = %q, want %q", used, codec)
+			}
+			if len(data) >= len(payload) {
+				t.Errorf("encoded %d bytes from %d, want it smaller", len(data), len(payload))
+			}
+
+			// As a consumer reads it back off the stream
+			msg, ok := decodeEntry(map[string]interface{}{"type": "task", "payload": data, "codec": used, "priority": "2"})
+			if !ok {
+				t.Fatal("entry did not decode")
+			}
+			if !bytes.Equal(msg.Payload, payload) || msg.Priority != 2 {
+				t.Errorf("decoded message differs from what was published")
+			}
+		})
+	}
+}
+
+func TestSmallPayloadStaysRaw(t *testing.T) {
+	c, err := NewCompressor(config.CompressionConfig{Codec: CodecGzip, Threshold: 1024})
+	if err != nil {
+		t.Fatalf("NewCompressor: %v", err)
+	}
+	payload := []byte(`{"question": "6 x 7?"}`)
+	data, codec, err := c.Encode(payload)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if codec != CodecNone || data != string(payload) {
+		t.Errorf("Encode = %q with codec %q, want the payload raw", data, codec)
+	}
+}
+
+func TestCompressionDisabledAndUnknownCodec(t *testing.T) {
+	c, err := NewCompressor(config.CompressionConfig{Codec: "none"})
+	if err != nil {
+		t.Fatalf("NewCompressor: %v", err)
+	}
+	if _, codec, _ := c.Encode(bytes.Repeat([]byte("x"), 1<<16)); codec != CodecNone {
+		t.Errorf("disabled compressor used %q", codec)
+	}
+
+	if _, err := NewCompressor(config.CompressionConfig{Codec: "lz4"}); err == nil {
+		t.Error("unknown codec accepted")
+	}
+	if _, err := Decode("AAAA", "lz4"); err == nil {
+		t.Error("Decode accepted an unknown codec")
+	}
+	if _, ok := decodeEntry(map[string]interface{}{"payload": "not base64!", "codec": CodecGzip}); ok {
+		t.Error("a corrupt payload decoded")
+	}
+}
@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Consumer reads messages from a Redis stream. Every consumer sees every
+// message published after it starts; there is no consumer group.
+type Consumer struct {
+	client *redis.Client
+	block  time.Duration
+}
+
+// NewConsumer creates a stream consumer
+func NewConsumer(client *redis.Client) *Consumer {
+	return &Consumer{client: client, block: 5 * time.Second}
+}
+
+// Consume calls fn for each message appended to odin:<channel> until ctx
+// is cancelled. Payloads are decompressed before fn sees them; messages
+// that can't be decoded are skipped.
+func (c *Consumer) Consume(ctx context.Context, channel string, fn func(Message)) error {
+	stream := prefix + channel
+	last := "$"
+
+	for {
+		res, err := c.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{stream, last},
+			Block:   c.block,
+			Count:   100,
+		}).Result()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", channel, err)
+		}
+
+		for _, s := range res {
+			for _, entry := range s.Messages {
+				last = entry.ID
+				if msg, ok := decodeEntry(entry.Values); ok {
+					fn(msg)
+				}
+			}
+		}
+	}
+}
+
+// decodeEntry turns stream fields back into a Message
+func decodeEntry(values map[string]interface{}) (Message, bool) {
+	field := func(name string) string {
+		v, _ := values[name].(string)
+		return v
+	}
+
+	payload, err := Decode(field("payload"), field("codec"))
+	if err != nil {
+		return Message{}, false
+	}
+	priority, _ := strconv.Atoi(field("priority"))
+
+	return Message{
+		Type:     field("type"),
+		Source:   field("source"),
+		Target:   field("target"),
+		Payload:  payload,
+		Priority: priority,
+	}, true
+}
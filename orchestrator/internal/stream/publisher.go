@@ -0,0 +1,87 @@
+package stream
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// prefix matches the stream prefix used by the Python message bus
+const prefix = "odin:"
+
+// maxLen caps stream length, approximately, like the agents' publisher
+const maxLen = 10000
+
+// Message mirrors the agents' message format (agents/shared/message_bus.py)
+type Message struct {
+	Type     string
+	Source   string
+	Target   string
+	Payload  []byte // JSON document
+	Priority int
+}
+
+// Publisher writes messages to Redis streams
+type Publisher struct {
+	client     *redis.Client
+	compressor *Compressor
+}
+
+// NewPublisher creates a stream publisher
+func NewPublisher(client *redis.Client, compressor *Compressor) *Publisher {
+	return &Publisher{client: client, compressor: compressor}
+}
+
+// Publish appends a message to odin:<channel>, compressing the payload
+// when it is large enough
+func (p *Publisher) Publish(ctx context.Context, channel string, msg Message) error {
+	payload, codec, err := p.compressor.Encode(msg.Payload)
+	if err != nil {
+		return err
+	}
+
+	target := msg.Target
+	if target == "" {
+		target = "*"
+	}
+
+	values := map[string]interface{}{
+		"id":             newMessageID(),
+		"type":           msg.Type,
+		"source":         msg.Source,
+		"target":         target,
+		"payload":        payload,
+		"priority":       msg.Priority,
+		"correlation_id": "",
+		"timestamp":      strconv.FormatFloat(float64(time.Now().UnixNano())/1e9, 'f', 6, 64),
+		"ttl":            "3600",
+	}
+	if codec != CodecNone {
+		values["codec"] = codec
+	}
+
+	err = p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: prefix + channel,
+		MaxLen: maxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", channel, err)
+	}
+	return nil
+}
+
+// newMessageID generates a random message identifier
+func newMessageID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("msg-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,244 @@
+// =============================================================================
+// ODIN v7.0 - Task Summaries
+// =============================================================================
+// Rolls submitted and finished tasks up into time buckets for dashboards
+// =============================================================================
+
+package summary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+// MaxBuckets bounds the buckets one summary may span
+const MaxBuckets = 10000
+
+const day = 24 * time.Hour
+
+// ErrInvalidQuery is returned for a query that cannot be summarized
+var ErrInvalidQuery = errors.New("invalid summary query")
+
+// errDone stops iteration once records pass the end of the range
+var errDone = errors.New("done")
+
+// Query selects the buckets of a summary: those from the one holding
+// Since up to Until
+type Query struct {
+	// Bucket evenly divides a day, or is a whole number of days
+	Bucket time.Duration
+	Since  time.Time
+	Until  time.Time
+
+	// Location's wall clock is what buckets align to, so hourly buckets
+	// start on local hours and daily ones at local midnight; nil is UTC
+	Location *time.Location
+}
+
+// Counts tallies tasks by outcome
+type Counts struct {
+	Submitted int `json:"submitted"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+}
+
+// Bucket is the tasks submitted or finished in one time slot. An agent is
+// only known once a task finishes, so ByAgent counts no submissions.
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Counts
+	ByType  map[string]*Counts `json:"by_type,omitempty"`
+	ByAgent map[string]*Counts `json:"by_agent,omitempty"`
+}
+
+// Summary is consecutive buckets, empty ones included
+type Summary struct {
+	Bucket   string    `json:"bucket"`
+	Location string    `json:"location"`
+	Since    time.Time `json:"since"` // start of the first bucket
+	Until    time.Time `json:"until"`
+	Buckets  []Bucket  `json:"buckets"`
+}
+
+// ParseBucket reads a bucket size: a duration such as 15m or 1h, or a
+// number of days such as 1d or 7d
+func ParseBucket(s string) (time.Duration, error) {
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("%w: bucket %q", ErrInvalidQuery, s)
+		}
+		return time.Duration(days) * day, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bucket %q", ErrInvalidQuery, s)
+	}
+	return d, nil
+}
+
+// Summarize counts the tasks in st submitted and finished in each bucket
+// of the query. Tasks moved to cold storage are not counted.
+func Summarize(ctx context.Context, st store.Store, q Query) (*Summary, error) {
+	if q.Location == nil {
+		q.Location = time.UTC
+	}
+	switch {
+	case q.Bucket < time.Minute:
+		return nil, fmt.Errorf("%w: bucket must be at least a minute", ErrInvalidQuery)
+	case day%q.Bucket != 0 && q.Bucket%day != 0:
+		return nil, fmt.Errorf("%w: bucket %s neither divides a day nor is whole days", ErrInvalidQuery, q.Bucket)
+	case !q.Until.After(q.Since):
+		return nil, fmt.Errorf("%w: until must be after since", ErrInvalidQuery)
+	case q.Until.Sub(q.Since)/q.Bucket >= MaxBuckets:
+		return nil, fmt.Errorf("%w: more than %d buckets", ErrInvalidQuery, MaxBuckets)
+	}
+
+	first := q.floor(q.Since)
+	sum := &Summary{
+		Bucket:   formatBucket(q.Bucket),
+		Location: q.Location.String(),
+		Since:    first,
+		Until:    q.Until,
+	}
+	index := make(map[int64]int)
+	for start := first; start.Before(q.Until); start = q.next(start) {
+		index[start.UnixNano()] = len(sum.Buckets)
+		sum.Buckets = append(sum.Buckets, Bucket{Start: start})
+	}
+
+	// A record's bucket is always one of the above, except around a
+	// daylight saving change that shifts alignment; such a bucket is
+	// added and put in order afterwards
+	added := false
+	bucketOf := func(at time.Time) *Bucket {
+		start := q.floor(at)
+		i, ok := index[start.UnixNano()]
+		if !ok {
+			i = len(sum.Buckets)
+			index[start.UnixNano()] = i
+			sum.Buckets = append(sum.Buckets, Bucket{Start: start})
+			added = true
+		}
+		return &sum.Buckets[i]
+	}
+
+	// The store's bound is exclusive
+	after := first.Add(-time.Nanosecond)
+	err := st.EachSubmitted(ctx, after, func(t store.TaskSummary) error {
+		if !t.CreatedAt.Before(q.Until) {
+			return errDone
+		}
+		b := bucketOf(t.CreatedAt)
+		b.Submitted++
+		b.byType(t.Type).Submitted++
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDone) {
+		return nil, fmt.Errorf("failed to count submitted tasks: %w", err)
+	}
+
+	err = st.EachFinished(ctx, after, func(rec store.TaskRecord) error {
+		if !rec.CompletedAt.Before(q.Until) {
+			return errDone
+		}
+		b := bucketOf(rec.CompletedAt)
+		counts := []*Counts{&b.Counts, b.byType(rec.Type)}
+		for _, agent := range rec.Agents {
+			counts = append(counts, b.byAgent(agent))
+		}
+		for _, c := range counts {
+			c.add(rec.Status)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDone) {
+		return nil, fmt.Errorf("failed to count finished tasks: %w", err)
+	}
+
+	if added {
+		sort.Slice(sum.Buckets, func(i, j int) bool { return sum.Buckets[i].Start.Before(sum.Buckets[j].Start) })
+	}
+	return sum, nil
+}
+
+// floor returns the start of the bucket holding t
+func (q Query) floor(t time.Time) time.Time {
+	t = t.In(q.Location)
+	if q.Bucket%day == 0 {
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, q.Location)
+		// Count calendar days from the epoch, so multi-day buckets line
+		// up the same way whatever the range
+		days := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		return midnight.AddDate(0, 0, -int(days%int64(q.Bucket/day)))
+	}
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(q.Bucket).Add(-shift)
+}
+
+// next returns the start of the bucket after the one starting at start
+func (q Query) next(start time.Time) time.Time {
+	var next time.Time
+	if q.Bucket%day == 0 {
+		next = q.floor(start.AddDate(0, 0, int(q.Bucket/day)))
+	} else {
+		next = q.floor(start.Add(q.Bucket))
+	}
+	if !next.After(start) {
+		next = start.Add(q.Bucket)
+	}
+	return next
+}
+
+func (b *Bucket) byType(taskType string) *Counts {
+	if b.ByType == nil {
+		b.ByType = make(map[string]*Counts)
+	}
+	c, ok := b.ByType[taskType]
+	if !ok {
+		c = &Counts{}
+		b.ByType[taskType] = c
+	}
+	return c
+}
+
+func (b *Bucket) byAgent(agent string) *Counts {
+	if b.ByAgent == nil {
+		b.ByAgent = make(map[string]*Counts)
+	}
+	c, ok := b.ByAgent[agent]
+	if !ok {
+		c = &Counts{}
+		b.ByAgent[agent] = c
+	}
+	return c
+}
+
+// add counts a finished task by its status
+func (c *Counts) add(status string) {
+	switch status {
+	case store.StatusCompleted:
+		c.Completed++
+	case store.StatusFailed:
+		c.Failed++
+	case store.StatusCancelled:
+		c.Cancelled++
+	}
+}
+
+// formatBucket writes whole days as Nd, the way ParseBucket reads them
+func formatBucket(d time.Duration) string {
+	if d%day == 0 {
+		return strconv.Itoa(int(d/day)) + "d"
+	}
+	return d.String()
+}
@@ -0,0 +1,166 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/krigsexe/odin/orchestrator/internal/store"
+)
+
+var morning = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+// finished adds a task created and finished at offsets from morning
+func finished(st *store.MemoryStore, id, taskType, status, agent string, created, done time.Duration) {
+	st.AddFinished(store.TaskRecord{
+		ID:          id,
+		Type:        taskType,
+		Status:      status,
+		Agents:      []string{agent},
+		CreatedAt:   morning.Add(created),
+		CompletedAt: morning.Add(done),
+	})
+}
+
+// starts lists the start of each bucket
+func starts(sum *Summary) []time.Time {
+	out := make([]time.Time, len(sum.Buckets))
+	for i, b := range sum.Buckets {
+		out[i] = b.Start
+	}
+	return out
+}
+
+func TestHourlyBucketsCountOutcomesAndZeroFillGaps(t *testing.T) {
+	st := store.NewMemory()
+	finished(st, "a", "review", store.StatusCompleted, "review", 5*time.Minute, 20*time.Minute)
+	finished(st, "b", "code_write", store.StatusFailed, "dev", 30*time.Minute, 70*time.Minute)
+	finished(st, "c", "review", store.StatusCompleted, "review", 195*time.Minute, 220*time.Minute)
+	finished(st, "late", "review", store.StatusCompleted, "review", 250*time.Minute, 260*time.Minute)
+
+	sum, err := Summarize(context.Background(), st, Query{
+		Bucket: time.Hour,
+		Since:  morning.Add(10 * time.Minute),
+		Until:  morning.Add(4 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if sum.Bucket != "1h0m0s" || !sum.Since.Equal(morning) {
+		t.Errorf("bucket %s since %v, want 1h from the start of the hour", sum.Bucket, sum.Since)
+	}
+
+	want := []Counts{
+		{Submitted: 2, Completed: 1},
+		{Failed: 1},
+		{}, // nothing happened between 12:00 and 13:00
+		{Submitted: 1, Completed: 1},
+	}
+	if len(sum.Buckets) != len(want) {
+		t.Fatalf("buckets = %v, want %d", starts(sum), len(want))
+	}
+	for i, b := range sum.Buckets {
+		if !b.Start.Equal(morning.Add(time.Duration(i) * time.Hour)) {
+			t.Errorf("bucket %d starts at %v", i, b.Start)
+		}
+		if b.Counts != want[i] {
+			t.Errorf("bucket %d counts = %+v, want %+v", i, b.Counts, want[i])
+		}
+	}
+
+	first := sum.Buckets[0]
+	if got := *first.ByType["review"]; got != (Counts{Submitted: 1, Completed: 1}) {
+		t.Errorf("review in the first hour = %+v", got)
+	}
+	if got := *first.ByType["code_write"]; got != (Counts{Submitted: 1}) {
+		t.Errorf("code_write in the first hour = %+v", got)
+	}
+	if got := *sum.Buckets[1].ByAgent["dev"]; got != (Counts{Failed: 1}) {
+		t.Errorf("dev in the second hour = %+v", got)
+	}
+	if sum.Buckets[2].ByType != nil || sum.Buckets[2].ByAgent != nil {
+		t.Errorf("empty bucket has breakdowns: %+v", sum.Buckets[2])
+	}
+}
+
+func TestDailyBucketsFollowLocalMidnight(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemory()
+	// 09:30 UTC is 15:00 in Kolkata, the same day; 20:00 UTC is already
+	// the next day there
+	finished(st, "day", "review", store.StatusCompleted, "review", -30*time.Minute, 0)
+	finished(st, "night", "review", store.StatusCancelled, "review", 9*time.Hour, 10*time.Hour)
+
+	sum, err := Summarize(context.Background(), st, Query{
+		Bucket:   24 * time.Hour,
+		Since:    time.Date(2026, 3, 2, 0, 0, 0, 0, kolkata),
+		Until:    time.Date(2026, 3, 4, 0, 0, 0, 0, kolkata),
+		Location: kolkata,
+	})
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if sum.Bucket != "1d" || sum.Location != "Asia/Kolkata" || len(sum.Buckets) != 2 {
+		t.Fatalf("summary = %s in %s with buckets %v", sum.Bucket, sum.Location, starts(sum))
+	}
+	if got := sum.Buckets[1].Start.UTC(); !got.Equal(time.Date(2026, 3, 2, 18, 30, 0, 0, time.UTC)) {
+		t.Errorf("second day starts at %v UTC, want local midnight", got)
+	}
+	if got := sum.Buckets[0].Counts; got != (Counts{Submitted: 1, Completed: 1}) {
+		t.Errorf("first day = %+v", got)
+	}
+	if got := sum.Buckets[1].Counts; got != (Counts{Submitted: 1, Cancelled: 1}) {
+		t.Errorf("second day = %+v", got)
+	}
+}
+
+func TestHourlyBucketsAcrossDaylightSaving(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Clocks go from 02:00 to 03:00 on 29 March 2026
+	sum, err := Summarize(context.Background(), store.NewMemory(), Query{
+		Bucket:   time.Hour,
+		Since:    time.Date(2026, 3, 29, 0, 0, 0, 0, paris),
+		Until:    time.Date(2026, 3, 29, 5, 0, 0, 0, paris),
+		Location: paris,
+	})
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	var hours []int
+	for _, start := range starts(sum) {
+		hours = append(hours, start.In(paris).Hour())
+	}
+	if len(hours) != 4 || hours[0] != 0 || hours[1] != 1 || hours[2] != 3 || hours[3] != 4 {
+		t.Errorf("local hours = %v, want 0 1 3 4", hours)
+	}
+}
+
+func TestInvalidQueriesRejected(t *testing.T) {
+	if d, err := ParseBucket("7d"); err != nil || d != 7*24*time.Hour {
+		t.Errorf("ParseBucket(7d) = %v, %v", d, err)
+	}
+	for _, s := range []string{"1.5d", "0d", "hourly"} {
+		if _, err := ParseBucket(s); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("ParseBucket(%q) err = %v, want ErrInvalidQuery", s, err)
+		}
+	}
+
+	for name, q := range map[string]Query{
+		"under a minute":       {Bucket: time.Second, Since: morning, Until: morning.Add(time.Hour)},
+		"does not divide days": {Bucket: 7 * time.Minute, Since: morning, Until: morning.Add(time.Hour)},
+		"empty range":          {Bucket: time.Hour, Since: morning, Until: morning},
+		"too many buckets":     {Bucket: time.Minute, Since: morning, Until: morning.Add(MaxBuckets * time.Minute)},
+	} {
+		if _, err := Summarize(context.Background(), store.NewMemory(), q); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%s: err = %v, want ErrInvalidQuery", name, err)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// =============================================================================
+// ODIN v7.0 - Agent Supervisor
+// =============================================================================
+// Launches configured agent processes and restarts them when they crash
+// =============================================================================
+
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/internal/router"
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Replica states
+const (
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateBackoff  = "backoff"
+	StateFailed   = "failed"
+	StateStopped  = "stopped"
+)
+
+// Spec is everything needed to start one replica
+type Spec struct {
+	ID      string
+	Agent   string
+	Command string
+	Args    []string
+	Env     []string
+	Dir     string
+}
+
+// Process is a started replica
+type Process interface {
+	// Wait blocks until the process exits
+	Wait() error
+}
+
+// Runner starts processes; tests substitute a fake
+type Runner interface {
+	Start(ctx context.Context, spec Spec) (Process, error)
+}
+
+// ExecRunner starts real OS processes. They are killed when ctx ends.
+type ExecRunner struct{}
+
+// Start launches spec as a child process
+func (ExecRunner) Start(ctx context.Context, spec Spec) (Process, error) {
+	cmd := exec.CommandContext(ctx, spec.Command, spec.Args...)
+	cmd.Env = append(os.Environ(), spec.Env...)
+	cmd.Dir = spec.Dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// Replica is the supervisor's view of one agent process
+type Replica struct {
+	ID        string    `json:"id"`
+	Agent     string    `json:"agent"`
+	State     string    `json:"state"`
+	Restarts  int       `json:"restarts"`
+	StartedAt time.Time `json:"started_at"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Supervisor keeps configured agent processes running
+type Supervisor struct {
+	config *config.Config
+	logger *zap.Logger
+	runner Runner
+
+	mu       sync.Mutex
+	replicas map[string]*Replica
+	wg       sync.WaitGroup
+}
+
+// New creates a new Supervisor instance
+func New(cfg *config.Config, logger *zap.Logger) *Supervisor {
+	return &Supervisor{
+		config:   cfg,
+		logger:   logger,
+		runner:   ExecRunner{},
+		replicas: make(map[string]*Replica),
+	}
+}
+
+// SetRunner replaces the process runner
+func (s *Supervisor) SetRunner(r Runner) {
+	s.runner = r
+}
+
+// Enabled reports whether any agent has a process definition to run
+func (s *Supervisor) Enabled() bool {
+	return s.config.Agents.AutoStart && len(s.specs()) > 0
+}
+
+// Start launches every enabled agent that has a process definition, one
+// replica per scale factor (default 1, and always 1 with the autoscaling
+// feature off), and blocks until ctx is done and
+// all replicas have exited
+func (s *Supervisor) Start(ctx context.Context) error {
+	for _, spec := range s.specs() {
+		s.mu.Lock()
+		s.replicas[spec.ID] = &Replica{ID: spec.ID, Agent: spec.Agent, State: StateStarting}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.supervise(ctx, spec)
+	}
+
+	<-ctx.Done()
+	s.wg.Wait()
+	return nil
+}
+
+// specs expands config into one Spec per replica
+func (s *Supervisor) specs() []Spec {
+	agents := s.config.Agents
+	scaling := s.config.Features().Enabled(config.FeatureAutoscaling)
+	var specs []Spec
+
+	for _, name := range agents.Enabled {
+		pc, ok := agents.Processes[name]
+		if !ok || pc.Command == "" {
+			continue
+		}
+
+		replicas := agents.ScaleFactors[name]
+		if !scaling || replicas <= 0 {
+			replicas = 1
+		}
+
+		env := make([]string, 0, len(pc.Env)+2)
+		for k, v := range pc.Env {
+			env = append(env, k+"="+v)
+		}
+		sort.Strings(env)
+
+		for i := 1; i <= replicas; i++ {
+			id := fmt.Sprintf("%s-%d", name, i)
+			specs = append(specs, Spec{
+				ID:      id,
+				Agent:   name,
+				Command: pc.Command,
+				Args:    pc.Args,
+				Env:     append(env, "ODIN_AGENT_NAME="+name, "ODIN_AGENT_ID="+id),
+				Dir:     pc.Dir,
+			})
+		}
+	}
+	return specs
+}
+
+// supervise runs one replica until ctx ends or it exhausts its restarts
+func (s *Supervisor) supervise(ctx context.Context, spec Spec) {
+	defer s.wg.Done()
+
+	rc := s.config.Agents.Restart
+	initial := time.Duration(rc.InitialBackoff) * time.Second
+	if initial <= 0 {
+		initial = time.Second
+	}
+	maxBackoff := time.Duration(rc.MaxBackoff) * time.Second
+	if maxBackoff < initial {
+		maxBackoff = initial
+	}
+	stableAfter := time.Duration(rc.StableAfter) * time.Second
+
+	backoff := initial
+	for {
+		started := time.Now()
+		s.update(spec.ID, func(r *Replica) {
+			r.State = StateRunning
+			r.StartedAt = started
+		})
+
+		proc, err := s.runner.Start(ctx, spec)
+		if err == nil {
+			s.logger.Info("Agent process started", zap.String("id", spec.ID))
+			err = proc.Wait()
+		}
+
+		if ctx.Err() != nil {
+			s.update(spec.ID, func(r *Replica) { r.State = StateStopped })
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("exited")
+		}
+
+		// A long enough run means the earlier crashes are history
+		if stableAfter > 0 && time.Since(started) >= stableAfter {
+			backoff = initial
+		}
+
+		var restarts int
+		s.update(spec.ID, func(r *Replica) {
+			r.Restarts++
+			r.LastError = err.Error()
+			restarts = r.Restarts
+		})
+
+		if rc.MaxRestarts > 0 && restarts > rc.MaxRestarts {
+			s.update(spec.ID, func(r *Replica) { r.State = StateFailed })
+			s.logger.Error("Agent process exceeded restart limit",
+				zap.String("id", spec.ID),
+				zap.Int("max_restarts", rc.MaxRestarts),
+				zap.Error(err),
+			)
+			return
+		}
+
+		s.update(spec.ID, func(r *Replica) { r.State = StateBackoff })
+		s.logger.Warn("Agent process crashed, restarting",
+			zap.String("id", spec.ID),
+			zap.Int("restart", restarts),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		if !sleepCtx(ctx, backoff) {
+			s.update(spec.ID, func(r *Replica) { r.State = StateStopped })
+			return
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (s *Supervisor) update(id string, fn func(r *Replica)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.replicas[id]; ok {
+		fn(r)
+	}
+}
+
+// Replicas returns a snapshot of all supervised replicas
+func (s *Supervisor) Replicas() []Replica {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Replica, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Discover reports running replicas, so the supervisor can stand in for
+// static discovery on single-node deployments
+func (s *Supervisor) Discover(ctx context.Context) ([]*router.AgentInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	agents := make([]*router.AgentInfo, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		if r.State != StateRunning {
+			continue
+		}
+		agents = append(agents, &router.AgentInfo{
+			ID:     r.ID,
+			Name:   r.Agent,
+			Status: router.AgentStatusReady,
+		})
+	}
+	return agents, nil
+}
+
+// sleepCtx waits for d and reports false if ctx ended first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
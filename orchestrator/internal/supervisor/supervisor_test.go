@@ -0,0 +1,148 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+	"go.uber.org/zap"
+)
+
+// crashingRunner starts processes that exit with an error at once,
+// recording when each was started
+type crashingRunner struct {
+	mu     sync.Mutex
+	starts []time.Time
+	specs  []Spec
+}
+
+type crashedProcess struct{}
+
+func (crashedProcess) Wait() error { return errors.New("exit status 1") }
+
+func (r *crashingRunner) Start(ctx context.Context, spec Spec) (Process, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.starts = append(r.starts, time.Now())
+	r.specs = append(r.specs, spec)
+	return crashedProcess{}, nil
+}
+
+func (r *crashingRunner) started() []time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]time.Time(nil), r.starts...)
+}
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Agents.AutoStart = true
+	cfg.Agents.Enabled = []string{"dev", "review"}
+	cfg.Agents.Processes = map[string]config.ProcessConfig{
+		"dev": {Command: "odin-agent", Args: []string{"--agent", "dev"}},
+	}
+	cfg.Agents.Restart = config.RestartConfig{MaxRestarts: 2, InitialBackoff: 1, MaxBackoff: 10}
+	return cfg
+}
+
+func TestCrashingAgentRestartsWithBackoffUpToLimit(t *testing.T) {
+	s := New(testConfig(), zap.NewNop())
+	runner := &crashingRunner{}
+	s.SetRunner(runner)
+	if !s.Enabled() {
+		t.Fatal("supervisor not enabled with a process configured")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Start(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		replicas := s.Replicas()
+		if len(replicas) == 1 && replicas[0].State == StateFailed {
+			if replicas[0].Restarts != 3 || replicas[0].LastError != "exit status 1" {
+				t.Errorf("replica = %+v, want failed after the third crash", replicas[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replica never failed: %+v", replicas)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// The first run and the two restarts allowed, 1s then 2s apart
+	starts := runner.started()
+	if len(starts) != 3 {
+		t.Fatalf("started %d times, want 3", len(starts))
+	}
+	for i, want := range []time.Duration{time.Second, 2 * time.Second} {
+		if gap := starts[i+1].Sub(starts[i]); gap < want {
+			t.Errorf("restart %d after %v, want a backoff of at least %v", i+1, gap, want)
+		}
+	}
+
+	spec := runner.specs[0]
+	if spec.ID != "dev-1" || spec.Command != "odin-agent" || !slices.Contains(spec.Env, "ODIN_AGENT_ID=dev-1") {
+		t.Errorf("spec = %+v", spec)
+	}
+}
+
+func TestSupervisorStopsReplicasOnCancel(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.Restart.InitialBackoff = 60
+	s := New(cfg, zap.NewNop())
+	s.SetRunner(&crashingRunner{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Start(ctx)
+		close(done)
+	}()
+	for deadline := time.Now().Add(time.Second); ; {
+		if r := s.Replicas(); len(r) == 1 && r[0].State == StateBackoff {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replica never backed off: %+v", s.Replicas())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after cancel")
+	}
+	if r := s.Replicas(); r[0].State != StateStopped {
+		t.Errorf("state = %s after cancel, want stopped", r[0].State)
+	}
+}
+
+func TestAutoscalingFlagControlsReplicas(t *testing.T) {
+	cfg := testConfig()
+	cfg.Agents.ScaleFactors = map[string]int{"dev": 3}
+
+	if got := len(New(cfg, zap.NewNop()).specs()); got != 3 {
+		t.Errorf("replicas with autoscaling on = %d, want 3", got)
+	}
+
+	cfg.Flags = map[string]bool{config.FeatureAutoscaling: false}
+	if got := len(New(cfg, zap.NewNop()).specs()); got != 1 {
+		t.Errorf("replicas with autoscaling off = %d, want 1", got)
+	}
+}
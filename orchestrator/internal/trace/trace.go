@@ -0,0 +1,92 @@
+// Package trace records debug spans for a sampled subset of tasks
+package trace
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+// Sampler decides which tasks are traced. The decision is a pure function
+// of the task ID, so every component that sees the task agrees on it.
+type Sampler struct {
+	rate float64
+}
+
+// NewSampler creates a sampler tracing the given fraction of tasks
+func NewSampler(rate float64) *Sampler {
+	return &Sampler{rate: math.Max(0, math.Min(1, rate))}
+}
+
+// Sampled reports whether a task is traced. Forced tasks always are.
+func (s *Sampler) Sampled(taskID string, force bool) bool {
+	if force {
+		return true
+	}
+	if s == nil || s.rate <= 0 {
+		return false
+	}
+	if s.rate >= 1 {
+		return true
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(taskID))
+	return float64(h.Sum64())/math.MaxUint64 < s.rate
+}
+
+// Span is one timed step in the life of a traced task
+type Span struct {
+	TaskID string            `json:"task_id"`
+	Name   string            `json:"name"`
+	Start  time.Time         `json:"start"`
+	End    time.Time         `json:"end"`
+	Attrs  map[string]string `json:"attrs,omitempty"`
+}
+
+// Duration returns how long the span lasted
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Recorder keeps the spans of the most recently traced tasks
+type Recorder struct {
+	mu       sync.Mutex
+	maxTasks int
+	spans    map[string][]Span
+	order    []string // task IDs, oldest first
+}
+
+// NewRecorder creates a span recorder
+func NewRecorder(cfg config.TracingConfig) *Recorder {
+	return &Recorder{
+		maxTasks: max(cfg.MaxTasks, 1),
+		spans:    make(map[string][]Span),
+	}
+}
+
+// Record adds a finished span
+func (r *Recorder) Record(span Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.spans[span.TaskID]; !ok {
+		r.order = append(r.order, span.TaskID)
+		for len(r.order) > r.maxTasks {
+			delete(r.spans, r.order[0])
+			r.order = r.order[1:]
+		}
+	}
+	r.spans[span.TaskID] = append(r.spans[span.TaskID], span)
+}
+
+// Spans returns a task's spans in the order they finished
+func (r *Recorder) Spans(taskID string) []Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Span{}, r.spans[taskID]...)
+}
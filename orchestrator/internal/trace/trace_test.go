@@ -0,0 +1,64 @@
+package trace
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/krigsexe/odin/orchestrator/pkg/config"
+)
+
+func TestForcedTasksAlwaysSampled(t *testing.T) {
+	for _, s := range []*Sampler{nil, NewSampler(0), NewSampler(0.5)} {
+		for i := 0; i < 100; i++ {
+			if !s.Sampled(fmt.Sprintf("t-%d", i), true) {
+				t.Fatalf("forced task t-%d not sampled", i)
+			}
+		}
+	}
+}
+
+func TestSampledFractionMatchesRate(t *testing.T) {
+	const n = 20000
+	for _, rate := range []float64{0.05, 0.25, 0.5} {
+		s := NewSampler(rate)
+		sampled := 0
+		for i := 0; i < n; i++ {
+			id := fmt.Sprintf("task-%d", i)
+			if s.Sampled(id, false) {
+				sampled++
+			}
+			// The decision is the same wherever the task is seen
+			if s.Sampled(id, false) != NewSampler(rate).Sampled(id, false) {
+				t.Fatalf("decision for %s is not stable", id)
+			}
+		}
+		if got := float64(sampled) / n; got < rate*0.9 || got > rate*1.1 {
+			t.Errorf("rate %g sampled %.3f of tasks", rate, got)
+		}
+	}
+}
+
+func TestSampleRateBounds(t *testing.T) {
+	if NewSampler(-1).Sampled("t", false) {
+		t.Error("negative rate sampled a task")
+	}
+	if !NewSampler(2).Sampled("t", false) {
+		t.Error("rate above 1 skipped a task")
+	}
+}
+
+func TestRecorderKeepsRecentTasks(t *testing.T) {
+	r := NewRecorder(config.TracingConfig{MaxTasks: 2})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, id := range []string{"a", "b", "a", "c"} {
+		r.Record(Span{TaskID: id, Name: "run", Start: start, End: start.Add(time.Second)})
+	}
+
+	if len(r.Spans("a")) != 0 {
+		t.Error("oldest traced task kept past max_tasks")
+	}
+	if got := r.Spans("c"); len(got) != 1 || got[0].Duration() != time.Second {
+		t.Errorf("spans of c = %+v, want one second-long span", got)
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes each file under dir, creating directories as needed
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadMergesFragmentsInOrder(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"odin.yaml": `
+config_dir: conf.d
+orchestrator:
+  max_concurrent_tasks: 4
+  task_timeout: 60
+llm:
+  primary: {provider: ollama, model: llama3}
+agents:
+  enabled: [dev, review]
+`,
+		"conf.d/10-llm.yaml": `
+llm:
+  primary: {model: llama3.1}
+  profiles:
+    question: {model: small}
+`,
+		"conf.d/20-agents.yaml": `
+agents:
+  enabled: [dev, review, security]
+llm:
+  profiles:
+    code_write: {model: big}
+`,
+		"conf.d/30-overrides.yaml": `
+orchestrator:
+  max_concurrent_tasks: 16
+llm:
+  profiles:
+    question: {model: tiny}
+`,
+		"conf.d/notes.txt": "orchestrator: {max_concurrent_tasks: 99}",
+	})
+
+	cfg, err := Load(filepath.Join(dir, "odin.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// Later fragments win, keys they leave out are kept
+	if cfg.Orchestrator.MaxConcurrentTasks != 16 || cfg.Orchestrator.TaskTimeout != 60 {
+		t.Errorf("orchestrator = %d tasks, %ds timeout; want 16 and 60",
+			cfg.Orchestrator.MaxConcurrentTasks, cfg.Orchestrator.TaskTimeout)
+	}
+	if p := cfg.LLM.Primary; p.Provider != "ollama" || p.Model != "llama3.1" {
+		t.Errorf("primary = %s/%s, want ollama/llama3.1", p.Provider, p.Model)
+	}
+	// Maps merge key by key
+	if got := cfg.LLM.Profiles["question"].Model; got != "tiny" {
+		t.Errorf("question profile model = %q, want the last fragment's", got)
+	}
+	if got := cfg.LLM.Profiles["code_write"].Model; got != "big" {
+		t.Errorf("code_write profile model = %q, want it kept from 20-agents", got)
+	}
+	// Lists are replaced wholesale
+	if want := []string{"dev", "review", "security"}; !slices.Equal(cfg.Agents.Enabled, want) {
+		t.Errorf("enabled agents = %v, want %v", cfg.Agents.Enabled, want)
+	}
+}
+
+func TestLoadWithoutFragments(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"odin.yaml": "orchestrator: {max_concurrent_tasks: 4}\n",
+	})
+	cfg, err := Load(filepath.Join(dir, "odin.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Orchestrator.MaxConcurrentTasks != 4 {
+		t.Errorf("max concurrent tasks = %d, want 4", cfg.Orchestrator.MaxConcurrentTasks)
+	}
+}
+
+func TestLoadRejectsBrokenFragment(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"odin.yaml":          "config_dir: conf.d\n",
+		"conf.d/10-bad.yaml": "orchestrator: [unclosed\n",
+	})
+	if _, err := Load(filepath.Join(dir, "odin.yaml")); err == nil {
+		t.Error("Load accepted a malformed fragment")
+	}
+}
+
+func TestMinimalConfigGetsDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"odin.yaml": "redis: {db: 1}\n"})
+	cfg, err := Load(filepath.Join(dir, "odin.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if len(cfg.Agents.Enabled) == 0 || cfg.LLM.Primary.Provider == "" {
+		t.Errorf("agents %v, provider %q; want defaults", cfg.Agents.Enabled, cfg.LLM.Primary.Provider)
+	}
+	if cfg.Orchestrator.MaxConcurrentTasks != 10 || cfg.Orchestrator.ListenAddr != ":9000" {
+		t.Errorf("orchestrator = %d tasks on %q, want the defaults", cfg.Orchestrator.MaxConcurrentTasks, cfg.Orchestrator.ListenAddr)
+	}
+	if w := cfg.Warnings(); len(w) != 0 {
+		t.Errorf("warnings for a defaulted config: %v", w)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestEmptySectionsAreRepaired(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"odin.yaml": `
+orchestrator:
+  listen_addr: ""
+  tick_interval: 0
+  result_transformers: {}
+agents:
+  enabled: []
+  processes: {}
+  access: {}
+llm:
+  primary: {provider: "", model: ""}
+`})
+	cfg, err := Load(filepath.Join(dir, "odin.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.Agents.Processes == nil || cfg.Agents.Access == nil || cfg.Agents.Tokens == nil ||
+		cfg.Orchestrator.ResultTransformers == nil || cfg.Flags == nil {
+		t.Error("empty section left a nil map")
+	}
+	if cfg.Orchestrator.TickInterval != 1000 || cfg.Orchestrator.ListenAddr != ":9000" {
+		t.Errorf("tick %d on %q, want the defaults restored", cfg.Orchestrator.TickInterval, cfg.Orchestrator.ListenAddr)
+	}
+
+	w := cfg.Warnings()
+	if len(w) != 2 || !strings.HasPrefix(w[0], "agents.enabled is empty") || !strings.HasPrefix(w[1], "no LLM provider") {
+		t.Errorf("warnings = %q, want no agents and no provider", w)
+	}
+}
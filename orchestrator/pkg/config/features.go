@@ -0,0 +1,48 @@
+package config
+
+import "sort"
+
+// Feature flags consulted by subsystems
+const (
+	// FeatureAutoscaling runs agents.scale_factors replicas of each
+	// supervised agent; off runs one each
+	FeatureAutoscaling = "autoscaling"
+)
+
+// KnownFeatures maps every feature flag to its default state
+var KnownFeatures = map[string]bool{
+	FeatureAutoscaling: true,
+}
+
+// Features is the resolved state of the feature flags
+type Features map[string]bool
+
+// Features returns the flags set under features, with defaults for the
+// rest. Unknown flags are kept so they can be reported.
+func (c *Config) Features() Features {
+	features := make(Features, len(KnownFeatures)+len(c.Flags))
+	for name, on := range KnownFeatures {
+		features[name] = on
+	}
+	for name, on := range c.Flags {
+		features[name] = on
+	}
+	return features
+}
+
+// Enabled reports whether a flag is on
+func (f Features) Enabled(name string) bool {
+	return f[name]
+}
+
+// Unknown returns the configured flags no subsystem consults, sorted
+func (f Features) Unknown() []string {
+	var unknown []string
+	for name := range f {
+		if _, ok := KnownFeatures[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
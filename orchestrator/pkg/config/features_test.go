@@ -0,0 +1,42 @@
+package config
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFeaturesDefaultAndOverride(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"odin.yaml": `
+features:
+  autoscaling: false
+  time_travel: true
+`,
+	})
+	cfg, err := Load(filepath.Join(dir, "odin.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	features := cfg.Features()
+	if features.Enabled(FeatureAutoscaling) {
+		t.Error("autoscaling on, want it turned off")
+	}
+	if got := features.Unknown(); !slices.Equal(got, []string{"time_travel"}) {
+		t.Errorf("unknown = %v, want [time_travel]", got)
+	}
+}
+
+func TestKnownFlagDefaultsApply(t *testing.T) {
+	if !(&Config{}).Features().Enabled(FeatureAutoscaling) {
+		t.Error("autoscaling off, want its default on")
+	}
+}
+
+func TestUnsetFlagIsOff(t *testing.T) {
+	if (&Config{}).Features().Enabled("no_such_flag") {
+		t.Error("unknown flag reported enabled")
+	}
+}
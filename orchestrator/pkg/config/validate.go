@@ -0,0 +1,289 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// fillDefaults repairs what an explicit but empty section leaves behind.
+// Viper only applies defaults to keys that are missing, so a section set
+// to zero values or empty maps bypasses them. Maps are made non-nil and
+// intervals that must be positive fall back to their defaults.
+func fillDefaults(cfg *Config) {
+	if cfg.Flags == nil {
+		cfg.Flags = make(map[string]bool)
+	}
+	if cfg.Orchestrator.ResultTransformers == nil {
+		cfg.Orchestrator.ResultTransformers = make(map[string][]string)
+	}
+	if cfg.Orchestrator.Aggregation == nil {
+		cfg.Orchestrator.Aggregation = make(map[string]AggregationConfig)
+	}
+	if cfg.Agents.ScaleFactors == nil {
+		cfg.Agents.ScaleFactors = make(map[string]int)
+	}
+	if cfg.Agents.Processes == nil {
+		cfg.Agents.Processes = make(map[string]ProcessConfig)
+	}
+	if cfg.Agents.Access == nil {
+		cfg.Agents.Access = make(map[string]AccessConfig)
+	}
+	if cfg.Agents.Sandbox == nil {
+		cfg.Agents.Sandbox = make(map[string]SandboxConfig)
+	}
+	if cfg.Agents.Tokens == nil {
+		cfg.Agents.Tokens = make(map[string]string)
+	}
+
+	if cfg.Orchestrator.ListenAddr == "" {
+		cfg.Orchestrator.ListenAddr = ":9000"
+	}
+	positive(&cfg.Agents.HealthCheck, 30)
+	positive(&cfg.Orchestrator.TickInterval, 1000)
+	positive(&cfg.Orchestrator.Archival.Interval, 60)
+	positive(&cfg.Orchestrator.Outbox.Interval, 5)
+	positive(&cfg.Orchestrator.Outbox.BatchSize, 200)
+	positive(&cfg.Orchestrator.Retention.Interval, 60)
+	positive(&cfg.Orchestrator.Retention.BatchSize, 500)
+	positive(&cfg.Orchestrator.Leases.TTL, 30)
+}
+
+// positive replaces a non-positive setting with its default
+func positive(v *int, def int) {
+	if *v <= 0 {
+		*v = def
+	}
+}
+
+// Warnings reports settings that load fine but leave the orchestrator
+// unable to do useful work
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if len(c.Agents.Enabled) == 0 {
+		warnings = append(warnings, "agents.enabled is empty: no agents will be discovered and every route will fail")
+	}
+	if c.LLM.Primary.Provider == "" && len(c.LLM.Fallback) == 0 {
+		warnings = append(warnings, "no LLM provider configured: llm.primary.provider is empty and llm.fallback has none")
+	}
+	if c.Orchestrator.MaxConcurrentTasks <= 0 {
+		warnings = append(warnings, "orchestrator.max_concurrent_tasks is not positive: no task will ever be dispatched")
+	}
+	insecure := c.LLM.Transport.InsecureSkipVerify
+	for _, pc := range slices.Concat([]ProviderConfig{c.LLM.Primary}, c.LLM.Fallback, c.LLM.Consensus.Providers) {
+		insecure = insecure || pc.Transport.InsecureSkipVerify
+	}
+	if insecure {
+		warnings = append(warnings, "insecure_skip_verify is set on a provider transport: its certificates are not checked")
+	}
+	return warnings
+}
+
+// Validate reports every setting the orchestrator would reject or
+// misread, as one error per problem joined with errors.Join. It only
+// inspects values; nothing is connected to.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		if value != "" && !slices.Contains(allowed, value) {
+			fail("%s: unknown value %q (want one of %v)", key, value, allowed)
+		}
+	}
+	nonNegative := func(key string, n float64) {
+		if n < 0 {
+			fail("%s: must not be negative, got %v", key, n)
+		}
+	}
+
+	nonNegative("database.max_connections", float64(c.Database.MaxConnections))
+	nonNegative("redis.db", float64(c.Redis.DB))
+	oneOf("redis.compression.codec", c.Redis.Compression.Codec, "none", "gzip", "zstd")
+
+	validateProvider("llm.primary", c.LLM.Primary, true, fail)
+	for i, pc := range c.LLM.Fallback {
+		validateProvider(fmt.Sprintf("llm.fallback[%d]", i), pc, true, fail)
+	}
+	for _, taskType := range sortedKeys(c.LLM.Profiles) {
+		pc := c.LLM.Profiles[taskType]
+		// A profile without a provider uses the primary's
+		validateProvider("llm.profiles."+taskType, pc, false, fail)
+	}
+	for i, pc := range c.LLM.Consensus.Providers {
+		validateProvider(fmt.Sprintf("llm.consensus.providers[%d]", i), pc, true, fail)
+	}
+	if a := c.LLM.Consensus.MinAgreement; a < 0 || a > 1 {
+		fail("llm.consensus.min_agreement: must be between 0 and 1, got %v", a)
+	}
+	oneOf("llm.consensus.fallback", c.LLM.Consensus.Fallback, "fail", "fallback_to_primary", "queue_until_available")
+	oneOf("llm.context.strategy", c.LLM.Context.Strategy, "oldest_first", "relevance")
+	oneOf("llm.cassette.mode", c.LLM.Cassette.Mode, "off", "record", "replay")
+	for _, taskType := range sortedKeys(c.LLM.Params) {
+		if err := c.LLM.Params[taskType].Validate(); err != nil {
+			fail("llm.params.%s: %v", taskType, err)
+		}
+	}
+	if c.LLM.Cassette.Mode == "replay" && c.LLM.Cassette.Dir == "" {
+		fail("llm.cassette.dir: required in replay mode")
+	}
+	nonNegative("llm.max_request_tokens", float64(c.LLM.MaxRequestTokens))
+	nonNegative("llm.max_request_bytes", float64(c.LLM.MaxRequestBytes))
+	validateTransport("llm.transport", c.LLM.Transport, fail)
+	oneOf("llm.fallback_timeout.policy", c.LLM.FallbackTimeout.Policy, "none", "even", "fixed")
+	nonNegative("llm.fallback_timeout.slice", float64(c.LLM.FallbackTimeout.Slice))
+	if c.LLM.FallbackTimeout.Policy == "fixed" && c.LLM.FallbackTimeout.Slice == 0 {
+		fail("llm.fallback_timeout.slice: required by the fixed policy")
+	}
+
+	o := c.Orchestrator
+	if _, _, err := net.SplitHostPort(o.ListenAddr); err != nil {
+		fail("orchestrator.listen_addr: %v", err)
+	}
+	nonNegative("orchestrator.max_concurrent_tasks", float64(o.MaxConcurrentTasks))
+	nonNegative("orchestrator.max_queued_tasks", float64(o.MaxQueuedTasks))
+	nonNegative("orchestrator.task_timeout", float64(o.TaskTimeout))
+	nonNegative("orchestrator.no_route_grace", float64(o.NoRouteGrace))
+	nonNegative("orchestrator.starvation_after", float64(o.StarvationAfter))
+	nonNegative("orchestrator.retry_exclusion", float64(o.RetryExclusion))
+	nonNegative("orchestrator.ack_timeout", float64(o.AckTimeout))
+	nonNegative("orchestrator.suspend_timeout", float64(o.SuspendTimeout))
+	nonNegative("orchestrator.retention.tasks", float64(o.Retention.Tasks))
+	nonNegative("orchestrator.retention.results", float64(o.Retention.Results))
+	nonNegative("orchestrator.retention.audit", float64(o.Retention.Audit))
+	if r := o.Retention; r.Enabled && r.Tasks == 0 && r.Results == 0 && r.Audit == 0 {
+		fail("orchestrator.retention: enabled but no window is set")
+	}
+	oneOf("orchestrator.retry_jitter", o.RetryJitter, "additive", "full", "equal", "decorrelated", "none")
+	oneOf("orchestrator.scheduling", o.Scheduling, "priority", "edf")
+	for _, taskType := range sortedKeys(o.Prerequisites) {
+		for i, pc := range o.Prerequisites[taskType] {
+			if pc.Type == "" {
+				fail("orchestrator.prerequisites.%s[%d].type: required", taskType, i)
+			}
+		}
+	}
+	for _, taskType := range sortedKeys(o.Aggregation) {
+		ac := o.Aggregation[taskType]
+		key := "orchestrator.aggregation." + taskType
+		oneOf(key+".policy", ac.Policy, "all", "majority", "weighted")
+		if ac.Threshold < 0 || ac.Threshold > 1 {
+			fail("%s.threshold: must be between 0 and 1, got %v", key, ac.Threshold)
+		}
+		for _, agent := range sortedKeys(ac.Weights) {
+			nonNegative(key+".weights."+agent, ac.Weights[agent])
+		}
+	}
+	if r := o.Tracing.SampleRate; r < 0 || r > 1 {
+		fail("orchestrator.tracing.sample_rate: must be between 0 and 1, got %v", r)
+	}
+	if ac := o.AdaptiveConcurrency; ac.Enabled {
+		if ac.Max > 0 && ac.Min > ac.Max {
+			fail("orchestrator.adaptive_concurrency: min %d is above max %d", ac.Min, ac.Max)
+		}
+		if ac.Tolerance <= 1 {
+			fail("orchestrator.adaptive_concurrency.tolerance: must be above 1, got %v", ac.Tolerance)
+		}
+		if ac.Backoff <= 0 || ac.Backoff >= 1 {
+			fail("orchestrator.adaptive_concurrency.backoff: must be between 0 and 1, got %v", ac.Backoff)
+		}
+	}
+
+	nonNegative("agents.prefer_wait", float64(c.Agents.PreferWait))
+	for _, agent := range sortedKeys(c.Agents.RedactInput.Agents) {
+		validateRedactedFields("agents.redact_input.agents."+agent, c.Agents.RedactInput.Agents[agent], fail)
+	}
+	for _, taskType := range sortedKeys(c.Agents.RedactInput.TaskTypes) {
+		validateRedactedFields("agents.redact_input.task_types."+taskType, c.Agents.RedactInput.TaskTypes[taskType], fail)
+	}
+
+	if c.Alerts.WebhookURL != "" {
+		if u, err := url.Parse(c.Alerts.WebhookURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("alerts.webhook_url: not an absolute URL: %q", c.Alerts.WebhookURL)
+		}
+	}
+	oneOf("alerts.min_severity", c.Alerts.MinSeverity, "info", "warning", "critical")
+
+	for i, pattern := range c.Logging.Redact.Keys {
+		if _, err := regexp.Compile(pattern); err != nil {
+			fail("logging.redact.keys[%d]: %v", i, err)
+		}
+	}
+	for i, pattern := range c.Logging.Redact.Values {
+		if _, err := regexp.Compile(pattern); err != nil {
+			fail("logging.redact.values[%d]: %v", i, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sortedKeys returns a map's keys in order, so problems under a map are
+// reported in the same order on every run
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// validateProvider checks one provider entry of an LLM chain
+func validateProvider(key string, pc ProviderConfig, needsProvider bool, fail func(string, ...interface{})) {
+	if needsProvider && pc.Provider == "" && (pc.Model != "" || pc.APIKey != "" || pc.BaseURL != "") {
+		fail("%s.provider: required when the entry sets other fields", key)
+	}
+	for _, field := range []struct {
+		name  string
+		value float64
+	}{
+		{"rate_limit", float64(pc.RateLimit)},
+		{"max_concurrent", float64(pc.MaxConcurrent)},
+		{"context_window", float64(pc.ContextWindow)},
+		{"input_cost", pc.InputCost},
+		{"output_cost", pc.OutputCost},
+		{"max_request_tokens", float64(pc.MaxRequestTokens)},
+		{"max_request_bytes", float64(pc.MaxRequestBytes)},
+	} {
+		if field.value < 0 {
+			fail("%s.%s: must not be negative, got %v", key, field.name, field.value)
+		}
+	}
+	if pc.BaseURL != "" {
+		if u, err := url.Parse(pc.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("%s.base_url: not an absolute URL: %q", key, pc.BaseURL)
+		}
+	}
+	if err := pc.Params.Validate(); err != nil {
+		fail("%s.params: %v", key, err)
+	}
+	validateTransport(key+".transport", pc.Transport, fail)
+}
+
+// validateTransport checks a provider transport's proxy URL
+func validateTransport(key string, t TransportConfig, fail func(string, ...interface{})) {
+	if t.Proxy == "" || t.Proxy == "none" {
+		return
+	}
+	u, err := url.Parse(t.Proxy)
+	if err != nil || u.Host == "" || !slices.Contains([]string{"http", "https", "socks5"}, u.Scheme) {
+		fail("%s.proxy: want an http, https or socks5 URL, or none", key)
+	}
+}
+
+// validateRedactedFields checks that each field is a dotted path into a
+// task's input or context
+func validateRedactedFields(key string, fields []string, fail func(string, ...interface{})) {
+	for i, field := range fields {
+		parts := strings.Split(field, ".")
+		if len(parts) < 2 || (parts[0] != "input" && parts[0] != "context") || slices.Contains(parts, "") {
+			fail("%s[%d]: want a path under input or context, got %q", key, i, field)
+		}
+	}
+}
@@ -194,7 +194,7 @@ const AgentStatus = ({ name, status }: { name: string, status: 'idle' | 'active'
 const TaskCard = ({ task }: { task: any }) => (
   <div className="bg-card rounded-lg border border-border p-4 shadow-sm animate-in fade-in slide-in-from-bottom-4">
     <div className="flex justify-between items-start mb-2">
-      <h3 className="font-medium">{task.description}</h3>
+      <h3 className="font-medium">{task.name || task.description}</h3>
       <span className="px-2 py-1 bg-blue-100 text-blue-800 dark:bg-blue-900 dark:text-blue-100 text-xs rounded-full">
         Processing
       </span>